@@ -2,25 +2,45 @@
 package access
 
 import (
+	"context"
 	"crypto/x509"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/canonical/lxd/lxd/response"
-	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/v2/rest"
 	"github.com/canonical/microcluster/v2/rest/access"
 	"github.com/canonical/microcluster/v2/state"
 
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/client"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
 )
 
+// bearerPrefix prefixes the read-only token in the token header
+const bearerPrefix = "Bearer "
+
+// verifyReadOnlyToken returns whether a token is a valid read-only token
+var verifyReadOnlyToken = func(ctx context.Context, s state.State, token string) (bool, error) {
+	return sunbeam.VerifyReadOnlyToken(ctx, s, token)
+}
+
 // AuthenticateClusterCAHandler authenticates the cluster CA for incoming requests.
 // It checks if the request is trusted and verifies the client certificate against the cluster CA.
 // If the request is trusted or the client certificate is successfully verified, it allows the request.
 // Otherwise, it returns a forbidden response.
+// Requests bearing a read-only token are forbidden, only the endpoints
+// created with ReadOnlyTokenEndpoint accept them.
 func AuthenticateClusterCAHandler(state state.State, r *http.Request) (bool, response.Response) {
+	_, bearer := readOnlyToken(r)
+	if bearer {
+		logging.FromRequest(r).Debug("Rejecting read-only token on endpoint not readable with it")
+		return false, response.Forbidden(fmt.Errorf("Read-only token cannot access %s", r.URL.Path))
+	}
 
 	trusted, resp := access.AllowAuthenticated(state, r)
 
@@ -28,10 +48,11 @@ func AuthenticateClusterCAHandler(state state.State, r *http.Request) (bool, res
 		return trusted, resp
 	}
 
+	l := logging.FromRequest(r)
 	leader, err := state.Leader()
 
 	if err != nil {
-		logger.Errorf("Failed to get leader client: %v", err)
+		l.Error("Failed to get leader client", "error", err)
 		return false, response.InternalError(err)
 	}
 
@@ -39,28 +60,28 @@ func AuthenticateClusterCAHandler(state state.State, r *http.Request) (bool, res
 	clusterCA, err := client.ConfigClusterCAGet(r.Context(), leader)
 	if err != nil {
 		// If no CA is configured, simply reject the request
-		if strings.Contains(err.Error(), "not found") {
-			logger.Debug("No cluster CA configured, rejecting request")
+		if errors.Is(err, client.ErrNotFound) {
+			l.Debug("No cluster CA configured, rejecting request")
 			return false, response.Forbidden(nil)
 		}
-		logger.Errorf("Failed to get cluster CA: %v", err)
+		l.Error("Failed to get cluster CA", "error", err)
 		return false, response.InternalError(nil)
 	}
 
 	roots := x509.NewCertPool()
 	ok := roots.AppendCertsFromPEM([]byte(clusterCA))
 	if !ok {
-		logger.Error("Failed to parse cluster CA")
+		l.Error("Failed to parse cluster CA")
 		return false, response.InternalError(nil)
 	}
 
 	if r.TLS == nil {
-		logger.Error("Rejecting request without TLS")
+		l.Error("Rejecting request without TLS")
 		return false, response.Forbidden(nil)
 	}
 
 	if len(r.TLS.PeerCertificates) > 10 {
-		logger.Error("Rejecting request with too many certificates")
+		l.Error("Rejecting request with too many certificates", "certificates", len(r.TLS.PeerCertificates))
 		return false, response.Forbidden(nil)
 	}
 
@@ -73,7 +94,7 @@ func AuthenticateClusterCAHandler(state state.State, r *http.Request) (bool, res
 	for _, cert := range r.TLS.PeerCertificates {
 		_, err := cert.Verify(opts)
 		if err == nil {
-			logger.Debug("Allowing request authenticated using cluster CA")
+			l.Debug("Allowing request authenticated using cluster CA")
 			return true, nil
 		}
 	}
@@ -81,6 +102,59 @@ func AuthenticateClusterCAHandler(state state.State, r *http.Request) (bool, res
 	return false, response.Forbidden(nil)
 }
 
+// readOnlyToken returns the read-only token the request bears, if any.
+func readOnlyToken(r *http.Request) (string, bool) {
+	header := r.Header.Get(apitypes.ReadOnlyTokenHeader)
+	if header == "" {
+		return "", false
+	}
+
+	return strings.TrimPrefix(header, bearerPrefix), true
+}
+
+// IsReadOnlyTokenRequest returns whether the request is authorized by a
+// read-only token, so that the endpoints created with ReadOnlyTokenEndpoint
+// can leave out secrets from their response.
+func IsReadOnlyTokenRequest(r *http.Request) bool {
+	_, bearer := readOnlyToken(r)
+	return bearer
+}
+
+// authorizeReadOnlyToken allows the GET and HEAD requests bearing a valid
+// read-only token, any other request is forbidden.
+func authorizeReadOnlyToken(state state.State, r *http.Request, token string) (bool, response.Response) {
+	l := logging.FromRequest(r)
+	valid, err := verifyReadOnlyToken(r.Context(), state, token)
+	if err != nil {
+		l.Error("Failed to verify read-only token", "error", err)
+		return false, response.InternalError(nil)
+	}
+
+	if !valid {
+		l.Debug("Rejecting request with invalid read-only token")
+		return false, response.Forbidden(nil)
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		l.Debug("Rejecting request with read-only token")
+		return false, response.Forbidden(fmt.Errorf("Read-only token cannot %s %s", r.Method, r.URL.Path))
+	}
+
+	return true, nil
+}
+
+// AuthenticateClusterCAOrReadOnlyTokenHandler authorizes the GET and HEAD
+// requests bearing a valid read-only token, and authenticates any other
+// request as AuthenticateClusterCAHandler does.
+func AuthenticateClusterCAOrReadOnlyTokenHandler(state state.State, r *http.Request) (bool, response.Response) {
+	token, bearer := readOnlyToken(r)
+	if bearer {
+		return authorizeReadOnlyToken(state, r, token)
+	}
+
+	return AuthenticateClusterCAHandler(state, r)
+}
+
 // AuthenticateUnixHandler only allow requests coming from the unix socket.
 func AuthenticateUnixHandler(_ state.State, r *http.Request) (bool, response.Response) {
 	if r.RemoteAddr == "@" {
@@ -98,3 +172,16 @@ func ClusterCATrustedEndpoint(handler func(state state.State, r *http.Request) r
 		ProxyTarget:    proxyTarget,
 	}
 }
+
+// ReadOnlyTokenEndpoint is a helper to create a cluster peer endpoint also
+// readable with a read-only token. It must only serve state that is not
+// secret, such as the nodes and their status, or leave out the secrets of
+// requests for which IsReadOnlyTokenRequest holds.
+func ReadOnlyTokenEndpoint(handler func(state state.State, r *http.Request) response.Response, proxyTarget bool) rest.EndpointAction {
+	return rest.EndpointAction{
+		Handler:        handler,
+		AccessHandler:  AuthenticateClusterCAOrReadOnlyTokenHandler,
+		AllowUntrusted: true,
+		ProxyTarget:    proxyTarget,
+	}
+}
@@ -0,0 +1,68 @@
+package access
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/microcluster/v2/state"
+)
+
+// TestReadOnlyToken tests that a read-only token can GET the nodes but is
+// forbidden to PUT a config item, and that an unknown token is forbidden.
+// Endpoints not created with ReadOnlyTokenEndpoint forbid any read-only token.
+func TestReadOnlyToken(t *testing.T) {
+	verify := verifyReadOnlyToken
+	t.Cleanup(func() { verifyReadOnlyToken = verify })
+	verifyReadOnlyToken = func(_ context.Context, _ state.State, token string) (bool, error) {
+		return token == "observer", nil
+	}
+
+	testCases := []struct {
+		name    string
+		method  string
+		path    string
+		token   string
+		handler string
+		allowed bool
+		status  int
+	}{
+		{name: "get nodes", method: http.MethodGet, path: "/1.0/nodes", token: "observer", allowed: true},
+		{name: "get config", method: http.MethodGet, path: "/1.0/config/TerraformManifest", token: "observer", handler: "cluster-ca", status: http.StatusForbidden},
+		{name: "put config", method: http.MethodPut, path: "/1.0/config/TerraformManifest", token: "observer", status: http.StatusForbidden},
+		{name: "delete node", method: http.MethodDelete, path: "/1.0/nodes/node1", token: "observer", status: http.StatusForbidden},
+		{name: "unknown token", method: http.MethodGet, path: "/1.0/nodes", token: "intruder", status: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+
+			handler := AuthenticateClusterCAOrReadOnlyTokenHandler
+			if tc.handler == "cluster-ca" {
+				handler = AuthenticateClusterCAHandler
+			}
+
+			allowed, resp := handler(nil, req)
+			if allowed != tc.allowed {
+				t.Fatalf("Expected allowed %v, got %v", tc.allowed, allowed)
+			}
+
+			if tc.allowed {
+				return
+			}
+
+			rec := httptest.NewRecorder()
+			err := resp.Render(rec, req)
+			if err != nil {
+				t.Fatalf("Failed to render response: %v", err)
+			}
+
+			if rec.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/canonical/microcluster/v2/rest"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+)
+
+// readOnlyTokenEndpoints are the only extended endpoints readable with a
+// read-only token, none of them serving secrets to it.
+var readOnlyTokenEndpoints = map[string]bool{
+	"GET " + nodesCmd.Path:               true,
+	"GET " + nodeCmd.Path:                true,
+	"GET " + statusCmd.Path:              true,
+	"GET " + maintenanceCmd.Path:         true,
+	"GET " + maintenanceProgressCmd.Path: true,
+	"GET " + metaCmd.Path:                true,
+	"GET " + configCmd.Path:              true,
+}
+
+// TestExtendedEndpointsAccess tests that every extended endpoint is
+// authenticated by the cluster CA handler, but the few ones also readable
+// with a read-only token.
+func TestExtendedEndpointsAccess(t *testing.T) {
+	clusterCA := reflect.ValueOf(access.AuthenticateClusterCAHandler).Pointer()
+	readOnly := reflect.ValueOf(access.AuthenticateClusterCAOrReadOnlyTokenHandler).Pointer()
+	for _, e := range extendedEndpoints {
+		actions := map[string]rest.EndpointAction{"GET": e.Get, "PUT": e.Put, "POST": e.Post, "DELETE": e.Delete, "PATCH": e.Patch}
+		for method, action := range actions {
+			if action.Handler == nil {
+				continue
+			}
+
+			expected, name := clusterCA, "cluster CA"
+			if readOnlyTokenEndpoints[method+" "+e.Path] {
+				expected, name = readOnly, "read-only token"
+			}
+
+			if action.AccessHandler == nil || reflect.ValueOf(action.AccessHandler).Pointer() != expected {
+				t.Errorf("Expected %s %s to be authenticated by the %s handler", method, e.Path, name)
+			}
+		}
+	}
+}
+
+// TestReadOnlyTokenSecretsForbidden tests that a read-only token cannot read
+// the endpoints serving secrets.
+func TestReadOnlyTokenSecretsForbidden(t *testing.T) {
+	testCases := []struct {
+		path     string
+		endpoint rest.Endpoint
+	}{
+		{path: "/1.0/backup", endpoint: backupCmd},
+		{path: "/1.0/jujuusers", endpoint: jujuusersCmd},
+		{path: "/1.0/jujuusers/admin", endpoint: jujuuserCmd},
+		{path: "/1.0/terraformstate/openstack", endpoint: terraformStateCmd},
+		{path: "/1.0/config:export", endpoint: configExportCmd},
+		{path: "/1.0/config/webhooks/history", endpoint: configHistoryCmd},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer observer")
+
+			allowed, resp := tc.endpoint.Get.AccessHandler(nil, req)
+			if allowed {
+				t.Fatalf("Expected a read-only token to be forbidden to GET %s", tc.path)
+			}
+
+			rec := httptest.NewRecorder()
+			err := resp.Render(rec, req)
+			if err != nil {
+				t.Fatalf("Failed to render response: %v", err)
+			}
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("Expected status %d, got %d", http.StatusForbidden, rec.Code)
+			}
+		})
+	}
+}
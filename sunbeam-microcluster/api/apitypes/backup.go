@@ -0,0 +1,44 @@
+package apitypes
+
+// BackupVersion is the version of the backup bundle format produced by
+// this daemon. Only bundles of the same version can be restored.
+const BackupVersion = 1
+
+// RedactedValue replaces secrets in a backup bundle exported with secrets
+// redacted.
+const RedactedValue = "<redacted>"
+
+// Backup is a snapshot of the cluster database state
+type Backup struct {
+	// Version is the backup bundle format version
+	Version int `json:"version" yaml:"version"`
+	// SchemaInternal is the microcluster internal schema version of the cluster
+	SchemaInternal int `json:"schema_internal" yaml:"schema_internal"`
+	// SchemaExternal is the sunbeam schema version of the cluster
+	SchemaExternal int `json:"schema_external" yaml:"schema_external"`
+	// CreatedAt is the RFC3339 time the backup was taken
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	// Redacted is set when secrets were replaced by RedactedValue
+	Redacted bool `json:"redacted" yaml:"redacted"`
+
+	Nodes           Nodes              `json:"nodes" yaml:"nodes"`
+	Config          []BackupConfigItem `json:"config" yaml:"config"`
+	Manifests       Manifests          `json:"manifests" yaml:"manifests"`
+	JujuUsers       JujuUsers          `json:"jujuusers" yaml:"jujuusers"`
+	StorageBackends StorageBackends    `json:"storage_backends" yaml:"storage_backends"`
+	FeatureGates    FeatureGates       `json:"feature_gates" yaml:"feature_gates"`
+	// RemovedNodes are the tombstones of the removed nodes, absent from
+	// bundles taken before they were saved
+	RemovedNodes Nodes `json:"removed_nodes" yaml:"removed_nodes"`
+	// JoinTokenScopes restrict the join tokens, which the restore keeps, to
+	// their roles
+	JoinTokenScopes JoinTokenScopes `json:"join_token_scopes" yaml:"join_token_scopes"`
+}
+
+// BackupConfigItem is a config item saved in a backup, terraform states
+// and locks included
+type BackupConfigItem struct {
+	Key      string `json:"key" yaml:"key"`
+	Value    string `json:"value" yaml:"value"`
+	Revision int    `json:"revision" yaml:"revision"`
+}
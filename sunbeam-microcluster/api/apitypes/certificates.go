@@ -6,3 +6,37 @@ type CertPair struct {
 	Certificate string `json:"certificate" yaml:"certificate"`
 	PrivateKey  string `json:"private-key" yaml:"private-key"`
 }
+
+// Certificates served by a member during a cluster certificate rotation
+const (
+	CertificateNew     = "new"
+	CertificateOld     = "old"
+	CertificateUnknown = "unknown"
+)
+
+// ClusterCertificatePut is a new cluster certificate for a member to serve.
+type ClusterCertificatePut struct {
+	Certificate string `json:"certificate" yaml:"certificate"`
+	PrivateKey  string `json:"private-key" yaml:"private-key"`
+	// CA validating the certificate chain, defaults to the cluster CA
+	CA string `json:"ca,omitempty" yaml:"ca,omitempty"`
+	// ValidateOnly validates the certificate without serving it
+	ValidateOnly bool `json:"validate-only,omitempty" yaml:"validate-only,omitempty"`
+}
+
+// ClusterCertificateMember is the certificate served by a member once a
+// rotation is over, one of CertificateNew, CertificateOld or
+// CertificateUnknown if the member cannot be reached.
+type ClusterCertificateMember struct {
+	Name        string `json:"name" yaml:"name"`
+	Certificate string `json:"certificate" yaml:"certificate"`
+}
+
+// ClusterCertificateRotation reports the outcome of a cluster certificate
+// rotation. A rotation halts on the first member failing to validate or
+// serve the new certificate.
+type ClusterCertificateRotation struct {
+	Members []ClusterCertificateMember `json:"members" yaml:"members"`
+	Failed  string                     `json:"failed,omitempty" yaml:"failed,omitempty"`
+	Error   string                     `json:"error,omitempty" yaml:"error,omitempty"`
+}
@@ -0,0 +1,119 @@
+package apitypes
+
+// ConfigStrictHeader is the request header rejecting writes of unknown
+// config keys when set to true.
+const ConfigStrictHeader = "X-Sunbeam-Config-Strict"
+
+// ConfigWarningHeader is the response header flagging a write of an
+// unknown config key.
+const ConfigWarningHeader = "X-Sunbeam-Config-Warning"
+
+// ConfigScopeHeader is the response header telling whether the value of a
+// config key read for a node role is the override of the role or the
+// global value.
+const ConfigScopeHeader = "X-Sunbeam-Config-Scope"
+
+// Config value scopes.
+const (
+	ConfigScopeGlobal = "global"
+	ConfigScopeRole   = "role"
+)
+
+// Config key types.
+const (
+	ConfigTypeString    = "string"
+	ConfigTypeBool      = "bool"
+	ConfigTypeUint      = "uint"
+	ConfigTypeCIDR      = "cidr"
+	ConfigTypeIP        = "ip"
+	ConfigTypeEnum      = "enum"
+	ConfigTypePortRange = "port-range"
+	ConfigTypeDuration  = "duration"
+	ConfigTypeJSON      = "json"
+)
+
+// ConfigSchema holds the list of known config keys
+type ConfigSchema []ConfigKeySchema
+
+// ConfigKeySchema describes the type of the value of a known config key
+type ConfigKeySchema struct {
+	Key         string `json:"key" yaml:"key"`
+	Type        string `json:"type" yaml:"type"`
+	Description string `json:"description" yaml:"description"`
+	// Values are the allowed values of an enum key
+	Values []string `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// ConfigHistory holds the recorded revisions of a config key, oldest first
+type ConfigHistory []ConfigRevision
+
+// ConfigRevision is a value a config key held
+type ConfigRevision struct {
+	Revision  int    `json:"revision" yaml:"revision"`
+	Value     string `json:"value" yaml:"value"`
+	ChangedAt string `json:"changed_at" yaml:"changed_at"`
+	ChangedBy string `json:"changed_by" yaml:"changed_by"`
+}
+
+// ConfigRollback selects the revision a config key is rolled back to, the
+// revision preceding the current one if unset
+type ConfigRollback struct {
+	Revision int `json:"revision,omitempty" yaml:"revision,omitempty"`
+}
+
+// ConfigTransactionFailedCode is the code of the error returned for a
+// config transaction rejected by errors on some of its keys
+const ConfigTransactionFailedCode = "config-transaction-failed"
+
+// ConfigTransaction sets config keys all at once, if the keys of
+// Preconditions hold their expected values
+type ConfigTransaction struct {
+	Set map[string]string `json:"set" yaml:"set"`
+	// Role scopes the keys of Set and Preconditions to the overrides of a
+	// node role, the global values are used if unset
+	Role string `json:"role,omitempty" yaml:"role,omitempty"`
+	// Preconditions are the expected current values of config keys, null
+	// expecting a key to be unset
+	Preconditions map[string]*string `json:"preconditions,omitempty" yaml:"preconditions,omitempty"`
+}
+
+// ConfigTransactionFailed is the metadata of the error returned for a
+// rejected config transaction
+type ConfigTransactionFailed struct {
+	// Code is ConfigTransactionFailedCode
+	Code string `json:"code" yaml:"code"`
+	// Errors are the errors of each rejected key, sorted by key
+	Errors []ConfigKeyError `json:"errors" yaml:"errors"`
+}
+
+// ConfigKeyError is the reason a config key of a transaction was rejected
+type ConfigKeyError struct {
+	Key   string `json:"key" yaml:"key"`
+	Error string `json:"error" yaml:"error"`
+}
+
+// ConfigDocument holds all the config keys, the overrides of the node roles
+// included under their scoped key
+type ConfigDocument struct {
+	// Redacted is set if the values of the keys holding credentials are
+	// RedactedValue
+	Redacted bool              `json:"redacted" yaml:"redacted"`
+	Config   map[string]string `json:"config" yaml:"config"`
+}
+
+// ConfigImport applies a config document in a single transaction
+type ConfigImport struct {
+	Config map[string]string `json:"config" yaml:"config"`
+	// Prune deletes the keys absent from Config
+	Prune bool `json:"prune,omitempty" yaml:"prune,omitempty"`
+}
+
+// ConfigImportResult reports the keys changed by a config import, sorted
+type ConfigImportResult struct {
+	Created []string `json:"created" yaml:"created"`
+	Updated []string `json:"updated" yaml:"updated"`
+	Deleted []string `json:"deleted" yaml:"deleted"`
+	// Skipped are the keys holding credentials whose value was redacted,
+	// they are left as is
+	Skipped []string `json:"skipped" yaml:"skipped"`
+}
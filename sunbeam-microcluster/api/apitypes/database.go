@@ -0,0 +1,37 @@
+package apitypes
+
+// DatabaseReport is the result of the integrity check of the cluster
+// database
+type DatabaseReport struct {
+	// OK is set when the integrity check found no problems
+	OK bool `json:"ok" yaml:"ok"`
+	// Problems are the corruptions found by the integrity check
+	Problems []string `json:"problems" yaml:"problems"`
+	// Tables are the tables of the database, ordered by name
+	Tables []DatabaseTable `json:"tables" yaml:"tables"`
+	// PageSize is the size in bytes of the pages of the database
+	PageSize int64 `json:"page_size" yaml:"page_size"`
+	// Pages is the number of pages of the database
+	Pages int64 `json:"pages" yaml:"pages"`
+	// FreePages is the number of unused pages, reused by later writes
+	FreePages int64 `json:"free_pages" yaml:"free_pages"`
+}
+
+// DatabaseTable is a table of the cluster database
+type DatabaseTable struct {
+	Name string `json:"name" yaml:"name"`
+	Rows int64  `json:"rows" yaml:"rows"`
+}
+
+// DatabaseCompaction is the result of the compaction of the cluster
+// database
+type DatabaseCompaction struct {
+	// PurgedRevisions is the number of config revisions deleted past the
+	// history limit
+	PurgedRevisions int64 `json:"purged_revisions" yaml:"purged_revisions"`
+	// PurgedTombstones is the number of removed nodes deleted past their
+	// retention
+	PurgedTombstones int64 `json:"purged_tombstones" yaml:"purged_tombstones"`
+	// FreePages is the number of unused pages once compacted
+	FreePages int64 `json:"free_pages" yaml:"free_pages"`
+}
@@ -1,12 +1,39 @@
 package apitypes
 
 import (
+	"strings"
+
 	"github.com/canonical/microcluster/v2/rest/types"
 )
 
 const (
 	// ExtendedPathPrefix is the prefix for all extended API paths.
 	ExtendedPathPrefix types.EndpointPrefix = "1.0"
+	// ExtendedPathPrefixV11 is the prefix for the 1.1 revision of the extended API paths.
+	ExtendedPathPrefixV11 types.EndpointPrefix = "1.1"
 	// LocalPathPrefix is the prefix for all local API paths.
 	LocalPathPrefix types.EndpointPrefix = "local"
+	// MetricsPathPrefix is the prefix for the Prometheus metrics endpoint.
+	MetricsPathPrefix types.EndpointPrefix = "metrics"
 )
+
+// ExtendedPathPrefixes is the list of extended API prefixes served by the
+// daemon, oldest first.
+var ExtendedPathPrefixes = []types.EndpointPrefix{
+	ExtendedPathPrefix,
+	ExtendedPathPrefixV11,
+}
+
+// NegotiatePathPrefix returns the extended API prefix used by a request path.
+// Paths not starting with a known extended prefix fall back to
+// ExtendedPathPrefix so that clients only aware of 1.0 keep working.
+func NegotiatePathPrefix(path string) types.EndpointPrefix {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	for _, prefix := range ExtendedPathPrefixes {
+		if first == string(prefix) {
+			return prefix
+		}
+	}
+
+	return ExtendedPathPrefix
+}
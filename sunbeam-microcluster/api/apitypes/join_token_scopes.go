@@ -0,0 +1,39 @@
+package apitypes
+
+// Join token scope states.
+const (
+	// JoinTokenValid is the state of a scope which still permits joins
+	JoinTokenValid = "valid"
+	// JoinTokenExpired is the state of a scope past its expiry
+	JoinTokenExpired = "expired"
+	// JoinTokenConsumed is the state of a scope whose uses are exhausted
+	JoinTokenConsumed = "consumed"
+)
+
+// JoinTokenScopes holds list of JoinTokenScope type
+type JoinTokenScopes []JoinTokenScope
+
+// JoinTokenScope restricts the join token issued for a node name: the node
+// can only join with the roles of the scope, once and before it expires
+type JoinTokenScope struct {
+	// Name is the name of the node the join token is issued for
+	Name string `json:"name" yaml:"name"`
+	// Roles are the roles the node may be recorded with
+	Roles []string `json:"roles" yaml:"roles"`
+	// Uses is the number of joins the token permits, 1 as join tokens are
+	// single-use
+	Uses int `json:"uses" yaml:"uses"`
+	// Used is the number of joins with the token
+	Used int `json:"used" yaml:"used"`
+	// ExpiresAt is the RFC3339 time the token expires, empty if it does not
+	ExpiresAt string `json:"expires_at" yaml:"expires_at"`
+	// State is one of valid, expired or consumed
+	State string `json:"state" yaml:"state"`
+	// CreatedAt is the RFC3339 time the scope was created
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	// CreatedBy is who created the scope
+	CreatedBy string `json:"created_by" yaml:"created_by"`
+	// TTL is a duration, e.g. 1h, only set on requests. When set, the token
+	// expires once it elapses.
+	TTL string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
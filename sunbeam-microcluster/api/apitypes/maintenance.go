@@ -0,0 +1,222 @@
+package apitypes
+
+const (
+	// MaintenanceEnabled is the status of a node in maintenance mode
+	MaintenanceEnabled = "enabled"
+	// MaintenanceDisabled is the status of a node out of maintenance mode
+	MaintenanceDisabled = "disabled"
+	// MaintenanceDegraded is the status of a node whose maintenance operation
+	// partially failed
+	MaintenanceDegraded = "degraded"
+)
+
+// MaintenanceStatuses is the list of known maintenance statuses
+var MaintenanceStatuses = []string{MaintenanceEnabled, MaintenanceDisabled, MaintenanceDegraded}
+
+// MaintenanceStatusList holds list of MaintenanceStatus type
+type MaintenanceStatusList []MaintenanceStatus
+
+// MaintenanceStatus structure to hold the maintenance state of a node
+type MaintenanceStatus struct {
+	// Node is the name of the node
+	Node string `json:"node" yaml:"node"`
+	// Status is one of enabled, disabled or degraded
+	Status string `json:"status" yaml:"status"`
+	// EnteredAt is the RFC3339 time the node entered maintenance
+	EnteredAt string `json:"entered_at" yaml:"entered_at"`
+	// Strategy describes the options used to enter maintenance
+	Strategy string `json:"strategy" yaml:"strategy"`
+	// TriggeredBy is who last changed the maintenance state
+	TriggeredBy string `json:"triggered_by" yaml:"triggered_by"`
+	// Message holds details on a degraded status
+	Message string `json:"message" yaml:"message"`
+	// ExpiresAt is the RFC3339 time maintenance is automatically disabled,
+	// empty if maintenance has no TTL
+	ExpiresAt string `json:"expires_at" yaml:"expires_at"`
+	// TTL is a duration, e.g. 2h, only set on requests. When set, maintenance
+	// is automatically disabled once it elapses, counting from the request.
+	TTL string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+// Maintenance progress states.
+const (
+	// ProgressMigrating is the state of an instance migration in progress
+	ProgressMigrating = "migrating"
+	// ProgressSucceeded is the state of a completed instance migration
+	ProgressSucceeded = "succeeded"
+	// ProgressFailed is the state of a failed instance migration
+	ProgressFailed = "failed"
+	// ProgressStalled is the state of an instance migration which made no
+	// progress for a while
+	ProgressStalled = "stalled"
+)
+
+// MaintenanceProgressList holds list of MaintenanceProgress type
+type MaintenanceProgressList []MaintenanceProgress
+
+// MaintenanceProgress is a progress event of the instance migrations run by
+// a maintenance operation on a node
+type MaintenanceProgress struct {
+	// Seq orders the events, it is assigned by clusterd
+	Seq int64 `json:"seq" yaml:"seq"`
+	// Node is the name of the node
+	Node string `json:"node" yaml:"node"`
+	// Time is the RFC3339 time of the event
+	Time string `json:"time" yaml:"time"`
+	// Instance is the ID of the instance migrating
+	Instance string `json:"instance" yaml:"instance"`
+	// State is one of migrating, succeeded, failed or stalled
+	State string `json:"state" yaml:"state"`
+	// Completed is the number of migrations completed so far
+	Completed int `json:"completed" yaml:"completed"`
+	// Total is the number of migrations of the operation
+	Total int `json:"total" yaml:"total"`
+	// Percent is the progress of the migration, if reported by Nova
+	Percent *int `json:"percent,omitempty" yaml:"percent,omitempty"`
+	// Message holds details, such as why a migration failed or stalled
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Scheduled maintenance statuses.
+const (
+	// ScheduledPending is the status of a scheduled maintenance waiting for
+	// its window
+	ScheduledPending = "pending"
+	// ScheduledRunning is the status of a scheduled maintenance being
+	// executed
+	ScheduledRunning = "running"
+	// ScheduledSucceeded is the status of a scheduled maintenance executed
+	// successfully
+	ScheduledSucceeded = "succeeded"
+	// ScheduledFailed is the status of a scheduled maintenance which failed,
+	// or could not run within its window
+	ScheduledFailed = "failed"
+	// ScheduledCancelled is the status of a scheduled maintenance cancelled
+	// before it ran
+	ScheduledCancelled = "cancelled"
+)
+
+// MaintenanceOptions are the options of a deferred maintenance enable,
+// matching the options of the maintenance enable command
+type MaintenanceOptions struct {
+	Force                      bool   `json:"force,omitempty" yaml:"force,omitempty"`
+	SkipUnmigratable           bool   `json:"skip_unmigratable,omitempty" yaml:"skip_unmigratable,omitempty"`
+	StopOSDs                   bool   `json:"stop_osds,omitempty" yaml:"stop_osds,omitempty"`
+	AllowDowntime              bool   `json:"allow_downtime,omitempty" yaml:"allow_downtime,omitempty"`
+	EnableCephCrushRebalancing bool   `json:"enable_ceph_crush_rebalancing,omitempty" yaml:"enable_ceph_crush_rebalancing,omitempty"`
+	DisableMigration           string `json:"disable_migration,omitempty" yaml:"disable_migration,omitempty"`
+	TTL                        string `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	MaxParallelMigrations      *int   `json:"max_parallel_migrations,omitempty" yaml:"max_parallel_migrations,omitempty"`
+}
+
+// ScheduledMaintenanceList holds list of ScheduledMaintenance type
+type ScheduledMaintenanceList []ScheduledMaintenance
+
+// ScheduledMaintenance is a maintenance enable of a node deferred to a
+// change window
+type ScheduledMaintenance struct {
+	// ID is assigned by clusterd
+	ID int `json:"id" yaml:"id"`
+	// Node is the name of the node
+	Node string `json:"node" yaml:"node"`
+	// WindowStart is the RFC3339 time the window opens
+	WindowStart string `json:"window_start" yaml:"window_start"`
+	// WindowEnd is the RFC3339 time the window closes, empty if it does not
+	WindowEnd string `json:"window_end" yaml:"window_end"`
+	// Options are the options maintenance is enabled with
+	Options MaintenanceOptions `json:"options" yaml:"options"`
+	// Status is one of pending, running, succeeded, failed or cancelled
+	Status string `json:"status" yaml:"status"`
+	// CreatedAt is the RFC3339 time the maintenance was scheduled
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	// CreatedBy is who scheduled the maintenance
+	CreatedBy string `json:"created_by" yaml:"created_by"`
+	// StartedAt is the RFC3339 time the execution started
+	StartedAt string `json:"started_at" yaml:"started_at"`
+	// FinishedAt is the RFC3339 time the execution finished, or the
+	// maintenance was cancelled
+	FinishedAt string `json:"finished_at" yaml:"finished_at"`
+	// Message holds details, such as why the execution failed
+	Message string `json:"message" yaml:"message"`
+	// Operation is the ID of the operation enabling maintenance once the
+	// window opened, 0 before
+	Operation int `json:"operation" yaml:"operation"`
+}
+
+// Maintenance plan operations.
+const (
+	// PlanOperationEnable is the operation of the audit planning the
+	// evacuation of a node entering maintenance
+	PlanOperationEnable = "enable"
+	// PlanOperationDisable is the operation of the audit planning the
+	// workload balancing of a node exiting maintenance
+	PlanOperationDisable = "disable"
+)
+
+// MaintenancePlanOperations is the list of known maintenance plan operations
+var MaintenancePlanOperations = []string{PlanOperationEnable, PlanOperationDisable}
+
+// Maintenance plan states.
+const (
+	// PlanSucceeded is the state of an audit which produced an action plan
+	PlanSucceeded = "succeeded"
+	// PlanTimedOut is the state of an audit which did not complete in time
+	PlanTimedOut = "timed-out"
+	// PlanFailed is the state of an audit which failed, or produced no
+	// feasible action plan
+	PlanFailed = "failed"
+)
+
+// MaintenancePlanStates is the list of known maintenance plan states
+var MaintenancePlanStates = []string{PlanSucceeded, PlanTimedOut, PlanFailed}
+
+// MaintenancePlanList holds list of MaintenancePlan type
+type MaintenancePlanList []MaintenancePlan
+
+// MaintenancePlan is the result of the Watcher audit planning a maintenance
+// operation on a node
+type MaintenancePlan struct {
+	// Node is the name of the node
+	Node string `json:"node" yaml:"node"`
+	// Operation is one of enable or disable
+	Operation string `json:"operation" yaml:"operation"`
+	// Audit is the UUID of the Watcher audit, empty if it was not created
+	Audit string `json:"audit" yaml:"audit"`
+	// ActionPlan is the UUID of the Watcher action plan, empty if the audit
+	// produced none
+	ActionPlan string `json:"action_plan" yaml:"action_plan"`
+	// State is one of succeeded, timed-out or failed
+	State string `json:"state" yaml:"state"`
+	// Reason holds why the audit timed out or failed
+	Reason string `json:"reason" yaml:"reason"`
+	// Actions are the actions of the action plan
+	Actions []MaintenancePlanAction `json:"actions" yaml:"actions"`
+	// Efficacy holds the global efficacy indicators of the action plan
+	Efficacy []MaintenancePlanIndicator `json:"efficacy" yaml:"efficacy"`
+	// CreatedAt is the RFC3339 time the plan was recorded
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	// CreatedBy is who recorded the plan
+	CreatedBy string `json:"created_by" yaml:"created_by"`
+}
+
+// MaintenancePlanAction is an action of a Watcher action plan
+type MaintenancePlanAction struct {
+	// UUID is the UUID of the Watcher action
+	UUID string `json:"uuid" yaml:"uuid"`
+	// Type is the Watcher action type, e.g. migrate
+	Type string `json:"type" yaml:"type"`
+	// State is the Watcher state of the action, e.g. PENDING
+	State string `json:"state" yaml:"state"`
+	// Parameters are the input parameters of the action
+	Parameters map[string]any `json:"parameters" yaml:"parameters"`
+	// Description is the rationale of the action given by Watcher
+	Description string `json:"description" yaml:"description"`
+}
+
+// MaintenancePlanIndicator is an efficacy indicator of a Watcher action plan
+type MaintenancePlanIndicator struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Value       *float64 `json:"value" yaml:"value"`
+	Unit        string   `json:"unit" yaml:"unit"`
+}
@@ -0,0 +1,16 @@
+package apitypes
+
+// Meta describes the daemon serving the API, for clients to check they are
+// compatible with it
+type Meta struct {
+	// Version is the version of the daemon
+	Version string `json:"version" yaml:"version"`
+	// SchemaInternal is the microcluster internal schema version of the cluster
+	SchemaInternal int `json:"schema_internal" yaml:"schema_internal"`
+	// SchemaExternal is the sunbeam schema version of the cluster
+	SchemaExternal int `json:"schema_external" yaml:"schema_external"`
+	// APIPrefixes are the extended API prefixes served, oldest first
+	APIPrefixes []string `json:"api_prefixes" yaml:"api_prefixes"`
+	// MicroclusterVersion is the version of the microcluster library
+	MicroclusterVersion string `json:"microcluster_version" yaml:"microcluster_version"`
+}
@@ -0,0 +1,69 @@
+package apitypes
+
+// NodeInventoryHistoryLimit is the number of inventory revisions kept per node
+const NodeInventoryHistoryLimit = 10
+
+// NodeInventories holds list of NodeInventory type, latest revision first
+type NodeInventories []NodeInventory
+
+// NodeInventory structure to hold a revision of the hardware inventory of a
+// node. A new revision is recorded each time the node reports different
+// hardware.
+type NodeInventory struct {
+	// Node is the name of the node
+	Node string `json:"node" yaml:"node"`
+	// Revision starts at 1 and is increased each time the hardware changes
+	Revision int `json:"revision" yaml:"revision"`
+	// CollectedAt is the RFC3339 time this hardware was first reported
+	CollectedAt string `json:"collected_at" yaml:"collected_at"`
+	// RefreshedAt is the RFC3339 time this hardware was last reported
+	RefreshedAt string `json:"refreshed_at" yaml:"refreshed_at"`
+	// Hardware is the reported hardware
+	Hardware Hardware `json:"hardware" yaml:"hardware"`
+}
+
+// Hardware structure to hold the hardware reported by a node.
+//
+// The documented schema is:
+//
+//	{
+//	  "cpu_count":     integer > 0,
+//	  "memory_total":  integer > 0, bytes,
+//	  "nics":          [{"name": string, "mac_address": string, "speed": integer >= 0, Mbit/s}],
+//	  "block_devices": [{"name": string, "model": string, "size": integer > 0, bytes, "rotational": boolean}]
+//	}
+//
+// Names are required and unique within their list, other fields are
+// rejected.
+type Hardware struct {
+	// CPUCount is the number of logical CPUs
+	CPUCount int `json:"cpu_count" yaml:"cpu_count"`
+	// MemoryTotal is the total memory in bytes
+	MemoryTotal uint64 `json:"memory_total" yaml:"memory_total"`
+	// NICs are the network interfaces
+	NICs []HardwareNIC `json:"nics" yaml:"nics"`
+	// BlockDevices are the disks
+	BlockDevices []HardwareBlockDevice `json:"block_devices" yaml:"block_devices"`
+}
+
+// HardwareNIC structure to hold a network interface of a node
+type HardwareNIC struct {
+	// Name is the name of the interface, e.g. eno1
+	Name string `json:"name" yaml:"name"`
+	// MACAddress is the hardware address of the interface
+	MACAddress string `json:"mac_address" yaml:"mac_address"`
+	// Speed is the link speed in Mbit/s, 0 if unknown
+	Speed int `json:"speed" yaml:"speed"`
+}
+
+// HardwareBlockDevice structure to hold a disk of a node
+type HardwareBlockDevice struct {
+	// Name is the name of the device, e.g. sda
+	Name string `json:"name" yaml:"name"`
+	// Model is the model of the device, if known
+	Model string `json:"model" yaml:"model"`
+	// Size is the size of the device in bytes
+	Size uint64 `json:"size" yaml:"size"`
+	// Rotational is whether the device is a spinning disk
+	Rotational bool `json:"rotational" yaml:"rotational"`
+}
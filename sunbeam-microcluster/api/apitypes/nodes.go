@@ -1,6 +1,26 @@
 // Package apitypes provides shared types and structs.
 package apitypes
 
+import (
+	"encoding/json"
+
+	"github.com/canonical/microcluster/v2/rest/types"
+)
+
+// NodeRoles is the list of built-in roles a node can be assigned.
+var NodeRoles = []string{"control", "compute", "storage", "network", "region_controller"}
+
+// NodeRole is a role a node can be assigned
+type NodeRole struct {
+	Name string `json:"name" yaml:"name"`
+	// Custom is set for the roles defined in the cluster config
+	Custom bool `json:"custom" yaml:"custom"`
+}
+
+// NodesTotalCountHeader is the response header holding the number of nodes
+// matching a listing request before pagination is applied.
+const NodesTotalCountHeader = "X-Total-Count"
+
 // Nodes holds list of Node type
 type Nodes []Node
 
@@ -12,4 +32,128 @@ type Node struct {
 	MachineID int `json:"machineid" yaml:"machineid"`
 	// SystemID is the unique identifier for the node in machine provider
 	SystemID string `json:"systemid" yaml:"systemid"`
+	// Member is the cluster member that recorded the node (1.1 only)
+	Member string `json:"member,omitempty" yaml:"member,omitempty"`
+	// Labels are arbitrary key/value metadata attached to the node
+	Labels NodeLabels `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Cordoned nodes are not selected for new workloads, their running
+	// workloads are left in place
+	Cordoned bool `json:"cordoned" yaml:"cordoned"`
+	// Addresses are the IP addresses of the node by network name, such as
+	// management or data
+	Addresses NodeAddresses `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	// PreferredAPI names the address serving the cluster API and raft traffic
+	PreferredAPI string `json:"preferred_api,omitempty" yaml:"preferred_api,omitempty"`
+	// RemovedAt is the RFC3339 time the node was removed, empty for active nodes
+	RemovedAt string `json:"removed_at,omitempty" yaml:"removed_at,omitempty"`
+	// RemovedReason is the reason given when removing the node
+	RemovedReason string `json:"removed_reason,omitempty" yaml:"removed_reason,omitempty"`
+	// RemovedBy is the client that removed the node
+	RemovedBy string `json:"removed_by,omitempty" yaml:"removed_by,omitempty"`
+}
+
+// NodeLabels holds the key/value labels of a node
+type NodeLabels map[string]string
+
+// NodeAddresses holds the IP addresses of a node by network name
+type NodeAddresses map[string]string
+
+// APIAddress returns the preferred API address of the node, empty if it has
+// none.
+func (n Node) APIAddress() string {
+	return n.Addresses[n.PreferredAPI]
+}
+
+// NodeCordon holds the cordon state requested for a node
+type NodeCordon struct {
+	Cordoned bool `json:"cordoned" yaml:"cordoned"`
+}
+
+// ForPrefix returns a copy of the node holding only the fields known to the
+// given extended API prefix.
+func (n Node) ForPrefix(prefix types.EndpointPrefix) Node {
+	if prefix == ExtendedPathPrefix {
+		n.Member = ""
+	}
+
+	return n
+}
+
+// ForPrefix returns a copy of the nodes holding only the fields known to the
+// given extended API prefix.
+func (n Nodes) ForPrefix(prefix types.EndpointPrefix) Nodes {
+	nodes := make(Nodes, len(n))
+	for i, node := range n {
+		nodes[i] = node.ForPrefix(prefix)
+	}
+
+	return nodes
+}
+
+// Node batch actions.
+const (
+	NodeBatchAdd    = "add"
+	NodeBatchRemove = "remove"
+)
+
+// Node batch item outcomes.
+const (
+	// NodeBatchApplied is the outcome of items of a committed batch
+	NodeBatchApplied = "applied"
+	// NodeBatchFailed is the outcome of the item that aborted the batch
+	NodeBatchFailed = "failed"
+	// NodeBatchRolledBack is the outcome of items that succeeded before the batch aborted
+	NodeBatchRolledBack = "rolled_back"
+	// NodeBatchSkipped is the outcome of items not attempted after the batch aborted
+	NodeBatchSkipped = "skipped"
+)
+
+// NodeBatchOperation is a node addition or removal within a batch
+type NodeBatchOperation struct {
+	Action string   `json:"action" yaml:"action"`
+	Name   string   `json:"name" yaml:"name"`
+	Role   []string `json:"role,omitempty" yaml:"role,omitempty"`
+	// MachineID and SystemID are only used when adding a node
+	MachineID int    `json:"machineid" yaml:"machineid"`
+	SystemID  string `json:"systemid,omitempty" yaml:"systemid,omitempty"`
+	// Addresses and PreferredAPI are only used when adding a node
+	Addresses    NodeAddresses `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	PreferredAPI string        `json:"preferred_api,omitempty" yaml:"preferred_api,omitempty"`
+	// Reason is only used when removing a node
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// UnmarshalJSON decodes a NodeBatchOperation, defaulting MachineID to -1
+// like a single node addition does.
+func (o *NodeBatchOperation) UnmarshalJSON(data []byte) error {
+	type operation NodeBatchOperation
+	op := operation{MachineID: -1}
+
+	err := json.Unmarshal(data, &op)
+	if err != nil {
+		return err
+	}
+
+	*o = NodeBatchOperation(op)
+	return nil
+}
+
+// NodeBatchRequest holds the operations applied by a node batch
+type NodeBatchRequest struct {
+	Operations []NodeBatchOperation `json:"operations" yaml:"operations"`
+}
+
+// NodeBatchResult is the outcome of a single operation of a node batch
+type NodeBatchResult struct {
+	Action string `json:"action" yaml:"action"`
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NodeBatchResponse reports whether a node batch was committed along with
+// the outcome of each of its operations, in request order.
+type NodeBatchResponse struct {
+	Applied bool              `json:"applied" yaml:"applied"`
+	Results []NodeBatchResult `json:"results" yaml:"results"`
 }
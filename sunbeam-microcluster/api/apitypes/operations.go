@@ -0,0 +1,78 @@
+package apitypes
+
+// Operation statuses.
+const (
+	// OperationPending is the status of an operation waiting to be started
+	OperationPending = "pending"
+	// OperationRunning is the status of an operation being run
+	OperationRunning = "running"
+	// OperationCancelling is the status of a running operation asked to
+	// stop, until its command exits
+	OperationCancelling = "cancelling"
+	// OperationSucceeded is the status of an operation whose command
+	// completed successfully
+	OperationSucceeded = "succeeded"
+	// OperationFailed is the status of an operation whose command failed,
+	// or whose runner stopped reporting
+	OperationFailed = "failed"
+	// OperationCancelled is the status of an operation cancelled before it
+	// started, or stopped once cancelling
+	OperationCancelled = "cancelled"
+)
+
+// Operations holds list of Operation type
+type Operations []Operation
+
+// Operation is a sunbeam command recorded by clusterd and run in the
+// background by a runner, the sunbeam CLI of an operator, so that it outlives
+// the client which started it. clusterd never runs the command itself, it
+// lacks the credentials of the operator.
+type Operation struct {
+	// ID is assigned by clusterd
+	ID int `json:"id" yaml:"id"`
+	// Command holds the arguments of the sunbeam command
+	Command []string `json:"command" yaml:"command"`
+	// Member is the member the operation was created on
+	Member string `json:"member" yaml:"member"`
+	// Runner names the runner which claimed the operation, empty while
+	// pending
+	Runner string `json:"runner" yaml:"runner"`
+	// Status is one of pending, running, cancelling, succeeded, failed or
+	// cancelled
+	Status string `json:"status" yaml:"status"`
+	// Progress is the latest line of output of the command
+	Progress string `json:"progress" yaml:"progress"`
+	// Result holds the end of the output of the command once it exited
+	Result string `json:"result" yaml:"result"`
+	// Error holds why the operation failed or was cancelled
+	Error string `json:"error" yaml:"error"`
+	// CreatedAt is the RFC3339 time the operation was requested
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	// CreatedBy is who requested the operation
+	CreatedBy string `json:"created_by" yaml:"created_by"`
+	// StartedAt is the RFC3339 time the command started
+	StartedAt string `json:"started_at" yaml:"started_at"`
+	// FinishedAt is the RFC3339 time the command exited, or the operation
+	// was cancelled
+	FinishedAt string `json:"finished_at" yaml:"finished_at"`
+	// HeartbeatAt is the RFC3339 time the runner last reported
+	HeartbeatAt string `json:"heartbeat_at" yaml:"heartbeat_at"`
+}
+
+// OperationUpdate is reported by the runner of an operation. A pending
+// operation is claimed with Status running, a running one is completed with
+// Status succeeded or failed, and an empty Status only records the progress
+// and heartbeat.
+type OperationUpdate struct {
+	// Status is running, succeeded, failed or empty
+	Status string `json:"status" yaml:"status"`
+	// Runner names the runner, it must be the one which claimed the
+	// operation
+	Runner string `json:"runner" yaml:"runner"`
+	// Progress is the latest line of output of the command, if any
+	Progress string `json:"progress" yaml:"progress"`
+	// Result holds the end of the output of the command once it exited
+	Result string `json:"result" yaml:"result"`
+	// Error holds why the command failed
+	Error string `json:"error" yaml:"error"`
+}
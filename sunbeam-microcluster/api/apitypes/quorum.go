@@ -0,0 +1,17 @@
+package apitypes
+
+// QuorumLostCode is the code of the error returned while a majority of the
+// database voters is unreachable
+const QuorumLostCode = "cluster-quorum-lost"
+
+// QuorumLost is the metadata of the error returned while the database has
+// lost its quorum
+type QuorumLost struct {
+	// Code is QuorumLostCode
+	Code string `json:"code" yaml:"code"`
+	// Expected are the names of the database voters
+	Expected []string `json:"expected" yaml:"expected"`
+	// Reachable are the names of the voters reachable from the member
+	// answering the request
+	Reachable []string `json:"reachable" yaml:"reachable"`
+}
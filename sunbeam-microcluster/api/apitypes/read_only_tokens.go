@@ -0,0 +1,26 @@
+package apitypes
+
+// ReadOnlyTokenHeader is the header read-only tokens are presented in, as
+// "Bearer <token>"
+const ReadOnlyTokenHeader = "Authorization"
+
+// ReadOnlyTokens holds list of ReadOnlyToken type
+type ReadOnlyTokens []ReadOnlyToken
+
+// ReadOnlyToken is a token authorizing its bearer to read the cluster state,
+// without a client certificate, for observers such as monitoring tools
+type ReadOnlyToken struct {
+	// Name identifies the token
+	Name string `json:"name" yaml:"name"`
+	// CreatedAt is the RFC3339 time the token was created
+	CreatedAt string `json:"created_at" yaml:"created_at"`
+	// Hash is the hex SHA-256 of the token, it is not listed
+	Hash string `json:"hash,omitempty" yaml:"hash,omitempty"`
+}
+
+// ReadOnlyTokenCreated is returned once on token creation, the token itself
+// is not stored
+type ReadOnlyTokenCreated struct {
+	// Token is the secret presented by the bearer
+	Token string `json:"token" yaml:"token"`
+}
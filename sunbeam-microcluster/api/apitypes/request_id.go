@@ -0,0 +1,6 @@
+package apitypes
+
+// RequestIDHeader is the header correlating a request with the log lines of
+// its handling, as sent by the client or generated by clusterd, and returned
+// in the response.
+const RequestIDHeader = "X-Request-ID"
@@ -0,0 +1,66 @@
+package apitypes
+
+// Cluster lifecycle event types.
+const (
+	// EventNodeAdded is emitted once a node is recorded in the cluster
+	EventNodeAdded = "node.added"
+	// EventNodeRemoved is emitted once a node is removed from the cluster
+	EventNodeRemoved = "node.removed"
+	// EventMaintenanceEntered is emitted once a node enters maintenance
+	EventMaintenanceEntered = "maintenance.entered"
+	// EventMaintenanceExited is emitted once a node leaves maintenance
+	EventMaintenanceExited = "maintenance.exited"
+	// EventBootstrapCompleted is emitted once the deployment is bootstrapped
+	EventBootstrapCompleted = "bootstrap.completed"
+)
+
+// EventTypes is the list of known event types
+var EventTypes = []string{
+	EventNodeAdded,
+	EventNodeRemoved,
+	EventMaintenanceEntered,
+	EventMaintenanceExited,
+	EventBootstrapCompleted,
+}
+
+// Headers of the webhook deliveries.
+const (
+	// WebhookEventHeader holds the type of the event delivered
+	WebhookEventHeader = "X-Sunbeam-Event"
+	// WebhookDeliveryHeader holds the ID of the event delivered, the same
+	// across retries
+	WebhookDeliveryHeader = "X-Sunbeam-Delivery"
+	// WebhookSignatureHeader holds sha256=<hex HMAC-SHA256 of the body>,
+	// keyed with the secret of the webhook
+	WebhookSignatureHeader = "X-Sunbeam-Signature"
+)
+
+// Event is a cluster lifecycle event, delivered as the json body of the
+// webhook requests
+type Event struct {
+	// ID identifies the event
+	ID string `json:"id" yaml:"id"`
+	// Type is one of the EventTypes
+	Type string `json:"type" yaml:"type"`
+	// Time is the RFC3339 time the event occurred
+	Time string `json:"time" yaml:"time"`
+	// Member is the cluster member the event occurred on
+	Member string `json:"member" yaml:"member"`
+	// Data holds the event details, such as the node concerned
+	Data map[string]string `json:"data" yaml:"data"`
+}
+
+// Webhooks holds list of Webhook type
+type Webhooks []Webhook
+
+// Webhook is a target the cluster events are posted to
+type Webhook struct {
+	// Name identifies the webhook
+	Name string `json:"name" yaml:"name"`
+	// URL is the http or https URL the events are posted to
+	URL string `json:"url" yaml:"url"`
+	// Secret keys the signature of the deliveries, it is not listed
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// Events are the event types delivered, all of them if empty
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/backup endpoint.
+var backupCmd = rest.Endpoint{
+	Path: "backup",
+
+	Get: access.ClusterCATrustedEndpoint(cmdBackupGet, true),
+}
+
+// /1.0/restore endpoint.
+var restoreCmd = rest.Endpoint{
+	Path: "restore",
+
+	Post: access.ClusterCATrustedEndpoint(cmdRestorePost, true),
+}
+
+// cmdBackupGet streams the backup bundle as is, so that it can be posted
+// back to the restore endpoint.
+func cmdBackupGet(s state.State, r *http.Request) response.Response {
+	redactSecrets := false
+	if value := r.URL.Query().Get("redact_secrets"); value != "" {
+		var err error
+		redactSecrets, err = strconv.ParseBool(value)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid redact_secrets %q: %w", value, err))
+		}
+	}
+
+	backup, err := sunbeam.ExportBackup(r.Context(), s, redactSecrets)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "application/json")
+		return json.NewEncoder(w).Encode(backup)
+	})
+}
+
+func cmdRestorePost(s state.State, r *http.Request) response.Response {
+	backup := apitypes.Backup{}
+
+	err := json.NewDecoder(r.Body).Decode(&backup)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = sunbeam.RestoreBackup(r.Context(), s, backup)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
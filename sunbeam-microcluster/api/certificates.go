@@ -1,14 +1,21 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/canonical/lxd/lxd/response"
-	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/api"
+	microCli "github.com/canonical/microcluster/v2/client"
 	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/rest/types"
 	"github.com/canonical/microcluster/v2/state"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
 )
 
 // url path: /local/certpair/server
@@ -22,11 +29,11 @@ var certPair = rest.Endpoint{
 }
 
 // Return the member server certpair, should only be allowed over the Unix socket.
-func cmdGetMemberServerCertPair(s state.State, _ *http.Request) response.Response {
+func cmdGetMemberServerCertPair(s state.State, r *http.Request) response.Response {
 	certs := s.ServerCert()
 
 	if certs == nil {
-		logger.Error("Failed to get server certpair")
+		logging.FromRequest(r).Error("Failed to get server certpair")
 		return response.InternalError(nil)
 	}
 
@@ -35,3 +42,85 @@ func cmdGetMemberServerCertPair(s state.State, _ *http.Request) response.Respons
 		PrivateKey:  string(certs.PrivateKey()),
 	})
 }
+
+// /1.0/certificates/cluster endpoint.
+var clusterCertificateCmd = rest.Endpoint{
+	Path: "certificates/cluster",
+
+	Put: access.ClusterCATrustedEndpoint(cmdClusterCertificatePut, true),
+}
+
+// /1.0/certificates/cluster:rotate endpoint.
+var clusterCertificateRotateCmd = rest.Endpoint{
+	Path: "certificates/cluster:rotate",
+
+	Post: access.ClusterCATrustedEndpoint(cmdClusterCertificateRotatePost, false),
+}
+
+// LocalClient returns a client to the local microcluster daemon, it is set
+// by the daemon before it starts.
+var LocalClient func() (*microCli.Client, error)
+
+// Replace the cluster certificate served by the member, once validated.
+// Only the member handling the request is updated, a rotation applies the
+// certificate to each member in turn.
+func cmdClusterCertificatePut(s state.State, r *http.Request) response.Response {
+	var req apitypes.ClusterCertificatePut
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = sunbeam.UpdateClusterCertificate(r.Context(), s, req, reloadClusterCertificate)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// cmdClusterCertificateRotatePost rotates the cluster certificate of all the
+// members. A rotation halted by a failed member is still answered with a sync
+// response, reporting the certificate served by each member.
+func cmdClusterCertificateRotatePost(s state.State, r *http.Request) response.Response {
+	var req apitypes.ClusterCertificatePut
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	resp, err := sunbeam.RotateClusterCertificate(r.Context(), s, req, reloadClusterCertificate)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, resp)
+}
+
+// reloadClusterCertificate writes the cluster keypair of this member and
+// reloads its listeners, through the local microcluster daemon.
+func reloadClusterCertificate(ctx context.Context, keypair types.KeyPair) error {
+	c, err := LocalClient()
+	if err != nil {
+		return fmt.Errorf("Failed to get local client: %w", err)
+	}
+
+	// Sent as a cluster notification, microcluster does not forward it to
+	// the other members
+	c.SetClusterNotification()
+
+	err = c.UpdateCertificate(ctx, types.ClusterCertificateName, keypair)
+	if err != nil {
+		return fmt.Errorf("Failed to reload cluster certificate: %w", err)
+	}
+
+	return nil
+}
@@ -2,8 +2,14 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/api"
@@ -12,25 +18,95 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
 )
 
+// /1.0/config/schema endpoint.
+// Registered before configCmd so that it takes precedence over the key path.
+var configSchemaCmd = rest.Endpoint{
+	Path: "config/schema",
+
+	Get: access.ClusterCATrustedEndpoint(cmdConfigSchemaGet, true),
+}
+
+// /1.0/config:transaction endpoint.
+var configTransactionCmd = rest.Endpoint{
+	Path: "config:transaction",
+
+	Post: access.ClusterCATrustedEndpoint(cmdConfigTransactionPost, true),
+}
+
+// /1.0/config:export endpoint.
+var configExportCmd = rest.Endpoint{
+	Path: "config:export",
+
+	Get: access.ClusterCATrustedEndpoint(cmdConfigExportGet, true),
+}
+
+// /1.0/config:import endpoint.
+var configImportCmd = rest.Endpoint{
+	Path: "config:import",
+
+	Post: access.ClusterCATrustedEndpoint(cmdConfigImportPost, true),
+}
+
 // /1.0/config/<name> endpoint.
+// Readable with a read-only token but for the keys holding credentials.
 var configCmd = rest.Endpoint{
 	Path: "config/{key}",
 
-	Get:    access.ClusterCATrustedEndpoint(cmdConfigGet, true),
+	Get:    access.ReadOnlyTokenEndpoint(cmdConfigGet, true),
 	Put:    access.ClusterCATrustedEndpoint(cmdConfigPut, true),
 	Delete: access.ClusterCATrustedEndpoint(cmdConfigDelete, true),
 }
 
+// /1.0/config/<name>/history endpoint.
+var configHistoryCmd = rest.Endpoint{
+	Path: "config/{key}/history",
+
+	Get: access.ClusterCATrustedEndpoint(cmdConfigHistoryGet, true),
+}
+
+// /1.0/config/<name>/rollback endpoint.
+var configRollbackCmd = rest.Endpoint{
+	Path: "config/{key}/rollback",
+
+	Post: access.ClusterCATrustedEndpoint(cmdConfigRollbackPost, true),
+}
+
+// cmdConfigGet returns the value of a config key. With the role query
+// parameter, the override of the role is returned, falling back to the
+// global value, with the scope of the value in the ConfigScopeHeader. The
+// keys holding credentials are forbidden to read-only tokens.
 func cmdConfigGet(s state.State, r *http.Request) response.Response {
 	var key string
 	key, err := url.PathUnescape(mux.Vars(r)["key"])
 	if err != nil {
 		return response.InternalError(err)
 	}
-	config, err := sunbeam.GetConfig(r.Context(), s, key)
+
+	if access.IsReadOnlyTokenRequest(r) && sunbeam.IsSecretConfigKey(key) {
+		return response.Forbidden(fmt.Errorf("Read-only token cannot read config key %q", key))
+	}
+
+	role := r.URL.Query().Get("role")
+	if role != "" {
+		config, revision, scope, err := sunbeam.GetRoleConfig(r.Context(), s, key, role)
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				return response.NotFound(err)
+			}
+			if api.StatusErrorCheck(err, http.StatusBadRequest) {
+				return response.BadRequest(err)
+			}
+			return response.InternalError(err)
+		}
+
+		return &configScopeResponse{Response: response.SyncResponseETag(true, config, revision), scope: scope}
+	}
+
+	config, revision, err := sunbeam.GetConfigWithRevision(r.Context(), s, key)
 	if err != nil {
 		if err, ok := err.(api.StatusError); ok {
 			if err.Status() == http.StatusNotFound {
@@ -40,44 +116,276 @@ func cmdConfigGet(s state.State, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
-	return response.SyncResponse(true, config)
+	return response.SyncResponseETag(true, config, revision)
+}
+
+// configScopeResponse tells the scope of the config value it renders
+type configScopeResponse struct {
+	response.Response
+
+	scope string
+}
+
+func (cr *configScopeResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set(apitypes.ConfigScopeHeader, cr.scope)
+
+	return cr.Response.Render(w, r)
 }
 
+func cmdConfigSchemaGet(_ state.State, _ *http.Request) response.Response {
+	return response.SyncResponse(true, sunbeam.ConfigSchema)
+}
+
+// configStrict returns whether the request rejects writes of unknown config
+// keys
+func configStrict(r *http.Request) (bool, error) {
+	value := r.Header.Get(apitypes.ConfigStrictHeader)
+	if value == "" {
+		return false, nil
+	}
+
+	strict, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid %s header %q: %w", apitypes.ConfigStrictHeader, value, err)
+	}
+
+	return strict, nil
+}
+
+// cmdConfigPut sets the value of a config key, or its override for the node
+// role of the role query parameter.
 func cmdConfigPut(s state.State, r *http.Request) response.Response {
 	key, err := url.PathUnescape(mux.Vars(r)["key"])
 	if err != nil {
 		return response.InternalError(err)
 	}
 
+	strict, err := configStrict(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
 	var body bytes.Buffer
 	_, err = body.ReadFrom(r.Body)
 	if err != nil {
 		return response.InternalError(err)
 	}
 
-	err = sunbeam.UpdateConfig(r.Context(), s, key, body.String())
+	known, err := sunbeam.ValidateConfig(sunbeam.ConfigSchema, key, body.String(), strict)
 	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
 		return response.InternalError(err)
 	}
 
+	// The override of a role is validated against the schema of its key
+	scoped := sunbeam.RoleConfigKey(key, r.URL.Query().Get("role"))
+	err = sunbeam.UpdateConfigIfMatch(r.Context(), s, scoped, body.String(), r.Header.Get("If-Match"), requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusPreconditionFailed) {
+			return response.PreconditionFailed(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	if !known {
+		headers := map[string]string{
+			apitypes.ConfigWarningHeader: fmt.Sprintf("Unknown config key %q", key),
+		}
+		return response.SyncResponseHeaders(true, nil, headers)
+	}
+
 	return response.EmptySyncResponse
 }
 
+// cmdConfigTransactionPost sets config keys all at once, if the keys of the
+// preconditions hold their expected values. A rejected transaction is
+// answered with an error whose metadata lists the errors of every rejected
+// key.
+func cmdConfigTransactionPost(s state.State, r *http.Request) response.Response {
+	strict, err := configStrict(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var req apitypes.ConfigTransaction
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	unknown, err := sunbeam.ApplyConfigTransaction(r.Context(), s, req, strict, requestActor(r))
+	if err != nil {
+		var rejected *sunbeam.ConfigTransactionError
+		if errors.As(err, &rejected) {
+			return &configTransactionFailedResponse{rejected: rejected}
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	if len(unknown) > 0 {
+		quoted := make([]string, 0, len(unknown))
+		for _, key := range unknown {
+			quoted = append(quoted, strconv.Quote(key))
+		}
+
+		headers := map[string]string{
+			apitypes.ConfigWarningHeader: fmt.Sprintf("Unknown config keys %s", strings.Join(quoted, ", ")),
+		}
+		return response.SyncResponseHeaders(true, nil, headers)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// cmdConfigExportGet returns all the config keys as a single document, the
+// credentials redacted if the redact_secrets query parameter is true.
+func cmdConfigExportGet(s state.State, r *http.Request) response.Response {
+	redactSecrets := false
+	if value := r.URL.Query().Get("redact_secrets"); value != "" {
+		var err error
+		redactSecrets, err = strconv.ParseBool(value)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid redact_secrets %q: %w", value, err))
+		}
+	}
+
+	doc, err := sunbeam.ExportConfig(r.Context(), s, redactSecrets)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, doc)
+}
+
+// cmdConfigImportPost applies a config document all at once, reporting the
+// keys it changed. A rejected import is answered like a rejected config
+// transaction.
+func cmdConfigImportPost(s state.State, r *http.Request) response.Response {
+	var req apitypes.ConfigImport
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	result, err := sunbeam.ImportConfig(r.Context(), s, req, requestActor(r))
+	if err != nil {
+		var rejected *sunbeam.ConfigTransactionError
+		if errors.As(err, &rejected) {
+			return &configTransactionFailedResponse{rejected: rejected}
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, result)
+}
+
+// configTransactionFailedResponse is a 400 or 412 error response whose
+// metadata lists the errors of the rejected keys.
+type configTransactionFailedResponse struct {
+	rejected *sunbeam.ConfigTransactionError
+}
+
+func (cr *configTransactionFailedResponse) Render(w http.ResponseWriter, _ *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(cr.rejected.Status())
+
+	return json.NewEncoder(w).Encode(api.ResponseRaw{
+		Type:     api.ErrorResponse,
+		Code:     cr.rejected.Status(),
+		Error:    cr.rejected.Error(),
+		Metadata: cr.rejected.ConfigTransactionFailed,
+	})
+}
+
+func (cr *configTransactionFailedResponse) String() string {
+	return cr.rejected.Error()
+}
+
+// cmdConfigDelete removes a config key, or its override for the node role of
+// the role query parameter.
 func cmdConfigDelete(s state.State, r *http.Request) response.Response {
 	key, err := url.PathUnescape(mux.Vars(r)["key"])
 	if err != nil {
 		return response.InternalError(err)
 	}
 
-	err = sunbeam.DeleteConfig(r.Context(), s, key)
+	// Deleting the override of a role falls back to the global value
+	err = sunbeam.DeleteConfig(r.Context(), s, sunbeam.RoleConfigKey(key, r.URL.Query().Get("role")))
 	if err != nil {
 		if err, ok := err.(api.StatusError); ok {
 			if err.Status() == http.StatusNotFound {
 				return response.NotFound(err)
 			}
 		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
 		return response.InternalError(err)
 	}
 
 	return response.EmptySyncResponse
 }
+
+// cmdConfigHistoryGet returns the recorded revisions of a config key, oldest
+// first.
+func cmdConfigHistoryGet(s state.State, r *http.Request) response.Response {
+	key, err := url.PathUnescape(mux.Vars(r)["key"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	history, err := sunbeam.GetConfigHistory(r.Context(), s, key)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, history)
+}
+
+// cmdConfigRollbackPost restores a previous revision of a config key, as a
+// new revision.
+func cmdConfigRollbackPost(s state.State, r *http.Request) response.Response {
+	key, err := url.PathUnescape(mux.Vars(r)["key"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	// An empty body rolls back to the previous revision
+	var req apitypes.ConfigRollback
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return response.BadRequest(err)
+	}
+
+	if req.Revision < 0 {
+		return response.BadRequest(fmt.Errorf("Invalid revision %d: must be positive", req.Revision))
+	}
+
+	revision, err := sunbeam.RollbackConfig(r.Context(), s, key, req.Revision, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, revision)
+}
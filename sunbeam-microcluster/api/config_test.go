@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// TestConfigSchemaRoute tests that the schema path is not served as a key
+func TestConfigSchemaRoute(t *testing.T) {
+	router := mux.NewRouter()
+	for _, e := range extendedEndpoints {
+		path := e.Path
+		router.HandleFunc(filepath.Join("/1.0", path), func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Endpoint", path)
+		})
+	}
+
+	testCases := map[string]string{
+		"/1.0/config/schema":       configSchemaCmd.Path,
+		"/1.0/config/cluster-ca":   configCmd.Path,
+		"/1.0/config/schema-store": configCmd.Path,
+		"/1.0/config:transaction":  configTransactionCmd.Path,
+	}
+
+	for url, expected := range testCases {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+
+		endpoint := rec.Header().Get("X-Endpoint")
+		if endpoint != expected {
+			t.Errorf("Expected %q to be served by %q, got %q", url, expected, endpoint)
+		}
+	}
+}
+
+// TestConfigGetReadOnlyTokenSecrets tests that a read-only token cannot read
+// the config keys holding credentials, nor their role overrides
+func TestConfigGetReadOnlyTokenSecrets(t *testing.T) {
+	for _, url := range []string{"/1.0/config/webhook.targets", "/1.0/config/K8SKubeConfig", "/1.0/config/tfstate-openstack-plan", "/1.0/config/K8SKubeConfig?role=compute"} {
+		t.Run(url, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			req.Header.Set(apitypes.ReadOnlyTokenHeader, "Bearer observer")
+			req = mux.SetURLVars(req, map[string]string{"key": filepath.Base(req.URL.Path)})
+
+			rec := httptest.NewRecorder()
+			err := cmdConfigGet(nil, req).Render(rec, req)
+			if err != nil {
+				t.Fatalf("Failed to render response: %v", err)
+			}
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("Expected status %d, got %d", http.StatusForbidden, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/database/verify endpoint.
+var databaseVerifyCmd = rest.Endpoint{
+	Path: "database/verify",
+
+	Get: access.ClusterCATrustedEndpoint(cmdDatabaseVerifyGet, true),
+}
+
+// /1.0/database/compact endpoint.
+var databaseCompactCmd = rest.Endpoint{
+	Path: "database/compact",
+
+	Post: access.ClusterCATrustedEndpoint(cmdDatabaseCompactPost, true),
+}
+
+// cmdDatabaseVerifyGet returns the integrity check report of the database,
+// a corrupted database is reported with a 200 like a sound one.
+func cmdDatabaseVerifyGet(s state.State, r *http.Request) response.Response {
+	report, err := sunbeam.VerifyDatabase(r.Context(), s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, report)
+}
+
+func cmdDatabaseCompactPost(s state.State, r *http.Request) response.Response {
+	compaction, err := sunbeam.CompactDatabase(r.Context(), s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, compaction)
+}
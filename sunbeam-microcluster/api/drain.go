@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// ShutdownDrainer tracks the requests to the extended endpoints, so that
+// they complete before the daemon stops
+var ShutdownDrainer = sunbeam.NewDrainer()
+
+// drainEndpoints returns copies of the given endpoints whose requests are
+// tracked by drainer until their response is rendered. The requests
+// arriving while drainer is draining are refused with a 503.
+func drainEndpoints(drainer *sunbeam.Drainer, endpoints []rest.Endpoint) []rest.Endpoint {
+	drained := make([]rest.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		for _, action := range []*rest.EndpointAction{&e.Get, &e.Put, &e.Post, &e.Delete, &e.Patch} {
+			if action.Handler != nil {
+				action.Handler = drainHandler(drainer, action.Handler)
+			}
+		}
+
+		drained = append(drained, e)
+	}
+
+	return drained
+}
+
+func drainHandler(drainer *sunbeam.Drainer, handler func(state.State, *http.Request) response.Response) func(state.State, *http.Request) response.Response {
+	return func(s state.State, r *http.Request) response.Response {
+		ctx, done, ok := drainer.Begin(r.Context())
+		if !ok {
+			return response.Unavailable(errors.New("Daemon is shutting down"))
+		}
+
+		return &drainResponse{Response: handler(s, r.WithContext(ctx)), done: done}
+	}
+}
+
+// drainResponse completes the tracking of its request once it is rendered
+type drainResponse struct {
+	response.Response
+
+	done func()
+}
+
+func (dr *drainResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	defer dr.done()
+
+	return dr.Response.Render(w, r)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// sendDrained sends a request to the endpoint and returns its response
+func sendDrained(t *testing.T, e rest.Endpoint) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/1.0/status", nil)
+	rec := httptest.NewRecorder()
+
+	err := e.Get.Handler(nil, req).Render(rec, req)
+	if err != nil {
+		t.Errorf("Failed to render response: %v", err)
+	}
+
+	return rec
+}
+
+// TestDrainOnShutdownSignal tests that on SIGTERM a slow request in flight
+// completes while the new ones are refused, and that the daemon is only
+// stopped once the slow request completed.
+func TestDrainOnShutdownSignal(t *testing.T) {
+	drainer := sunbeam.NewDrainer()
+	stopped := make(chan struct{})
+	sunbeam.DrainOnSignal(drainer, time.Minute, func() { close(stopped) }, syscall.SIGTERM)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := func(_ state.State, r *http.Request) response.Response {
+		close(started)
+		select {
+		case <-release:
+			return response.SyncResponse(true, "done")
+		case <-r.Context().Done():
+			return response.InternalError(r.Context().Err())
+		}
+	}
+
+	fast := func(_ state.State, _ *http.Request) response.Response {
+		return response.EmptySyncResponse
+	}
+
+	endpoints := drainEndpoints(drainer, []rest.Endpoint{
+		{Path: "slow", Get: rest.EndpointAction{Handler: slow}},
+		{Path: "fast", Get: rest.EndpointAction{Handler: fast}},
+	})
+
+	slowDone := make(chan *httptest.ResponseRecorder)
+	go func() {
+		slowDone <- sendDrained(t, endpoints[0])
+	}()
+	<-started
+
+	err := syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	if err != nil {
+		t.Fatalf("Failed to send SIGTERM: %v", err)
+	}
+
+	// The signal is handled asynchronously, new requests are refused once
+	// the drain started
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := sendDrained(t, endpoints[1])
+		if rec.Code == http.StatusServiceUnavailable {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected new requests to be refused while draining, got %d", rec.Code)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-stopped:
+		t.Fatal("Expected the daemon not to be stopped with a request in flight")
+	default:
+	}
+
+	close(release)
+	rec := <-slowDone
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the slow request to complete, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the daemon to be stopped once the requests drained")
+	}
+}
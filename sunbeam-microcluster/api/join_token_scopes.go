@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/join-token-scopes endpoint.
+var joinTokenScopesCmd = rest.Endpoint{
+	Path: "join-token-scopes",
+
+	Get:  access.ClusterCATrustedEndpoint(cmdJoinTokenScopesGetAll, true),
+	Post: access.ClusterCATrustedEndpoint(cmdJoinTokenScopesPost, true),
+}
+
+// /1.0/join-token-scopes/<name> endpoint.
+var joinTokenScopeCmd = rest.Endpoint{
+	Path: "join-token-scopes/{name}",
+
+	Delete: access.ClusterCATrustedEndpoint(cmdJoinTokenScopeDelete, true),
+}
+
+// cmdJoinTokenScopesGetAll returns the join token scopes in their state.
+func cmdJoinTokenScopesGetAll(s state.State, r *http.Request) response.Response {
+	scopes, err := sunbeam.ListJoinTokenScopes(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, scopes)
+}
+
+// cmdJoinTokenScopesPost scopes the join token of a node name, returning the
+// scope recorded.
+func cmdJoinTokenScopesPost(s state.State, r *http.Request) response.Response {
+	var req apitypes.JoinTokenScope
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	scope, err := sunbeam.CreateJoinTokenScope(r.Context(), s, req, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, scope)
+}
+
+func cmdJoinTokenScopeDelete(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	err = sunbeam.DeleteJoinTokenScope(r.Context(), s, name)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/maintenance endpoint.
+var maintenanceCmd = rest.Endpoint{
+	Path: "maintenance",
+
+	Get: access.ReadOnlyTokenEndpoint(cmdMaintenanceGetAll, true),
+}
+
+// /1.0/maintenance/<name> endpoint.
+var maintenanceNodeCmd = rest.Endpoint{
+	Path: "maintenance/{name}",
+
+	Put: access.ClusterCATrustedEndpoint(cmdMaintenancePut, true),
+}
+
+// /1.0/maintenance/<name>/progress endpoint.
+var maintenanceProgressCmd = rest.Endpoint{
+	Path: "maintenance/{name}/progress",
+
+	Get:  access.ReadOnlyTokenEndpoint(cmdMaintenanceProgressGet, true),
+	Post: access.ClusterCATrustedEndpoint(cmdMaintenanceProgressPost, true),
+}
+
+// /1.0/maintenance/<name>/plan endpoint.
+var maintenancePlanCmd = rest.Endpoint{
+	Path: "maintenance/{name}/plan",
+
+	Get: access.ClusterCATrustedEndpoint(cmdMaintenancePlanGet, true),
+	Put: access.ClusterCATrustedEndpoint(cmdMaintenancePlanPut, true),
+}
+
+func cmdMaintenanceGetAll(s state.State, r *http.Request) response.Response {
+	statuses, err := sunbeam.ListMaintenanceStatus(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, statuses)
+}
+
+func cmdMaintenancePut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	var req apitypes.MaintenanceStatus
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	req.Node = name
+	if req.TriggeredBy == "" {
+		req.TriggeredBy = requestActor(r)
+	}
+
+	err = sunbeam.UpdateMaintenanceStatus(r.Context(), s, req)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// cmdMaintenanceProgressGet returns the progress events of the maintenance
+// operation on a node after the after sequence number. With wait, a duration,
+// the request waits for new events if there are none yet.
+func cmdMaintenanceProgressGet(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	query := r.URL.Query()
+	var after int64
+	if value := query.Get("after"); value != "" {
+		after, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid after sequence number %q: %w", value, err))
+		}
+	}
+
+	var wait time.Duration
+	if value := query.Get("wait"); value != "" {
+		wait, err = time.ParseDuration(value)
+		if err != nil || wait < 0 {
+			return response.BadRequest(fmt.Errorf("Invalid wait duration %q", value))
+		}
+	}
+
+	events := sunbeam.GetMaintenanceProgress(r.Context(), name, after, wait)
+	return response.SyncResponse(true, events)
+}
+
+// cmdMaintenanceProgressPost records progress events of the maintenance
+// operation on a node.
+func cmdMaintenanceProgressPost(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	var req apitypes.MaintenanceProgressList
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	events := sunbeam.AddMaintenanceProgress(name, req)
+	return response.SyncResponse(true, events)
+}
+
+// cmdMaintenancePlanGet returns the Watcher plans recorded for the
+// maintenance operations of a node, only the plan of the operation query
+// parameter if set.
+func cmdMaintenancePlanGet(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	plans, err := sunbeam.GetMaintenancePlans(r.Context(), s, name, r.URL.Query().Get("operation"))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, plans)
+}
+
+// cmdMaintenancePlanPut records the Watcher plan of a maintenance operation
+// of a node.
+func cmdMaintenancePlanPut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	var req apitypes.MaintenancePlan
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	req.Node = name
+	err = sunbeam.UpdateMaintenancePlan(r.Context(), s, req, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/maintenance-schedule endpoint.
+var maintenanceScheduleCmd = rest.Endpoint{
+	Path: "maintenance-schedule",
+
+	Get:  access.ClusterCATrustedEndpoint(cmdMaintenanceScheduleGetAll, true),
+	Post: access.ClusterCATrustedEndpoint(cmdMaintenanceSchedulePost, true),
+}
+
+// /1.0/maintenance-schedule/<id> endpoint.
+var maintenanceScheduleIDCmd = rest.Endpoint{
+	Path: "maintenance-schedule/{id}",
+
+	Delete: access.ClusterCATrustedEndpoint(cmdMaintenanceScheduleDelete, true),
+}
+
+func cmdMaintenanceScheduleGetAll(s state.State, r *http.Request) response.Response {
+	scheduled, err := sunbeam.ListScheduledMaintenances(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, scheduled)
+}
+
+// cmdMaintenanceSchedulePost schedules a maintenance enable of a node,
+// returning the scheduled maintenance recorded.
+func cmdMaintenanceSchedulePost(s state.State, r *http.Request) response.Response {
+	var req apitypes.ScheduledMaintenance
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	scheduled, err := sunbeam.ScheduleMaintenance(r.Context(), s, req, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, scheduled)
+}
+
+// cmdMaintenanceScheduleDelete cancels a pending scheduled maintenance, the
+// record is kept as cancelled.
+func cmdMaintenanceScheduleDelete(s state.State, r *http.Request) response.Response {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return response.BadRequest(fmt.Errorf("Invalid scheduled maintenance ID %q", mux.Vars(r)["id"]))
+	}
+
+	err = sunbeam.CancelScheduledMaintenance(r.Context(), s, id, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/meta endpoint.
+var metaCmd = rest.Endpoint{
+	Path: "meta",
+
+	Get: access.ReadOnlyTokenEndpoint(cmdMetaGet, true),
+}
+
+// cmdMetaGet returns the versions of the daemon and database schema, and
+// the API prefixes served, for clients to check their compatibility.
+// Members are queried with the target parameter during upgrades, when their
+// versions differ.
+func cmdMetaGet(s state.State, r *http.Request) response.Response {
+	meta, err := sunbeam.GetMeta(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, meta)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// MetricsUnauthenticated allows scraping the metrics endpoint without a
+// trusted client certificate, for deployments scraping from a trusted network.
+var MetricsUnauthenticated bool
+
+// /metrics endpoint.
+var metricsCmd = rest.Endpoint{
+	Path: "",
+
+	Get: rest.EndpointAction{
+		Handler:        cmdMetricsGet,
+		AccessHandler:  metricsAccessHandler,
+		AllowUntrusted: true,
+	},
+}
+
+func metricsAccessHandler(s state.State, r *http.Request) (bool, response.Response) {
+	if MetricsUnauthenticated {
+		return true, nil
+	}
+
+	return access.AuthenticateClusterCAOrReadOnlyTokenHandler(s, r)
+}
+
+func cmdMetricsGet(s state.State, r *http.Request) response.Response {
+	nodes, err := sunbeam.ListNodes(r.Context(), s, nil, nil, false)
+	if err != nil {
+		logging.FromRequest(r).Warn("Failed to count nodes for metrics", "error", err)
+	} else {
+		counts := make(map[string]int)
+		for _, node := range nodes {
+			for _, role := range node.Role {
+				counts[role]++
+			}
+		}
+
+		metrics.Nodes.Set(counts)
+	}
+
+	handler := promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{})
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		handler.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+// instrumentEndpoints returns copies of the given endpoints whose handlers
+// record request counts and latencies.
+func instrumentEndpoints(endpoints []rest.Endpoint) []rest.Endpoint {
+	instrumented := make([]rest.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		for _, action := range []*rest.EndpointAction{&e.Get, &e.Put, &e.Post, &e.Delete, &e.Patch} {
+			if action.Handler != nil {
+				action.Handler = instrumentHandler(e.Path, action.Handler)
+			}
+		}
+
+		instrumented = append(instrumented, e)
+	}
+
+	return instrumented
+}
+
+func instrumentHandler(path string, handler func(state.State, *http.Request) response.Response) func(state.State, *http.Request) response.Response {
+	return func(s state.State, r *http.Request) response.Response {
+		start := time.Now()
+
+		return &instrumentedResponse{
+			Response: handler(s, r),
+			handler:  path,
+			start:    start,
+		}
+	}
+}
+
+// instrumentedResponse records the metrics of a request once its response
+// is rendered.
+type instrumentedResponse struct {
+	response.Response
+
+	handler string
+	start   time.Time
+}
+
+func (ir *instrumentedResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	err := ir.Response.Render(rec, r)
+
+	metrics.RequestsTotal.WithLabelValues(ir.handler, r.Method, strconv.Itoa(rec.status)).Inc()
+	metrics.RequestDuration.WithLabelValues(ir.handler, r.Method).Observe(time.Since(ir.start).Seconds())
+
+	return err
+}
+
+// statusRecorder keeps the status code written to a ResponseWriter
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+)
+
+// TestInstrumentEndpoints tests that the request counter of a handler
+// increments after a nodes GET.
+func TestInstrumentEndpoints(t *testing.T) {
+	endpoints := instrumentEndpoints([]rest.Endpoint{{
+		Path: nodesCmd.Path,
+		Get: rest.EndpointAction{
+			Handler: func(_ state.State, _ *http.Request) response.Response {
+				return response.SyncResponse(true, []string{})
+			},
+		},
+	}})
+
+	if endpoints[0].Put.Handler != nil {
+		t.Error("Expected unset handlers to stay unset")
+	}
+
+	counter := metrics.RequestsTotal.WithLabelValues(nodesCmd.Path, http.MethodGet, "200")
+	before := testutil.ToFloat64(counter)
+
+	req := httptest.NewRequest(http.MethodGet, "/1.0/nodes", nil)
+	rec := httptest.NewRecorder()
+	err := endpoints[0].Get.Handler(nil, req).Render(rec, req)
+	if err != nil {
+		t.Fatalf("Failed to render response: %v", err)
+	}
+
+	after := testutil.ToFloat64(counter)
+	if after != before+1 {
+		t.Errorf("Expected request counter to increment from %v, got %v", before, after)
+	}
+
+	notFound := metrics.RequestsTotal.WithLabelValues(nodesCmd.Path, http.MethodGet, "404")
+	if testutil.ToFloat64(notFound) != 0 {
+		t.Error("Expected no requests recorded with status 404")
+	}
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/api"
@@ -20,28 +22,86 @@ import (
 var nodesCmd = rest.Endpoint{
 	Path: "nodes",
 
-	Get:  access.ClusterCATrustedEndpoint(cmdNodesGetAll, true),
+	Get:  access.ReadOnlyTokenEndpoint(cmdNodesGetAll, true),
 	Post: access.ClusterCATrustedEndpoint(cmdNodesPost, true),
 }
 
+// /1.0/nodes:batch endpoint.
+var nodesBatchCmd = rest.Endpoint{
+	Path: "nodes:batch",
+
+	Post: access.ClusterCATrustedEndpoint(cmdNodesBatchPost, true),
+}
+
 // /1.0/nodes/<name> endpoint.
 var nodeCmd = rest.Endpoint{
 	Path: "nodes/{name}",
 
-	Get:    access.ClusterCATrustedEndpoint(cmdNodesGet, true),
+	Get:    access.ReadOnlyTokenEndpoint(cmdNodesGet, true),
 	Put:    access.ClusterCATrustedEndpoint(cmdNodesPut, true),
 	Delete: access.ClusterCATrustedEndpoint(cmdNodesDelete, true),
 }
 
+// /1.0/nodes/<name>/labels endpoint.
+var nodeLabelsCmd = rest.Endpoint{
+	Path: "nodes/{name}/labels",
+
+	Get:   access.ClusterCATrustedEndpoint(cmdNodeLabelsGet, true),
+	Put:   access.ClusterCATrustedEndpoint(cmdNodeLabelsPut, true),
+	Patch: access.ClusterCATrustedEndpoint(cmdNodeLabelsPatch, true),
+}
+
+// /1.0/nodes/<name>/inventory endpoint.
+var nodeInventoryCmd = rest.Endpoint{
+	Path: "nodes/{name}/inventory",
+
+	Get: access.ClusterCATrustedEndpoint(cmdNodeInventoryGet, true),
+	Put: access.ClusterCATrustedEndpoint(cmdNodeInventoryPut, true),
+}
+
+// /1.0/nodes/<name>/cordon endpoint.
+var nodeCordonCmd = rest.Endpoint{
+	Path: "nodes/{name}/cordon",
+
+	Put: access.ClusterCATrustedEndpoint(cmdNodeCordonPut, true),
+}
+
+// maxNodesLimit is the largest page size accepted by the nodes listing.
+const maxNodesLimit = 1000
+
 func cmdNodesGetAll(s state.State, r *http.Request) response.Response {
-	roles := r.URL.Query()["role"]
+	query := r.URL.Query()
+	roles := query["role"]
+
+	limit, offset, err := parsePagination(query)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	labels, err := sunbeam.ParseLabelSelectors(query["label"])
+	if err != nil {
+		return response.BadRequest(err)
+	}
 
-	nodes, err := sunbeam.ListNodes(r.Context(), s, roles)
+	includeRemoved := false
+	if value := query.Get("include_removed"); value != "" {
+		includeRemoved, err = strconv.ParseBool(value)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid include_removed %q: %w", value, err))
+		}
+	}
+
+	nodes, err := sunbeam.ListNodes(r.Context(), s, roles, labels, includeRemoved)
 	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
 		return response.InternalError(err)
 	}
 
-	return response.SyncResponse(true, nodes)
+	total := len(nodes)
+
+	return nodesResponse(r, paginateNodes(nodes, limit, offset), total)
 }
 
 func cmdNodesGet(s state.State, r *http.Request) response.Response {
@@ -60,7 +120,7 @@ func cmdNodesGet(s state.State, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
-	return response.SyncResponse(true, node)
+	return nodeResponse(r, node)
 }
 
 func cmdNodesPost(s state.State, r *http.Request) response.Response {
@@ -71,14 +131,45 @@ func cmdNodesPost(s state.State, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
-	err = sunbeam.AddNode(r.Context(), s, req.Name, req.Role, req.MachineID, req.SystemID)
+	err = sunbeam.AddNode(r.Context(), s, req.Name, req.Role, req.MachineID, req.SystemID, req.Addresses, req.PreferredAPI)
 	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusForbidden) {
+			return response.Forbidden(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
 		return response.InternalError(err)
 	}
 
 	return response.EmptySyncResponse
 }
 
+// cmdNodesBatchPost applies node additions and removals atomically.
+// A batch aborted by a failed operation is rolled back and still answered
+// with a sync response, reporting the outcome of each operation.
+func cmdNodesBatchPost(s state.State, r *http.Request) response.Response {
+	req := apitypes.NodeBatchRequest{}
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	resp, err := sunbeam.ApplyNodeBatch(r.Context(), s, req.Operations, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, resp)
+}
+
 func cmdNodesPut(s state.State, r *http.Request) response.Response {
 	req := apitypes.Node{MachineID: -1}
 
@@ -94,6 +185,9 @@ func cmdNodesPut(s state.State, r *http.Request) response.Response {
 
 	err = sunbeam.UpdateNode(r.Context(), s, name, req.Role, req.MachineID, req.SystemID)
 	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
 		return response.InternalError(err)
 	}
 
@@ -105,10 +199,211 @@ func cmdNodesDelete(s state.State, r *http.Request) response.Response {
 	if err != nil {
 		return response.SmartError(err)
 	}
-	err = sunbeam.DeleteNode(r.Context(), s, name)
+	reason := r.URL.Query().Get("reason")
+
+	err = sunbeam.DeleteNode(r.Context(), s, name, reason, requestActor(r))
 	if err != nil {
 		return response.InternalError(err)
 	}
 
 	return response.EmptySyncResponse
 }
+
+func cmdNodeLabelsGet(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	labels, err := sunbeam.GetNodeLabels(r.Context(), s, name)
+	if err != nil {
+		return nodeAttributeError(err)
+	}
+
+	return response.SyncResponse(true, labels)
+}
+
+func cmdNodeLabelsPut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	req := apitypes.NodeLabels{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	labels, err := sunbeam.SetNodeLabels(r.Context(), s, name, req)
+	if err != nil {
+		return nodeAttributeError(err)
+	}
+
+	return response.SyncResponse(true, labels)
+}
+
+func cmdNodeLabelsPatch(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	req := map[string]*string{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	labels, err := sunbeam.MergeNodeLabels(r.Context(), s, name, req)
+	if err != nil {
+		return nodeAttributeError(err)
+	}
+
+	return response.SyncResponse(true, labels)
+}
+
+// nodeAttributeError maps errors from the node labels, inventory and
+// cordon operations to a response.
+func nodeAttributeError(err error) response.Response {
+	if api.StatusErrorCheck(err, http.StatusNotFound) {
+		return response.NotFound(err)
+	}
+
+	if api.StatusErrorCheck(err, http.StatusBadRequest) {
+		return response.BadRequest(err)
+	}
+
+	return response.InternalError(err)
+}
+
+func cmdNodeInventoryGet(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	history := false
+	historyStr := r.URL.Query().Get("history")
+	if historyStr != "" {
+		history, err = strconv.ParseBool(historyStr)
+		if err != nil {
+			return response.BadRequest(fmt.Errorf("Invalid history value %q", historyStr))
+		}
+	}
+
+	inventories, err := sunbeam.GetNodeInventory(r.Context(), s, name, history)
+	if err != nil {
+		return nodeAttributeError(err)
+	}
+
+	if !history {
+		return response.SyncResponse(true, inventories[0])
+	}
+
+	return response.SyncResponse(true, inventories)
+}
+
+func cmdNodeInventoryPut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	hardware, err := sunbeam.DecodeHardware(r.Body)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	inventory, err := sunbeam.UpdateNodeInventory(r.Context(), s, name, hardware)
+	if err != nil {
+		return nodeAttributeError(err)
+	}
+
+	return response.SyncResponse(true, inventory)
+}
+
+// cmdNodeCordonPut cordons or uncordons a node, returning the node.
+func cmdNodeCordonPut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	var req apitypes.NodeCordon
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	node, err := sunbeam.SetNodeCordon(r.Context(), s, name, req.Cordoned)
+	if err != nil {
+		return nodeAttributeError(err)
+	}
+
+	return nodeResponse(r, node)
+}
+
+// requestActor returns the client a request originates from, as the common
+// name of its certificate or the unix socket.
+func requestActor(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	return "unix-socket"
+}
+
+// nodesResponse renders the nodes with the fields known to the request prefix,
+// along with the total number of nodes matching the request.
+func nodesResponse(r *http.Request, nodes apitypes.Nodes, total int) response.Response {
+	headers := map[string]string{
+		apitypes.NodesTotalCountHeader: strconv.Itoa(total),
+	}
+
+	return response.SyncResponseHeaders(true, nodes.ForPrefix(apitypes.NegotiatePathPrefix(r.URL.Path)), headers)
+}
+
+// nodeResponse renders the node with the fields known to the request prefix.
+func nodeResponse(r *http.Request, node apitypes.Node) response.Response {
+	return response.SyncResponse(true, node.ForPrefix(apitypes.NegotiatePathPrefix(r.URL.Path)))
+}
+
+// parsePagination reads the limit and offset query parameters.
+// A missing limit returns -1, meaning no limit. Out of range values are
+// clamped, only non integer values are rejected.
+func parsePagination(query url.Values) (int, int, error) {
+	limit := -1
+	offset := 0
+
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid limit %q: %w", value, err)
+		}
+
+		limit = min(max(parsed, 1), maxNodesLimit)
+	}
+
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid offset %q: %w", value, err)
+		}
+
+		offset = max(parsed, 0)
+	}
+
+	return limit, offset, nil
+}
+
+// paginateNodes returns the page of nodes selected by limit and offset.
+func paginateNodes(nodes apitypes.Nodes, limit int, offset int) apitypes.Nodes {
+	offset = min(offset, len(nodes))
+	end := len(nodes)
+	if limit >= 0 {
+		end = min(offset+limit, len(nodes))
+	}
+
+	return nodes[offset:end]
+}
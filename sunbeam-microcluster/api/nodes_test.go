@@ -0,0 +1,224 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// newPrefixTestRouter registers the nodes/{name} path under every extended
+// prefix, rendering the given node the same way cmdNodesGet does.
+func newPrefixTestRouter(t *testing.T, node apitypes.Node) *mux.Router {
+	router := mux.NewRouter()
+
+	for _, resource := range extendedResources(extendedEndpoints) {
+		found := false
+		for _, e := range resource.Endpoints {
+			if e.Path != nodeCmd.Path {
+				continue
+			}
+
+			found = true
+			url := filepath.Join("/", string(resource.PathPrefix), e.Path)
+			router.HandleFunc(url, func(w http.ResponseWriter, r *http.Request) {
+				err := nodeResponse(r, node).Render(w, r)
+				if err != nil {
+					t.Errorf("Failed to render response: %v", err)
+				}
+			})
+		}
+
+		if !found {
+			t.Fatalf("Endpoint %q not registered under prefix %q", nodeCmd.Path, resource.PathPrefix)
+		}
+	}
+
+	return router
+}
+
+// TestNodeGetAcrossPrefixes tests that the same request is served under both
+// 1.0 and 1.1, that 1.1-only fields are omitted on the 1.0 path and that the
+// cordon state is served on both.
+func TestNodeGetAcrossPrefixes(t *testing.T) {
+	node := apitypes.Node{
+		Name:      "node-1",
+		Role:      []string{"compute", "control"},
+		MachineID: 1,
+		SystemID:  "abc123",
+		Member:    "member-1",
+		Cordoned:  true,
+	}
+	router := newPrefixTestRouter(t, node)
+
+	testCases := []struct {
+		name       string
+		prefix     string
+		wantMember bool
+	}{
+		{
+			name:       "1.0 omits member",
+			prefix:     "1.0",
+			wantMember: false,
+		},
+		{
+			name:       "1.1 includes member",
+			prefix:     "1.1",
+			wantMember: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.prefix+"/nodes/node-1", nil)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+
+			var resp struct {
+				Metadata map[string]any `json:"metadata"`
+			}
+			err := json.Unmarshal(rec.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if resp.Metadata["name"] != node.Name {
+				t.Errorf("Expected name %q, got %v", node.Name, resp.Metadata["name"])
+			}
+
+			if resp.Metadata["cordoned"] != true {
+				t.Errorf("Expected cordoned true, got %v", resp.Metadata["cordoned"])
+			}
+
+			_, hasMember := resp.Metadata["member"]
+			if hasMember != tc.wantMember {
+				t.Errorf("Expected member present=%v, got %v", tc.wantMember, hasMember)
+			}
+		})
+	}
+}
+
+// TestNegotiatePathPrefix tests the prefix detection and fallback to 1.0
+func TestNegotiatePathPrefix(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/1.0/nodes", expected: "1.0"},
+		{path: "/1.1/nodes/node-1", expected: "1.1"},
+		{path: "1.1/config/key", expected: "1.1"},
+		{path: "/2.0/nodes", expected: "1.0"},
+		{path: "/", expected: "1.0"},
+		{path: "", expected: "1.0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			prefix := apitypes.NegotiatePathPrefix(tc.path)
+			if string(prefix) != tc.expected {
+				t.Errorf("Expected prefix %q, got %q", tc.expected, prefix)
+			}
+		})
+	}
+}
+
+// TestParsePagination tests limit/offset parsing with clamping
+func TestParsePagination(t *testing.T) {
+	testCases := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{name: "no parameters", query: "", wantLimit: -1, wantOffset: 0},
+		{name: "limit and offset", query: "limit=50&offset=100", wantLimit: 50, wantOffset: 100},
+		{name: "limit clamped to maximum", query: "limit=100000", wantLimit: maxNodesLimit, wantOffset: 0},
+		{name: "limit clamped to one", query: "limit=0", wantLimit: 1, wantOffset: 0},
+		{name: "negative offset clamped", query: "offset=-5", wantLimit: -1, wantOffset: 0},
+		{name: "invalid limit", query: "limit=abc", wantErr: true},
+		{name: "invalid offset", query: "offset=1.5", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+
+			limit, offset, err := parsePagination(query)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if limit != tc.wantLimit || offset != tc.wantOffset {
+				t.Errorf("Expected limit=%d offset=%d, got limit=%d offset=%d", tc.wantLimit, tc.wantOffset, limit, offset)
+			}
+		})
+	}
+}
+
+// TestPaginateNodes tests page selection and total count header
+func TestPaginateNodes(t *testing.T) {
+	nodes := apitypes.Nodes{}
+	for _, name := range []string{"node-1", "node-2", "node-3", "node-4", "node-5"} {
+		nodes = append(nodes, apitypes.Node{Name: name})
+	}
+
+	testCases := []struct {
+		name     string
+		limit    int
+		offset   int
+		expected []string
+	}{
+		{name: "no limit", limit: -1, offset: 0, expected: []string{"node-1", "node-2", "node-3", "node-4", "node-5"}},
+		{name: "first page", limit: 2, offset: 0, expected: []string{"node-1", "node-2"}},
+		{name: "middle page", limit: 2, offset: 2, expected: []string{"node-3", "node-4"}},
+		{name: "last partial page", limit: 2, offset: 4, expected: []string{"node-5"}},
+		{name: "offset past the end", limit: 2, offset: 10, expected: []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page := paginateNodes(nodes, tc.limit, tc.offset)
+			if len(page) != len(tc.expected) {
+				t.Fatalf("Expected %d nodes, got %d", len(tc.expected), len(page))
+			}
+
+			for i, name := range tc.expected {
+				if page[i].Name != name {
+					t.Errorf("Node %d: expected %q, got %q", i, name, page[i].Name)
+				}
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/1.0/nodes", nil)
+			rec := httptest.NewRecorder()
+			err := nodesResponse(req, page, len(nodes)).Render(rec, req)
+			if err != nil {
+				t.Fatalf("Failed to render response: %v", err)
+			}
+
+			if total := rec.Header().Get(apitypes.NodesTotalCountHeader); total != "5" {
+				t.Errorf("Expected total count header 5, got %q", total)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/operations endpoint.
+var operationsCmd = rest.Endpoint{
+	Path: "operations",
+
+	Get:  access.ClusterCATrustedEndpoint(cmdOperationsGetAll, true),
+	Post: access.ClusterCATrustedEndpoint(cmdOperationsPost, true),
+}
+
+// /1.0/operations/<id> endpoint.
+var operationIDCmd = rest.Endpoint{
+	Path: "operations/{id}",
+
+	Get:    access.ClusterCATrustedEndpoint(cmdOperationGet, true),
+	Put:    access.ClusterCATrustedEndpoint(cmdOperationPut, true),
+	Delete: access.ClusterCATrustedEndpoint(cmdOperationDelete, true),
+}
+
+func cmdOperationsGetAll(s state.State, r *http.Request) response.Response {
+	operations, err := sunbeam.ListOperations(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, operations)
+}
+
+// cmdOperationsPost records a sunbeam command to run in the background by a
+// runner, returning the pending operation recorded.
+func cmdOperationsPost(s state.State, r *http.Request) response.Response {
+	var req apitypes.Operation
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	operation, err := sunbeam.CreateOperation(r.Context(), s, req.Command, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, operation)
+}
+
+// operationID parses the operation ID of the request path
+func operationID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return 0, fmt.Errorf("Invalid operation ID %q", mux.Vars(r)["id"])
+	}
+
+	return id, nil
+}
+
+func cmdOperationGet(s state.State, r *http.Request) response.Response {
+	id, err := operationID(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	operation, err := sunbeam.GetOperation(r.Context(), s, id)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, operation)
+}
+
+// cmdOperationPut records the claim, progress or outcome reported by the
+// runner of an operation, returning the operation so that the runner learns
+// whether it is cancelling.
+func cmdOperationPut(s state.State, r *http.Request) response.Response {
+	id, err := operationID(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var req apitypes.OperationUpdate
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	operation, err := sunbeam.UpdateOperation(r.Context(), s, id, req)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, operation)
+}
+
+// cmdOperationDelete cancels a pending or running operation, returning it.
+// The record is kept as cancelling until its command exits.
+func cmdOperationDelete(s state.State, r *http.Request) response.Response {
+	id, err := operationID(r)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	operation, err := sunbeam.CancelOperation(r.Context(), s, id, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, operation)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// quorumChecker detects the loss of the quorum of the database before the
+// requests are handled
+var quorumChecker = sunbeam.NewQuorumChecker(sunbeam.NewDatabaseMembers(), sunbeam.ProbeVoter)
+
+// quorumEndpoints returns copies of the given endpoints whose handlers
+// reject the requests with a 503 listing the expected and reachable voters
+// while the database has no quorum, rather than letting their queries time
+// out.
+func quorumEndpoints(checker *sunbeam.QuorumChecker, endpoints []rest.Endpoint) []rest.Endpoint {
+	checked := make([]rest.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		for _, action := range []*rest.EndpointAction{&e.Get, &e.Put, &e.Post, &e.Delete, &e.Patch} {
+			if action.Handler != nil {
+				action.Handler = quorumHandler(checker, action.Handler)
+			}
+		}
+
+		checked = append(checked, e)
+	}
+
+	return checked
+}
+
+func quorumHandler(checker *sunbeam.QuorumChecker, handler func(state.State, *http.Request) response.Response) func(state.State, *http.Request) response.Response {
+	return func(s state.State, r *http.Request) response.Response {
+		err := checker.Check(r.Context(), s)
+		var lost *sunbeam.QuorumLostError
+		if errors.As(err, &lost) {
+			return &quorumLostResponse{lost: lost}
+		}
+
+		return handler(s, r)
+	}
+}
+
+// quorumLostResponse is a 503 error response whose metadata lists the
+// expected and reachable voters.
+type quorumLostResponse struct {
+	lost *sunbeam.QuorumLostError
+}
+
+func (qr *quorumLostResponse) Render(w http.ResponseWriter, _ *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusServiceUnavailable)
+
+	return json.NewEncoder(w).Encode(api.ResponseRaw{
+		Type:     api.ErrorResponse,
+		Code:     http.StatusServiceUnavailable,
+		Error:    qr.lost.Error(),
+		Metadata: qr.lost.QuorumLost,
+	})
+}
+
+func (qr *quorumLostResponse) String() string {
+	return qr.lost.Error()
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/cluster"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// quorumEndpoint returns a status endpoint checked by a quorum checker of
+// three voters, the ones in down being unreachable. Its handler blocks
+// until the request times out, as the queries do without quorum.
+func quorumEndpoint(down ...string) (rest.Endpoint, *bool) {
+	members := []cluster.DqliteMember{
+		{DqliteID: 1, Address: "10.0.0.1:7000", Role: sunbeam.DatabaseVoterRole, Name: "node-1"},
+		{DqliteID: 2, Address: "10.0.0.2:7000", Role: sunbeam.DatabaseVoterRole, Name: "node-2"},
+		{DqliteID: 3, Address: "10.0.0.3:7000", Role: sunbeam.DatabaseVoterRole, Name: "node-3"},
+	}
+
+	checker := sunbeam.NewQuorumChecker(
+		func(state.State) ([]cluster.DqliteMember, error) {
+			return members, nil
+		},
+		func(_ context.Context, address string) error {
+			for _, member := range members {
+				if member.Address == address && slices.Contains(down, member.Name) {
+					return errors.New("connection refused")
+				}
+			}
+			return nil
+		},
+	)
+
+	handled := false
+	blocking := func(_ state.State, r *http.Request) response.Response {
+		handled = true
+		<-r.Context().Done()
+		return response.InternalError(r.Context().Err())
+	}
+
+	return quorumEndpoints(checker, []rest.Endpoint{{
+		Path: statusCmd.Path,
+		Get:  rest.EndpointAction{Handler: blocking},
+	}})[0], &handled
+}
+
+// sendQuorumChecked sends a request to the endpoint, timing out after
+// timeout, and returns the response
+func sendQuorumChecked(t *testing.T, e rest.Endpoint, timeout time.Duration) *httptest.ResponseRecorder {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/1.0/status", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	err := e.Get.Handler(nil, req).Render(rec, req)
+	if err != nil {
+		t.Fatalf("Failed to render response: %v", err)
+	}
+
+	return rec
+}
+
+// TestQuorumLostResponse tests that with two of the three voters down the
+// request is rejected with a typed 503 listing the voters, rather than
+// timing out in the handler
+func TestQuorumLostResponse(t *testing.T) {
+	e, handled := quorumEndpoint("node-2", "node-3")
+
+	rec := sendQuorumChecked(t, e, 10*time.Second)
+
+	if *handled {
+		t.Errorf("Expected the handler not to be called without quorum")
+	}
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected a 503, got %d", rec.Code)
+	}
+
+	var body struct {
+		api.ResponseRaw
+		Metadata apitypes.QuorumLost `json:"metadata"`
+	}
+
+	err := json.NewDecoder(rec.Body).Decode(&body)
+	if err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if body.Type != api.ErrorResponse || body.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected an error response, got %+v", body.ResponseRaw)
+	}
+
+	if body.Metadata.Code != apitypes.QuorumLostCode {
+		t.Errorf("Expected the %s code, got %q", apitypes.QuorumLostCode, body.Metadata.Code)
+	}
+
+	if !slices.Equal(body.Metadata.Expected, []string{"node-1", "node-2", "node-3"}) || !slices.Equal(body.Metadata.Reachable, []string{"node-1"}) {
+		t.Errorf("Unexpected voters %+v", body.Metadata)
+	}
+
+	expected := "Cluster quorum lost: 1 of 3 database voters reachable, unreachable: node-2, node-3"
+	if body.Error != expected {
+		t.Errorf("Expected %q, got %q", expected, body.Error)
+	}
+}
+
+// TestQuorumKeptHandled tests that the request is handled with a single
+// voter down
+func TestQuorumKeptHandled(t *testing.T) {
+	e, handled := quorumEndpoint("node-3")
+
+	rec := sendQuorumChecked(t, e, 10*time.Millisecond)
+
+	if !*handled {
+		t.Errorf("Expected the handler to be called with quorum")
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected the response of the handler, got %d", rec.Code)
+	}
+}
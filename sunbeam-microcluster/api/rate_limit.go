@@ -0,0 +1,110 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/rest/access"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// unixSocketClient identifies the requests received on the unix socket
+const unixSocketClient = "unix-socket"
+
+// rateLimiter limits the requests to the extended endpoints
+var rateLimiter = sunbeam.NewRateLimiter(sunbeam.LoadRateLimits)
+
+// rateLimitEndpoints returns copies of the given endpoints whose handlers
+// reject the requests over the rate limits of the limiter with a 429. GET
+// requests count as reads, the others as mutating requests.
+func rateLimitEndpoints(limiter *sunbeam.RateLimiter, endpoints []rest.Endpoint) []rest.Endpoint {
+	limited := make([]rest.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if e.Get.Handler != nil {
+			e.Get.Handler = rateLimitHandler(limiter, false, e.Get.Handler)
+		}
+
+		for _, action := range []*rest.EndpointAction{&e.Put, &e.Post, &e.Delete, &e.Patch} {
+			if action.Handler != nil {
+				action.Handler = rateLimitHandler(limiter, true, action.Handler)
+			}
+		}
+
+		limited = append(limited, e)
+	}
+
+	return limited
+}
+
+func rateLimitHandler(limiter *sunbeam.RateLimiter, write bool, handler func(state.State, *http.Request) response.Response) func(state.State, *http.Request) response.Response {
+	return func(s state.State, r *http.Request) response.Response {
+		// Requests forwarded by the other cluster members were limited
+		// where they were received
+		if r.RemoteAddr != "@" {
+			trusted, _ := access.AllowAuthenticated(s, r)
+			if trusted {
+				return handler(s, r)
+			}
+		}
+
+		allowed, wait := limiter.Allow(r.Context(), s, rateLimitClient(r), write)
+		if !allowed {
+			return &rateLimitedResponse{retryAfter: wait}
+		}
+
+		return handler(s, r)
+	}
+}
+
+// rateLimitClient identifies the client of a request: by its read-only
+// token, its client certificate or its address, in that order
+func rateLimitClient(r *http.Request) string {
+	if token := r.Header.Get(apitypes.ReadOnlyTokenHeader); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "token:" + hex.EncodeToString(sum[:8])
+	}
+
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		sum := sha256.Sum256(r.TLS.PeerCertificates[0].Raw)
+		return "cert:" + hex.EncodeToString(sum[:8])
+	}
+
+	if r.RemoteAddr == "@" {
+		return unixSocketClient
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// rateLimitedResponse is the 429 response to a request over the rate limit,
+// telling the client when to retry
+type rateLimitedResponse struct {
+	retryAfter time.Duration
+}
+
+func (rr *rateLimitedResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	seconds := max(1, int(math.Ceil(rr.retryAfter.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	return response.ErrorResponse(http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded, retry in %ds", seconds)).Render(w, r)
+}
+
+func (rr *rateLimitedResponse) String() string {
+	return http.StatusText(http.StatusTooManyRequests)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// rateLimitedConfigEndpoint returns a config endpoint rate limited with
+// limits, whose handlers always succeed
+func rateLimitedConfigEndpoint(limits sunbeam.RateLimits) rest.Endpoint {
+	limiter := sunbeam.NewRateLimiter(func(context.Context, state.State) (sunbeam.RateLimits, error) {
+		return limits, nil
+	})
+
+	ok := func(_ state.State, _ *http.Request) response.Response {
+		return response.EmptySyncResponse
+	}
+
+	return rateLimitEndpoints(limiter, []rest.Endpoint{{
+		Path: configCmd.Path,
+		Get:  rest.EndpointAction{Handler: ok},
+		Put:  rest.EndpointAction{Handler: ok},
+	}})[0]
+}
+
+// send sends a request to the config endpoint from the unix socket and
+// returns the response
+func send(t *testing.T, e rest.Endpoint, method string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/1.0/config/key", nil)
+	req.RemoteAddr = "@"
+	rec := httptest.NewRecorder()
+
+	handler := e.Get.Handler
+	if method == http.MethodPut {
+		handler = e.Put.Handler
+	}
+
+	err := handler(nil, req).Render(rec, req)
+	if err != nil {
+		t.Fatalf("Failed to render response: %v", err)
+	}
+
+	return rec
+}
+
+// TestRateLimitBurst tests that a burst over the limit gets 429 responses
+// with a Retry-After header
+func TestRateLimitBurst(t *testing.T) {
+	e := rateLimitedConfigEndpoint(sunbeam.RateLimits{ClientReads: 3, ClientWrites: 1, GlobalReads: 100, GlobalWrites: 100})
+
+	codes := map[int]int{}
+	var limited *httptest.ResponseRecorder
+	for range 5 {
+		rec := send(t, e, http.MethodGet)
+		codes[rec.Code]++
+		if rec.Code == http.StatusTooManyRequests {
+			limited = rec
+		}
+	}
+
+	if codes[http.StatusOK] != 3 || codes[http.StatusTooManyRequests] != 2 {
+		t.Fatalf("Expected 3 requests served and 2 limited, got %v", codes)
+	}
+
+	if limited.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", limited.Header().Get("Retry-After"))
+	}
+}
+
+// TestRateLimitReadFlood tests that a flood of reads, over both the client
+// and the global read limits, does not block writes below their own limit
+func TestRateLimitReadFlood(t *testing.T) {
+	e := rateLimitedConfigEndpoint(sunbeam.RateLimits{ClientReads: 3, ClientWrites: 2, GlobalReads: 3, GlobalWrites: 10})
+
+	for range 50 {
+		send(t, e, http.MethodGet)
+	}
+
+	if code := send(t, e, http.MethodGet).Code; code != http.StatusTooManyRequests {
+		t.Fatalf("Expected reads to be limited, got %d", code)
+	}
+
+	for i := range 2 {
+		if code := send(t, e, http.MethodPut).Code; code != http.StatusOK {
+			t.Errorf("Expected write %d to be served during the reads flood, got %d", i, code)
+		}
+	}
+
+	if code := send(t, e, http.MethodPut).Code; code != http.StatusTooManyRequests {
+		t.Errorf("Expected writes over their limit to be limited, got %d", code)
+	}
+}
+
+// TestRateLimitClient tests how the clients are told apart
+func TestRateLimitClient(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/1.0/config/key", nil)
+	req.RemoteAddr = "10.0.0.1:41000"
+	if client := rateLimitClient(req); client != "10.0.0.1" {
+		t.Errorf("Expected the client address, got %q", client)
+	}
+
+	req.Header.Set(apitypes.ReadOnlyTokenHeader, "Bearer token")
+	if client := rateLimitClient(req); client == "10.0.0.1" {
+		t.Errorf("Expected the token to identify the client, got %q", client)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/1.0/config/key", nil)
+	req.RemoteAddr = "@"
+	if client := rateLimitClient(req); client != unixSocketClient {
+		t.Errorf("Expected the unix socket client, got %q", client)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/read-only-tokens endpoint.
+var readOnlyTokensCmd = rest.Endpoint{
+	Path: "read-only-tokens",
+
+	Get:  access.ClusterCATrustedEndpoint(cmdReadOnlyTokensGetAll, true),
+	Post: access.ClusterCATrustedEndpoint(cmdReadOnlyTokensPost, true),
+}
+
+// /1.0/read-only-tokens/<name> endpoint.
+var readOnlyTokenCmd = rest.Endpoint{
+	Path: "read-only-tokens/{name}",
+
+	Delete: access.ClusterCATrustedEndpoint(cmdReadOnlyTokenDelete, true),
+}
+
+// cmdReadOnlyTokensGetAll returns the read-only tokens, without their hashes.
+func cmdReadOnlyTokensGetAll(s state.State, r *http.Request) response.Response {
+	tokens, err := sunbeam.ListReadOnlyTokens(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, tokens)
+}
+
+// cmdReadOnlyTokensPost creates a read-only token, returning the token once.
+func cmdReadOnlyTokensPost(s state.State, r *http.Request) response.Response {
+	var req apitypes.ReadOnlyToken
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	token, err := sunbeam.CreateReadOnlyToken(r.Context(), s, req.Name, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, apitypes.ReadOnlyTokenCreated{Token: token})
+}
+
+func cmdReadOnlyTokenDelete(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	err = sunbeam.DeleteReadOnlyToken(r.Context(), s, name, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
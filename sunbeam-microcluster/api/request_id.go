@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+// requestIDEndpoints returns copies of the given endpoints whose responses
+// carry the ID of the request, and whose handlers are passed a context
+// carrying a logger of the request.
+func requestIDEndpoints(endpoints []rest.Endpoint) []rest.Endpoint {
+	identified := make([]rest.Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		for _, action := range []*rest.EndpointAction{&e.Get, &e.Put, &e.Post, &e.Delete, &e.Patch} {
+			if action.Handler != nil {
+				action.Handler = requestIDHandler(action.Handler)
+			}
+
+			if action.AccessHandler != nil {
+				action.AccessHandler = requestIDAccessHandler(action.AccessHandler)
+			}
+		}
+
+		identified = append(identified, e)
+	}
+
+	return identified
+}
+
+func requestIDHandler(handler func(state.State, *http.Request) response.Response) func(state.State, *http.Request) response.Response {
+	return func(s state.State, r *http.Request) response.Response {
+		start := time.Now()
+		l := logging.FromRequest(r)
+		r = r.WithContext(logging.NewContext(r.Context(), l))
+
+		return &requestIDResponse{
+			Response: handler(s, r),
+			id:       logging.RequestID(r),
+			start:    start,
+		}
+	}
+}
+
+// requestIDAccessHandler returns the ID of the requests the access handler
+// rejects. The ID of the granted ones is returned by their endpoint handler.
+func requestIDAccessHandler(handler func(state.State, *http.Request) (bool, response.Response)) func(state.State, *http.Request) (bool, response.Response) {
+	return func(s state.State, r *http.Request) (bool, response.Response) {
+		start := time.Now()
+		trusted, resp := handler(s, r)
+		if trusted {
+			return trusted, resp
+		}
+
+		if resp == nil {
+			resp = response.Forbidden(nil)
+		}
+
+		return false, &requestIDResponse{Response: resp, id: logging.RequestID(r), start: start}
+	}
+}
+
+// requestIDResponse sets the request ID header of a response and logs the
+// request once its response is rendered.
+type requestIDResponse struct {
+	response.Response
+
+	id    string
+	start time.Time
+}
+
+func (ir *requestIDResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set(apitypes.RequestIDHeader, ir.id)
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	err := ir.Response.Render(rec, r)
+
+	logging.FromRequest(r).Debug("Handled request", "status", rec.status, "duration", time.Since(ir.start))
+
+	return err
+}
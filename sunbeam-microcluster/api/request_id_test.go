@@ -0,0 +1,139 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+// captureLogs sends the default logger output to the returned buffer until
+// the end of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buf
+}
+
+// identifiedEndpoint returns a config endpoint with request IDs, whose GET
+// handler logs with the logger of its context and whose access handler
+// rejects the PUT requests
+func identifiedEndpoint() rest.Endpoint {
+	get := func(_ state.State, r *http.Request) response.Response {
+		logging.FromContext(r.Context()).Info("Reading config")
+		return response.EmptySyncResponse
+	}
+
+	rejectPut := func(_ state.State, r *http.Request) (bool, response.Response) {
+		return r.Method != http.MethodPut, nil
+	}
+
+	return requestIDEndpoints([]rest.Endpoint{{
+		Path: configCmd.Path,
+		Get:  rest.EndpointAction{Handler: get, AccessHandler: rejectPut},
+		Put:  rest.EndpointAction{Handler: get, AccessHandler: rejectPut},
+	}})[0]
+}
+
+// sendIdentified sends a request to the endpoint, through its access handler
+// then its handler as microcluster does, and returns the response
+func sendIdentified(t *testing.T, e rest.Endpoint, method string, id string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, "/1.0/config/key", nil)
+	if id != "" {
+		req.Header.Set(apitypes.RequestIDHeader, id)
+	}
+	rec := httptest.NewRecorder()
+
+	action := e.Get
+	if method == http.MethodPut {
+		action = e.Put
+	}
+
+	trusted, resp := action.AccessHandler(nil, req)
+	if trusted {
+		resp = action.Handler(nil, req)
+	}
+
+	err := resp.Render(rec, req)
+	if err != nil {
+		t.Fatalf("Failed to render response: %v", err)
+	}
+
+	return rec
+}
+
+// TestRequestIDRoundTrip tests that the ID sent by the client is returned and
+// logged by the handler, along with the endpoint
+func TestRequestIDRoundTrip(t *testing.T) {
+	logs := captureLogs(t)
+
+	rec := sendIdentified(t, identifiedEndpoint(), http.MethodGet, "cli-1234")
+
+	if got := rec.Header().Get(apitypes.RequestIDHeader); got != "cli-1234" {
+		t.Errorf("Expected the request ID to be returned, got %q", got)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if !strings.Contains(line, "request_id=cli-1234") || !strings.Contains(line, "endpoint=/1.0/config/key") {
+			t.Errorf("Expected the log line to carry the request ID and endpoint, got %q", line)
+		}
+	}
+
+	if !strings.Contains(logs.String(), `msg="Reading config"`) {
+		t.Errorf("Expected the handler log line, got %q", logs.String())
+	}
+}
+
+// TestRequestIDGenerated tests that an ID is generated for the requests
+// without one, or with an invalid one
+func TestRequestIDGenerated(t *testing.T) {
+	logs := captureLogs(t)
+	e := identifiedEndpoint()
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+	for _, id := range []string{"", "forged\nline"} {
+		rec := sendIdentified(t, e, http.MethodGet, id)
+
+		got := rec.Header().Get(apitypes.RequestIDHeader)
+		if !generated.MatchString(got) {
+			t.Errorf("Expected a generated request ID for %q, got %q", id, got)
+		}
+
+		if !strings.Contains(logs.String(), "request_id="+got) {
+			t.Errorf("Expected the generated request ID to be logged, got %q", logs.String())
+		}
+	}
+}
+
+// TestRequestIDRejected tests that the responses of the requests rejected by
+// the access handler carry the request ID
+func TestRequestIDRejected(t *testing.T) {
+	captureLogs(t)
+
+	rec := sendIdentified(t, identifiedEndpoint(), http.MethodPut, "cli-5678")
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected a 403, got %d", rec.Code)
+	}
+
+	if got := rec.Header().Get(apitypes.RequestIDHeader); got != "cli-5678" {
+		t.Errorf("Expected the request ID to be returned, got %q", got)
+	}
+}
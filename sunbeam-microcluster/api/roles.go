@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/roles endpoint.
+var rolesCmd = rest.Endpoint{
+	Path: "roles",
+
+	Get: access.ClusterCATrustedEndpoint(cmdRolesGet, true),
+}
+
+// cmdRolesGet returns the roles a node can be assigned, the built-in roles
+// followed by the custom roles defined in the node.custom-roles config key.
+func cmdRolesGet(s state.State, r *http.Request) response.Response {
+	roles, err := sunbeam.ListNodeRoles(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, roles)
+}
@@ -6,41 +6,94 @@ import (
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
 )
 
-// Servers is a global list of all API servers on the /1.0 endpoint of
-// microcluster.
+// extendedEndpoints is the list of endpoints served under every extended API
+// prefix. Handlers needing to behave differently per prefix should use
+// apitypes.NegotiatePathPrefix on the request path.
+var extendedEndpoints = []rest.Endpoint{
+	nodesCmd,
+	nodesBatchCmd,
+	nodeCmd,
+	nodeLabelsCmd,
+	nodeInventoryCmd,
+	nodeCordonCmd,
+	rolesCmd,
+	terraformStateListCmd,
+	terraformStateCmd,
+	terraformLockListCmd,
+	terraformLockCmd,
+	terraformUnlockCmd,
+	jujuusersCmd,
+	jujuuserCmd,
+	configSchemaCmd,
+	configTransactionCmd,
+	configExportCmd,
+	configImportCmd,
+	configCmd,
+	configHistoryCmd,
+	configRollbackCmd,
+	manifestsCmd,
+	manifestCmd,
+	statusCmd,
+	storageBackendsCmd,
+	storageBackendCmd,
+	featureGatesCmd,
+	featureGateCmd,
+	maintenanceCmd,
+	maintenanceNodeCmd,
+	maintenanceProgressCmd,
+	maintenancePlanCmd,
+	maintenanceScheduleCmd,
+	maintenanceScheduleIDCmd,
+	backupCmd,
+	restoreCmd,
+	databaseVerifyCmd,
+	databaseCompactCmd,
+	clusterCertificateCmd,
+	clusterCertificateRotateCmd,
+	metaCmd,
+	webhooksCmd,
+	webhookCmd,
+	readOnlyTokensCmd,
+	readOnlyTokenCmd,
+	joinTokenScopesCmd,
+	joinTokenScopeCmd,
+	operationsCmd,
+	operationIDCmd,
+}
+
+// extendedResources returns the resources serving the given endpoints under
+// each of the extended API prefixes.
+func extendedResources(endpoints []rest.Endpoint) []rest.Resources {
+	resources := make([]rest.Resources, 0, len(apitypes.ExtendedPathPrefixes))
+	for _, prefix := range apitypes.ExtendedPathPrefixes {
+		resources = append(resources, rest.Resources{
+			PathPrefix: prefix,
+			Endpoints:  endpoints,
+		})
+	}
+
+	return resources
+}
+
+// Servers is a global list of all API servers on the /1.0 and /1.1 endpoints
+// of microcluster.
 var Servers = map[string]rest.Server{
 	"sunbeam": {
 		CoreAPI:   true,
 		ServeUnix: true,
-		Resources: []rest.Resources{
-			{
-				PathPrefix: apitypes.ExtendedPathPrefix,
+		Resources: append(extendedResources(requestIDEndpoints(drainEndpoints(ShutdownDrainer, instrumentEndpoints(quorumEndpoints(quorumChecker, rateLimitEndpoints(rateLimiter, extendedEndpoints)))))),
+			rest.Resources{
+				PathPrefix: apitypes.LocalPathPrefix,
 				Endpoints: []rest.Endpoint{
-					nodesCmd,
-					nodeCmd,
-					terraformStateListCmd,
-					terraformStateCmd,
-					terraformLockListCmd,
-					terraformLockCmd,
-					terraformUnlockCmd,
-					jujuusersCmd,
-					jujuuserCmd,
-					configCmd,
-					manifestsCmd,
-					manifestCmd,
-					statusCmd,
-					storageBackendsCmd,
-					storageBackendCmd,
-					featureGatesCmd,
-					featureGateCmd,
+					certPair,
 				},
 			},
-			{
-				PathPrefix: apitypes.LocalPathPrefix,
+			rest.Resources{
+				PathPrefix: apitypes.MetricsPathPrefix,
 				Endpoints: []rest.Endpoint{
-					certPair,
+					metricsCmd,
 				},
 			},
-		},
+		),
 	},
 }
@@ -11,22 +11,23 @@ import (
 	"github.com/canonical/microcluster/v2/state"
 
 	"github.com/canonical/lxd/shared/api"
-	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
 )
 
 var statusCmd = rest.Endpoint{
 	Path: "status",
 
-	Get: access.ClusterCATrustedEndpoint(cmdGetStatus, false),
+	Get: access.ReadOnlyTokenEndpoint(cmdGetStatus, false),
 }
 
 func cmdGetStatus(s state.State, r *http.Request) response.Response {
+	l := logging.FromRequest(r)
 	leader, err := s.Leader()
 
 	if err != nil {
-		logger.Errorf("Failed to get leader client: %v", err)
+		l.Error("Failed to get leader client", "error", err)
 		return response.InternalError(err)
 	}
 
@@ -36,7 +37,7 @@ func cmdGetStatus(s state.State, r *http.Request) response.Response {
 	var data []map[string]interface{}
 	err = leader.Query(queryCtx, "GET", "core/1.0", api.NewURL().Path("cluster"), nil, &data)
 	if err != nil {
-		logger.Errorf("Failed to get cluster status: %v", err)
+		l.Error("Failed to get cluster status", "error", err)
 		return response.InternalError(err)
 	}
 
@@ -50,6 +50,9 @@ var terraformLockListCmd = rest.Endpoint{
 }
 
 // /1.0/terraformlock/{name} endpoint.
+// Terraform http backend LOCK/UNLOCK are custom HTTP methods that cannot be
+// routed by microcluster, so they are served as PUT on the terraformlock and
+// terraformunlock endpoints, set as lock_address and unlock_address.
 var terraformLockCmd = rest.Endpoint{
 	Path: "terraformlock/{name}",
 
@@ -58,6 +61,8 @@ var terraformLockCmd = rest.Endpoint{
 }
 
 // /1.0/terraformunlock/{name} endpoint.
+// An empty body is sent by terraform force-unlock and clears the lock
+// regardless of its ID.
 var terraformUnlockCmd = rest.Endpoint{
 	Path: "terraformunlock/{name}",
 
@@ -219,16 +224,13 @@ func cmdLockPut(s state.State, r *http.Request) response.Response {
 			if err1 != nil {
 				return response.InternalError(err1)
 			}
+			// Terraform http backend reads the current lock holder from
+			// the body of a 423 response.
 			if err.Status() == http.StatusLocked {
 				return response.ManualResponse(func(w http.ResponseWriter) error {
 					w.WriteHeader(http.StatusLocked)
 					return util.WriteJSON(w, jsonDBLock, nil)
 				})
-			} else if err.Status() == http.StatusConflict {
-				return response.ManualResponse(func(w http.ResponseWriter) error {
-					w.WriteHeader(http.StatusConflict)
-					return util.WriteJSON(w, jsonDBLock, nil)
-				})
 			}
 		}
 		return response.InternalError(err)
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest"
+	"github.com/canonical/microcluster/v2/state"
+	"github.com/gorilla/mux"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/access"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+// /1.0/webhooks endpoint.
+var webhooksCmd = rest.Endpoint{
+	Path: "webhooks",
+
+	Get:  access.ClusterCATrustedEndpoint(cmdWebhooksGetAll, true),
+	Post: access.ClusterCATrustedEndpoint(cmdWebhooksPost, true),
+}
+
+// /1.0/webhooks/<name> endpoint.
+var webhookCmd = rest.Endpoint{
+	Path: "webhooks/{name}",
+
+	Delete: access.ClusterCATrustedEndpoint(cmdWebhookDelete, true),
+}
+
+// cmdWebhooksGetAll returns the webhooks the cluster events are posted to,
+// without their secrets.
+func cmdWebhooksGetAll(s state.State, r *http.Request) response.Response {
+	hooks, err := sunbeam.ListWebhooks(r.Context(), s)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return response.SyncResponse(true, hooks)
+}
+
+func cmdWebhooksPost(s state.State, r *http.Request) response.Response {
+	var req apitypes.Webhook
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = sunbeam.AddWebhook(r.Context(), s, req, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			return response.BadRequest(err)
+		}
+		if api.StatusErrorCheck(err, http.StatusConflict) {
+			return response.Conflict(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func cmdWebhookDelete(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	err = sunbeam.DeleteWebhook(r.Context(), s, name, requestActor(r))
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return response.NotFound(err)
+		}
+		return response.InternalError(err)
+	}
+
+	return response.EmptySyncResponse
+}
@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+	microCli "github.com/canonical/microcluster/v2/client"
+	"github.com/canonical/microcluster/v2/rest/response"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// Client is a typed client for the extended endpoints of the sunbeam
+// cluster daemon. The transport and authentication are the ones of the
+// wrapped microcluster client, a local one talks to the control socket and
+// a remote one authenticates with the certificates it was created with.
+type Client struct {
+	c *microCli.Client
+}
+
+// New returns a Client querying the daemon through c.
+func New(c *microCli.Client) *Client {
+	return &Client{c: c}
+}
+
+// query sends a request to an extended endpoint and unpacks the metadata of
+// the response into out, when not nil.
+func (c *Client) query(ctx context.Context, method string, path *api.URL, in any, out any) error {
+	err := c.c.Query(ctx, method, apitypes.ExtendedPathPrefix, path, in, out)
+	if err != nil {
+		return queryError(method, path, err)
+	}
+
+	return nil
+}
+
+// queryRaw sends a request to an extended endpoint with the given headers,
+// returning the raw response after checking its status code. The caller
+// closes the response body.
+func (c *Client) queryRaw(ctx context.Context, method string, path *api.URL, body io.Reader, headers map[string]string) (*http.Response, error) {
+	base := c.c.URL()
+	endpoint := *path
+	endpoint.URL.Scheme = base.URL.Scheme
+	endpoint.URL.Host = base.URL.Host
+	endpoint.URL.Path = "/" + string(apitypes.ExtendedPathPrefix) + path.URL.Path
+	endpoint.RawPath = "/" + string(apitypes.ExtendedPathPrefix) + path.RawPath
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.c.Do(req)
+	if err != nil {
+		return nil, queryError(method, path, err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		_, err = response.ParseResponse(resp)
+		if err == nil || !errors.As(err, new(api.StatusError)) {
+			// Not an error response of the daemon
+			err = api.StatusErrorf(resp.StatusCode, "%s", http.StatusText(resp.StatusCode))
+		}
+
+		return nil, queryError(method, path, err)
+	}
+
+	return resp, nil
+}
+
+// decodeResponse unpacks the metadata of a sync response into out and
+// closes its body.
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	parsed, err := response.ParseResponse(resp)
+	if err != nil {
+		return err
+	}
+
+	return parsed.MetadataAsStruct(out)
+}
+
+// queryError wraps an error of the query of an endpoint, keeping the status
+// code of the error responses
+func queryError(method string, path *api.URL, err error) error {
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		return &StatusError{StatusCode: statusErr.Status(), Err: err}
+	}
+
+	return fmt.Errorf("Failed to query %s %s: %w", method, path.URL.Path, err)
+}
@@ -0,0 +1,268 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	microCli "github.com/canonical/microcluster/v2/client"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// serverTransport sends the requests of the client to a test server
+type serverTransport struct {
+	url *url.URL
+}
+
+func (t serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.url.Scheme
+	req.URL.Host = t.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// failingTransport fails every request as if the daemon were unreachable
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(_ *http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+// newTestClient returns a Client querying a test server serving handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+
+	mc := &microCli.Client{}
+	mc.Client.Client = &http.Client{Transport: serverTransport{url: u}}
+	return New(mc)
+}
+
+// render writes resp as the daemon would
+func render(t *testing.T, w http.ResponseWriter, r *http.Request, resp response.Response) {
+	t.Helper()
+
+	err := resp.Render(w, r)
+	if err != nil {
+		t.Errorf("Failed to render response: %v", err)
+	}
+}
+
+// TestListNodes tests that the nodes are decoded and the roles sent as
+// query parameters
+func TestListNodes(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/1.0/nodes" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if roles := r.URL.Query()["role"]; !slices.Equal(roles, []string{"compute", "storage"}) {
+			t.Errorf("Expected the roles to be sent, got %v", roles)
+		}
+
+		render(t, w, r, response.SyncResponse(true, apitypes.Nodes{
+			{Name: "node-1", Role: []string{"compute"}, MachineID: 1, Cordoned: true},
+		}))
+	})
+
+	nodes, err := c.ListNodes(context.Background(), []string{"compute", "storage"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(nodes) != 1 || nodes[0].Name != "node-1" || nodes[0].MachineID != 1 || !nodes[0].Cordoned {
+		t.Errorf("Unexpected nodes %+v", nodes)
+	}
+}
+
+// TestQuorumLost tests that the 503 returned without database quorum
+// matches ErrQuorumLost, unlike the other 503 responses
+func TestQuorumLost(t *testing.T) {
+	message := "Cluster quorum lost: 1 of 3 database voters reachable, unreachable: node-2, node-3"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/1.0/nodes/node-1" {
+			render(t, w, r, response.Unavailable(errors.New(message)))
+			return
+		}
+		render(t, w, r, response.Unavailable(errors.New("Database is not ready yet")))
+	})
+
+	_, err := c.GetNode(context.Background(), "node-1")
+	if !errors.Is(err, ErrQuorumLost) || err.Error() != message {
+		t.Errorf("Expected ErrQuorumLost with the message of the daemon, got %v", err)
+	}
+
+	_, err = c.GetNode(context.Background(), "node-2")
+	if errors.Is(err, ErrQuorumLost) {
+		t.Errorf("Expected another unavailable error not to match ErrQuorumLost")
+	}
+}
+
+// TestGetNodeNotFound tests that a 404 response matches ErrNotFound and
+// keeps the message of the daemon
+func TestGetNodeNotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		render(t, w, r, response.NotFound(fmt.Errorf("Node not found")))
+	})
+
+	_, err := c.GetNode(context.Background(), "node-1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+
+	if errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a not found error not to match ErrConflict")
+	}
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 StatusError, got %v", err)
+	}
+
+	if err.Error() != "Node not found" {
+		t.Errorf("Expected the message of the daemon, got %q", err.Error())
+	}
+}
+
+// TestSetNodeCordon tests that the cordon state is sent and the updated
+// node returned
+func TestSetNodeCordon(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/1.0/nodes/node-1/cordon" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "{\"cordoned\":true}\n" {
+			t.Errorf("Unexpected body %q", body)
+		}
+
+		render(t, w, r, response.SyncResponse(true, apitypes.Node{Name: "node-1", Cordoned: true}))
+	})
+
+	node, err := c.SetNodeCordon(context.Background(), "node-1", true)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !node.Cordoned {
+		t.Errorf("Expected the node to be cordoned")
+	}
+}
+
+// TestConfigRevision tests that the revision read with the value is sent
+// back as If-Match, and a stale one matches ErrPreconditionFailed
+func TestConfigRevision(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1.0/config/key" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+
+		switch r.Method {
+		case "GET":
+			render(t, w, r, response.SyncResponseETag(true, "{\"a\": 1}", 2))
+		case "PUT":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != "{\"a\": 2}" {
+				t.Errorf("Expected the raw value to be sent, got %q", body)
+			}
+			if r.Header.Get("If-Match") != "stale" {
+				render(t, w, r, response.EmptySyncResponse)
+				return
+			}
+			render(t, w, r, response.PreconditionFailed(fmt.Errorf("Config modified")))
+		}
+	})
+
+	value, revision, err := c.GetConfigWithRevision(context.Background(), "key")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if value != "{\"a\": 1}" || revision == "" {
+		t.Errorf("Unexpected value %q and revision %q", value, revision)
+	}
+
+	err = c.SetConfigIfMatch(context.Background(), "key", "{\"a\": 2}", revision)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	err = c.SetConfigIfMatch(context.Background(), "key", "{\"a\": 2}", "stale")
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+// TestGetTerraformState tests that the state, served as is, is returned
+func TestGetTerraformState(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/1.0/terraformstate/plan" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte("{\"version\":4}"))
+	})
+
+	state, err := c.GetTerraformState(context.Background(), "plan")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if state != "{\"version\":4}" {
+		t.Errorf("Unexpected state %q", state)
+	}
+}
+
+// TestPutTerraformStateLocked tests that the lock ID is sent and the lock
+// conflict response, not an error response, matches ErrConflict
+func TestPutTerraformStateLocked(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ID") != "lock-1" {
+			t.Errorf("Expected the lock ID to be sent, got %q", r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte("{\"ID\":\"lock-2\"}"))
+	})
+
+	err := c.PutTerraformState(context.Background(), "plan", "lock-1", "{}")
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+}
+
+// TestTransportError tests that failing to reach the daemon is not reported
+// as a StatusError
+func TestTransportError(t *testing.T) {
+	mc := &microCli.Client{}
+	mc.Client.Client = &http.Client{Transport: failingTransport{}}
+	c := New(mc)
+
+	_, err := c.ListNodes(context.Background(), nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a transport error, got %v", err)
+	}
+
+	_, err = c.GetTerraformState(context.Background(), "plan")
+	if err == nil || errors.As(err, &statusErr) {
+		t.Errorf("Expected a transport error, got %v", err)
+	}
+}
@@ -1,8 +1,10 @@
-// Package client contains helper functions to configure the microcluster
+// Package client contains a typed client for the extended endpoints of the
+// cluster daemon, and helper functions to configure the microcluster
 package client
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/canonical/lxd/shared/api"
@@ -16,18 +18,79 @@ const (
 	ClusterCA = "cluster-ca"
 )
 
+// GetConfig returns the value of a config key, the error matches ErrNotFound
+// if the key is not set.
+func (c *Client) GetConfig(ctx context.Context, key string) (string, error) {
+	var value string
+	err := c.query(ctx, "GET", api.NewURL().Path("config", key), nil, &value)
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// GetConfigWithRevision returns the value of a config key along with the
+// ETag of its revision, to be passed to SetConfigIfMatch.
+func (c *Client) GetConfigWithRevision(ctx context.Context, key string) (string, string, error) {
+	resp, err := c.queryRaw(ctx, "GET", api.NewURL().Path("config", key), nil, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	var value string
+	err = decodeResponse(resp, &value)
+	if err != nil {
+		return "", "", err
+	}
+
+	return value, resp.Header.Get("ETag"), nil
+}
+
+// SetConfig sets the value of a config key.
+func (c *Client) SetConfig(ctx context.Context, key string, value string) error {
+	return c.SetConfigIfMatch(ctx, key, value, "")
+}
+
+// SetConfigIfMatch sets the value of a config key if its revision still
+// matches the ETag revision, the error matches ErrPreconditionFailed
+// otherwise. An empty revision skips the check.
+func (c *Client) SetConfigIfMatch(ctx context.Context, key string, value string, revision string) error {
+	headers := map[string]string{}
+	if revision != "" {
+		headers["If-Match"] = revision
+	}
+
+	resp, err := c.queryRaw(ctx, "PUT", api.NewURL().Path("config", key), strings.NewReader(value), headers)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// ApplyConfigTransaction sets the config keys of req all at once, if the
+// keys of its preconditions hold their expected values. The error lists
+// every rejected key and matches ErrPreconditionFailed if only
+// preconditions failed.
+func (c *Client) ApplyConfigTransaction(ctx context.Context, req apitypes.ConfigTransaction) error {
+	return c.query(ctx, "POST", api.NewURL().Path("config:transaction"), req, nil)
+}
+
+// DeleteConfig removes a config key, the error matches ErrNotFound if the
+// key is not set.
+func (c *Client) DeleteConfig(ctx context.Context, key string) error {
+	return c.query(ctx, "DELETE", api.NewURL().Path("config", key), nil, nil)
+}
+
 // ConfigClusterCASet configures the cluster ca.
 // This CA is used to validate incoming queries to extended endpoints.
 func ConfigClusterCASet(ctx context.Context, c *microCli.Client, data string) error {
 	queryCtx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
-	err := c.Query(queryCtx, "PUT", apitypes.ExtendedPathPrefix, api.NewURL().Path("config", ClusterCA), data, nil)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// Sent json encoded, as read back by ConfigClusterCAGet
+	return New(c).query(queryCtx, "PUT", api.NewURL().Path("config", ClusterCA), data, nil)
 }
 
 // ConfigClusterCAGet fetches the cluster ca.
@@ -35,11 +98,5 @@ func ConfigClusterCAGet(ctx context.Context, c *microCli.Client) (string, error)
 	queryCtx, cancel := context.WithTimeout(ctx, time.Second*60)
 	defer cancel()
 
-	var data string
-	err := c.Query(queryCtx, "GET", apitypes.ExtendedPathPrefix, api.NewURL().Path("config", ClusterCA), nil, &data)
-	if err != nil {
-		return "", err
-	}
-
-	return data, nil
+	return New(c).GetConfig(queryCtx, ClusterCA)
 }
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// quorumLostPrefix prefixes the message of the errors returned while the
+// database has lost its quorum
+const quorumLostPrefix = "Cluster quorum lost"
+
+var (
+	// ErrNotFound matches the errors of the queries on missing resources.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches the errors of the queries conflicting with the
+	// state of the resource, such as a taken terraform lock.
+	ErrConflict = errors.New("conflict")
+	// ErrPreconditionFailed matches the errors of the conditional updates
+	// of resources modified since they were read.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrQuorumLost matches the errors of the queries rejected while a
+	// majority of the database voters is unreachable.
+	ErrQuorumLost = errors.New("cluster quorum lost")
+)
+
+// StatusError is returned for the error responses of the daemon. The other
+// errors returned by the Client are transport or decoding failures.
+type StatusError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	Err        error
+}
+
+// Error returns the message of the error response.
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying api.StatusError.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the status code matches ErrNotFound, ErrConflict,
+// ErrPreconditionFailed or ErrQuorumLost.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrQuorumLost:
+		return e.StatusCode == http.StatusServiceUnavailable && strings.HasPrefix(e.Err.Error(), quorumLostPrefix)
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// ListNodes returns the nodes of the cluster, only the ones with one of
+// roles when not empty.
+func (c *Client) ListNodes(ctx context.Context, roles []string) (apitypes.Nodes, error) {
+	path := api.NewURL().Path("nodes")
+	for _, role := range roles {
+		path.WithQuery("role", role)
+	}
+
+	// Sent raw, microcluster only keeps the first value of a repeated query
+	// parameter
+	resp, err := c.queryRaw(ctx, "GET", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes apitypes.Nodes
+	err = decodeResponse(resp, &nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+// GetNode returns a node of the cluster, the error matches ErrNotFound if it
+// does not exist.
+func (c *Client) GetNode(ctx context.Context, name string) (apitypes.Node, error) {
+	var node apitypes.Node
+	err := c.query(ctx, "GET", api.NewURL().Path("nodes", name), nil, &node)
+	if err != nil {
+		return apitypes.Node{}, err
+	}
+
+	return node, nil
+}
+
+// SetNodeCordon records whether a node is cordoned, returning the updated
+// node. The error matches ErrNotFound if it does not exist.
+func (c *Client) SetNodeCordon(ctx context.Context, name string, cordoned bool) (apitypes.Node, error) {
+	var node apitypes.Node
+	err := c.query(ctx, "PUT", api.NewURL().Path("nodes", name, "cordon"), apitypes.NodeCordon{Cordoned: cordoned}, &node)
+	if err != nil {
+		return apitypes.Node{}, err
+	}
+
+	return node, nil
+}
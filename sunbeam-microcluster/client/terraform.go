@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// ListTerraformStates returns the names of the terraform plans with a state.
+func (c *Client) ListTerraformStates(ctx context.Context) ([]string, error) {
+	var names []string
+	err := c.query(ctx, "GET", api.NewURL().Path("terraformstate"), nil, &names)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// GetTerraformState returns the state of a terraform plan, as the json
+// document stored by terraform. The error matches ErrNotFound if the plan has
+// no state.
+func (c *Client) GetTerraformState(ctx context.Context, name string) (string, error) {
+	// The state is served as is for the terraform http backend, not wrapped
+	// in a sync response
+	resp, err := c.queryRaw(ctx, "GET", api.NewURL().Path("terraformstate", name), nil, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	state, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(state), nil
+}
+
+// PutTerraformState stores the state of a terraform plan, locked with lockID
+// if not empty. The error matches ErrConflict if the plan is locked with
+// another ID.
+func (c *Client) PutTerraformState(ctx context.Context, name string, lockID string, state string) error {
+	path := api.NewURL().Path("terraformstate", name)
+	if lockID != "" {
+		path.WithQuery("ID", lockID)
+	}
+
+	resp, err := c.queryRaw(ctx, "PUT", path, strings.NewReader(state), nil)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
@@ -3,8 +3,10 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"os"
+	"syscall"
 	"time"
 
 	"github.com/canonical/lxd/shared/logger"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/api"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/version"
 )
@@ -45,8 +48,10 @@ func (c *cmdGlobal) Run(_ *cobra.Command, _ []string) error {
 type cmdDaemon struct {
 	global *cmdGlobal
 
-	flagStateDir    string
-	flagSocketGroup string
+	flagStateDir               string
+	flagSocketGroup            string
+	flagMetricsUnauthenticated bool
+	flagShutdownGracePeriod    time.Duration
 }
 
 func (c *cmdDaemon) Command() *cobra.Command {
@@ -62,11 +67,26 @@ func (c *cmdDaemon) Command() *cobra.Command {
 }
 
 func (c *cmdDaemon) Run(_ *cobra.Command, _ []string) error {
+	api.MetricsUnauthenticated = c.flagMetricsUnauthenticated
+
+	// The request handlers log key=value lines, with the ID of the request
+	logging.Setup(os.Stderr, c.global.flagLogVerbose, c.global.flagLogDebug)
+
 	m, err := microcluster.App(microcluster.Args{StateDir: c.flagStateDir})
 	if err != nil {
 		return err
 	}
 
+	api.LocalClient = m.LocalClient
+
+	if c.flagShutdownGracePeriod < 0 {
+		return fmt.Errorf("Invalid shutdown grace period %s: must be positive", c.flagShutdownGracePeriod)
+	}
+
+	// Cancelled once the in-flight requests are drained on shutdown
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+
 	// Placeholder for post-action hooks that can be run by MicroCluster.
 	h := &state.Hooks{
 		// PreBootstrap is before after the daemon is initialized and bootstrapped.
@@ -87,9 +107,27 @@ func (c *cmdDaemon) Run(_ *cobra.Command, _ []string) error {
 		OnStart: func(ctx context.Context, s state.State) error {
 			logger.Info("This is a hook that runs after the daemon first starts")
 
+			// Let the in-flight requests complete before the daemon stops
+			sunbeam.DrainOnSignal(api.ShutdownDrainer, c.flagShutdownGracePeriod, stop, syscall.SIGTERM, os.Interrupt)
+
 			// Start the feature gate sync watcher to sync cluster DB to snap config
 			sunbeam.StartFeatureGateSync(ctx, s)
 
+			// Start the reaper purging removed nodes past their retention
+			sunbeam.StartNodeReaper(ctx, s)
+
+			// Start the bus posting the cluster events to the webhooks
+			sunbeam.StartEventBus(ctx, s)
+
+			// Start the expirer disabling maintenance once its TTL elapsed
+			sunbeam.StartMaintenanceExpirer(ctx, s)
+
+			// Start the scheduler enabling maintenance once its window opens
+			sunbeam.StartMaintenanceScheduler(ctx, s)
+
+			// Start the reaper failing the operations whose runner stopped reporting
+			sunbeam.StartOperationReaper(ctx, s)
+
 			return nil
 		},
 
@@ -101,10 +139,11 @@ func (c *cmdDaemon) Run(_ *cobra.Command, _ []string) error {
 		},
 
 		// PreJoin is run after the daemon is initialized and joins a cluster.
-		PreJoin: func(_ context.Context, _ state.State, _ map[string]string) error {
+		PreJoin: func(ctx context.Context, s state.State, initConfig map[string]string) error {
 			logger.Info("This is a hook that runs after the daemon is initialized and joins an existing cluster, before OnNewMember runs on all peers")
 
-			return nil
+			// Reject the join unless the scope of the join token permits it
+			return sunbeam.ConsumeJoinTokenScope(ctx, s, initConfig)
 		},
 
 		// PostRemove is run after the daemon is removed from a cluster.
@@ -146,7 +185,7 @@ func (c *cmdDaemon) Run(_ *cobra.Command, _ []string) error {
 		SocketGroup:      c.flagSocketGroup,
 	}
 
-	return m.Start(context.Background(), daemonArgs)
+	return m.Start(ctx, daemonArgs)
 }
 
 func init() {
@@ -166,9 +205,15 @@ func main() {
 
 	app.PersistentFlags().StringVar(&daemonCmd.flagStateDir, "state-dir", "", "Path to store state information"+"``")
 	app.PersistentFlags().StringVar(&daemonCmd.flagSocketGroup, "socket-group", "", "Group to set socket's group ownership to")
+	app.PersistentFlags().BoolVar(&daemonCmd.flagMetricsUnauthenticated, "metrics-unauthenticated", false, "Serve the metrics endpoint without client authentication")
+
+	app.PersistentFlags().DurationVar(&daemonCmd.flagShutdownGracePeriod, "shutdown-grace-period", sunbeam.DefaultShutdownGracePeriod, "How long the in-flight requests are given to complete on SIGTERM or SIGINT before they are cancelled")
 
 	app.SetVersionTemplate("{{.Version}}\n")
 
+	recoverCmd := cmdRecover{daemon: &daemonCmd}
+	app.AddCommand(recoverCmd.Command())
+
 	err := app.Execute()
 	if err != nil {
 		os.Exit(1)
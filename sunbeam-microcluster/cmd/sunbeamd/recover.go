@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/canonical/microcluster/v2/microcluster"
+	"github.com/spf13/cobra"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/sunbeam"
+)
+
+type cmdRecover struct {
+	daemon *cmdDaemon
+
+	flagForceQuorum bool
+	flagName        string
+	flagVoters      []string
+}
+
+func (c *cmdRecover) Command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recover",
+		Short: "Recover the database quorum from this member, with the daemons of all the members stopped",
+		Long: `Recover the database quorum from this member, with the daemons of all the members stopped.
+
+This member, which must have the most recent copy of the database, and the
+given voters become the only database voters. The other members become
+spares, to be removed from the cluster once it is recovered.
+
+The recovery writes a tarball of the database, to be copied to the state
+directory of the other surviving members before their daemon is started.`,
+		RunE: c.Run,
+	}
+
+	cmd.Flags().BoolVar(&c.flagForceQuorum, "force-quorum", false, "Confirm the database membership is to be reconfigured")
+	cmd.Flags().StringVar(&c.flagName, "name", "", "Name of this member")
+	cmd.Flags().StringSliceVar(&c.flagVoters, "voter", nil, "Name of another surviving member to keep as a voter, can be repeated")
+
+	return cmd
+}
+
+func (c *cmdRecover) Run(cmd *cobra.Command, _ []string) error {
+	if !c.flagForceQuorum {
+		return fmt.Errorf("Recovering the quorum reconfigures the database membership, confirm with --force-quorum")
+	}
+
+	if c.flagName == "" {
+		return fmt.Errorf("Missing the name of this member")
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.daemon.flagStateDir})
+	if err != nil {
+		return err
+	}
+
+	members, err := m.GetDqliteClusterMembers()
+	if err != nil {
+		return fmt.Errorf("Failed to read the database members: %w", err)
+	}
+
+	reconfigured, err := sunbeam.ForceQuorumMembers(members, c.flagName, c.flagVoters)
+	if err != nil {
+		return err
+	}
+
+	tarballPath, err := m.RecoverFromQuorumLoss(reconfigured)
+	if err != nil {
+		return fmt.Errorf("Failed to recover the database quorum: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	for _, member := range reconfigured {
+		fmt.Fprintf(out, "%s: %s\n", member.Name, member.Role)
+	}
+
+	fmt.Fprintf(out, "Database state of the recovered members saved to %s\n", tarballPath)
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// backupTables are the tables holding the state saved in a backup.
+// Removing nodes also removes their maintenance status, scheduled
+// maintenances and maintenance plans.
+var backupTables = []string{"nodes", "config", "jujuuser", "manifest", "storage_backends", "feature_gates", "node_tombstones", "join_token_scopes"}
+
+// GetSchemaVersions returns the internal and external (extension) schema
+// versions of the database.
+func GetSchemaVersions(ctx context.Context, tx *sql.Tx) (int, int, error) {
+	stmt := `
+SELECT COALESCE(MAX(version), 0) FROM schemas WHERE type = 0
+UNION ALL
+SELECT COALESCE(MAX(version), 0) FROM schemas WHERE type = 1
+`
+
+	versions, err := query.SelectIntegers(ctx, tx, stmt)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Failed to fetch schema versions: %w", err)
+	}
+
+	if len(versions) != 2 {
+		return 0, 0, fmt.Errorf("Expected 2 schema versions, got %d", len(versions))
+	}
+
+	return versions[0], versions[1], nil
+}
+
+// GetClusterMemberNames returns the names of the cluster members.
+func GetClusterMemberNames(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	names, err := query.SelectStrings(ctx, tx, "SELECT name FROM core_cluster_members")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch cluster members: %w", err)
+	}
+
+	return names, nil
+}
+
+// DeleteBackupState deletes all the entries of the tables saved in a backup,
+// along with the config history and the operations.
+func DeleteBackupState(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range backupTables {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", table))
+		if err != nil {
+			return fmt.Errorf("Failed to delete \"%s\" entries: %w", table, err)
+		}
+	}
+
+	// The history of the config replaced does not apply to the restored one
+	_, err := tx.ExecContext(ctx, "DELETE FROM config_history")
+	if err != nil {
+		return fmt.Errorf("Failed to delete \"config_history\" entries: %w", err)
+	}
+
+	// The operations were requested against the replaced state, such as the
+	// disable of a maintenance, they must not run against the restored one
+	_, err = tx.ExecContext(ctx, "DELETE FROM operations")
+	if err != nil {
+		return fmt.Errorf("Failed to delete \"operations\" entries: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreManifestItem adds a ManifestItem keeping its applied date.
+func RestoreManifestItem(ctx context.Context, tx *sql.Tx, object ManifestItem) error {
+	stmt := `
+INSERT INTO manifest (manifest_id, applied_date, data)
+  VALUES (?, ?, ?)
+`
+
+	_, err := tx.ExecContext(ctx, stmt, object.ManifestID, object.AppliedDate, object.Data)
+	if err != nil {
+		return fmt.Errorf("Failed to create \"manifest\" entry: %w", err)
+	}
+
+	return nil
+}
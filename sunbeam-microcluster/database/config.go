@@ -32,6 +32,8 @@ type ConfigItem struct {
 	ID    int
 	Key   string `db:"primary=yes"`
 	Value string
+	// Revision is bumped on every write to the item
+	Revision int
 }
 
 // ConfigItemFilter is a required struct for use with lxd-generate. It is used for filtering fields on database fetches.
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// ConfigRevision is used to keep the values a ConfigItem held, for rollback.
+// ChangedAt is stored as RFC3339 UTC text, ChangedBy is the client that
+// wrote the revision.
+type ConfigRevision struct {
+	ID        int
+	Key       string
+	Revision  int
+	Value     string
+	ChangedAt string
+	ChangedBy string
+}
+
+// CreateConfigRevision records a revision of a ConfigItem.
+func CreateConfigRevision(ctx context.Context, tx *sql.Tx, object ConfigRevision) error {
+	stmt := `
+INSERT INTO config_history (key, revision, value, changed_at, changed_by)
+  VALUES (?, ?, ?, ?, ?)
+`
+
+	_, err := tx.ExecContext(ctx, stmt, object.Key, object.Revision, object.Value, object.ChangedAt, object.ChangedBy)
+	if err != nil {
+		return fmt.Errorf("Failed to create \"config_history\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetConfigRevisions returns the recorded revisions of a ConfigItem, oldest
+// first.
+func GetConfigRevisions(ctx context.Context, tx *sql.Tx, key string) ([]ConfigRevision, error) {
+	return getConfigRevisions(ctx, tx, `WHERE config_history.key = ?`, key)
+}
+
+// GetConfigRevision returns a recorded revision of a ConfigItem, or a 404
+// StatusError if it is not recorded.
+func GetConfigRevision(ctx context.Context, tx *sql.Tx, key string, revision int) (ConfigRevision, error) {
+	revisions, err := getConfigRevisions(ctx, tx, `WHERE config_history.key = ? AND config_history.revision = ?`, key, revision)
+	if err != nil {
+		return ConfigRevision{}, err
+	}
+
+	if len(revisions) == 0 {
+		return ConfigRevision{}, api.StatusErrorf(http.StatusNotFound, "Revision %d of config key %q not found", revision, key)
+	}
+
+	return revisions[0], nil
+}
+
+// getConfigRevisions returns the revisions matching the where clause, ordered
+// by revision.
+func getConfigRevisions(ctx context.Context, tx *sql.Tx, where string, args ...any) ([]ConfigRevision, error) {
+	stmt := `
+SELECT config_history.id, config_history.key, config_history.revision, config_history.value, config_history.changed_at, config_history.changed_by
+  FROM config_history
+  ` + where + `
+  ORDER BY config_history.revision
+`
+
+	objects := make([]ConfigRevision, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		r := ConfigRevision{}
+		err := scan(&r.ID, &r.Key, &r.Revision, &r.Value, &r.ChangedAt, &r.ChangedBy)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, r)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"config_history\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetLatestConfigRevision returns the latest recorded revision number of a
+// ConfigItem, 0 if none is recorded.
+func GetLatestConfigRevision(ctx context.Context, tx *sql.Tx, key string) (int, error) {
+	var revision sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT MAX(revision) FROM config_history WHERE key = ?`, key).Scan(&revision)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to fetch from \"config_history\" table: %w", err)
+	}
+
+	return int(revision.Int64), nil
+}
+
+// DeleteConfigRevisionsBefore deletes the revisions of a ConfigItem older
+// than the given revision.
+func DeleteConfigRevisionsBefore(ctx context.Context, tx *sql.Tx, key string, revision int) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM config_history WHERE key = ? AND revision < ?`, key, revision)
+	if err != nil {
+		return fmt.Errorf("Delete \"config_history\" entries failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteConfigRevisionsBeyond deletes, for every key, the revisions of a
+// ConfigItem more than limit revisions older than its latest one, and
+// returns the number of deleted revisions.
+func DeleteConfigRevisionsBeyond(ctx context.Context, tx *sql.Tx, limit int) (int64, error) {
+	stmt := `
+DELETE FROM config_history
+  WHERE revision < (SELECT MAX(latest.revision) FROM config_history AS latest WHERE latest.key = config_history.key) - ?`
+
+	result, err := tx.ExecContext(ctx, stmt, limit)
+	if err != nil {
+		return 0, fmt.Errorf("Delete \"config_history\" entries failed: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	return n, nil
+}
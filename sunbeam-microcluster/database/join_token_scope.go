@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// JoinTokenScope is used to persist the restrictions of the join token of a
+// node name. Roles holds the json encoded roles permitted. Times are stored
+// as RFC3339 UTC text, ExpiresAt is empty if the token does not expire.
+type JoinTokenScope struct {
+	Name      string
+	Roles     string
+	Uses      int
+	Used      int
+	ExpiresAt string
+	CreatedAt string
+	CreatedBy string
+}
+
+// joinTokenScopeColumns are the columns of a JoinTokenScope, in the order
+// they are scanned
+const joinTokenScopeColumns = `name, roles, uses, used, expires_at, created_at, created_by`
+
+func scanJoinTokenScope(scan func(dest ...any) error, s *JoinTokenScope) error {
+	return scan(&s.Name, &s.Roles, &s.Uses, &s.Used, &s.ExpiresAt, &s.CreatedAt, &s.CreatedBy)
+}
+
+// GetJoinTokenScopes returns all the JoinTokenScopes ordered by name.
+func GetJoinTokenScopes(ctx context.Context, tx *sql.Tx) ([]JoinTokenScope, error) {
+	stmt := `SELECT ` + joinTokenScopeColumns + ` FROM join_token_scopes ORDER BY name`
+
+	objects := make([]JoinTokenScope, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		s := JoinTokenScope{}
+		err := scanJoinTokenScope(scan, &s)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, s)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"join_token_scopes\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// GetJoinTokenScope returns the JoinTokenScope of a node name, or a 404
+// StatusError if the join token of the name is not scoped.
+func GetJoinTokenScope(ctx context.Context, tx *sql.Tx, name string) (*JoinTokenScope, error) {
+	stmt := `SELECT ` + joinTokenScopeColumns + ` FROM join_token_scopes WHERE name = ?`
+
+	s := JoinTokenScope{}
+	err := scanJoinTokenScope(tx.QueryRowContext(ctx, stmt, name).Scan, &s)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.StatusErrorf(http.StatusNotFound, "JoinTokenScope not found")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"join_token_scopes\" table: %w", err)
+	}
+
+	return &s, nil
+}
+
+// UpsertJoinTokenScope records the JoinTokenScope of a node name, replacing
+// the previous one.
+func UpsertJoinTokenScope(ctx context.Context, tx *sql.Tx, object JoinTokenScope) error {
+	stmt := `
+INSERT INTO join_token_scopes (name, roles, uses, used, expires_at, created_at, created_by)
+  VALUES (?, ?, ?, ?, ?, ?, ?)
+  ON CONFLICT(name) DO UPDATE SET roles = excluded.roles, uses = excluded.uses, used = excluded.used, expires_at = excluded.expires_at,
+    created_at = excluded.created_at, created_by = excluded.created_by
+`
+
+	_, err := tx.ExecContext(ctx, stmt, object.Name, object.Roles, object.Uses, object.Used, object.ExpiresAt, object.CreatedAt, object.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("Failed to upsert \"join_token_scopes\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeJoinTokenScope counts a use of the JoinTokenScope of a node name if
+// it has uses left and is not expired at now, an RFC3339 UTC time. Returns
+// whether it was counted, the check and count being a single statement so
+// that concurrent uses cannot exceed the limit.
+func ConsumeJoinTokenScope(ctx context.Context, tx *sql.Tx, name string, now string) (bool, error) {
+	stmt := `
+UPDATE join_token_scopes
+  SET used = used + 1
+  WHERE name = ? AND used < uses AND (expires_at = '' OR expires_at > ?)
+`
+
+	result, err := tx.ExecContext(ctx, stmt, name, now)
+	if err != nil {
+		return false, fmt.Errorf("Failed to update \"join_token_scopes\" entry: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// DeleteJoinTokenScope deletes the JoinTokenScope of a node name, returning
+// a 404 StatusError if there is none.
+func DeleteJoinTokenScope(ctx context.Context, tx *sql.Tx, name string) error {
+	result, err := tx.ExecContext(ctx, `DELETE FROM join_token_scopes WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("Delete \"join_token_scopes\": %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return api.StatusErrorf(http.StatusNotFound, "JoinTokenScope not found")
+	}
+
+	return nil
+}
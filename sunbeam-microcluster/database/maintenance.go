@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// Maintenance is used to persist the maintenance state of a Node.
+// A Node without a Maintenance record is not in maintenance, records are
+// deleted along with their Node.
+// ExpiresAt is the RFC3339 time maintenance is automatically disabled, empty
+// if it has no TTL. AutoDisableAttempts and NextAttemptAt track the retries of
+// a failed automatic disable, DisableOperation is the operation requested for
+// the pending attempt, 0 if none. Member is the member that recorded the Node,
+// it is not persisted in the maintenance table.
+type Maintenance struct {
+	Node                string
+	Member              string
+	Status              string
+	EnteredAt           string
+	Strategy            string
+	TriggeredBy         string
+	Message             string
+	ExpiresAt           string
+	AutoDisableAttempts int
+	NextAttemptAt       string
+	DisableOperation    int
+}
+
+// GetMaintenances returns the maintenance state of every Node ordered by
+// join time, Nodes without a record have an empty Status.
+func GetMaintenances(ctx context.Context, tx *sql.Tx) ([]Maintenance, error) {
+	stmt := `
+SELECT nodes.name, core_cluster_members.name, coalesce(maintenance.status, ''), coalesce(maintenance.entered_at, ''), coalesce(maintenance.strategy, ''), coalesce(maintenance.triggered_by, ''), coalesce(maintenance.message, ''),
+  coalesce(maintenance.expires_at, ''), coalesce(maintenance.auto_disable_attempts, 0), coalesce(maintenance.next_attempt_at, ''),
+  coalesce(maintenance.disable_operation, 0)
+  FROM nodes
+  JOIN core_cluster_members ON nodes.member_id = core_cluster_members.id
+  LEFT JOIN maintenance ON maintenance.node_id = nodes.id
+  ORDER BY nodes.id
+`
+
+	objects := make([]Maintenance, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		m := Maintenance{}
+		err := scan(&m.Node, &m.Member, &m.Status, &m.EnteredAt, &m.Strategy, &m.TriggeredBy, &m.Message, &m.ExpiresAt, &m.AutoDisableAttempts, &m.NextAttemptAt, &m.DisableOperation)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, m)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"maintenance\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// UpsertMaintenance records the maintenance state of an existing Node.
+func UpsertMaintenance(ctx context.Context, tx *sql.Tx, object Maintenance) error {
+	nodeID, err := GetNodeID(ctx, tx, object.Node)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+INSERT INTO maintenance (node_id, status, entered_at, strategy, triggered_by, message, expires_at, auto_disable_attempts, next_attempt_at, disable_operation)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  ON CONFLICT(node_id) DO UPDATE SET status = excluded.status, entered_at = excluded.entered_at, strategy = excluded.strategy, triggered_by = excluded.triggered_by, message = excluded.message,
+    expires_at = excluded.expires_at, auto_disable_attempts = excluded.auto_disable_attempts, next_attempt_at = excluded.next_attempt_at,
+    disable_operation = excluded.disable_operation
+`
+
+	_, err = tx.ExecContext(ctx, stmt, nodeID, object.Status, object.EnteredAt, object.Strategy, object.TriggeredBy, object.Message, object.ExpiresAt, object.AutoDisableAttempts, object.NextAttemptAt, object.DisableOperation)
+	if err != nil {
+		return fmt.Errorf("Failed to record \"maintenance\" entry: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// MaintenancePlan is used to persist the result of the Watcher audit planning
+// a maintenance operation of a Node. A Node holds a single record per
+// operation, the latest. Records are deleted along with their Node.
+// Actions and Efficacy hold the json encoded actions and efficacy indicators
+// of the action plan.
+type MaintenancePlan struct {
+	Node       string
+	Operation  string
+	Audit      string
+	ActionPlan string
+	State      string
+	Reason     string
+	Actions    string
+	Efficacy   string
+	CreatedAt  string
+	CreatedBy  string
+}
+
+// UpsertMaintenancePlan records the MaintenancePlan of an operation of an
+// existing Node, replacing the previous one.
+func UpsertMaintenancePlan(ctx context.Context, tx *sql.Tx, object MaintenancePlan) error {
+	nodeID, err := GetNodeID(ctx, tx, object.Node)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+INSERT INTO maintenance_plan (node_id, operation, audit, action_plan, state, reason, actions, efficacy, created_at, created_by)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+  ON CONFLICT(node_id, operation) DO UPDATE SET audit = excluded.audit, action_plan = excluded.action_plan, state = excluded.state, reason = excluded.reason,
+    actions = excluded.actions, efficacy = excluded.efficacy, created_at = excluded.created_at, created_by = excluded.created_by
+`
+
+	_, err = tx.ExecContext(ctx, stmt, nodeID, object.Operation, object.Audit, object.ActionPlan, object.State, object.Reason, object.Actions, object.Efficacy, object.CreatedAt, object.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("Failed to upsert \"maintenance_plan\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetMaintenancePlans returns the MaintenancePlans of an existing Node
+// ordered by operation.
+func GetMaintenancePlans(ctx context.Context, tx *sql.Tx, node string) ([]MaintenancePlan, error) {
+	nodeID, err := GetNodeID(ctx, tx, node)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := `
+SELECT maintenance_plan.operation, maintenance_plan.audit, maintenance_plan.action_plan, maintenance_plan.state, maintenance_plan.reason,
+  maintenance_plan.actions, maintenance_plan.efficacy, maintenance_plan.created_at, maintenance_plan.created_by
+  FROM maintenance_plan
+  WHERE maintenance_plan.node_id = ?
+  ORDER BY maintenance_plan.operation
+`
+
+	objects := make([]MaintenancePlan, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		m := MaintenancePlan{Node: node}
+		err := scan(&m.Operation, &m.Audit, &m.ActionPlan, &m.State, &m.Reason, &m.Actions, &m.Efficacy, &m.CreatedAt, &m.CreatedBy)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, m)
+
+		return nil
+	}
+
+	err = query.Scan(ctx, tx, stmt, dest, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"maintenance_plan\" table: %w", err)
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// ScheduledMaintenance is used to persist a maintenance operation of a Node
+// deferred to a change window. Records are deleted along with their Node.
+// Times are stored as RFC3339 UTC text, WindowEnd is empty if the window has
+// no end. Options holds the json encoded options of the operation, Operation
+// the ID of the operation enabling maintenance once the window opened, 0
+// before. Member is the member that recorded the Node, it is not persisted in
+// the maintenance_schedule table.
+type ScheduledMaintenance struct {
+	ID          int
+	Node        string
+	Member      string
+	WindowStart string
+	WindowEnd   string
+	Options     string
+	Status      string
+	CreatedAt   string
+	CreatedBy   string
+	StartedAt   string
+	FinishedAt  string
+	Message     string
+	Operation   int
+}
+
+// CreateScheduledMaintenance records a ScheduledMaintenance of an existing
+// Node and returns its ID.
+func CreateScheduledMaintenance(ctx context.Context, tx *sql.Tx, object ScheduledMaintenance) (int64, error) {
+	nodeID, err := GetNodeID(ctx, tx, object.Node)
+	if err != nil {
+		return -1, err
+	}
+
+	stmt := `
+INSERT INTO maintenance_schedule (node_id, window_start, window_end, options, status, created_at, created_by)
+  VALUES (?, ?, ?, ?, ?, ?, ?)
+`
+
+	result, err := tx.ExecContext(ctx, stmt, nodeID, object.WindowStart, object.WindowEnd, object.Options, object.Status, object.CreatedAt, object.CreatedBy)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create \"maintenance_schedule\" entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to fetch \"maintenance_schedule\" entry ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetScheduledMaintenances returns all the ScheduledMaintenances ordered by
+// window start.
+func GetScheduledMaintenances(ctx context.Context, tx *sql.Tx) ([]ScheduledMaintenance, error) {
+	stmt := `
+SELECT maintenance_schedule.id, nodes.name, core_cluster_members.name, maintenance_schedule.window_start, maintenance_schedule.window_end, maintenance_schedule.options, maintenance_schedule.status,
+  maintenance_schedule.created_at, maintenance_schedule.created_by, maintenance_schedule.started_at, maintenance_schedule.finished_at, maintenance_schedule.message,
+  maintenance_schedule.operation
+  FROM maintenance_schedule
+  JOIN nodes ON maintenance_schedule.node_id = nodes.id
+  JOIN core_cluster_members ON nodes.member_id = core_cluster_members.id
+  ORDER BY maintenance_schedule.window_start, maintenance_schedule.id
+`
+
+	objects := make([]ScheduledMaintenance, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		m := ScheduledMaintenance{}
+		err := scan(&m.ID, &m.Node, &m.Member, &m.WindowStart, &m.WindowEnd, &m.Options, &m.Status, &m.CreatedAt, &m.CreatedBy, &m.StartedAt, &m.FinishedAt, &m.Message, &m.Operation)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, m)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"maintenance_schedule\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// UpdateScheduledMaintenanceStatus moves the ScheduledMaintenance with the
+// given ID from status from to status to, recording the times and message
+// given if not empty. Returns whether it was in status from, in which case
+// it was updated, so that concurrent updates only apply once.
+func UpdateScheduledMaintenanceStatus(ctx context.Context, tx *sql.Tx, id int, from string, to string, startedAt string, finishedAt string, message string) (bool, error) {
+	stmt := `
+UPDATE maintenance_schedule
+  SET status = ?, started_at = coalesce(nullif(?, ''), started_at), finished_at = coalesce(nullif(?, ''), finished_at), message = ?
+  WHERE id = ? AND status = ?
+`
+
+	result, err := tx.ExecContext(ctx, stmt, to, startedAt, finishedAt, message, id, from)
+	if err != nil {
+		return false, fmt.Errorf("Failed to update \"maintenance_schedule\" entry: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// UpdateScheduledMaintenanceOperation records the operation enabling the
+// maintenance of the ScheduledMaintenance with the given ID.
+func UpdateScheduledMaintenanceOperation(ctx context.Context, tx *sql.Tx, id int, operation int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE maintenance_schedule SET operation = ? WHERE id = ?", operation, id)
+	if err != nil {
+		return fmt.Errorf("Failed to update \"maintenance_schedule\" entry: %w", err)
+	}
+
+	return nil
+}
@@ -38,6 +38,14 @@ type Node struct {
 	Role      string
 	MachineID int
 	SystemID  string
+	// Labels is a json object of the node labels
+	Labels string
+	// Cordoned nodes are not selected for new workloads
+	Cordoned bool
+	// Addresses is a json object of the node addresses by network name
+	Addresses string
+	// PreferredAPI is the name of the address serving the cluster API
+	PreferredAPI string
 }
 
 // NodeFilter is a required struct for use with lxd-generate. It is used for filtering fields on database fetches.
@@ -48,7 +56,8 @@ type NodeFilter struct {
 	MachineID *int
 }
 
-// GetNodesFromRoles returns a slice of Nodes that match the given roles.
+// GetNodesFromRoles returns a slice of Nodes that match any of the given roles,
+// ordered by join time.
 func GetNodesFromRoles(ctx context.Context, tx *sql.Tx, roles []string) ([]Node, error) {
 
 	stmt, err := cluster.StmtString(nodeObjects)
@@ -65,7 +74,7 @@ func GetNodesFromRoles(ctx context.Context, tx *sql.Tx, roles []string) ([]Node,
 		queryParts[0] += " WHERE"
 		for i, role := range roles {
 			if i > 0 {
-				queryParts[0] += " AND"
+				queryParts[0] += " OR"
 			}
 			queryParts[0] += " instr(nodes.role, ?) > 0"
 			// Role is a json list, quote it to cheaply do full string match
@@ -73,7 +82,9 @@ func GetNodesFromRoles(ctx context.Context, tx *sql.Tx, roles []string) ([]Node,
 		}
 	}
 
-	stmt = strings.Join(queryParts, " ORDER BY")
+	// IDs are allocated incrementally when a node is recorded, ordering by
+	// them gives a stable join time ordering.
+	stmt = queryParts[0] + " ORDER BY nodes.id"
 
 	nodes, err := getNodesRaw(ctx, tx, stmt, args...)
 	if err != nil {
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// NodeInventory is used to persist a revision of the hardware inventory of a
+// Node. A new revision is recorded when the hardware changes, records are
+// deleted along with their Node.
+// CollectedAt and RefreshedAt are stored as RFC3339 UTC text.
+type NodeInventory struct {
+	Node        string
+	Revision    int
+	CollectedAt string
+	RefreshedAt string
+	Data        string
+}
+
+// GetNodeInventories returns the inventory revisions of a Node, latest first.
+func GetNodeInventories(ctx context.Context, tx *sql.Tx, node string) ([]NodeInventory, error) {
+	stmt := `
+SELECT nodes.name, node_inventory.revision, node_inventory.collected_at, node_inventory.refreshed_at, node_inventory.data
+  FROM node_inventory
+  JOIN nodes ON node_inventory.node_id = nodes.id
+  WHERE nodes.name = ?
+  ORDER BY node_inventory.revision DESC
+`
+
+	objects := make([]NodeInventory, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		i := NodeInventory{}
+		err := scan(&i.Node, &i.Revision, &i.CollectedAt, &i.RefreshedAt, &i.Data)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, i)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, node)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"node_inventory\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// UpsertNodeInventory records an inventory revision of an existing Node,
+// updating it if the revision is already recorded.
+func UpsertNodeInventory(ctx context.Context, tx *sql.Tx, object NodeInventory) error {
+	nodeID, err := GetNodeID(ctx, tx, object.Node)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+INSERT INTO node_inventory (node_id, revision, collected_at, refreshed_at, data)
+  VALUES (?, ?, ?, ?, ?)
+  ON CONFLICT(node_id, revision) DO UPDATE SET collected_at = excluded.collected_at, refreshed_at = excluded.refreshed_at, data = excluded.data
+`
+
+	_, err = tx.ExecContext(ctx, stmt, nodeID, object.Revision, object.CollectedAt, object.RefreshedAt, object.Data)
+	if err != nil {
+		return fmt.Errorf("Failed to record \"node_inventory\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteNodeInventoriesBefore deletes the inventory revisions of a Node older
+// than the given revision.
+func DeleteNodeInventoriesBefore(ctx context.Context, tx *sql.Tx, node string, revision int) error {
+	stmt := `
+DELETE FROM node_inventory
+  WHERE revision < ? AND node_id = (SELECT id FROM nodes WHERE name = ?)
+`
+
+	_, err := tx.ExecContext(ctx, stmt, revision, node)
+	if err != nil {
+		return fmt.Errorf("Failed to delete \"node_inventory\" entries: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/canonical/lxd/lxd/db/query"
+)
+
+// NodeTombstone is used to keep the record of a removed Node for auditing.
+// Member is the name of the cluster member that recorded the node, stored as
+// text so that tombstones do not hold a reference to core_cluster_members.
+// RemovedAt is stored as RFC3339 UTC text so it can be compared as a string.
+type NodeTombstone struct {
+	ID            int
+	Name          string
+	Member        string
+	Role          string
+	MachineID     int
+	SystemID      string
+	Labels        string
+	RemovedAt     string
+	RemovedReason string
+	RemovedBy     string
+}
+
+// CreateNodeTombstone records the tombstone of a removed Node, replacing any
+// older tombstone with the same name.
+func CreateNodeTombstone(ctx context.Context, tx *sql.Tx, object NodeTombstone) error {
+	err := DeleteNodeTombstone(ctx, tx, object.Name)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+INSERT INTO node_tombstones (name, member, role, machine_id, system_id, labels, removed_at, removed_reason, removed_by)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+	_, err = tx.ExecContext(ctx, stmt, object.Name, object.Member, object.Role, object.MachineID, object.SystemID, object.Labels, object.RemovedAt, object.RemovedReason, object.RemovedBy)
+	if err != nil {
+		return fmt.Errorf("Failed to create \"node_tombstones\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetNodeTombstones returns all the NodeTombstones ordered by removal.
+func GetNodeTombstones(ctx context.Context, tx *sql.Tx) ([]NodeTombstone, error) {
+	stmt := `
+SELECT node_tombstones.id, node_tombstones.name, node_tombstones.member, node_tombstones.role, node_tombstones.machine_id, node_tombstones.system_id, node_tombstones.labels, node_tombstones.removed_at, node_tombstones.removed_reason, node_tombstones.removed_by
+  FROM node_tombstones
+  ORDER BY node_tombstones.id
+`
+
+	objects := make([]NodeTombstone, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		n := NodeTombstone{}
+		err := scan(&n.ID, &n.Name, &n.Member, &n.Role, &n.MachineID, &n.SystemID, &n.Labels, &n.RemovedAt, &n.RemovedReason, &n.RemovedBy)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, n)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"node_tombstones\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// DeleteNodeTombstone deletes the NodeTombstone with the given name, if any.
+func DeleteNodeTombstone(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM node_tombstones WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("Delete \"node_tombstones\" entry failed: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteNodeTombstonesBefore deletes the NodeTombstones removed before the
+// given RFC3339 UTC time and returns the number of deleted tombstones.
+func DeleteNodeTombstonesBefore(ctx context.Context, tx *sql.Tx, before string) (int64, error) {
+	result, err := tx.ExecContext(ctx, `DELETE FROM node_tombstones WHERE removed_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("Delete \"node_tombstones\" entries failed: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	return n, nil
+}
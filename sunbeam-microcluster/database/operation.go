@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// Operation is used to persist a sunbeam command run in the background by
+// a runner. Member is the name of the member the operation was created on,
+// Runner the name of the runner which claimed it, Command holds its json
+// encoded arguments. Times are stored as RFC3339 UTC text.
+type Operation struct {
+	ID          int
+	Member      string
+	Runner      string
+	Command     string
+	Status      string
+	Progress    string
+	Result      string
+	Error       string
+	CreatedAt   string
+	CreatedBy   string
+	StartedAt   string
+	FinishedAt  string
+	HeartbeatAt string
+}
+
+var operationColumns = `operations.id, operations.member, operations.runner, operations.command, operations.status, operations.progress, operations.result, operations.error,
+  operations.created_at, operations.created_by, operations.started_at, operations.finished_at, operations.heartbeat_at`
+
+// CreateOperation records an Operation and returns its ID.
+func CreateOperation(ctx context.Context, tx *sql.Tx, object Operation) (int64, error) {
+	stmt := `
+INSERT INTO operations (member, command, status, created_at, created_by)
+  VALUES (?, ?, ?, ?, ?)
+`
+
+	result, err := tx.ExecContext(ctx, stmt, object.Member, object.Command, object.Status, object.CreatedAt, object.CreatedBy)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to create \"operations\" entry: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return -1, fmt.Errorf("Failed to fetch \"operations\" entry ID: %w", err)
+	}
+
+	return id, nil
+}
+
+// GetOperations returns all the Operations ordered by ID.
+func GetOperations(ctx context.Context, tx *sql.Tx) ([]Operation, error) {
+	stmt := fmt.Sprintf(`
+SELECT %s
+  FROM operations
+  ORDER BY operations.id
+`, operationColumns)
+
+	return getOperations(ctx, tx, stmt)
+}
+
+// GetOperation returns the Operation with the given ID, or a 404
+// StatusError if there is none.
+func GetOperation(ctx context.Context, tx *sql.Tx, id int) (*Operation, error) {
+	stmt := fmt.Sprintf(`
+SELECT %s
+  FROM operations
+  WHERE operations.id = ?
+`, operationColumns)
+
+	objects, err := getOperations(ctx, tx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Operation not found")
+	}
+
+	return &objects[0], nil
+}
+
+func getOperations(ctx context.Context, tx *sql.Tx, stmt string, args ...any) ([]Operation, error) {
+	objects := make([]Operation, 0)
+
+	dest := func(scan func(dest ...any) error) error {
+		o := Operation{}
+		err := scan(&o.ID, &o.Member, &o.Runner, &o.Command, &o.Status, &o.Progress, &o.Result, &o.Error, &o.CreatedAt, &o.CreatedBy, &o.StartedAt, &o.FinishedAt, &o.HeartbeatAt)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, o)
+
+		return nil
+	}
+
+	err := query.Scan(ctx, tx, stmt, dest, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch from \"operations\" table: %w", err)
+	}
+
+	return objects, nil
+}
+
+// UpdateOperationStatus moves the Operation with the given ID from status
+// from to status to, recording the times, result and error given if not
+// empty. Returns whether it was in status from, in which case it was
+// updated, so that concurrent updates only apply once.
+func UpdateOperationStatus(ctx context.Context, tx *sql.Tx, id int, from string, to string, startedAt string, finishedAt string, result string, errorMessage string) (bool, error) {
+	stmt := `
+UPDATE operations
+  SET status = ?, started_at = coalesce(nullif(?, ''), started_at), finished_at = coalesce(nullif(?, ''), finished_at),
+    result = coalesce(nullif(?, ''), result), error = coalesce(nullif(?, ''), error)
+  WHERE id = ? AND status = ?
+`
+
+	res, err := tx.ExecContext(ctx, stmt, to, startedAt, finishedAt, result, errorMessage, id, from)
+	if err != nil {
+		return false, fmt.Errorf("Failed to update \"operations\" entry: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("Fetch affected rows: %w", err)
+	}
+
+	return n == 1, nil
+}
+
+// UpdateOperationProgress records the progress of the Operation with the
+// given ID, keeping the current one if progress is empty.
+func UpdateOperationProgress(ctx context.Context, tx *sql.Tx, id int, progress string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE operations SET progress = coalesce(nullif(?, ''), progress) WHERE id = ?", progress, id)
+	if err != nil {
+		return fmt.Errorf("Failed to update \"operations\" entry: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateOperationHeartbeat records the runner of the Operation with the
+// given ID and the time it last reported.
+func UpdateOperationHeartbeat(ctx context.Context, tx *sql.Tx, id int, runner string, heartbeatAt string) error {
+	_, err := tx.ExecContext(ctx, "UPDATE operations SET runner = ?, heartbeat_at = ? WHERE id = ?", runner, heartbeatAt, id)
+	if err != nil {
+		return fmt.Errorf("Failed to update \"operations\" entry: %w", err)
+	}
+
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 // SchemaExtensions is a list of schema extensions that can be passed to the MicroCluster daemon.
 // Each entry will increase the database schema version by one, and will be applied after internal schema updates.
+// The CLI checks the schema version served by /1.0/meta, add each entry to its compatibility matrix too.
 var SchemaExtensions = []schema.Update{
 	NodesSchemaUpdate,
 	ConfigSchemaUpdate,
@@ -18,6 +19,22 @@ var SchemaExtensions = []schema.Update{
 	AddSystemIDToNodes,
 	StorageBackendSchemaUpdate,
 	FeatureGatesSchemaUpdate,
+	AddRevisionToConfig,
+	AddLabelsToNodes,
+	NodeTombstonesSchemaUpdate,
+	MaintenanceSchemaUpdate,
+	NodeInventorySchemaUpdate,
+	AddExpiryToMaintenance,
+	ConfigHistorySchemaUpdate,
+	MaintenanceScheduleSchemaUpdate,
+	AddCordonedToNodes,
+	MaintenancePlanSchemaUpdate,
+	JoinTokenScopesSchemaUpdate,
+	AddAddressesToNodes,
+	OperationsSchemaUpdate,
+	AddOperationRunners,
+	AddMaintenanceDisableOperation,
+	AddScheduledMaintenanceOperation,
 }
 
 // NodesSchemaUpdate is schema for table nodes
@@ -131,3 +148,273 @@ CREATE TABLE feature_gates (
 	_, err := tx.Exec(stmt)
 	return err
 }
+
+// AddRevisionToConfig is schema update for table config
+func AddRevisionToConfig(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE config ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// AddLabelsToNodes is schema update for table nodes
+func AddLabelsToNodes(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE nodes ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// NodeTombstonesSchemaUpdate is schema for table node_tombstones
+func NodeTombstonesSchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE node_tombstones (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  name                          TEXT     NOT NULL,
+  member                        TEXT     NOT NULL,
+  role                          TEXT,
+  machine_id                    INTEGER,
+  system_id                     TEXT     NOT NULL DEFAULT '',
+  labels                        TEXT     NOT NULL DEFAULT '{}',
+  removed_at                    TEXT     NOT NULL,
+  removed_reason                TEXT     NOT NULL DEFAULT '',
+  removed_by                    TEXT     NOT NULL DEFAULT '',
+  UNIQUE(name)
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// MaintenanceSchemaUpdate is schema for table maintenance
+func MaintenanceSchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE maintenance (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  node_id                       INTEGER  NOT NULL,
+  status                        TEXT     NOT NULL,
+  entered_at                    TEXT     NOT NULL DEFAULT '',
+  strategy                      TEXT     NOT NULL DEFAULT '',
+  triggered_by                  TEXT     NOT NULL DEFAULT '',
+  message                       TEXT     NOT NULL DEFAULT '',
+  FOREIGN KEY (node_id) REFERENCES "nodes" (id) ON DELETE CASCADE
+  UNIQUE(node_id)
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// NodeInventorySchemaUpdate is schema for table node_inventory
+func NodeInventorySchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE node_inventory (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  node_id                       INTEGER  NOT NULL,
+  revision                      INTEGER  NOT NULL,
+  collected_at                  TEXT     NOT NULL,
+  refreshed_at                  TEXT     NOT NULL,
+  data                          TEXT     NOT NULL,
+  FOREIGN KEY (node_id) REFERENCES "nodes" (id) ON DELETE CASCADE
+  UNIQUE(node_id, revision)
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// AddExpiryToMaintenance is schema update for table maintenance
+func AddExpiryToMaintenance(_ context.Context, tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE maintenance ADD COLUMN expires_at TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE maintenance ADD COLUMN auto_disable_attempts INTEGER NOT NULL DEFAULT 0;`,
+		`ALTER TABLE maintenance ADD COLUMN next_attempt_at TEXT NOT NULL DEFAULT '';`,
+	}
+
+	for _, stmt := range stmts {
+		_, err := tx.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ConfigHistorySchemaUpdate is schema for table config_history
+func ConfigHistorySchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE config_history (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  key                           TEXT     NOT NULL,
+  revision                      INTEGER  NOT NULL,
+  value                         TEXT     NOT NULL,
+  changed_at                    TEXT     NOT NULL,
+  changed_by                    TEXT     NOT NULL DEFAULT '',
+  UNIQUE(key, revision)
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// MaintenanceScheduleSchemaUpdate is schema for table maintenance_schedule
+func MaintenanceScheduleSchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE maintenance_schedule (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  node_id                       INTEGER  NOT NULL,
+  window_start                  TEXT     NOT NULL,
+  window_end                    TEXT     NOT NULL DEFAULT '',
+  options                       TEXT     NOT NULL DEFAULT '{}',
+  status                        TEXT     NOT NULL,
+  created_at                    TEXT     NOT NULL,
+  created_by                    TEXT     NOT NULL DEFAULT '',
+  started_at                    TEXT     NOT NULL DEFAULT '',
+  finished_at                   TEXT     NOT NULL DEFAULT '',
+  message                       TEXT     NOT NULL DEFAULT '',
+  FOREIGN KEY (node_id) REFERENCES "nodes" (id) ON DELETE CASCADE
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// AddCordonedToNodes adds the cordoned flag to table nodes
+func AddCordonedToNodes(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE nodes ADD COLUMN cordoned INTEGER NOT NULL DEFAULT 0;
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// MaintenancePlanSchemaUpdate is schema for table maintenance_plan
+func MaintenancePlanSchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE maintenance_plan (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  node_id                       INTEGER  NOT NULL,
+  operation                     TEXT     NOT NULL,
+  audit                         TEXT     NOT NULL DEFAULT '',
+  action_plan                   TEXT     NOT NULL DEFAULT '',
+  state                         TEXT     NOT NULL,
+  reason                        TEXT     NOT NULL DEFAULT '',
+  actions                       TEXT     NOT NULL DEFAULT '[]',
+  efficacy                      TEXT     NOT NULL DEFAULT '[]',
+  created_at                    TEXT     NOT NULL,
+  created_by                    TEXT     NOT NULL DEFAULT '',
+  FOREIGN KEY (node_id) REFERENCES "nodes" (id) ON DELETE CASCADE
+  UNIQUE(node_id, operation)
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// JoinTokenScopesSchemaUpdate is schema for table join_token_scopes
+func JoinTokenScopesSchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE join_token_scopes (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  name                          TEXT     NOT NULL,
+  roles                         TEXT     NOT NULL,
+  uses                          INTEGER  NOT NULL,
+  used                          INTEGER  NOT NULL DEFAULT 0,
+  expires_at                    TEXT     NOT NULL DEFAULT '',
+  created_at                    TEXT     NOT NULL,
+  created_by                    TEXT     NOT NULL DEFAULT '',
+  UNIQUE(name)
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// AddAddressesToNodes adds the named addresses and the preferred API address
+// to table nodes
+func AddAddressesToNodes(_ context.Context, tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE nodes ADD COLUMN addresses TEXT NOT NULL DEFAULT '{}';`,
+		`ALTER TABLE nodes ADD COLUMN preferred_api TEXT NOT NULL DEFAULT '';`,
+	}
+
+	for _, stmt := range stmts {
+		_, err := tx.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// OperationsSchemaUpdate is schema for table operations
+func OperationsSchemaUpdate(_ context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE operations (
+  id                            INTEGER  PRIMARY KEY AUTOINCREMENT NOT NULL,
+  member                        TEXT     NOT NULL,
+  command                       TEXT     NOT NULL,
+  status                        TEXT     NOT NULL,
+  progress                      TEXT     NOT NULL DEFAULT '',
+  result                        TEXT     NOT NULL DEFAULT '',
+  error                         TEXT     NOT NULL DEFAULT '',
+  created_at                    TEXT     NOT NULL,
+  created_by                    TEXT     NOT NULL DEFAULT '',
+  started_at                    TEXT     NOT NULL DEFAULT '',
+  finished_at                   TEXT     NOT NULL DEFAULT ''
+);
+  `
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// AddOperationRunners adds the runner claiming an operation and its last
+// heartbeat
+func AddOperationRunners(_ context.Context, tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE operations ADD COLUMN runner TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE operations ADD COLUMN heartbeat_at TEXT NOT NULL DEFAULT '';`,
+	}
+
+	for _, stmt := range stmts {
+		_, err := tx.Exec(stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AddMaintenanceDisableOperation adds the operation disabling maintenance
+// once its TTL elapsed to table maintenance
+func AddMaintenanceDisableOperation(_ context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE maintenance ADD COLUMN disable_operation INTEGER NOT NULL DEFAULT 0;`
+
+	_, err := tx.Exec(stmt)
+	return err
+}
+
+// AddScheduledMaintenanceOperation adds the operation enabling maintenance
+// once its window opened to table maintenance_schedule
+func AddScheduledMaintenanceOperation(_ context.Context, tx *sql.Tx) error {
+	stmt := `ALTER TABLE maintenance_schedule ADD COLUMN operation INTEGER NOT NULL DEFAULT 0;`
+
+	_, err := tx.Exec(stmt)
+	return err
+}
@@ -0,0 +1,82 @@
+// Package logging provides the structured loggers of clusterd, carrying the
+// ID and endpoint of the request they log for.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// requestIDPattern matches the request IDs accepted from the clients, other
+// IDs are replaced so that they cannot forge log lines
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type contextKey struct{}
+
+// Setup makes the default logger write key=value lines to w, at the level
+// selected by the daemon flags.
+func Setup(w io.Writer, verbose bool, debug bool) {
+	level := slog.LevelWarn
+	if debug {
+		level = slog.LevelDebug
+	} else if verbose {
+		level = slog.LevelInfo
+	}
+
+	slog.SetDefault(slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})))
+}
+
+// RequestID returns the ID of a request, from its request ID header. A new
+// ID is generated and set in the header if it has no valid one, so that the
+// access and endpoint handlers of the request log the same ID.
+func RequestID(r *http.Request) string {
+	id := r.Header.Get(apitypes.RequestIDHeader)
+	if requestIDPattern.MatchString(id) {
+		return id
+	}
+
+	id = newRequestID()
+	r.Header.Set(apitypes.RequestIDHeader, id)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// FromRequest returns the logger of a request, the one of its context if
+// any, a new one logging its ID, method and endpoint otherwise.
+func FromRequest(r *http.Request) *slog.Logger {
+	if l, ok := r.Context().Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return slog.Default().With(
+		"request_id", RequestID(r),
+		"method", r.Method,
+		"endpoint", r.URL.Path,
+	)
+}
+
+// NewContext returns a copy of ctx carrying the logger l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, the default one if none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+
+	return slog.Default()
+}
@@ -0,0 +1,121 @@
+// Package metrics provides the Prometheus collectors exposed by clusterd.
+package metrics
+
+import (
+	"maps"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "sunbeam"
+
+var (
+	// Registry holds all clusterd collectors, served on the metrics endpoint.
+	Registry = prometheus.NewRegistry()
+
+	// RequestsTotal counts API requests by handler, method and status code.
+	RequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of API requests by handler, method and status code.",
+	}, []string{"handler", "method", "code"})
+
+	// RequestDuration tracks API request latencies by handler and method.
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of API requests by handler and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler", "method"})
+
+	// Nodes is the number of cluster nodes by role.
+	Nodes = &NodesCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "nodes"),
+			"Number of cluster nodes by role.",
+			[]string{"role"}, nil,
+		),
+	}
+
+	// ConfigOperationsTotal counts config reads and writes.
+	ConfigOperationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "config",
+		Name:      "operations_total",
+		Help:      "Number of config operations by type.",
+	}, []string{"operation"})
+
+	// TerraformLockContentionTotal counts attempts to lock an already locked
+	// terraform plan.
+	TerraformLockContentionTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "terraform",
+		Name:      "lock_contention_total",
+		Help:      "Number of lock attempts on an already locked terraform plan.",
+	}, []string{"plan"})
+
+	// DatabaseTransactionDuration tracks the duration of database transactions.
+	DatabaseTransactionDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "database",
+		Name:      "transaction_duration_seconds",
+		Help:      "Duration of database transactions.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// Config operation label values.
+const (
+	ConfigRead  = "read"
+	ConfigWrite = "write"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RequestsTotal,
+		RequestDuration,
+		Nodes,
+		ConfigOperationsTotal,
+		TerraformLockContentionTotal,
+		DatabaseTransactionDuration,
+	)
+}
+
+// NodesCollector reports the number of cluster nodes by role, as last set.
+// The counts are replaced as a whole, so that a scrape never reports those
+// of a concurrent scrape half counted.
+type NodesCollector struct {
+	desc *prometheus.Desc
+
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// Set replaces the node counts by role.
+func (c *NodesCollector) Set(counts map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.counts = maps.Clone(counts)
+}
+
+// Describe implements prometheus.Collector.
+func (c *NodesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect implements prometheus.Collector.
+func (c *NodesCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for role, count := range c.counts {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(count), role)
+	}
+}
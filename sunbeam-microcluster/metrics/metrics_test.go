@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestNodesCollector tests that setting the node counts replaces those of
+// the roles no longer reported.
+func TestNodesCollector(t *testing.T) {
+	c := &NodesCollector{desc: Nodes.desc}
+
+	c.Set(map[string]int{"control": 1, "compute": 2})
+	c.Set(map[string]int{"compute": 3})
+
+	expected := `
+# HELP sunbeam_nodes Number of cluster nodes by role.
+# TYPE sunbeam_nodes gauge
+sunbeam_nodes{role="compute"} 3
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,380 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// secretConfigKeys are the config keys holding credentials
+var secretConfigKeys = []string{"K8SKubeConfig", "VaultDevModeInfo", WebhooksConfigKey}
+
+// secretConfigPrefixes are the config key prefixes of items holding
+// credentials, terraform states embed the secrets of the deployed resources
+var secretConfigPrefixes = []string{tfstatePrefix}
+
+// ExportBackup returns a snapshot of the cluster database state, taken in a
+// single transaction. If redactSecrets is set, juju user tokens, storage
+// backend configs and config items holding credentials are redacted.
+func ExportBackup(ctx context.Context, s state.State, redactSecrets bool) (apitypes.Backup, error) {
+	var backup apitypes.Backup
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		backup, err = exportBackup(ctx, tx, time.Now())
+		return err
+	})
+	if err != nil {
+		return apitypes.Backup{}, err
+	}
+
+	if redactSecrets {
+		redactBackup(&backup)
+	}
+
+	return backup, nil
+}
+
+// exportBackup returns a snapshot of the database state in tx taken at now
+func exportBackup(ctx context.Context, tx *sql.Tx, now time.Time) (apitypes.Backup, error) {
+	backup := apitypes.Backup{
+		Version:   apitypes.BackupVersion,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+	}
+
+	var err error
+	backup.SchemaInternal, backup.SchemaExternal, err = database.GetSchemaVersions(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, err
+	}
+
+	nodes, err := database.GetNodes(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch nodes: %w", err)
+	}
+
+	backup.Nodes = make(apitypes.Nodes, 0, len(nodes))
+	for _, node := range nodes {
+		nodeRole, err := roleFromStr(node.Role)
+		if err != nil {
+			return apitypes.Backup{}, err
+		}
+
+		nodeLabels, err := labelsFromStr(node.Labels)
+		if err != nil {
+			return apitypes.Backup{}, err
+		}
+
+		nodeAddresses, err := addressesFromStr(node.Addresses)
+		if err != nil {
+			return apitypes.Backup{}, err
+		}
+
+		backup.Nodes = append(backup.Nodes, apitypes.Node{
+			Name:         node.Name,
+			Role:         nodeRole,
+			MachineID:    node.MachineID,
+			SystemID:     node.SystemID,
+			Member:       node.Member,
+			Labels:       nodeLabels,
+			Cordoned:     node.Cordoned,
+			Addresses:    nodeAddresses,
+			PreferredAPI: node.PreferredAPI,
+		})
+	}
+
+	items, err := database.GetConfigItems(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch config items: %w", err)
+	}
+
+	backup.Config = make([]apitypes.BackupConfigItem, 0, len(items))
+	for _, item := range items {
+		backup.Config = append(backup.Config, apitypes.BackupConfigItem{Key: item.Key, Value: item.Value, Revision: item.Revision})
+	}
+
+	manifests, err := database.GetManifestItems(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch manifests: %w", err)
+	}
+
+	backup.Manifests = make(apitypes.Manifests, 0, len(manifests))
+	for _, manifest := range manifests {
+		backup.Manifests = append(backup.Manifests, apitypes.Manifest{ManifestID: manifest.ManifestID, AppliedDate: manifest.AppliedDate, Data: manifest.Data})
+	}
+
+	users, err := database.GetJujuUsers(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch juju users: %w", err)
+	}
+
+	backup.JujuUsers = make(apitypes.JujuUsers, 0, len(users))
+	for _, user := range users {
+		backup.JujuUsers = append(backup.JujuUsers, apitypes.JujuUser{Username: user.Username, Token: user.Token})
+	}
+
+	backends, err := database.GetStorageBackends(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch storage backends: %w", err)
+	}
+
+	backup.StorageBackends = make(apitypes.StorageBackends, 0, len(backends))
+	for _, backend := range backends {
+		backup.StorageBackends = append(backup.StorageBackends, apitypes.StorageBackend{
+			Name:      backend.Name,
+			Type:      backend.Type,
+			Config:    backend.Config,
+			Principal: backend.Principal,
+			ModelUUID: backend.ModelUUID,
+		})
+	}
+
+	gates, err := database.GetFeatureGates(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch feature gates: %w", err)
+	}
+
+	backup.FeatureGates = make(apitypes.FeatureGates, 0, len(gates))
+	for _, gate := range gates {
+		backup.FeatureGates = append(backup.FeatureGates, apitypes.FeatureGate{GateKey: gate.GateKey, Enabled: gate.Enabled})
+	}
+
+	tombstones, err := database.GetNodeTombstones(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch removed nodes: %w", err)
+	}
+
+	backup.RemovedNodes = make(apitypes.Nodes, 0, len(tombstones))
+	for _, tombstone := range tombstones {
+		node, err := nodeFromTombstone(tombstone)
+		if err != nil {
+			return apitypes.Backup{}, err
+		}
+
+		backup.RemovedNodes = append(backup.RemovedNodes, node)
+	}
+
+	scopes, err := database.GetJoinTokenScopes(ctx, tx)
+	if err != nil {
+		return apitypes.Backup{}, fmt.Errorf("Failed to fetch join token scopes: %w", err)
+	}
+
+	backup.JoinTokenScopes = make(apitypes.JoinTokenScopes, 0, len(scopes))
+	for _, record := range scopes {
+		scope, err := joinTokenScopeFromRecord(record, now)
+		if err != nil {
+			return apitypes.Backup{}, err
+		}
+
+		backup.JoinTokenScopes = append(backup.JoinTokenScopes, scope)
+	}
+
+	return backup, nil
+}
+
+// RestoreBackup replaces the cluster database state with the one of backup.
+// The schema versions are checked and the state replaced in a single
+// transaction, so a failed restore leaves the current state untouched.
+// Nodes recorded by members absent from the cluster are attributed to the
+// local member. The operations of the replaced state are dropped.
+func RestoreBackup(ctx context.Context, s state.State, backup apitypes.Backup) error {
+	err := ValidateBackup(backup)
+	if err != nil {
+		return err
+	}
+
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		return restoreBackup(ctx, tx, backup, s.Name())
+	})
+}
+
+// restoreBackup replaces the database state in tx with the one of backup,
+// attributing the nodes of absent members to the local member.
+func restoreBackup(ctx context.Context, tx *sql.Tx, backup apitypes.Backup, local string) error {
+	internal, external, err := database.GetSchemaVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	err = CheckBackupSchema(backup, internal, external)
+	if err != nil {
+		return err
+	}
+
+	members, err := database.GetClusterMemberNames(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	err = database.DeleteBackupState(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range backup.Nodes {
+		nodeRole, err := roleToStr(node.Role)
+		if err != nil {
+			return err
+		}
+
+		nodeLabels, err := labelsToStr(node.Labels)
+		if err != nil {
+			return err
+		}
+
+		nodeAddresses, err := addressesToStr(node.Addresses)
+		if err != nil {
+			return err
+		}
+
+		member := node.Member
+		if !slices.Contains(members, member) {
+			member = local
+		}
+
+		_, err = database.CreateNode(ctx, tx, database.Node{Member: member, Name: node.Name, Role: nodeRole, MachineID: node.MachineID, SystemID: node.SystemID, Labels: nodeLabels, Cordoned: node.Cordoned, Addresses: nodeAddresses, PreferredAPI: node.PreferredAPI})
+		if err != nil {
+			return fmt.Errorf("Failed to restore node %q: %w", node.Name, err)
+		}
+	}
+
+	for _, item := range backup.Config {
+		_, err := database.CreateConfigItem(ctx, tx, database.ConfigItem{Key: item.Key, Value: item.Value, Revision: item.Revision})
+		if err != nil {
+			return fmt.Errorf("Failed to restore config item %q: %w", item.Key, err)
+		}
+	}
+
+	for _, manifest := range backup.Manifests {
+		err := database.RestoreManifestItem(ctx, tx, database.ManifestItem{ManifestID: manifest.ManifestID, AppliedDate: manifest.AppliedDate, Data: manifest.Data})
+		if err != nil {
+			return fmt.Errorf("Failed to restore manifest %q: %w", manifest.ManifestID, err)
+		}
+	}
+
+	for _, user := range backup.JujuUsers {
+		_, err := database.CreateJujuUser(ctx, tx, database.JujuUser{Username: user.Username, Token: user.Token})
+		if err != nil {
+			return fmt.Errorf("Failed to restore juju user %q: %w", user.Username, err)
+		}
+	}
+
+	for _, backend := range backup.StorageBackends {
+		_, err := database.CreateStorageBackend(ctx, tx, database.StorageBackend{
+			Name:      backend.Name,
+			Type:      backend.Type,
+			Config:    backend.Config,
+			Principal: backend.Principal,
+			ModelUUID: backend.ModelUUID,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to restore storage backend %q: %w", backend.Name, err)
+		}
+	}
+
+	for _, gate := range backup.FeatureGates {
+		_, err := database.CreateFeatureGate(ctx, tx, database.FeatureGate{GateKey: gate.GateKey, Enabled: gate.Enabled})
+		if err != nil {
+			return fmt.Errorf("Failed to restore feature gate %q: %w", gate.GateKey, err)
+		}
+	}
+
+	for _, node := range backup.RemovedNodes {
+		nodeRole, err := roleToStr(node.Role)
+		if err != nil {
+			return err
+		}
+
+		nodeLabels, err := labelsToStr(node.Labels)
+		if err != nil {
+			return err
+		}
+
+		err = database.CreateNodeTombstone(ctx, tx, database.NodeTombstone{Name: node.Name, Member: node.Member, Role: nodeRole, MachineID: node.MachineID, SystemID: node.SystemID, Labels: nodeLabels, RemovedAt: node.RemovedAt, RemovedReason: node.RemovedReason, RemovedBy: node.RemovedBy})
+		if err != nil {
+			return fmt.Errorf("Failed to restore removed node %q: %w", node.Name, err)
+		}
+	}
+
+	for _, scope := range backup.JoinTokenScopes {
+		roles, err := json.Marshal(scope.Roles)
+		if err != nil {
+			return err
+		}
+
+		err = database.UpsertJoinTokenScope(ctx, tx, database.JoinTokenScope{Name: scope.Name, Roles: string(roles), Uses: scope.Uses, Used: scope.Used, ExpiresAt: scope.ExpiresAt, CreatedAt: scope.CreatedAt, CreatedBy: scope.CreatedBy})
+		if err != nil {
+			return fmt.Errorf("Failed to restore join token scope of node %q: %w", scope.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateBackup checks that backup can be restored by this daemon
+func ValidateBackup(backup apitypes.Backup) error {
+	if backup.Version != apitypes.BackupVersion {
+		return api.StatusErrorf(http.StatusBadRequest, "Unsupported backup version %d, expected %d", backup.Version, apitypes.BackupVersion)
+	}
+
+	if backup.Redacted {
+		return api.StatusErrorf(http.StatusBadRequest, "Cannot restore a backup with redacted secrets")
+	}
+
+	return nil
+}
+
+// CheckBackupSchema checks that backup was taken with the given schema
+// versions, returning a 409 StatusError otherwise.
+func CheckBackupSchema(backup apitypes.Backup, internal int, external int) error {
+	if backup.SchemaInternal != internal || backup.SchemaExternal != external {
+		return api.StatusErrorf(http.StatusConflict, "Backup schema version mismatch: backup has internal %d and external %d, cluster has internal %d and external %d", backup.SchemaInternal, backup.SchemaExternal, internal, external)
+	}
+
+	return nil
+}
+
+// IsSecretConfigKey returns whether the config item key holds credentials,
+// such items are not readable with a read-only token
+func IsSecretConfigKey(key string) bool {
+	if slices.Contains(secretConfigKeys, key) {
+		return true
+	}
+
+	for _, prefix := range secretConfigPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactBackup replaces the secrets held in backup by RedactedValue
+func redactBackup(backup *apitypes.Backup) {
+	backup.Redacted = true
+
+	for i, item := range backup.Config {
+		if IsSecretConfigKey(item.Key) {
+			backup.Config[i].Value = apitypes.RedactedValue
+		}
+	}
+
+	for i := range backup.JujuUsers {
+		backup.JujuUsers[i].Token = apitypes.RedactedValue
+	}
+
+	for i := range backup.StorageBackends {
+		backup.StorageBackends[i].Config = apitypes.RedactedValue
+	}
+}
@@ -0,0 +1,199 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestValidateBackup tests that only unredacted bundles of the current
+// version are accepted
+func TestValidateBackup(t *testing.T) {
+	testCases := []struct {
+		name   string
+		backup apitypes.Backup
+		valid  bool
+	}{
+		{name: "current version", backup: apitypes.Backup{Version: apitypes.BackupVersion}, valid: true},
+		{name: "missing version", backup: apitypes.Backup{}, valid: false},
+		{name: "newer version", backup: apitypes.Backup{Version: apitypes.BackupVersion + 1}, valid: false},
+		{name: "redacted", backup: apitypes.Backup{Version: apitypes.BackupVersion, Redacted: true}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateBackup(tc.backup)
+			if tc.valid && err != nil {
+				t.Errorf("Expected backup to be valid, got %v", err)
+			}
+
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestCheckBackupSchema tests that a restore into a cluster with another
+// schema version is rejected
+func TestCheckBackupSchema(t *testing.T) {
+	backup := apitypes.Backup{Version: apitypes.BackupVersion, SchemaInternal: 4, SchemaExternal: 11}
+
+	err := CheckBackupSchema(backup, 4, 11)
+	if err != nil {
+		t.Errorf("Unexpected error for matching schema: %v", err)
+	}
+
+	for _, versions := range [][2]int{{4, 10}, {4, 12}, {5, 11}} {
+		err := CheckBackupSchema(backup, versions[0], versions[1])
+		if !api.StatusErrorCheck(err, http.StatusConflict) {
+			t.Errorf("Expected conflict error for schema %v, got %v", versions, err)
+		}
+	}
+}
+
+// TestRedactBackup tests that only secrets are replaced
+func TestRedactBackup(t *testing.T) {
+	backup := apitypes.Backup{
+		Version: apitypes.BackupVersion,
+		Config: []apitypes.BackupConfigItem{
+			{Key: "tfstate-openstack-plan", Value: `{"resources": []}`},
+			{Key: "tflock-openstack-plan", Value: `{"ID": "lock-a"}`},
+			{Key: "K8SKubeConfig", Value: "apiVersion: v1"},
+			{Key: "TerraformVarsOpenstack", Value: `{"region": "RegionOne"}`},
+		},
+		JujuUsers:       apitypes.JujuUsers{{Username: "admin", Token: "token"}},
+		StorageBackends: apitypes.StorageBackends{{Name: "purestorage", Type: "pure", Config: `{"api-token": "x"}`}},
+	}
+
+	redactBackup(&backup)
+
+	if !backup.Redacted {
+		t.Error("Expected backup to be marked as redacted")
+	}
+
+	expected := []string{apitypes.RedactedValue, `{"ID": "lock-a"}`, apitypes.RedactedValue, `{"region": "RegionOne"}`}
+	for i, item := range backup.Config {
+		if item.Value != expected[i] {
+			t.Errorf("Config %q: expected %q, got %q", item.Key, expected[i], item.Value)
+		}
+	}
+
+	if backup.JujuUsers[0].Username != "admin" || backup.JujuUsers[0].Token != apitypes.RedactedValue {
+		t.Errorf("Expected juju user token to be redacted, got %+v", backup.JujuUsers[0])
+	}
+
+	if backup.StorageBackends[0].Name != "purestorage" || backup.StorageBackends[0].Config != apitypes.RedactedValue {
+		t.Errorf("Expected storage backend config to be redacted, got %+v", backup.StorageBackends[0])
+	}
+
+	err := ValidateBackup(backup)
+	if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+		t.Errorf("Expected redacted backup to be rejected, got %v", err)
+	}
+}
+
+// TestRestoreBackup tests that a restore brings back the removed nodes and
+// join token scopes of the backup, and drops the operations requested since
+func TestRestoreBackup(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	schema := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `CREATE TABLE schemas (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, version INTEGER NOT NULL, type INTEGER NOT NULL)`)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO schemas (version, type) VALUES (?, 0), (?, 1)`, 4, len(database.SchemaExtensions))
+		return err
+	}
+
+	var backup apitypes.Backup
+	err := fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		err := schema(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = database.CreateNodeTombstone(ctx, tx, database.NodeTombstone{Name: "node-9", Member: "node-1", Role: `["compute"]`, MachineID: 9, Labels: "{}", RemovedAt: "2025-02-01T00:00:00Z", RemovedReason: "decommissioned", RemovedBy: "ubuntu@node-1"})
+		if err != nil {
+			return err
+		}
+
+		err = database.UpsertJoinTokenScope(ctx, tx, database.JoinTokenScope{Name: "node-3", Roles: `["compute"]`, Uses: 1, CreatedAt: "2025-03-01T11:00:00Z", CreatedBy: "ubuntu@node-1"})
+		if err != nil {
+			return err
+		}
+
+		backup, err = exportBackup(ctx, tx, now)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backup.RemovedNodes) != 1 || backup.RemovedNodes[0].RemovedReason != "decommissioned" || len(backup.JoinTokenScopes) != 1 {
+		t.Fatalf("Expected the removed node and the join token scope in the backup, got %+v", backup)
+	}
+
+	// Changed since the backup
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		err := removeNodeRecord(ctx, tx, "node-2", now, "failed", "ubuntu@node-1")
+		if err != nil {
+			return err
+		}
+
+		err = database.UpsertJoinTokenScope(ctx, tx, database.JoinTokenScope{Name: "node-4", Roles: `["storage"]`, Uses: 1, CreatedAt: "2025-03-01T12:00:00Z"})
+		if err != nil {
+			return err
+		}
+
+		_, err = createOperation(txOperationStore{ctx: ctx, tx: tx}, []string{"cluster", "maintenance", "disable", "--yes", "node-1"}, "node-1", maintenanceExpiryActor, now)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		return restoreBackup(ctx, tx, backup, "node-1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		for table, want := range map[string]string{
+			`SELECT name FROM nodes ORDER BY name`:             "node-1,node-2",
+			`SELECT name FROM node_tombstones ORDER BY name`:   "node-9",
+			`SELECT name FROM join_token_scopes ORDER BY name`: "node-3",
+		} {
+			names, err := query.SelectStrings(ctx, tx, table)
+			if err != nil {
+				return err
+			}
+
+			if got := strings.Join(names, ","); got != want {
+				t.Errorf("Expected %s from %q, got %s", want, table, got)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := countRows(t, db, "operations"); count != 0 {
+		t.Errorf("Expected the operations dropped, got %d", count)
+	}
+}
@@ -0,0 +1,343 @@
+package sunbeam
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/cluster"
+	"github.com/canonical/microcluster/v2/rest/types"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+// clusterCAKey is the config key of the CA trusted for the extended endpoints
+const clusterCAKey = "cluster-ca"
+
+// ValidateClusterCertificate checks that certificate is a valid keypair with
+// key, and that its chain verifies against ca. A certificate without ca
+// must be self-signed. Intermediate certificates follow the leaf one in
+// certificate. The leaf certificate is returned.
+func ValidateClusterCertificate(certificate string, key string, ca string, now time.Time) (*x509.Certificate, error) {
+	keypair, err := tls.X509KeyPair([]byte(certificate), []byte(key))
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid certificate and key pair: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(keypair.Certificate[0])
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid certificate: %v", err)
+	}
+
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Certificate is only valid from %s to %s", leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	if ca == "" {
+		err = leaf.CheckSignature(leaf.SignatureAlgorithm, leaf.RawTBSCertificate, leaf.Signature)
+		if err != nil {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Certificate must be self-signed without a CA: %v", err)
+		}
+
+		return leaf, nil
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(ca)) {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "CA must be PEM encoded certificates")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, der := range keypair.Certificate[1:] {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid intermediate certificate: %v", err)
+		}
+		intermediates.AddCert(cert)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Certificate chain does not verify against the CA: %v", err)
+	}
+
+	return leaf, nil
+}
+
+// clusterCA returns the configured cluster CA, empty if there is none
+func clusterCA(ctx context.Context, s state.State) (string, error) {
+	value, err := GetConfig(ctx, s, clusterCAKey)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	// The CA is stored as a JSON string by the client
+	var ca string
+	if json.Unmarshal([]byte(value), &ca) != nil {
+		ca = value
+	}
+
+	return ca, nil
+}
+
+// validateCertificatePut validates the certificate of req against the CA of
+// req, or else the cluster CA. The leaf certificate is returned.
+func validateCertificatePut(ctx context.Context, s state.State, req apitypes.ClusterCertificatePut) (*x509.Certificate, error) {
+	ca := req.CA
+	if ca == "" {
+		var err error
+		ca, err = clusterCA(ctx, s)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetch cluster CA: %w", err)
+		}
+	}
+
+	return ValidateClusterCertificate(req.Certificate, req.PrivateKey, ca, time.Now())
+}
+
+// UpdateClusterCertificate validates the certificate of req and passes it to
+// reload to be served by this member, unless it is only validated.
+func UpdateClusterCertificate(ctx context.Context, s state.State, req apitypes.ClusterCertificatePut, reload func(context.Context, types.KeyPair) error) error {
+	_, err := validateCertificatePut(ctx, s, req)
+	if err != nil {
+		return err
+	}
+
+	if req.ValidateOnly {
+		return nil
+	}
+
+	return reload(ctx, types.KeyPair{Cert: req.Certificate, Key: req.PrivateKey, CA: req.CA})
+}
+
+// certificateRotator validates and applies a new cluster certificate on the
+// cluster members.
+type certificateRotator interface {
+	// Validate checks that member accepts the new certificate
+	Validate(member string) error
+	// Apply makes member serve the new certificate
+	Apply(member string) error
+	// Serving returns which certificate member serves
+	Serving(member string) (string, error)
+}
+
+// rotateClusterCertificate validates the new certificate on all members,
+// before applying it to each member in turn. The rotation halts on the
+// first failure, the response then reports the failed member and which
+// certificate each member serves, to be rotated again once fixed.
+func rotateClusterCertificate(l *slog.Logger, rotator certificateRotator, members []string) apitypes.ClusterCertificateRotation {
+	var resp apitypes.ClusterCertificateRotation
+
+	for _, member := range members {
+		err := rotator.Validate(member)
+		if err != nil {
+			resp.Failed = member
+			resp.Error = fmt.Sprintf("Member %q rejected the certificate: %v", member, err)
+			break
+		}
+	}
+
+	if resp.Failed == "" {
+		for _, member := range members {
+			err := rotator.Apply(member)
+			if err != nil {
+				resp.Failed = member
+				resp.Error = fmt.Sprintf("Member %q failed to reload the certificate: %v", member, err)
+				break
+			}
+		}
+	}
+
+	resp.Members = make([]apitypes.ClusterCertificateMember, 0, len(members))
+	for _, member := range members {
+		certificate, err := rotator.Serving(member)
+		if err != nil {
+			l.Warn("Failed to check the certificate of member", "member", member, "error", err)
+			certificate = apitypes.CertificateUnknown
+		}
+		resp.Members = append(resp.Members, apitypes.ClusterCertificateMember{Name: member, Certificate: certificate})
+	}
+
+	return resp
+}
+
+// memberRotator rotates the cluster certificate of the members over the
+// extended API. Members are reached trusting both the old and the new
+// certificates, so that a halted rotation can be run again.
+type memberRotator struct {
+	ctx       context.Context
+	s         state.State
+	req       apitypes.ClusterCertificatePut
+	addresses map[string]string
+	oldCert   *x509.Certificate
+	newCert   *x509.Certificate
+	reload    func(context.Context, types.KeyPair) error
+}
+
+func (m memberRotator) tlsConfig() *tls.Config {
+	keypair := m.s.ServerCert().KeyPair()
+	config := shared.InitTLSConfig()
+	config.Certificates = []tls.Certificate{keypair}
+	// Members serve the cluster certificate, pinned instead of verified
+	// against a CA and hostname
+	config.InsecureSkipVerify = true
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) > 0 && (bytes.Equal(rawCerts[0], m.oldCert.Raw) || bytes.Equal(rawCerts[0], m.newCert.Raw)) {
+			return nil
+		}
+		return errors.New("Member serves neither the old nor the new cluster certificate")
+	}
+
+	return config
+}
+
+// put sends req to the cluster certificate endpoint of member
+func (m memberRotator) put(member string, req apitypes.ClusterCertificatePut) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, 30*time.Second)
+	defer cancel()
+
+	url := api.NewURL().Scheme("https").Host(m.addresses[member]).Path(string(apitypes.ExtendedPathPrefix), "certificates", "cluster")
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPut, url.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: m.tlsConfig(), DisableKeepAlives: true}}
+	httpResp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		var resp api.Response
+		err = json.NewDecoder(httpResp.Body).Decode(&resp)
+		if err != nil || resp.Error == "" {
+			return fmt.Errorf("Unexpected response status %q", httpResp.Status)
+		}
+		return errors.New(resp.Error)
+	}
+
+	return nil
+}
+
+func (m memberRotator) Validate(member string) error {
+	req := m.req
+	req.ValidateOnly = true
+	if member == m.s.Name() {
+		return UpdateClusterCertificate(m.ctx, m.s, req, m.reload)
+	}
+
+	return m.put(member, req)
+}
+
+func (m memberRotator) Apply(member string) error {
+	if member == m.s.Name() {
+		return UpdateClusterCertificate(m.ctx, m.s, m.req, m.reload)
+	}
+
+	return m.put(member, m.req)
+}
+
+func (m memberRotator) Serving(member string) (string, error) {
+	ctx, cancel := context.WithTimeout(m.ctx, 10*time.Second)
+	defer cancel()
+
+	dialer := tls.Dialer{Config: m.tlsConfig()}
+	conn, err := dialer.DialContext(ctx, "tcp", m.addresses[member])
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	peerCerts := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peerCerts) > 0 && peerCerts[0].Equal(m.newCert) {
+		return apitypes.CertificateNew, nil
+	}
+
+	return apitypes.CertificateOld, nil
+}
+
+// RotateClusterCertificate rotates the cluster certificate of all the
+// cluster members to the one of req, this member last. See
+// rotateClusterCertificate for how a failure is handled.
+func RotateClusterCertificate(ctx context.Context, s state.State, req apitypes.ClusterCertificatePut, reload func(context.Context, types.KeyPair) error) (apitypes.ClusterCertificateRotation, error) {
+	var resp apitypes.ClusterCertificateRotation
+
+	newCert, err := validateCertificatePut(ctx, s, req)
+	if err != nil {
+		return resp, err
+	}
+
+	oldCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return resp, fmt.Errorf("Failed to parse cluster certificate: %w", err)
+	}
+
+	addresses := map[string]string{}
+	var members []string
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch cluster members: %w", err)
+		}
+
+		for _, record := range records {
+			if record.Role == cluster.Pending {
+				continue
+			}
+			addresses[record.Name] = record.Address
+			if record.Name != s.Name() {
+				members = append(members, record.Name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	// Like microcluster, peers are rotated before this member
+	sort.Strings(members)
+	members = append(members, s.Name())
+
+	rotator := memberRotator{
+		ctx:       ctx,
+		s:         s,
+		req:       req,
+		addresses: addresses,
+		oldCert:   oldCert,
+		newCert:   newCert,
+		reload:    reload,
+	}
+
+	return rotateClusterCertificate(logging.FromContext(ctx), rotator, members), nil
+}
@@ -0,0 +1,303 @@
+package sunbeam
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/rest/types"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// testCert is a certificate and its key, PEM encoded
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCert returns a certificate valid from notBefore to notAfter, signed
+// by parent or else self-signed
+func newTestCert(t *testing.T, name string, isCA bool, parent *testCert, notBefore time.Time, notAfter time.Time) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+// TestValidateClusterCertificate tests that only certificates matching their
+// key, currently valid and chaining to the CA are accepted
+func TestValidateClusterCertificate(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(time.Hour)
+
+	ca := newTestCert(t, "ca", true, nil, notBefore, notAfter)
+	otherCA := newTestCert(t, "other-ca", true, nil, notBefore, notAfter)
+	intermediate := newTestCert(t, "intermediate", true, ca, notBefore, notAfter)
+	signed := newTestCert(t, "cluster", false, ca, notBefore, notAfter)
+	chained := newTestCert(t, "cluster", false, intermediate, notBefore, notAfter)
+	selfSigned := newTestCert(t, "cluster", false, nil, notBefore, notAfter)
+	expired := newTestCert(t, "cluster", false, ca, now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	testCases := []struct {
+		name  string
+		cert  string
+		key   string
+		ca    string
+		valid bool
+	}{
+		{name: "signed by CA", cert: signed.certPEM, key: signed.keyPEM, ca: ca.certPEM, valid: true},
+		{name: "chained to CA", cert: chained.certPEM + intermediate.certPEM, key: chained.keyPEM, ca: ca.certPEM, valid: true},
+		{name: "self-signed without CA", cert: selfSigned.certPEM, key: selfSigned.keyPEM, valid: true},
+		{name: "missing intermediate", cert: chained.certPEM, key: chained.keyPEM, ca: ca.certPEM, valid: false},
+		{name: "signed by other CA", cert: signed.certPEM, key: signed.keyPEM, ca: otherCA.certPEM, valid: false},
+		{name: "signed without CA", cert: signed.certPEM, key: signed.keyPEM, valid: false},
+		{name: "self-signed with CA", cert: selfSigned.certPEM, key: selfSigned.keyPEM, ca: ca.certPEM, valid: false},
+		{name: "mismatched key", cert: signed.certPEM, key: selfSigned.keyPEM, ca: ca.certPEM, valid: false},
+		{name: "expired", cert: expired.certPEM, key: expired.keyPEM, ca: ca.certPEM, valid: false},
+		{name: "invalid CA", cert: signed.certPEM, key: signed.keyPEM, ca: "not a CA", valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			leaf, err := ValidateClusterCertificate(tc.cert, tc.key, tc.ca, now)
+			if tc.valid {
+				if err != nil {
+					t.Fatalf("Expected certificate to be valid, got %v", err)
+				}
+				if leaf.Subject.CommonName != "cluster" {
+					t.Errorf("Expected the leaf certificate, got %q", leaf.Subject.CommonName)
+				}
+				return
+			}
+
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestUpdateClusterCertificate tests that only a valid certificate is
+// reloaded, unless it is only validated
+func TestUpdateClusterCertificate(t *testing.T) {
+	now := time.Now()
+	ca := newTestCert(t, "ca", true, nil, now.Add(-time.Hour), now.Add(time.Hour))
+	signed := newTestCert(t, "cluster", false, ca, now.Add(-time.Hour), now.Add(time.Hour))
+	other := newTestCert(t, "cluster", false, nil, now.Add(-time.Hour), now.Add(time.Hour))
+
+	testCases := []struct {
+		name     string
+		req      apitypes.ClusterCertificatePut
+		reloaded bool
+		valid    bool
+	}{
+		{
+			name:     "valid",
+			req:      apitypes.ClusterCertificatePut{Certificate: signed.certPEM, PrivateKey: signed.keyPEM, CA: ca.certPEM},
+			reloaded: true,
+			valid:    true,
+		},
+		{
+			name:  "validate only",
+			req:   apitypes.ClusterCertificatePut{Certificate: signed.certPEM, PrivateKey: signed.keyPEM, CA: ca.certPEM, ValidateOnly: true},
+			valid: true,
+		},
+		{
+			name: "invalid",
+			req:  apitypes.ClusterCertificatePut{Certificate: other.certPEM, PrivateKey: other.keyPEM, CA: ca.certPEM},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reloaded *types.KeyPair
+			reload := func(_ context.Context, keypair types.KeyPair) error {
+				reloaded = &keypair
+				return nil
+			}
+
+			// The cluster CA is not looked up when the request holds a CA
+			err := UpdateClusterCertificate(context.Background(), nil, tc.req, reload)
+			if tc.valid && err != nil {
+				t.Fatalf("Expected certificate to be valid, got %v", err)
+			}
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Fatalf("Expected bad request error, got %v", err)
+			}
+
+			if (reloaded != nil) != tc.reloaded {
+				t.Fatalf("Expected reloaded to be %v, got %v", tc.reloaded, reloaded != nil)
+			}
+			if reloaded != nil && (reloaded.Cert != signed.certPEM || reloaded.Key != signed.keyPEM || reloaded.CA != ca.certPEM) {
+				t.Errorf("Unexpected reloaded keypair")
+			}
+		})
+	}
+}
+
+// fakeRotator is a certificateRotator tracking the certificate served by
+// each member, failing as configured.
+type fakeRotator struct {
+	serving      map[string]string
+	validateErrs map[string]error
+	applyErrs    map[string]error
+	applied      []string
+}
+
+func (f *fakeRotator) Validate(member string) error {
+	return f.validateErrs[member]
+}
+
+func (f *fakeRotator) Apply(member string) error {
+	f.applied = append(f.applied, member)
+	err := f.applyErrs[member]
+	if err == nil {
+		f.serving[member] = apitypes.CertificateNew
+	}
+
+	return err
+}
+
+func (f *fakeRotator) Serving(member string) (string, error) {
+	certificate, ok := f.serving[member]
+	if !ok {
+		return "", errors.New("member is unreachable")
+	}
+
+	return certificate, nil
+}
+
+// TestRotateClusterCertificate tests that a rotation halts on the first
+// failing member, reporting which certificate each member serves
+func TestRotateClusterCertificate(t *testing.T) {
+	members := []string{"node1", "node2", "node3"}
+
+	testCases := []struct {
+		name         string
+		validateErrs map[string]error
+		applyErrs    map[string]error
+		down         []string
+		applied      []string
+		failed       string
+		serving      []string
+	}{
+		{
+			name:    "success",
+			applied: members,
+			serving: []string{apitypes.CertificateNew, apitypes.CertificateNew, apitypes.CertificateNew},
+		},
+		{
+			name:         "rejected",
+			validateErrs: map[string]error{"node3": errors.New("Certificate chain does not verify")},
+			failed:       "node3",
+			serving:      []string{apitypes.CertificateOld, apitypes.CertificateOld, apitypes.CertificateOld},
+		},
+		{
+			name:      "partial failure",
+			applyErrs: map[string]error{"node2": errors.New("failed to reload")},
+			applied:   []string{"node1", "node2"},
+			failed:    "node2",
+			serving:   []string{apitypes.CertificateNew, apitypes.CertificateOld, apitypes.CertificateOld},
+		},
+		{
+			name:      "partial failure with member down",
+			applyErrs: map[string]error{"node2": errors.New("connection reset")},
+			down:      []string{"node2"},
+			applied:   []string{"node1", "node2"},
+			failed:    "node2",
+			serving:   []string{apitypes.CertificateNew, apitypes.CertificateUnknown, apitypes.CertificateOld},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rotator := &fakeRotator{
+				serving:      map[string]string{},
+				validateErrs: tc.validateErrs,
+				applyErrs:    tc.applyErrs,
+			}
+			for _, member := range members {
+				rotator.serving[member] = apitypes.CertificateOld
+			}
+			for _, member := range tc.down {
+				delete(rotator.serving, member)
+			}
+
+			resp := rotateClusterCertificate(slog.Default(), rotator, members)
+
+			if !reflect.DeepEqual(rotator.applied, tc.applied) {
+				t.Errorf("Expected certificate applied to %v, got %v", tc.applied, rotator.applied)
+			}
+			if resp.Failed != tc.failed {
+				t.Errorf("Expected failed member %q, got %q", tc.failed, resp.Failed)
+			}
+			if (resp.Error != "") != (tc.failed != "") {
+				t.Errorf("Unexpected error %q", resp.Error)
+			}
+
+			if len(resp.Members) != len(members) {
+				t.Fatalf("Expected %d members, got %d", len(members), len(resp.Members))
+			}
+			for i, member := range resp.Members {
+				if member.Name != members[i] || member.Certificate != tc.serving[i] {
+					t.Errorf("Expected member %q on %q certificate, got %q on %q", members[i], tc.serving[i], member.Name, member.Certificate)
+				}
+			}
+		})
+	}
+}
@@ -5,18 +5,27 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/canonical/lxd/lxd/util"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/microcluster/v2/state"
 
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
 )
 
 // GetConfig returns the ConfigItem based on key from the database
 func GetConfig(ctx context.Context, s state.State, key string) (string, error) {
 	var value string
 
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigRead).Inc()
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		record, err := database.GetConfigItem(ctx, tx, key)
 		if err != nil {
 			return err
@@ -32,11 +41,36 @@ func GetConfig(ctx context.Context, s state.State, key string) (string, error) {
 	return value, nil
 }
 
+// GetConfigWithRevision returns the value and revision of the ConfigItem
+// based on key from the database
+func GetConfigWithRevision(ctx context.Context, s state.State, key string) (string, int, error) {
+	var value string
+	var revision int
+
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigRead).Inc()
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		record, err := database.GetConfigItem(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		value = record.Value
+		revision = record.Revision
+		return nil
+	})
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	return value, revision, nil
+}
+
 // GetConfigItemKeys returns the list of ConfigItem keys from the database
 func GetConfigItemKeys(ctx context.Context, s state.State, prefix *string) ([]string, error) {
 	var keys []string
 
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		var err error
 		keys, err = database.GetConfigItemKeys(ctx, tx, prefix)
 		if err != nil {
@@ -54,9 +88,10 @@ func GetConfigItemKeys(ctx context.Context, s state.State, prefix *string) ([]st
 
 // CreateConfig adds a new ConfigItem to the database
 func CreateConfig(ctx context.Context, s state.State, key string, value string) error {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigWrite).Inc()
 
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		_, err := database.CreateConfigItem(ctx, tx, database.ConfigItem{Key: key, Value: value})
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := database.CreateConfigItem(ctx, tx, database.ConfigItem{Key: key, Value: value, Revision: 1})
 		if err != nil {
 			return fmt.Errorf("Failed to record config item: %w", err)
 		}
@@ -64,26 +99,163 @@ func CreateConfig(ctx context.Context, s state.State, key string, value string)
 	})
 }
 
-// UpdateConfig updates a ConfigItem in the database
+// UpdateConfig updates a ConfigItem in the database on behalf of clusterd
 func UpdateConfig(ctx context.Context, s state.State, key string, value string) error {
-	configItem := database.ConfigItem{Key: key, Value: value}
+	return UpdateConfigIfMatch(ctx, s, key, value, "", clusterdActor)
+}
 
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		err := database.UpdateConfigItem(ctx, tx, key, configItem)
-		if err != nil && strings.Contains(err.Error(), "ConfigItem not found") {
-			_, err = database.CreateConfigItem(ctx, tx, configItem)
+// UpdateConfigIfMatch updates a ConfigItem in the database if ifMatch matches
+// the ETag of the stored revision. An empty ifMatch skips the check. The new
+// revision is recorded in the config history as written by actor. Marking
+// the deployment as bootstrapped emits a bootstrap.completed event.
+func UpdateConfigIfMatch(ctx context.Context, s state.State, key string, value string, ifMatch string, actor string) error {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigWrite).Inc()
+
+	var bootstrapped bool
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		bootstrapped, err = putConfigItem(ctx, tx, key, value, ifMatch, actor)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if bootstrapped {
+		emitEvent(ctx, apitypes.EventBootstrapCompleted, nil)
+	}
+
+	return nil
+}
+
+// putConfigItem records a ConfigItem in the transaction if ifMatch matches
+// the ETag of the stored revision, after the checks specific to key, along
+// with its revision written by actor in the config history. Returns whether
+// the update marks the deployment as bootstrapped.
+func putConfigItem(ctx context.Context, tx *sql.Tx, key string, value string, ifMatch string, actor string) (bool, error) {
+	record, err := database.GetConfigItem(ctx, tx, key)
+	if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return false, fmt.Errorf("Failed to record config item: %w", err)
+	}
+
+	exists := err == nil
+	revision := 0
+	if exists {
+		revision = record.Revision
+	}
+
+	err = CheckConfigRevision(ifMatch, exists, revision)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists && hasConfigHistory(key) {
+		// A deleted key carries on from its recorded revisions, keeping the
+		// history append-only
+		revision, err = database.GetLatestConfigRevision(ctx, tx, key)
+		if err != nil {
+			return false, err
 		}
+	}
+
+	err = checkRoleConfigKey(ctx, tx, key)
+	if err != nil {
+		return false, err
+	}
+
+	switch key {
+	case CustomRolesConfigKey:
+		err = checkCustomRolesUpdate(ctx, tx, value)
+	case WebhooksConfigKey:
+		_, err = ParseWebhooks(value)
+	case ReadOnlyTokensConfigKey:
+		_, err = ParseReadOnlyTokens(value)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	configItem := database.ConfigItem{Key: key, Value: value, Revision: revision + 1}
+	if exists {
+		err = database.UpdateConfigItem(ctx, tx, key, configItem)
+	} else {
+		_, err = database.CreateConfigItem(ctx, tx, configItem)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Failed to record config item: %w", err)
+	}
+
+	if hasConfigHistory(key) {
+		limit, err := configHistoryLimit(ctx, tx)
 		if err != nil {
-			return fmt.Errorf("Failed to record config item: %w", err)
+			return false, err
+		}
+
+		err = recordConfigRevision(txConfigHistoryStore{ctx: ctx, tx: tx}, key, value, configItem.Revision, actor, limit, time.Now())
+		if err != nil {
+			return false, err
 		}
+	}
+
+	bootstrapped := key == BootstrappedConfigKey && isTrue(value) && !(exists && isTrue(record.Value))
+	return bootstrapped, nil
+}
+
+// isTrue returns whether a config value holds true, as a bool or a json
+// string
+func isTrue(value string) bool {
+	b, err := strconv.ParseBool(configScalar(value))
+	return err == nil && b
+}
+
+// ConfigETag returns the ETag for the given ConfigItem revision
+func ConfigETag(revision int) (string, error) {
+	return util.EtagHash(revision)
+}
 
+// CheckConfigRevision validates the If-Match header value sent by a client
+// against the stored revision of a ConfigItem. An empty ifMatch always
+// passes, "*" only passes if the item exists.
+func CheckConfigRevision(ifMatch string, exists bool, revision int) error {
+	ifMatch = strings.Trim(ifMatch, "\"")
+	if ifMatch == "" {
 		return nil
-	})
+	}
+
+	if !exists {
+		return api.StatusErrorf(http.StatusPreconditionFailed, "ETag doesn't match: ConfigItem does not exist")
+	}
+
+	if ifMatch == "*" {
+		return nil
+	}
+
+	etag, err := ConfigETag(revision)
+	if err != nil {
+		return err
+	}
+
+	if etag != ifMatch {
+		return api.StatusErrorf(http.StatusPreconditionFailed, "ETag doesn't match: ConfigItem has been modified since it was retrieved")
+	}
+
+	return nil
 }
 
-// DeleteConfig deletes a ConfigItem from the database
+// DeleteConfig deletes a ConfigItem from the database, its history is kept
+// so that it can be rolled back
 func DeleteConfig(ctx context.Context, s state.State, key string) error {
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigWrite).Inc()
+
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		if key == CustomRolesConfigKey {
+			err := checkCustomRolesUpdate(ctx, tx, "")
+			if err != nil {
+				return err
+			}
+		}
+
 		return database.DeleteConfigItem(ctx, tx, key)
 	})
 }
@@ -0,0 +1,241 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+)
+
+// configImportStore is the subset of config operations applied by a config
+// import, scoped to a single database transaction.
+type configImportStore interface {
+	// Items returns the values of all the config keys
+	Items() (map[string]string, error)
+	// Put records the value of key, returning whether it marks the
+	// deployment as bootstrapped
+	Put(key string, value string) (bool, error)
+	// Delete removes key, its history is kept
+	Delete(key string) error
+}
+
+// txConfigImportStore is a configImportStore backed by a database
+// transaction
+type txConfigImportStore struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	actor string
+}
+
+func (t txConfigImportStore) Items() (map[string]string, error) {
+	return configItems(t.ctx, t.tx)
+}
+
+func (t txConfigImportStore) Put(key string, value string) (bool, error) {
+	return putConfigItem(t.ctx, t.tx, key, value, "", t.actor)
+}
+
+func (t txConfigImportStore) Delete(key string) error {
+	if key == CustomRolesConfigKey {
+		err := checkCustomRolesUpdate(t.ctx, t.tx, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return database.DeleteConfigItem(t.ctx, t.tx, key)
+}
+
+// configItems returns the values of all the config keys
+func configItems(ctx context.Context, tx *sql.Tx) (map[string]string, error) {
+	records, err := database.GetConfigItems(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch config items: %w", err)
+	}
+
+	items := make(map[string]string, len(records))
+	for _, record := range records {
+		items[record.Key] = record.Value
+	}
+
+	return items, nil
+}
+
+// ExportConfig returns all the config keys as a single document, the
+// overrides of the node roles included. If redactSecrets is set, the values
+// of the keys holding credentials are RedactedValue.
+func ExportConfig(ctx context.Context, s state.State, redactSecrets bool) (apitypes.ConfigDocument, error) {
+	var doc apitypes.ConfigDocument
+
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigRead).Inc()
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		doc.Config, err = configItems(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return apitypes.ConfigDocument{}, err
+	}
+
+	if redactSecrets {
+		redactConfigDocument(&doc)
+	}
+
+	return doc, nil
+}
+
+// redactConfigDocument replaces the values of the keys of doc holding
+// credentials by RedactedValue
+func redactConfigDocument(doc *apitypes.ConfigDocument) {
+	doc.Redacted = true
+
+	for key := range doc.Config {
+		if isSecretRoleConfigKey(key) {
+			doc.Config[key] = apitypes.RedactedValue
+		}
+	}
+}
+
+// isSecretRoleConfigKey returns whether key, or the key it overrides for a
+// node role, holds credentials
+func isSecretRoleConfigKey(key string) bool {
+	base, _, _ := splitRoleConfigKey(key)
+	return IsSecretConfigKey(base)
+}
+
+// ImportConfig applies the config document of req in a single database
+// transaction, deleting the keys absent from it if req.Prune is set. Either
+// all the keys are changed or none are: every invalid value is reported at
+// once in a ConfigTransactionError. Redacted values of the keys holding
+// credentials are skipped, keeping the stored secrets. Returns the keys
+// changed by the import.
+func ImportConfig(ctx context.Context, s state.State, req apitypes.ConfigImport, actor string) (apitypes.ConfigImportResult, error) {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigWrite).Inc()
+
+	var result apitypes.ConfigImportResult
+	var bootstrapped bool
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		store := txConfigImportStore{ctx: ctx, tx: tx, actor: actor}
+		result, bootstrapped, err = importConfig(store, ConfigSchema, req)
+		return err
+	})
+	if err != nil {
+		return apitypes.ConfigImportResult{}, err
+	}
+
+	if bootstrapped {
+		emitEvent(ctx, apitypes.EventBootstrapCompleted, nil)
+	}
+
+	return result, nil
+}
+
+// importConfig validates the values of req, then records the keys whose
+// value changed and, with req.Prune, deletes the keys absent from req. The
+// overrides of the node roles are validated against the schema of the key
+// they override. It returns a ConfigTransactionError listing the rejected
+// keys, in which case the caller must roll back the keys changed so far,
+// along with the changed keys and whether the deployment is marked as
+// bootstrapped.
+func importConfig(store configImportStore, schema apitypes.ConfigSchema, req apitypes.ConfigImport) (apitypes.ConfigImportResult, bool, error) {
+	result := apitypes.ConfigImportResult{Created: []string{}, Updated: []string{}, Deleted: []string{}, Skipped: []string{}}
+
+	current, err := store.Items()
+	if err != nil {
+		return result, false, err
+	}
+
+	rejected := &ConfigTransactionError{
+		ConfigTransactionFailed: apitypes.ConfigTransactionFailed{Code: apitypes.ConfigTransactionFailedCode},
+		status:                  http.StatusBadRequest,
+	}
+	reject := func(key string, err error) {
+		rejected.Errors = append(rejected.Errors, apitypes.ConfigKeyError{Key: key, Error: err.Error()})
+	}
+
+	var changed []string
+	for _, key := range slices.Sorted(maps.Keys(req.Config)) {
+		value := req.Config[key]
+		if value == apitypes.RedactedValue && isSecretRoleConfigKey(key) {
+			result.Skipped = append(result.Skipped, key)
+			continue
+		}
+
+		stored, exists := current[key]
+		if exists && stored == value {
+			continue
+		}
+
+		base, _, _ := splitRoleConfigKey(key)
+		_, err := ValidateConfig(schema, base, value, false)
+		if err != nil {
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				return result, false, err
+			}
+			reject(key, err)
+			continue
+		}
+
+		changed = append(changed, key)
+		if exists {
+			result.Updated = append(result.Updated, key)
+		} else {
+			result.Created = append(result.Created, key)
+		}
+	}
+
+	if len(rejected.Errors) > 0 {
+		return result, false, rejected
+	}
+
+	// The checks specific to some keys are only run when recording them,
+	// carry on so that all their errors are reported
+	var bootstrapped bool
+	for _, key := range changed {
+		marked, err := store.Put(key, req.Config[key])
+		if err != nil {
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				return result, false, fmt.Errorf("Failed to set config key %q: %w", key, err)
+			}
+			reject(key, err)
+		}
+
+		bootstrapped = bootstrapped || marked
+	}
+
+	if req.Prune {
+		for _, key := range slices.Sorted(maps.Keys(current)) {
+			_, ok := req.Config[key]
+			if ok {
+				continue
+			}
+
+			err := store.Delete(key)
+			if err != nil {
+				if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+					return result, false, fmt.Errorf("Failed to delete config key %q: %w", key, err)
+				}
+				reject(key, err)
+			}
+
+			result.Deleted = append(result.Deleted, key)
+		}
+	}
+
+	if len(rejected.Errors) > 0 {
+		return result, false, rejected
+	}
+
+	return result, bootstrapped, nil
+}
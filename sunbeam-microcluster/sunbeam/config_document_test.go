@@ -0,0 +1,248 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// memConfigImportStore is an in-memory configImportStore
+type memConfigImportStore struct {
+	items map[string]string
+}
+
+func (m *memConfigImportStore) Items() (map[string]string, error) {
+	return maps.Clone(m.items), nil
+}
+
+func (m *memConfigImportStore) Put(key string, value string) (bool, error) {
+	m.items[key] = value
+	return key == BootstrappedConfigKey && isTrue(value), nil
+}
+
+func (m *memConfigImportStore) Delete(key string) error {
+	_, ok := m.items[key]
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "ConfigItem not found")
+	}
+
+	delete(m.items, key)
+	return nil
+}
+
+// TestImportConfig tests that a config document is applied all at once,
+// reporting the changed keys, pruning the absent keys only if asked and
+// keeping the secrets whose value was redacted.
+func TestImportConfig(t *testing.T) {
+	initial := map[string]string{
+		"sunbeam_bootstrapped": `"false"`,
+		"deployment.type":      `"local"`,
+		"K8SKubeConfig":        `"kubeconfig"`,
+		"maintenance.max-parallel-migrations@role:compute": `"2"`,
+	}
+
+	testCases := []struct {
+		name         string
+		req          apitypes.ConfigImport
+		wantResult   apitypes.ConfigImportResult
+		wantRejected []string
+		wantItems    map[string]string
+	}{
+		{
+			name:       "round trip",
+			req:        apitypes.ConfigImport{Config: maps.Clone(initial), Prune: true},
+			wantResult: apitypes.ConfigImportResult{},
+			wantItems:  initial,
+		},
+		{
+			name: "changes reported",
+			req: apitypes.ConfigImport{Config: map[string]string{
+				"deployment.type":      `"maas"`,
+				"external_gateway":     `"10.0.0.1"`,
+				"sunbeam_bootstrapped": `"false"`,
+			}},
+			wantResult: apitypes.ConfigImportResult{Created: []string{"external_gateway"}, Updated: []string{"deployment.type"}},
+			wantItems: map[string]string{
+				"sunbeam_bootstrapped": `"false"`,
+				"deployment.type":      `"maas"`,
+				"external_gateway":     `"10.0.0.1"`,
+				"K8SKubeConfig":        `"kubeconfig"`,
+				"maintenance.max-parallel-migrations@role:compute": `"2"`,
+			},
+		},
+		{
+			name:       "prune",
+			req:        apitypes.ConfigImport{Config: map[string]string{"deployment.type": `"local"`, "K8SKubeConfig": `"kubeconfig"`}, Prune: true},
+			wantResult: apitypes.ConfigImportResult{Deleted: []string{"maintenance.max-parallel-migrations@role:compute", "sunbeam_bootstrapped"}},
+			wantItems:  map[string]string{"deployment.type": `"local"`, "K8SKubeConfig": `"kubeconfig"`},
+		},
+		{
+			name:       "redacted secret skipped",
+			req:        apitypes.ConfigImport{Config: map[string]string{"K8SKubeConfig": apitypes.RedactedValue, "VaultDevModeInfo": apitypes.RedactedValue}},
+			wantResult: apitypes.ConfigImportResult{Skipped: []string{"K8SKubeConfig", "VaultDevModeInfo"}},
+			wantItems:  initial,
+		},
+		{
+			name: "redacted secret kept when pruning",
+			req: apitypes.ConfigImport{Config: map[string]string{
+				"K8SKubeConfig":   apitypes.RedactedValue,
+				"deployment.type": `"local"`,
+			}, Prune: true},
+			wantResult: apitypes.ConfigImportResult{Deleted: []string{"maintenance.max-parallel-migrations@role:compute", "sunbeam_bootstrapped"}, Skipped: []string{"K8SKubeConfig"}},
+			wantItems:  map[string]string{"deployment.type": `"local"`, "K8SKubeConfig": `"kubeconfig"`},
+		},
+		{
+			name:       "redacted value of a plain key set",
+			req:        apitypes.ConfigImport{Config: map[string]string{"note": apitypes.RedactedValue}},
+			wantResult: apitypes.ConfigImportResult{Created: []string{"note"}},
+			wantItems: map[string]string{
+				"sunbeam_bootstrapped": `"false"`,
+				"deployment.type":      `"local"`,
+				"K8SKubeConfig":        `"kubeconfig"`,
+				"maintenance.max-parallel-migrations@role:compute": `"2"`,
+				"note": apitypes.RedactedValue,
+			},
+		},
+		{
+			name: "invalid values reject the import",
+			req: apitypes.ConfigImport{Config: map[string]string{
+				"deployment.type":  `"openstack"`,
+				"external_gateway": `"10.0.0.1"`,
+				"maintenance.max-parallel-migrations@role:compute": `"-1"`,
+			}, Prune: true},
+			wantRejected: []string{"deployment.type", "maintenance.max-parallel-migrations@role:compute"},
+			wantItems:    initial,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memConfigImportStore{items: maps.Clone(initial)}
+
+			result, _, err := importConfig(store, testConfigSchema, tc.req)
+			if tc.wantRejected != nil {
+				if !slices.Equal(rejectedKeys(err), tc.wantRejected) {
+					t.Errorf("Expected rejected keys %v, got %v", tc.wantRejected, err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				for _, keys := range []struct{ want, got []string }{
+					{tc.wantResult.Created, result.Created},
+					{tc.wantResult.Updated, result.Updated},
+					{tc.wantResult.Deleted, result.Deleted},
+					{tc.wantResult.Skipped, result.Skipped},
+				} {
+					if !slices.Equal(keys.want, keys.got) {
+						t.Errorf("Expected result %+v, got %+v", tc.wantResult, result)
+						break
+					}
+				}
+			}
+
+			if !maps.Equal(store.items, tc.wantItems) {
+				t.Errorf("Expected items %v, got %v", tc.wantItems, store.items)
+			}
+		})
+	}
+}
+
+// TestImportConfigBootstrapped tests that an import marking the deployment
+// as bootstrapped is reported
+func TestImportConfigBootstrapped(t *testing.T) {
+	store := &memConfigImportStore{items: map[string]string{"sunbeam_bootstrapped": `"false"`}}
+
+	_, bootstrapped, err := importConfig(store, testConfigSchema, apitypes.ConfigImport{Config: map[string]string{"sunbeam_bootstrapped": `"true"`}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bootstrapped {
+		t.Error("Expected the deployment to be marked as bootstrapped")
+	}
+}
+
+// TestImportConfigRollback tests that a key rejected when recorded in the
+// database rolls back the keys recorded and pruned by the import, along
+// with their history.
+func TestImportConfigRollback(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	_, err := db.ExecContext(t.Context(), `INSERT INTO config (key, value) VALUES ('note', '"kept"')`)
+	if err != nil {
+		t.Fatalf("Failed to record the config: %v", err)
+	}
+
+	req := apitypes.ConfigImport{
+		Config: map[string]string{
+			DeploymentTypeConfigKey: `"maas"`,
+			"external_gateway":      `"10.0.0.1"`,
+			WebhooksConfigKey:       `[{"name": "Not A Name", "url": "https://hooks.example.com"}]`,
+		},
+		Prune: true,
+	}
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		store := txConfigImportStore{ctx: ctx, tx: tx, actor: "ubuntu@node-1"}
+		_, _, err := importConfig(store, ConfigSchema, req)
+		return err
+	})
+
+	keys := rejectedKeys(err)
+	if !slices.Equal(keys, []string{WebhooksConfigKey}) {
+		t.Fatalf("Expected rejected keys %v, got %v", []string{WebhooksConfigKey}, keys)
+	}
+
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		items, err := configItems(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		want := map[string]string{DeploymentTypeConfigKey: `"local"`, "note": `"kept"`}
+		if !maps.Equal(items, want) {
+			t.Errorf("Expected config %v, got %v", want, items)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch the config: %v", err)
+	}
+
+	if n := countRows(t, db, "config_history"); n != 0 {
+		t.Errorf("Expected no config history, got %d revisions", n)
+	}
+}
+
+// TestRedactConfigDocument tests that the keys holding credentials, and
+// their role overrides, are redacted
+func TestRedactConfigDocument(t *testing.T) {
+	doc := apitypes.ConfigDocument{Config: map[string]string{
+		"deployment.type":            `"local"`,
+		"K8SKubeConfig":              `"kubeconfig"`,
+		"K8SKubeConfig@role:compute": `"kubeconfig"`,
+		tfstatePrefix + "openstack":  `{"secret": "x"}`,
+	}}
+
+	redactConfigDocument(&doc)
+
+	if !doc.Redacted {
+		t.Error("Expected the document to be marked as redacted")
+	}
+
+	for key, value := range doc.Config {
+		redacted := value == apitypes.RedactedValue
+		if redacted != (key != "deployment.type") {
+			t.Errorf("Unexpected value %q of key %q", value, key)
+		}
+	}
+}
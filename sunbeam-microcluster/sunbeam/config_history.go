@@ -0,0 +1,209 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+)
+
+const (
+	// ConfigHistoryLimitConfigKey is the config key holding how many
+	// previous revisions are kept per config key
+	ConfigHistoryLimitConfigKey = "config.history-limit"
+
+	// defaultConfigHistoryLimit is how many previous revisions are kept per
+	// config key by default
+	defaultConfigHistoryLimit = 10
+
+	// clusterdActor is recorded as the author of the revisions written by
+	// clusterd itself
+	clusterdActor = "sunbeam-clusterd"
+)
+
+// configHistoryStore records the revisions of the config items
+type configHistoryStore interface {
+	Record(revision database.ConfigRevision) error
+	Revisions(key string) ([]database.ConfigRevision, error)
+	Prune(key string, before int) error
+}
+
+// txConfigHistoryStore is a configHistoryStore backed by a database
+// transaction
+type txConfigHistoryStore struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (t txConfigHistoryStore) Record(revision database.ConfigRevision) error {
+	return database.CreateConfigRevision(t.ctx, t.tx, revision)
+}
+
+func (t txConfigHistoryStore) Revisions(key string) ([]database.ConfigRevision, error) {
+	return database.GetConfigRevisions(t.ctx, t.tx, key)
+}
+
+func (t txConfigHistoryStore) Prune(key string, before int) error {
+	return database.DeleteConfigRevisionsBefore(t.ctx, t.tx, key, before)
+}
+
+// hasConfigHistory returns whether the revisions of key are recorded. The
+// terraform states and locks are left out, they are large or short-lived
+// and only ever written by terraform.
+func hasConfigHistory(key string) bool {
+	return !strings.HasPrefix(key, tfstatePrefix) && !strings.HasPrefix(key, tflockPrefix)
+}
+
+// recordConfigRevision records revision of key holding value, written by
+// actor at now, and drops the revisions older than the limit previous ones.
+func recordConfigRevision(store configHistoryStore, key string, value string, revision int, actor string, limit int, now time.Time) error {
+	err := store.Record(database.ConfigRevision{
+		Key:       key,
+		Revision:  revision,
+		Value:     value,
+		ChangedAt: now.UTC().Format(time.RFC3339),
+		ChangedBy: actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.Prune(key, revision-limit)
+}
+
+// configRollbackTarget returns the recorded revision of key to roll back to,
+// revision if set or else the latest one preceding current. A deleted key,
+// which does not exist, is rolled back to its latest recorded revision.
+// Returns a 404 StatusError if there is no such revision.
+func configRollbackTarget(store configHistoryStore, key string, current int, exists bool, revision int) (database.ConfigRevision, error) {
+	revisions, err := store.Revisions(key)
+	if err != nil {
+		return database.ConfigRevision{}, err
+	}
+
+	if revision > 0 {
+		for _, r := range revisions {
+			if r.Revision == revision {
+				return r, nil
+			}
+		}
+
+		return database.ConfigRevision{}, api.StatusErrorf(http.StatusNotFound, "Revision %d of config key %q not found", revision, key)
+	}
+
+	// Revisions are ordered oldest first
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if !exists || revisions[i].Revision < current {
+			return revisions[i], nil
+		}
+	}
+
+	return database.ConfigRevision{}, api.StatusErrorf(http.StatusNotFound, "No previous revision of config key %q to roll back to", key)
+}
+
+// configHistoryLimit returns how many previous revisions are kept per config
+// key, falling back to the default on unset or invalid values.
+func configHistoryLimit(ctx context.Context, tx *sql.Tx) (int, error) {
+	record, err := database.GetConfigItem(ctx, tx, ConfigHistoryLimitConfigKey)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return defaultConfigHistoryLimit, nil
+		}
+		return 0, err
+	}
+
+	limit, err := strconv.Atoi(configScalar(record.Value))
+	if err != nil || limit < 0 {
+		return defaultConfigHistoryLimit, nil
+	}
+
+	return limit, nil
+}
+
+// GetConfigHistory returns the recorded revisions of key, oldest first, or a
+// 404 StatusError if key neither exists nor has recorded revisions.
+func GetConfigHistory(ctx context.Context, s state.State, key string) (apitypes.ConfigHistory, error) {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigRead).Inc()
+
+	var revisions []database.ConfigRevision
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		revisions, err = database.GetConfigRevisions(ctx, tx, key)
+		if err != nil || len(revisions) > 0 {
+			return err
+		}
+
+		// Keys written before their history was recorded have none
+		_, err = database.GetConfigItem(ctx, tx, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(apitypes.ConfigHistory, 0, len(revisions))
+	for _, r := range revisions {
+		history = append(history, apitypes.ConfigRevision{Revision: r.Revision, Value: r.Value, ChangedAt: r.ChangedAt, ChangedBy: r.ChangedBy})
+	}
+
+	return history, nil
+}
+
+// RollbackConfig restores the value key held at revision, or at the revision
+// preceding the current one if revision is 0. The rollback is written as a
+// new revision by actor, the history is never rewritten. Returns the new
+// revision, or a 404 StatusError if the revision to restore is not recorded.
+func RollbackConfig(ctx context.Context, s state.State, key string, revision int, actor string) (apitypes.ConfigRevision, error) {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigWrite).Inc()
+
+	var bootstrapped bool
+	var restored database.ConfigRevision
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		record, err := database.GetConfigItem(ctx, tx, key)
+		if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+			return err
+		}
+
+		exists := err == nil
+		current := 0
+		if exists {
+			current = record.Revision
+		}
+
+		target, err := configRollbackTarget(txConfigHistoryStore{ctx: ctx, tx: tx}, key, current, exists, revision)
+		if err != nil {
+			return err
+		}
+
+		bootstrapped, err = putConfigItem(ctx, tx, key, target.Value, "", actor)
+		if err != nil {
+			return err
+		}
+
+		record, err = database.GetConfigItem(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		restored, err = database.GetConfigRevision(ctx, tx, key, record.Revision)
+		return err
+	})
+	if err != nil {
+		return apitypes.ConfigRevision{}, err
+	}
+
+	if bootstrapped {
+		emitEvent(ctx, apitypes.EventBootstrapCompleted, nil)
+	}
+
+	return apitypes.ConfigRevision{Revision: restored.Revision, Value: restored.Value, ChangedAt: restored.ChangedAt, ChangedBy: restored.ChangedBy}, nil
+}
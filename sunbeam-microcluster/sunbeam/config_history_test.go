@@ -0,0 +1,193 @@
+package sunbeam
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// memConfigHistoryStore is an in-memory configHistoryStore
+type memConfigHistoryStore struct {
+	revisions []database.ConfigRevision
+}
+
+func (m *memConfigHistoryStore) Record(revision database.ConfigRevision) error {
+	m.revisions = append(m.revisions, revision)
+	return nil
+}
+
+func (m *memConfigHistoryStore) Revisions(key string) ([]database.ConfigRevision, error) {
+	revisions := make([]database.ConfigRevision, 0)
+	for _, r := range m.revisions {
+		if r.Key == key {
+			revisions = append(revisions, r)
+		}
+	}
+
+	return revisions, nil
+}
+
+func (m *memConfigHistoryStore) Prune(key string, before int) error {
+	m.revisions = slices.DeleteFunc(m.revisions, func(r database.ConfigRevision) bool {
+		return r.Key == key && r.Revision < before
+	})
+	return nil
+}
+
+// revisionValues returns the revision numbers and values of key in store
+func revisionValues(t *testing.T, store configHistoryStore, key string) ([]int, []string) {
+	t.Helper()
+
+	revisions, err := store.Revisions(key)
+	if err != nil {
+		t.Fatalf("Failed to list revisions: %v", err)
+	}
+
+	numbers := make([]int, 0, len(revisions))
+	values := make([]string, 0, len(revisions))
+	for _, r := range revisions {
+		numbers = append(numbers, r.Revision)
+		values = append(values, r.Value)
+	}
+
+	return numbers, values
+}
+
+// TestConfigHistoryRollback writes three revisions of a key, then rolls it
+// back to the second one
+func TestConfigHistoryRollback(t *testing.T) {
+	store := &memConfigHistoryStore{}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	for i, value := range []string{`"one"`, `"two"`, `"three"`} {
+		err := recordConfigRevision(store, "key", value, i+1, "admin", defaultConfigHistoryLimit, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatalf("Failed to record revision %d: %v", i+1, err)
+		}
+	}
+
+	numbers, values := revisionValues(t, store, "key")
+	if !slices.Equal(numbers, []int{1, 2, 3}) || !slices.Equal(values, []string{`"one"`, `"two"`, `"three"`}) {
+		t.Fatalf("Expected revisions 1 to 3, got %v with values %v", numbers, values)
+	}
+
+	target, err := configRollbackTarget(store, "key", 3, true, 0)
+	if err != nil {
+		t.Fatalf("Failed to find the revision to roll back to: %v", err)
+	}
+
+	if target.Revision != 2 || target.Value != `"two"` {
+		t.Fatalf("Expected to roll back to revision 2, got %d with value %s", target.Revision, target.Value)
+	}
+
+	// The rollback is written as a new revision
+	err = recordConfigRevision(store, "key", target.Value, 4, "operator", defaultConfigHistoryLimit, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to record the rollback: %v", err)
+	}
+
+	numbers, values = revisionValues(t, store, "key")
+	if !slices.Equal(numbers, []int{1, 2, 3, 4}) || !slices.Equal(values, []string{`"one"`, `"two"`, `"three"`, `"two"`}) {
+		t.Fatalf("Expected the history to be appended to, got %v with values %v", numbers, values)
+	}
+
+	latest := store.revisions[len(store.revisions)-1]
+	if latest.ChangedBy != "operator" || latest.ChangedAt != "2026-10-14T13:00:00Z" {
+		t.Fatalf("Expected the rollback by operator at 13:00, got %s at %s", latest.ChangedBy, latest.ChangedAt)
+	}
+
+	// Rolling back again goes to the revision before the rollback
+	target, err = configRollbackTarget(store, "key", 4, true, 0)
+	if err != nil || target.Revision != 3 {
+		t.Fatalf("Expected to roll back to revision 3, got %d: %v", target.Revision, err)
+	}
+}
+
+// TestConfigRollbackTarget tests the selection of the revision to restore
+func TestConfigRollbackTarget(t *testing.T) {
+	store := &memConfigHistoryStore{}
+	for i, value := range []string{"a", "b", "c"} {
+		_ = store.Record(database.ConfigRevision{Key: "key", Revision: i + 3, Value: value})
+	}
+
+	_ = store.Record(database.ConfigRevision{Key: "other", Revision: 9, Value: "z"})
+
+	testCases := []struct {
+		name     string
+		current  int
+		exists   bool
+		revision int
+		want     int
+		notFound bool
+	}{
+		{name: "previous revision", current: 5, exists: true, want: 4},
+		{name: "explicit revision", current: 5, exists: true, revision: 3, want: 3},
+		{name: "deleted key", current: 0, exists: false, want: 5},
+		{name: "pruned revision", current: 5, exists: true, revision: 1, notFound: true},
+		{name: "no previous revision", current: 3, exists: true, notFound: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, err := configRollbackTarget(store, "key", tc.current, tc.exists, tc.revision)
+			if tc.notFound {
+				if !api.StatusErrorCheck(err, http.StatusNotFound) {
+					t.Fatalf("Expected a 404 error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if target.Revision != tc.want {
+				t.Fatalf("Expected revision %d, got %d", tc.want, target.Revision)
+			}
+		})
+	}
+}
+
+// TestRecordConfigRevisionPrunes tests that only the limit previous
+// revisions are kept
+func TestRecordConfigRevisionPrunes(t *testing.T) {
+	store := &memConfigHistoryStore{}
+	_ = store.Record(database.ConfigRevision{Key: "other", Revision: 1})
+
+	for revision := 1; revision <= 5; revision++ {
+		err := recordConfigRevision(store, "key", "value", revision, "admin", 2, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to record revision %d: %v", revision, err)
+		}
+	}
+
+	numbers, _ := revisionValues(t, store, "key")
+	if !slices.Equal(numbers, []int{3, 4, 5}) {
+		t.Fatalf("Expected the current and 2 previous revisions, got %v", numbers)
+	}
+
+	numbers, _ = revisionValues(t, store, "other")
+	if !slices.Equal(numbers, []int{1}) {
+		t.Fatalf("Expected the revisions of other keys to be kept, got %v", numbers)
+	}
+}
+
+// TestHasConfigHistory tests that the terraform keys have no history
+func TestHasConfigHistory(t *testing.T) {
+	for key, want := range map[string]bool{
+		"sunbeam_bootstrapped":   true,
+		WebhooksConfigKey:        true,
+		tfstatePrefix + "plan":   false,
+		tflockPrefix + "plan":    false,
+		"deployment.tfstate-old": true,
+	} {
+		if got := hasConfigHistory(key); got != want {
+			t.Errorf("hasConfigHistory(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+)
+
+// roleConfigKeySeparator separates a config key from the node role in the
+// key of the override of the role
+const roleConfigKeySeparator = "@role:"
+
+// clusterWideConfigKeys are the config keys clusterd reads for the whole
+// cluster, which cannot be overridden per role
+var clusterWideConfigKeys = []string{
+	BootstrappedConfigKey,
+	ConfigHistoryLimitConfigKey,
+	CustomRolesConfigKey,
+	ReadOnlyTokensConfigKey,
+	WebhooksConfigKey,
+}
+
+// RoleConfigKey returns the config key holding the override of key for the
+// nodes of role, key itself if role is empty.
+func RoleConfigKey(key string, role string) string {
+	if role == "" {
+		return key
+	}
+
+	return key + roleConfigKeySeparator + role
+}
+
+// splitRoleConfigKey returns the config key and the node role of the key of
+// a role override, and whether key is one.
+func splitRoleConfigKey(key string) (string, string, bool) {
+	return strings.Cut(key, roleConfigKeySeparator)
+}
+
+// checkRoleConfigKey returns a 400 StatusError if key is the override of a
+// cluster-wide key or of a role that is neither built-in nor custom.
+func checkRoleConfigKey(ctx context.Context, tx *sql.Tx, key string) error {
+	key, role, ok := splitRoleConfigKey(key)
+	if !ok {
+		return nil
+	}
+
+	if slices.Contains(clusterWideConfigKeys, key) {
+		return api.StatusErrorf(http.StatusBadRequest, "Config key %q cannot be overridden per role", key)
+	}
+
+	return validateNodeRoles(ctx, tx, []string{role})
+}
+
+// configItemReader reads config items, scoped to a single transaction
+type configItemReader interface {
+	// Item returns the config item of key and whether it is set
+	Item(key string) (database.ConfigItem, bool, error)
+}
+
+// txConfigItemReader is a configItemReader backed by a database transaction
+type txConfigItemReader struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (t txConfigItemReader) Item(key string) (database.ConfigItem, bool, error) {
+	record, err := database.GetConfigItem(t.ctx, t.tx, key)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return database.ConfigItem{}, false, nil
+		}
+		return database.ConfigItem{}, false, err
+	}
+
+	return *record, true, nil
+}
+
+// resolveRoleConfig returns the config item of key for the nodes of role,
+// along with its scope. The first of these that is set wins:
+//
+//  1. the override of key for role
+//  2. the global value of key
+//
+// Returns a 404 StatusError if neither is set.
+func resolveRoleConfig(store configItemReader, key string, role string) (database.ConfigItem, string, error) {
+	if role != "" {
+		item, ok, err := store.Item(RoleConfigKey(key, role))
+		if err != nil {
+			return database.ConfigItem{}, "", err
+		}
+
+		if ok {
+			return item, apitypes.ConfigScopeRole, nil
+		}
+	}
+
+	item, ok, err := store.Item(key)
+	if err != nil {
+		return database.ConfigItem{}, "", err
+	}
+
+	if !ok {
+		return database.ConfigItem{}, "", api.StatusErrorf(http.StatusNotFound, "ConfigItem not found")
+	}
+
+	return item, apitypes.ConfigScopeGlobal, nil
+}
+
+// GetRoleConfig returns the value and revision of key for the nodes of
+// role, the override of the role falling back to the global value, along
+// with the scope of the value. Returns a 400 StatusError for unknown roles.
+func GetRoleConfig(ctx context.Context, s state.State, key string, role string) (string, int, string, error) {
+	var item database.ConfigItem
+	var scope string
+
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigRead).Inc()
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		err := validateNodeRoles(ctx, tx, []string{role})
+		if err != nil {
+			return err
+		}
+
+		item, scope, err = resolveRoleConfig(txConfigItemReader{ctx: ctx, tx: tx}, key, role)
+		return err
+	})
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	return item.Value, item.Revision, scope, nil
+}
@@ -0,0 +1,118 @@
+package sunbeam
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// memConfigItemReader is an in-memory configItemReader
+type memConfigItemReader map[string]database.ConfigItem
+
+func (m memConfigItemReader) Item(key string) (database.ConfigItem, bool, error) {
+	item, ok := m[key]
+	return item, ok, nil
+}
+
+func (m memConfigItemReader) set(key string, value string, revision int) {
+	m[key] = database.ConfigItem{Key: key, Value: value, Revision: revision}
+}
+
+// TestResolveRoleConfig tests that the override of a role takes precedence
+// over the global value, which is used by the other roles and once the
+// override is deleted.
+func TestResolveRoleConfig(t *testing.T) {
+	store := memConfigItemReader{}
+	store.set("cpu-mode", `"host-model"`, 2)
+	store.set(RoleConfigKey("cpu-mode", "storage"), `"none"`, 1)
+
+	testCases := []struct {
+		name         string
+		role         string
+		wantValue    string
+		wantRevision int
+		wantScope    string
+	}{
+		{name: "override", role: "storage", wantValue: `"none"`, wantRevision: 1, wantScope: apitypes.ConfigScopeRole},
+		{name: "fallback", role: "compute", wantValue: `"host-model"`, wantRevision: 2, wantScope: apitypes.ConfigScopeGlobal},
+		{name: "no role", role: "", wantValue: `"host-model"`, wantRevision: 2, wantScope: apitypes.ConfigScopeGlobal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			item, scope, err := resolveRoleConfig(store, "cpu-mode", tc.role)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if item.Value != tc.wantValue || item.Revision != tc.wantRevision || scope != tc.wantScope {
+				t.Errorf("Expected %s at revision %d from the %s scope, got %s at revision %d from the %s scope", tc.wantValue, tc.wantRevision, tc.wantScope, item.Value, item.Revision, scope)
+			}
+		})
+	}
+
+	t.Run("deleted override", func(t *testing.T) {
+		delete(store, RoleConfigKey("cpu-mode", "storage"))
+
+		item, scope, err := resolveRoleConfig(store, "cpu-mode", "storage")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if item.Value != `"host-model"` || scope != apitypes.ConfigScopeGlobal {
+			t.Errorf("Expected the global value once the override is deleted, got %s from the %s scope", item.Value, scope)
+		}
+	})
+
+	t.Run("override without global value", func(t *testing.T) {
+		store.set(RoleConfigKey("osd-memory", "storage"), "4096", 1)
+
+		item, scope, err := resolveRoleConfig(store, "osd-memory", "storage")
+		if err != nil || item.Value != "4096" || scope != apitypes.ConfigScopeRole {
+			t.Errorf("Expected the override, got %s from the %s scope: %v", item.Value, scope, err)
+		}
+
+		_, _, err = resolveRoleConfig(store, "osd-memory", "compute")
+		if !api.StatusErrorCheck(err, http.StatusNotFound) {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+// TestRoleConfigKey tests that the key of an override is split back into
+// the config key and the role
+func TestRoleConfigKey(t *testing.T) {
+	if RoleConfigKey("cpu-mode", "") != "cpu-mode" {
+		t.Errorf("Expected the global key without a role, got %q", RoleConfigKey("cpu-mode", ""))
+	}
+
+	key, role, ok := splitRoleConfigKey(RoleConfigKey("cpu-mode", "storage"))
+	if !ok || key != "cpu-mode" || role != "storage" {
+		t.Errorf("Expected cpu-mode overridden for storage, got %q for %q", key, role)
+	}
+
+	_, _, ok = splitRoleConfigKey("cpu-mode")
+	if ok {
+		t.Error("Expected a global key not to be an override")
+	}
+}
+
+// TestCheckRoleConfigKeyClusterWide tests that the keys read for the whole
+// cluster cannot be overridden
+func TestCheckRoleConfigKeyClusterWide(t *testing.T) {
+	for _, key := range clusterWideConfigKeys {
+		err := checkRoleConfigKey(t.Context(), nil, RoleConfigKey(key, "storage"))
+		if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+			t.Errorf("Expected bad request error for %q, got %v", key, err)
+		}
+	}
+
+	err := checkRoleConfigKey(t.Context(), nil, "webhook.targets")
+	if err != nil {
+		t.Errorf("Expected the global key to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package sunbeam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// DeploymentTypeConfigKey is the config key holding the deployment type
+const DeploymentTypeConfigKey = "deployment.type"
+
+// BootstrappedConfigKey is the config key recording whether the deployment
+// is bootstrapped
+const BootstrappedConfigKey = "sunbeam_bootstrapped"
+
+// MaxParallelMigrationsConfigKey is the config key holding how many instance
+// migrations maintenance operations run at once
+const MaxParallelMigrationsConfigKey = "maintenance.max-parallel-migrations"
+
+// ConfigSchema is the registry of known config keys
+var ConfigSchema = apitypes.ConfigSchema{
+	{
+		Key:         DeploymentTypeConfigKey,
+		Type:        apitypes.ConfigTypeEnum,
+		Description: "Type of the deployment",
+		Values:      []string{"local", "maas"},
+	},
+	{
+		Key:         BootstrappedConfigKey,
+		Type:        apitypes.ConfigTypeBool,
+		Description: "Whether the deployment is bootstrapped",
+	},
+	{
+		Key:         "juju_controller_migrated_to_k8s",
+		Type:        apitypes.ConfigTypeBool,
+		Description: "Whether the juju controller is migrated to kubernetes",
+	},
+	{
+		Key:         TombstoneRetentionConfigKey,
+		Type:        apitypes.ConfigTypeDuration,
+		Description: "How long removed nodes are kept, e.g. 720h",
+	},
+	{
+		Key:         MaxParallelMigrationsConfigKey,
+		Type:        apitypes.ConfigTypeUint,
+		Description: "Maximum number of instance migrations run at once by maintenance operations, 0 leaves it to Watcher",
+	},
+	{
+		Key:         ConfigHistoryLimitConfigKey,
+		Type:        apitypes.ConfigTypeUint,
+		Description: "Number of previous revisions kept per config key for rollback, defaults to 10",
+	},
+	{
+		Key:         RateLimitClientReadsConfigKey,
+		Type:        apitypes.ConfigTypeUint,
+		Description: "Read requests per second a client can send to the API, 0 for no limit, defaults to 50",
+	},
+	{
+		Key:         RateLimitClientWritesConfigKey,
+		Type:        apitypes.ConfigTypeUint,
+		Description: "Mutating requests per second a client can send to the API, 0 for no limit, defaults to 10",
+	},
+	{
+		Key:         RateLimitGlobalReadsConfigKey,
+		Type:        apitypes.ConfigTypeUint,
+		Description: "Read requests per second the API serves across all the clients, 0 for no limit, defaults to 500",
+	},
+	{
+		Key:         RateLimitGlobalWritesConfigKey,
+		Type:        apitypes.ConfigTypeUint,
+		Description: "Mutating requests per second the API serves across all the clients, 0 for no limit, defaults to 50",
+	},
+	{
+		Key:         CustomRolesConfigKey,
+		Type:        apitypes.ConfigTypeJSON,
+		Description: "Custom roles nodes can be assigned besides the built-in ones, a json list of role names",
+	},
+	{
+		Key:         WebhooksConfigKey,
+		Type:        apitypes.ConfigTypeJSON,
+		Description: "Webhooks the cluster events are posted to, a json list of objects with name, url, secret and events",
+	},
+	{
+		Key:         ReadOnlyTokensConfigKey,
+		Type:        apitypes.ConfigTypeJSON,
+		Description: "Tokens authorizing observers to read the cluster state, a json list of objects with name, created_at and the hex SHA-256 hash of the token",
+	},
+	{
+		Key:         "juju.retry-policy",
+		Type:        apitypes.ConfigTypeJSON,
+		Description: "Retry policy of the Juju commands failing with transient errors, a json object with max_attempts, base_delay, max_delay and jitter in seconds",
+	},
+	{
+		Key:         "deployment.step-timeouts",
+		Type:        apitypes.ConfigTypeJSON,
+		Description: "Timeouts of the deployment steps overriding their defaults, a json object mapping step names to durations, e.g. {\"DeployControlPlaneStep\": \"2h\"}",
+	},
+	{
+		Key:         "BootstrapAnswers",
+		Type:        apitypes.ConfigTypeJSON,
+		Description: "Answers given when bootstrapping the deployment",
+	},
+}
+
+// configValidators validate the value of a config key, by key type
+var configValidators = map[string]func(apitypes.ConfigKeySchema, string) error{
+	apitypes.ConfigTypeString:    func(apitypes.ConfigKeySchema, string) error { return nil },
+	apitypes.ConfigTypeBool:      validateBool,
+	apitypes.ConfigTypeUint:      validateUint,
+	apitypes.ConfigTypeCIDR:      validateCIDR,
+	apitypes.ConfigTypeIP:        validateIP,
+	apitypes.ConfigTypeEnum:      validateEnum,
+	apitypes.ConfigTypePortRange: validatePortRange,
+	apitypes.ConfigTypeDuration:  validateDuration,
+	apitypes.ConfigTypeJSON:      validateJSON,
+}
+
+// LookupConfigKey returns the schema of a known config key
+func LookupConfigKey(schema apitypes.ConfigSchema, key string) (apitypes.ConfigKeySchema, bool) {
+	for _, keySchema := range schema {
+		if keySchema.Key == key {
+			return keySchema, true
+		}
+	}
+
+	return apitypes.ConfigKeySchema{}, false
+}
+
+// ValidateConfig checks value against the schema of key, returning a 400
+// StatusError on invalid values. Unknown keys are accepted, or rejected with
+// a 400 StatusError in strict mode. Returns whether the key is known.
+func ValidateConfig(schema apitypes.ConfigSchema, key string, value string, strict bool) (bool, error) {
+	keySchema, ok := LookupConfigKey(schema, key)
+	if !ok {
+		if strict {
+			return false, api.StatusErrorf(http.StatusBadRequest, "Unknown config key %q", key)
+		}
+
+		return false, nil
+	}
+
+	validate, ok := configValidators[keySchema.Type]
+	if !ok {
+		return true, fmt.Errorf("Unknown type %q for config key %q", keySchema.Type, key)
+	}
+
+	// Scalar values are usually stored as json strings, validate their content
+	scalar := value
+	if keySchema.Type != apitypes.ConfigTypeJSON {
+		scalar = configScalar(value)
+	}
+
+	err := validate(keySchema, scalar)
+	if err != nil {
+		return true, api.StatusErrorf(http.StatusBadRequest, "Invalid value for config key %q: %v", key, err)
+	}
+
+	return true, nil
+}
+
+// configScalar returns value decoded from a json string, or as is if value
+// is not a json string.
+func configScalar(value string) string {
+	var scalar string
+	err := json.Unmarshal([]byte(value), &scalar)
+	if err != nil {
+		return value
+	}
+
+	return scalar
+}
+
+func validateBool(_ apitypes.ConfigKeySchema, value string) error {
+	_, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", value)
+	}
+
+	return nil
+}
+
+func validateUint(_ apitypes.ConfigKeySchema, value string) error {
+	_, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a non negative integer", value)
+	}
+
+	return nil
+}
+
+func validateCIDR(_ apitypes.ConfigKeySchema, value string) error {
+	_, _, err := net.ParseCIDR(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a CIDR", value)
+	}
+
+	return nil
+}
+
+func validateIP(_ apitypes.ConfigKeySchema, value string) error {
+	if net.ParseIP(value) == nil {
+		return fmt.Errorf("%q is not an IP address", value)
+	}
+
+	return nil
+}
+
+func validateEnum(keySchema apitypes.ConfigKeySchema, value string) error {
+	if !slices.Contains(keySchema.Values, value) {
+		return fmt.Errorf("%q is not one of %s", value, strings.Join(keySchema.Values, ", "))
+	}
+
+	return nil
+}
+
+// validatePortRange accepts a port, e.g. 8080, or an inclusive range of
+// ports, e.g. 30000-32767
+func validatePortRange(_ apitypes.ConfigKeySchema, value string) error {
+	first, last, isRange := strings.Cut(value, "-")
+	if !isRange {
+		last = first
+	}
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 1 || start > 65535 {
+		return fmt.Errorf("%q is not a port or port range", value)
+	}
+
+	end, err := strconv.Atoi(last)
+	if err != nil || end < 1 || end > 65535 {
+		return fmt.Errorf("%q is not a port or port range", value)
+	}
+
+	if start > end {
+		return fmt.Errorf("%q starts after it ends", value)
+	}
+
+	return nil
+}
+
+func validateDuration(_ apitypes.ConfigKeySchema, value string) error {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration", value)
+	}
+
+	if duration < 0 {
+		return fmt.Errorf("%q must not be negative", value)
+	}
+
+	return nil
+}
+
+func validateJSON(_ apitypes.ConfigKeySchema, value string) error {
+	if !json.Valid([]byte(value)) {
+		return fmt.Errorf("value is not valid json")
+	}
+
+	return nil
+}
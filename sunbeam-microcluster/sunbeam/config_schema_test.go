@@ -0,0 +1,98 @@
+package sunbeam
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+var testConfigSchema = apitypes.ConfigSchema{
+	{Key: "external_network_cidr", Type: apitypes.ConfigTypeCIDR},
+	{Key: "external_gateway", Type: apitypes.ConfigTypeIP},
+	{Key: "deployment.type", Type: apitypes.ConfigTypeEnum, Values: []string{"local", "maas"}},
+	{Key: "sunbeam_bootstrapped", Type: apitypes.ConfigTypeBool},
+	{Key: "maintenance.max-parallel-migrations", Type: apitypes.ConfigTypeUint},
+	{Key: "nodeport_range", Type: apitypes.ConfigTypePortRange},
+	{Key: "BootstrapAnswers", Type: apitypes.ConfigTypeJSON},
+}
+
+// TestValidateConfig tests the validators of each config key type
+func TestValidateConfig(t *testing.T) {
+	testCases := []struct {
+		name  string
+		key   string
+		value string
+		valid bool
+	}{
+		{name: "ipv4 cidr", key: "external_network_cidr", value: "172.16.1.0/24", valid: true},
+		{name: "ipv6 cidr", key: "external_network_cidr", value: "fd42::/64", valid: true},
+		{name: "json encoded cidr", key: "external_network_cidr", value: `"10.0.0.0/8"`, valid: true},
+		{name: "not a cidr", key: "external_network_cidr", value: "not-a-cidr", valid: false},
+		{name: "ip without prefix", key: "external_network_cidr", value: "172.16.1.0", valid: false},
+		{name: "ip", key: "external_gateway", value: "172.16.1.1", valid: true},
+		{name: "not an ip", key: "external_gateway", value: "172.16.1.256", valid: false},
+		{name: "enum value", key: "deployment.type", value: "maas", valid: true},
+		{name: "unknown enum value", key: "deployment.type", value: "manual", valid: false},
+		{name: "enum is case sensitive", key: "deployment.type", value: "MAAS", valid: false},
+		{name: "json encoded bool", key: "sunbeam_bootstrapped", value: `"True"`, valid: true},
+		{name: "not a bool", key: "sunbeam_bootstrapped", value: "yes", valid: false},
+		{name: "uint", key: "maintenance.max-parallel-migrations", value: "4", valid: true},
+		{name: "json encoded uint", key: "maintenance.max-parallel-migrations", value: `"0"`, valid: true},
+		{name: "negative uint", key: "maintenance.max-parallel-migrations", value: "-1", valid: false},
+		{name: "not a uint", key: "maintenance.max-parallel-migrations", value: "four", valid: false},
+		{name: "port", key: "nodeport_range", value: "8080", valid: true},
+		{name: "port range", key: "nodeport_range", value: "30000-32767", valid: true},
+		{name: "reversed port range", key: "nodeport_range", value: "32767-30000", valid: false},
+		{name: "port out of range", key: "nodeport_range", value: "0-65536", valid: false},
+		{name: "json object", key: "BootstrapAnswers", value: `{"bootstrap": {}}`, valid: true},
+		{name: "invalid json", key: "BootstrapAnswers", value: `{"bootstrap":`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			known, err := ValidateConfig(testConfigSchema, tc.key, tc.value, false)
+			if !known {
+				t.Errorf("Expected key %q to be known", tc.key)
+			}
+
+			if tc.valid && err != nil {
+				t.Errorf("Expected %q to be valid, got %v", tc.value, err)
+			}
+
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error for %q, got %v", tc.value, err)
+			}
+		})
+	}
+}
+
+// TestValidateConfigUnknownKey tests that unknown keys are only rejected in
+// strict mode
+func TestValidateConfigUnknownKey(t *testing.T) {
+	known, err := ValidateConfig(testConfigSchema, "external_network_cdir", "not-a-cidr", false)
+	if known || err != nil {
+		t.Errorf("Expected unknown key to be accepted, got known=%v and %v", known, err)
+	}
+
+	known, err = ValidateConfig(testConfigSchema, "external_network_cdir", "not-a-cidr", true)
+	if known || !api.StatusErrorCheck(err, http.StatusBadRequest) {
+		t.Errorf("Expected unknown key to be rejected in strict mode, got known=%v and %v", known, err)
+	}
+}
+
+// TestConfigSchema tests that all the registered keys have a known type
+func TestConfigSchema(t *testing.T) {
+	for _, keySchema := range ConfigSchema {
+		_, ok := configValidators[keySchema.Type]
+		if !ok {
+			t.Errorf("Config key %q has unknown type %q", keySchema.Key, keySchema.Type)
+		}
+
+		if keySchema.Type == apitypes.ConfigTypeEnum && len(keySchema.Values) == 0 {
+			t.Errorf("Enum config key %q has no values", keySchema.Key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package sunbeam
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+)
+
+// TestCheckConfigRevision tests If-Match validation against stored revisions
+func TestCheckConfigRevision(t *testing.T) {
+	// The ETag a client gets back from GET /1.0/config/{key} at revision 3
+	req := httptest.NewRequest(http.MethodGet, "/1.0/config/key", nil)
+	rec := httptest.NewRecorder()
+	err := response.SyncResponseETag(true, "value", 3).Render(rec, req)
+	if err != nil {
+		t.Fatalf("Failed to render response: %v", err)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected ETag header to be set")
+	}
+
+	testCases := []struct {
+		name     string
+		ifMatch  string
+		exists   bool
+		revision int
+		wantErr  bool
+	}{
+		{
+			name:     "matching If-Match",
+			ifMatch:  etag,
+			exists:   true,
+			revision: 3,
+		},
+		{
+			name:     "stale If-Match",
+			ifMatch:  etag,
+			exists:   true,
+			revision: 4,
+			wantErr:  true,
+		},
+		{
+			name:     "missing If-Match",
+			ifMatch:  "",
+			exists:   true,
+			revision: 4,
+		},
+		{
+			name:    "missing If-Match on new key",
+			ifMatch: "",
+			exists:  false,
+		},
+		{
+			name:    "If-Match on new key",
+			ifMatch: etag,
+			exists:  false,
+			wantErr: true,
+		},
+		{
+			name:     "wildcard If-Match",
+			ifMatch:  "*",
+			exists:   true,
+			revision: 7,
+		},
+		{
+			name:    "wildcard If-Match on new key",
+			ifMatch: "*",
+			exists:  false,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckConfigRevision(tc.ifMatch, tc.exists, tc.revision)
+			if !tc.wantErr {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !api.StatusErrorCheck(err, http.StatusPreconditionFailed) {
+				t.Errorf("Expected precondition failed error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+)
+
+// configTransactionStore is the subset of config operations applied by a
+// config transaction, scoped to a single database transaction.
+type configTransactionStore interface {
+	// Get returns the value of key and whether it is set
+	Get(key string) (string, bool, error)
+	// Put records the value of key, returning whether it marks the
+	// deployment as bootstrapped
+	Put(key string, value string) (bool, error)
+}
+
+// txConfigTransactionStore is a configTransactionStore backed by a database
+// transaction
+type txConfigTransactionStore struct {
+	ctx   context.Context
+	tx    *sql.Tx
+	actor string
+}
+
+func (t txConfigTransactionStore) Get(key string) (string, bool, error) {
+	record, err := database.GetConfigItem(t.ctx, t.tx, key)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	return record.Value, true, nil
+}
+
+func (t txConfigTransactionStore) Put(key string, value string) (bool, error) {
+	return putConfigItem(t.ctx, t.tx, key, value, "", t.actor)
+}
+
+// ConfigTransactionError is returned for a config transaction rejected by
+// errors on some of its keys, it lists all of them. Its status is 400 if a
+// value is invalid, 412 if only preconditions failed.
+type ConfigTransactionError struct {
+	apitypes.ConfigTransactionFailed
+	status int
+}
+
+// Status returns the HTTP status of the rejection
+func (e *ConfigTransactionError) Status() int {
+	return e.status
+}
+
+func (e *ConfigTransactionError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, keyErr := range e.Errors {
+		messages = append(messages, keyErr.Error)
+	}
+
+	return fmt.Sprintf("Config transaction failed, %d keys rejected: %s", len(e.Errors), strings.Join(messages, "; "))
+}
+
+// ApplyConfigTransaction sets the keys of the transaction in a single
+// database transaction, if the keys of its preconditions hold their
+// expected values. Either all the keys are set or none are: every invalid
+// value and failed precondition is reported at once in a
+// ConfigTransactionError. The keys are scoped to the overrides of the role
+// of req, if set. Unknown keys are accepted, or rejected in strict mode.
+// Returns the unknown keys that were set.
+func ApplyConfigTransaction(ctx context.Context, s state.State, req apitypes.ConfigTransaction, strict bool, actor string) ([]string, error) {
+	metrics.ConfigOperationsTotal.WithLabelValues(metrics.ConfigWrite).Inc()
+
+	var unknown []string
+	var bootstrapped bool
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		store := txConfigTransactionStore{ctx: ctx, tx: tx, actor: actor}
+		unknown, bootstrapped, err = applyConfigTransaction(store, ConfigSchema, req, strict)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if bootstrapped {
+		emitEvent(ctx, apitypes.EventBootstrapCompleted, nil)
+	}
+
+	return unknown, nil
+}
+
+// applyConfigTransaction checks the preconditions and values of req, then
+// sets its keys in order, or their overrides for the role of req. It returns
+// a ConfigTransactionError listing the rejected keys, in which case the
+// caller must roll back the keys set so far, along with the unknown keys and
+// whether the deployment is marked as bootstrapped.
+func applyConfigTransaction(store configTransactionStore, schema apitypes.ConfigSchema, req apitypes.ConfigTransaction, strict bool) ([]string, bool, error) {
+	if len(req.Set) == 0 {
+		return nil, false, api.StatusErrorf(http.StatusBadRequest, "Config transaction sets no keys")
+	}
+
+	rejected := &ConfigTransactionError{
+		ConfigTransactionFailed: apitypes.ConfigTransactionFailed{Code: apitypes.ConfigTransactionFailedCode},
+		status:                  http.StatusPreconditionFailed,
+	}
+	reject := func(key string, err error) {
+		rejected.Errors = append(rejected.Errors, apitypes.ConfigKeyError{Key: key, Error: err.Error()})
+		if api.StatusErrorCheck(err, http.StatusBadRequest) {
+			rejected.status = http.StatusBadRequest
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(req.Preconditions)) {
+		err := checkConfigPrecondition(store, RoleConfigKey(key, req.Role), req.Preconditions[key])
+		if err != nil {
+			if !api.StatusErrorCheck(err, http.StatusPreconditionFailed) {
+				return nil, false, err
+			}
+			reject(key, err)
+		}
+	}
+
+	keys := slices.Sorted(maps.Keys(req.Set))
+	var unknown []string
+	for _, key := range keys {
+		known, err := ValidateConfig(schema, key, req.Set[key], strict)
+		if err != nil {
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				return nil, false, err
+			}
+			reject(key, err)
+		}
+
+		if !known && err == nil {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if len(rejected.Errors) > 0 {
+		return nil, false, rejected
+	}
+
+	// The checks specific to some keys are only run when recording them,
+	// carry on so that all their errors are reported
+	var bootstrapped bool
+	for _, key := range keys {
+		marked, err := store.Put(RoleConfigKey(key, req.Role), req.Set[key])
+		if err != nil {
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				return nil, false, fmt.Errorf("Failed to set config key %q: %w", key, err)
+			}
+			reject(key, err)
+		}
+
+		bootstrapped = bootstrapped || marked
+	}
+
+	if len(rejected.Errors) > 0 {
+		return nil, false, rejected
+	}
+
+	return unknown, bootstrapped, nil
+}
+
+// checkConfigPrecondition returns a 412 StatusError unless key holds
+// expected, or is unset if expected is nil
+func checkConfigPrecondition(store configTransactionStore, key string, expected *string) error {
+	value, exists, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case expected == nil && exists:
+		return api.StatusErrorf(http.StatusPreconditionFailed, "Config key %q is set, expected it to be unset", key)
+	case expected != nil && !exists:
+		return api.StatusErrorf(http.StatusPreconditionFailed, "Config key %q is not set, expected it to be set", key)
+	case expected != nil && value != *expected:
+		// The values are left out, they may be secrets
+		return api.StatusErrorf(http.StatusPreconditionFailed, "Config key %q does not hold its expected value", key)
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// memConfigTransactionStore is an in-memory configTransactionStore
+type memConfigTransactionStore struct {
+	items map[string]string
+}
+
+func (m *memConfigTransactionStore) Get(key string) (string, bool, error) {
+	value, ok := m.items[key]
+	return value, ok, nil
+}
+
+func (m *memConfigTransactionStore) Put(key string, value string) (bool, error) {
+	m.items[key] = value
+	return key == BootstrappedConfigKey && isTrue(value), nil
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func rejectedKeys(err error) []string {
+	var rejected *ConfigTransactionError
+	if !errors.As(err, &rejected) {
+		return nil
+	}
+
+	keys := make([]string, 0, len(rejected.Errors))
+	for _, keyErr := range rejected.Errors {
+		keys = append(keys, keyErr.Key)
+	}
+
+	return keys
+}
+
+// TestApplyConfigTransaction tests that the keys of a transaction are set
+// all at once, or none of them when a key is rejected before recording
+// them, with every rejected key reported.
+func TestApplyConfigTransaction(t *testing.T) {
+	initial := map[string]string{"sunbeam_bootstrapped": `"false"`, "deployment.type": `"local"`}
+
+	testCases := []struct {
+		name         string
+		req          apitypes.ConfigTransaction
+		strict       bool
+		wantStatus   int
+		wantRejected []string
+		wantItems    map[string]string
+		wantUnknown  []string
+	}{
+		{
+			name: "all keys set",
+			req: apitypes.ConfigTransaction{
+				Set: map[string]string{"sunbeam_bootstrapped": `"true"`, "external_gateway": `"10.0.0.1"`, "other": "x"},
+			},
+			wantItems:   map[string]string{"sunbeam_bootstrapped": `"true"`, "external_gateway": `"10.0.0.1"`, "deployment.type": `"local"`, "other": "x"},
+			wantUnknown: []string{"other"},
+		},
+		{
+			name: "one key invalid",
+			req: apitypes.ConfigTransaction{
+				Set: map[string]string{"sunbeam_bootstrapped": `"true"`, "external_gateway": `"not-an-ip"`},
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantRejected: []string{"external_gateway"},
+			wantItems:    initial,
+		},
+		{
+			name: "all invalid keys reported",
+			req: apitypes.ConfigTransaction{
+				Set: map[string]string{"sunbeam_bootstrapped": `"maybe"`, "external_gateway": `"not-an-ip"`, "deployment.type": `"cloud"`},
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantRejected: []string{"deployment.type", "external_gateway", "sunbeam_bootstrapped"},
+			wantItems:    initial,
+		},
+		{
+			name: "unknown key in strict mode",
+			req: apitypes.ConfigTransaction{
+				Set: map[string]string{"sunbeam_bootstrapped": `"true"`, "other": "x"},
+			},
+			strict:       true,
+			wantStatus:   http.StatusBadRequest,
+			wantRejected: []string{"other"},
+			wantItems:    initial,
+		},
+		{
+			name: "preconditions hold",
+			req: apitypes.ConfigTransaction{
+				Set:           map[string]string{"sunbeam_bootstrapped": `"true"`, "external_gateway": `"10.0.0.1"`},
+				Preconditions: map[string]*string{"sunbeam_bootstrapped": ptr(`"false"`), "external_gateway": nil},
+			},
+			wantItems: map[string]string{"sunbeam_bootstrapped": `"true"`, "external_gateway": `"10.0.0.1"`, "deployment.type": `"local"`},
+		},
+		{
+			name: "preconditions fail",
+			req: apitypes.ConfigTransaction{
+				Set:           map[string]string{"sunbeam_bootstrapped": `"true"`},
+				Preconditions: map[string]*string{"sunbeam_bootstrapped": ptr(`"true"`), "deployment.type": nil, "external_gateway": ptr(`"10.0.0.1"`)},
+			},
+			wantStatus:   http.StatusPreconditionFailed,
+			wantRejected: []string{"deployment.type", "external_gateway", "sunbeam_bootstrapped"},
+			wantItems:    initial,
+		},
+		{
+			name: "role overrides",
+			req: apitypes.ConfigTransaction{
+				Set:           map[string]string{"external_gateway": `"10.0.0.1"`},
+				Role:          "storage",
+				Preconditions: map[string]*string{"external_gateway": nil, "deployment.type": nil},
+			},
+			wantItems: map[string]string{
+				"sunbeam_bootstrapped":          `"false"`,
+				"deployment.type":               `"local"`,
+				"external_gateway@role:storage": `"10.0.0.1"`,
+			},
+		},
+		{
+			name: "precondition failed and key invalid",
+			req: apitypes.ConfigTransaction{
+				Set:           map[string]string{"external_gateway": `"not-an-ip"`},
+				Preconditions: map[string]*string{"deployment.type": ptr(`"maas"`)},
+			},
+			wantStatus:   http.StatusBadRequest,
+			wantRejected: []string{"deployment.type", "external_gateway"},
+			wantItems:    initial,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memConfigTransactionStore{items: maps.Clone(initial)}
+
+			unknown, _, err := applyConfigTransaction(store, testConfigSchema, tc.req, tc.strict)
+			if tc.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			} else {
+				var rejected *ConfigTransactionError
+				if !errors.As(err, &rejected) {
+					t.Fatalf("Expected a config transaction error, got %v", err)
+				}
+
+				if rejected.Status() != tc.wantStatus {
+					t.Errorf("Expected status %d, got %d", tc.wantStatus, rejected.Status())
+				}
+
+				if rejected.Code != apitypes.ConfigTransactionFailedCode {
+					t.Errorf("Expected code %q, got %q", apitypes.ConfigTransactionFailedCode, rejected.Code)
+				}
+			}
+
+			keys := rejectedKeys(err)
+			if !slices.Equal(keys, tc.wantRejected) {
+				t.Errorf("Expected rejected keys %v, got %v", tc.wantRejected, keys)
+			}
+
+			if !maps.Equal(store.items, tc.wantItems) {
+				t.Errorf("Expected items %v, got %v", tc.wantItems, store.items)
+			}
+
+			if !slices.Equal(unknown, tc.wantUnknown) {
+				t.Errorf("Expected unknown keys %v, got %v", tc.wantUnknown, unknown)
+			}
+		})
+	}
+}
+
+// TestApplyConfigTransactionBootstrapped tests that marking the deployment
+// as bootstrapped is reported
+func TestApplyConfigTransactionBootstrapped(t *testing.T) {
+	store := &memConfigTransactionStore{items: map[string]string{}}
+	req := apitypes.ConfigTransaction{Set: map[string]string{BootstrappedConfigKey: `"True"`}}
+
+	_, bootstrapped, err := applyConfigTransaction(store, testConfigSchema, req, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bootstrapped {
+		t.Error("Expected the deployment to be marked as bootstrapped")
+	}
+}
+
+// TestApplyConfigTransactionEmpty tests that a transaction must set a key
+func TestApplyConfigTransactionEmpty(t *testing.T) {
+	store := &memConfigTransactionStore{items: map[string]string{}}
+
+	_, _, err := applyConfigTransaction(store, testConfigSchema, apitypes.ConfigTransaction{}, false)
+	if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+		t.Errorf("Expected bad request error, got %v", err)
+	}
+}
+
+// TestApplyConfigTransactionRollback tests that a key rejected when
+// recorded in the database rolls back the keys recorded before it, along
+// with their history.
+func TestApplyConfigTransactionRollback(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	req := apitypes.ConfigTransaction{
+		Set: map[string]string{
+			DeploymentTypeConfigKey: `"maas"`,
+			BootstrappedConfigKey:   `"true"`,
+			WebhooksConfigKey:       `[{"name": "Not A Name", "url": "https://hooks.example.com"}]`,
+		},
+	}
+	err := fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		store := txConfigTransactionStore{ctx: ctx, tx: tx, actor: "ubuntu@node-1"}
+		_, _, err := applyConfigTransaction(store, ConfigSchema, req, false)
+		return err
+	})
+
+	keys := rejectedKeys(err)
+	if !slices.Equal(keys, []string{WebhooksConfigKey}) {
+		t.Fatalf("Expected rejected keys %v, got %v", []string{WebhooksConfigKey}, keys)
+	}
+
+	rows, err := db.QueryContext(t.Context(), `SELECT key, value FROM config`)
+	if err != nil {
+		t.Fatalf("Failed to fetch the config: %v", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	items := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		err := rows.Scan(&key, &value)
+		if err != nil {
+			t.Fatalf("Failed to fetch the config: %v", err)
+		}
+
+		items[key] = value
+	}
+
+	want := map[string]string{DeploymentTypeConfigKey: `"local"`}
+	if !maps.Equal(items, want) {
+		t.Errorf("Expected config %v, got %v", want, items)
+	}
+
+	if n := countRows(t, db, "config_history"); n != 0 {
+		t.Errorf("Expected no config history, got %d revisions", n)
+	}
+}
@@ -0,0 +1,149 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// databaseQueryer runs read queries, either in a database transaction or on
+// a database handle
+type databaseQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// VerifyDatabase runs the integrity check of the cluster database and counts
+// the rows of its tables. It only reads from the database, in a single
+// transaction, so it is safe to run while the cluster is in use.
+func VerifyDatabase(ctx context.Context, s state.State) (apitypes.DatabaseReport, error) {
+	var report apitypes.DatabaseReport
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		report, err = checkDatabase(ctx, tx)
+		return err
+	})
+
+	return report, err
+}
+
+// checkDatabase returns the integrity check report of the database queried
+// by q. The tables whose rows cannot be counted are reported as corrupted.
+func checkDatabase(ctx context.Context, q databaseQueryer) (apitypes.DatabaseReport, error) {
+	report := apitypes.DatabaseReport{Problems: []string{}, Tables: []apitypes.DatabaseTable{}}
+
+	problems, err := queryStrings(ctx, q, `PRAGMA integrity_check`)
+	if err != nil {
+		return apitypes.DatabaseReport{}, fmt.Errorf("Failed to check the database integrity: %w", err)
+	}
+
+	// A sound database reports a single "ok" row
+	if len(problems) != 1 || problems[0] != "ok" {
+		report.Problems = append(report.Problems, problems...)
+	}
+
+	tables, err := queryStrings(ctx, q, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return apitypes.DatabaseReport{}, fmt.Errorf("Failed to list the database tables: %w", err)
+	}
+
+	for _, table := range tables {
+		var rows int64
+		err := q.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, strings.ReplaceAll(table, `"`, `""`))).Scan(&rows)
+		if err != nil {
+			if ctx.Err() != nil {
+				return apitypes.DatabaseReport{}, ctx.Err()
+			}
+
+			report.Problems = append(report.Problems, fmt.Sprintf("Failed to count the rows of table %q: %v", table, err))
+			continue
+		}
+
+		report.Tables = append(report.Tables, apitypes.DatabaseTable{Name: table, Rows: rows})
+	}
+
+	pragmas := []struct {
+		name  string
+		value *int64
+	}{
+		{name: "page_size", value: &report.PageSize},
+		{name: "page_count", value: &report.Pages},
+		{name: "freelist_count", value: &report.FreePages},
+	}
+
+	for _, pragma := range pragmas {
+		err := q.QueryRowContext(ctx, "PRAGMA "+pragma.name).Scan(pragma.value)
+		if err != nil {
+			return apitypes.DatabaseReport{}, fmt.Errorf("Failed to read the database %s: %w", pragma.name, err)
+		}
+	}
+
+	report.OK = len(report.Problems) == 0
+
+	return report, nil
+}
+
+// queryStrings returns the first column of the rows of query
+func queryStrings(ctx context.Context, q databaseQueryer, query string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		err := rows.Scan(&value)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// CompactDatabase deletes the records clusterd no longer needs: the config
+// revisions past the history limit, which is only enforced on the next
+// write of their key, and the removed nodes past their retention. The pages
+// they used are left free for the later writes, dqlite reclaiming the disk
+// space of its log on its own through snapshots.
+func CompactDatabase(ctx context.Context, s state.State) (apitypes.DatabaseCompaction, error) {
+	var compaction apitypes.DatabaseCompaction
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		limit, err := configHistoryLimit(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		compaction.PurgedRevisions, err = database.DeleteConfigRevisionsBeyond(ctx, tx, limit)
+		return err
+	})
+	if err != nil {
+		return apitypes.DatabaseCompaction{}, err
+	}
+
+	compaction.PurgedTombstones, err = reapNodeTombstones(ctx, s, time.Now())
+	if err != nil {
+		return apitypes.DatabaseCompaction{}, fmt.Errorf("Failed to purge the removed nodes: %w", err)
+	}
+
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `PRAGMA freelist_count`).Scan(&compaction.FreePages)
+	})
+	if err != nil {
+		return apitypes.DatabaseCompaction{}, fmt.Errorf("Failed to read the database freelist_count: %w", err)
+	}
+
+	return compaction, nil
+}
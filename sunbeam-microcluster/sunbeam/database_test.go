@@ -0,0 +1,312 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/canonical/microcluster/v2/cluster"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+func openDatabase(t *testing.T, path string) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("Failed to open the database: %v", err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	// A single connection, so that the pragmas apply to all the statements
+	db.SetMaxOpenConns(1)
+
+	return db
+}
+
+// newFixtureDatabase returns the path of a SQLite database with the sunbeam
+// schema, two members and their nodes and a config item, and the database
+// opened with the registered statements prepared.
+func newFixtureDatabase(t *testing.T) (string, *sql.DB) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "fixture.db")
+	db := openDatabase(t, path)
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin the fixture transaction: %v", err)
+	}
+
+	for _, update := range database.SchemaExtensions {
+		err := update(t.Context(), tx)
+		if err != nil {
+			t.Fatalf("Failed to apply the schema: %v", err)
+		}
+	}
+
+	for _, stmt := range []string{
+		// The cluster members, as far as the nodes refer to them
+		`CREATE TABLE core_cluster_members (id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, name TEXT NOT NULL, UNIQUE(name))`,
+		`INSERT INTO core_cluster_members (id, name) VALUES (1, 'node-1'), (2, 'node-2')`,
+		`INSERT INTO nodes (member_id, name, role, machine_id) VALUES (1, 'node-1', '["control"]', 1), (2, 'node-2', '["compute"]', 2)`,
+		`INSERT INTO config (key, value) VALUES ('deployment.type', '"local"')`,
+	} {
+		_, err := tx.ExecContext(t.Context(), stmt)
+		if err != nil {
+			t.Fatalf("Failed to fill the fixture database: %v", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("Failed to commit the fixture database: %v", err)
+	}
+
+	// The statements of the other tables of microcluster itself fail to
+	// prepare, they are missing from the fixture
+	err = cluster.PrepareStmts(db, "", true)
+	if err != nil {
+		t.Fatalf("Failed to prepare the statements: %v", err)
+	}
+
+	return path, db
+}
+
+// fixtureTransaction runs f in a transaction of db, rolled back if f fails
+// and committed otherwise, like the dqlite transactions of the cluster
+func fixtureTransaction(t *testing.T, db *sql.DB, f func(context.Context, *sql.Tx) error) error {
+	t.Helper()
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin the transaction: %v", err)
+	}
+
+	err = f(t.Context(), tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatalf("Failed to commit the transaction: %v", err)
+	}
+
+	return nil
+}
+
+// countRows returns the number of rows of a table of db
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+
+	var n int
+	err := db.QueryRowContext(t.Context(), `SELECT COUNT(*) FROM `+table).Scan(&n)
+	if err != nil {
+		t.Fatalf("Failed to count the rows of %q: %v", table, err)
+	}
+
+	return n
+}
+
+func tableRows(report apitypes.DatabaseReport, name string) int64 {
+	for _, table := range report.Tables {
+		if table.Name == name {
+			return table.Rows
+		}
+	}
+
+	return -1
+}
+
+// TestCheckDatabase tests that a sound database passes the integrity check,
+// with the rows of its tables counted
+func TestCheckDatabase(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	report, err := checkDatabase(t.Context(), db)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !report.OK || len(report.Problems) != 0 {
+		t.Errorf("Expected the database to be sound, got problems %v", report.Problems)
+	}
+
+	if rows := tableRows(report, "nodes"); rows != 2 {
+		t.Errorf("Expected 2 nodes, got %d", rows)
+	}
+
+	if rows := tableRows(report, "config"); rows != 1 {
+		t.Errorf("Expected 1 config item, got %d", rows)
+	}
+
+	names := make([]string, 0, len(report.Tables))
+	for _, table := range report.Tables {
+		names = append(names, table.Name)
+	}
+
+	if !slices.IsSorted(names) || !slices.Contains(names, "maintenance_schedule") {
+		t.Errorf("Expected all the tables ordered by name, got %v", names)
+	}
+
+	if report.PageSize == 0 || report.Pages == 0 {
+		t.Errorf("Expected the page size and count, got %d and %d", report.PageSize, report.Pages)
+	}
+}
+
+// TestCheckDatabaseCorrupted tests that the corruption of a database is
+// reported rather than failing the check
+func TestCheckDatabaseCorrupted(t *testing.T) {
+	path, db := newFixtureDatabase(t)
+
+	// Dropping the schema entry of an index orphans its pages, which the
+	// integrity check of a new connection reports as never used
+	for _, stmt := range []string{
+		`CREATE INDEX nodes_role ON nodes (role)`,
+		`PRAGMA writable_schema = ON`,
+		`DELETE FROM sqlite_master WHERE name = 'nodes_role'`,
+		`PRAGMA writable_schema = OFF`,
+	} {
+		_, err := db.ExecContext(t.Context(), stmt)
+		if err != nil {
+			t.Fatalf("Failed to corrupt the fixture database: %v", err)
+		}
+	}
+
+	_ = db.Close()
+
+	report, err := checkDatabase(t.Context(), openDatabase(t, path))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if report.OK {
+		t.Fatal("Expected the database to be reported as corrupted")
+	}
+
+	if len(report.Problems) == 0 || !strings.Contains(strings.Join(report.Problems, "\n"), "never used") {
+		t.Errorf("Expected the orphaned pages to be reported, got %v", report.Problems)
+	}
+
+	if rows := tableRows(report, "nodes"); rows != 2 {
+		t.Errorf("Expected the rows of the sound tables to be counted, got %d nodes", rows)
+	}
+}
+
+// TestDeleteConfigRevisionsBeyond tests that only the revisions more than
+// the limit older than the latest one of their key are deleted
+func TestDeleteConfigRevisionsBeyond(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	_, err := db.ExecContext(t.Context(), `INSERT INTO config_history (key, revision, value, changed_at, changed_by) VALUES
+  ('a', 1, 'x', '', ''), ('a', 2, 'x', '', ''), ('a', 3, 'x', '', ''), ('a', 4, 'x', '', ''), ('a', 5, 'x', '', ''),
+  ('b', 1, 'x', '', ''), ('b', 2, 'x', '', '')`)
+	if err != nil {
+		t.Fatalf("Failed to record the revisions: %v", err)
+	}
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin the transaction: %v", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	n, err := database.DeleteConfigRevisionsBeyond(t.Context(), tx, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if n != 2 {
+		t.Errorf("Expected 2 revisions deleted, got %d", n)
+	}
+
+	for key, want := range map[string][]int{"a": {3, 4, 5}, "b": {1, 2}} {
+		revisions, err := database.GetConfigRevisions(t.Context(), tx, key)
+		if err != nil {
+			t.Fatalf("Failed to fetch the revisions of %q: %v", key, err)
+		}
+
+		got := make([]int, 0, len(revisions))
+		for _, revision := range revisions {
+			got = append(got, revision.Revision)
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected revisions %v of %q, got %v", want, key, got)
+		}
+	}
+}
+
+// TestOperationRecords tests that the status transitions of the operations
+// only apply from the expected status, keeping the fields not given
+func TestOperationRecords(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin the transaction: %v", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := database.CreateOperation(t.Context(), tx, database.Operation{Member: "node-1", Command: `["cluster"]`, Status: apitypes.OperationPending, CreatedAt: "2025-07-01T02:00:00Z", CreatedBy: "admin"})
+	if err != nil {
+		t.Fatalf("Failed to create the operation: %v", err)
+	}
+
+	ok, err := database.UpdateOperationStatus(t.Context(), tx, int(id), apitypes.OperationPending, apitypes.OperationRunning, "2025-07-01T02:01:00Z", "", "", "")
+	if err != nil || !ok {
+		t.Fatalf("Expected the operation started, got %v, %v", ok, err)
+	}
+
+	ok, err = database.UpdateOperationStatus(t.Context(), tx, int(id), apitypes.OperationPending, apitypes.OperationCancelled, "", "2025-07-01T02:02:00Z", "", "Cancelled by admin")
+	if err != nil || ok {
+		t.Fatalf("Expected a running operation not cancelled as pending, got %v, %v", ok, err)
+	}
+
+	err = database.UpdateOperationProgress(t.Context(), tx, int(id), "Migrating")
+	if err != nil {
+		t.Fatalf("Failed to record the progress: %v", err)
+	}
+
+	err = database.UpdateOperationHeartbeat(t.Context(), tx, int(id), "laptop", "2025-07-01T02:02:30Z")
+	if err != nil {
+		t.Fatalf("Failed to record the heartbeat: %v", err)
+	}
+
+	// A heartbeat without progress keeps the latest one
+	err = database.UpdateOperationProgress(t.Context(), tx, int(id), "")
+	if err != nil {
+		t.Fatalf("Failed to record the progress: %v", err)
+	}
+
+	ok, err = database.UpdateOperationStatus(t.Context(), tx, int(id), apitypes.OperationRunning, apitypes.OperationSucceeded, "", "2025-07-01T02:03:00Z", "Done", "")
+	if err != nil || !ok {
+		t.Fatalf("Expected the operation succeeded, got %v, %v", ok, err)
+	}
+
+	record, err := database.GetOperation(t.Context(), tx, int(id))
+	if err != nil {
+		t.Fatalf("Failed to fetch the operation: %v", err)
+	}
+
+	want := database.Operation{ID: int(id), Member: "node-1", Runner: "laptop", Command: `["cluster"]`, Status: apitypes.OperationSucceeded, Progress: "Migrating", Result: "Done", CreatedAt: "2025-07-01T02:00:00Z", CreatedBy: "admin", StartedAt: "2025-07-01T02:01:00Z", FinishedAt: "2025-07-01T02:03:00Z", HeartbeatAt: "2025-07-01T02:02:30Z"}
+	if *record != want {
+		t.Errorf("Expected %+v, got %+v", want, *record)
+	}
+
+	_, err = database.GetOperation(t.Context(), tx, int(id)+1)
+	if err == nil {
+		t.Error("Expected an error fetching an unknown operation")
+	}
+}
@@ -0,0 +1,272 @@
+package sunbeam
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+const (
+	// eventQueueSize bounds the events waiting to be dispatched to the
+	// webhooks
+	eventQueueSize = 256
+
+	// maxPendingDeliveries bounds the deliveries queued, in flight or
+	// waiting to be retried
+	maxPendingDeliveries = 1024
+
+	// webhookWorkers is the number of deliveries sent at once
+	webhookWorkers = 4
+
+	// webhookTimeout bounds a single delivery
+	webhookTimeout = 10 * time.Second
+
+	// maxDeliveryAttempts is the number of attempts before a delivery is
+	// given up
+	maxDeliveryAttempts = 5
+
+	// minDeliveryBackoff and maxDeliveryBackoff bound the delay before
+	// retrying a failed delivery
+	minDeliveryBackoff = time.Second
+	maxDeliveryBackoff = time.Minute
+
+	// signaturePrefix prefixes the hex HMAC in the signature header
+	signaturePrefix = "sha256="
+)
+
+// eventBus is the bus the daemon emits events on once started
+var eventBus atomic.Pointer[EventBus]
+
+// delivery is an event to post to a webhook
+type delivery struct {
+	hook    apitypes.Webhook
+	event   apitypes.Event
+	body    []byte
+	attempt int
+}
+
+// EventBus delivers the cluster events to the webhooks. Delivery is best
+// effort: events and deliveries are queued in bounded queues and dropped,
+// with a warning, once they are full, so that a slow webhook never blocks
+// cluster operations.
+type EventBus struct {
+	member     string
+	targets    func(ctx context.Context) (apitypes.Webhooks, error)
+	client     *http.Client
+	backoff    func(attempt int) time.Duration
+	events     chan apitypes.Event
+	deliveries chan delivery
+	pending    atomic.Int64
+}
+
+// NewEventBus returns an EventBus emitting the events of member, posting
+// them to the webhooks returned by targets at the time of the event.
+func NewEventBus(member string, targets func(ctx context.Context) (apitypes.Webhooks, error)) *EventBus {
+	return &EventBus{
+		member:     member,
+		targets:    targets,
+		client:     &http.Client{Timeout: webhookTimeout},
+		backoff:    deliveryBackoff,
+		events:     make(chan apitypes.Event, eventQueueSize),
+		deliveries: make(chan delivery, maxPendingDeliveries),
+	}
+}
+
+// StartEventBus starts the bus the daemon emits the cluster events on,
+// posting them to the webhooks defined in the cluster config.
+func StartEventBus(ctx context.Context, s state.State) {
+	bus := NewEventBus(s.Name(), func(ctx context.Context) (apitypes.Webhooks, error) {
+		return loadWebhooks(ctx, s)
+	})
+	bus.Start(ctx)
+	eventBus.Store(bus)
+
+	logger.Info("Started cluster event bus")
+}
+
+// Start starts the goroutines dispatching and delivering the events, until
+// ctx is done.
+func (b *EventBus) Start(ctx context.Context) {
+	go b.dispatchLoop(ctx)
+	for range webhookWorkers {
+		go b.deliverLoop(ctx)
+	}
+}
+
+// Emit queues an event of eventType with data, without blocking. The event
+// is dropped if the queue is full, logged with the logger of ctx.
+func (b *EventBus) Emit(ctx context.Context, eventType string, data map[string]string) {
+	event := apitypes.Event{
+		ID:     newEventID(),
+		Type:   eventType,
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Member: b.member,
+		Data:   data,
+	}
+
+	select {
+	case b.events <- event:
+	default:
+		logging.FromContext(ctx).Warn("Event queue full, dropping event", "type", event.Type, "event", event.ID)
+	}
+}
+
+// emitEvent emits an event on the daemon bus, it is a no-op until the bus is
+// started.
+func emitEvent(ctx context.Context, eventType string, data map[string]string) {
+	bus := eventBus.Load()
+	if bus == nil {
+		return
+	}
+
+	bus.Emit(ctx, eventType, data)
+}
+
+// dispatchLoop queues a delivery of each event to each webhook subscribed to
+// its type.
+func (b *EventBus) dispatchLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-b.events:
+			b.dispatch(ctx, event)
+		}
+	}
+}
+
+func (b *EventBus) dispatch(ctx context.Context, event apitypes.Event) {
+	hooks, err := b.targets(ctx)
+	if err != nil {
+		logger.Warnf("Failed to fetch webhooks, dropping %s event %s: %v", event.Type, event.ID, err)
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Warnf("Failed to encode %s event %s: %v", event.Type, event.ID, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, event.Type) {
+			continue
+		}
+
+		if b.pending.Add(1) > maxPendingDeliveries {
+			b.pending.Add(-1)
+			logger.Warnf("Webhook delivery queue full, dropping %s event %s for webhook %q", event.Type, event.ID, hook.Name)
+			continue
+		}
+
+		b.deliveries <- delivery{hook: hook, event: event, body: body, attempt: 1}
+	}
+}
+
+// deliverLoop posts the queued deliveries, scheduling the retry of the
+// failed ones.
+func (b *EventBus) deliverLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-b.deliveries:
+			b.deliver(ctx, d)
+		}
+	}
+}
+
+func (b *EventBus) deliver(ctx context.Context, d delivery) {
+	err := b.post(ctx, d)
+	if err == nil {
+		b.pending.Add(-1)
+		return
+	}
+
+	if d.attempt >= maxDeliveryAttempts || ctx.Err() != nil {
+		b.pending.Add(-1)
+		logger.Warnf("Giving up delivery of %s event %s to webhook %q after %d attempts: %v", d.event.Type, d.event.ID, d.hook.Name, d.attempt, err)
+		return
+	}
+
+	delay := b.backoff(d.attempt)
+	logger.Debugf("Failed delivery of %s event %s to webhook %q, retrying in %s: %v", d.event.Type, d.event.ID, d.hook.Name, delay, err)
+
+	// Pending deliveries are bounded by the queue capacity, re-queuing
+	// never blocks
+	d.attempt++
+	time.AfterFunc(delay, func() { b.deliveries <- d })
+}
+
+// post sends a delivery, signed with the webhook secret.
+func (b *EventBus) post(ctx context.Context, d delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.hook.URL, bytes.NewReader(d.body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(apitypes.WebhookEventHeader, d.event.Type)
+	req.Header.Set(apitypes.WebhookDeliveryHeader, d.event.ID)
+	req.Header.Set(apitypes.WebhookSignatureHeader, SignPayload(d.hook.Secret, d.body))
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SignPayload returns the signature header value of body for secret,
+// sha256=<hex HMAC-SHA256 of body>.
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature returns whether signature is the signature header value of
+// body for secret, for receivers to check a delivery is authentic.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignPayload(secret, body)), []byte(signature))
+}
+
+// deliveryBackoff returns the delay before the attempt following the given
+// attempt, doubling from minDeliveryBackoff up to maxDeliveryBackoff.
+func deliveryBackoff(attempt int) time.Duration {
+	delay := minDeliveryBackoff << (attempt - 1)
+	if delay <= 0 || delay > maxDeliveryBackoff {
+		return maxDeliveryBackoff
+	}
+
+	return delay
+}
+
+// newEventID returns a random event ID.
+func newEventID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
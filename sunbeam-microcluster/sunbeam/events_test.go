@@ -0,0 +1,167 @@
+package sunbeam
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// receivedDelivery is a delivery received by a test webhook
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestWebhook returns a test webhook server failing the first failures
+// requests, and the channel of the deliveries it accepted
+func newTestWebhook(t *testing.T, failures int32) (*httptest.Server, chan receivedDelivery) {
+	received := make(chan receivedDelivery, 10)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("Failed to read delivery: %v", err)
+		}
+
+		received <- receivedDelivery{header: r.Header, body: body}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+func startTestEventBus(t *testing.T, hooks apitypes.Webhooks) *EventBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	bus := NewEventBus("member1", func(context.Context) (apitypes.Webhooks, error) {
+		return hooks, nil
+	})
+	bus.backoff = func(int) time.Duration { return time.Millisecond }
+	bus.Start(ctx)
+
+	return bus
+}
+
+func waitDelivery(t *testing.T, received chan receivedDelivery) receivedDelivery {
+	select {
+	case d := <-received:
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the delivery")
+	}
+
+	return receivedDelivery{}
+}
+
+// TestEventBusDelivery tests that an event is posted to the webhook, signed
+// with its secret
+func TestEventBusDelivery(t *testing.T) {
+	server, received := newTestWebhook(t, 0)
+	bus := startTestEventBus(t, apitypes.Webhooks{{Name: "pager", URL: server.URL, Secret: "s3cret"}})
+
+	bus.Emit(context.Background(), apitypes.EventNodeAdded, map[string]string{"node": "node1"})
+
+	d := waitDelivery(t, received)
+	if !VerifySignature("s3cret", d.body, d.header.Get(apitypes.WebhookSignatureHeader)) {
+		t.Errorf("Invalid signature %q", d.header.Get(apitypes.WebhookSignatureHeader))
+	}
+
+	if VerifySignature("other", d.body, d.header.Get(apitypes.WebhookSignatureHeader)) {
+		t.Error("Signature verified with another secret")
+	}
+
+	if d.header.Get(apitypes.WebhookEventHeader) != apitypes.EventNodeAdded {
+		t.Errorf("Expected event header %q, got %q", apitypes.EventNodeAdded, d.header.Get(apitypes.WebhookEventHeader))
+	}
+
+	var event apitypes.Event
+	err := json.Unmarshal(d.body, &event)
+	if err != nil {
+		t.Fatalf("Invalid delivery body %q: %v", d.body, err)
+	}
+
+	if event.Type != apitypes.EventNodeAdded || event.Member != "member1" || event.Data["node"] != "node1" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	if d.header.Get(apitypes.WebhookDeliveryHeader) != event.ID {
+		t.Errorf("Expected delivery header %q, got %q", event.ID, d.header.Get(apitypes.WebhookDeliveryHeader))
+	}
+}
+
+// TestEventBusRetry tests that failed deliveries are retried
+func TestEventBusRetry(t *testing.T) {
+	server, received := newTestWebhook(t, 2)
+	bus := startTestEventBus(t, apitypes.Webhooks{{Name: "pager", URL: server.URL, Secret: "s3cret"}})
+
+	bus.Emit(context.Background(), apitypes.EventMaintenanceEntered, map[string]string{"node": "node1"})
+
+	d := waitDelivery(t, received)
+	if d.header.Get(apitypes.WebhookEventHeader) != apitypes.EventMaintenanceEntered {
+		t.Errorf("Unexpected event delivered %q", d.header.Get(apitypes.WebhookEventHeader))
+	}
+}
+
+// TestEventBusFilter tests that webhooks only receive the events they are
+// subscribed to
+func TestEventBusFilter(t *testing.T) {
+	server, received := newTestWebhook(t, 0)
+	bus := startTestEventBus(t, apitypes.Webhooks{{Name: "pager", URL: server.URL, Secret: "s3cret", Events: []string{apitypes.EventNodeRemoved}}})
+
+	bus.Emit(context.Background(), apitypes.EventNodeAdded, map[string]string{"node": "node1"})
+	bus.Emit(context.Background(), apitypes.EventNodeRemoved, map[string]string{"node": "node1"})
+
+	d := waitDelivery(t, received)
+	if d.header.Get(apitypes.WebhookEventHeader) != apitypes.EventNodeRemoved {
+		t.Errorf("Unexpected event delivered %q", d.header.Get(apitypes.WebhookEventHeader))
+	}
+}
+
+// TestMaintenanceEvent tests the events emitted on maintenance transitions
+func TestMaintenanceEvent(t *testing.T) {
+	testCases := []struct {
+		current string
+		next    string
+		event   string
+	}{
+		{current: "", next: apitypes.MaintenanceEnabled, event: apitypes.EventMaintenanceEntered},
+		{current: apitypes.MaintenanceDisabled, next: apitypes.MaintenanceEnabled, event: apitypes.EventMaintenanceEntered},
+		{current: apitypes.MaintenanceDegraded, next: apitypes.MaintenanceEnabled, event: apitypes.EventMaintenanceEntered},
+		{current: apitypes.MaintenanceEnabled, next: apitypes.MaintenanceEnabled, event: ""},
+		{current: apitypes.MaintenanceEnabled, next: apitypes.MaintenanceDegraded, event: ""},
+		{current: apitypes.MaintenanceEnabled, next: apitypes.MaintenanceDisabled, event: apitypes.EventMaintenanceExited},
+		{current: apitypes.MaintenanceDegraded, next: apitypes.MaintenanceDisabled, event: apitypes.EventMaintenanceExited},
+		{current: "", next: apitypes.MaintenanceDisabled, event: ""},
+	}
+
+	for _, tc := range testCases {
+		event := maintenanceEvent(tc.current, tc.next)
+		if event != tc.event {
+			t.Errorf("Expected event %q from %q to %q, got %q", tc.event, tc.current, tc.next, event)
+		}
+	}
+}
+
+// TestDeliveryBackoff tests the delivery backoff doubles up to its maximum
+func TestDeliveryBackoff(t *testing.T) {
+	if deliveryBackoff(1) != minDeliveryBackoff || deliveryBackoff(2) != 2*minDeliveryBackoff {
+		t.Errorf("Unexpected backoffs %s, %s", deliveryBackoff(1), deliveryBackoff(2))
+	}
+
+	if deliveryBackoff(100) != maxDeliveryBackoff {
+		t.Errorf("Expected backoff capped to %s, got %s", maxDeliveryBackoff, deliveryBackoff(100))
+	}
+}
@@ -136,7 +136,7 @@ func (fgs *featureGateSyncer) syncOnceUnlocked(ctx context.Context) error {
 	// In MAAS mode, we want one-way sync (snap -> cluster) but not writeback (cluster -> snap)
 	// because each node manages its own snap configuration independently
 	if fgs.state != nil {
-		deploymentType, err := GetConfig(ctx, fgs.state, "deployment.type")
+		deploymentType, err := GetConfig(ctx, fgs.state, DeploymentTypeConfigKey)
 		if err == nil && deploymentType == "maas" {
 			// Skip sync for MAAS deployments
 			return nil
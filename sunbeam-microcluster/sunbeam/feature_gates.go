@@ -16,7 +16,7 @@ func ListFeatureGates(ctx context.Context, s state.State) (apitypes.FeatureGates
 	gates := apitypes.FeatureGates{}
 
 	// Get the feature gates from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		records, err := database.GetFeatureGates(ctx, tx)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch feature gates: %w", err)
@@ -41,7 +41,7 @@ func ListFeatureGates(ctx context.Context, s state.State) (apitypes.FeatureGates
 // GetFeatureGate returns a FeatureGate with the given gate key.
 func GetFeatureGate(ctx context.Context, s state.State, gateKey string) (apitypes.FeatureGate, error) {
 	gate := apitypes.FeatureGate{}
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		record, err := database.GetFeatureGate(ctx, tx, gateKey)
 		if err != nil {
 			return err
@@ -61,7 +61,7 @@ func GetFeatureGate(ctx context.Context, s state.State, gateKey string) (apitype
 // AddFeatureGate adds a feature gate to the database.
 func AddFeatureGate(ctx context.Context, s state.State, gateKey string, enabled bool) error {
 	// Add feature gate to the database.
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := database.CreateFeatureGate(ctx, tx, database.FeatureGate{
 			GateKey: gateKey,
 			Enabled: enabled,
@@ -77,7 +77,7 @@ func AddFeatureGate(ctx context.Context, s state.State, gateKey string, enabled
 // UpdateFeatureGate updates a feature gate record in the database.
 func UpdateFeatureGate(ctx context.Context, s state.State, gateKey string, enabled bool) error {
 	// Update feature gate in the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := database.GetFeatureGate(ctx, tx, gateKey)
 		if err != nil {
 			return fmt.Errorf("Failed to retrieve feature gate details: %w", err)
@@ -99,7 +99,7 @@ func UpdateFeatureGate(ctx context.Context, s state.State, gateKey string, enabl
 // DeleteFeatureGate deletes a feature gate from the database.
 func DeleteFeatureGate(ctx context.Context, s state.State, gateKey string) error {
 	// Delete feature gate from the database.
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		err := database.DeleteFeatureGate(ctx, tx, gateKey)
 		if err != nil {
 			return fmt.Errorf("Failed to delete feature gate: %w", err)
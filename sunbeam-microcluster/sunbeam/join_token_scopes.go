@@ -0,0 +1,279 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// JoinRolesConfigKey is the key of the init config of a join holding the
+// comma separated roles the joining node is to be recorded with
+const JoinRolesConfigKey = "roles"
+
+// ListJoinTokenScopes returns the scopes of the join tokens ordered by node
+// name, including the expired and consumed ones.
+func ListJoinTokenScopes(ctx context.Context, s state.State) (apitypes.JoinTokenScopes, error) {
+	scopes := apitypes.JoinTokenScopes{}
+	now := time.Now()
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := database.GetJoinTokenScopes(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			scope, err := joinTokenScopeFromRecord(record, now)
+			if err != nil {
+				return err
+			}
+
+			scopes = append(scopes, scope)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scopes, nil
+}
+
+// CreateJoinTokenScope restricts the join token of a node name to the roles,
+// uses and TTL of req, returning the scope recorded. The scope replaces an
+// expired or consumed one, it returns a 409 StatusError if the token of the
+// name is already scoped, and a 400 StatusError for unknown roles.
+func CreateJoinTokenScope(ctx context.Context, s state.State, req apitypes.JoinTokenScope, actor string) (apitypes.JoinTokenScope, error) {
+	now := time.Now()
+	record, err := joinTokenScopeToRecord(req, actor, now)
+	if err != nil {
+		return apitypes.JoinTokenScope{}, err
+	}
+
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		err := validateNodeRoles(ctx, tx, req.Roles)
+		if err != nil {
+			return err
+		}
+
+		current, err := database.GetJoinTokenScope(ctx, tx, req.Name)
+		if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+			return err
+		}
+
+		if current != nil && joinTokenScopeState(*current, now) == apitypes.JoinTokenValid {
+			return api.StatusErrorf(http.StatusConflict, "Join token of node %q is already scoped", req.Name)
+		}
+
+		return database.UpsertJoinTokenScope(ctx, tx, record)
+	})
+	if err != nil {
+		return apitypes.JoinTokenScope{}, err
+	}
+
+	return joinTokenScopeFromRecord(record, now)
+}
+
+// DeleteJoinTokenScope deletes the scope of the join token of a node name,
+// returning a 404 StatusError if there is none
+func DeleteJoinTokenScope(ctx context.Context, s state.State, name string) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		return database.DeleteJoinTokenScope(ctx, tx, name)
+	})
+	if api.StatusErrorCheck(err, http.StatusNotFound) {
+		return api.StatusErrorf(http.StatusNotFound, "Join token scope of node %q not found", name)
+	}
+
+	return err
+}
+
+// ConsumeJoinTokenScope counts a use of the scope of the join token of this
+// member, joining the cluster with the roles of initConfig. It is run by the
+// pre-join hook, once the member joined the database but before the other
+// members trust it: a 403 StatusError fails the join, and microcluster then
+// removes the member.
+func ConsumeJoinTokenScope(ctx context.Context, s state.State, initConfig map[string]string) error {
+	roles := []string{}
+	if initConfig[JoinRolesConfigKey] != "" {
+		roles = strings.Split(initConfig[JoinRolesConfigKey], ",")
+	}
+
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		return consumeJoinTokenScope(ctx, tx, s.Name(), roles, time.Now())
+	})
+}
+
+// consumeJoinTokenScope counts a use of the scope of the join token of a
+// node joining with roles at now. It returns a 403 StatusError if the scope
+// is expired, consumed or does not permit the roles, or if the join does not
+// name its roles. Nodes whose token is not scoped are not restricted.
+func consumeJoinTokenScope(ctx context.Context, tx *sql.Tx, name string, roles []string, now time.Time) error {
+	record, err := database.GetJoinTokenScope(ctx, tx, name)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	scope, err := joinTokenScopeFromRecord(*record, now)
+	if err != nil {
+		return err
+	}
+
+	if len(roles) == 0 {
+		return api.StatusErrorf(http.StatusForbidden, "Join token of node %q is scoped, the join must name the roles of the node", name)
+	}
+
+	err = checkJoinTokenScope(scope, roles)
+	if err != nil {
+		return err
+	}
+
+	consumed, err := database.ConsumeJoinTokenScope(ctx, tx, name, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	if !consumed {
+		return api.StatusErrorf(http.StatusForbidden, "Join token of node %q is no longer valid", name)
+	}
+
+	return nil
+}
+
+// checkJoinTokenScopeRoles returns a 403 StatusError if the join token of
+// a node recorded with roles is scoped to other roles. The scope was
+// consumed by the join of the node, its state is not checked.
+func checkJoinTokenScopeRoles(ctx context.Context, tx *sql.Tx, name string, roles []string) error {
+	record, err := database.GetJoinTokenScope(ctx, tx, name)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	scope, err := joinTokenScopeFromRecord(*record, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return checkJoinTokenScopePermits(scope, roles)
+}
+
+// checkJoinTokenScope returns a 403 StatusError unless scope is valid and
+// permits all the roles
+func checkJoinTokenScope(scope apitypes.JoinTokenScope, roles []string) error {
+	switch scope.State {
+	case apitypes.JoinTokenExpired:
+		return api.StatusErrorf(http.StatusForbidden, "Join token of node %q expired at %s", scope.Name, scope.ExpiresAt)
+	case apitypes.JoinTokenConsumed:
+		return api.StatusErrorf(http.StatusForbidden, "Join token of node %q was already used", scope.Name)
+	}
+
+	return checkJoinTokenScopePermits(scope, roles)
+}
+
+// checkJoinTokenScopePermits returns a 403 StatusError unless scope permits
+// all the roles
+func checkJoinTokenScopePermits(scope apitypes.JoinTokenScope, roles []string) error {
+	for _, role := range roles {
+		if !slices.Contains(scope.Roles, role) {
+			return api.StatusErrorf(http.StatusForbidden, "Join token of node %q does not permit role %q, only %s", scope.Name, role, strings.Join(scope.Roles, ", "))
+		}
+	}
+
+	return nil
+}
+
+// joinTokenScopeState returns the state of the scope record at now
+func joinTokenScopeState(record database.JoinTokenScope, now time.Time) string {
+	switch {
+	case record.Used >= record.Uses:
+		return apitypes.JoinTokenConsumed
+	case record.ExpiresAt != "" && record.ExpiresAt <= now.UTC().Format(time.RFC3339):
+		return apitypes.JoinTokenExpired
+	}
+
+	return apitypes.JoinTokenValid
+}
+
+// joinTokenScopeToRecord validates scope and converts it to the record
+// created by actor at now. A scope permits a single use, as the join token
+// it restricts.
+func joinTokenScopeToRecord(scope apitypes.JoinTokenScope, actor string, now time.Time) (database.JoinTokenScope, error) {
+	if scope.Name == "" {
+		return database.JoinTokenScope{}, api.StatusErrorf(http.StatusBadRequest, "Join token scope must name its node")
+	}
+
+	if len(scope.Roles) == 0 {
+		return database.JoinTokenScope{}, api.StatusErrorf(http.StatusBadRequest, "Join token scope of node %q must permit at least a role", scope.Name)
+	}
+
+	uses := scope.Uses
+	if uses == 0 {
+		uses = 1
+	}
+
+	if uses != 1 {
+		return database.JoinTokenScope{}, api.StatusErrorf(http.StatusBadRequest, "Invalid join token uses %d, join tokens are single-use", uses)
+	}
+
+	var expiresAt string
+	if scope.TTL != "" {
+		ttl, err := time.ParseDuration(scope.TTL)
+		if err != nil || ttl <= 0 {
+			return database.JoinTokenScope{}, api.StatusErrorf(http.StatusBadRequest, "Invalid join token TTL %q, expected a positive duration such as 1h", scope.TTL)
+		}
+
+		expiresAt = now.Add(ttl).UTC().Format(time.RFC3339)
+	}
+
+	roles, err := roleToStr(slices.Clone(scope.Roles))
+	if err != nil {
+		return database.JoinTokenScope{}, err
+	}
+
+	return database.JoinTokenScope{
+		Name:      scope.Name,
+		Roles:     roles,
+		Uses:      uses,
+		ExpiresAt: expiresAt,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		CreatedBy: actor,
+	}, nil
+}
+
+// joinTokenScopeFromRecord converts a record to a JoinTokenScope in its
+// state at now
+func joinTokenScopeFromRecord(record database.JoinTokenScope, now time.Time) (apitypes.JoinTokenScope, error) {
+	var roles []string
+	err := json.Unmarshal([]byte(record.Roles), &roles)
+	if err != nil {
+		return apitypes.JoinTokenScope{}, fmt.Errorf("Failed to decode the roles of the join token scope of node %q: %w", record.Name, err)
+	}
+
+	return apitypes.JoinTokenScope{
+		Name:      record.Name,
+		Roles:     roles,
+		Uses:      record.Uses,
+		Used:      record.Used,
+		ExpiresAt: record.ExpiresAt,
+		State:     joinTokenScopeState(record, now),
+		CreatedAt: record.CreatedAt,
+		CreatedBy: record.CreatedBy,
+	}, nil
+}
@@ -0,0 +1,219 @@
+package sunbeam
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// recordJoinTokenScope records scope as created at now
+func recordJoinTokenScope(t *testing.T, tx *sql.Tx, scope apitypes.JoinTokenScope, now time.Time) {
+	t.Helper()
+
+	record, err := joinTokenScopeToRecord(scope, "ubuntu@node-1", now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = database.UpsertJoinTokenScope(t.Context(), tx, record)
+	if err != nil {
+		t.Fatalf("Failed to record the scope: %v", err)
+	}
+}
+
+// beginFixtureTx returns a transaction of a fixture database, rolled back
+// once the test ends
+func beginFixtureTx(t *testing.T) *sql.Tx {
+	t.Helper()
+
+	_, db := newFixtureDatabase(t)
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin the transaction: %v", err)
+	}
+
+	t.Cleanup(func() { _ = tx.Rollback() })
+
+	return tx
+}
+
+// TestJoinTokenScopeToRecord tests a scope must name its node and permit
+// roles, with a single use and a positive TTL
+func TestJoinTokenScopeToRecord(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		scope     apitypes.JoinTokenScope
+		wantErr   bool
+		uses      int
+		expiresAt string
+	}{
+		{name: "single use by default", scope: apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}}, uses: 1},
+		{name: "ttl", scope: apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, Uses: 1, TTL: "1h"}, uses: 1, expiresAt: "2025-03-01T13:00:00Z"},
+		{name: "several uses", scope: apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, Uses: 2}, wantErr: true},
+		{name: "no name", scope: apitypes.JoinTokenScope{Roles: []string{"compute"}}, wantErr: true},
+		{name: "no roles", scope: apitypes.JoinTokenScope{Name: "node-3"}, wantErr: true},
+		{name: "negative uses", scope: apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, Uses: -1}, wantErr: true},
+		{name: "invalid ttl", scope: apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, TTL: "an hour"}, wantErr: true},
+		{name: "negative ttl", scope: apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, TTL: "-1h"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := joinTokenScopeToRecord(tc.scope, "ubuntu@node-1", now)
+			if tc.wantErr {
+				if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+					t.Errorf("Expected a 400 error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if record.Uses != tc.uses || record.ExpiresAt != tc.expiresAt || record.Used != 0 {
+				t.Errorf("Expected %d uses expiring at %q, got %+v", tc.uses, tc.expiresAt, record)
+			}
+		})
+	}
+}
+
+// TestConsumeJoinTokenScopeExpiry tests an expired token is rejected and
+// listed as expired
+func TestConsumeJoinTokenScopeExpiry(t *testing.T) {
+	tx := beginFixtureTx(t)
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordJoinTokenScope(t, tx, apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, TTL: "1h"}, created)
+
+	err := consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"compute"}, created.Add(2*time.Hour))
+	if !api.StatusErrorCheck(err, http.StatusForbidden) || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("Expected a 403 error for the expired token, got %v", err)
+	}
+
+	record, err := database.GetJoinTokenScope(t.Context(), tx, "node-3")
+	if err != nil {
+		t.Fatalf("Failed to fetch the scope: %v", err)
+	}
+
+	if state := joinTokenScopeState(*record, created.Add(2*time.Hour)); state != apitypes.JoinTokenExpired || record.Used != 0 {
+		t.Errorf("Expected the unused scope to be expired, got %s with %d uses", state, record.Used)
+	}
+
+	// Expiry is checked by the statement counting the use too
+	consumed, err := database.ConsumeJoinTokenScope(t.Context(), tx, "node-3", "2025-03-01T13:00:00Z")
+	if err != nil || consumed {
+		t.Errorf("Expected the token not to be consumed at its expiry, got %t and %v", consumed, err)
+	}
+}
+
+// TestConsumeJoinTokenScopeExhaustion tests a token is rejected once its
+// uses are exhausted and listed as consumed
+func TestConsumeJoinTokenScopeExhaustion(t *testing.T) {
+	tx := beginFixtureTx(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordJoinTokenScope(t, tx, apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, TTL: "1h"}, now)
+
+	err := consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"compute"}, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"compute"}, now.Add(2*time.Minute))
+	if !api.StatusErrorCheck(err, http.StatusForbidden) || !strings.Contains(err.Error(), "already used") {
+		t.Fatalf("Expected a 403 error for the consumed token, got %v", err)
+	}
+
+	record, err := database.GetJoinTokenScope(t.Context(), tx, "node-3")
+	if err != nil {
+		t.Fatalf("Failed to fetch the scope: %v", err)
+	}
+
+	scope, err := joinTokenScopeFromRecord(*record, now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if scope.State != apitypes.JoinTokenConsumed || scope.Used != 1 || scope.Uses != 1 {
+		t.Errorf("Expected the scope to be consumed by its single use, got %+v", scope)
+	}
+}
+
+// TestConsumeJoinTokenScopeRoleMismatch tests a token is rejected for roles
+// it does not permit, without counting a use
+func TestConsumeJoinTokenScopeRoleMismatch(t *testing.T) {
+	tx := beginFixtureTx(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordJoinTokenScope(t, tx, apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"storage", "compute"}}, now)
+
+	err := consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"compute", "control"}, now)
+	if !api.StatusErrorCheck(err, http.StatusForbidden) || !strings.Contains(err.Error(), `does not permit role "control", only compute, storage`) {
+		t.Fatalf("Expected a 403 error for the control role, got %v", err)
+	}
+
+	err = consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"storage"}, now)
+	if err != nil {
+		t.Errorf("Expected a subset of the roles to be permitted, got %v", err)
+	}
+}
+
+// TestConsumeJoinTokenScopeNoRoles tests a join not naming its roles, such
+// as by an older CLI, is rejected when the token is scoped
+func TestConsumeJoinTokenScopeNoRoles(t *testing.T) {
+	tx := beginFixtureTx(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	recordJoinTokenScope(t, tx, apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}}, now)
+
+	err := consumeJoinTokenScope(t.Context(), tx, "node-3", []string{}, now)
+	if !api.StatusErrorCheck(err, http.StatusForbidden) || !strings.Contains(err.Error(), "must name the roles") {
+		t.Errorf("Expected a 403 error for the join without roles, got %v", err)
+	}
+}
+
+// TestConsumeJoinTokenScopeUnscoped tests the tokens of names without a
+// scope are not restricted
+func TestConsumeJoinTokenScopeUnscoped(t *testing.T) {
+	tx := beginFixtureTx(t)
+
+	err := consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"control"}, time.Now())
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestAddNodeRecordJoinTokenScope tests a node is recorded with the roles
+// its token, consumed by the join, permits only
+func TestAddNodeRecordJoinTokenScope(t *testing.T) {
+	tx := beginFixtureTx(t)
+	now := time.Now()
+	recordJoinTokenScope(t, tx, apitypes.JoinTokenScope{Name: "node-3", Roles: []string{"compute"}, TTL: "1h"}, now)
+
+	err := consumeJoinTokenScope(t.Context(), tx, "node-3", []string{"compute"}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = addNodeRecord(t.Context(), tx, database.Node{Member: "node-3", Name: "node-3", Role: `["control"]`, MachineID: 3, Labels: "{}"})
+	if !api.StatusErrorCheck(err, http.StatusForbidden) {
+		t.Fatalf("Expected a 403 error for the control role, got %v", err)
+	}
+
+	var nodes int
+	err = tx.QueryRowContext(t.Context(), `SELECT count(*) FROM nodes WHERE name = 'node-3'`).Scan(&nodes)
+	if err != nil || nodes != 0 {
+		t.Errorf("Expected the rejected node not to be recorded, got %d nodes and %v", nodes, err)
+	}
+
+	err = checkJoinTokenScopeRoles(t.Context(), tx, "node-3", []string{"compute"})
+	if err != nil {
+		t.Errorf("Expected the permitted role once the token is consumed, got %v", err)
+	}
+}
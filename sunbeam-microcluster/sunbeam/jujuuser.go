@@ -16,7 +16,7 @@ func ListJujuUsers(ctx context.Context, s state.State) (apitypes.JujuUsers, erro
 	users := apitypes.JujuUsers{}
 
 	// Get the juju users from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		records, err := database.GetJujuUsers(ctx, tx)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch juju user: %w", err)
@@ -41,7 +41,7 @@ func ListJujuUsers(ctx context.Context, s state.State) (apitypes.JujuUsers, erro
 // GetJujuUser returns a JujuUser with the given name
 func GetJujuUser(ctx context.Context, s state.State, name string) (apitypes.JujuUser, error) {
 	jujuUser := apitypes.JujuUser{}
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		record, err := database.GetJujuUser(ctx, tx, name)
 		if err != nil {
 			return err
@@ -59,7 +59,7 @@ func GetJujuUser(ctx context.Context, s state.State, name string) (apitypes.Juju
 // AddJujuUser adds a Jujuuser to the database
 func AddJujuUser(ctx context.Context, s state.State, name string, token string) error {
 	// Add juju user to the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := database.CreateJujuUser(ctx, tx, database.JujuUser{Username: name, Token: token})
 		if err != nil {
 			return fmt.Errorf("Failed to record juju user: %w", err)
@@ -77,7 +77,7 @@ func AddJujuUser(ctx context.Context, s state.State, name string, token string)
 // DeleteJujuUser deletes the juju user record from the database
 func DeleteJujuUser(ctx context.Context, s state.State, name string) error {
 	// Delete juju user from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		err := database.DeleteJujuUser(ctx, tx, name)
 		if err != nil {
 			return fmt.Errorf("Failed to delete juju user: %w", err)
@@ -95,7 +95,7 @@ func DeleteJujuUser(ctx context.Context, s state.State, name string) error {
 // UpdateJujuUser updates the juju user's token in the database
 func UpdateJujuUser(ctx context.Context, s state.State, name string, token string) error {
 	// Update juju user in the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		jujuUser := database.JujuUser{Username: name, Token: token}
 		err := database.UpdateJujuUser(ctx, tx, name, jujuUser)
 		if err != nil {
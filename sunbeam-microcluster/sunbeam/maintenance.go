@@ -0,0 +1,198 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// ListMaintenanceStatus returns the maintenance state of all the nodes ordered by join time
+func ListMaintenanceStatus(ctx context.Context, s state.State) (apitypes.MaintenanceStatusList, error) {
+	statuses := apitypes.MaintenanceStatusList{}
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := database.GetMaintenances(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch maintenance status: %w", err)
+		}
+
+		for _, record := range records {
+			statuses = append(statuses, maintenanceStatusFromRecord(record))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// UpdateMaintenanceStatus records the maintenance state of a node, emitting
+// an event when it enters or exits maintenance
+func UpdateMaintenanceStatus(ctx context.Context, s state.State, status apitypes.MaintenanceStatus) error {
+	err := ValidateMaintenanceStatus(status.Status)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := parseMaintenanceTTL(status)
+	if err != nil {
+		return err
+	}
+
+	var current, next apitypes.MaintenanceStatus
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := database.GetMaintenances(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch maintenance status: %w", err)
+		}
+
+		var retry database.Maintenance
+		for _, record := range records {
+			if record.Node == status.Node {
+				current = maintenanceStatusFromRecord(record)
+				retry = record
+				break
+			}
+		}
+
+		next = nextMaintenanceStatus(current, status, ttl, time.Now())
+
+		// Explicit updates start over the retries of a failed automatic
+		// disable, but for those keeping the node in maintenance with the same
+		// expiry, such as the disable operation recording a partial failure
+		if next.Status == apitypes.MaintenanceDisabled || next.ExpiresAt != current.ExpiresAt {
+			retry = database.Maintenance{}
+		}
+
+		return database.UpsertMaintenance(ctx, tx, database.Maintenance{
+			Node:                status.Node,
+			Status:              next.Status,
+			EnteredAt:           next.EnteredAt,
+			Strategy:            next.Strategy,
+			TriggeredBy:         next.TriggeredBy,
+			Message:             next.Message,
+			ExpiresAt:           next.ExpiresAt,
+			AutoDisableAttempts: retry.AutoDisableAttempts,
+			NextAttemptAt:       retry.NextAttemptAt,
+			DisableOperation:    retry.DisableOperation,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	eventType := maintenanceEvent(current.Status, next.Status)
+	if eventType != "" {
+		emitEvent(ctx, eventType, map[string]string{"node": next.Node, "strategy": next.Strategy, "triggered_by": next.TriggeredBy})
+	}
+
+	return nil
+}
+
+// maintenanceEvent returns the type of the event emitted when the
+// maintenance status of a node changes from current to next, empty if none.
+// A node enters maintenance once enabled, a degraded status meaning the
+// maintenance operation partially failed. It exits maintenance once disabled
+// from an enabled or degraded status.
+func maintenanceEvent(current string, next string) string {
+	switch {
+	case next == apitypes.MaintenanceEnabled && current != apitypes.MaintenanceEnabled:
+		return apitypes.EventMaintenanceEntered
+	case next == apitypes.MaintenanceDisabled && (current == apitypes.MaintenanceEnabled || current == apitypes.MaintenanceDegraded):
+		return apitypes.EventMaintenanceExited
+	}
+
+	return ""
+}
+
+// ValidateMaintenanceStatus checks the status is a known maintenance status
+func ValidateMaintenanceStatus(status string) error {
+	if !slices.Contains(apitypes.MaintenanceStatuses, status) {
+		return api.StatusErrorf(http.StatusBadRequest, "Unknown maintenance status %q", status)
+	}
+
+	return nil
+}
+
+// parseMaintenanceTTL returns the TTL requested along with status, 0 if none.
+// Only entering maintenance accepts a TTL.
+func parseMaintenanceTTL(status apitypes.MaintenanceStatus) (time.Duration, error) {
+	if status.TTL == "" {
+		return 0, nil
+	}
+
+	if status.Status != apitypes.MaintenanceEnabled && status.Status != apitypes.MaintenanceDegraded {
+		return 0, api.StatusErrorf(http.StatusBadRequest, "Maintenance TTL cannot be set with status %q", status.Status)
+	}
+
+	ttl, err := time.ParseDuration(status.TTL)
+	if err != nil || ttl <= 0 {
+		return 0, api.StatusErrorf(http.StatusBadRequest, "Invalid maintenance TTL %q, expected a positive duration such as 2h", status.TTL)
+	}
+
+	return ttl, nil
+}
+
+// maintenanceStatusFromRecord converts a record to a MaintenanceStatus, nodes
+// without a record are not in maintenance.
+func maintenanceStatusFromRecord(record database.Maintenance) apitypes.MaintenanceStatus {
+	status := record.Status
+	if status == "" {
+		status = apitypes.MaintenanceDisabled
+	}
+
+	return apitypes.MaintenanceStatus{
+		Node:        record.Node,
+		Status:      status,
+		EnteredAt:   record.EnteredAt,
+		Strategy:    record.Strategy,
+		TriggeredBy: record.TriggeredBy,
+		Message:     record.Message,
+		ExpiresAt:   record.ExpiresAt,
+	}
+}
+
+// nextMaintenanceStatus returns the state to record when moving from current
+// to requested with ttl at now. The time the node entered maintenance is kept
+// while it stays enabled or degraded, and cleared once maintenance is
+// disabled. A TTL sets the expiry from now, so a repeated enable extends it,
+// otherwise the current expiry is kept until maintenance is disabled.
+func nextMaintenanceStatus(current apitypes.MaintenanceStatus, requested apitypes.MaintenanceStatus, ttl time.Duration, now time.Time) apitypes.MaintenanceStatus {
+	next := requested
+	next.TTL = ""
+	inMaintenance := current.Status == apitypes.MaintenanceEnabled || current.Status == apitypes.MaintenanceDegraded
+
+	switch {
+	case requested.Status == apitypes.MaintenanceDisabled:
+		next.EnteredAt = ""
+	case inMaintenance:
+		next.EnteredAt = current.EnteredAt
+	default:
+		next.EnteredAt = now.UTC().Format(time.RFC3339)
+	}
+
+	switch {
+	case requested.Status == apitypes.MaintenanceDisabled:
+		next.ExpiresAt = ""
+	case ttl > 0:
+		next.ExpiresAt = now.Add(ttl).UTC().Format(time.RFC3339)
+	case inMaintenance:
+		next.ExpiresAt = current.ExpiresAt
+	default:
+		next.ExpiresAt = ""
+	}
+
+	return next
+}
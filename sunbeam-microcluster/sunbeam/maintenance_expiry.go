@@ -0,0 +1,256 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+const (
+	// maintenanceExpiryInterval is how often maintenance TTLs are checked
+	maintenanceExpiryInterval = time.Minute
+
+	// minAutoDisableBackoff and maxAutoDisableBackoff bound the delay before
+	// retrying a failed automatic disable
+	minAutoDisableBackoff = time.Minute
+	maxAutoDisableBackoff = time.Hour
+
+	// autoDisableRunnerTimeout is how long a disable operation may wait for
+	// a runner before the maintenance is reported degraded
+	autoDisableRunnerTimeout = 15 * time.Minute
+
+	// maintenanceExpiryActor is recorded as the trigger of automatic disables
+	maintenanceExpiryActor = "sunbeam-clusterd"
+)
+
+// maintenanceDisableCommand is the sunbeam command, with the node name
+// appended, of the operations taking a node out of maintenance once its TTL
+// elapsed.
+var maintenanceDisableCommand = []string{"cluster", "maintenance", "disable", "--yes"}
+
+// StartMaintenanceExpirer starts a background goroutine that requests an
+// operation disabling maintenance of the nodes recorded by this member once
+// their TTL elapsed. clusterd lacks the credentials to disable maintenance,
+// the operations are run by `sunbeam operation agent`, the operation-agent
+// service of the snap. Expiries are persisted, so they are enforced across
+// restarts.
+func StartMaintenanceExpirer(ctx context.Context, s state.State) {
+	go expireMaintenanceLoop(ctx, s)
+
+	logger.Info("Started maintenance TTL expirer")
+}
+
+// expireMaintenanceLoop checks the maintenance TTLs at start, then
+// periodically.
+func expireMaintenanceLoop(ctx context.Context, s state.State) {
+	ticker := time.NewTicker(maintenanceExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		err := expireMaintenances(ctx, s, time.Now())
+		if err != nil {
+			logger.Warnf("Failed to check maintenance TTLs: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping maintenance TTL expirer")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireMaintenances requests the disable of maintenance of the nodes due at
+// now and records the outcome.
+func expireMaintenances(ctx context.Context, s state.State, now time.Time) error {
+	var records []database.Maintenance
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		records, err = database.GetMaintenances(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to fetch maintenance status: %w", err)
+	}
+
+	for _, record := range dueMaintenances(records, s.Name(), now) {
+		err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+			return expireMaintenance(ctx, tx, record, s.Name(), now)
+		})
+		if err != nil {
+			logger.Warnf("Failed to record automatic disable of maintenance for node %q: %v", record.Node, err)
+		}
+	}
+
+	return nil
+}
+
+// expireMaintenance follows the automatic disable of the maintenance of
+// record in the transaction, so that a disable operation is requested along
+// with the record referring to it. The record is left as is if it changed
+// since it was read, such as by the disable operation.
+func expireMaintenance(ctx context.Context, tx *sql.Tx, record database.Maintenance, member string, now time.Time) error {
+	records, err := database.GetMaintenances(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(records, record) {
+		return nil
+	}
+
+	next := autoDisableMaintenance(txOperationStore{ctx: ctx, tx: tx}, record, member, now)
+	if next == record {
+		return nil
+	}
+
+	return database.UpsertMaintenance(ctx, tx, next)
+}
+
+// dueMaintenances returns the records of the nodes recorded by member whose
+// maintenance TTL elapsed at now, leaving out those waiting to retry a failed
+// automatic disable. Only the recording member acts on a node, so that its
+// maintenance is disabled once across the cluster.
+func dueMaintenances(records []database.Maintenance, member string, now time.Time) []database.Maintenance {
+	due := []database.Maintenance{}
+	for _, record := range records {
+		if record.Member != member || record.ExpiresAt == "" {
+			continue
+		}
+
+		if record.Status != apitypes.MaintenanceEnabled && record.Status != apitypes.MaintenanceDegraded {
+			continue
+		}
+
+		if !elapsed(record.ExpiresAt, now) {
+			continue
+		}
+
+		if record.NextAttemptAt != "" && !elapsed(record.NextAttemptAt, now) {
+			continue
+		}
+
+		due = append(due, record)
+	}
+
+	return due
+}
+
+// elapsed returns whether the RFC3339 time at is not after now. Unparsable
+// times are considered elapsed so that they do not block maintenance forever.
+func elapsed(at string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return true
+	}
+
+	return !t.After(now)
+}
+
+// autoDisableMaintenance follows the operation disabling maintenance of the
+// node of record on behalf of member, requesting one if there is none, and
+// returns the record to persist. The operation records the node disabled
+// once it succeeds. While it waits for a runner the record says so, so that
+// the expired maintenance is visible in the maintenance status, and the node
+// is degraded once it waited for autoDisableRunnerTimeout. A failed
+// operation keeps the node degraded with the error and schedules a new one
+// with an exponential backoff.
+func autoDisableMaintenance(operations operationStore, record database.Maintenance, member string, now time.Time) database.Maintenance {
+	if record.DisableOperation != 0 {
+		operation, err := getOperation(operations, record.DisableOperation)
+		if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+			logger.Warnf("Failed to fetch operation %d disabling maintenance of node %q: %v", record.DisableOperation, record.Node, err)
+			return record
+		}
+
+		switch {
+		case err != nil:
+			// Lost, such as with a database restored from a backup
+		case operation.Status == apitypes.OperationPending && elapsed(operation.CreatedAt, now.Add(-autoDisableRunnerTimeout)):
+			return autoDisableStranded(record, operation)
+		case operation.Status == apitypes.OperationPending:
+			return autoDisableWaiting(record, awaitingRunner(operation.ID))
+		case operation.Status == apitypes.OperationRunning || operation.Status == apitypes.OperationCancelling:
+			return autoDisableWaiting(record, fmt.Sprintf("operation %d is disabling it on %s", operation.ID, operation.Runner))
+		case operation.Status != apitypes.OperationSucceeded:
+			return autoDisableFailed(record, fmt.Errorf("operation %d %s: %s", operation.ID, operation.Status, operation.Error), now)
+		}
+
+		// Succeeded, but maintenance was enabled again since
+	}
+
+	logger.Infof("Maintenance TTL of node %q expired at %s, requesting its disable", record.Node, record.ExpiresAt)
+
+	operation, err := createOperation(operations, append(slices.Clone(maintenanceDisableCommand), record.Node), member, maintenanceExpiryActor, now)
+	if err != nil {
+		return autoDisableFailed(record, fmt.Errorf("failed to request the disable: %w", err), now)
+	}
+
+	next := record
+	next.DisableOperation = operation.ID
+	next.NextAttemptAt = ""
+	return autoDisableWaiting(next, awaitingRunner(operation.ID))
+}
+
+// autoDisableWaiting returns record updated while its disable operation
+// waits or runs, as described by progress.
+func autoDisableWaiting(record database.Maintenance, progress string) database.Maintenance {
+	next := record
+	next.TriggeredBy = maintenanceExpiryActor
+	next.Message = fmt.Sprintf("Maintenance TTL expired at %s, %s", record.ExpiresAt, progress)
+
+	return next
+}
+
+// autoDisableStranded returns record degraded as its pending disable
+// operation waited for a runner for too long. The operation is kept, it runs
+// once a runner is available.
+func autoDisableStranded(record database.Maintenance, operation database.Operation) database.Maintenance {
+	next := autoDisableWaiting(record, fmt.Sprintf("no runner claimed operation %d since %s, is `sunbeam operation agent` running?", operation.ID, operation.CreatedAt))
+	if next.Status != apitypes.MaintenanceDegraded {
+		logger.Warnf("Disable of maintenance of node %q waits for a runner since %s", record.Node, operation.CreatedAt)
+	}
+
+	next.Status = apitypes.MaintenanceDegraded
+
+	return next
+}
+
+// autoDisableFailed returns record updated after a failed automatic disable
+// at now, to be retried with a new operation after the backoff.
+func autoDisableFailed(record database.Maintenance, err error, now time.Time) database.Maintenance {
+	next := record
+	next.DisableOperation = 0
+	next.AutoDisableAttempts++
+	retryAt := now.Add(autoDisableBackoff(next.AutoDisableAttempts)).UTC().Format(time.RFC3339)
+	next.Status = apitypes.MaintenanceDegraded
+	next.TriggeredBy = maintenanceExpiryActor
+	next.NextAttemptAt = retryAt
+	next.Message = fmt.Sprintf("Automatic disable after TTL expiry failed %d times, retrying at %s: %v", next.AutoDisableAttempts, retryAt, err)
+
+	logger.Warnf("Failed to disable maintenance of node %q after its TTL expired: %v", record.Node, err)
+
+	return next
+}
+
+// autoDisableBackoff returns the delay before the attempt following the
+// given number of failed attempts.
+func autoDisableBackoff(attempts int) time.Duration {
+	backoff := minAutoDisableBackoff
+	for i := 1; i < attempts && backoff < maxAutoDisableBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxAutoDisableBackoff)
+}
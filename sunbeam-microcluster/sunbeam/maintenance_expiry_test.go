@@ -0,0 +1,240 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestDueMaintenances tests which nodes have their maintenance TTL expired
+func TestDueMaintenances(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []database.Maintenance{
+		{Node: "expired", Member: "member-1", Status: "enabled", ExpiresAt: "2025-03-01T11:59:00Z"},
+		{Node: "not-expired", Member: "member-1", Status: "enabled", ExpiresAt: "2025-03-01T12:01:00Z"},
+		{Node: "no-ttl", Member: "member-1", Status: "enabled"},
+		{Node: "disabled", Member: "member-1", Status: "disabled", ExpiresAt: "2025-03-01T11:00:00Z"},
+		{Node: "other-member", Member: "member-2", Status: "enabled", ExpiresAt: "2025-03-01T11:00:00Z"},
+		{Node: "retry-due", Member: "member-1", Status: "degraded", ExpiresAt: "2025-03-01T11:00:00Z", AutoDisableAttempts: 1, NextAttemptAt: "2025-03-01T12:00:00Z"},
+		{Node: "retry-pending", Member: "member-1", Status: "degraded", ExpiresAt: "2025-03-01T11:00:00Z", AutoDisableAttempts: 2, NextAttemptAt: "2025-03-01T12:02:00Z"},
+	}
+
+	var due []string
+	for _, record := range dueMaintenances(records, "member-1", now) {
+		due = append(due, record.Node)
+	}
+
+	want := []string{"expired", "retry-due"}
+	if !slices.Equal(due, want) {
+		t.Errorf("Expected due nodes %v, got %v", want, due)
+	}
+}
+
+// TestAutoDisableMaintenance tests that the disable of a node whose TTL
+// expired is requested once as an operation, and followed until it runs
+func TestAutoDisableMaintenance(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	record := database.Maintenance{Node: "node-1", Member: "member-1", Status: "enabled", EnteredAt: "2025-03-01T10:00:00Z", TriggeredBy: "ubuntu@node-1", ExpiresAt: "2025-03-01T12:00:00Z"}
+	operations := &memOperationStore{}
+
+	next := autoDisableMaintenance(operations, record, "member-1", now)
+	if len(operations.records) != 1 || next.DisableOperation != 1 {
+		t.Fatalf("Expected an operation requested, got %+v and %+v", operations.records, next)
+	}
+
+	operation := operationFromRecord(operations.get(t, 1))
+	if !slices.Equal(operation.Command, []string{"cluster", "maintenance", "disable", "--yes", "node-1"}) || operation.CreatedBy != maintenanceExpiryActor || operation.Member != "member-1" {
+		t.Errorf("Expected an operation of clusterd disabling node-1, got %+v", operation)
+	}
+
+	// The node stays in maintenance until the operation runs, saying why
+	if next.Status != "enabled" || next.TriggeredBy != maintenanceExpiryActor || !strings.Contains(next.Message, "waiting for a runner to run operation 1") {
+		t.Errorf("Expected maintenance waiting for a runner, got %+v", next)
+	}
+
+	// A pending operation is not requested again
+	waiting := autoDisableMaintenance(operations, next, "member-1", now.Add(time.Minute))
+	if waiting != next || len(operations.records) != 1 {
+		t.Errorf("Expected the pending operation followed, got %+v", waiting)
+	}
+
+	operations.records[0].Status = apitypes.OperationRunning
+	operations.records[0].Runner = "agent:1"
+	running := autoDisableMaintenance(operations, next, "member-1", now.Add(time.Minute))
+	if running.DisableOperation != 1 || !strings.Contains(running.Message, "operation 1 is disabling it on agent:1") {
+		t.Errorf("Expected the running operation reported, got %+v", running)
+	}
+
+	// Enabled again once the operation succeeded, the new expiry requests a
+	// new operation
+	operations.records[0].Status = apitypes.OperationSucceeded
+	again := autoDisableMaintenance(operations, running, "member-1", now.Add(time.Hour))
+	if again.DisableOperation != 2 || len(operations.records) != 2 {
+		t.Errorf("Expected a new operation requested, got %+v", again)
+	}
+}
+
+// TestAutoDisableMaintenanceStranded tests that a node whose disable
+// operation waits for a runner for too long is reported degraded, keeping
+// the operation until a runner claims it
+func TestAutoDisableMaintenanceStranded(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	record := database.Maintenance{Node: "node-1", Member: "member-1", Status: "enabled", ExpiresAt: "2025-03-01T12:00:00Z"}
+	operations := &memOperationStore{}
+
+	requested := autoDisableMaintenance(operations, record, "member-1", now)
+
+	waiting := autoDisableMaintenance(operations, requested, "member-1", now.Add(autoDisableRunnerTimeout-time.Minute))
+	if waiting.Status != "enabled" {
+		t.Errorf("Expected the node in maintenance while the operation waits, got %+v", waiting)
+	}
+
+	stranded := autoDisableMaintenance(operations, requested, "member-1", now.Add(autoDisableRunnerTimeout))
+	if stranded.Status != "degraded" || stranded.DisableOperation != 1 || len(operations.records) != 1 {
+		t.Fatalf("Expected the node degraded with its operation kept, got %+v", stranded)
+	}
+
+	if !strings.Contains(stranded.Message, "no runner claimed operation 1 since 2025-03-01T12:00:00Z") {
+		t.Errorf("Expected the stranded operation in the message, got %q", stranded.Message)
+	}
+
+	// Still followed once a runner claims it
+	operations.records[0].Status = apitypes.OperationRunning
+	operations.records[0].Runner = "agent:1"
+	running := autoDisableMaintenance(operations, stranded, "member-1", now.Add(autoDisableRunnerTimeout+time.Minute))
+	if running.DisableOperation != 1 || !strings.Contains(running.Message, "operation 1 is disabling it on agent:1") {
+		t.Errorf("Expected the running operation reported, got %+v", running)
+	}
+}
+
+// TestExpireMaintenance tests that the disable operation is requested in the
+// transaction recording it in the maintenance of the node, so that it is
+// requested once even if the transaction fails.
+func TestExpireMaintenance(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	record := database.Maintenance{Node: "node-1", Member: "node-1", Status: "enabled", EnteredAt: "2025-03-01T10:00:00Z", ExpiresAt: "2025-03-01T11:00:00Z"}
+
+	err := fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		return database.UpsertMaintenance(ctx, tx, record)
+	})
+	if err != nil {
+		t.Fatalf("Failed to record the maintenance: %v", err)
+	}
+
+	// Failing after the request rolls it back along with the record
+	errCrash := errors.New("crash")
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		err := expireMaintenance(ctx, tx, record, "node-1", now)
+		if err != nil {
+			return err
+		}
+
+		return errCrash
+	})
+	if !errors.Is(err, errCrash) {
+		t.Fatalf("Expected the transaction to fail, got %v", err)
+	}
+
+	if n := countRows(t, db, "operations"); n != 0 {
+		t.Fatalf("Expected no operation once rolled back, got %d", n)
+	}
+
+	for range 2 {
+		err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+			records, err := database.GetMaintenances(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			for _, current := range dueMaintenances(records, "node-1", now) {
+				err := expireMaintenance(ctx, tx, current, "node-1", now)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if n := countRows(t, db, "operations"); n != 1 {
+		t.Errorf("Expected a single disable operation, got %d", n)
+	}
+
+	var operation int
+	err = db.QueryRowContext(t.Context(), `SELECT disable_operation FROM maintenance`).Scan(&operation)
+	if err != nil {
+		t.Fatalf("Failed to fetch the maintenance: %v", err)
+	}
+
+	if operation != 1 {
+		t.Errorf("Expected the maintenance to refer to operation 1, got %d", operation)
+	}
+}
+
+// TestAutoDisableMaintenanceRetries tests that a failed automatic disable is
+// surfaced in the status and requested again with an increasing backoff
+func TestAutoDisableMaintenanceRetries(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	record := database.Maintenance{Node: "node-1", Member: "member-1", Status: "enabled", ExpiresAt: "2025-03-01T12:00:00Z"}
+	operations := &memOperationStore{}
+
+	requested := autoDisableMaintenance(operations, record, "member-1", now)
+	operations.records[0].Status = apitypes.OperationFailed
+	operations.records[0].Error = "exit status 1: live migration of instance-1 failed"
+
+	next := autoDisableMaintenance(operations, requested, "member-1", now)
+	if next.Status != "degraded" || next.AutoDisableAttempts != 1 || next.NextAttemptAt != "2025-03-01T12:01:00Z" || next.DisableOperation != 0 {
+		t.Fatalf("Expected a retry in a minute, got %+v", next)
+	}
+
+	if !strings.Contains(next.Message, "operation 1 failed: exit status 1: live migration of instance-1 failed") {
+		t.Errorf("Expected the failure in the message, got %q", next.Message)
+	}
+
+	// Not retried before the backoff elapsed
+	if len(dueMaintenances([]database.Maintenance{next}, "member-1", now.Add(30*time.Second))) != 0 {
+		t.Errorf("Expected no retry before %s", next.NextAttemptAt)
+	}
+
+	retry := autoDisableMaintenance(operations, next, "member-1", now.Add(time.Minute))
+	if retry.DisableOperation != 2 || retry.NextAttemptAt != "" || retry.AutoDisableAttempts != 1 {
+		t.Fatalf("Expected a new operation requested, got %+v", retry)
+	}
+
+	operations.records[1].Status = apitypes.OperationCancelled
+	operations.records[1].Error = "Cancelled by admin"
+	retried := autoDisableMaintenance(operations, retry, "member-1", now.Add(time.Minute))
+	if retried.AutoDisableAttempts != 2 || retried.NextAttemptAt != "2025-03-01T12:03:00Z" || retried.ExpiresAt != record.ExpiresAt {
+		t.Errorf("Expected a second retry two minutes later, got %+v", retried)
+	}
+}
+
+// TestAutoDisableBackoff tests that the backoff doubles up to its maximum
+func TestAutoDisableBackoff(t *testing.T) {
+	testCases := map[int]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		7:  maxAutoDisableBackoff,
+		50: maxAutoDisableBackoff,
+	}
+
+	for attempts, want := range testCases {
+		got := autoDisableBackoff(attempts)
+		if got != want {
+			t.Errorf("Attempt %d: expected backoff %s, got %s", attempts, want, got)
+		}
+	}
+}
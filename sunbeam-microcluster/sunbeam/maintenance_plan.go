@@ -0,0 +1,164 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// GetMaintenancePlans returns the maintenance plans recorded for a node,
+// only the plan of operation if not empty.
+func GetMaintenancePlans(ctx context.Context, s state.State, node string, operation string) (apitypes.MaintenancePlanList, error) {
+	if operation != "" {
+		err := validateMaintenancePlanOperation(operation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	plans := apitypes.MaintenancePlanList{}
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := database.GetMaintenancePlans(ctx, tx, node)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if operation != "" && record.Operation != operation {
+				continue
+			}
+
+			plan, err := maintenancePlanFromRecord(record)
+			if err != nil {
+				return err
+			}
+
+			plans = append(plans, plan)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plans, nil
+}
+
+// UpdateMaintenancePlan records the plan of a maintenance operation of a
+// node, replacing the previous plan of the operation.
+func UpdateMaintenancePlan(ctx context.Context, s state.State, plan apitypes.MaintenancePlan, actor string) error {
+	record, err := maintenancePlanToRecord(plan, actor, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		return database.UpsertMaintenancePlan(ctx, tx, record)
+	})
+}
+
+// validateMaintenancePlanOperation checks the operation is a known
+// maintenance plan operation
+func validateMaintenancePlanOperation(operation string) error {
+	if !slices.Contains(apitypes.MaintenancePlanOperations, operation) {
+		return api.StatusErrorf(http.StatusBadRequest, "Unknown maintenance plan operation %q", operation)
+	}
+
+	return nil
+}
+
+// validateMaintenancePlan checks the operation and state of the plan, a plan
+// which timed out or failed must give its reason
+func validateMaintenancePlan(plan apitypes.MaintenancePlan) error {
+	err := validateMaintenancePlanOperation(plan.Operation)
+	if err != nil {
+		return err
+	}
+
+	if !slices.Contains(apitypes.MaintenancePlanStates, plan.State) {
+		return api.StatusErrorf(http.StatusBadRequest, "Unknown maintenance plan state %q", plan.State)
+	}
+
+	if plan.State != apitypes.PlanSucceeded && plan.Reason == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Maintenance plan in state %q must give its reason", plan.State)
+	}
+
+	return nil
+}
+
+// maintenancePlanToRecord validates plan and converts it to the record
+// created by actor at now
+func maintenancePlanToRecord(plan apitypes.MaintenancePlan, actor string, now time.Time) (database.MaintenancePlan, error) {
+	err := validateMaintenancePlan(plan)
+	if err != nil {
+		return database.MaintenancePlan{}, err
+	}
+
+	if plan.Actions == nil {
+		plan.Actions = []apitypes.MaintenancePlanAction{}
+	}
+
+	if plan.Efficacy == nil {
+		plan.Efficacy = []apitypes.MaintenancePlanIndicator{}
+	}
+
+	actions, err := json.Marshal(plan.Actions)
+	if err != nil {
+		return database.MaintenancePlan{}, fmt.Errorf("Failed to encode the maintenance plan actions: %w", err)
+	}
+
+	efficacy, err := json.Marshal(plan.Efficacy)
+	if err != nil {
+		return database.MaintenancePlan{}, fmt.Errorf("Failed to encode the maintenance plan efficacy: %w", err)
+	}
+
+	return database.MaintenancePlan{
+		Node:       plan.Node,
+		Operation:  plan.Operation,
+		Audit:      plan.Audit,
+		ActionPlan: plan.ActionPlan,
+		State:      plan.State,
+		Reason:     plan.Reason,
+		Actions:    string(actions),
+		Efficacy:   string(efficacy),
+		CreatedAt:  now.UTC().Format(time.RFC3339),
+		CreatedBy:  actor,
+	}, nil
+}
+
+// maintenancePlanFromRecord converts a record to a MaintenancePlan
+func maintenancePlanFromRecord(record database.MaintenancePlan) (apitypes.MaintenancePlan, error) {
+	plan := apitypes.MaintenancePlan{
+		Node:       record.Node,
+		Operation:  record.Operation,
+		Audit:      record.Audit,
+		ActionPlan: record.ActionPlan,
+		State:      record.State,
+		Reason:     record.Reason,
+		CreatedAt:  record.CreatedAt,
+		CreatedBy:  record.CreatedBy,
+	}
+
+	err := json.Unmarshal([]byte(record.Actions), &plan.Actions)
+	if err != nil {
+		return apitypes.MaintenancePlan{}, fmt.Errorf("Failed to decode the actions of the %s plan of node %q: %w", record.Operation, record.Node, err)
+	}
+
+	err = json.Unmarshal([]byte(record.Efficacy), &plan.Efficacy)
+	if err != nil {
+		return apitypes.MaintenancePlan{}, fmt.Errorf("Failed to decode the efficacy of the %s plan of node %q: %w", record.Operation, record.Node, err)
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,130 @@
+package sunbeam
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestValidateMaintenancePlan tests the plans recorded must have a known
+// operation and state, with the reason of a timeout or failure
+func TestValidateMaintenancePlan(t *testing.T) {
+	testCases := []struct {
+		name    string
+		plan    apitypes.MaintenancePlan
+		wantErr bool
+	}{
+		{name: "succeeded", plan: apitypes.MaintenancePlan{Operation: "enable", State: "succeeded"}},
+		{name: "timed out", plan: apitypes.MaintenancePlan{Operation: "disable", State: "timed-out", Reason: "Audit not SUCCEEDED after 180s"}},
+		{name: "failed", plan: apitypes.MaintenancePlan{Operation: "enable", State: "failed", Reason: "No feasible action plan"}},
+		{name: "unknown operation", plan: apitypes.MaintenancePlan{Operation: "drain", State: "succeeded"}, wantErr: true},
+		{name: "unknown state", plan: apitypes.MaintenancePlan{Operation: "enable", State: "ONGOING"}, wantErr: true},
+		{name: "failed without reason", plan: apitypes.MaintenancePlan{Operation: "enable", State: "failed"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMaintenancePlan(tc.plan)
+			if !tc.wantErr {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected a 400 error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestMaintenancePlanRecord tests a plan is recorded with its actions and
+// efficacy, the latest plan of an operation replacing the previous one
+func TestMaintenancePlanRecord(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	value := 75.0
+
+	tx, err := db.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to begin the transaction: %v", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	plans := []apitypes.MaintenancePlan{
+		{Node: "node-2", Operation: "enable", Audit: "audit-1", State: "timed-out", Reason: "Audit not SUCCEEDED"},
+		{Node: "node-2", Operation: "disable", Audit: "audit-2", State: "failed", Reason: "No feasible action plan"},
+		{
+			Node: "node-2", Operation: "enable", Audit: "audit-3", ActionPlan: "plan-3", State: "succeeded",
+			Actions: []apitypes.MaintenancePlanAction{{
+				UUID: "action-1", Type: "migrate", State: "PENDING",
+				Parameters:  map[string]any{"migration_type": "live", "resource_id": "vm-1"},
+				Description: "Moving a VM instance from source_node to destination_node",
+			}},
+			Efficacy: []apitypes.MaintenancePlanIndicator{{Name: "live_migrate_instance_count", Value: &value, Unit: "%"}},
+		},
+	}
+
+	for _, plan := range plans {
+		record, err := maintenancePlanToRecord(plan, "ubuntu@node-1", now)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		err = database.UpsertMaintenancePlan(t.Context(), tx, record)
+		if err != nil {
+			t.Fatalf("Failed to record the plan: %v", err)
+		}
+	}
+
+	records, err := database.GetMaintenancePlans(t.Context(), tx, "node-2")
+	if err != nil {
+		t.Fatalf("Failed to fetch the plans: %v", err)
+	}
+
+	if len(records) != 2 || records[0].Operation != "disable" || records[1].Operation != "enable" {
+		t.Fatalf("Expected the disable and enable plans, got %+v", records)
+	}
+
+	disable, err := maintenancePlanFromRecord(records[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if disable.Reason != "No feasible action plan" || disable.Actions == nil || len(disable.Actions) != 0 {
+		t.Errorf("Expected the failed plan with its reason and no actions, got %+v", disable)
+	}
+
+	enable, err := maintenancePlanFromRecord(records[1])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if enable.Audit != "audit-3" || enable.State != "succeeded" || enable.Reason != "" {
+		t.Errorf("Expected the latest enable plan, got %+v", enable)
+	}
+
+	if enable.CreatedAt != "2025-03-01T12:00:00Z" || enable.CreatedBy != "ubuntu@node-1" {
+		t.Errorf("Expected the plan created by ubuntu@node-1 at noon, got %q by %q", enable.CreatedAt, enable.CreatedBy)
+	}
+
+	if len(enable.Actions) != 1 || enable.Actions[0].Parameters["resource_id"] != "vm-1" || enable.Actions[0].Description == "" {
+		t.Errorf("Expected the migrate action with its rationale, got %+v", enable.Actions)
+	}
+
+	if len(enable.Efficacy) != 1 || enable.Efficacy[0].Value == nil || *enable.Efficacy[0].Value != value {
+		t.Errorf("Expected the efficacy indicator, got %+v", enable.Efficacy)
+	}
+
+	_, err = database.GetMaintenancePlans(t.Context(), tx, "node-3")
+	if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		t.Errorf("Expected a 404 error for an unknown node, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package sunbeam
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+const (
+	// maxProgressEvents bounds the progress events kept per node, the
+	// oldest are dropped
+	maxProgressEvents = 1000
+
+	// MaxProgressWait bounds how long a progress request waits for events
+	MaxProgressWait = time.Minute
+)
+
+// progressLog holds the recent maintenance progress events of the nodes, in
+// memory: progress is transient, it is only served by the member the events
+// are posted to.
+type progressLog struct {
+	mu     sync.Mutex
+	seq    int64
+	events map[string][]apitypes.MaintenanceProgress
+	// updated is closed and replaced on each append, waking up the waiters
+	updated chan struct{}
+}
+
+func newProgressLog() *progressLog {
+	return &progressLog{
+		events:  map[string][]apitypes.MaintenanceProgress{},
+		updated: make(chan struct{}),
+	}
+}
+
+// maintenanceProgress is the progress log of the daemon
+var maintenanceProgress = newProgressLog()
+
+// append records events of node at now, assigning their sequence numbers.
+// Events without a time are stamped with now.
+func (l *progressLog) append(node string, events []apitypes.MaintenanceProgress, now time.Time) apitypes.MaintenanceProgressList {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recorded := make(apitypes.MaintenanceProgressList, 0, len(events))
+	for _, event := range events {
+		l.seq++
+		event.Seq = l.seq
+		event.Node = node
+		if event.Time == "" {
+			event.Time = now.UTC().Format(time.RFC3339)
+		}
+		recorded = append(recorded, event)
+	}
+
+	nodeEvents := append(l.events[node], recorded...)
+	if len(nodeEvents) > maxProgressEvents {
+		nodeEvents = nodeEvents[len(nodeEvents)-maxProgressEvents:]
+	}
+	l.events[node] = nodeEvents
+
+	close(l.updated)
+	l.updated = make(chan struct{})
+
+	return recorded
+}
+
+// since returns the events of node after the sequence number after, and a
+// channel closed on the next append.
+func (l *progressLog) since(node string, after int64) (apitypes.MaintenanceProgressList, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events := apitypes.MaintenanceProgressList{}
+	for _, event := range l.events[node] {
+		if event.Seq > after {
+			events = append(events, event)
+		}
+	}
+
+	return events, l.updated
+}
+
+// wait returns the events of node after the sequence number after, waiting
+// up to timeout for some if there are none yet.
+func (l *progressLog) wait(ctx context.Context, node string, after int64, timeout time.Duration) apitypes.MaintenanceProgressList {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		events, updated := l.since(node, after)
+		if len(events) > 0 {
+			return events
+		}
+
+		select {
+		case <-updated:
+		case <-timer.C:
+			return events
+		case <-ctx.Done():
+			return events
+		}
+	}
+}
+
+// AddMaintenanceProgress records progress events of the maintenance operation
+// on node, returning them with their sequence numbers.
+func AddMaintenanceProgress(node string, events []apitypes.MaintenanceProgress) apitypes.MaintenanceProgressList {
+	return maintenanceProgress.append(node, events, time.Now())
+}
+
+// GetMaintenanceProgress returns the progress events of node after the
+// sequence number after. If there are none yet, it waits up to wait, capped
+// to MaxProgressWait, for new events, so that clients can follow the
+// progress with successive requests.
+func GetMaintenanceProgress(ctx context.Context, node string, after int64, wait time.Duration) apitypes.MaintenanceProgressList {
+	return maintenanceProgress.wait(ctx, node, after, min(wait, MaxProgressWait))
+}
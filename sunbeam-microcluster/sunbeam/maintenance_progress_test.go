@@ -0,0 +1,82 @@
+package sunbeam
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// TestProgressLogSince tests that events are returned per node after a
+// sequence number
+func TestProgressLogSince(t *testing.T) {
+	log := newProgressLog()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	recorded := log.append("node1", []apitypes.MaintenanceProgress{{Instance: "i1", State: apitypes.ProgressMigrating}}, now)
+	log.append("node2", []apitypes.MaintenanceProgress{{Instance: "i2", State: apitypes.ProgressMigrating}}, now)
+	log.append("node1", []apitypes.MaintenanceProgress{{Instance: "i1", State: apitypes.ProgressSucceeded, Time: "2026-01-01T00:01:00Z"}}, now)
+
+	if recorded[0].Seq != 1 || recorded[0].Node != "node1" || recorded[0].Time != "2026-01-01T00:00:00Z" {
+		t.Errorf("Unexpected recorded event %+v", recorded[0])
+	}
+
+	events, _ := log.since("node1", 0)
+	if len(events) != 2 || events[1].Seq != 3 || events[1].Time != "2026-01-01T00:01:00Z" {
+		t.Errorf("Unexpected node1 events %+v", events)
+	}
+
+	events, _ = log.since("node1", 1)
+	if len(events) != 1 || events[0].State != apitypes.ProgressSucceeded {
+		t.Errorf("Unexpected node1 events after 1 %+v", events)
+	}
+
+	events, _ = log.since("node3", 0)
+	if len(events) != 0 {
+		t.Errorf("Unexpected node3 events %+v", events)
+	}
+}
+
+// TestProgressLogBounded tests that only the most recent events are kept
+func TestProgressLogBounded(t *testing.T) {
+	log := newProgressLog()
+	for i := range maxProgressEvents + 10 {
+		log.append("node1", []apitypes.MaintenanceProgress{{Instance: fmt.Sprint(i)}}, time.Now())
+	}
+
+	events, _ := log.since("node1", 0)
+	if len(events) != maxProgressEvents || events[0].Seq != 11 {
+		t.Errorf("Expected the last %d events, got %d from %d", maxProgressEvents, len(events), events[0].Seq)
+	}
+}
+
+// TestProgressLogWait tests that waiting requests return once events are
+// appended, or once they time out
+func TestProgressLogWait(t *testing.T) {
+	log := newProgressLog()
+
+	done := make(chan apitypes.MaintenanceProgressList)
+	go func() {
+		done <- log.wait(context.Background(), "node1", 0, 5*time.Second)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	log.append("node2", []apitypes.MaintenanceProgress{{Instance: "i2"}}, time.Now())
+	log.append("node1", []apitypes.MaintenanceProgress{{Instance: "i1"}}, time.Now())
+
+	select {
+	case events := <-done:
+		if len(events) != 1 || events[0].Instance != "i1" {
+			t.Errorf("Unexpected events %+v", events)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the events")
+	}
+
+	events := log.wait(context.Background(), "node1", 2, time.Millisecond)
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %+v", events)
+	}
+}
@@ -0,0 +1,502 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// maintenanceScheduleInterval is how often the scheduled maintenances are
+// checked
+const maintenanceScheduleInterval = time.Minute
+
+// maintenanceEnableCommand is the sunbeam command, with the maintenance
+// options and the node name appended, of the operations enabling the
+// scheduled maintenances once their window opens.
+var maintenanceEnableCommand = []string{"cluster", "maintenance", "enable", "--yes"}
+
+// disableMigrationModes are the valid values of the disable_migration option
+var disableMigrationModes = []string{"both", "live", "cold"}
+
+// maintenanceEnableArgs returns the maintenance enable command arguments
+// setting options
+func maintenanceEnableArgs(options apitypes.MaintenanceOptions) []string {
+	args := []string{}
+	if options.Force {
+		args = append(args, "--force")
+	}
+
+	if options.SkipUnmigratable {
+		args = append(args, "--skip-unmigratable")
+	}
+
+	if options.StopOSDs {
+		args = append(args, "--stop-osds")
+	}
+
+	if options.AllowDowntime {
+		args = append(args, "--allow-downtime")
+	}
+
+	if options.EnableCephCrushRebalancing {
+		args = append(args, "--enable-ceph-crush-rebalancing")
+	}
+
+	if options.DisableMigration != "" {
+		args = append(args, "--disable-migration="+options.DisableMigration)
+	}
+
+	if options.TTL != "" {
+		args = append(args, "--ttl="+options.TTL)
+	}
+
+	if options.MaxParallelMigrations != nil {
+		args = append(args, "--max-parallel-migrations="+strconv.Itoa(*options.MaxParallelMigrations))
+	}
+
+	return args
+}
+
+// maintenanceScheduleStore persists the scheduled maintenances
+type maintenanceScheduleStore interface {
+	List() ([]database.ScheduledMaintenance, error)
+	// Transition moves a scheduled maintenance from status from to status
+	// to, returning whether it was in status from
+	Transition(id int, from string, to string, startedAt string, finishedAt string, message string) (bool, error)
+	// Start moves a scheduled maintenance from pending to running with the
+	// operation enabling maintenance, returning whether it was pending
+	Start(id int, startedAt string, operation int, message string) (bool, error)
+}
+
+// txMaintenanceScheduleStore is a maintenanceScheduleStore running its
+// operations in the transaction tx
+type txMaintenanceScheduleStore struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (t txMaintenanceScheduleStore) List() ([]database.ScheduledMaintenance, error) {
+	return database.GetScheduledMaintenances(t.ctx, t.tx)
+}
+
+func (t txMaintenanceScheduleStore) Transition(id int, from string, to string, startedAt string, finishedAt string, message string) (bool, error) {
+	return database.UpdateScheduledMaintenanceStatus(t.ctx, t.tx, id, from, to, startedAt, finishedAt, message)
+}
+
+func (t txMaintenanceScheduleStore) Start(id int, startedAt string, operation int, message string) (bool, error) {
+	ok, err := database.UpdateScheduledMaintenanceStatus(t.ctx, t.tx, id, apitypes.ScheduledPending, apitypes.ScheduledRunning, startedAt, "", message)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	return true, database.UpdateScheduledMaintenanceOperation(t.ctx, t.tx, id, operation)
+}
+
+// stateMaintenanceScheduleStore is a maintenanceScheduleStore backed by the
+// database, each operation runs in its own transaction
+type stateMaintenanceScheduleStore struct {
+	ctx context.Context
+	s   state.State
+}
+
+func (t stateMaintenanceScheduleStore) List() ([]database.ScheduledMaintenance, error) {
+	var records []database.ScheduledMaintenance
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		records, err = txMaintenanceScheduleStore{ctx: ctx, tx: tx}.List()
+		return err
+	})
+
+	return records, err
+}
+
+func (t stateMaintenanceScheduleStore) Transition(id int, from string, to string, startedAt string, finishedAt string, message string) (bool, error) {
+	var ok bool
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		ok, err = txMaintenanceScheduleStore{ctx: ctx, tx: tx}.Transition(id, from, to, startedAt, finishedAt, message)
+		return err
+	})
+
+	return ok, err
+}
+
+func (t stateMaintenanceScheduleStore) Start(id int, startedAt string, operation int, message string) (bool, error) {
+	var ok bool
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		ok, err = txMaintenanceScheduleStore{ctx: ctx, tx: tx}.Start(id, startedAt, operation, message)
+		return err
+	})
+
+	return ok, err
+}
+
+// scheduleTransaction runs f with a maintenanceScheduleStore and an
+// operationStore sharing a transaction, committed only if f succeeds
+type scheduleTransaction func(f func(store maintenanceScheduleStore, operations operationStore) error) error
+
+// stateScheduleTransaction returns a scheduleTransaction running f in a
+// database transaction
+func stateScheduleTransaction(ctx context.Context, s state.State) scheduleTransaction {
+	return func(f func(store maintenanceScheduleStore, operations operationStore) error) error {
+		return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+			return f(txMaintenanceScheduleStore{ctx: ctx, tx: tx}, txOperationStore{ctx: ctx, tx: tx})
+		})
+	}
+}
+
+// errScheduledMaintenanceChanged rolls back the start of a scheduled
+// maintenance that is no longer pending
+var errScheduledMaintenanceChanged = errors.New("Scheduled maintenance is no longer pending")
+
+// ValidateScheduledMaintenance checks the window and options of a scheduled
+// maintenance requested at now, returning a 400 StatusError if they are
+// invalid. It returns the window normalized to RFC3339 UTC times.
+func ValidateScheduledMaintenance(req apitypes.ScheduledMaintenance, now time.Time) (string, string, error) {
+	start, err := time.Parse(time.RFC3339, req.WindowStart)
+	if err != nil {
+		return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance window start %q, expected an RFC3339 time such as 2025-07-01T02:00:00Z", req.WindowStart)
+	}
+
+	windowEnd := ""
+	if req.WindowEnd != "" {
+		end, err := time.Parse(time.RFC3339, req.WindowEnd)
+		if err != nil {
+			return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance window end %q, expected an RFC3339 time such as 2025-07-01T04:00:00Z", req.WindowEnd)
+		}
+
+		if !end.After(start) {
+			return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance window: end %s is not after start %s", req.WindowEnd, req.WindowStart)
+		}
+
+		if !end.After(now) {
+			return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance window: end %s has passed", req.WindowEnd)
+		}
+
+		windowEnd = end.UTC().Format(time.RFC3339)
+	}
+
+	options := req.Options
+	if options.DisableMigration != "" && !slices.Contains(disableMigrationModes, options.DisableMigration) {
+		return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance option disable_migration %q, must be one of %v", options.DisableMigration, disableMigrationModes)
+	}
+
+	if options.TTL != "" {
+		ttl, err := time.ParseDuration(options.TTL)
+		if err != nil || ttl <= 0 {
+			return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance option ttl %q, expected a positive duration such as 2h", options.TTL)
+		}
+	}
+
+	if options.MaxParallelMigrations != nil && *options.MaxParallelMigrations < 0 {
+		return "", "", api.StatusErrorf(http.StatusBadRequest, "Invalid scheduled maintenance option max_parallel_migrations %d, must not be negative", *options.MaxParallelMigrations)
+	}
+
+	return start.UTC().Format(time.RFC3339), windowEnd, nil
+}
+
+// scheduledMaintenanceFromRecord converts a record to a ScheduledMaintenance
+func scheduledMaintenanceFromRecord(record database.ScheduledMaintenance) apitypes.ScheduledMaintenance {
+	var options apitypes.MaintenanceOptions
+	err := json.Unmarshal([]byte(record.Options), &options)
+	if err != nil {
+		logger.Warnf("Invalid options of scheduled maintenance %d: %v", record.ID, err)
+	}
+
+	return apitypes.ScheduledMaintenance{
+		ID:          record.ID,
+		Node:        record.Node,
+		WindowStart: record.WindowStart,
+		WindowEnd:   record.WindowEnd,
+		Options:     options,
+		Status:      record.Status,
+		CreatedAt:   record.CreatedAt,
+		CreatedBy:   record.CreatedBy,
+		StartedAt:   record.StartedAt,
+		FinishedAt:  record.FinishedAt,
+		Message:     record.Message,
+		Operation:   record.Operation,
+	}
+}
+
+// ScheduleMaintenance records a maintenance enable of a node deferred to its
+// window, scheduled by actor. It returns a 400 StatusError if the request is
+// invalid, a 404 one if the node does not exist and a 409 one if the node
+// already has a pending scheduled maintenance.
+func ScheduleMaintenance(ctx context.Context, s state.State, req apitypes.ScheduledMaintenance, actor string) (apitypes.ScheduledMaintenance, error) {
+	now := time.Now()
+	windowStart, windowEnd, err := ValidateScheduledMaintenance(req, now)
+	if err != nil {
+		return apitypes.ScheduledMaintenance{}, err
+	}
+
+	options, err := json.Marshal(req.Options)
+	if err != nil {
+		return apitypes.ScheduledMaintenance{}, err
+	}
+
+	var scheduled apitypes.ScheduledMaintenance
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := database.GetScheduledMaintenances(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if record.Node == req.Node && record.Status == apitypes.ScheduledPending {
+				return api.StatusErrorf(http.StatusConflict, "Node %q already has pending scheduled maintenance %d", req.Node, record.ID)
+			}
+		}
+
+		id, err := database.CreateScheduledMaintenance(ctx, tx, database.ScheduledMaintenance{
+			Node:        req.Node,
+			WindowStart: windowStart,
+			WindowEnd:   windowEnd,
+			Options:     string(options),
+			Status:      apitypes.ScheduledPending,
+			CreatedAt:   now.UTC().Format(time.RFC3339),
+			CreatedBy:   actor,
+		})
+		if err != nil {
+			return err
+		}
+
+		records, err = database.GetScheduledMaintenances(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			if int64(record.ID) == id {
+				scheduled = scheduledMaintenanceFromRecord(record)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Scheduled maintenance %d not recorded", id)
+	})
+
+	return scheduled, err
+}
+
+// ListScheduledMaintenances returns the scheduled maintenances ordered by
+// window start, including the ones which ran or were cancelled.
+func ListScheduledMaintenances(ctx context.Context, s state.State) (apitypes.ScheduledMaintenanceList, error) {
+	records, err := stateMaintenanceScheduleStore{ctx: ctx, s: s}.List()
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := make(apitypes.ScheduledMaintenanceList, 0, len(records))
+	for _, record := range records {
+		scheduled = append(scheduled, scheduledMaintenanceFromRecord(record))
+	}
+
+	return scheduled, nil
+}
+
+// CancelScheduledMaintenance cancels a pending scheduled maintenance on
+// behalf of actor. The record is kept as cancelled.
+func CancelScheduledMaintenance(ctx context.Context, s state.State, id int, actor string) error {
+	return cancelScheduledMaintenance(stateMaintenanceScheduleStore{ctx: ctx, s: s}, id, actor, time.Now())
+}
+
+// cancelScheduledMaintenance cancels the pending scheduled maintenance id at
+// now. It returns a 404 StatusError if it does not exist, and a 409 one if
+// it is not pending anymore.
+func cancelScheduledMaintenance(store maintenanceScheduleStore, id int, actor string, now time.Time) error {
+	ok, err := store.Transition(id, apitypes.ScheduledPending, apitypes.ScheduledCancelled, "", now.UTC().Format(time.RFC3339), fmt.Sprintf("Cancelled by %s", actor))
+	if err != nil || ok {
+		return err
+	}
+
+	records, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.ID == id {
+			return api.StatusErrorf(http.StatusConflict, "Scheduled maintenance %d is %s, only pending ones can be cancelled", id, record.Status)
+		}
+	}
+
+	return api.StatusErrorf(http.StatusNotFound, "Scheduled maintenance %d not found", id)
+}
+
+// StartMaintenanceScheduler starts a background goroutine that requests an
+// operation enabling the maintenance of the nodes recorded by this member
+// once their scheduled window opens. clusterd lacks the credentials to
+// enable maintenance, the operations are run by `sunbeam operation agent`,
+// the operation-agent service of the snap. Without it the operations are
+// never claimed, and the maintenances fail once their window closed.
+// Scheduled maintenances are persisted, so they run across restarts.
+func StartMaintenanceScheduler(ctx context.Context, s state.State) {
+	go scheduleMaintenanceLoop(ctx, stateMaintenanceScheduleStore{ctx: ctx, s: s}, stateOperationStore{ctx: ctx, s: s}, stateScheduleTransaction(ctx, s), s.Name())
+
+	logger.Info("Started maintenance scheduler")
+}
+
+// scheduleMaintenanceLoop checks the scheduled maintenances at start, then
+// periodically.
+func scheduleMaintenanceLoop(ctx context.Context, store maintenanceScheduleStore, operations operationStore, atomically scheduleTransaction, member string) {
+	ticker := time.NewTicker(maintenanceScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		err := runScheduledMaintenances(store, operations, atomically, member, time.Now())
+		if err != nil {
+			logger.Warnf("Failed to check scheduled maintenances: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping maintenance scheduler")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runScheduledMaintenances follows the running scheduled maintenances of the
+// nodes recorded by member and starts the pending ones whose window is open
+// at now. Only the recording member acts on a node, and each maintenance is
+// claimed along with its operation, so that it runs once across the
+// cluster. A maintenance whose window closed before it could run is failed
+// without running it. A failed maintenance does not prevent the others from
+// running.
+func runScheduledMaintenances(store maintenanceScheduleStore, operations operationStore, atomically scheduleTransaction, member string, now time.Time) error {
+	records, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch scheduled maintenances: %w", err)
+	}
+
+	for _, record := range records {
+		if record.Member != member {
+			continue
+		}
+
+		switch record.Status {
+		case apitypes.ScheduledRunning:
+			followScheduledMaintenance(store, operations, record, now)
+		case apitypes.ScheduledPending:
+			if elapsed(record.WindowStart, now) {
+				startScheduledMaintenance(store, atomically, record, now)
+			}
+		}
+	}
+
+	return nil
+}
+
+// startScheduledMaintenance requests the operation enabling the maintenance
+// of the node of the pending record at now, or fails it if its window
+// closed. The operation is requested in the transaction moving the record to
+// running, so that it is not requested for a record no longer pending, such
+// as cancelled since it was listed.
+func startScheduledMaintenance(store maintenanceScheduleStore, atomically scheduleTransaction, record database.ScheduledMaintenance, now time.Time) {
+	if record.WindowEnd != "" && elapsed(record.WindowEnd, now) {
+		message := fmt.Sprintf("Window closed at %s before the maintenance could run", record.WindowEnd)
+		finishScheduledMaintenance(store, record, apitypes.ScheduledPending, apitypes.ScheduledFailed, message, now)
+		return
+	}
+
+	logger.Infof("Window of scheduled maintenance %d opened at %s, requesting the enable of maintenance of node %q", record.ID, record.WindowStart, record.Node)
+
+	scheduled := scheduledMaintenanceFromRecord(record)
+	args := append(slices.Clone(maintenanceEnableCommand), maintenanceEnableArgs(scheduled.Options)...)
+	var requested bool
+	err := atomically(func(store maintenanceScheduleStore, operations operationStore) error {
+		operation, err := createOperation(operations, append(args, record.Node), record.Member, record.CreatedBy, now)
+		if err != nil {
+			return err
+		}
+
+		requested = true
+		ok, err := store.Start(record.ID, now.UTC().Format(time.RFC3339), operation.ID, fmt.Sprintf("Enabling maintenance with operation %d", operation.ID))
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return errScheduledMaintenanceChanged
+		}
+
+		return nil
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, errScheduledMaintenanceChanged):
+		logger.Infof("Scheduled maintenance %d changed since it was listed, not enabling maintenance of node %q", record.ID, record.Node)
+	case !requested:
+		finishScheduledMaintenance(store, record, apitypes.ScheduledPending, apitypes.ScheduledFailed, fmt.Sprintf("Failed to request the enable: %v", err), now)
+	default:
+		// Retried at the next check
+		logger.Warnf("Failed to start scheduled maintenance %d: %v", record.ID, err)
+	}
+}
+
+// followScheduledMaintenance records the outcome of the operation of the
+// running record once it completed. An operation still pending once the
+// window closed is cancelled, so that the maintenance does not start late.
+func followScheduledMaintenance(store maintenanceScheduleStore, operations operationStore, record database.ScheduledMaintenance, now time.Time) {
+	if record.Operation == 0 {
+		// Run by a clusterd predating operations, and interrupted by its
+		// upgrade
+		finishScheduledMaintenance(store, record, apitypes.ScheduledRunning, apitypes.ScheduledFailed, "Interrupted by a clusterd restart, check the maintenance status of the node", now)
+		return
+	}
+
+	operation, err := getOperation(operations, record.Operation)
+	if api.StatusErrorCheck(err, http.StatusNotFound) {
+		finishScheduledMaintenance(store, record, apitypes.ScheduledRunning, apitypes.ScheduledFailed, err.Error(), now)
+		return
+	}
+
+	if err != nil {
+		logger.Warnf("Failed to fetch operation %d of scheduled maintenance %d: %v", record.Operation, record.ID, err)
+		return
+	}
+
+	switch operation.Status {
+	case apitypes.OperationPending:
+		if record.WindowEnd == "" || !elapsed(record.WindowEnd, now) {
+			return
+		}
+
+		logger.Warnf("Window of scheduled maintenance %d closed at %s before operation %d was run", record.ID, record.WindowEnd, operation.ID)
+		err := cancelOperation(operations, operation.ID, fmt.Sprintf("scheduled maintenance %d, its window closed at %s", record.ID, record.WindowEnd), now)
+		if err != nil {
+			logger.Warnf("Failed to cancel operation %d of scheduled maintenance %d: %v", operation.ID, record.ID, err)
+		}
+	case apitypes.OperationSucceeded:
+		logger.Infof("Enabled maintenance of node %q as scheduled by maintenance %d", record.Node, record.ID)
+		finishScheduledMaintenance(store, record, apitypes.ScheduledRunning, apitypes.ScheduledSucceeded, "", now)
+	case apitypes.OperationFailed, apitypes.OperationCancelled:
+		logger.Warnf("Scheduled maintenance %d of node %q failed: operation %d %s", record.ID, record.Node, operation.ID, operation.Status)
+		finishScheduledMaintenance(store, record, apitypes.ScheduledRunning, apitypes.ScheduledFailed, fmt.Sprintf("Operation %d %s: %s", operation.ID, operation.Status, operation.Error), now)
+	}
+}
+
+// finishScheduledMaintenance moves record from status from to the completed
+// status to at now, with message.
+func finishScheduledMaintenance(store maintenanceScheduleStore, record database.ScheduledMaintenance, from string, to string, message string, now time.Time) {
+	_, err := store.Transition(record.ID, from, to, "", now.UTC().Format(time.RFC3339), message)
+	if err != nil {
+		logger.Warnf("Failed to record outcome of scheduled maintenance %d: %v", record.ID, err)
+	}
+}
@@ -0,0 +1,453 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// memMaintenanceScheduleStore is an in-memory maintenanceScheduleStore,
+// with compare-and-swap transitions like the database one
+type memMaintenanceScheduleStore struct {
+	records []database.ScheduledMaintenance
+}
+
+func (m *memMaintenanceScheduleStore) List() ([]database.ScheduledMaintenance, error) {
+	return slices.Clone(m.records), nil
+}
+
+func (m *memMaintenanceScheduleStore) Transition(id int, from string, to string, startedAt string, finishedAt string, message string) (bool, error) {
+	for i, record := range m.records {
+		if record.ID != id || record.Status != from {
+			continue
+		}
+
+		m.records[i].Status = to
+		if startedAt != "" {
+			m.records[i].StartedAt = startedAt
+		}
+
+		if finishedAt != "" {
+			m.records[i].FinishedAt = finishedAt
+		}
+
+		m.records[i].Message = message
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (m *memMaintenanceScheduleStore) Start(id int, startedAt string, operation int, message string) (bool, error) {
+	for i, record := range m.records {
+		if record.ID == id && record.Status == apitypes.ScheduledPending {
+			m.records[i].Status = apitypes.ScheduledRunning
+			m.records[i].StartedAt = startedAt
+			m.records[i].Operation = operation
+			m.records[i].Message = message
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (m *memMaintenanceScheduleStore) get(id int) database.ScheduledMaintenance {
+	for _, record := range m.records {
+		if record.ID == id {
+			return record
+		}
+	}
+
+	return database.ScheduledMaintenance{}
+}
+
+// memScheduleTransaction returns a scheduleTransaction over store and
+// operations, restoring both if f fails
+func memScheduleTransaction(store *memMaintenanceScheduleStore, operations *memOperationStore) scheduleTransaction {
+	return func(f func(store maintenanceScheduleStore, operations operationStore) error) error {
+		records, operated := slices.Clone(store.records), slices.Clone(operations.records)
+		err := f(store, operations)
+		if err != nil {
+			store.records, operations.records = records, operated
+		}
+
+		return err
+	}
+}
+
+// fixtureScheduleStore is a maintenanceScheduleStore running each operation
+// in its own transaction of the fixture database
+type fixtureScheduleStore struct {
+	t  *testing.T
+	db *sql.DB
+}
+
+func (f fixtureScheduleStore) List() ([]database.ScheduledMaintenance, error) {
+	var records []database.ScheduledMaintenance
+	err := fixtureTransaction(f.t, f.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		records, err = txMaintenanceScheduleStore{ctx: ctx, tx: tx}.List()
+		return err
+	})
+
+	return records, err
+}
+
+func (f fixtureScheduleStore) Transition(id int, from string, to string, startedAt string, finishedAt string, message string) (bool, error) {
+	var ok bool
+	err := fixtureTransaction(f.t, f.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		ok, err = txMaintenanceScheduleStore{ctx: ctx, tx: tx}.Transition(id, from, to, startedAt, finishedAt, message)
+		return err
+	})
+
+	return ok, err
+}
+
+func (f fixtureScheduleStore) Start(id int, startedAt string, operation int, message string) (bool, error) {
+	var ok bool
+	err := fixtureTransaction(f.t, f.db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		ok, err = txMaintenanceScheduleStore{ctx: ctx, tx: tx}.Start(id, startedAt, operation, message)
+		return err
+	})
+
+	return ok, err
+}
+
+// fixtureScheduleTransaction returns a scheduleTransaction over the fixture
+// database, failing with fail once f succeeded if not nil
+func fixtureScheduleTransaction(t *testing.T, db *sql.DB, fail error) scheduleTransaction {
+	return func(f func(store maintenanceScheduleStore, operations operationStore) error) error {
+		return fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+			err := f(txMaintenanceScheduleStore{ctx: ctx, tx: tx}, txOperationStore{ctx: ctx, tx: tx})
+			if err != nil {
+				return err
+			}
+
+			return fail
+		})
+	}
+}
+
+// TestValidateScheduledMaintenance tests the validation of the window and
+// options of a scheduled maintenance
+func TestValidateScheduledMaintenance(t *testing.T) {
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	negative := -1
+
+	start, end, err := ValidateScheduledMaintenance(apitypes.ScheduledMaintenance{WindowStart: "2025-07-01T04:00:00+02:00", WindowEnd: "2025-07-01T06:00:00+02:00"}, now)
+	if err != nil {
+		t.Fatalf("Expected a valid window, got %v", err)
+	}
+
+	if start != "2025-07-01T02:00:00Z" || end != "2025-07-01T04:00:00Z" {
+		t.Errorf("Expected the window in UTC, got %s - %s", start, end)
+	}
+
+	tests := []struct {
+		name string
+		req  apitypes.ScheduledMaintenance
+	}{
+		{"invalid start", apitypes.ScheduledMaintenance{WindowStart: "tonight"}},
+		{"invalid end", apitypes.ScheduledMaintenance{WindowStart: "2025-07-01T02:00:00Z", WindowEnd: "later"}},
+		{"end before start", apitypes.ScheduledMaintenance{WindowStart: "2025-07-01T02:00:00Z", WindowEnd: "2025-07-01T01:00:00Z"}},
+		{"end passed", apitypes.ScheduledMaintenance{WindowStart: "2025-06-30T02:00:00Z", WindowEnd: "2025-06-30T04:00:00Z"}},
+		{"disable migration", apitypes.ScheduledMaintenance{WindowStart: "2025-07-01T02:00:00Z", Options: apitypes.MaintenanceOptions{DisableMigration: "warm"}}},
+		{"ttl", apitypes.ScheduledMaintenance{WindowStart: "2025-07-01T02:00:00Z", Options: apitypes.MaintenanceOptions{TTL: "-1h"}}},
+		{"max parallel migrations", apitypes.ScheduledMaintenance{WindowStart: "2025-07-01T02:00:00Z", Options: apitypes.MaintenanceOptions{MaxParallelMigrations: &negative}}},
+	}
+
+	for _, tt := range tests {
+		_, _, err := ValidateScheduledMaintenance(tt.req, now)
+		if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+			t.Errorf("%s: expected a 400 error, got %v", tt.name, err)
+		}
+	}
+}
+
+// TestMaintenanceEnableArgs tests the command arguments of the options
+func TestMaintenanceEnableArgs(t *testing.T) {
+	parallel := 2
+	args := maintenanceEnableArgs(apitypes.MaintenanceOptions{Force: true, SkipUnmigratable: true, DisableMigration: "live", TTL: "2h", MaxParallelMigrations: &parallel})
+	want := []string{"--force", "--skip-unmigratable", "--disable-migration=live", "--ttl=2h", "--max-parallel-migrations=2"}
+	if !slices.Equal(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+
+	if args := maintenanceEnableArgs(apitypes.MaintenanceOptions{}); len(args) != 0 {
+		t.Errorf("Expected no arguments by default, got %v", args)
+	}
+}
+
+// TestRunScheduledMaintenances tests that only the pending maintenances of
+// the member whose window is open run, once, as operations
+func TestRunScheduledMaintenances(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memMaintenanceScheduleStore{records: []database.ScheduledMaintenance{
+		{ID: 1, Node: "due", Member: "member-1", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T02:00:00Z", Options: `{"force":true}`, CreatedBy: "ubuntu@node-1"},
+		{ID: 2, Node: "later", Member: "member-1", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T03:00:00Z", Options: "{}"},
+		{ID: 3, Node: "other-member", Member: "member-2", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T01:00:00Z", Options: "{}"},
+		{ID: 4, Node: "cancelled", Member: "member-1", Status: apitypes.ScheduledCancelled, WindowStart: "2025-07-01T01:00:00Z", Options: "{}"},
+		{ID: 5, Node: "closed", Member: "member-1", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T00:00:00Z", WindowEnd: "2025-07-01T01:00:00Z", Options: "{}"},
+	}}
+	operations := &memOperationStore{}
+
+	err := runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(operations.records) != 1 {
+		t.Fatalf("Expected only due to be enabled, got %+v", operations.records)
+	}
+
+	operation := operationFromRecord(operations.get(t, 1))
+	if !slices.Equal(operation.Command, []string{"cluster", "maintenance", "enable", "--yes", "--force", "due"}) || operation.CreatedBy != "ubuntu@node-1" || operation.Member != "member-1" {
+		t.Errorf("Expected an operation enabling due with force, got %+v", operation)
+	}
+
+	want := map[int]string{1: apitypes.ScheduledRunning, 2: apitypes.ScheduledPending, 3: apitypes.ScheduledPending, 4: apitypes.ScheduledCancelled, 5: apitypes.ScheduledFailed}
+	for id, status := range want {
+		if store.get(id).Status != status {
+			t.Errorf("Expected scheduled maintenance %d %s, got %+v", id, status, store.get(id))
+		}
+	}
+
+	if store.get(1).Operation != 1 || store.get(1).StartedAt != "2025-07-01T02:00:00Z" {
+		t.Errorf("Expected due started with operation 1, got %+v", store.get(1))
+	}
+
+	// Running until the operation completes
+	_, _ = operations.Transition(1, apitypes.OperationPending, apitypes.OperationRunning, "", "", "", "")
+	err = runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.get(1).Status != apitypes.ScheduledRunning {
+		t.Errorf("Expected due running along with its operation, got %+v", store.get(1))
+	}
+
+	// A later check never runs a maintenance again
+	_, _ = operations.Transition(1, apitypes.OperationRunning, apitypes.OperationSucceeded, "", "", "", "")
+	err = runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.get(1).Status != apitypes.ScheduledSucceeded || store.get(1).FinishedAt != "2025-07-01T03:00:00Z" {
+		t.Errorf("Expected due succeeded along with its operation, got %+v", store.get(1))
+	}
+
+	if len(operations.records) != 2 || store.get(2).Operation != 2 {
+		t.Errorf("Expected only later to run in its window, got %+v", operations.records)
+	}
+}
+
+// TestRunScheduledMaintenancesFailure tests that a failed operation, such as
+// a failed precheck, fails its maintenance and does not block the next ones
+func TestRunScheduledMaintenancesFailure(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memMaintenanceScheduleStore{records: []database.ScheduledMaintenance{
+		{ID: 1, Node: "node-1", Member: "member-1", Status: apitypes.ScheduledRunning, WindowStart: "2025-07-01T01:00:00Z", Options: "{}", Operation: 1},
+		{ID: 2, Node: "node-2", Member: "member-1", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T02:00:00Z", Options: "{}"},
+	}}
+	operations := &memOperationStore{records: []database.Operation{
+		{ID: 1, Member: "member-1", Command: `["cluster","maintenance","enable","--yes","node-1"]`, Status: apitypes.OperationFailed, Error: "exit status 1: Node node-1 hosts the last ceph monitor"},
+	}}
+
+	err := runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.get(1).Status != apitypes.ScheduledFailed || !strings.Contains(store.get(1).Message, "Operation 1 failed: exit status 1: Node node-1 hosts the last ceph monitor") {
+		t.Errorf("Expected node-1 maintenance failed with the precheck error, got %+v", store.get(1))
+	}
+
+	if store.get(2).Status != apitypes.ScheduledRunning || store.get(2).Operation != 2 {
+		t.Errorf("Expected node-2 maintenance to run, got %+v", store.get(2))
+	}
+}
+
+// TestRunScheduledMaintenancesWindowClosed tests that an operation not run
+// by the time the window closes is cancelled, failing its maintenance
+func TestRunScheduledMaintenancesWindowClosed(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memMaintenanceScheduleStore{records: []database.ScheduledMaintenance{
+		{ID: 1, Node: "node-1", Member: "member-1", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T02:00:00Z", WindowEnd: "2025-07-01T04:00:00Z", Options: "{}"},
+	}}
+	operations := &memOperationStore{}
+
+	err := runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if operations.get(t, 1).Status != apitypes.OperationCancelled {
+		t.Fatalf("Expected the pending operation cancelled once the window closed, got %+v", operations.get(t, 1))
+	}
+
+	err = runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now.Add(2*time.Hour+time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.get(1).Status != apitypes.ScheduledFailed || !strings.Contains(store.get(1).Message, "Operation 1 cancelled: Cancelled by scheduled maintenance 1, its window closed") {
+		t.Errorf("Expected the maintenance failed with its cancelled operation, got %+v", store.get(1))
+	}
+}
+
+// TestCancelScheduledMaintenance tests that only pending maintenances can be
+// cancelled, and that a cancelled one never runs
+func TestCancelScheduledMaintenance(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memMaintenanceScheduleStore{records: []database.ScheduledMaintenance{
+		{ID: 1, Node: "node-1", Member: "member-1", Status: apitypes.ScheduledPending, WindowStart: "2025-07-01T03:00:00Z", Options: "{}"},
+		{ID: 2, Node: "node-2", Member: "member-1", Status: apitypes.ScheduledSucceeded, WindowStart: "2025-07-01T01:00:00Z", Options: "{}"},
+	}}
+
+	err := cancelScheduledMaintenance(store, 1, "ubuntu@node-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if store.get(1).Status != apitypes.ScheduledCancelled || !strings.Contains(store.records[0].Message, "ubuntu@node-1") {
+		t.Errorf("Expected maintenance 1 cancelled by ubuntu@node-1, got %+v", store.records[0])
+	}
+
+	if err := cancelScheduledMaintenance(store, 2, "ubuntu@node-1", now); !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error cancelling a maintenance which ran, got %v", err)
+	}
+
+	if err := cancelScheduledMaintenance(store, 3, "ubuntu@node-1", now); !api.StatusErrorCheck(err, http.StatusNotFound) {
+		t.Errorf("Expected a 404 error cancelling an unknown maintenance, got %v", err)
+	}
+
+	operations := &memOperationStore{}
+	err = runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(operations.records) != 0 {
+		t.Errorf("Expected the cancelled maintenance not to run, got %+v", operations.records)
+	}
+}
+
+// TestScheduledMaintenanceInterrupted tests that a maintenance running
+// without an operation, interrupted by the upgrade of clusterd, or whose
+// operation was lost is failed without running again
+func TestScheduledMaintenanceInterrupted(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memMaintenanceScheduleStore{records: []database.ScheduledMaintenance{
+		{ID: 1, Node: "interrupted", Member: "member-1", Status: apitypes.ScheduledRunning, WindowStart: "2025-07-01T01:00:00Z", StartedAt: "2025-07-01T01:00:00Z", Options: "{}"},
+		{ID: 2, Node: "lost", Member: "member-1", Status: apitypes.ScheduledRunning, WindowStart: "2025-07-01T01:00:00Z", Options: "{}", Operation: 7},
+		{ID: 3, Node: "other-member", Member: "member-2", Status: apitypes.ScheduledRunning, WindowStart: "2025-07-01T01:00:00Z", Options: "{}"},
+	}}
+	operations := &memOperationStore{}
+
+	err := runScheduledMaintenances(store, operations, memScheduleTransaction(store, operations), "member-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(operations.records) != 0 {
+		t.Errorf("Expected no maintenance to run again, got %+v", operations.records)
+	}
+
+	if store.get(1).Status != apitypes.ScheduledFailed || store.get(1).StartedAt != "2025-07-01T01:00:00Z" {
+		t.Errorf("Expected the interrupted maintenance failed, got %+v", store.get(1))
+	}
+
+	if store.get(2).Status != apitypes.ScheduledFailed || !strings.Contains(store.get(2).Message, "Operation 7 not found") {
+		t.Errorf("Expected the maintenance of the lost operation failed, got %+v", store.get(2))
+	}
+
+	if store.get(3).Status != apitypes.ScheduledRunning {
+		t.Errorf("Expected the maintenance of another member untouched, got %+v", store.get(3))
+	}
+}
+
+// TestStartScheduledMaintenanceTransaction tests that the operation of a
+// scheduled maintenance is requested along with its start, and neither is
+// persisted without the other
+func TestStartScheduledMaintenanceTransaction(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	err := fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := database.CreateScheduledMaintenance(ctx, tx, database.ScheduledMaintenance{Node: "node-1", WindowStart: "2025-07-01T01:00:00Z", Options: "{}", Status: apitypes.ScheduledPending, CreatedAt: "2025-06-30T00:00:00Z", CreatedBy: "ubuntu@node-1"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := fixtureScheduleStore{t: t, db: db}
+	records, err := store.List()
+	if err != nil || len(records) != 1 || records[0].Member != "node-1" {
+		t.Fatalf("Expected the scheduled maintenance of node-1, got %+v: %v", records, err)
+	}
+
+	// A crash before the transaction commits leaves neither behind
+	errCrash := errors.New("crash")
+	startScheduledMaintenance(store, fixtureScheduleTransaction(t, db, errCrash), records[0], now)
+	if count := countRows(t, db, "operations"); count != 0 {
+		t.Errorf("Expected no operation requested, got %d", count)
+	}
+
+	if records, _ := store.List(); records[0].Status != apitypes.ScheduledPending || records[0].Operation != 0 {
+		t.Errorf("Expected the maintenance still pending, got %+v", records[0])
+	}
+
+	// Cancelled after it was listed, the operation is not requested
+	err = cancelScheduledMaintenance(store, 1, "ubuntu@node-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startScheduledMaintenance(store, fixtureScheduleTransaction(t, db, nil), records[0], now)
+	if count := countRows(t, db, "operations"); count != 0 {
+		t.Errorf("Expected no operation for the cancelled maintenance, got %d", count)
+	}
+
+	if records, _ := store.List(); records[0].Status != apitypes.ScheduledCancelled || records[0].Operation != 0 {
+		t.Errorf("Expected the maintenance to stay cancelled, got %+v", records[0])
+	}
+
+	// Started along with its operation otherwise
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := database.CreateScheduledMaintenance(ctx, tx, database.ScheduledMaintenance{Node: "node-2", WindowStart: "2025-07-01T01:00:00Z", Options: "{}", Status: apitypes.ScheduledPending, CreatedAt: "2025-06-30T00:00:00Z", CreatedBy: "ubuntu@node-1"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, _ = store.List()
+	startScheduledMaintenance(store, fixtureScheduleTransaction(t, db, nil), records[1], now)
+	if count := countRows(t, db, "operations"); count != 1 {
+		t.Errorf("Expected the operation of node-2 requested, got %d", count)
+	}
+
+	if records, _ := store.List(); records[1].Status != apitypes.ScheduledRunning || records[1].Operation != 1 {
+		t.Errorf("Expected node-2 running with operation 1, got %+v", records[1])
+	}
+}
@@ -0,0 +1,155 @@
+package sunbeam
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestMaintenanceStatusFromRecord tests aggregation of nodes in mixed states
+func TestMaintenanceStatusFromRecord(t *testing.T) {
+	records := []database.Maintenance{
+		{Node: "node-1", Status: "enabled", EnteredAt: "2025-03-01T10:00:00Z", Strategy: "stop-osds", TriggeredBy: "ubuntu@node-2"},
+		{Node: "node-2"},
+		{Node: "node-3", Status: "degraded", EnteredAt: "2025-03-01T11:00:00Z", TriggeredBy: "ubuntu@node-2", Message: "MicroCephActionStep failed"},
+		{Node: "node-4", Status: "disabled", TriggeredBy: "ubuntu@node-1"},
+	}
+
+	expected := []string{"enabled", "disabled", "degraded", "disabled"}
+	for i, record := range records {
+		status := maintenanceStatusFromRecord(record)
+		if status.Node != record.Node {
+			t.Errorf("Expected node %q, got %q", record.Node, status.Node)
+		}
+
+		if status.Status != expected[i] {
+			t.Errorf("Node %q: expected status %q, got %q", record.Node, expected[i], status.Status)
+		}
+
+		if status.Message != record.Message || status.EnteredAt != record.EnteredAt {
+			t.Errorf("Node %q: expected details of %+v, got %+v", record.Node, record, status)
+		}
+	}
+}
+
+// TestNextMaintenanceStatus tests entered_at tracking across transitions
+func TestNextMaintenanceStatus(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entered := "2025-03-01T10:00:00Z"
+
+	testCases := []struct {
+		name          string
+		current       string
+		requested     string
+		wantEnteredAt string
+	}{
+		{name: "enable", current: "disabled", requested: "enabled", wantEnteredAt: "2025-03-01T12:00:00Z"},
+		{name: "partially failed enable", current: "disabled", requested: "degraded", wantEnteredAt: "2025-03-01T12:00:00Z"},
+		{name: "retry degraded enable", current: "degraded", requested: "enabled", wantEnteredAt: entered},
+		{name: "partially failed disable", current: "enabled", requested: "degraded", wantEnteredAt: entered},
+		{name: "disable", current: "enabled", requested: "disabled", wantEnteredAt: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			current := apitypes.MaintenanceStatus{Node: "node-1", Status: tc.current}
+			if tc.current != "disabled" {
+				current.EnteredAt = entered
+			}
+
+			requested := apitypes.MaintenanceStatus{Node: "node-1", Status: tc.requested, TriggeredBy: "ubuntu@node-2"}
+			next := nextMaintenanceStatus(current, requested, 0, now)
+
+			if next.Status != tc.requested || next.TriggeredBy != requested.TriggeredBy {
+				t.Errorf("Expected requested state %+v, got %+v", requested, next)
+			}
+
+			if next.EnteredAt != tc.wantEnteredAt {
+				t.Errorf("Expected entered_at %q, got %q", tc.wantEnteredAt, next.EnteredAt)
+			}
+		})
+	}
+}
+
+// TestValidateMaintenanceStatus tests that unknown statuses are rejected
+func TestValidateMaintenanceStatus(t *testing.T) {
+	for _, status := range apitypes.MaintenanceStatuses {
+		err := ValidateMaintenanceStatus(status)
+		if err != nil {
+			t.Errorf("Expected status %q to be valid, got %v", status, err)
+		}
+	}
+
+	err := ValidateMaintenanceStatus("paused")
+	if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+		t.Errorf("Expected bad request error, got %v", err)
+	}
+}
+
+// TestNextMaintenanceStatusTTL tests expiry tracking, including extending
+// the TTL with a repeated enable
+func TestNextMaintenanceStatusTTL(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	expires := "2025-03-01T13:00:00Z"
+
+	testCases := []struct {
+		name          string
+		current       string
+		requested     string
+		ttl           time.Duration
+		wantExpiresAt string
+	}{
+		{name: "enable with ttl", current: "disabled", requested: "enabled", ttl: 2 * time.Hour, wantExpiresAt: "2025-03-01T14:00:00Z"},
+		{name: "extend with repeated enable", current: "enabled", requested: "enabled", ttl: 4 * time.Hour, wantExpiresAt: "2025-03-01T16:00:00Z"},
+		{name: "repeated enable without ttl", current: "enabled", requested: "enabled", wantExpiresAt: expires},
+		{name: "partially failed disable", current: "enabled", requested: "degraded", wantExpiresAt: expires},
+		{name: "enable without ttl", current: "disabled", requested: "enabled", wantExpiresAt: ""},
+		{name: "disable", current: "enabled", requested: "disabled", wantExpiresAt: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			current := apitypes.MaintenanceStatus{Node: "node-1", Status: tc.current}
+			if tc.current != "disabled" {
+				current.EnteredAt = "2025-03-01T10:00:00Z"
+				current.ExpiresAt = expires
+			}
+
+			requested := apitypes.MaintenanceStatus{Node: "node-1", Status: tc.requested, TTL: tc.ttl.String()}
+			next := nextMaintenanceStatus(current, requested, tc.ttl, now)
+
+			if next.ExpiresAt != tc.wantExpiresAt {
+				t.Errorf("Expected expires_at %q, got %q", tc.wantExpiresAt, next.ExpiresAt)
+			}
+
+			if next.TTL != "" {
+				t.Errorf("Expected the requested ttl not to be recorded, got %q", next.TTL)
+			}
+		})
+	}
+}
+
+// TestParseMaintenanceTTL tests that invalid TTLs are rejected
+func TestParseMaintenanceTTL(t *testing.T) {
+	ttl, err := parseMaintenanceTTL(apitypes.MaintenanceStatus{Status: "enabled", TTL: "1h30m"})
+	if err != nil || ttl != 90*time.Minute {
+		t.Errorf("Expected ttl of 1h30m, got %v, %v", ttl, err)
+	}
+
+	for _, status := range []apitypes.MaintenanceStatus{
+		{Status: "enabled", TTL: "2 hours"},
+		{Status: "enabled", TTL: "-1h"},
+		{Status: "enabled", TTL: "0s"},
+		{Status: "disabled", TTL: "2h"},
+	} {
+		_, err := parseMaintenanceTTL(status)
+		if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+			t.Errorf("Expected bad request error for %+v, got %v", status, err)
+		}
+	}
+}
@@ -16,7 +16,7 @@ func ListManifests(ctx context.Context, s state.State) (apitypes.Manifests, erro
 	manifests := apitypes.Manifests{}
 
 	// Get the manifests from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		records, err := database.GetManifestItems(ctx, tx)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch manifests: %w", err)
@@ -43,7 +43,7 @@ func ListManifests(ctx context.Context, s state.State) (apitypes.Manifests, erro
 func GetManifest(ctx context.Context, s state.State, manifestid string) (apitypes.Manifest, error) {
 	manifest := apitypes.Manifest{}
 
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		var record *database.ManifestItem
 		var err error
 		// If manifest id is latest, retrieve the latest inserted record.
@@ -69,7 +69,7 @@ func GetManifest(ctx context.Context, s state.State, manifestid string) (apitype
 // AddManifest adds a manifest to the database
 func AddManifest(ctx context.Context, s state.State, manifestid string, data string) error {
 	// Add manifest to the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := database.CreateManifestItem(ctx, tx, database.ManifestItem{ManifestID: manifestid, Data: data})
 		if err != nil {
 			return fmt.Errorf("Failed to record manifest: %w", err)
@@ -87,7 +87,7 @@ func AddManifest(ctx context.Context, s state.State, manifestid string, data str
 // DeleteManifest deletes a manifest from database
 func DeleteManifest(ctx context.Context, s state.State, manifestid string) error {
 	// Delete manifest from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		err := database.DeleteManifestItem(ctx, tx, manifestid)
 		if err != nil {
 			return fmt.Errorf("Failed to delete manifest: %w", err)
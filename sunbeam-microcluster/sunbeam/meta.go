@@ -0,0 +1,36 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/version"
+)
+
+// GetMeta returns the versions of the daemon, of its database schema and of
+// the API it serves
+func GetMeta(ctx context.Context, s state.State) (apitypes.Meta, error) {
+	meta := apitypes.Meta{
+		Version:             version.Version,
+		APIPrefixes:         make([]string, 0, len(apitypes.ExtendedPathPrefixes)),
+		MicroclusterVersion: version.MicroclusterVersion(),
+	}
+	for _, prefix := range apitypes.ExtendedPathPrefixes {
+		meta.APIPrefixes = append(meta.APIPrefixes, string(prefix))
+	}
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		meta.SchemaInternal, meta.SchemaExternal, err = database.GetSchemaVersions(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return apitypes.Meta{}, err
+	}
+
+	return meta, nil
+}
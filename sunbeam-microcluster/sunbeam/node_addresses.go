@@ -0,0 +1,101 @@
+package sunbeam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// maxAddressNameLength is the maximum length of a node address name
+const maxAddressNameLength = 63
+
+// addressNameRegex matches lowercase alphanumerics separated by '-'
+var addressNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// ValidateNodeAddresses checks the names and IPs of the addresses of a node
+// and returns the name of its preferred API address. A node with a single
+// address prefers it unless set, a node with several addresses must name
+// the preferred one. A 400 StatusError is returned for invalid addresses or
+// a preferred address the node does not have.
+func ValidateNodeAddresses(addresses apitypes.NodeAddresses, preferred string) (string, error) {
+	for name, address := range addresses {
+		if len(name) > maxAddressNameLength || !addressNameRegex.MatchString(name) {
+			return "", api.StatusErrorf(http.StatusBadRequest, "Invalid node address name %q, expected lowercase alphanumerics separated by '-'", name)
+		}
+
+		if net.ParseIP(address) == nil {
+			return "", api.StatusErrorf(http.StatusBadRequest, "Invalid node address %s=%q, expected an IP address", name, address)
+		}
+	}
+
+	if preferred == "" {
+		switch len(addresses) {
+		case 0:
+			return "", nil
+		case 1:
+			for name := range addresses {
+				return name, nil
+			}
+		default:
+			return "", api.StatusErrorf(http.StatusBadRequest, "Invalid preferred API address, the node has several addresses and none is named")
+		}
+	}
+
+	_, ok := addresses[preferred]
+	if !ok {
+		return "", api.StatusErrorf(http.StatusBadRequest, "Invalid preferred API address %q, it is not an address of the node", preferred)
+	}
+
+	return preferred, nil
+}
+
+// nodeAddressesRecord validates the addresses of a node, returning them as
+// a json string along with the name of the preferred API address
+func nodeAddressesRecord(addresses apitypes.NodeAddresses, preferred string) (string, string, error) {
+	preferred, err := ValidateNodeAddresses(addresses, preferred)
+	if err != nil {
+		return "", "", err
+	}
+
+	addressesStr, err := addressesToStr(addresses)
+	if err != nil {
+		return "", "", err
+	}
+
+	return addressesStr, preferred, nil
+}
+
+// addressesToStr converts addresses to a json string
+func addressesToStr(addresses apitypes.NodeAddresses) (string, error) {
+	if addresses == nil {
+		addresses = apitypes.NodeAddresses{}
+	}
+
+	addressesJSON, err := json.Marshal(addresses)
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal addresses: %w", err)
+	}
+
+	return string(addressesJSON), nil
+}
+
+// addressesFromStr converts a json string to addresses
+func addressesFromStr(addressesStr string) (apitypes.NodeAddresses, error) {
+	addresses := apitypes.NodeAddresses{}
+	if addressesStr == "" {
+		return addresses, nil
+	}
+
+	err := json.Unmarshal([]byte(addressesStr), &addresses)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal addresses: %w", err)
+	}
+
+	return addresses, nil
+}
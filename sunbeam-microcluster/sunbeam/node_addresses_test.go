@@ -0,0 +1,91 @@
+package sunbeam
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// TestValidateNodeAddresses tests address validation and the selection of
+// the preferred API address
+func TestValidateNodeAddresses(t *testing.T) {
+	multi := apitypes.NodeAddresses{"management": "10.0.0.5", "data": "10.1.0.5", "public": "2001:db8::5"}
+
+	testCases := []struct {
+		name          string
+		addresses     apitypes.NodeAddresses
+		preferred     string
+		wantPreferred string
+		valid         bool
+	}{
+		{name: "no addresses", addresses: nil, valid: true},
+		{name: "single address preferred by default", addresses: apitypes.NodeAddresses{"management": "10.0.0.5"}, wantPreferred: "management", valid: true},
+		{name: "several addresses with preferred", addresses: multi, preferred: "data", wantPreferred: "data", valid: true},
+		{name: "several addresses without preferred", addresses: multi, valid: false},
+		{name: "unknown preferred", addresses: multi, preferred: "storage", valid: false},
+		{name: "preferred without addresses", addresses: nil, preferred: "management", valid: false},
+		{name: "invalid IP", addresses: apitypes.NodeAddresses{"management": "node-1.local"}, valid: false},
+		{name: "address with port", addresses: apitypes.NodeAddresses{"management": "10.0.0.5:7000"}, valid: false},
+		{name: "uppercase name", addresses: apitypes.NodeAddresses{"Management": "10.0.0.5"}, valid: false},
+		{name: "name too long", addresses: apitypes.NodeAddresses{strings.Repeat("a", maxAddressNameLength+1): "10.0.0.5"}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			preferred, err := ValidateNodeAddresses(tc.addresses, tc.preferred)
+			if !tc.valid {
+				if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+					t.Errorf("Expected bad request error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected addresses to be valid, got error: %v", err)
+			}
+
+			if preferred != tc.wantPreferred {
+				t.Errorf("Expected preferred API address %q, got %q", tc.wantPreferred, preferred)
+			}
+		})
+	}
+}
+
+// TestNodeAddressesRecord tests that the addresses of a node round trip
+// through their record and select the preferred API address
+func TestNodeAddressesRecord(t *testing.T) {
+	addresses := apitypes.NodeAddresses{"management": "10.0.0.5", "data": "10.1.0.5"}
+
+	record, preferred, err := nodeAddressesRecord(addresses, "data")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if record != `{"data":"10.1.0.5","management":"10.0.0.5"}` {
+		t.Errorf("Unexpected addresses record %s", record)
+	}
+
+	got, err := addressesFromStr(record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !maps.Equal(got, addresses) {
+		t.Errorf("Expected addresses %v, got %v", addresses, got)
+	}
+
+	node := apitypes.Node{Addresses: got, PreferredAPI: preferred}
+	if node.APIAddress() != "10.1.0.5" {
+		t.Errorf("Expected the data address to serve the API, got %q", node.APIAddress())
+	}
+
+	empty, err := addressesFromStr("")
+	if err != nil || len(empty) != 0 || (apitypes.Node{}).APIAddress() != "" {
+		t.Errorf("Expected no addresses for nodes recorded without them, got %v, %v", empty, err)
+	}
+}
@@ -0,0 +1,171 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// nodeStore is the subset of node operations applied by a node batch,
+// scoped to a single transaction.
+type nodeStore interface {
+	// Add records a node
+	Add(op apitypes.NodeBatchOperation) error
+	// Remove moves a node to the tombstones
+	Remove(op apitypes.NodeBatchOperation) error
+}
+
+// txNodeStore is a nodeStore backed by a database transaction
+type txNodeStore struct {
+	ctx       context.Context
+	tx        *sql.Tx
+	member    string
+	actor     string
+	removedAt time.Time
+}
+
+func (t txNodeStore) Add(op apitypes.NodeBatchOperation) error {
+	nodeRole, err := roleToStr(op.Role)
+	if err != nil {
+		return err
+	}
+
+	nodeAddresses, preferredAPI, err := nodeAddressesRecord(op.Addresses, op.PreferredAPI)
+	if err != nil {
+		return err
+	}
+
+	return addNodeRecord(t.ctx, t.tx, database.Node{Member: t.member, Name: op.Name, Role: nodeRole, MachineID: op.MachineID, SystemID: op.SystemID, Labels: "{}", Addresses: nodeAddresses, PreferredAPI: preferredAPI})
+}
+
+func (t txNodeStore) Remove(op apitypes.NodeBatchOperation) error {
+	return removeNodeRecord(t.ctx, t.tx, op.Name, t.removedAt, op.Reason, t.actor)
+}
+
+// ApplyNodeBatch adds and removes nodes in a single database transaction.
+// Either all the operations are committed or, if any of them fails, none
+// are: the transaction is rolled back and the response reports the failed
+// operation, the operations rolled back before it and the ones skipped
+// after it. Only invalid requests and failures outside of the operations,
+// such as the commit itself, are returned as errors. The events of the
+// operations are emitted once the batch is committed.
+func ApplyNodeBatch(ctx context.Context, s state.State, ops []apitypes.NodeBatchOperation, actor string) (apitypes.NodeBatchResponse, error) {
+	var resp apitypes.NodeBatchResponse
+	var batchErr error
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		// Validated in the transaction recording the nodes, so that their
+		// custom roles cannot be removed meanwhile
+		custom, err := customRoles(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = ValidateNodeBatch(ops, custom)
+		if err != nil {
+			return err
+		}
+
+		store := txNodeStore{ctx: ctx, tx: tx, member: s.Name(), actor: actor, removedAt: time.Now()}
+		resp, batchErr = applyNodeBatch(store, ops)
+		return batchErr
+	})
+	if err != nil && batchErr == nil {
+		return resp, err
+	}
+
+	if resp.Applied {
+		emitNodeBatchEvents(ctx, ops, actor)
+	}
+
+	return resp, nil
+}
+
+// emitNodeBatchEvents emits the events of the operations of a committed node
+// batch, in order
+func emitNodeBatchEvents(ctx context.Context, ops []apitypes.NodeBatchOperation, actor string) {
+	for _, op := range ops {
+		switch op.Action {
+		case apitypes.NodeBatchAdd:
+			emitEvent(ctx, apitypes.EventNodeAdded, nodeAddedData(op.Name, op.Role))
+		case apitypes.NodeBatchRemove:
+			emitEvent(ctx, apitypes.EventNodeRemoved, nodeRemovedData(op.Name, op.Reason, actor))
+		}
+	}
+}
+
+// applyNodeBatch applies ops in order, stopping at the first failure.
+// The returned error is the one of the failed operation, the caller must
+// roll back the operations applied before it.
+func applyNodeBatch(store nodeStore, ops []apitypes.NodeBatchOperation) (apitypes.NodeBatchResponse, error) {
+	results := make([]apitypes.NodeBatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = apitypes.NodeBatchResult{Action: op.Action, Name: op.Name, Status: apitypes.NodeBatchSkipped}
+	}
+
+	for i, op := range ops {
+		var err error
+		switch op.Action {
+		case apitypes.NodeBatchAdd:
+			err = store.Add(op)
+		case apitypes.NodeBatchRemove:
+			err = store.Remove(op)
+		default:
+			err = api.StatusErrorf(http.StatusBadRequest, "Unknown node batch action %q", op.Action)
+		}
+
+		if err != nil {
+			for j := range i {
+				results[j].Status = apitypes.NodeBatchRolledBack
+			}
+			results[i].Status = apitypes.NodeBatchFailed
+			results[i].Error = err.Error()
+
+			return apitypes.NodeBatchResponse{Applied: false, Results: results}, fmt.Errorf("Failed to %s node %q: %w", op.Action, op.Name, err)
+		}
+
+		results[i].Status = apitypes.NodeBatchApplied
+	}
+
+	return apitypes.NodeBatchResponse{Applied: true, Results: results}, nil
+}
+
+// ValidateNodeBatch checks that a node batch holds operations with a known
+// action, a node name, built-in or custom roles and, when adding a node,
+// valid addresses.
+func ValidateNodeBatch(ops []apitypes.NodeBatchOperation, custom []string) error {
+	if len(ops) == 0 {
+		return api.StatusErrorf(http.StatusBadRequest, "Node batch has no operations")
+	}
+
+	for i, op := range ops {
+		if op.Action != apitypes.NodeBatchAdd && op.Action != apitypes.NodeBatchRemove {
+			return api.StatusErrorf(http.StatusBadRequest, "Unknown node batch action %q for operation %d", op.Action, i)
+		}
+
+		if op.Name == "" {
+			return api.StatusErrorf(http.StatusBadRequest, "Missing node name for operation %d", i)
+		}
+
+		err := ValidateRoles(op.Role, custom)
+		if err != nil {
+			return err
+		}
+
+		if op.Action == apitypes.NodeBatchAdd {
+			_, err = ValidateNodeAddresses(op.Addresses, op.PreferredAPI)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,234 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// memNodeStore is an in-memory nodeStore failing on the operations of the
+// nodes listed in failOn.
+type memNodeStore struct {
+	nodes  map[string]apitypes.NodeBatchOperation
+	failOn []string
+}
+
+func (m *memNodeStore) Add(op apitypes.NodeBatchOperation) error {
+	if slices.Contains(m.failOn, op.Name) {
+		return errors.New("injected failure")
+	}
+
+	_, ok := m.nodes[op.Name]
+	if ok {
+		return api.StatusErrorf(http.StatusConflict, "This \"nodes\" entry already exists")
+	}
+
+	m.nodes[op.Name] = op
+	return nil
+}
+
+func (m *memNodeStore) Remove(op apitypes.NodeBatchOperation) error {
+	if slices.Contains(m.failOn, op.Name) {
+		return errors.New("injected failure")
+	}
+
+	_, ok := m.nodes[op.Name]
+	if !ok {
+		return api.StatusErrorf(http.StatusNotFound, "Node not found")
+	}
+
+	delete(m.nodes, op.Name)
+	return nil
+}
+
+func resultStatuses(resp apitypes.NodeBatchResponse) []string {
+	statuses := make([]string, len(resp.Results))
+	for i, result := range resp.Results {
+		statuses[i] = result.Status
+	}
+
+	return statuses
+}
+
+// TestApplyNodeBatch tests that a batch is applied entirely, or stops at the
+// operation failing mid-batch, reporting the operations before it as rolled
+// back and the ones after it as skipped.
+func TestApplyNodeBatch(t *testing.T) {
+	ops := []apitypes.NodeBatchOperation{
+		{Action: apitypes.NodeBatchAdd, Name: "node-2", Role: []string{"compute"}},
+		{Action: apitypes.NodeBatchAdd, Name: "node-3", Role: []string{"compute"}},
+		{Action: apitypes.NodeBatchAdd, Name: "node-4", Role: []string{"compute"}},
+		{Action: apitypes.NodeBatchRemove, Name: "node-1", Reason: "resize"},
+		{Action: apitypes.NodeBatchAdd, Name: "node-5", Role: []string{"storage"}},
+	}
+
+	testCases := []struct {
+		name         string
+		failOn       []string
+		wantApplied  bool
+		wantStatuses []string
+	}{
+		{
+			name:         "all operations succeed",
+			wantApplied:  true,
+			wantStatuses: []string{"applied", "applied", "applied", "applied", "applied"},
+		},
+		{
+			name:         "mid-batch failure",
+			failOn:       []string{"node-4"},
+			wantStatuses: []string{"rolled_back", "rolled_back", "failed", "skipped", "skipped"},
+		},
+		{
+			name:         "last operation fails",
+			failOn:       []string{"node-5"},
+			wantStatuses: []string{"rolled_back", "rolled_back", "rolled_back", "rolled_back", "failed"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memNodeStore{
+				nodes:  map[string]apitypes.NodeBatchOperation{"node-1": {Name: "node-1"}},
+				failOn: tc.failOn,
+			}
+
+			resp, err := applyNodeBatch(store, ops)
+			if tc.wantApplied && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !tc.wantApplied && err == nil {
+				t.Fatal("Expected the batch to fail")
+			}
+
+			if resp.Applied != tc.wantApplied {
+				t.Errorf("Expected applied=%v, got %v", tc.wantApplied, resp.Applied)
+			}
+
+			statuses := resultStatuses(resp)
+			if !slices.Equal(statuses, tc.wantStatuses) {
+				t.Errorf("Expected outcomes %v, got %v", tc.wantStatuses, statuses)
+			}
+		})
+	}
+}
+
+// TestApplyNodeBatchReportsError tests that the failed operation carries
+// the error that aborted the batch.
+func TestApplyNodeBatchReportsError(t *testing.T) {
+	store := &memNodeStore{nodes: map[string]apitypes.NodeBatchOperation{}}
+	ops := []apitypes.NodeBatchOperation{
+		{Action: apitypes.NodeBatchAdd, Name: "node-1"},
+		{Action: apitypes.NodeBatchRemove, Name: "node-2"},
+	}
+
+	resp, err := applyNodeBatch(store, ops)
+	if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+
+	failed := resp.Results[1]
+	if failed.Action != "remove" || failed.Name != "node-2" || failed.Error != "Node not found" {
+		t.Errorf("Expected failed removal of node-2, got %+v", failed)
+	}
+}
+
+// TestApplyNodeBatchRollback tests that an operation failing mid-batch rolls
+// back the nodes added and removed before it in the database.
+func TestApplyNodeBatchRollback(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	ops := []apitypes.NodeBatchOperation{
+		{Action: apitypes.NodeBatchAdd, Name: "node-3", Role: []string{"compute"}, MachineID: 3},
+		{Action: apitypes.NodeBatchRemove, Name: "node-1", Reason: "resize"},
+		{Action: apitypes.NodeBatchAdd, Name: "node-2", Role: []string{"compute"}, MachineID: 2},
+	}
+
+	var resp apitypes.NodeBatchResponse
+	err := fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		store := txNodeStore{ctx: ctx, tx: tx, member: "node-1", actor: "ubuntu@node-1", removedAt: time.Now()}
+		resp, err = applyNodeBatch(store, ops)
+		return err
+	})
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Fatalf("Expected conflict error, got %v", err)
+	}
+
+	statuses := resultStatuses(resp)
+	if !slices.Equal(statuses, []string{"rolled_back", "rolled_back", "failed"}) {
+		t.Errorf("Expected the batch to be rolled back, got %v", statuses)
+	}
+
+	var nodes []string
+	err = fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		nodes, err = query.SelectStrings(ctx, tx, `SELECT name FROM nodes ORDER BY name`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to fetch the nodes: %v", err)
+	}
+
+	if !slices.Equal(nodes, []string{"node-1", "node-2"}) {
+		t.Errorf("Expected nodes node-1 and node-2, got %v", nodes)
+	}
+
+	if n := countRows(t, db, "node_tombstones"); n != 0 {
+		t.Errorf("Expected no tombstone, got %d", n)
+	}
+}
+
+// TestValidateNodeBatch tests rejection of malformed batches
+func TestValidateNodeBatch(t *testing.T) {
+	testCases := []struct {
+		name  string
+		ops   []apitypes.NodeBatchOperation
+		valid bool
+	}{
+		{name: "add and remove", ops: []apitypes.NodeBatchOperation{{Action: "add", Name: "node-1", Role: []string{"compute"}}, {Action: "remove", Name: "node-2"}}, valid: true},
+		{name: "empty batch", ops: nil, valid: false},
+		{name: "unknown action", ops: []apitypes.NodeBatchOperation{{Action: "update", Name: "node-1"}}, valid: false},
+		{name: "missing name", ops: []apitypes.NodeBatchOperation{{Action: "add"}}, valid: false},
+		{name: "unknown role", ops: []apitypes.NodeBatchOperation{{Action: "add", Name: "node-1", Role: []string{"gpu"}}}, valid: false},
+		{name: "custom role", ops: []apitypes.NodeBatchOperation{{Action: "add", Name: "node-1", Role: []string{"compute", "lb"}}}, valid: true},
+		{name: "undefined custom role", ops: []apitypes.NodeBatchOperation{{Action: "add", Name: "node-1", Role: []string{"lbs"}}}, valid: false},
+		{name: "addresses with preferred", ops: []apitypes.NodeBatchOperation{{Action: "add", Name: "node-1", Addresses: apitypes.NodeAddresses{"management": "10.0.0.5", "data": "10.1.0.5"}, PreferredAPI: "management"}}, valid: true},
+		{name: "unknown preferred address", ops: []apitypes.NodeBatchOperation{{Action: "add", Name: "node-1", Addresses: apitypes.NodeAddresses{"management": "10.0.0.5"}, PreferredAPI: "data"}}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateNodeBatch(tc.ops, []string{"lb"})
+			if tc.valid && err != nil {
+				t.Errorf("Expected batch to be valid, got %v", err)
+			}
+
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestNodeBatchOperationDefaults tests that a missing machine ID is unset
+func TestNodeBatchOperationDefaults(t *testing.T) {
+	req := apitypes.NodeBatchRequest{}
+	err := json.Unmarshal([]byte(`{"operations": [{"action": "add", "name": "node-1"}, {"action": "add", "name": "node-2", "machineid": 0}]}`), &req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if req.Operations[0].MachineID != -1 || req.Operations[1].MachineID != 0 {
+		t.Errorf("Expected machine IDs -1 and 0, got %d and %d", req.Operations[0].MachineID, req.Operations[1].MachineID)
+	}
+}
@@ -0,0 +1,243 @@
+package sunbeam
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// DecodeHardware decodes a hardware inventory, returning a 400 StatusError
+// on unknown fields or fields of the wrong type.
+func DecodeHardware(r io.Reader) (apitypes.Hardware, error) {
+	hardware := apitypes.Hardware{}
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&hardware)
+	if err != nil {
+		return apitypes.Hardware{}, api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: %v", err)
+	}
+
+	return hardware, nil
+}
+
+// ValidateHardware checks a hardware inventory against the Hardware schema
+func ValidateHardware(hardware apitypes.Hardware) error {
+	if hardware.CPUCount < 1 {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: cpu_count must be positive")
+	}
+
+	if hardware.MemoryTotal == 0 {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: memory_total must be positive")
+	}
+
+	nics := make([]string, 0, len(hardware.NICs))
+	for _, nic := range hardware.NICs {
+		if nic.Name == "" || slices.Contains(nics, nic.Name) {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: nic name %q is empty or duplicated", nic.Name)
+		}
+
+		if nic.Speed < 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: speed of nic %q must not be negative", nic.Name)
+		}
+
+		nics = append(nics, nic.Name)
+	}
+
+	devices := make([]string, 0, len(hardware.BlockDevices))
+	for _, device := range hardware.BlockDevices {
+		if device.Name == "" || slices.Contains(devices, device.Name) {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: block device name %q is empty or duplicated", device.Name)
+		}
+
+		if device.Size == 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid hardware inventory: size of block device %q must be positive", device.Name)
+		}
+
+		devices = append(devices, device.Name)
+	}
+
+	return nil
+}
+
+// GetNodeInventory returns the latest hardware inventory of a node, or all
+// its recorded revisions, latest first, if history is set.
+func GetNodeInventory(ctx context.Context, s state.State, name string, history bool) (apitypes.NodeInventories, error) {
+	inventories := apitypes.NodeInventories{}
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		// Tell apart unknown nodes from nodes without inventory
+		_, err := database.GetNodeID(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		records, err := database.GetNodeInventories(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			inventory, err := nodeInventoryFromRecord(record)
+			if err != nil {
+				return err
+			}
+
+			inventories = append(inventories, inventory)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(inventories) == 0 {
+		return nil, api.StatusErrorf(http.StatusNotFound, "Node inventory not found")
+	}
+
+	if !history {
+		return inventories[:1], nil
+	}
+
+	return inventories, nil
+}
+
+// UpdateNodeInventory records the hardware reported by a node and returns the
+// resulting inventory revision. Only the last NodeInventoryHistoryLimit
+// revisions are kept.
+func UpdateNodeInventory(ctx context.Context, s state.State, name string, hardware apitypes.Hardware) (apitypes.NodeInventory, error) {
+	err := ValidateHardware(hardware)
+	if err != nil {
+		return apitypes.NodeInventory{}, err
+	}
+
+	var next apitypes.NodeInventory
+
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		records, err := database.GetNodeInventories(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		var current *apitypes.NodeInventory
+		if len(records) > 0 {
+			latest, err := nodeInventoryFromRecord(records[0])
+			if err != nil {
+				return err
+			}
+
+			current = &latest
+		}
+
+		next = nextNodeInventory(current, name, hardware, time.Now())
+
+		data, err := json.Marshal(next.Hardware)
+		if err != nil {
+			return err
+		}
+
+		err = database.UpsertNodeInventory(ctx, tx, database.NodeInventory{
+			Node:        name,
+			Revision:    next.Revision,
+			CollectedAt: next.CollectedAt,
+			RefreshedAt: next.RefreshedAt,
+			Data:        string(data),
+		})
+		if err != nil {
+			return err
+		}
+
+		return database.DeleteNodeInventoriesBefore(ctx, tx, name, next.Revision-apitypes.NodeInventoryHistoryLimit+1)
+	})
+	if err != nil {
+		return apitypes.NodeInventory{}, err
+	}
+
+	return next, nil
+}
+
+// nextNodeInventory returns the inventory to record when hardware is reported
+// at now for a node whose latest inventory is current, nil if none. The
+// revision is only increased when the hardware differs, so drift shows up as
+// a new revision.
+func nextNodeInventory(current *apitypes.NodeInventory, name string, hardware apitypes.Hardware, now time.Time) apitypes.NodeInventory {
+	reportedAt := now.UTC().Format(time.RFC3339)
+	hardware = normalizeHardware(hardware)
+
+	if current != nil && reflect.DeepEqual(normalizeHardware(current.Hardware), hardware) {
+		next := *current
+		next.RefreshedAt = reportedAt
+		return next
+	}
+
+	revision := 1
+	if current != nil {
+		revision = current.Revision + 1
+	}
+
+	return apitypes.NodeInventory{
+		Node:        name,
+		Revision:    revision,
+		CollectedAt: reportedAt,
+		RefreshedAt: reportedAt,
+		Hardware:    hardware,
+	}
+}
+
+// normalizeHardware sorts the devices of hardware by name, so that the order
+// in which they were collected does not count as a change.
+func normalizeHardware(hardware apitypes.Hardware) apitypes.Hardware {
+	nics := slices.Clone(hardware.NICs)
+	if nics == nil {
+		nics = []apitypes.HardwareNIC{}
+	}
+
+	slices.SortFunc(nics, func(a, b apitypes.HardwareNIC) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	devices := slices.Clone(hardware.BlockDevices)
+	if devices == nil {
+		devices = []apitypes.HardwareBlockDevice{}
+	}
+
+	slices.SortFunc(devices, func(a, b apitypes.HardwareBlockDevice) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	hardware.NICs = nics
+	hardware.BlockDevices = devices
+
+	return hardware
+}
+
+// nodeInventoryFromRecord converts a record to a NodeInventory
+func nodeInventoryFromRecord(record database.NodeInventory) (apitypes.NodeInventory, error) {
+	hardware := apitypes.Hardware{}
+	err := json.Unmarshal([]byte(record.Data), &hardware)
+	if err != nil {
+		return apitypes.NodeInventory{}, fmt.Errorf("Failed to parse inventory of node %q: %w", record.Node, err)
+	}
+
+	return apitypes.NodeInventory{
+		Node:        record.Node,
+		Revision:    record.Revision,
+		CollectedAt: record.CollectedAt,
+		RefreshedAt: record.RefreshedAt,
+		Hardware:    hardware,
+	}, nil
+}
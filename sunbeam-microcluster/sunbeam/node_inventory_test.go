@@ -0,0 +1,125 @@
+package sunbeam
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+const testHardware = `{
+  "cpu_count": 16,
+  "memory_total": 68719476736,
+  "nics": [{"name": "eno2", "mac_address": "00:16:3e:00:00:02", "speed": 10000}, {"name": "eno1", "mac_address": "00:16:3e:00:00:01", "speed": 1000}],
+  "block_devices": [{"name": "sda", "model": "SSD 870", "size": 1000204886016, "rotational": false}]
+}`
+
+// TestDecodeHardware tests rejection of inventories not following the schema
+func TestDecodeHardware(t *testing.T) {
+	testCases := []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{name: "documented schema", body: testHardware, valid: true},
+		{name: "unknown field", body: `{"cpu_count": 1, "memory_total": 1, "gpus": []}`, valid: false},
+		{name: "wrong type", body: `{"cpu_count": "16", "memory_total": 1}`, valid: false},
+		{name: "negative memory", body: `{"cpu_count": 1, "memory_total": -1}`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeHardware(strings.NewReader(tc.body))
+			if tc.valid && err != nil {
+				t.Errorf("Expected inventory to be valid, got %v", err)
+			}
+
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestValidateHardware tests the constraints on the inventory values
+func TestValidateHardware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		hardware apitypes.Hardware
+		valid    bool
+	}{
+		{name: "no devices", hardware: apitypes.Hardware{CPUCount: 1, MemoryTotal: 1}, valid: true},
+		{name: "missing cpu count", hardware: apitypes.Hardware{MemoryTotal: 1}, valid: false},
+		{name: "missing memory", hardware: apitypes.Hardware{CPUCount: 1}, valid: false},
+		{name: "unnamed nic", hardware: apitypes.Hardware{CPUCount: 1, MemoryTotal: 1, NICs: []apitypes.HardwareNIC{{MACAddress: "00:16:3e:00:00:01"}}}, valid: false},
+		{name: "negative nic speed", hardware: apitypes.Hardware{CPUCount: 1, MemoryTotal: 1, NICs: []apitypes.HardwareNIC{{Name: "eno1", Speed: -1}}}, valid: false},
+		{name: "duplicated block device", hardware: apitypes.Hardware{CPUCount: 1, MemoryTotal: 1, BlockDevices: []apitypes.HardwareBlockDevice{{Name: "sda", Size: 1}, {Name: "sda", Size: 1}}}, valid: false},
+		{name: "empty block device", hardware: apitypes.Hardware{CPUCount: 1, MemoryTotal: 1, BlockDevices: []apitypes.HardwareBlockDevice{{Name: "sda"}}}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateHardware(tc.hardware)
+			if tc.valid && err != nil {
+				t.Errorf("Expected inventory to be valid, got %v", err)
+			}
+
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestNextNodeInventory tests that posting an inventory records a revision
+// that reads back the same, and that only hardware changes make new revisions.
+func TestNextNodeInventory(t *testing.T) {
+	hardware, err := DecodeHardware(strings.NewReader(testHardware))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	joined := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	first := nextNodeInventory(nil, "node-1", hardware, joined)
+	if first.Revision != 1 || first.CollectedAt != "2025-03-01T10:00:00Z" || first.RefreshedAt != first.CollectedAt {
+		t.Fatalf("Expected first revision collected at join time, got %+v", first)
+	}
+
+	// Read back the inventory the way it is stored
+	data, err := json.Marshal(first.Hardware)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stored, err := nodeInventoryFromRecord(database.NodeInventory{Node: "node-1", Revision: first.Revision, CollectedAt: first.CollectedAt, RefreshedAt: first.RefreshedAt, Data: string(data)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if stored.Hardware.CPUCount != 16 || len(stored.Hardware.NICs) != 2 || stored.Hardware.NICs[0].Name != "eno1" {
+		t.Errorf("Expected stored inventory with sorted nics, got %+v", stored.Hardware)
+	}
+
+	// Same hardware collected in another order
+	refreshed := joined.Add(time.Hour)
+	same := hardware
+	same.NICs = []apitypes.HardwareNIC{hardware.NICs[1], hardware.NICs[0]}
+	unchanged := nextNodeInventory(&stored, "node-1", same, refreshed)
+	if unchanged.Revision != 1 || unchanged.CollectedAt != first.CollectedAt || unchanged.RefreshedAt != "2025-03-01T11:00:00Z" {
+		t.Errorf("Expected revision 1 refreshed, got %+v", unchanged)
+	}
+
+	// A disk was added
+	changed := hardware
+	changed.BlockDevices = append(changed.BlockDevices, apitypes.HardwareBlockDevice{Name: "sdb", Size: 4000787030016, Rotational: true})
+	drifted := nextNodeInventory(&unchanged, "node-1", changed, refreshed.Add(time.Hour))
+	if drifted.Revision != 2 || drifted.CollectedAt != "2025-03-01T12:00:00Z" || len(drifted.Hardware.BlockDevices) != 2 {
+		t.Errorf("Expected revision 2 with the new disk, got %+v", drifted)
+	}
+}
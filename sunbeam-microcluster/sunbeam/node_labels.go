@@ -0,0 +1,208 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// maxLabelKeyLength is the maximum length of a node label key
+const maxLabelKeyLength = 63
+
+// maxLabelValueLength is the maximum length in bytes of a node label value
+const maxLabelValueLength = 256
+
+// labelKeyRegex matches lowercase alphanumerics separated by '-', '.' or '_'
+var labelKeyRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`)
+
+// GetNodeLabels returns the labels of the node with the given name
+func GetNodeLabels(ctx context.Context, s state.State, name string) (apitypes.NodeLabels, error) {
+	var labels apitypes.NodeLabels
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		record, err := database.GetNode(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		labels, err = labelsFromStr(record.Labels)
+		return err
+	})
+
+	return labels, err
+}
+
+// SetNodeLabels replaces the labels of the node with the given name
+func SetNodeLabels(ctx context.Context, s state.State, name string, labels apitypes.NodeLabels) (apitypes.NodeLabels, error) {
+	err := ValidateLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateNodeLabels(ctx, s, name, func(apitypes.NodeLabels) apitypes.NodeLabels {
+		return labels
+	})
+}
+
+// MergeNodeLabels merges patch into the labels of the node with the given
+// name. Keys with a nil value are removed.
+func MergeNodeLabels(ctx context.Context, s state.State, name string, patch map[string]*string) (apitypes.NodeLabels, error) {
+	set := apitypes.NodeLabels{}
+	for key, value := range patch {
+		if value != nil {
+			set[key] = *value
+		}
+	}
+
+	err := ValidateLabels(set)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateNodeLabels(ctx, s, name, func(labels apitypes.NodeLabels) apitypes.NodeLabels {
+		return mergeLabels(labels, patch)
+	})
+}
+
+// updateNodeLabels applies update to the stored labels of a node within a
+// single transaction and returns the resulting labels.
+func updateNodeLabels(ctx context.Context, s state.State, name string, update func(apitypes.NodeLabels) apitypes.NodeLabels) (apitypes.NodeLabels, error) {
+	var labels apitypes.NodeLabels
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		record, err := database.GetNode(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		current, err := labelsFromStr(record.Labels)
+		if err != nil {
+			return err
+		}
+
+		labels = update(current)
+		record.Labels, err = labelsToStr(labels)
+		if err != nil {
+			return err
+		}
+
+		err = database.UpdateNode(ctx, tx, name, *record)
+		if err != nil {
+			return fmt.Errorf("Failed to update node labels: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// mergeLabels returns labels with patch applied, keys with a nil value in
+// patch are removed.
+func mergeLabels(labels apitypes.NodeLabels, patch map[string]*string) apitypes.NodeLabels {
+	merged := apitypes.NodeLabels{}
+	for key, value := range labels {
+		merged[key] = value
+	}
+
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+
+		merged[key] = *value
+	}
+
+	return merged
+}
+
+// ValidateLabels checks label keys are DNS label like and values are within
+// the size limit.
+func ValidateLabels(labels apitypes.NodeLabels) error {
+	for key, value := range labels {
+		if len(key) > maxLabelKeyLength || !labelKeyRegex.MatchString(key) {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid label key %q: must be at most %d lowercase alphanumerics, '-', '.' or '_'", key, maxLabelKeyLength)
+		}
+
+		if len(value) > maxLabelValueLength {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid value for label %q: must be at most %d bytes", key, maxLabelValueLength)
+		}
+	}
+
+	return nil
+}
+
+// ParseLabelSelectors parses a list of key=value label selectors
+func ParseLabelSelectors(selectors []string) (apitypes.NodeLabels, error) {
+	labels := apitypes.NodeLabels{}
+	for _, selector := range selectors {
+		key, value, found := strings.Cut(selector, "=")
+		if !found {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid label selector %q: expected key=value", selector)
+		}
+
+		labels[key] = value
+	}
+
+	err := ValidateLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// MatchLabels returns whether labels hold all the key/value pairs of selector
+func MatchLabels(labels apitypes.NodeLabels, selector apitypes.NodeLabels) bool {
+	for key, value := range selector {
+		current, ok := labels[key]
+		if !ok || current != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// labelsToStr converts labels to a json string
+func labelsToStr(labels apitypes.NodeLabels) (string, error) {
+	if labels == nil {
+		labels = apitypes.NodeLabels{}
+	}
+
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return "", fmt.Errorf("Failed to marshal labels: %w", err)
+	}
+
+	return string(labelsJSON), nil
+}
+
+// labelsFromStr converts a json string to labels
+func labelsFromStr(labelsStr string) (apitypes.NodeLabels, error) {
+	labels := apitypes.NodeLabels{}
+	if labelsStr == "" {
+		return labels, nil
+	}
+
+	err := json.Unmarshal([]byte(labelsStr), &labels)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal labels: %w", err)
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,111 @@
+package sunbeam
+
+import (
+	"maps"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// TestValidateLabels tests label key format and value size validation
+func TestValidateLabels(t *testing.T) {
+	testCases := []struct {
+		name   string
+		labels apitypes.NodeLabels
+		valid  bool
+	}{
+		{name: "no labels", labels: apitypes.NodeLabels{}, valid: true},
+		{name: "simple labels", labels: apitypes.NodeLabels{"rack": "A3", "gpu": "true", "az": "zone-1"}, valid: true},
+		{name: "key with separators", labels: apitypes.NodeLabels{"topology.zone_1-a": "x"}, valid: true},
+		{name: "empty value", labels: apitypes.NodeLabels{"gpu": ""}, valid: true},
+		{name: "value at size limit", labels: apitypes.NodeLabels{"gpu": strings.Repeat("x", maxLabelValueLength)}, valid: true},
+		{name: "empty key", labels: apitypes.NodeLabels{"": "x"}, valid: false},
+		{name: "uppercase key", labels: apitypes.NodeLabels{"Rack": "A3"}, valid: false},
+		{name: "key with slash", labels: apitypes.NodeLabels{"rack/row": "A3"}, valid: false},
+		{name: "key starting with separator", labels: apitypes.NodeLabels{"-rack": "A3"}, valid: false},
+		{name: "key ending with separator", labels: apitypes.NodeLabels{"rack.": "A3"}, valid: false},
+		{name: "key too long", labels: apitypes.NodeLabels{strings.Repeat("a", maxLabelKeyLength+1): "x"}, valid: false},
+		{name: "value too long", labels: apitypes.NodeLabels{"gpu": strings.Repeat("x", maxLabelValueLength+1)}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateLabels(tc.labels)
+			if tc.valid && err != nil {
+				t.Errorf("Expected labels to be valid, got error: %v", err)
+			}
+
+			if !tc.valid && !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestParseLabelSelectors tests parsing of key=value label selectors
+func TestParseLabelSelectors(t *testing.T) {
+	labels, err := ParseLabelSelectors([]string{"gpu=true", "az=zone=1", "empty="})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := apitypes.NodeLabels{"gpu": "true", "az": "zone=1", "empty": ""}
+	if !maps.Equal(labels, expected) {
+		t.Errorf("Expected %v, got %v", expected, labels)
+	}
+
+	for _, selector := range []string{"gpu", "GPU=true"} {
+		_, err := ParseLabelSelectors([]string{selector})
+		if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+			t.Errorf("Expected bad request error for %q, got %v", selector, err)
+		}
+	}
+}
+
+// TestMatchLabels tests that all selector labels must match
+func TestMatchLabels(t *testing.T) {
+	labels := apitypes.NodeLabels{"gpu": "true", "rack": "A3"}
+
+	testCases := []struct {
+		name     string
+		selector apitypes.NodeLabels
+		expected bool
+	}{
+		{name: "empty selector", selector: nil, expected: true},
+		{name: "single match", selector: apitypes.NodeLabels{"gpu": "true"}, expected: true},
+		{name: "all match", selector: apitypes.NodeLabels{"gpu": "true", "rack": "A3"}, expected: true},
+		{name: "value mismatch", selector: apitypes.NodeLabels{"gpu": "false"}, expected: false},
+		{name: "missing key", selector: apitypes.NodeLabels{"az": "zone-1"}, expected: false},
+		{name: "partial match", selector: apitypes.NodeLabels{"gpu": "true", "az": "zone-1"}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if MatchLabels(labels, tc.selector) != tc.expected {
+				t.Errorf("Expected match=%v", tc.expected)
+			}
+		})
+	}
+}
+
+// TestMergeLabels tests PATCH semantics: set, overwrite and remove keys
+func TestMergeLabels(t *testing.T) {
+	labels := apitypes.NodeLabels{"gpu": "true", "rack": "A3"}
+	zone := "zone-1"
+	rack := "B1"
+
+	merged := mergeLabels(labels, map[string]*string{"az": &zone, "rack": &rack, "gpu": nil})
+
+	expected := apitypes.NodeLabels{"az": "zone-1", "rack": "B1"}
+	if !maps.Equal(merged, expected) {
+		t.Errorf("Expected %v, got %v", expected, merged)
+	}
+
+	if labels["gpu"] != "true" || labels["rack"] != "A3" {
+		t.Errorf("Expected original labels to be unchanged, got %v", labels)
+	}
+}
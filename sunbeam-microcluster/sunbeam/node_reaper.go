@@ -0,0 +1,107 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+const (
+	// reapInterval is how often removed nodes older than the retention are purged
+	reapInterval = time.Hour
+
+	// defaultTombstoneRetention is how long removed nodes are kept by default
+	defaultTombstoneRetention = 30 * 24 * time.Hour
+
+	// TombstoneRetentionConfigKey is the config key holding the retention of
+	// removed nodes as a duration, e.g. 720h
+	TombstoneRetentionConfigKey = "nodes.tombstone-retention"
+)
+
+// StartNodeReaper starts a background goroutine that purges the tombstones of
+// removed nodes older than the configured retention.
+func StartNodeReaper(ctx context.Context, s state.State) {
+	go reapLoop(ctx, s)
+
+	logger.Info("Started removed nodes reaper")
+}
+
+// reapLoop periodically purges expired tombstones
+func reapLoop(ctx context.Context, s state.State) {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopping removed nodes reaper")
+			return
+		case <-ticker.C:
+			n, err := reapNodeTombstones(ctx, s, time.Now())
+			if err != nil {
+				logger.Warnf("Failed to purge removed nodes: %v", err)
+				continue
+			}
+
+			if n > 0 {
+				logger.Infof("Purged %d removed nodes past retention", n)
+			}
+		}
+	}
+}
+
+// reapNodeTombstones deletes the tombstones older than the retention at now
+func reapNodeTombstones(ctx context.Context, s state.State, now time.Time) (int64, error) {
+	value, err := GetConfig(ctx, s, TombstoneRetentionConfigKey)
+	if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return 0, err
+	}
+
+	retention, err := parseTombstoneRetention(configScalar(value))
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := tombstoneCutoff(now, retention)
+
+	var n int64
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		n, err = database.DeleteNodeTombstonesBefore(ctx, tx, cutoff)
+		return err
+	})
+
+	return n, err
+}
+
+// parseTombstoneRetention parses the configured retention, falling back to
+// the default when unset.
+func parseTombstoneRetention(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultTombstoneRetention, nil
+	}
+
+	retention, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s %q: %w", TombstoneRetentionConfigKey, value, err)
+	}
+
+	if retention < 0 {
+		return 0, fmt.Errorf("Invalid %s %q: must not be negative", TombstoneRetentionConfigKey, value)
+	}
+
+	return retention, nil
+}
+
+// tombstoneCutoff returns the removal time before which tombstones expire,
+// in the format tombstones are stored with.
+func tombstoneCutoff(now time.Time, retention time.Duration) string {
+	return now.Add(-retention).UTC().Format(time.RFC3339)
+}
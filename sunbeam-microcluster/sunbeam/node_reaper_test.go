@@ -0,0 +1,64 @@
+package sunbeam
+
+import (
+	"testing"
+	"time"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestParseTombstoneRetention tests the retention parsing and default
+func TestParseTombstoneRetention(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{value: "", expected: defaultTombstoneRetention},
+		{value: "168h", expected: 7 * 24 * time.Hour},
+		{value: "0s", expected: 0},
+		{value: "-1h", wantErr: true},
+		{value: "7d", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			retention, err := parseTombstoneRetention(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error for %q", tc.value)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if retention != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, retention)
+			}
+		})
+	}
+}
+
+// TestTombstoneCutoff tests that tombstones older than the retention sort
+// before the cutoff and newer ones after it.
+func TestTombstoneCutoff(t *testing.T) {
+	now := time.Date(2025, 3, 31, 10, 0, 0, 0, time.UTC)
+	cutoff := tombstoneCutoff(now, 30*24*time.Hour)
+
+	if cutoff != "2025-03-01T10:00:00Z" {
+		t.Errorf("Unexpected cutoff %q", cutoff)
+	}
+
+	expired := tombstoneFromNode(database.Node{Name: "node-1"}, now.Add(-31*24*time.Hour), "", "")
+	if !(expired.RemovedAt < cutoff) {
+		t.Errorf("Expected %q to be purged with cutoff %q", expired.RemovedAt, cutoff)
+	}
+
+	kept := tombstoneFromNode(database.Node{Name: "node-1"}, now.Add(-29*24*time.Hour), "", "")
+	if kept.RemovedAt < cutoff {
+		t.Errorf("Expected %q to be kept with cutoff %q", kept.RemovedAt, cutoff)
+	}
+}
@@ -5,20 +5,32 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/microcluster/v2/state"
 
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
 )
 
-// ListNodes return all the nodes, filterable by role (Optional)
-func ListNodes(ctx context.Context, s state.State, roles []string) (apitypes.Nodes, error) {
+// ListNodes return all the nodes ordered by join time, filterable by role and labels (Optional).
+// Nodes matching any of the given roles and all of the given labels are returned.
+// If includeRemoved is set, matching removed nodes follow ordered by removal time.
+func ListNodes(ctx context.Context, s state.State, roles []string, labels apitypes.NodeLabels, includeRemoved bool) (apitypes.Nodes, error) {
 	nodes := apitypes.Nodes{}
 
 	// Get the nodes from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		err := validateNodeRoles(ctx, tx, roles)
+		if err != nil {
+			return err
+		}
+
 		records, err := database.GetNodesFromRoles(ctx, tx, roles)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch nodes: %w", err)
@@ -29,14 +41,52 @@ func ListNodes(ctx context.Context, s state.State, roles []string) (apitypes.Nod
 			if err != nil {
 				return err
 			}
+			nodeLabels, err := labelsFromStr(node.Labels)
+			if err != nil {
+				return err
+			}
+			if !MatchLabels(nodeLabels, labels) {
+				continue
+			}
+			nodeAddresses, err := addressesFromStr(node.Addresses)
+			if err != nil {
+				return err
+			}
 			nodes = append(nodes, apitypes.Node{
-				Name:      node.Name,
-				Role:      nodeRole,
-				MachineID: node.MachineID,
-				SystemID:  node.SystemID,
+				Name:         node.Name,
+				Role:         nodeRole,
+				MachineID:    node.MachineID,
+				SystemID:     node.SystemID,
+				Member:       node.Member,
+				Labels:       nodeLabels,
+				Cordoned:     node.Cordoned,
+				Addresses:    nodeAddresses,
+				PreferredAPI: node.PreferredAPI,
 			})
 		}
 
+		if !includeRemoved {
+			return nil
+		}
+
+		tombstones, err := database.GetNodeTombstones(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch removed nodes: %w", err)
+		}
+
+		for _, tombstone := range tombstones {
+			node, err := nodeFromTombstone(tombstone)
+			if err != nil {
+				return err
+			}
+
+			if !matchRoles(node.Role, roles) || !MatchLabels(node.Labels, labels) {
+				continue
+			}
+
+			nodes = append(nodes, node)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -49,7 +99,7 @@ func ListNodes(ctx context.Context, s state.State, roles []string) (apitypes.Nod
 // GetNode returns a Node with the given name
 func GetNode(ctx context.Context, s state.State, name string) (apitypes.Node, error) {
 	node := apitypes.Node{MachineID: -1}
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		record, err := database.GetNode(ctx, tx, name)
 		if err != nil {
 			return err
@@ -59,10 +109,23 @@ func GetNode(ctx context.Context, s state.State, name string) (apitypes.Node, er
 		if err != nil {
 			return err
 		}
+		nodeLabels, err := labelsFromStr(record.Labels)
+		if err != nil {
+			return err
+		}
+		nodeAddresses, err := addressesFromStr(record.Addresses)
+		if err != nil {
+			return err
+		}
 		node.Name = record.Name
 		node.Role = nodeRole
 		node.MachineID = record.MachineID
 		node.SystemID = record.SystemID
+		node.Member = record.Member
+		node.Labels = nodeLabels
+		node.Cordoned = record.Cordoned
+		node.Addresses = nodeAddresses
+		node.PreferredAPI = record.PreferredAPI
 
 		return nil
 	})
@@ -70,25 +133,62 @@ func GetNode(ctx context.Context, s state.State, name string) (apitypes.Node, er
 	return node, err
 }
 
-// AddNode adds a node to the database
-func AddNode(ctx context.Context, s state.State, name string, role []string, machineid int, systemid string) error {
+// SetNodeCordon records whether the node with the given name is cordoned and
+// returns it. Cordoned nodes are not selected for new workloads, the workload
+// placement being updated by the client.
+func SetNodeCordon(ctx context.Context, s state.State, name string, cordoned bool) (apitypes.Node, error) {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		record, err := database.GetNode(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		if record.Cordoned == cordoned {
+			return nil
+		}
+
+		record.Cordoned = cordoned
+		err = database.UpdateNode(ctx, tx, name, *record)
+		if err != nil {
+			return fmt.Errorf("Failed to update node cordon: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return apitypes.Node{}, err
+	}
+
+	return GetNode(ctx, s, name)
+}
+
+// AddNode adds a node to the database, its roles must be built-in or custom
+// node roles and its preferred API address one of its addresses. A
+// node.added event is emitted once it is recorded.
+func AddNode(ctx context.Context, s state.State, name string, role []string, machineid int, systemid string, addresses apitypes.NodeAddresses, preferredAPI string) error {
 	nodeRole, err := roleToStr(role)
 	if err != nil {
 		return err
 	}
+	nodeAddresses, preferredAPI, err := nodeAddressesRecord(addresses, preferredAPI)
+	if err != nil {
+		return err
+	}
 	// Add node to the database.
-	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		_, err := database.CreateNode(ctx, tx, database.Node{Member: s.Name(), Name: name, Role: nodeRole, MachineID: machineid, SystemID: systemid})
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		err := validateNodeRoles(ctx, tx, role)
 		if err != nil {
-			return fmt.Errorf("Failed to record node: %w", err)
+			return err
 		}
 
-		return nil
+		return addNodeRecord(ctx, tx, database.Node{Member: s.Name(), Name: name, Role: nodeRole, MachineID: machineid, SystemID: systemid, Labels: "{}", Addresses: nodeAddresses, PreferredAPI: preferredAPI})
 	})
 	if err != nil {
 		return err
 	}
 
+	emitEvent(ctx, apitypes.EventNodeAdded, nodeAddedData(name, role))
+
 	return nil
 }
 
@@ -99,7 +199,7 @@ func UpdateNode(ctx context.Context, s state.State, name string, role []string,
 		return err
 	}
 	// Update node to the database.
-	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		node, err := database.GetNode(ctx, tx, name)
 		if err != nil {
 			return fmt.Errorf("Failed to retrieve node details: %w", err)
@@ -107,6 +207,11 @@ func UpdateNode(ctx context.Context, s state.State, name string, role []string,
 
 		if role == nil {
 			nodeRole = node.Role
+		} else {
+			err = validateNodeRoles(ctx, tx, role)
+			if err != nil {
+				return err
+			}
 		}
 		if machineid == -1 {
 			machineid = node.MachineID
@@ -115,7 +220,9 @@ func UpdateNode(ctx context.Context, s state.State, name string, role []string,
 			systemid = node.SystemID
 		}
 
-		err = database.UpdateNode(ctx, tx, name, database.Node{Member: s.Name(), Name: name, Role: nodeRole, MachineID: machineid, SystemID: systemid})
+		// Labels and cordon are managed through their own endpoints, and
+		// addresses are set when adding the node, keep them as is
+		err = database.UpdateNode(ctx, tx, name, database.Node{Member: s.Name(), Name: name, Role: nodeRole, MachineID: machineid, SystemID: systemid, Labels: node.Labels, Cordoned: node.Cordoned, Addresses: node.Addresses, PreferredAPI: node.PreferredAPI})
 		if err != nil {
 			return fmt.Errorf("Failed to update record node: %w", err)
 		}
@@ -129,21 +236,155 @@ func UpdateNode(ctx context.Context, s state.State, name string, role []string,
 	return nil
 }
 
-// DeleteNode deletes a node from database
-func DeleteNode(ctx context.Context, s state.State, name string) error {
-	// Delete node from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
-		err := database.DeleteNode(ctx, tx, name)
-		if err != nil {
-			return fmt.Errorf("Failed to delete node: %w", err)
-		}
-
-		return nil
+// DeleteNode removes a node from the database, keeping a tombstone of it
+// with the removal reason and actor for auditing. A node.removed event is
+// emitted once it is removed.
+func DeleteNode(ctx context.Context, s state.State, name string, reason string, actor string) error {
+	// Move node to the tombstones in the database.
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		return removeNodeRecord(ctx, tx, name, time.Now(), reason, actor)
 	})
 	if err != nil {
 		return err
 	}
 
+	emitEvent(ctx, apitypes.EventNodeRemoved, nodeRemovedData(name, reason, actor))
+
+	return nil
+}
+
+// nodeAddedData returns the data of the node.added event of a node
+func nodeAddedData(name string, role []string) map[string]string {
+	return map[string]string{"node": name, "role": strings.Join(role, ",")}
+}
+
+// nodeRemovedData returns the data of the node.removed event of a node
+func nodeRemovedData(name string, reason string, actor string) map[string]string {
+	return map[string]string{"node": name, "reason": reason, "actor": actor}
+}
+
+// addNodeRecord records a node, resurrecting it if it was removed. A 403
+// StatusError is returned if the join token of the node is scoped to other
+// roles, a 409 one if the node is already recorded.
+func addNodeRecord(ctx context.Context, tx *sql.Tx, node database.Node) error {
+	role, err := roleFromStr(node.Role)
+	if err != nil {
+		return err
+	}
+
+	err = checkJoinTokenScopeRoles(ctx, tx, node.Name, role)
+	if err != nil {
+		return err
+	}
+
+	// Re-adding a removed node resurrects it, clear its tombstone
+	err = database.DeleteNodeTombstone(ctx, tx, node.Name)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.CreateNode(ctx, tx, node)
+	if api.StatusErrorCheck(err, http.StatusConflict) {
+		return api.StatusErrorf(http.StatusConflict, "Node %q already exists", node.Name)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to record node: %w", err)
+	}
+
+	return nil
+}
+
+// removeNodeRecord moves a node record to the tombstones
+func removeNodeRecord(ctx context.Context, tx *sql.Tx, name string, removedAt time.Time, reason string, actor string) error {
+	record, err := database.GetNode(ctx, tx, name)
+	if err != nil {
+		return fmt.Errorf("Failed to retrieve node details: %w", err)
+	}
+
+	err = database.CreateNodeTombstone(ctx, tx, tombstoneFromNode(*record, removedAt, reason, actor))
+	if err != nil {
+		return fmt.Errorf("Failed to record removed node: %w", err)
+	}
+
+	err = database.DeleteNode(ctx, tx, name)
+	if err != nil {
+		return fmt.Errorf("Failed to delete node: %w", err)
+	}
+
+	// The node joins again with a new token
+	err = database.DeleteJoinTokenScope(ctx, tx, name)
+	if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// tombstoneFromNode returns the tombstone of a node removed at the given time
+func tombstoneFromNode(node database.Node, removedAt time.Time, reason string, actor string) database.NodeTombstone {
+	return database.NodeTombstone{
+		Name:          node.Name,
+		Member:        node.Member,
+		Role:          node.Role,
+		MachineID:     node.MachineID,
+		SystemID:      node.SystemID,
+		Labels:        node.Labels,
+		RemovedAt:     removedAt.UTC().Format(time.RFC3339),
+		RemovedReason: reason,
+		RemovedBy:     actor,
+	}
+}
+
+// nodeFromTombstone converts a tombstone to a removed Node
+func nodeFromTombstone(tombstone database.NodeTombstone) (apitypes.Node, error) {
+	nodeRole, err := roleFromStr(tombstone.Role)
+	if err != nil {
+		return apitypes.Node{}, err
+	}
+
+	nodeLabels, err := labelsFromStr(tombstone.Labels)
+	if err != nil {
+		return apitypes.Node{}, err
+	}
+
+	return apitypes.Node{
+		Name:          tombstone.Name,
+		Role:          nodeRole,
+		MachineID:     tombstone.MachineID,
+		SystemID:      tombstone.SystemID,
+		Member:        tombstone.Member,
+		Labels:        nodeLabels,
+		RemovedAt:     tombstone.RemovedAt,
+		RemovedReason: tombstone.RemovedReason,
+		RemovedBy:     tombstone.RemovedBy,
+	}, nil
+}
+
+// matchRoles returns whether nodeRole holds any of roles, or roles is empty
+func matchRoles(nodeRole []string, roles []string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+
+	for _, role := range roles {
+		if slices.Contains(nodeRole, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateRoles checks that all the given roles are built-in node roles or
+// one of the custom roles.
+func ValidateRoles(roles []string, custom []string) error {
+	for _, role := range roles {
+		if !slices.Contains(apitypes.NodeRoles, role) && !slices.Contains(custom, role) {
+			return api.StatusErrorf(http.StatusBadRequest, "Unknown node role %q", role)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,153 @@
+package sunbeam
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestValidateRoles tests that roles neither built-in nor custom are rejected
+// with a bad request
+func TestValidateRoles(t *testing.T) {
+	testCases := []struct {
+		name   string
+		roles  []string
+		custom []string
+		valid  bool
+	}{
+		{name: "no roles", roles: nil, valid: true},
+		{name: "single known role", roles: []string{"compute"}, valid: true},
+		{name: "multiple known roles", roles: []string{"compute", "storage", "region_controller"}, valid: true},
+		{name: "unknown role", roles: []string{"gpu"}, valid: false},
+		{name: "known and unknown roles", roles: []string{"control", "computer"}, valid: false},
+		{name: "custom role", roles: []string{"compute", "load-balancer"}, custom: []string{"load-balancer"}, valid: true},
+		{name: "undefined custom role", roles: []string{"load-balancer"}, custom: []string{"gateway"}, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRoles(tc.roles, tc.custom)
+			if tc.valid {
+				if err != nil {
+					t.Errorf("Expected roles %v to be valid, got %v", tc.roles, err)
+				}
+				return
+			}
+
+			if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+				t.Errorf("Expected bad request error for roles %v, got %v", tc.roles, err)
+			}
+		})
+	}
+}
+
+// TestNodeTombstoneRoundTrip tests that a removed node keeps its details
+// along with the removal audit fields.
+func TestNodeTombstoneRoundTrip(t *testing.T) {
+	record := database.Node{
+		ID:        4,
+		Member:    "member-1",
+		Name:      "node-1",
+		Role:      `["compute","storage"]`,
+		MachineID: 3,
+		SystemID:  "abc123",
+		Labels:    `{"rack":"A3"}`,
+	}
+	removedAt := time.Date(2025, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	tombstone := tombstoneFromNode(record, removedAt, "hardware failure", "unix-socket")
+	if tombstone.RemovedAt != "2025-03-01T11:30:00Z" {
+		t.Errorf("Expected removal time in UTC, got %q", tombstone.RemovedAt)
+	}
+
+	node, err := nodeFromTombstone(tombstone)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if node.Name != record.Name || node.Member != record.Member || node.MachineID != record.MachineID || node.SystemID != record.SystemID {
+		t.Errorf("Expected node details of %+v, got %+v", record, node)
+	}
+
+	if !slices.Equal(node.Role, []string{"compute", "storage"}) {
+		t.Errorf("Expected roles compute and storage, got %v", node.Role)
+	}
+
+	if !maps.Equal(node.Labels, map[string]string{"rack": "A3"}) {
+		t.Errorf("Expected labels to be kept, got %v", node.Labels)
+	}
+
+	if node.RemovedReason != "hardware failure" || node.RemovedBy != "unix-socket" {
+		t.Errorf("Expected removal reason and actor to be kept, got %q and %q", node.RemovedReason, node.RemovedBy)
+	}
+}
+
+// TestMatchRoles tests role filtering of removed nodes
+func TestMatchRoles(t *testing.T) {
+	nodeRole := []string{"compute", "storage"}
+
+	if !matchRoles(nodeRole, nil) {
+		t.Error("Expected no roles to match")
+	}
+
+	if !matchRoles(nodeRole, []string{"control", "storage"}) {
+		t.Error("Expected any role to match")
+	}
+
+	if matchRoles(nodeRole, []string{"control"}) {
+		t.Error("Expected control not to match")
+	}
+}
+
+// TestAddNodeRecordConcurrent tests that of two nodes added concurrently with
+// the same name one is recorded, and the other rejected with a conflict
+func TestAddNodeRecordConcurrent(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			tx, err := db.BeginTx(t.Context(), nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			errs[i] = addNodeRecord(t.Context(), tx, database.Node{Member: "node-1", Name: "node-3", Role: `["compute"]`, MachineID: 3 + i, Labels: "{}"})
+			if errs[i] != nil {
+				_ = tx.Rollback()
+				return
+			}
+
+			errs[i] = tx.Commit()
+		}()
+	}
+
+	wg.Wait()
+
+	added := slices.IndexFunc(errs, func(err error) bool { return err == nil })
+	if added == -1 {
+		t.Fatalf("Expected one of the nodes added, got %v", errs)
+	}
+
+	if err := errs[1-added]; !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error for the other node, got %v", err)
+	}
+
+	var nodes int
+	err := db.QueryRowContext(t.Context(), `SELECT count(*) FROM nodes WHERE name = 'node-3'`).Scan(&nodes)
+	if err != nil || nodes != 1 {
+		t.Errorf("Expected node-3 recorded once, got %d nodes and %v", nodes, err)
+	}
+}
@@ -0,0 +1,482 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+const (
+	// operationReapInterval is how often the operations whose runner stopped
+	// reporting are checked
+	operationReapInterval = time.Minute
+
+	// operationHeartbeatTimeout is how long the runner of an operation may
+	// not report before the operation is failed
+	operationHeartbeatTimeout = 5 * time.Minute
+)
+
+// DetachableCommands are the sunbeam commands which can run as operations,
+// the arguments of an operation start with one of them.
+var DetachableCommands = [][]string{
+	{"cluster", "maintenance", "enable"},
+	{"cluster", "maintenance", "disable"},
+	{"cluster", "maintenance", "drain"},
+}
+
+// operationStore persists the operations
+type operationStore interface {
+	List() ([]database.Operation, error)
+	// Get returns the operation id, or a 404 StatusError
+	Get(id int) (database.Operation, error)
+	Create(record database.Operation) (database.Operation, error)
+	// Transition moves an operation from status from to status to,
+	// returning whether it was in status from
+	Transition(id int, from string, to string, startedAt string, finishedAt string, result string, errorMessage string) (bool, error)
+	// Report records the runner reporting at heartbeatAt, and the progress
+	// if not empty
+	Report(id int, runner string, progress string, heartbeatAt string) error
+}
+
+// txOperationStore is an operationStore backed by a database transaction,
+// so that operations are requested along with the records referring to them
+type txOperationStore struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (t txOperationStore) List() ([]database.Operation, error) {
+	return database.GetOperations(t.ctx, t.tx)
+}
+
+func (t txOperationStore) Get(id int) (database.Operation, error) {
+	record, err := database.GetOperation(t.ctx, t.tx, id)
+	if err != nil {
+		return database.Operation{}, err
+	}
+
+	return *record, nil
+}
+
+func (t txOperationStore) Create(record database.Operation) (database.Operation, error) {
+	id, err := database.CreateOperation(t.ctx, t.tx, record)
+	if err != nil {
+		return database.Operation{}, err
+	}
+
+	return t.Get(int(id))
+}
+
+func (t txOperationStore) Transition(id int, from string, to string, startedAt string, finishedAt string, result string, errorMessage string) (bool, error) {
+	return database.UpdateOperationStatus(t.ctx, t.tx, id, from, to, startedAt, finishedAt, result, errorMessage)
+}
+
+func (t txOperationStore) Report(id int, runner string, progress string, heartbeatAt string) error {
+	err := database.UpdateOperationProgress(t.ctx, t.tx, id, progress)
+	if err != nil {
+		return err
+	}
+
+	return database.UpdateOperationHeartbeat(t.ctx, t.tx, id, runner, heartbeatAt)
+}
+
+// stateOperationStore is an operationStore backed by the database, each
+// operation runs in its own transaction
+type stateOperationStore struct {
+	ctx context.Context
+	s   state.State
+}
+
+func (t stateOperationStore) List() ([]database.Operation, error) {
+	var records []database.Operation
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		records, err = txOperationStore{ctx: ctx, tx: tx}.List()
+		return err
+	})
+
+	return records, err
+}
+
+func (t stateOperationStore) Get(id int) (database.Operation, error) {
+	var record database.Operation
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		record, err = txOperationStore{ctx: ctx, tx: tx}.Get(id)
+		return err
+	})
+
+	return record, err
+}
+
+func (t stateOperationStore) Create(record database.Operation) (database.Operation, error) {
+	var created database.Operation
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		created, err = txOperationStore{ctx: ctx, tx: tx}.Create(record)
+		return err
+	})
+
+	return created, err
+}
+
+func (t stateOperationStore) Transition(id int, from string, to string, startedAt string, finishedAt string, result string, errorMessage string) (bool, error) {
+	var ok bool
+	err := transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		ok, err = txOperationStore{ctx: ctx, tx: tx}.Transition(id, from, to, startedAt, finishedAt, result, errorMessage)
+		return err
+	})
+
+	return ok, err
+}
+
+func (t stateOperationStore) Report(id int, runner string, progress string, heartbeatAt string) error {
+	return transaction(t.ctx, t.s, func(ctx context.Context, tx *sql.Tx) error {
+		return txOperationStore{ctx: ctx, tx: tx}.Report(id, runner, progress, heartbeatAt)
+	})
+}
+
+// ValidateOperationCommand checks that args run one of the
+// DetachableCommands, returning a 400 StatusError otherwise.
+func ValidateOperationCommand(args []string) error {
+	if slices.Contains(args, "--detach") {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid operation command %q, it must not detach", strings.Join(args, " "))
+	}
+
+	detachable := make([]string, 0, len(DetachableCommands))
+	for _, command := range DetachableCommands {
+		if len(args) >= len(command) && slices.Equal(args[:len(command)], command) {
+			return nil
+		}
+
+		detachable = append(detachable, strings.Join(command, " "))
+	}
+
+	return api.StatusErrorf(http.StatusBadRequest, "Invalid operation command %q, expected one of: %s", strings.Join(args, " "), strings.Join(detachable, ", "))
+}
+
+// operationFromRecord converts a record to an Operation
+func operationFromRecord(record database.Operation) apitypes.Operation {
+	command := []string{}
+	err := json.Unmarshal([]byte(record.Command), &command)
+	if err != nil {
+		logger.Warnf("Invalid command of operation %d: %v", record.ID, err)
+	}
+
+	return apitypes.Operation{
+		ID:          record.ID,
+		Command:     command,
+		Member:      record.Member,
+		Runner:      record.Runner,
+		Status:      record.Status,
+		Progress:    record.Progress,
+		Result:      record.Result,
+		Error:       record.Error,
+		CreatedAt:   record.CreatedAt,
+		CreatedBy:   record.CreatedBy,
+		StartedAt:   record.StartedAt,
+		FinishedAt:  record.FinishedAt,
+		HeartbeatAt: record.HeartbeatAt,
+	}
+}
+
+// awaitingRunner describes the pending operation id, which runs once a
+// runner is available
+func awaitingRunner(id int) string {
+	return fmt.Sprintf("waiting for a runner to run operation %d, is `sunbeam operation agent` running?", id)
+}
+
+// CreateOperation records an operation running the sunbeam command of args
+// on this member, requested by actor. The operation is returned pending,
+// until a runner claims it. It returns a 400 StatusError if the command
+// cannot run as an operation.
+func CreateOperation(ctx context.Context, s state.State, args []string, actor string) (apitypes.Operation, error) {
+	return createOperation(stateOperationStore{ctx: ctx, s: s}, args, s.Name(), actor, time.Now())
+}
+
+// createOperation records an operation of member running args, requested by
+// actor at now
+func createOperation(store operationStore, args []string, member string, actor string, now time.Time) (apitypes.Operation, error) {
+	err := ValidateOperationCommand(args)
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	command, err := json.Marshal(args)
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	record, err := store.Create(database.Operation{
+		Member:    member,
+		Command:   string(command),
+		Status:    apitypes.OperationPending,
+		CreatedAt: now.UTC().Format(time.RFC3339),
+		CreatedBy: actor,
+	})
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	return operationFromRecord(record), nil
+}
+
+// ListOperations returns the operations of all the members ordered by ID,
+// including the ones which completed.
+func ListOperations(ctx context.Context, s state.State) (apitypes.Operations, error) {
+	records, err := stateOperationStore{ctx: ctx, s: s}.List()
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make(apitypes.Operations, 0, len(records))
+	for _, record := range records {
+		operations = append(operations, operationFromRecord(record))
+	}
+
+	return operations, nil
+}
+
+// GetOperation returns the operation id, or a 404 StatusError if there is
+// none.
+func GetOperation(ctx context.Context, s state.State, id int) (apitypes.Operation, error) {
+	record, err := getOperation(stateOperationStore{ctx: ctx, s: s}, id)
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	return operationFromRecord(record), nil
+}
+
+// getOperation returns the operation id, or a 404 StatusError naming it
+func getOperation(store operationStore, id int) (database.Operation, error) {
+	record, err := store.Get(id)
+	if api.StatusErrorCheck(err, http.StatusNotFound) {
+		return database.Operation{}, api.StatusErrorf(http.StatusNotFound, "Operation %d not found", id)
+	}
+
+	return record, err
+}
+
+// CancelOperation cancels the operation id on behalf of actor and returns
+// it. The command of a running operation is interrupted by its runner, the
+// operation is cancelling until the command exits.
+func CancelOperation(ctx context.Context, s state.State, id int, actor string) (apitypes.Operation, error) {
+	store := stateOperationStore{ctx: ctx, s: s}
+	err := cancelOperation(store, id, actor, time.Now())
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	record, err := getOperation(store, id)
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	return operationFromRecord(record), nil
+}
+
+// cancelOperation cancels the operation id at now. A pending operation is
+// cancelled at once, a running one is cancelling. It returns a 404
+// StatusError if it does not exist, and a 409 one if it already completed.
+func cancelOperation(store operationStore, id int, actor string, now time.Time) error {
+	record, err := getOperation(store, id)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Cancelled by %s", actor)
+	var ok bool
+	switch record.Status {
+	case apitypes.OperationPending:
+		ok, err = store.Transition(id, apitypes.OperationPending, apitypes.OperationCancelled, "", now.UTC().Format(time.RFC3339), "", message)
+	case apitypes.OperationRunning:
+		ok, err = store.Transition(id, apitypes.OperationRunning, apitypes.OperationCancelling, "", "", "", message)
+	case apitypes.OperationCancelling:
+		return nil
+	default:
+		return api.StatusErrorf(http.StatusConflict, "Operation %d is %s, only pending and running ones can be cancelled", id, record.Status)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// Started or completed since it was read
+		return cancelOperation(store, id, actor, now)
+	}
+
+	return nil
+}
+
+// UpdateOperation records the update reported by the runner of the
+// operation id and returns the operation, so that the runner learns it is
+// cancelling. It returns a 400 StatusError if the update is invalid, a 404
+// one if the operation does not exist and a 409 one if the update does not
+// apply to the status of the operation.
+func UpdateOperation(ctx context.Context, s state.State, id int, update apitypes.OperationUpdate) (apitypes.Operation, error) {
+	store := stateOperationStore{ctx: ctx, s: s}
+	err := updateOperation(store, id, update, time.Now())
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	record, err := getOperation(store, id)
+	if err != nil {
+		return apitypes.Operation{}, err
+	}
+
+	if update.Status != "" {
+		logging.FromContext(ctx).Info("Operation updated", "operation", id, "status", record.Status)
+	}
+
+	return operationFromRecord(record), nil
+}
+
+// updateOperation records update of the operation id at now. A pending
+// operation is claimed once, by the first runner asking, and only that
+// runner reports on it afterwards.
+func updateOperation(store operationStore, id int, update apitypes.OperationUpdate, now time.Time) error {
+	if update.Runner == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid update of operation %d: runner is required", id)
+	}
+
+	at := now.UTC().Format(time.RFC3339)
+	switch update.Status {
+	case apitypes.OperationRunning:
+		ok, err := store.Transition(id, apitypes.OperationPending, apitypes.OperationRunning, at, "", "", "")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			record, err := getOperation(store, id)
+			if err != nil {
+				return err
+			}
+
+			return api.StatusErrorf(http.StatusConflict, "Operation %d is %s, only pending ones can be claimed", id, record.Status)
+		}
+
+		return store.Report(id, update.Runner, update.Progress, at)
+	case "", apitypes.OperationSucceeded, apitypes.OperationFailed:
+	default:
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid update of operation %d: status %q, expected running, succeeded, failed or none", id, update.Status)
+	}
+
+	record, err := getOperation(store, id)
+	if err != nil {
+		return err
+	}
+
+	if record.Status != apitypes.OperationRunning && record.Status != apitypes.OperationCancelling {
+		return api.StatusErrorf(http.StatusConflict, "Operation %d is %s, not running", id, record.Status)
+	}
+
+	if record.Runner != update.Runner {
+		return api.StatusErrorf(http.StatusConflict, "Operation %d is run by %q, not %q", id, record.Runner, update.Runner)
+	}
+
+	err = store.Report(id, update.Runner, update.Progress, at)
+	if err != nil || update.Status == "" {
+		return err
+	}
+
+	status, message := update.Status, update.Error
+	if record.Status == apitypes.OperationCancelling && status == apitypes.OperationFailed {
+		// Keep who cancelled it as the error
+		status, message = apitypes.OperationCancelled, ""
+	}
+
+	ok, err := store.Transition(id, record.Status, status, "", at, update.Result, message)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// Cancelled since it was read
+		return updateOperation(store, id, update, now)
+	}
+
+	return nil
+}
+
+// StartOperationReaper starts a background goroutine failing the
+// operations whose runner stopped reporting, for instance because the
+// machine it ran on went down. Every member runs it, each operation is only
+// failed once.
+func StartOperationReaper(ctx context.Context, s state.State) {
+	go func() {
+		ticker := time.NewTicker(operationReapInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Info("Stopping operation reaper")
+				return
+			case <-ticker.C:
+			}
+
+			err := failStaleOperations(stateOperationStore{ctx: ctx, s: s}, time.Now())
+			if err != nil {
+				logger.Warnf("Failed to check operation runners: %v", err)
+			}
+		}
+	}()
+
+	logger.Info("Started operation reaper")
+}
+
+// failStaleOperations fails the running and cancelling operations whose
+// runner did not report for operationHeartbeatTimeout at now. They are not
+// run again, as the command may have partially completed.
+func failStaleOperations(store operationStore, now time.Time) error {
+	records, err := store.List()
+	if err != nil {
+		return fmt.Errorf("Failed to fetch operations: %w", err)
+	}
+
+	for _, record := range records {
+		if record.Status != apitypes.OperationRunning && record.Status != apitypes.OperationCancelling {
+			continue
+		}
+
+		reported := record.HeartbeatAt
+		if reported == "" {
+			reported = record.StartedAt
+		}
+
+		if !elapsed(reported, now.Add(-operationHeartbeatTimeout)) {
+			continue
+		}
+
+		message := fmt.Sprintf("Runner %q stopped reporting at %s, check whether the command completed", record.Runner, reported)
+		ok, err := store.Transition(record.ID, record.Status, apitypes.OperationFailed, "", now.UTC().Format(time.RFC3339), "", message)
+		if err != nil {
+			logger.Warnf("Failed to record the stale runner of operation %d: %v", record.ID, err)
+			continue
+		}
+
+		if ok {
+			logger.Warnf("Operation %d failed: %s", record.ID, message)
+		}
+	}
+
+	return nil
+}
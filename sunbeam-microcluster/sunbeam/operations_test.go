@@ -0,0 +1,339 @@
+package sunbeam
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// memOperationStore is an in-memory operationStore, with compare-and-swap
+// transitions like the database one
+type memOperationStore struct {
+	records []database.Operation
+}
+
+func (m *memOperationStore) List() ([]database.Operation, error) {
+	return slices.Clone(m.records), nil
+}
+
+func (m *memOperationStore) Get(id int) (database.Operation, error) {
+	for _, record := range m.records {
+		if record.ID == id {
+			return record, nil
+		}
+	}
+
+	return database.Operation{}, api.StatusErrorf(http.StatusNotFound, "Operation not found")
+}
+
+func (m *memOperationStore) Create(record database.Operation) (database.Operation, error) {
+	record.ID = len(m.records) + 1
+	m.records = append(m.records, record)
+
+	return record, nil
+}
+
+func (m *memOperationStore) Transition(id int, from string, to string, startedAt string, finishedAt string, result string, errorMessage string) (bool, error) {
+	for i, record := range m.records {
+		if record.ID != id || record.Status != from {
+			continue
+		}
+
+		m.records[i].Status = to
+		for field, value := range map[*string]string{&m.records[i].StartedAt: startedAt, &m.records[i].FinishedAt: finishedAt, &m.records[i].Result: result, &m.records[i].Error: errorMessage} {
+			if value != "" {
+				*field = value
+			}
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (m *memOperationStore) Report(id int, runner string, progress string, heartbeatAt string) error {
+	for i, record := range m.records {
+		if record.ID != id {
+			continue
+		}
+
+		m.records[i].Runner = runner
+		m.records[i].HeartbeatAt = heartbeatAt
+		if progress != "" {
+			m.records[i].Progress = progress
+		}
+	}
+
+	return nil
+}
+
+func (m *memOperationStore) get(t *testing.T, id int) database.Operation {
+	t.Helper()
+
+	record, err := m.Get(id)
+	if err != nil {
+		t.Fatalf("Expected operation %d, got %v", id, err)
+	}
+
+	return record
+}
+
+// TestValidateOperationCommand tests that only the detachable commands can
+// run as operations
+func TestValidateOperationCommand(t *testing.T) {
+	err := ValidateOperationCommand([]string{"cluster", "maintenance", "enable", "--yes", "node-1"})
+	if err != nil {
+		t.Errorf("Expected a valid command, got %v", err)
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"cluster", "maintenance"},
+		{"cluster", "bootstrap"},
+		{"--verbose", "cluster", "maintenance", "enable", "node-1"},
+		{"cluster", "maintenance", "enable", "--detach", "node-1"},
+	} {
+		err := ValidateOperationCommand(args)
+		if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+			t.Errorf("%v: expected a 400 error, got %v", args, err)
+		}
+	}
+}
+
+// TestOperationRunToCompletion tests that a created operation is claimed by
+// a single runner, which reports its progress and outcome
+func TestOperationRunToCompletion(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memOperationStore{}
+
+	args := []string{"cluster", "maintenance", "enable", "--yes", "node-1"}
+	operation, err := createOperation(store, args, "node-1", "admin", now)
+	if err != nil {
+		t.Fatalf("Expected the operation created, got %v", err)
+	}
+
+	if operation.Status != apitypes.OperationPending || operation.CreatedBy != "admin" || !slices.Equal(operation.Command, args) {
+		t.Fatalf("Expected a pending operation of admin running %v, got %+v", args, operation)
+	}
+
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Status: apitypes.OperationRunning, Runner: "laptop"}, now)
+	if err != nil {
+		t.Fatalf("Expected the operation claimed, got %v", err)
+	}
+
+	record := store.get(t, operation.ID)
+	if record.Status != apitypes.OperationRunning || record.Runner != "laptop" || record.StartedAt != "2025-07-01T02:00:00Z" || record.HeartbeatAt != record.StartedAt {
+		t.Errorf("Expected the operation run by laptop, got %+v", record)
+	}
+
+	// A claimed operation is not run twice
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Status: apitypes.OperationRunning, Runner: "agent"}, now)
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error claiming a running operation, got %v", err)
+	}
+
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Runner: "agent", Progress: "Migrating"}, now)
+	if !api.StatusErrorCheck(err, http.StatusConflict) || !strings.Contains(err.Error(), `run by "laptop"`) {
+		t.Errorf("Expected a 409 error reporting as another runner, got %v", err)
+	}
+
+	later := now.Add(time.Minute)
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Runner: "laptop", Progress: "Migrating"}, later)
+	if err != nil {
+		t.Fatalf("Expected the progress recorded, got %v", err)
+	}
+
+	record = store.get(t, operation.ID)
+	if record.Progress != "Migrating" || record.HeartbeatAt != "2025-07-01T02:01:00Z" || record.Status != apitypes.OperationRunning {
+		t.Errorf("Expected the progress and heartbeat recorded, got %+v", record)
+	}
+
+	update := apitypes.OperationUpdate{Status: apitypes.OperationSucceeded, Runner: "laptop", Result: "Migrating\nEnabled maintenance\n"}
+	err = updateOperation(store, operation.ID, update, later)
+	if err != nil {
+		t.Fatalf("Expected the operation completed, got %v", err)
+	}
+
+	record = store.get(t, operation.ID)
+	if record.Status != apitypes.OperationSucceeded || record.Result != update.Result || record.Progress != "Migrating" || record.FinishedAt != "2025-07-01T02:01:00Z" {
+		t.Errorf("Expected the operation succeeded, got %+v", record)
+	}
+
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Runner: "laptop"}, later)
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error reporting on a completed operation, got %v", err)
+	}
+}
+
+// TestOperationFailure tests that a failed command fails its operation, and
+// that invalid updates are rejected
+func TestOperationFailure(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memOperationStore{}
+
+	operation, err := createOperation(store, []string{"cluster", "maintenance", "drain", "node-1"}, "node-1", "admin", now)
+	if err != nil {
+		t.Fatalf("Expected the operation created, got %v", err)
+	}
+
+	for _, update := range []apitypes.OperationUpdate{
+		{Status: apitypes.OperationRunning},
+		{Status: apitypes.OperationCancelled, Runner: "laptop"},
+	} {
+		err := updateOperation(store, operation.ID, update, now)
+		if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+			t.Errorf("%+v: expected a 400 error, got %v", update, err)
+		}
+	}
+
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Status: apitypes.OperationFailed, Runner: "laptop"}, now)
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error failing a pending operation, got %v", err)
+	}
+
+	err = updateOperation(store, 42, apitypes.OperationUpdate{Status: apitypes.OperationRunning, Runner: "laptop"}, now)
+	if !api.StatusErrorCheck(err, http.StatusNotFound) {
+		t.Errorf("Expected a 404 error claiming an unknown operation, got %v", err)
+	}
+
+	err = updateOperation(store, operation.ID, apitypes.OperationUpdate{Status: apitypes.OperationRunning, Runner: "laptop"}, now)
+	if err != nil {
+		t.Fatalf("Expected the operation claimed, got %v", err)
+	}
+
+	update := apitypes.OperationUpdate{Status: apitypes.OperationFailed, Runner: "laptop", Result: "Error: no capacity\n", Error: "exit status 1: Error: no capacity"}
+	err = updateOperation(store, operation.ID, update, now)
+	if err != nil {
+		t.Fatalf("Expected the operation completed, got %v", err)
+	}
+
+	record := store.get(t, operation.ID)
+	if record.Status != apitypes.OperationFailed || record.Error != update.Error || record.Result != update.Result {
+		t.Errorf("Expected the operation failed, got %+v", record)
+	}
+
+	_, err = createOperation(store, []string{"cluster", "bootstrap"}, "node-1", "admin", now)
+	if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+		t.Errorf("Expected a 400 error creating an operation not detachable, got %v", err)
+	}
+}
+
+// TestCancelOperation tests that a pending operation is cancelled at once,
+// and a running one once its runner reports the command interrupted
+func TestCancelOperation(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	store := &memOperationStore{}
+	args := []string{"cluster", "maintenance", "enable", "--yes", "node-1"}
+
+	pending, err := createOperation(store, args, "node-1", "admin", now)
+	if err != nil {
+		t.Fatalf("Expected the operation created, got %v", err)
+	}
+
+	err = cancelOperation(store, pending.ID, "operator", now)
+	if err != nil {
+		t.Fatalf("Expected the operation cancelled, got %v", err)
+	}
+
+	record := store.get(t, pending.ID)
+	if record.Status != apitypes.OperationCancelled || record.Error != "Cancelled by operator" || record.FinishedAt != "2025-07-01T02:00:00Z" {
+		t.Errorf("Expected the operation cancelled, got %+v", record)
+	}
+
+	// A cancelled operation is not claimed
+	err = updateOperation(store, pending.ID, apitypes.OperationUpdate{Status: apitypes.OperationRunning, Runner: "laptop"}, now)
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error claiming a cancelled operation, got %v", err)
+	}
+
+	running, err := createOperation(store, args, "node-1", "admin", now)
+	if err != nil {
+		t.Fatalf("Expected the operation created, got %v", err)
+	}
+
+	err = updateOperation(store, running.ID, apitypes.OperationUpdate{Status: apitypes.OperationRunning, Runner: "laptop"}, now)
+	if err != nil {
+		t.Fatalf("Expected the operation claimed, got %v", err)
+	}
+
+	err = cancelOperation(store, running.ID, "operator", now)
+	if err != nil {
+		t.Fatalf("Expected the operation cancelled, got %v", err)
+	}
+
+	if store.get(t, running.ID).Status != apitypes.OperationCancelling {
+		t.Errorf("Expected the operation cancelling, got %s", store.get(t, running.ID).Status)
+	}
+
+	// Cancelling again is a no-op
+	err = cancelOperation(store, running.ID, "operator", now)
+	if err != nil {
+		t.Errorf("Expected cancelling again to succeed, got %v", err)
+	}
+
+	// The runner keeps reporting until the command is interrupted
+	err = updateOperation(store, running.ID, apitypes.OperationUpdate{Runner: "laptop", Progress: "Interrupting"}, now)
+	if err != nil {
+		t.Fatalf("Expected the progress recorded, got %v", err)
+	}
+
+	err = updateOperation(store, running.ID, apitypes.OperationUpdate{Status: apitypes.OperationFailed, Runner: "laptop", Result: "Interrupted\n", Error: "signal: interrupt"}, now)
+	if err != nil {
+		t.Fatalf("Expected the operation completed, got %v", err)
+	}
+
+	record = store.get(t, running.ID)
+	if record.Status != apitypes.OperationCancelled || record.Error != "Cancelled by operator" || record.Result != "Interrupted\n" {
+		t.Errorf("Expected the operation cancelled, got %+v", record)
+	}
+
+	err = cancelOperation(store, running.ID, "operator", now)
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		t.Errorf("Expected a 409 error cancelling a completed operation, got %v", err)
+	}
+
+	err = cancelOperation(store, 42, "operator", now)
+	if !api.StatusErrorCheck(err, http.StatusNotFound) || !strings.Contains(err.Error(), "Operation 42") {
+		t.Errorf("Expected a 404 error cancelling an unknown operation, got %v", err)
+	}
+}
+
+// TestFailStaleOperations tests that the operations whose runner stopped
+// reporting are failed, and only those
+func TestFailStaleOperations(t *testing.T) {
+	now := time.Date(2025, 7, 1, 2, 0, 0, 0, time.UTC)
+	stale := now.Add(-operationHeartbeatTimeout - time.Second).Format(time.RFC3339)
+	recent := now.Add(-time.Minute).Format(time.RFC3339)
+	store := &memOperationStore{records: []database.Operation{
+		{ID: 1, Runner: "laptop", Status: apitypes.OperationRunning, StartedAt: stale, HeartbeatAt: stale},
+		{ID: 2, Runner: "laptop", Status: apitypes.OperationCancelling, StartedAt: stale, HeartbeatAt: stale},
+		{ID: 3, Runner: "agent", Status: apitypes.OperationRunning, StartedAt: stale, HeartbeatAt: recent},
+		{ID: 4, Status: apitypes.OperationPending, CreatedAt: stale},
+		{ID: 5, Runner: "agent", Status: apitypes.OperationRunning, StartedAt: stale},
+	}}
+
+	err := failStaleOperations(store, now)
+	if err != nil {
+		t.Fatalf("Expected the operations checked, got %v", err)
+	}
+
+	want := []string{apitypes.OperationFailed, apitypes.OperationFailed, apitypes.OperationRunning, apitypes.OperationPending, apitypes.OperationFailed}
+	for i, status := range want {
+		record := store.get(t, i+1)
+		if record.Status != status {
+			t.Errorf("Expected operation %d %s, got %s", i+1, status, record.Status)
+		}
+	}
+
+	if !strings.Contains(store.get(t, 1).Error, `Runner "laptop" stopped reporting`) {
+		t.Errorf("Expected the stale runner recorded, got %q", store.get(t, 1).Error)
+	}
+}
@@ -0,0 +1,265 @@
+package sunbeam
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/canonical/microcluster/v2/cluster"
+	"github.com/canonical/microcluster/v2/microcluster"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+const (
+	// DatabaseVoterRole is the dqlite role of the members taking part in
+	// the raft quorum of the database
+	DatabaseVoterRole = "voter"
+
+	// DatabaseSpareRole is the dqlite role of the members not replicating
+	// the database
+	DatabaseSpareRole = "spare"
+
+	// quorumCheckInterval is how long the outcome of a quorum check is
+	// reused before the voters are probed again
+	quorumCheckInterval = 5 * time.Second
+
+	// quorumProbeTimeout is how long a voter has to accept a connection
+	quorumProbeTimeout = 2 * time.Second
+)
+
+// NewDatabaseMembers returns a function returning the members of the
+// database, read from the dqlite configuration and the trust store of the
+// member rather than queried from the database, so that they are known
+// without quorum. The microcluster App reading them is built once, on the
+// first call.
+func NewDatabaseMembers() func(s state.State) ([]cluster.DqliteMember, error) {
+	var mu sync.Mutex
+	var m *microcluster.MicroCluster
+
+	return func(s state.State) ([]cluster.DqliteMember, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if m == nil {
+			app, err := microcluster.App(microcluster.Args{StateDir: s.FileSystem().StateDir})
+			if err != nil {
+				return nil, err
+			}
+
+			m = app
+		}
+
+		return m.GetDqliteClusterMembers()
+	}
+}
+
+// ProbeVoter returns an error if the voter at address does not accept a
+// connection.
+func ProbeVoter(ctx context.Context, address string) error {
+	dialer := net.Dialer{Timeout: quorumProbeTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// QuorumLostError is returned while a majority of the database voters is
+// unreachable, along with the voters expected and reachable.
+type QuorumLostError struct {
+	apitypes.QuorumLost
+}
+
+// Error describes the voters unreachable.
+func (e *QuorumLostError) Error() string {
+	unreachable := []string{}
+	for _, name := range e.Expected {
+		if !slices.Contains(e.Reachable, name) {
+			unreachable = append(unreachable, name)
+		}
+	}
+
+	return fmt.Sprintf("Cluster quorum lost: %d of %d database voters reachable, unreachable: %s", len(e.Reachable), len(e.Expected), strings.Join(unreachable, ", "))
+}
+
+// QuorumChecker detects the loss of the quorum of the database by probing
+// its voters, without querying the database which blocks until the timeout
+// of the request when it has no quorum.
+type QuorumChecker struct {
+	members func(s state.State) ([]cluster.DqliteMember, error)
+	probe   func(ctx context.Context, address string) error
+	now     func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	lost      *QuorumLostError
+	// checking is closed once the check in progress completes, nil if there
+	// is none
+	checking chan struct{}
+}
+
+// NewQuorumChecker returns a QuorumChecker probing with probe the voters
+// among the database members returned by members.
+func NewQuorumChecker(members func(s state.State) ([]cluster.DqliteMember, error), probe func(ctx context.Context, address string) error) *QuorumChecker {
+	return &QuorumChecker{
+		members: members,
+		probe:   probe,
+		now:     time.Now,
+	}
+}
+
+// Check returns a QuorumLostError if half or more of the database voters
+// are unreachable, nil otherwise or if the members are not known, such as
+// before bootstrap. The outcome is reused for a few seconds so that the
+// requests do not each probe the voters. The voters are probed apart from
+// the requests, which wait for the check in progress, so that a request
+// cancelled meanwhile neither fails the check nor holds up the others. It
+// returns the error of ctx if it is done before the check completes.
+func (q *QuorumChecker) Check(ctx context.Context, s state.State) error {
+	q.mu.Lock()
+	if !q.checkedAt.IsZero() && q.now().Sub(q.checkedAt) < quorumCheckInterval {
+		defer q.mu.Unlock()
+		return q.result()
+	}
+
+	checking := q.checking
+	if checking == nil {
+		checking = make(chan struct{})
+		q.checking = checking
+		go q.refresh(logging.FromContext(ctx), s, checking)
+	}
+
+	q.mu.Unlock()
+
+	select {
+	case <-checking:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.result()
+}
+
+// refresh records the outcome of a new check, then closes checking
+func (q *QuorumChecker) refresh(l *slog.Logger, s state.State, checking chan struct{}) {
+	lost := q.check(l, s)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.lost = lost
+	q.checkedAt = q.now()
+	q.checking = nil
+	close(checking)
+}
+
+// result returns the outcome of the last check, avoiding a typed nil error
+func (q *QuorumChecker) result() error {
+	if q.lost == nil {
+		return nil
+	}
+
+	return q.lost
+}
+
+// check probes the voters concurrently and returns which are reachable if
+// they are not a majority
+func (q *QuorumChecker) check(l *slog.Logger, s state.State) *QuorumLostError {
+	members, err := q.members(s)
+	if err != nil {
+		l.Debug("Failed to read the database members, skipping quorum check", "error", err)
+		return nil
+	}
+
+	voters := []cluster.DqliteMember{}
+	for _, member := range members {
+		if member.Role == DatabaseVoterRole {
+			voters = append(voters, member)
+		}
+	}
+
+	if len(voters) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), quorumProbeTimeout)
+	defer cancel()
+
+	reachable := make([]bool, len(voters))
+	var wg sync.WaitGroup
+	for i, voter := range voters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := q.probe(ctx, voter.Address)
+			if err != nil {
+				l.Debug("Database voter unreachable", "voter", voter.Name, "address", voter.Address, "error", err)
+				return
+			}
+
+			reachable[i] = true
+		}()
+	}
+
+	wg.Wait()
+
+	lost := &QuorumLostError{QuorumLost: apitypes.QuorumLost{
+		Code:      apitypes.QuorumLostCode,
+		Expected:  []string{},
+		Reachable: []string{},
+	}}
+	for i, voter := range voters {
+		lost.Expected = append(lost.Expected, voter.Name)
+		if reachable[i] {
+			lost.Reachable = append(lost.Reachable, voter.Name)
+		}
+	}
+
+	if len(lost.Reachable)*2 > len(lost.Expected) {
+		return nil
+	}
+
+	slices.Sort(lost.Expected)
+	slices.Sort(lost.Reachable)
+	l.Warn("Database quorum lost", "expected", lost.Expected, "reachable", lost.Reachable)
+
+	return lost
+}
+
+// ForceQuorumMembers returns the database members reconfigured so that the
+// surviving members, the local one and the given voters, are the only
+// voters. The other members become spares, so that the survivors alone
+// form a quorum. Members cannot be added or removed by a recovery, the
+// spares are to be removed from the cluster once it is recovered.
+func ForceQuorumMembers(members []cluster.DqliteMember, local string, voters []string) ([]cluster.DqliteMember, error) {
+	survivors := append([]string{local}, voters...)
+	for _, name := range survivors {
+		if !slices.ContainsFunc(members, func(m cluster.DqliteMember) bool { return m.Name == name }) {
+			return nil, fmt.Errorf("Unknown database member %q", name)
+		}
+	}
+
+	reconfigured := make([]cluster.DqliteMember, 0, len(members))
+	for _, member := range members {
+		member.Role = DatabaseSpareRole
+		if slices.Contains(survivors, member.Name) {
+			member.Role = DatabaseVoterRole
+		}
+
+		reconfigured = append(reconfigured, member)
+	}
+
+	return reconfigured, nil
+}
@@ -0,0 +1,202 @@
+package sunbeam
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/canonical/microcluster/v2/cluster"
+	"github.com/canonical/microcluster/v2/state"
+)
+
+// testDatabaseMembers are three voters and a spare
+var testDatabaseMembers = []cluster.DqliteMember{
+	{DqliteID: 1, Address: "10.0.0.1:7000", Role: DatabaseVoterRole, Name: "node-1"},
+	{DqliteID: 2, Address: "10.0.0.2:7000", Role: DatabaseVoterRole, Name: "node-2"},
+	{DqliteID: 3, Address: "10.0.0.3:7000", Role: DatabaseVoterRole, Name: "node-3"},
+	{DqliteID: 4, Address: "10.0.0.4:7000", Role: DatabaseSpareRole, Name: "node-4"},
+}
+
+// newTestQuorumChecker returns a QuorumChecker of the test members, on a
+// fake clock, failing the probes of the addresses in down
+func newTestQuorumChecker(down map[string]bool) (*QuorumChecker, *fakeClock, *atomic.Int32) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	probes := &atomic.Int32{}
+	checker := NewQuorumChecker(
+		func(state.State) ([]cluster.DqliteMember, error) {
+			return testDatabaseMembers, nil
+		},
+		func(_ context.Context, address string) error {
+			probes.Add(1)
+			if down[address] {
+				return errors.New("connection refused")
+			}
+			return nil
+		},
+	)
+	checker.now = clock.Now
+
+	return checker, clock, probes
+}
+
+// TestQuorumCheckerDownedVoter tests that a single unreachable voter out of
+// three keeps the quorum
+func TestQuorumCheckerDownedVoter(t *testing.T) {
+	checker, _, _ := newTestQuorumChecker(map[string]bool{"10.0.0.3:7000": true, "10.0.0.4:7000": true})
+
+	err := checker.Check(context.Background(), nil)
+	if err != nil {
+		t.Errorf("Expected the quorum to be kept, got %v", err)
+	}
+}
+
+// TestQuorumCheckerLost tests that two unreachable voters out of three lose
+// the quorum, and that the error lists the expected and reachable voters
+func TestQuorumCheckerLost(t *testing.T) {
+	checker, _, _ := newTestQuorumChecker(map[string]bool{"10.0.0.2:7000": true, "10.0.0.3:7000": true})
+
+	err := checker.Check(context.Background(), nil)
+	var lost *QuorumLostError
+	if !errors.As(err, &lost) {
+		t.Fatalf("Expected a QuorumLostError, got %v", err)
+	}
+
+	if lost.Code != "cluster-quorum-lost" {
+		t.Errorf("Unexpected code %q", lost.Code)
+	}
+
+	if !slices.Equal(lost.Expected, []string{"node-1", "node-2", "node-3"}) {
+		t.Errorf("Expected the voters only, got %v", lost.Expected)
+	}
+
+	if !slices.Equal(lost.Reachable, []string{"node-1"}) {
+		t.Errorf("Unexpected reachable voters %v", lost.Reachable)
+	}
+
+	expected := "Cluster quorum lost: 1 of 3 database voters reachable, unreachable: node-2, node-3"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+// TestQuorumCheckerInterval tests that the voters are not probed again until
+// the outcome of the last check expires
+func TestQuorumCheckerInterval(t *testing.T) {
+	checker, clock, probes := newTestQuorumChecker(map[string]bool{"10.0.0.2:7000": true, "10.0.0.3:7000": true})
+
+	for range 3 {
+		if checker.Check(context.Background(), nil) == nil {
+			t.Fatalf("Expected the quorum to be lost")
+		}
+	}
+
+	if probes.Load() != 3 {
+		t.Errorf("Expected the 3 voters to be probed once, got %d probes", probes.Load())
+	}
+
+	clock.now = clock.now.Add(quorumCheckInterval)
+	checker.Check(context.Background(), nil)
+
+	if probes.Load() != 6 {
+		t.Errorf("Expected the voters to be probed again, got %d probes", probes.Load())
+	}
+}
+
+// TestQuorumCheckerUnknownMembers tests that the check passes when the
+// database members cannot be read, such as before bootstrap
+func TestQuorumCheckerUnknownMembers(t *testing.T) {
+	checker := NewQuorumChecker(
+		func(state.State) ([]cluster.DqliteMember, error) {
+			return nil, errors.New("no such file or directory")
+		},
+		func(context.Context, string) error {
+			t.Errorf("Expected no probe")
+			return nil
+		},
+	)
+
+	err := checker.Check(context.Background(), nil)
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+// TestForceQuorumMembers tests that the survivors become the only voters and
+// the other members spares
+func TestForceQuorumMembers(t *testing.T) {
+	members, err := ForceQuorumMembers(testDatabaseMembers, "node-1", []string{"node-4"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	roles := map[string]string{}
+	for _, member := range members {
+		roles[member.Name] = member.Role
+	}
+
+	expected := map[string]string{
+		"node-1": DatabaseVoterRole,
+		"node-2": DatabaseSpareRole,
+		"node-3": DatabaseSpareRole,
+		"node-4": DatabaseVoterRole,
+	}
+	for name, role := range expected {
+		if roles[name] != role {
+			t.Errorf("Expected %s to be a %s, got %q", name, role, roles[name])
+		}
+	}
+
+	if testDatabaseMembers[1].Role != DatabaseVoterRole {
+		t.Errorf("Expected the members not to be modified")
+	}
+}
+
+// TestForceQuorumMembersUnknown tests that the survivors must be members
+func TestForceQuorumMembersUnknown(t *testing.T) {
+	_, err := ForceQuorumMembers(testDatabaseMembers, "node-5", nil)
+	if err == nil || err.Error() != `Unknown database member "node-5"` {
+		t.Errorf("Expected an unknown member error, got %v", err)
+	}
+}
+
+// TestQuorumCheckerCancelledRequest tests that the voters are probed apart
+// from the request, so that the cancellation of the request is not taken
+// for unreachable voters
+func TestQuorumCheckerCancelledRequest(t *testing.T) {
+	release := make(chan struct{})
+	checker := NewQuorumChecker(
+		func(state.State) ([]cluster.DqliteMember, error) {
+			return testDatabaseMembers, nil
+		},
+		func(ctx context.Context, _ string) error {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Errorf("Expected the probe to time out")
+			}
+
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+
+			return ctx.Err()
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := checker.Check(ctx, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation of the request, got %v", err)
+	}
+
+	close(release)
+
+	err = checker.Check(context.Background(), nil)
+	if err != nil {
+		t.Errorf("Expected the quorum to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/logging"
+)
+
+const (
+	// RateLimitClientReadsConfigKey is the config key holding how many read
+	// requests per second a client can send to the API
+	RateLimitClientReadsConfigKey = "ratelimit.client-reads-per-second"
+
+	// RateLimitClientWritesConfigKey is the config key holding how many
+	// mutating requests per second a client can send to the API
+	RateLimitClientWritesConfigKey = "ratelimit.client-writes-per-second"
+
+	// RateLimitGlobalReadsConfigKey is the config key holding how many read
+	// requests per second the API serves across all the clients
+	RateLimitGlobalReadsConfigKey = "ratelimit.global-reads-per-second"
+
+	// RateLimitGlobalWritesConfigKey is the config key holding how many
+	// mutating requests per second the API serves across all the clients
+	RateLimitGlobalWritesConfigKey = "ratelimit.global-writes-per-second"
+
+	// rateLimitsRefreshInterval is how often the limits are read from the
+	// cluster config
+	rateLimitsRefreshInterval = 30 * time.Second
+
+	// rateLimitIdleTimeout is how long the state of an idle client is kept
+	rateLimitIdleTimeout = 10 * time.Minute
+)
+
+// RateLimits are the requests per second served by the API, a burst of up to
+// one second of requests is allowed. A zero limit does not limit requests.
+type RateLimits struct {
+	ClientReads  int
+	ClientWrites int
+	GlobalReads  int
+	GlobalWrites int
+}
+
+// DefaultRateLimits are the limits applied when they are not set in the
+// cluster config, mutating requests are limited more strictly than reads.
+var DefaultRateLimits = RateLimits{
+	ClientReads:  50,
+	ClientWrites: 10,
+	GlobalReads:  500,
+	GlobalWrites: 50,
+}
+
+// rateLimits returns the limits set in the cluster config, the default limit
+// of the keys which are not set or invalid
+func rateLimits(ctx context.Context, tx *sql.Tx) (RateLimits, error) {
+	limits := DefaultRateLimits
+	for key, limit := range map[string]*int{
+		RateLimitClientReadsConfigKey:  &limits.ClientReads,
+		RateLimitClientWritesConfigKey: &limits.ClientWrites,
+		RateLimitGlobalReadsConfigKey:  &limits.GlobalReads,
+		RateLimitGlobalWritesConfigKey: &limits.GlobalWrites,
+	} {
+		record, err := database.GetConfigItem(ctx, tx, key)
+		if err != nil {
+			if api.StatusErrorCheck(err, http.StatusNotFound) {
+				continue
+			}
+			return RateLimits{}, err
+		}
+
+		value, err := strconv.Atoi(configScalar(record.Value))
+		if err != nil || value < 0 {
+			logging.FromContext(ctx).Warn("Invalid rate limit, using the default", "key", key, "value", record.Value, "default", *limit)
+			continue
+		}
+
+		*limit = value
+	}
+
+	return limits, nil
+}
+
+// LoadRateLimits returns the rate limits of the API set in the cluster
+// config.
+func LoadRateLimits(ctx context.Context, s state.State) (RateLimits, error) {
+	var limits RateLimits
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		limits, err = rateLimits(ctx, tx)
+		return err
+	})
+
+	return limits, err
+}
+
+// tokenBucket holds the requests a client can send, refilled at the rate
+// limit up to one second of requests
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens accumulated at rate since the last refill
+func (b *tokenBucket) refill(rate int, now time.Time) {
+	burst := float64(rate)
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*float64(rate))
+	}
+
+	b.last = now
+}
+
+// wait returns how long until a token is available at rate, zero if one is
+func (b *tokenBucket) wait(rate int) time.Duration {
+	if rate == 0 || b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / float64(rate) * float64(time.Second))
+}
+
+// take removes a token, if the bucket is limited
+func (b *tokenBucket) take(rate int) {
+	if rate != 0 {
+		b.tokens--
+	}
+}
+
+// clientBuckets are the buckets of the read and mutating requests of a client
+type clientBuckets struct {
+	reads  tokenBucket
+	writes tokenBucket
+	seen   time.Time
+}
+
+// RateLimiter limits the requests per second of each client and across all
+// the clients, with separate limits for reads and mutating requests so that
+// a flood of reads does not prevent writes.
+type RateLimiter struct {
+	load func(ctx context.Context, s state.State) (RateLimits, error)
+	now  func() time.Time
+
+	mu       sync.Mutex
+	limits   RateLimits
+	loadedAt time.Time
+	reads    tokenBucket
+	writes   tokenBucket
+	clients  map[string]*clientBuckets
+}
+
+// NewRateLimiter returns a RateLimiter applying the limits returned by load,
+// which are reloaded periodically. The default limits apply until they are
+// loaded.
+func NewRateLimiter(load func(ctx context.Context, s state.State) (RateLimits, error)) *RateLimiter {
+	return &RateLimiter{
+		load:    load,
+		now:     time.Now,
+		limits:  DefaultRateLimits,
+		clients: map[string]*clientBuckets{},
+	}
+}
+
+// Allow returns whether client can send a request now, a mutating one if
+// write, and otherwise how long until it can. An allowed request counts
+// towards the limits.
+func (l *RateLimiter) Allow(ctx context.Context, s state.State, client string, write bool) (bool, time.Duration) {
+	l.refresh(ctx, s)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	buckets, ok := l.clients[client]
+	if !ok {
+		buckets = &clientBuckets{}
+		l.clients[client] = buckets
+	}
+
+	buckets.seen = now
+
+	global, clientBucket := &l.reads, &buckets.reads
+	globalRate, clientRate := l.limits.GlobalReads, l.limits.ClientReads
+	if write {
+		global, clientBucket = &l.writes, &buckets.writes
+		globalRate, clientRate = l.limits.GlobalWrites, l.limits.ClientWrites
+	}
+
+	global.refill(globalRate, now)
+	clientBucket.refill(clientRate, now)
+
+	wait := max(global.wait(globalRate), clientBucket.wait(clientRate))
+	if wait > 0 {
+		return false, wait
+	}
+
+	global.take(globalRate)
+	clientBucket.take(clientRate)
+
+	return true, 0
+}
+
+// refresh reloads the limits once they are older than the refresh interval,
+// and forgets the idle clients. A single caller reloads them, the others
+// keep applying the current limits meanwhile.
+func (l *RateLimiter) refresh(ctx context.Context, s state.State) {
+	l.mu.Lock()
+	now := l.now()
+	if now.Sub(l.loadedAt) < rateLimitsRefreshInterval {
+		l.mu.Unlock()
+		return
+	}
+
+	l.loadedAt = now
+	for client, buckets := range l.clients {
+		if now.Sub(buckets.seen) > rateLimitIdleTimeout {
+			delete(l.clients, client)
+		}
+	}
+
+	l.mu.Unlock()
+
+	limits, err := l.load(ctx, s)
+	if err != nil {
+		logging.FromContext(ctx).Warn("Failed to load rate limits, keeping the current ones", "error", err)
+		return
+	}
+
+	l.mu.Lock()
+	l.limits = limits
+	l.mu.Unlock()
+}
@@ -0,0 +1,137 @@
+package sunbeam
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/canonical/microcluster/v2/state"
+)
+
+// fakeClock is a settable clock
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// newTestRateLimiter returns a RateLimiter applying limits, on a fake clock
+func newTestRateLimiter(limits RateLimits) (*RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	limiter := NewRateLimiter(func(context.Context, state.State) (RateLimits, error) {
+		return limits, nil
+	})
+	limiter.now = clock.Now
+
+	return limiter, clock
+}
+
+// allowed returns how many of n requests of client are allowed at once
+func allowed(limiter *RateLimiter, client string, write bool, n int) int {
+	count := 0
+	for range n {
+		ok, _ := limiter.Allow(context.Background(), nil, client, write)
+		if ok {
+			count++
+		}
+	}
+
+	return count
+}
+
+// TestRateLimiterBurst tests that a burst is allowed up to the client limit,
+// and that the client can send requests again once the bucket refills
+func TestRateLimiterBurst(t *testing.T) {
+	limiter, clock := newTestRateLimiter(RateLimits{ClientReads: 5, ClientWrites: 2, GlobalReads: 100, GlobalWrites: 100})
+
+	if got := allowed(limiter, "client-1", false, 10); got != 5 {
+		t.Errorf("Expected 5 reads allowed, got %d", got)
+	}
+
+	ok, wait := limiter.Allow(context.Background(), nil, "client-1", false)
+	if ok || wait != 200*time.Millisecond {
+		t.Errorf("Expected a read to be allowed in 200ms, got %v %s", ok, wait)
+	}
+
+	if got := allowed(limiter, "client-2", false, 10); got != 5 {
+		t.Errorf("Expected another client to have its own limit, got %d", got)
+	}
+
+	clock.now = clock.now.Add(400 * time.Millisecond)
+	if got := allowed(limiter, "client-1", false, 10); got != 2 {
+		t.Errorf("Expected 2 reads allowed after 400ms, got %d", got)
+	}
+}
+
+// TestRateLimiterReadsDoNotBlockWrites tests that a flood of reads leaves
+// the writes below their own limit allowed
+func TestRateLimiterReadsDoNotBlockWrites(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimits{ClientReads: 5, ClientWrites: 2, GlobalReads: 8, GlobalWrites: 10})
+
+	allowed(limiter, "client-1", false, 100)
+	allowed(limiter, "client-2", false, 100)
+
+	if got := allowed(limiter, "client-1", true, 10); got != 2 {
+		t.Errorf("Expected 2 writes allowed after the reads flood, got %d", got)
+	}
+}
+
+// TestRateLimiterGlobal tests the limit across all the clients
+func TestRateLimiterGlobal(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimits{ClientReads: 5, ClientWrites: 2, GlobalReads: 8, GlobalWrites: 10})
+
+	total := allowed(limiter, "client-1", false, 10) + allowed(limiter, "client-2", false, 10)
+	if total != 8 {
+		t.Errorf("Expected 8 reads allowed across the clients, got %d", total)
+	}
+}
+
+// TestRateLimiterUnlimited tests that a zero limit does not limit requests
+func TestRateLimiterUnlimited(t *testing.T) {
+	limiter, _ := newTestRateLimiter(RateLimits{})
+
+	if got := allowed(limiter, "client-1", true, 1000); got != 1000 {
+		t.Errorf("Expected all the writes allowed, got %d", got)
+	}
+}
+
+// TestRateLimiterRefresh tests that the limits are reloaded periodically,
+// the current ones being kept when they fail to load, and that idle clients
+// are forgotten
+func TestRateLimiterRefresh(t *testing.T) {
+	limits := RateLimits{ClientReads: 1, GlobalReads: 100}
+	var loadErr error
+	limiter, clock := newTestRateLimiter(limits)
+	limiter.load = func(context.Context, state.State) (RateLimits, error) {
+		return limits, loadErr
+	}
+
+	if got := allowed(limiter, "client-1", false, 5); got != 1 {
+		t.Fatalf("Expected 1 read allowed, got %d", got)
+	}
+
+	limits.ClientReads = 3
+	clock.now = clock.now.Add(time.Second)
+	if got := allowed(limiter, "client-1", false, 5); got != 1 {
+		t.Errorf("Expected the limits to be kept until refreshed, got %d reads", got)
+	}
+
+	clock.now = clock.now.Add(rateLimitsRefreshInterval)
+	if got := allowed(limiter, "client-1", false, 5); got != 3 {
+		t.Errorf("Expected the new limit of 3 reads, got %d", got)
+	}
+
+	loadErr = errors.New("database unavailable")
+	clock.now = clock.now.Add(rateLimitIdleTimeout + time.Second)
+	allowed(limiter, "client-2", false, 1)
+	if limiter.limits.ClientReads != 3 {
+		t.Errorf("Expected the limits kept on load failure, got %+v", limiter.limits)
+	}
+
+	if _, ok := limiter.clients["client-1"]; ok {
+		t.Error("Expected the idle client to be forgotten")
+	}
+}
@@ -0,0 +1,216 @@
+package sunbeam
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// ReadOnlyTokensConfigKey is the config key holding the read-only tokens, a
+// json list of token names and hashes
+const ReadOnlyTokensConfigKey = "auth.read-only-tokens"
+
+// readOnlyTokenNamePattern matches the valid read-only token names
+var readOnlyTokenNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
+// ParseReadOnlyTokens decodes a read-only tokens config value, returning a
+// 400 StatusError if it is not a list of valid tokens with distinct names. An
+// empty value holds no tokens.
+func ParseReadOnlyTokens(value string) (apitypes.ReadOnlyTokens, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var tokens apitypes.ReadOnlyTokens
+	err := json.Unmarshal([]byte(value), &tokens)
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid read-only tokens: must be a list of tokens")
+	}
+
+	for i, token := range tokens {
+		err := validateReadOnlyTokenName(token.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		hash, err := hex.DecodeString(token.Hash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid read-only token %q: hash must be a hex SHA-256", token.Name)
+		}
+
+		if slices.ContainsFunc(tokens[:i], func(other apitypes.ReadOnlyToken) bool { return other.Name == token.Name }) {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid read-only token %q: duplicated", token.Name)
+		}
+	}
+
+	return tokens, nil
+}
+
+// validateReadOnlyTokenName returns a 400 StatusError if name is not a valid
+// token name
+func validateReadOnlyTokenName(name string) error {
+	if !readOnlyTokenNamePattern.MatchString(name) {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid read-only token name %q: must be lowercase alphanumeric, dashes or underscores, starting with a letter", name)
+	}
+
+	return nil
+}
+
+// readOnlyTokens returns the read-only tokens defined in the cluster config
+func readOnlyTokens(ctx context.Context, tx *sql.Tx) (apitypes.ReadOnlyTokens, error) {
+	record, err := database.GetConfigItem(ctx, tx, ReadOnlyTokensConfigKey)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failed to fetch read-only tokens: %w", err)
+	}
+
+	return ParseReadOnlyTokens(record.Value)
+}
+
+// loadReadOnlyTokens returns the read-only tokens defined in the cluster
+// config
+func loadReadOnlyTokens(ctx context.Context, s state.State) (apitypes.ReadOnlyTokens, error) {
+	var tokens apitypes.ReadOnlyTokens
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		tokens, err = readOnlyTokens(ctx, tx)
+		return err
+	})
+
+	return tokens, err
+}
+
+// ListReadOnlyTokens returns the read-only tokens, with their hashes left out
+func ListReadOnlyTokens(ctx context.Context, s state.State) (apitypes.ReadOnlyTokens, error) {
+	tokens, err := loadReadOnlyTokens(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make(apitypes.ReadOnlyTokens, 0, len(tokens))
+	for _, token := range tokens {
+		token.Hash = ""
+		redacted = append(redacted, token)
+	}
+
+	return redacted, nil
+}
+
+// CreateReadOnlyToken creates a read-only token named name, returning the
+// token, only its hash is stored. It returns a 409 StatusError if a token
+// with the same name exists.
+func CreateReadOnlyToken(ctx context.Context, s state.State, name string, actor string) (string, error) {
+	err := validateReadOnlyTokenName(name)
+	if err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate read-only token: %w", err)
+	}
+
+	token := hex.EncodeToString(secret)
+	err = updateReadOnlyTokens(ctx, s, actor, func(tokens apitypes.ReadOnlyTokens) (apitypes.ReadOnlyTokens, error) {
+		if slices.ContainsFunc(tokens, func(other apitypes.ReadOnlyToken) bool { return other.Name == name }) {
+			return nil, api.StatusErrorf(http.StatusConflict, "Read-only token %q already exists", name)
+		}
+
+		return append(tokens, apitypes.ReadOnlyToken{
+			Name:      name,
+			CreatedAt: time.Now().UTC().Format(time.RFC3339),
+			Hash:      hashReadOnlyToken(token),
+		}), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// DeleteReadOnlyToken revokes a read-only token, returning a 404 StatusError
+// if it does not exist
+func DeleteReadOnlyToken(ctx context.Context, s state.State, name string, actor string) error {
+	return updateReadOnlyTokens(ctx, s, actor, func(tokens apitypes.ReadOnlyTokens) (apitypes.ReadOnlyTokens, error) {
+		i := slices.IndexFunc(tokens, func(token apitypes.ReadOnlyToken) bool { return token.Name == name })
+		if i == -1 {
+			return nil, api.StatusErrorf(http.StatusNotFound, "Read-only token %q not found", name)
+		}
+
+		return slices.Delete(tokens, i, i+1), nil
+	})
+}
+
+// VerifyReadOnlyToken returns whether token is one of the read-only tokens
+// defined in the cluster config
+func VerifyReadOnlyToken(ctx context.Context, s state.State, token string) (bool, error) {
+	tokens, err := loadReadOnlyTokens(ctx, s)
+	if err != nil {
+		return false, err
+	}
+
+	return matchReadOnlyToken(tokens, token), nil
+}
+
+// matchReadOnlyToken returns whether the hash of token is the one of tokens,
+// comparing in constant time
+func matchReadOnlyToken(tokens apitypes.ReadOnlyTokens, token string) bool {
+	hash := []byte(hashReadOnlyToken(token))
+	matched := false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), hash) == 1 {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// hashReadOnlyToken returns the hex SHA-256 of token
+func hashReadOnlyToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// updateReadOnlyTokens replaces the read-only tokens defined in the cluster
+// config with the ones returned by update, in a single transaction
+func updateReadOnlyTokens(ctx context.Context, s state.State, actor string, update func(apitypes.ReadOnlyTokens) (apitypes.ReadOnlyTokens, error)) error {
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		tokens, err := readOnlyTokens(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = update(tokens)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(tokens)
+		if err != nil {
+			return err
+		}
+
+		_, err = putConfigItem(ctx, tx, ReadOnlyTokensConfigKey, string(value), "", actor)
+		return err
+	})
+}
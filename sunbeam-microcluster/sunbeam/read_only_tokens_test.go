@@ -0,0 +1,75 @@
+package sunbeam
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// TestParseReadOnlyTokens tests that read-only tokens must have distinct valid
+// names and a SHA-256 hash
+func TestParseReadOnlyTokens(t *testing.T) {
+	hash := hashReadOnlyToken("observer")
+	testCases := []struct {
+		name  string
+		value string
+		count int
+		valid bool
+	}{
+		{name: "empty value", value: "", count: 0, valid: true},
+		{name: "empty list", value: `[]`, count: 0, valid: true},
+		{name: "tokens", value: `[{"name": "grafana", "hash": "` + hash + `"}, {"name": "nagios", "hash": "` + hash + `"}]`, count: 2, valid: true},
+		{name: "not a list", value: `{"name": "grafana"}`, valid: false},
+		{name: "invalid name", value: `[{"name": "Grafana", "hash": "` + hash + `"}]`, valid: false},
+		{name: "no hash", value: `[{"name": "grafana"}]`, valid: false},
+		{name: "short hash", value: `[{"name": "grafana", "hash": "abcd"}]`, valid: false},
+		{name: "duplicated name", value: `[{"name": "grafana", "hash": "` + hash + `"}, {"name": "grafana", "hash": "` + hash + `"}]`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := ParseReadOnlyTokens(tc.value)
+			if !tc.valid {
+				if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+					t.Errorf("Expected bad request error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(tokens) != tc.count {
+				t.Errorf("Expected %d tokens, got %d", tc.count, len(tokens))
+			}
+		})
+	}
+}
+
+// TestMatchReadOnlyToken tests that a token matches only the stored hash of
+// the same token
+func TestMatchReadOnlyToken(t *testing.T) {
+	tokens := apitypes.ReadOnlyTokens{
+		{Name: "grafana", Hash: hashReadOnlyToken("observer")},
+	}
+
+	if !matchReadOnlyToken(tokens, "observer") {
+		t.Error("Expected token to match")
+	}
+
+	if matchReadOnlyToken(tokens, "intruder") {
+		t.Error("Expected unknown token not to match")
+	}
+
+	if matchReadOnlyToken(tokens, "") {
+		t.Error("Expected empty token not to match")
+	}
+
+	if matchReadOnlyToken(nil, "observer") {
+		t.Error("Expected no token to match without tokens")
+	}
+}
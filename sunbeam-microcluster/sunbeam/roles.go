@@ -0,0 +1,165 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// CustomRolesConfigKey is the config key holding the custom node roles, a
+// json list of role names
+const CustomRolesConfigKey = "node.custom-roles"
+
+// roleNamePattern matches the valid custom role names. Quotes are excluded,
+// role filters match the quoted role in the json list of node roles.
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
+// ParseCustomRoles decodes a custom roles config value, returning a 400
+// StatusError if it is not a list of new valid role names. An empty value
+// holds no roles.
+func ParseCustomRoles(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var roles []string
+	err := json.Unmarshal([]byte(value), &roles)
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid custom node roles: must be a list of role names")
+	}
+
+	for i, role := range roles {
+		if !roleNamePattern.MatchString(role) {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid custom node role %q: must be lowercase alphanumeric, dashes or underscores, starting with a letter", role)
+		}
+
+		if slices.Contains(apitypes.NodeRoles, role) {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid custom node role %q: built-in role", role)
+		}
+
+		if slices.Contains(roles[:i], role) {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid custom node role %q: duplicated", role)
+		}
+	}
+
+	return roles, nil
+}
+
+// customRoles returns the custom roles defined in the cluster config
+func customRoles(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	record, err := database.GetConfigItem(ctx, tx, CustomRolesConfigKey)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failed to fetch custom node roles: %w", err)
+	}
+
+	return ParseCustomRoles(record.Value)
+}
+
+// ListNodeRoles returns the built-in node roles followed by the custom ones
+func ListNodeRoles(ctx context.Context, s state.State) ([]apitypes.NodeRole, error) {
+	var custom []string
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		custom, err = customRoles(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make([]apitypes.NodeRole, 0, len(apitypes.NodeRoles)+len(custom))
+	for _, role := range apitypes.NodeRoles {
+		roles = append(roles, apitypes.NodeRole{Name: role})
+	}
+	for _, role := range custom {
+		roles = append(roles, apitypes.NodeRole{Name: role, Custom: true})
+	}
+
+	return roles, nil
+}
+
+// validateNodeRoles checks that roles are built-in or custom node roles
+func validateNodeRoles(ctx context.Context, tx *sql.Tx, roles []string) error {
+	if len(roles) == 0 {
+		return nil
+	}
+
+	custom, err := customRoles(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return ValidateRoles(roles, custom)
+}
+
+// checkCustomRolesUpdate validates a new custom roles config value, and
+// rejects the removal of roles still assigned to nodes. It runs in the
+// transaction updating the value, so that no node is assigned a removed
+// role meanwhile. An empty value removes all the custom roles.
+func checkCustomRolesUpdate(ctx context.Context, tx *sql.Tx, value string) error {
+	roles, err := ParseCustomRoles(value)
+	if err != nil {
+		return err
+	}
+
+	current, err := customRoles(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, role := range current {
+		if !slices.Contains(roles, role) {
+			removed = append(removed, role)
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	nodes, err := database.GetNodesFromRoles(ctx, tx, removed)
+	if err != nil {
+		return err
+	}
+
+	return checkRemovedRoles(removed, nodes)
+}
+
+// checkRemovedRoles returns a 400 StatusError if any of the removed roles is
+// assigned to one of nodes
+func checkRemovedRoles(removed []string, nodes []database.Node) error {
+	for _, role := range removed {
+		var names []string
+		for _, node := range nodes {
+			nodeRole, err := roleFromStr(node.Role)
+			if err != nil {
+				return err
+			}
+
+			if slices.Contains(nodeRole, role) {
+				names = append(names, node.Name)
+			}
+		}
+
+		if len(names) > 0 {
+			return api.StatusErrorf(http.StatusBadRequest, "Custom node role %q is in use by nodes %s", role, strings.Join(names, ", "))
+		}
+	}
+
+	return nil
+}
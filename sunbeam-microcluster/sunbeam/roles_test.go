@@ -0,0 +1,75 @@
+package sunbeam
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// TestParseCustomRoles tests that custom roles must be new valid role names
+func TestParseCustomRoles(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		roles []string
+		valid bool
+	}{
+		{name: "empty value", value: "", roles: nil, valid: true},
+		{name: "empty list", value: `[]`, roles: []string{}, valid: true},
+		{name: "roles", value: `["load-balancer", "gpu_compute"]`, roles: []string{"load-balancer", "gpu_compute"}, valid: true},
+		{name: "not a list", value: `"load-balancer"`, valid: false},
+		{name: "uppercase", value: `["LoadBalancer"]`, valid: false},
+		{name: "quote", value: `["lb\""]`, valid: false},
+		{name: "leading digit", value: `["1lb"]`, valid: false},
+		{name: "built-in role", value: `["compute"]`, valid: false},
+		{name: "duplicated role", value: `["lb", "lb"]`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			roles, err := ParseCustomRoles(tc.value)
+			if !tc.valid {
+				if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+					t.Errorf("Expected bad request error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected roles to be valid, got %v", err)
+			}
+
+			if !slices.Equal(roles, tc.roles) {
+				t.Errorf("Expected roles %v, got %v", tc.roles, roles)
+			}
+		})
+	}
+}
+
+// TestCheckRemovedRoles tests that roles still assigned to nodes cannot be
+// removed
+func TestCheckRemovedRoles(t *testing.T) {
+	nodes := []database.Node{
+		{Name: "node-1", Role: `["compute","load-balancer"]`},
+		{Name: "node-2", Role: `["load-balancer"]`},
+	}
+
+	err := checkRemovedRoles([]string{"gateway"}, nodes)
+	if err != nil {
+		t.Errorf("Expected unused role to be removable, got %v", err)
+	}
+
+	err = checkRemovedRoles([]string{"gateway", "load-balancer"}, nodes)
+	if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+		t.Fatalf("Expected bad request error, got %v", err)
+	}
+
+	expected := `Custom node role "load-balancer" is in use by nodes node-1, node-2`
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err.Error())
+	}
+}
@@ -0,0 +1,132 @@
+package sunbeam
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/canonical/lxd/shared/logger"
+)
+
+const (
+	// DefaultShutdownGracePeriod is how long the in-flight requests are
+	// given to complete on shutdown by default
+	DefaultShutdownGracePeriod = 30 * time.Second
+
+	// drainCancelTimeout is how long the requests still in flight after the
+	// grace period are given to return once their context is cancelled
+	drainCancelTimeout = 5 * time.Second
+)
+
+// Drainer tracks the requests being handled, so that the daemon lets them
+// complete before stopping the database they run their queries against.
+type Drainer struct {
+	mu       sync.Mutex
+	draining bool
+	inflight sync.WaitGroup
+
+	// ctx is the parent of the contexts of the requests, cancelled once
+	// the grace period elapsed
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	cancelTimeout time.Duration
+}
+
+// NewDrainer returns a Drainer accepting requests until it is drained.
+func NewDrainer() *Drainer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Drainer{ctx: ctx, cancel: cancel, cancelTimeout: drainCancelTimeout}
+}
+
+// Begin registers a request, returning the context its handler runs with
+// and the function to call once it is handled. The context is cancelled
+// with ctx, or once the grace period of the drain elapsed. Returns false if
+// the Drainer is draining, the request must then be refused.
+func (d *Drainer) Begin(ctx context.Context) (context.Context, func(), bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return ctx, func() {}, false
+	}
+
+	d.inflight.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(d.ctx, cancel)
+
+	var once sync.Once
+	done := func() {
+		once.Do(func() {
+			stop()
+			cancel()
+			d.inflight.Done()
+		})
+	}
+
+	return ctx, done, true
+}
+
+// Drain refuses the new requests and waits for the ones in flight to
+// complete. The contexts of those still in flight after grace are
+// cancelled, and they are given a few more seconds to return. Returns
+// whether all the requests completed.
+func (d *Drainer) Drain(grace time.Duration) bool {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	completed := make(chan struct{})
+	go func() {
+		d.inflight.Wait()
+		close(completed)
+	}()
+
+	select {
+	case <-completed:
+		return true
+	case <-time.After(grace):
+	}
+
+	d.cancel()
+
+	select {
+	case <-completed:
+		return true
+	case <-time.After(d.cancelTimeout):
+		return false
+	}
+}
+
+// DrainOnSignal drains drainer when one of the signals is received, then
+// calls stop. A second signal calls stop right away. The signals are taken
+// over from the handlers already notified of them, such as the one of
+// microcluster stopping the daemon without draining.
+func DrainOnSignal(drainer *Drainer, grace time.Duration, stop func(), signals ...os.Signal) {
+	signal.Reset(signals...)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		sig := <-ch
+		logger.Info("Draining the in-flight requests before shutting down", logger.Ctx{"signal": sig.String(), "grace": grace.String()})
+
+		drained := make(chan bool, 1)
+		go func() {
+			drained <- drainer.Drain(grace)
+		}()
+
+		select {
+		case completed := <-drained:
+			if !completed {
+				logger.Warn("Shutting down with requests still in flight after the grace period", logger.Ctx{"grace": grace.String()})
+			}
+		case sig = <-ch:
+			logger.Warn("Shutting down without waiting for the in-flight requests", logger.Ctx{"signal": sig.String()})
+		}
+
+		stop()
+	}()
+}
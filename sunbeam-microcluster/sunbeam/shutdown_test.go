@@ -0,0 +1,86 @@
+package sunbeam
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestDrainerWaitsForRequests tests that a drain refuses the new requests
+// and returns once the ones in flight completed
+func TestDrainerWaitsForRequests(t *testing.T) {
+	d := NewDrainer()
+
+	ctx, done, ok := d.Begin(context.Background())
+	if !ok {
+		t.Fatal("Expected the request to be accepted")
+	}
+
+	drained := make(chan bool)
+	go func() {
+		drained <- d.Drain(time.Minute)
+	}()
+
+	// Refused once draining, while the first request is still in flight
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, refusedDone, ok := d.Begin(context.Background())
+		if !ok {
+			break
+		}
+		refusedDone()
+
+		if time.Now().After(deadline) {
+			t.Fatal("Expected new requests to be refused while draining")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	select {
+	case <-drained:
+		t.Fatal("Expected the drain to wait for the request in flight")
+	default:
+	}
+
+	done()
+	if !<-drained {
+		t.Error("Expected all the requests to complete")
+	}
+
+	if ctx.Err() == nil {
+		t.Error("Expected the context of the request to be released once done")
+	}
+}
+
+// TestDrainerCancelsAfterGrace tests that the requests in flight after the
+// grace period have their context cancelled
+func TestDrainerCancelsAfterGrace(t *testing.T) {
+	d := NewDrainer()
+	d.cancelTimeout = time.Second
+
+	// A request returning once cancelled
+	ctx, done, _ := d.Begin(context.Background())
+	go func() {
+		<-ctx.Done()
+		done()
+	}()
+
+	start := time.Now()
+	if !d.Drain(10 * time.Millisecond) {
+		t.Error("Expected the cancelled request to complete")
+	}
+
+	if time.Since(start) >= d.cancelTimeout {
+		t.Error("Expected the drain to return once the cancelled request completed")
+	}
+
+	// A request ignoring the cancellation of its context
+	d = NewDrainer()
+	d.cancelTimeout = 10 * time.Millisecond
+	_, done, _ = d.Begin(context.Background())
+	defer done()
+
+	if d.Drain(10 * time.Millisecond) {
+		t.Error("Expected the request ignoring its cancellation to be reported")
+	}
+}
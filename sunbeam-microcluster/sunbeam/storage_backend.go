@@ -16,7 +16,7 @@ func ListStorageBackends(ctx context.Context, s state.State) (apitypes.StorageBa
 	backends := apitypes.StorageBackends{}
 
 	// Get the storage backends from the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		records, err := database.GetStorageBackends(ctx, tx)
 		if err != nil {
 			return fmt.Errorf("Failed to fetch storage backends: %w", err)
@@ -46,7 +46,7 @@ func ListStorageBackends(ctx context.Context, s state.State) (apitypes.StorageBa
 // GetStorageBackend returns a StorageBackend with the given name
 func GetStorageBackend(ctx context.Context, s state.State, name string) (apitypes.StorageBackend, error) {
 	backend := apitypes.StorageBackend{}
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		record, err := database.GetStorageBackend(ctx, tx, name)
 		if err != nil {
 			return err
@@ -69,7 +69,7 @@ func GetStorageBackend(ctx context.Context, s state.State, name string) (apitype
 // AddStorageBackend adds a storage backend to the database
 func AddStorageBackend(ctx context.Context, s state.State, name string, backendType string, principal string, modelUUID string, config string) error {
 	// Add storage backend to the database.
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		_, err := database.CreateStorageBackend(ctx, tx, database.StorageBackend{
 			Name:      name,
 			Type:      backendType,
@@ -88,7 +88,7 @@ func AddStorageBackend(ctx context.Context, s state.State, name string, backendT
 // UpdateStorageBackend updates a storage backend record in the database
 func UpdateStorageBackend(ctx context.Context, s state.State, name string, backendType string, principal string, modelUUID string, config string) error {
 	// Update storage backend to the database.
-	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		backend, err := database.GetStorageBackend(ctx, tx, name)
 		if err != nil {
 			return fmt.Errorf("Failed to retrieve storage backend details: %w", err)
@@ -120,7 +120,7 @@ func UpdateStorageBackend(ctx context.Context, s state.State, name string, backe
 
 // DeleteStorageBackend deletes a storage backend from database
 func DeleteStorageBackend(ctx context.Context, s state.State, name string) error {
-	return s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
 		return database.DeleteStorageBackend(ctx, tx, name)
 	})
 
@@ -2,6 +2,7 @@ package sunbeam
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -10,6 +11,8 @@ import (
 	"github.com/canonical/microcluster/v2/state"
 
 	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
 )
 
 const tfstatePrefix = "tfstate-"
@@ -96,7 +99,44 @@ func GetTerraformLock(ctx context.Context, s state.State, name string) (string,
 	return lock, err
 }
 
-// UpdateTerraformLock updates the terraform lock record in the database
+// configStore is the subset of ConfigItem operations needed to manage a
+// terraform lock, scoped to a single transaction.
+type configStore interface {
+	// Get returns the value of key, or a 404 StatusError if it does not exist
+	Get(key string) (string, error)
+	// Create adds key, or returns a 409 StatusError if it already exists
+	Create(key string, value string) error
+	// Delete removes key
+	Delete(key string) error
+}
+
+// txConfigStore is a configStore backed by a database transaction
+type txConfigStore struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (t txConfigStore) Get(key string) (string, error) {
+	record, err := database.GetConfigItem(t.ctx, t.tx, key)
+	if err != nil {
+		return "", err
+	}
+
+	return record.Value, nil
+}
+
+func (t txConfigStore) Create(key string, value string) error {
+	_, err := database.CreateConfigItem(t.ctx, t.tx, database.ConfigItem{Key: key, Value: value, Revision: 1})
+	return err
+}
+
+func (t txConfigStore) Delete(key string) error {
+	return database.DeleteConfigItem(t.ctx, t.tx, key)
+}
+
+// UpdateTerraformLock acquires the terraform lock in the database.
+// The check for an existing lock and the acquisition happen in the same
+// transaction so that concurrent acquire attempts cannot both succeed.
 func UpdateTerraformLock(ctx context.Context, s state.State, name string, lock string) (apitypes.Lock, error) {
 	var reqLock apitypes.Lock
 	var dbLock apitypes.Lock
@@ -106,21 +146,42 @@ func UpdateTerraformLock(ctx context.Context, s state.State, name string, lock s
 		return dbLock, err
 	}
 
+	err = transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		dbLock, err = acquireTerraformLock(txConfigStore{ctx: ctx, tx: tx}, name, reqLock)
+		return err
+	})
+
+	if api.StatusErrorCheck(err, http.StatusLocked) {
+		metrics.TerraformLockContentionTotal.WithLabelValues(name).Inc()
+	}
+
+	return dbLock, err
+}
+
+// acquireTerraformLock records reqLock as the lock for the plan name.
+// If the plan is already locked, the current lock is returned along with
+// a http 423 error.
+func acquireTerraformLock(store configStore, name string, reqLock apitypes.Lock) (apitypes.Lock, error) {
+	var dbLock apitypes.Lock
+
+	j, err := json.Marshal(reqLock)
+	if err != nil {
+		return dbLock, err
+	}
+
 	tflockKey := tflockPrefix + name
-	lockInDb, err := GetConfig(ctx, s, tflockKey)
+	err = store.Create(tflockKey, string(j))
+	if err == nil {
+		return dbLock, nil
+	}
+
+	if !api.StatusErrorCheck(err, http.StatusConflict) {
+		return dbLock, err
+	}
+
+	lockInDb, err := store.Get(tflockKey)
 	if err != nil {
-		if err, ok := err.(api.StatusError); ok {
-			// No Lock exists, add lock details in DB
-			if err.Status() == http.StatusNotFound {
-				j, err := json.Marshal(reqLock)
-				if err != nil {
-					return dbLock, err
-				}
-
-				err = UpdateConfig(ctx, s, tflockKey, string(j))
-				return dbLock, err
-			}
-		}
 		return dbLock, err
 	}
 
@@ -129,45 +190,61 @@ func UpdateTerraformLock(ctx context.Context, s state.State, name string, lock s
 		return dbLock, err
 	}
 
-	// If the lock from DB and request are same, send http 423
-	if dbLock.ID == reqLock.ID && dbLock.Operation == reqLock.Operation && dbLock.Who == reqLock.Who {
+	if dbLock.ID == reqLock.ID {
 		return dbLock, api.StatusErrorf(http.StatusLocked, "Already locked with same ID")
 	}
 
-	// Already locked and request has different lockid, send http 409
-	return dbLock, api.StatusErrorf(http.StatusConflict, "Conflict in Lock ID")
+	// Already locked by another holder, send http 423 with the current lock
+	return dbLock, api.StatusErrorf(http.StatusLocked, "Locked by %q with ID %q", dbLock.Who, dbLock.ID)
 }
 
-// DeleteTerraformLock deletes the terraform lock from the database
+// DeleteTerraformLock deletes the terraform lock from the database.
+// An empty lock is sent by terraform force-unlock and clears the lock
+// regardless of its ID.
 func DeleteTerraformLock(ctx context.Context, s state.State, name string, lock string) (apitypes.Lock, error) {
+	var dbLock apitypes.Lock
+
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		dbLock, err = releaseTerraformLock(txConfigStore{ctx: ctx, tx: tx}, name, lock)
+		return err
+	})
+
+	return dbLock, err
+}
+
+// releaseTerraformLock clears the lock for the plan name if the ID of lock
+// matches the current lock, or unconditionally if lock is empty.
+func releaseTerraformLock(store configStore, name string, lock string) (apitypes.Lock, error) {
 	var reqLock apitypes.Lock
 	var dbLock apitypes.Lock
 
-	err := json.Unmarshal([]byte(lock), &reqLock)
-	if err != nil {
-		return dbLock, err
+	force := strings.TrimSpace(lock) == ""
+	if !force {
+		err := json.Unmarshal([]byte(lock), &reqLock)
+		if err != nil {
+			return dbLock, err
+		}
 	}
 
 	tflockKey := tflockPrefix + name
-	lockInDb, err := GetConfig(ctx, s, tflockKey)
+	lockInDb, err := store.Get(tflockKey)
 	if err != nil {
-		if err, ok := err.(api.StatusError); ok {
-			// No Lock exists to unlock, send 200: OK
-			if err.Status() == http.StatusNotFound {
-				return dbLock, nil
-			}
+		// No Lock exists to unlock, send 200: OK
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return dbLock, nil
 		}
 		return dbLock, err
 	}
 
 	err = json.Unmarshal([]byte(lockInDb), &dbLock)
-	if err != nil {
+	if err != nil && !force {
 		return dbLock, err
 	}
 
-	// If the lock from DB and request are same, clear the lock from DB
-	if dbLock.ID == reqLock.ID && dbLock.Operation == reqLock.Operation && dbLock.Who == reqLock.Who {
-		err = DeleteConfig(ctx, s, tflockKey)
+	// If the lock ID from DB and request are same, clear the lock from DB
+	if force || dbLock.ID == reqLock.ID {
+		err = store.Delete(tflockKey)
 		return dbLock, err
 	}
 
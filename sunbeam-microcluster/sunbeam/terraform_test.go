@@ -0,0 +1,193 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+)
+
+// memConfigStore is an in-memory configStore
+type memConfigStore struct {
+	items map[string]string
+}
+
+func newMemConfigStore() *memConfigStore {
+	return &memConfigStore{items: map[string]string{}}
+}
+
+func (m *memConfigStore) Get(key string) (string, error) {
+	value, ok := m.items[key]
+	if !ok {
+		return "", api.StatusErrorf(http.StatusNotFound, "ConfigItem not found")
+	}
+
+	return value, nil
+}
+
+func (m *memConfigStore) Create(key string, value string) error {
+	_, ok := m.items[key]
+	if ok {
+		return api.StatusErrorf(http.StatusConflict, "This \"config\" entry already exists")
+	}
+
+	m.items[key] = value
+	return nil
+}
+
+func (m *memConfigStore) Delete(key string) error {
+	delete(m.items, key)
+	return nil
+}
+
+func mustMarshalLock(t *testing.T, lock apitypes.Lock) string {
+	j, err := json.Marshal(lock)
+	if err != nil {
+		t.Fatalf("Failed to marshal lock: %v", err)
+	}
+
+	return string(j)
+}
+
+// TestAcquireTerraformLockConcurrent tests that only one of two concurrent
+// acquire attempts, each in its own transaction of the fixture database,
+// gets the lock and the other sees the current holder.
+func TestAcquireTerraformLockConcurrent(t *testing.T) {
+	_, db := newFixtureDatabase(t)
+	locks := []apitypes.Lock{
+		{ID: "lock-a", Operation: "OperationTypeApply", Who: "ubuntu@node-a"},
+		{ID: "lock-b", Operation: "OperationTypeApply", Who: "ubuntu@node-b"},
+	}
+
+	var wg sync.WaitGroup
+	dbLocks := make([]apitypes.Lock, len(locks))
+	errs := make([]error, len(locks))
+	for i, lock := range locks {
+		wg.Add(1)
+		go func(i int, lock apitypes.Lock) {
+			defer wg.Done()
+
+			tx, err := db.BeginTx(t.Context(), nil)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			dbLocks[i], errs[i] = acquireTerraformLock(txConfigStore{ctx: t.Context(), tx: tx}, "plan", lock)
+			if errs[i] != nil {
+				_ = tx.Rollback()
+				return
+			}
+
+			errs[i] = tx.Commit()
+		}(i, lock)
+	}
+
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		if err == nil {
+			if winner != -1 {
+				t.Fatal("Expected only one acquire attempt to succeed")
+			}
+			winner = i
+		}
+	}
+
+	if winner == -1 {
+		t.Fatalf("Expected one acquire attempt to succeed, got %v", errs)
+	}
+
+	loser := 1 - winner
+	if !api.StatusErrorCheck(errs[loser], http.StatusLocked) {
+		t.Fatalf("Expected http 423 for the second attempt, got %v", errs[loser])
+	}
+
+	if dbLocks[loser].ID != locks[winner].ID || dbLocks[loser].Who != locks[winner].Who {
+		t.Errorf("Expected current lock %+v, got %+v", locks[winner], dbLocks[loser])
+	}
+
+	var stored string
+	err := fixtureTransaction(t, db, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		stored, err = txConfigStore{ctx: ctx, tx: tx}.Get(tflockPrefix + "plan")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to get stored lock: %v", err)
+	}
+
+	if stored != mustMarshalLock(t, locks[winner]) {
+		t.Errorf("Expected stored lock of the winner, got %s", stored)
+	}
+}
+
+// TestReleaseTerraformLock tests lock ID verification and force-unlock
+func TestReleaseTerraformLock(t *testing.T) {
+	held := apitypes.Lock{ID: "lock-a", Operation: "OperationTypeApply", Who: "ubuntu@node-a"}
+
+	testCases := []struct {
+		name         string
+		lock         string
+		wantStatus   int
+		wantReleased bool
+	}{
+		{
+			name:         "matching lock ID",
+			lock:         mustMarshalLock(t, apitypes.Lock{ID: "lock-a"}),
+			wantReleased: true,
+		},
+		{
+			name:       "different lock ID",
+			lock:       mustMarshalLock(t, apitypes.Lock{ID: "lock-b", Who: "ubuntu@node-b"}),
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:         "force-unlock",
+			lock:         "",
+			wantReleased: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newMemConfigStore()
+			_, err := acquireTerraformLock(store, "plan", held)
+			if err != nil {
+				t.Fatalf("Failed to acquire lock: %v", err)
+			}
+
+			dbLock, err := releaseTerraformLock(store, "plan", tc.lock)
+			if tc.wantStatus != 0 {
+				if !api.StatusErrorCheck(err, tc.wantStatus) {
+					t.Errorf("Expected http %d, got %v", tc.wantStatus, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if dbLock.ID != held.ID {
+				t.Errorf("Expected current lock ID %q, got %q", held.ID, dbLock.ID)
+			}
+
+			_, err = store.Get(tflockPrefix + "plan")
+			released := api.StatusErrorCheck(err, http.StatusNotFound)
+			if released != tc.wantReleased {
+				t.Errorf("Expected released=%v, got %v", tc.wantReleased, released)
+			}
+		})
+	}
+
+	// Unlocking a plan without a lock is not an error
+	_, err := releaseTerraformLock(newMemConfigStore(), "plan", "")
+	if err != nil {
+		t.Errorf("Unexpected error unlocking a plan without lock: %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/metrics"
+)
+
+// transaction runs f in a database transaction, recording its duration
+func transaction(ctx context.Context, s state.State, f func(context.Context, *sql.Tx) error) error {
+	start := time.Now()
+	defer func() {
+		metrics.DatabaseTransactionDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	return s.Database().Transaction(ctx, f)
+}
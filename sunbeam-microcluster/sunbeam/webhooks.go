@@ -0,0 +1,169 @@
+package sunbeam
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/microcluster/v2/state"
+
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/api/apitypes"
+	"github.com/canonical/snap-openstack/sunbeam-microcluster/database"
+)
+
+// WebhooksConfigKey is the config key holding the webhooks the cluster
+// events are posted to, a json list of webhooks
+const WebhooksConfigKey = "webhook.targets"
+
+// webhookNamePattern matches the valid webhook names
+var webhookNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+
+// ParseWebhooks decodes a webhooks config value, returning a 400 StatusError
+// if it is not a list of valid webhooks with distinct names. An empty value
+// holds no webhooks.
+func ParseWebhooks(value string) (apitypes.Webhooks, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var hooks apitypes.Webhooks
+	err := json.Unmarshal([]byte(value), &hooks)
+	if err != nil {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid webhooks: must be a list of webhooks")
+	}
+
+	for i, hook := range hooks {
+		err := validateWebhook(hook)
+		if err != nil {
+			return nil, err
+		}
+
+		if slices.ContainsFunc(hooks[:i], func(other apitypes.Webhook) bool { return other.Name == hook.Name }) {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Invalid webhook %q: duplicated", hook.Name)
+		}
+	}
+
+	return hooks, nil
+}
+
+// validateWebhook returns a 400 StatusError if hook has an invalid name, URL
+// or event type, or no secret
+func validateWebhook(hook apitypes.Webhook) error {
+	if !webhookNamePattern.MatchString(hook.Name) {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid webhook name %q: must be lowercase alphanumeric, dashes or underscores, starting with a letter", hook.Name)
+	}
+
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid webhook %q: URL %q must be an http or https URL", hook.Name, hook.URL)
+	}
+
+	if hook.Secret == "" {
+		return api.StatusErrorf(http.StatusBadRequest, "Invalid webhook %q: secret is required to sign the deliveries", hook.Name)
+	}
+
+	for _, event := range hook.Events {
+		if !slices.Contains(apitypes.EventTypes, event) {
+			return api.StatusErrorf(http.StatusBadRequest, "Invalid webhook %q: unknown event type %q, must be one of %v", hook.Name, event, apitypes.EventTypes)
+		}
+	}
+
+	return nil
+}
+
+// webhooks returns the webhooks defined in the cluster config
+func webhooks(ctx context.Context, tx *sql.Tx) (apitypes.Webhooks, error) {
+	record, err := database.GetConfigItem(ctx, tx, WebhooksConfigKey)
+	if err != nil {
+		if api.StatusErrorCheck(err, http.StatusNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Failed to fetch webhooks: %w", err)
+	}
+
+	return ParseWebhooks(record.Value)
+}
+
+// loadWebhooks returns the webhooks defined in the cluster config
+func loadWebhooks(ctx context.Context, s state.State) (apitypes.Webhooks, error) {
+	var hooks apitypes.Webhooks
+	err := transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		hooks, err = webhooks(ctx, tx)
+		return err
+	})
+
+	return hooks, err
+}
+
+// ListWebhooks returns the webhooks defined in the cluster config, with
+// their secrets left out
+func ListWebhooks(ctx context.Context, s state.State) (apitypes.Webhooks, error) {
+	hooks, err := loadWebhooks(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make(apitypes.Webhooks, 0, len(hooks))
+	for _, hook := range hooks {
+		hook.Secret = ""
+		redacted = append(redacted, hook)
+	}
+
+	return redacted, nil
+}
+
+// AddWebhook defines a new webhook, returning a 409 StatusError if one with
+// the same name exists
+func AddWebhook(ctx context.Context, s state.State, hook apitypes.Webhook, actor string) error {
+	return updateWebhooks(ctx, s, actor, func(hooks apitypes.Webhooks) (apitypes.Webhooks, error) {
+		if slices.ContainsFunc(hooks, func(other apitypes.Webhook) bool { return other.Name == hook.Name }) {
+			return nil, api.StatusErrorf(http.StatusConflict, "Webhook %q already exists", hook.Name)
+		}
+
+		return append(hooks, hook), nil
+	})
+}
+
+// DeleteWebhook removes a webhook, returning a 404 StatusError if it does
+// not exist
+func DeleteWebhook(ctx context.Context, s state.State, name string, actor string) error {
+	return updateWebhooks(ctx, s, actor, func(hooks apitypes.Webhooks) (apitypes.Webhooks, error) {
+		i := slices.IndexFunc(hooks, func(hook apitypes.Webhook) bool { return hook.Name == name })
+		if i == -1 {
+			return nil, api.StatusErrorf(http.StatusNotFound, "Webhook %q not found", name)
+		}
+
+		return slices.Delete(hooks, i, i+1), nil
+	})
+}
+
+// updateWebhooks replaces the webhooks defined in the cluster config with
+// the ones returned by update, in a single transaction
+func updateWebhooks(ctx context.Context, s state.State, actor string, update func(apitypes.Webhooks) (apitypes.Webhooks, error)) error {
+	return transaction(ctx, s, func(ctx context.Context, tx *sql.Tx) error {
+		hooks, err := webhooks(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		hooks, err = update(hooks)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(hooks)
+		if err != nil {
+			return err
+		}
+
+		_, err = putConfigItem(ctx, tx, WebhooksConfigKey, string(value), "", actor)
+		return err
+	})
+}
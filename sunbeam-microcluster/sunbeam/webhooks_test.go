@@ -0,0 +1,50 @@
+package sunbeam
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/canonical/lxd/shared/api"
+)
+
+// TestParseWebhooks tests that webhooks must have distinct valid names, an
+// http URL, a secret and known event types
+func TestParseWebhooks(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+		count int
+		valid bool
+	}{
+		{name: "empty value", value: "", count: 0, valid: true},
+		{name: "empty list", value: `[]`, count: 0, valid: true},
+		{name: "webhooks", value: `[{"name": "pager", "url": "https://pager.example.com/hook", "secret": "s"}, {"name": "chat", "url": "http://10.0.0.1:8080", "secret": "s", "events": ["node.added"]}]`, count: 2, valid: true},
+		{name: "not a list", value: `{"name": "pager"}`, valid: false},
+		{name: "invalid name", value: `[{"name": "Pager", "url": "https://pager.example.com", "secret": "s"}]`, valid: false},
+		{name: "no scheme", value: `[{"name": "pager", "url": "pager.example.com", "secret": "s"}]`, valid: false},
+		{name: "unsupported scheme", value: `[{"name": "pager", "url": "ftp://pager.example.com", "secret": "s"}]`, valid: false},
+		{name: "no secret", value: `[{"name": "pager", "url": "https://pager.example.com"}]`, valid: false},
+		{name: "unknown event", value: `[{"name": "pager", "url": "https://pager.example.com", "secret": "s", "events": ["node.updated"]}]`, valid: false},
+		{name: "duplicated name", value: `[{"name": "pager", "url": "https://a.example.com", "secret": "s"}, {"name": "pager", "url": "https://b.example.com", "secret": "s"}]`, valid: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hooks, err := ParseWebhooks(tc.value)
+			if !tc.valid {
+				if !api.StatusErrorCheck(err, http.StatusBadRequest) {
+					t.Errorf("Expected bad request error, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(hooks) != tc.count {
+				t.Errorf("Expected %d webhooks, got %v", tc.count, hooks)
+			}
+		})
+	}
+}
@@ -1,5 +1,39 @@
 // Package version provides shared version information.
 package version
 
+import "runtime/debug"
+
 // Version is the current API version.
 const Version = "0.1"
+
+// MicroclusterModule is the module path of the microcluster library.
+const MicroclusterModule = "github.com/canonical/microcluster/v2"
+
+// MicroclusterVersion returns the version of the microcluster library the
+// daemon is built with, or "unknown" without build information.
+func MicroclusterVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	return moduleVersion(info, MicroclusterModule)
+}
+
+// moduleVersion returns the version of the dependency at path, following
+// replace directives, or "unknown" if it is not a dependency.
+func moduleVersion(info *debug.BuildInfo, path string) string {
+	for _, dep := range info.Deps {
+		if dep.Path != path {
+			continue
+		}
+
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+
+		return dep.Version
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,31 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+// TestModuleVersion tests that the version of a dependency follows replace
+// directives
+func TestModuleVersion(t *testing.T) {
+	info := &debug.BuildInfo{
+		Deps: []*debug.Module{
+			{Path: "example.com/other", Version: "v1.0.0"},
+			{Path: MicroclusterModule, Version: "v2.2.0"},
+			{Path: "example.com/replaced", Version: "v1.0.0", Replace: &debug.Module{Path: "example.com/fork", Version: "v1.0.1"}},
+		},
+	}
+
+	testCases := map[string]string{
+		MicroclusterModule:     "v2.2.0",
+		"example.com/replaced": "v1.0.1",
+		"example.com/missing":  "unknown",
+	}
+
+	for path, expected := range testCases {
+		version := moduleVersion(info, path)
+		if version != expected {
+			t.Errorf("Expected version %q for %q, got %q", expected, path, version)
+		}
+	}
+}
@@ -5,16 +5,36 @@ import atexit
 import os
 import ssl
 import tempfile
+from pathlib import Path
 from urllib.parse import quote
 
 import requests
 import requests.adapters
 import requests_unixsocket  # type: ignore [import-untyped]
-from snaphelpers import Snap
+from snaphelpers import Snap, UnknownConfigKey
 from urllib3 import poolmanager
 
 from sunbeam.clusterd.cluster import ClusterService
 
+# Snap option overriding the state directory of clusterd, holding its
+# database and socket
+STATE_DIR_CONFIG_KEY = "daemon.state-dir"
+
+
+def clusterd_state_dir(snap: Snap) -> Path:
+    """Return the state directory of clusterd.
+
+    It is set by the daemon.state-dir snap option, $SNAP_COMMON/state if
+    unset.
+    """
+    try:
+        state_dir = snap.config.get(STATE_DIR_CONFIG_KEY)
+    except UnknownConfigKey:
+        state_dir = None
+    if state_dir and isinstance(state_dir, str):
+        return Path(state_dir)
+    return snap.paths.common / "state"
+
 
 class MTLSAdapter(requests.adapters.HTTPAdapter):
     def __init__(self, *args, **kwargs):
@@ -94,7 +114,7 @@ class Client:
     def from_socket(cls) -> "Client":
         """Return a client initialized to the clusterd socket."""
         escaped_socket_path = quote(
-            str(Snap().paths.common / "state" / "control.socket"), safe=""
+            str(clusterd_state_dir(Snap()) / "control.socket"), safe=""
         )
         return cls("http+unix://" + escaped_socket_path)
 
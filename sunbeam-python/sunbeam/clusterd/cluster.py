@@ -1,10 +1,11 @@
 # SPDX-FileCopyrightText: 2023 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import datetime
 import json
 import logging
 import secrets
-from typing import Any, Union
+from typing import Any, Mapping, Union
 
 from requests import codes
 from requests.models import HTTPError
@@ -13,6 +14,10 @@ from sunbeam.clusterd import models, service
 
 LOG = logging.getLogger(__name__)
 
+# Key of the init config of a join holding the comma separated roles of the
+# node, checked by clusterd against the scope of the join token
+JOIN_ROLES_CONFIG_KEY = "roles"
+
 
 class MicroClusterService(service.BaseService):
     """Client for default MicroCluster Service API."""
@@ -31,18 +36,26 @@ class MicroClusterService(service.BaseService):
         data = {"bootstrap": True, "address": address, "name": name}
         self._post("/core/control", data=json.dumps(data))
 
-    def join(self, name: str, address: str, token: str) -> None:
+    def join(
+        self, name: str, address: str, token: str, role: list[str] | None = None
+    ) -> None:
         """Join node to the micro cluster.
 
         Verified the token with the list of saved tokens and
-        joins the node with the given name and address.
+        joins the node with the given name and address. The roles of the
+        node are checked against the scope of the token before the other
+        members trust the node.
 
         Raises NodeAlreadyExistsException if the node is already
         part of the cluster.
         Raises NodeJoinException if the token doesnot match or not
         part of the generated tokens list.
+        Raises JoinTokenRejectedException if the scope of the token rejects
+        the node, which is then removed from the cluster.
         """
-        data = {"join_token": token, "address": address, "name": name}
+        data: dict = {"join_token": token, "address": address, "name": name}
+        if role:
+            data["config"] = {JOIN_ROLES_CONFIG_KEY: ",".join(role)}
         self._post("/core/control", data=json.dumps(data))
 
     def get_cluster_members(self) -> list:
@@ -53,30 +66,49 @@ class MicroClusterService(service.BaseService):
         result = []
         cluster = self._get("/core/1.0/cluster")
         members = cluster.get("metadata", {})
-        keys = ["name", "address", "status"]
+        keys = ["name", "address", "status", "role"]
         for member in members:
             result.append({k: v for k, v in member.items() if k in keys})
         return result
 
-    def remove(self, name: str) -> None:
+    def remove(self, name: str, force: bool = False) -> None:
         """Remove node from the cluster.
 
+        With force, the node is removed from the raft membership and the
+        trust store even if it is unreachable, its remove hooks and reset
+        errors being ignored.
+
         Raises NodeNotExistInClusterException if node does not
         exist in the cluster.
         Raises NodeRemoveFromClusterException if the node is last
         member of the cluster.
         """
-        self._delete(f"/core/1.0/cluster/{name}")
+        params = {"force": "1"} if force else None
+        self._delete(f"/core/1.0/cluster/{name}", params=params)
+
+    def reset(self, name: str) -> None:
+        """Reset the cluster member to its state before bootstrap.
+
+        The state of the member is cleared and its daemon re-executed,
+        this only applies to the last member of a cluster.
+        """
+        self._put(f"/core/internal/cluster/{name}")
 
-    def generate_token(self, name: str) -> str:
+    def generate_token(
+        self, name: str, expire_after: datetime.timedelta | None = None
+    ) -> str:
         """Generate token for the node.
 
-        Generate a new token for the node with name.
+        Generate a new token for the node with name, expiring after
+        expire_after if set.
 
         Raises TokenAlreadyGeneratedException if token is already
         generated.
         """
-        data = {"name": name}
+        data: dict = {"name": name}
+        if expire_after is not None:
+            # microcluster expects a Go duration, in nanoseconds
+            data["expire_after"] = int(expire_after.total_seconds()) * 1_000_000_000
         result = self._post("/core/control/tokens", data=json.dumps(data))
         return result.get("metadata")
 
@@ -96,16 +128,37 @@ class MicroClusterService(service.BaseService):
 class ExtendedAPIService(service.BaseService):
     """Client for Sunbeam extended Cluster API."""
 
+    NODES_TOTAL_COUNT_HEADER = "X-Total-Count"
+    CONFIG_STRICT_HEADER = "X-Sunbeam-Config-Strict"
+    CONFIG_SCOPE_HEADER = "X-Sunbeam-Config-Scope"
+    CUSTOM_ROLES_KEY = "node.custom-roles"
+
     def add_node_info(
-        self, name: str, role: list[str], machineid: int = -1, systemid: str = ""
+        self,
+        name: str,
+        role: list[str],
+        machineid: int = -1,
+        systemid: str = "",
+        addresses: dict[str, str] | None = None,
+        preferred_api: str | None = None,
     ) -> None:
-        """Add Node information to cluster database."""
-        data = {
+        """Add Node information to cluster database.
+
+        Addresses are the IP addresses of the node by network name, such as
+        management or data, preferred_api naming the one serving the cluster
+        API. Raises InvalidNodeAddressException if the preferred API address
+        is not one of them.
+        """
+        data: dict[str, Any] = {
             "name": name,
             "role": role,
             "machineid": machineid,
             "systemid": systemid,
         }
+        if addresses:
+            data["addresses"] = addresses
+        if preferred_api:
+            data["preferred_api"] = preferred_api
         self._post("/1.0/nodes", data=json.dumps(data))
 
     def list_nodes(self) -> list[dict]:
@@ -113,13 +166,110 @@ class ExtendedAPIService(service.BaseService):
         nodes = self._get("/1.0/nodes")
         return nodes.get("metadata")
 
+    def list_nodes_page(
+        self,
+        role: list[str] | None = None,
+        limit: int | None = None,
+        offset: int = 0,
+        label: list[str] | None = None,
+        include_removed: bool = False,
+    ) -> tuple[list[dict], int]:
+        """List a page of nodes, ordered by join time.
+
+        Nodes matching any of the given roles and all of the given key=value
+        labels are returned. Returns the page of nodes along with the total
+        number of matching nodes. Removed nodes follow the active ones when
+        include_removed is set.
+
+        Raises InvalidNodeRoleException if a role is unknown and
+        InvalidNodeLabelException if a label selector is invalid.
+        """
+        params: dict[str, Any] = {}
+        if role:
+            params["role"] = role
+        if label:
+            params["label"] = label
+        if include_removed:
+            params["include_removed"] = "true"
+        if limit is not None:
+            params["limit"] = limit
+        if offset:
+            params["offset"] = offset
+        nodes, headers = self._get("/1.0/nodes", params=params, include_headers=True)
+        page = nodes.get("metadata") or []
+        total = int(headers.get(self.NODES_TOTAL_COUNT_HEADER, len(page)))
+        return page, total
+
+    def batch_nodes(self, operations: list[dict]) -> models.NodeBatchResponse:
+        """Add and remove nodes in a single transaction.
+
+        Each operation holds an action, add or remove, and the node name
+        along with its role for additions or the removal reason for removals.
+        If any operation fails, none are applied and the response reports
+        the outcome of each operation.
+        """
+        response = self._post(
+            "/1.0/nodes:batch", data=json.dumps({"operations": operations})
+        )
+        return models.NodeBatchResponse(**response.get("metadata"))
+
     def get_node_info(self, name: str) -> dict:
         """Fetch Node Information from a name."""
         return self._get(f"1.0/nodes/{name}").get("metadata")
 
-    def remove_node_info(self, name: str) -> None:
-        """Remove Node information from cluster database."""
-        self._delete(f"1.0/nodes/{name}")
+    def get_node_labels(self, name: str) -> dict[str, str]:
+        """Fetch the labels of a node."""
+        return self._get(f"1.0/nodes/{name}/labels").get("metadata") or {}
+
+    def set_node_labels(self, name: str, labels: dict[str, str]) -> dict[str, str]:
+        """Replace the labels of a node."""
+        response = self._put(f"1.0/nodes/{name}/labels", data=json.dumps(labels))
+        return response.get("metadata") or {}
+
+    def update_node_labels(
+        self, name: str, labels: Mapping[str, str | None]
+    ) -> dict[str, str]:
+        """Merge labels into the labels of a node, None values remove keys."""
+        response = self._patch(
+            f"1.0/nodes/{name}/labels", data=json.dumps(dict(labels))
+        )
+        return response.get("metadata") or {}
+
+    def set_node_cordon(self, name: str, cordoned: bool) -> dict:
+        """Record whether a node is cordoned, returning the node."""
+        response = self._put(
+            f"1.0/nodes/{name}/cordon", data=json.dumps({"cordoned": cordoned})
+        )
+        return response.get("metadata")
+
+    def update_node_inventory(
+        self, name: str, hardware: models.Hardware
+    ) -> models.NodeInventory:
+        """Record the hardware reported by a node."""
+        response = self._put(
+            f"1.0/nodes/{name}/inventory", data=json.dumps(hardware.model_dump())
+        )
+        return models.NodeInventory(**response.get("metadata"))
+
+    def get_node_inventory(self, name: str) -> models.NodeInventory:
+        """Fetch the latest hardware inventory of a node."""
+        response = self._get(f"1.0/nodes/{name}/inventory")
+        return models.NodeInventory(**response.get("metadata"))
+
+    def list_node_inventories(self, name: str) -> models.NodeInventories:
+        """Fetch the recorded hardware inventory revisions of a node."""
+        response = self._get(
+            f"1.0/nodes/{name}/inventory", params={"history": "true"}
+        )
+        return models.NodeInventories(root=response.get("metadata") or [])
+
+    def remove_node_info(self, name: str, reason: str | None = None) -> None:
+        """Remove Node information from cluster database.
+
+        The node is kept as removed, along with the reason, for auditing.
+        """
+        params = {"reason": reason} if reason else None
+        self._delete(f"1.0/nodes/{name}", params=params)
 
     def update_node_info(
         self,
@@ -170,13 +320,143 @@ class ExtendedAPIService(service.BaseService):
         """Fetch configuration from database."""
         return self._get(f"/1.0/config/{key}").get("metadata")
 
-    def update_config(self, key: str, value: Any):
-        """Update configuration in database, create if missing."""
-        self._put(f"/1.0/config/{key}", data=value)
+    def get_role_config(self, key: str, role: str) -> tuple[Any, str]:
+        """Fetch configuration for the nodes of a role from database.
+
+        The override of the role is returned if set, the global value
+        otherwise, along with the scope of the value: role or global.
+        """
+        config, headers = self._get(
+            f"/1.0/config/{key}", params={"role": role}, include_headers=True
+        )
+        return config.get("metadata"), headers.get(self.CONFIG_SCOPE_HEADER)
+
+    def update_config(
+        self, key: str, value: Any, strict: bool = False, role: str | None = None
+    ):
+        """Update configuration in database, create if missing.
+
+        Values of known keys are validated against the config schema, raising
+        InvalidConfigException. Unknown keys are rejected in strict mode. With
+        a role, the override of the key for the nodes of the role is updated.
+        """
+        headers = {self.CONFIG_STRICT_HEADER: "true"} if strict else None
+        params = {"role": role} if role else None
+        self._put(f"/1.0/config/{key}", data=value, headers=headers, params=params)
+
+    def apply_config_transaction(
+        self,
+        values: dict[str, str],
+        preconditions: dict[str, str | None] | None = None,
+        strict: bool = False,
+        role: str | None = None,
+    ):
+        """Update configuration keys all at once in database.
+
+        The keys are only set if the keys of preconditions hold their expected
+        value, None expecting a key to be unset. Either all the keys are set
+        or none are, raising ConfigTransactionFailedException with the errors
+        of every rejected key. Unknown keys are rejected in strict mode. With
+        a role, the keys are the overrides for the nodes of the role.
+        """
+        data: dict[str, Any] = {"set": values}
+        if preconditions:
+            data["preconditions"] = preconditions
+        if role:
+            data["role"] = role
+        headers = {self.CONFIG_STRICT_HEADER: "true"} if strict else None
+        self._post(
+            "/1.0/config:transaction",
+            data=json.dumps(data),
+            headers=headers,
+            redact_request=True,
+        )
+
+    def export_config(self, redact_secrets: bool = False) -> dict:
+        """Export all the config keys as a single document.
+
+        The overrides of the node roles are included under their scoped key.
+        Config items holding credentials are replaced by a placeholder if
+        redact_secrets is set, such values are skipped on import.
+        """
+        params = {"redact_secrets": "true"} if redact_secrets else None
+        response = self._get(
+            "/1.0/config:export", params=params, redact_response=True
+        )
+        return response.get("metadata")
+
+    def import_config(
+        self, config: dict[str, str], prune: bool = False
+    ) -> models.ConfigImportResult:
+        """Apply a config document all at once, returning the changed keys.
+
+        The keys absent from config are deleted if prune is set. Either all
+        the keys are changed or none are, raising
+        ConfigTransactionFailedException with the errors of every rejected
+        key.
+        """
+        data: dict[str, Any] = {"config": config}
+        if prune:
+            data["prune"] = True
+        response = self._post(
+            "/1.0/config:import", data=json.dumps(data), redact_request=True
+        )
+        return models.ConfigImportResult(**(response.get("metadata") or {}))
+
+    def get_config_schema(self) -> models.ConfigSchema:
+        """List the known config keys along with the type of their value."""
+        schema = self._get("/1.0/config/schema")
+        return models.ConfigSchema(root=schema.get("metadata") or [])
+
+    def delete_config(self, key: str, role: str | None = None):
+        """Remove configuration from database, its history is kept.
+
+        With a role, only the override of the key for the nodes of the role is
+        removed, the global value being used again.
+        """
+        params = {"role": role} if role else None
+        self._delete(f"/1.0/config/{key}", params=params)
+
+    def get_config_history(self, key: str) -> list[models.ConfigRevision]:
+        """List the recorded revisions of a config key, oldest first.
 
-    def delete_config(self, key: str):
-        """Remove configuration from database."""
-        self._delete(f"/1.0/config/{key}")
+        Raises ConfigItemNotFoundException if the key has neither a value nor
+        recorded revisions.
+        """
+        history = self._get(f"/1.0/config/{key}/history", redact_response=True)
+        return [
+            models.ConfigRevision(**revision)
+            for revision in history.get("metadata") or []
+        ]
+
+    def rollback_config(
+        self, key: str, revision: int | None = None
+    ) -> models.ConfigRevision:
+        """Restore a recorded revision of a config key, the previous one if unset.
+
+        The restored value is written as a new revision, which is returned.
+        Raises ConfigRevisionNotFoundException if the revision is not recorded.
+        """
+        data = {"revision": revision} if revision is not None else {}
+        response = self._post(
+            f"/1.0/config/{key}/rollback",
+            data=json.dumps(data),
+            redact_response=True,
+        )
+        return models.ConfigRevision(**response.get("metadata"))
+
+    def list_roles(self) -> list[models.NodeRole]:
+        """List the roles nodes can be assigned, built-in and custom ones."""
+        roles = self._get("/1.0/roles")
+        return [models.NodeRole(**role) for role in roles.get("metadata") or []]
+
+    def set_custom_roles(self, roles: list[str]) -> None:
+        """Define the custom roles nodes can be assigned.
+
+        Raises InvalidNodeRoleException if a role is invalid, or if a removed
+        role is still assigned to nodes.
+        """
+        self.update_config(self.CUSTOM_ROLES_KEY, json.dumps(roles))
 
     def list_nodes_by_role(self, role: Union[str, list[str]]) -> list:
         """List nodes by role."""
@@ -204,6 +484,10 @@ class ExtendedAPIService(service.BaseService):
         """Unlock plan."""
         self._put(f"/1.0/terraformunlock/{plan}", data=json.dumps(lock))
 
+    def force_unlock_terraform_plan(self, plan: str) -> None:
+        """Unlock plan regardless of the lock ID, like terraform force-unlock."""
+        self._put(f"/1.0/terraformunlock/{plan}", data="")
+
     def add_manifest(self, data: str) -> str:
         """Add manifest to cluster database."""
         manifest_id = secrets.token_hex(16)
@@ -237,6 +521,105 @@ class ExtendedAPIService(service.BaseService):
         """
         return self._get("/local/certpair/server", redact_response=True).get("metadata")
 
+    def rotate_cluster_certificate(
+        self, certificate: str, private_key: str, ca: str | None = None
+    ) -> models.ClusterCertificateRotation:
+        """Rotate the cluster certificate served by all the members.
+
+        The certificate chain is validated against ca, defaulting to the
+        cluster CA, or must be self-signed if there is none. The members
+        switch in turn, the rotation halts on the first failing member.
+        """
+        data = {"certificate": certificate, "private-key": private_key}
+        if ca:
+            data["ca"] = ca
+        response = self._post(
+            "/1.0/certificates/cluster:rotate",
+            data=json.dumps(data),
+            redact_request=True,
+        )
+        return models.ClusterCertificateRotation(**response.get("metadata"))
+
+    def list_webhooks(self) -> list[models.Webhook]:
+        """List the webhooks the cluster events are posted to, without secrets."""
+        webhooks = self._get("/1.0/webhooks")
+        return [models.Webhook(**hook) for hook in webhooks.get("metadata") or []]
+
+    def add_webhook(self, webhook: models.Webhook) -> None:
+        """Define a webhook the cluster events are posted to.
+
+        Raises InvalidWebhookException if the webhook is invalid, or
+        WebhookAlreadyExistsException if its name is used.
+        """
+        self._post(
+            "/1.0/webhooks",
+            data=webhook.model_dump_json(exclude_none=True),
+            redact_request=True,
+        )
+
+    def remove_webhook(self, name: str) -> None:
+        """Remove a webhook, raise WebhookNotFoundException if it is not defined."""
+        self._delete(f"/1.0/webhooks/{name}")
+
+    def list_read_only_tokens(self) -> list[models.ReadOnlyToken]:
+        """List the read-only tokens, without the tokens themselves."""
+        tokens = self._get("/1.0/read-only-tokens")
+        return [models.ReadOnlyToken(**token) for token in tokens.get("metadata") or []]
+
+    def create_read_only_token(self, name: str) -> str:
+        """Create a read-only token and return it, it cannot be retrieved later.
+
+        Raises InvalidReadOnlyTokenException if the name is invalid, or
+        ReadOnlyTokenAlreadyExistsException if it is used.
+        """
+        result = self._post(
+            "/1.0/read-only-tokens",
+            data=json.dumps({"name": name}),
+            redact_response=True,
+        )
+        return result.get("metadata", {}).get("token")
+
+    def delete_read_only_token(self, name: str) -> None:
+        """Revoke a read-only token.
+
+        Raises ReadOnlyTokenNotFoundException if it does not exist.
+        """
+        self._delete(f"/1.0/read-only-tokens/{name}")
+
+    def list_join_token_scopes(self) -> list[models.JoinTokenScope]:
+        """List the join token scopes, including expired and consumed ones."""
+        scopes = self._get("/1.0/join-token-scopes")
+        return [
+            models.JoinTokenScope(**scope) for scope in scopes.get("metadata") or []
+        ]
+
+    def create_join_token_scope(
+        self, name: str, roles: list[str], ttl: str | None = None
+    ) -> models.JoinTokenScope:
+        """Restrict the single-use join token of node name to roles and a TTL.
+
+        Raises InvalidJoinTokenScopeException if the scope is invalid,
+        InvalidNodeRoleException for unknown roles, or
+        JoinTokenScopeAlreadyExistsException if the token is already scoped.
+        """
+        data: dict = {"name": name, "roles": roles}
+        if ttl:
+            data["ttl"] = ttl
+        result = self._post("/1.0/join-token-scopes", data=json.dumps(data))
+        return models.JoinTokenScope(**result.get("metadata"))
+
+    def delete_join_token_scope(self, name: str) -> None:
+        """Lift the restrictions of the join token of node name.
+
+        Raises JoinTokenScopeNotFoundException if it is not scoped.
+        """
+        self._delete(f"/1.0/join-token-scopes/{name}")
+
+    def get_meta(self) -> models.ClusterdMeta:
+        """Get the versions of clusterd, its schema and the API it serves."""
+        meta = self._get("/1.0/meta")
+        return models.ClusterdMeta(**meta.get("metadata"))
+
     def get_status(self) -> dict[str, dict]:
         """Get status of the cluster."""
         cluster = self._get("/1.0/status")
@@ -245,6 +628,7 @@ class ExtendedAPIService(service.BaseService):
             member["name"]: {
                 "status": member["status"],
                 "address": member["address"],
+                "last_heartbeat": member.get("last_heartbeat"),
             }
             for member in members
         }
@@ -331,6 +715,201 @@ class ExtendedAPIService(service.BaseService):
         }
         self._put(f"/1.0/feature-gates/{gate_key}", data=json.dumps(data))
 
+    def list_maintenance_status(self) -> models.MaintenanceStatusList:
+        """List the maintenance status of all the nodes."""
+        statuses = self._get("/1.0/maintenance")
+        return models.MaintenanceStatusList(root=statuses.get("metadata") or [])
+
+    def update_maintenance_status(
+        self,
+        node: str,
+        status: str,
+        strategy: str = "",
+        triggered_by: str = "",
+        message: str = "",
+        ttl: str = "",
+    ) -> None:
+        """Record the maintenance status of a node.
+
+        When ttl, a duration such as 2h, is set, clusterd automatically
+        disables maintenance once it elapses.
+        """
+        data = {
+            "status": status,
+            "strategy": strategy,
+            "triggered_by": triggered_by,
+            "message": message,
+        }
+        if ttl:
+            data["ttl"] = ttl
+        self._put(f"/1.0/maintenance/{node}", data=json.dumps(data))
+
+    def add_maintenance_progress(
+        self, node: str, events: list[models.MaintenanceProgress]
+    ) -> list[models.MaintenanceProgress]:
+        """Post progress events of the maintenance operation on a node."""
+        data = [event.model_dump(exclude={"seq", "node"}) for event in events]
+        response = self._post(
+            f"/1.0/maintenance/{node}/progress", data=json.dumps(data)
+        )
+        return [
+            models.MaintenanceProgress(**event)
+            for event in response.get("metadata") or []
+        ]
+
+    def get_maintenance_progress(
+        self, node: str, after: int = 0, wait: int = 0
+    ) -> list[models.MaintenanceProgress]:
+        """Get the progress events of a node numbered after after.
+
+        With wait, in seconds, clusterd waits until it has new events, for up
+        to a minute, so that successive calls follow the progress.
+        """
+        response = self._get(
+            f"/1.0/maintenance/{node}/progress?after={after}&wait={wait}s"
+        )
+        return [
+            models.MaintenanceProgress(**event)
+            for event in response.get("metadata") or []
+        ]
+
+    def schedule_maintenance(
+        self, scheduled: models.ScheduledMaintenance
+    ) -> models.ScheduledMaintenance:
+        """Schedule a maintenance enable of a node in a change window.
+
+        Returns the scheduled maintenance recorded by clusterd.
+        """
+        data = scheduled.model_dump(include={"node", "window_start", "window_end"})
+        data["options"] = scheduled.options.model_dump(exclude_defaults=True)
+        response = self._post("/1.0/maintenance-schedule", data=json.dumps(data))
+        return models.ScheduledMaintenance(**response.get("metadata"))
+
+    def list_scheduled_maintenances(self) -> list[models.ScheduledMaintenance]:
+        """List the scheduled maintenances, including the ones which ran."""
+        response = self._get("/1.0/maintenance-schedule")
+        return [
+            models.ScheduledMaintenance(**scheduled)
+            for scheduled in response.get("metadata") or []
+        ]
+
+    def cancel_scheduled_maintenance(self, id: int) -> None:
+        """Cancel a pending scheduled maintenance."""
+        self._delete(f"/1.0/maintenance-schedule/{id}")
+
+    def update_maintenance_plan(self, plan: models.MaintenancePlan) -> None:
+        """Record the Watcher plan of a maintenance operation of a node.
+
+        It replaces the previous plan of the operation of the node.
+        """
+        data = plan.model_dump(exclude={"node", "created_at", "created_by"})
+        self._put(f"/1.0/maintenance/{plan.node}/plan", data=json.dumps(data))
+
+    def get_maintenance_plans(
+        self, node: str, operation: str | None = None
+    ) -> list[models.MaintenancePlan]:
+        """Get the Watcher plans of the maintenance operations of a node.
+
+        Only the plan of operation, enable or disable, is returned if set.
+        """
+        params = {"operation": operation} if operation else None
+        response = self._get(f"/1.0/maintenance/{node}/plan", params=params)
+        return [
+            models.MaintenancePlan(**plan) for plan in response.get("metadata") or []
+        ]
+
+    def create_operation(self, command: list[str]) -> models.Operation:
+        """Record a sunbeam command to run in the background.
+
+        Command holds the arguments of the command, which must be one of the
+        detachable commands. Returns the pending operation recorded, until a
+        runner claims it.
+        """
+        response = self._post(
+            "/1.0/operations", data=json.dumps({"command": command})
+        )
+        return models.Operation(**response.get("metadata"))
+
+    def list_operations(self) -> list[models.Operation]:
+        """List the operations of all the members, including completed ones."""
+        response = self._get("/1.0/operations")
+        return [
+            models.Operation(**operation)
+            for operation in response.get("metadata") or []
+        ]
+
+    def get_operation(self, id: int) -> models.Operation:
+        """Get an operation."""
+        response = self._get(f"/1.0/operations/{id}")
+        return models.Operation(**response.get("metadata"))
+
+    def cancel_operation(self, id: int) -> models.Operation:
+        """Cancel a pending or running operation.
+
+        The command of a running operation is interrupted, the operation is
+        cancelling until it exits.
+        """
+        response = self._delete(f"/1.0/operations/{id}")
+        return models.Operation(**response.get("metadata"))
+
+    def update_operation(
+        self,
+        id: int,
+        runner: str,
+        status: str = "",
+        progress: str = "",
+        result: str = "",
+        error: str = "",
+    ) -> models.Operation:
+        """Report on an operation as its runner.
+
+        Status running claims a pending operation, succeeded or failed
+        completes it, and no status only records the progress and heartbeat.
+        Returns the operation, cancelling if its command is to be interrupted.
+        """
+        update = {
+            "status": status,
+            "runner": runner,
+            "progress": progress,
+            "result": result,
+            "error": error,
+        }
+        response = self._put(f"/1.0/operations/{id}", data=json.dumps(update))
+        return models.Operation(**response.get("metadata"))
+
+    def backup(self, redact_secrets: bool = False) -> dict:
+        """Export a snapshot of the cluster database state.
+
+        Juju user tokens, storage backend configs and config items holding
+        credentials are replaced by a placeholder if redact_secrets is set,
+        such a backup cannot be restored.
+        """
+        params = {"redact_secrets": "true"} if redact_secrets else None
+        return self._get("/1.0/backup", params=params)
+
+    def restore(self, backup: dict) -> None:
+        """Replace the cluster database state with the one of a backup.
+
+        The state is replaced atomically. Raises InvalidBackupException if
+        the backup version is unsupported or its secrets were redacted, and
+        BackupSchemaMismatchException if it was taken with another database
+        schema version.
+        """
+        self._post("/1.0/restore", data=json.dumps(backup))
+
+    def verify_database(self) -> models.DatabaseReport:
+        """Run the integrity check of the cluster database.
+
+        The database is only read, the check is safe to run on a live cluster.
+        """
+        report = self._get("/1.0/database/verify")
+        return models.DatabaseReport(**report.get("metadata"))
+
+    def compact_database(self) -> models.DatabaseCompaction:
+        """Delete the records of the cluster database no longer needed."""
+        compaction = self._post("/1.0/database/compact")
+        return models.DatabaseCompaction(**compaction.get("metadata"))
+
 
 class ClusterService(MicroClusterService, ExtendedAPIService):
     """Lists and manages cluster."""
@@ -344,36 +923,67 @@ class ClusterService(MicroClusterService, ExtendedAPIService):
     JUJU_CONTROLLER_MIGRATE_KEY = "juju_controller_migrated_to_k8s"
 
     def bootstrap(
-        self, name: str, address: str, role: list[str], machineid: int = -1
+        self,
+        name: str,
+        address: str,
+        role: list[str],
+        machineid: int = -1,
+        addresses: dict[str, str] | None = None,
+        preferred_api: str | None = None,
     ) -> None:
         """Bootstrap cluster and register node information."""
         self.bootstrap_cluster(name, address)
-        self.add_node_info(name, role, machineid)
+        self.add_node_info(
+            name, role, machineid, addresses=addresses, preferred_api=preferred_api
+        )
 
-    def add_node(self, name: str) -> str:
-        """Request token for additional node."""
-        return self.generate_token(name)
+    def add_node(
+        self, name: str, expire_after: datetime.timedelta | None = None
+    ) -> str:
+        """Request token for additional node, expiring after expire_after."""
+        return self.generate_token(name, expire_after=expire_after)
 
-    def join_node(self, name: str, address: str, token: str, role: list[str]) -> None:
+    def join_node(
+        self,
+        name: str,
+        address: str,
+        token: str,
+        role: list[str],
+        addresses: dict[str, str] | None = None,
+        preferred_api: str | None = None,
+    ) -> None:
         """Join node to cluster and register node information."""
-        self.join(name, address, token)
-        self.add_node_info(name, role)
+        self.join(name, address, token, role)
+        self.add_node_info(
+            name, role, addresses=addresses, preferred_api=preferred_api
+        )
 
-    def remove_node(self, name) -> None:
+    def remove_node(self, name, force: bool = False) -> None:
         """Remove node from cluster and database.
 
-        If node is not part of cluster, remove its potential token.
+        If node is not part of cluster, remove its potential token, and the
+        record left by an interrupted removal. With force, the node is
+        removed from the cluster even if it is unreachable.
         """
         members = self.get_cluster_members()
         member_names = [member.get("name") for member in members]
 
+        # Cannot remove user as the same user name cannot be reused
+        # self.remove_juju_user(name)
+        try:
+            self.remove_node_info(name)
+        except service.NodeNotExistInClusterException:
+            LOG.debug("Node %s has no record in the cluster database", name)
+
         # If node is part of cluster, remove node from cluster
         if name in member_names:
-            # Cannot remove user as the same user name cannot be reused
-            # self.remove_juju_user(name)
-            self.remove_node_info(name)
-            self.remove(name)
+            self.remove(name, force=force)
         else:
+            # A valid scope would prevent scoping a new token for the name
+            try:
+                self.delete_join_token_scope(name)
+            except service.JoinTokenScopeNotFoundException:
+                LOG.debug("Join token of node %s is not scoped", name)
             # Check if token exists in token list and remove
             self.delete_token(name)
 
@@ -0,0 +1,94 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Compatibility of the CLI with the clusterd it talks to."""
+
+import logging
+
+from requests.exceptions import HTTPError
+
+from sunbeam.clusterd.cluster import ClusterService
+from sunbeam.clusterd.models import ClusterdMeta
+from sunbeam.clusterd.service import RemoteException, URLNotFoundException
+from sunbeam.errors import SunbeamException
+
+LOG = logging.getLogger(__name__)
+
+# Sunbeam database schema versions of clusterd, and what each brings. The CLI
+# relies on all of them, add an entry along with each schema extension.
+SCHEMA_FEATURES: dict[int, str] = {
+    1: "nodes",
+    2: "config",
+    3: "juju users",
+    4: "manifests",
+    5: "node system ids",
+    6: "storage backends",
+    7: "feature gates",
+    8: "config revisions",
+    9: "node labels",
+    10: "node tombstones",
+    11: "maintenance status",
+    12: "node inventory",
+    13: "maintenance expiry",
+    14: "config history",
+    15: "maintenance schedule",
+    16: "node cordon",
+    17: "maintenance plans",
+    18: "join token scopes",
+    19: "node addresses",
+    20: "operations",
+    21: "operation runners",
+    22: "maintenance disable operations",
+    23: "maintenance schedule operations",
+}
+EXPECTED_SCHEMA_VERSION = max(SCHEMA_FEATURES)
+# Extended API prefixes the CLI sends requests to
+REQUIRED_API_PREFIXES = ("1.0",)
+
+
+class IncompatibleClusterdException(SunbeamException):
+    """Raised when clusterd is too old for the CLI."""
+
+
+def compatibility_issues(meta: ClusterdMeta) -> list[str]:
+    """Return why the CLI cannot work with the clusterd described by meta."""
+    issues = []
+    missing = [
+        feature
+        for version, feature in sorted(SCHEMA_FEATURES.items())
+        if version > meta.schema_external
+    ]
+    if missing:
+        issues.append(
+            f"clusterd schema version {meta.schema_external} is older than"
+            f" {EXPECTED_SCHEMA_VERSION}, it lacks {', '.join(missing)}"
+        )
+    for prefix in REQUIRED_API_PREFIXES:
+        if prefix not in meta.api_prefixes:
+            issues.append(f"clusterd does not serve the /{prefix} API")
+    return issues
+
+
+def check_clusterd_compatibility(
+    cluster: ClusterService, force: bool = False
+) -> list[str]:
+    """Check clusterd is recent enough for the CLI, return warnings.
+
+    Raise IncompatibleClusterdException if it is not, unless force in which
+    case the issues are returned as warnings. The check is skipped when
+    clusterd cannot be reached, as before bootstrap. A clusterd predating
+    the meta endpoint is warned about only, its schema is unknown.
+    """
+    try:
+        meta = cluster.get_meta()
+    except URLNotFoundException:
+        return ["clusterd does not report its versions, its compatibility is unknown"]
+    except (RemoteException, HTTPError) as e:
+        LOG.debug(f"Skipping clusterd compatibility check: {e}")
+        return []
+
+    LOG.debug(f"clusterd meta: {meta.model_dump()}")
+    issues = compatibility_issues(meta)
+    if issues and not force:
+        raise IncompatibleClusterdException("; ".join(issues))
+    return issues
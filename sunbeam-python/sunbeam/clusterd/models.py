@@ -45,3 +45,352 @@ class FeatureGate(pydantic.BaseModel):
 
 class FeatureGates(pydantic.RootModel[list[FeatureGate]]):
     """Feature gates model."""
+
+
+class ConfigKeySchema(pydantic.BaseModel):
+    """Type of the value of a known config key."""
+
+    key: str
+    type: typing.Literal[
+        "string",
+        "bool",
+        "uint",
+        "cidr",
+        "ip",
+        "enum",
+        "port-range",
+        "duration",
+        "json",
+    ]
+    description: str = ""
+    values: list[str] = []
+
+
+class ConfigSchema(pydantic.RootModel[list[ConfigKeySchema]]):
+    """Known config keys."""
+
+
+class ConfigRevision(pydantic.BaseModel):
+    """Value a config key held, along with who wrote it and when."""
+
+    revision: int
+    value: str
+    changed_at: str
+    changed_by: str = ""
+
+
+class ConfigImportResult(pydantic.BaseModel):
+    """Config keys changed by a config import, sorted."""
+
+    created: list[str] = []
+    updated: list[str] = []
+    deleted: list[str] = []
+    # Keys holding credentials whose value was redacted, left as is
+    skipped: list[str] = []
+
+
+class DatabaseTable(pydantic.BaseModel):
+    """Table of the cluster database along with its row count."""
+
+    name: str
+    rows: int
+
+
+class DatabaseReport(pydantic.BaseModel):
+    """Integrity check report of the cluster database."""
+
+    ok: bool
+    problems: list[str] = []
+    tables: list[DatabaseTable] = []
+    page_size: int = 0
+    pages: int = 0
+    free_pages: int = 0
+
+
+class DatabaseCompaction(pydantic.BaseModel):
+    """Records deleted by the compaction of the cluster database."""
+
+    purged_revisions: int = 0
+    purged_tombstones: int = 0
+    free_pages: int = 0
+
+
+class MaintenanceStatus(pydantic.BaseModel):
+    """Maintenance status of a node."""
+
+    node: str
+    status: typing.Literal["enabled", "disabled", "degraded"]
+    entered_at: str = ""
+    strategy: str = ""
+    triggered_by: str = ""
+    message: str = ""
+    expires_at: str = ""
+
+
+class MaintenanceStatusList(pydantic.RootModel[list[MaintenanceStatus]]):
+    """Maintenance status of all the nodes."""
+
+
+class MaintenanceProgress(pydantic.BaseModel):
+    """Progress event of the instance migrations of a maintenance operation.
+
+    Events are numbered by clusterd, seq is 0 until they are posted. Percent
+    is only known for the live migrations Nova reports the progress of.
+    """
+
+    seq: int = 0
+    node: str = ""
+    time: str = ""
+    instance: str
+    state: typing.Literal["migrating", "succeeded", "failed", "stalled"]
+    completed: int
+    total: int
+    percent: int | None = None
+    message: str = ""
+
+
+class MaintenanceOptions(pydantic.BaseModel):
+    """Options of a maintenance enable, as given to the enable command."""
+
+    force: bool = False
+    skip_unmigratable: bool = False
+    stop_osds: bool = False
+    allow_downtime: bool = False
+    enable_ceph_crush_rebalancing: bool = False
+    disable_migration: str = ""
+    ttl: str = ""
+    max_parallel_migrations: int | None = None
+
+
+class ScheduledMaintenance(pydantic.BaseModel):
+    """Maintenance enable of a node deferred to a change window.
+
+    Clusterd requests an operation enabling it once the window opens, run by
+    `sunbeam operation agent`. It fails without running if the window closes
+    first. Window end is empty if the window has no end, operation is 0 until
+    the window opens.
+    """
+
+    id: int = 0
+    node: str
+    window_start: str
+    window_end: str = ""
+    options: MaintenanceOptions = MaintenanceOptions()
+    status: typing.Literal[
+        "pending", "running", "succeeded", "failed", "cancelled"
+    ] = "pending"
+    created_at: str = ""
+    created_by: str = ""
+    started_at: str = ""
+    finished_at: str = ""
+    message: str = ""
+    operation: int = 0
+
+
+class MaintenancePlanAction(pydantic.BaseModel):
+    """Action of the Watcher action plan of a maintenance operation."""
+
+    uuid: str
+    type: str
+    state: str = ""
+    parameters: dict[str, typing.Any] = {}
+    description: str = ""
+
+
+class MaintenancePlanIndicator(pydantic.BaseModel):
+    """Global efficacy indicator of a Watcher action plan."""
+
+    name: str
+    description: str = ""
+    value: float | None = None
+    unit: str = ""
+
+
+class MaintenancePlan(pydantic.BaseModel):
+    """Watcher audit planning a maintenance operation of a node.
+
+    Clusterd keeps the latest plan of each operation of a node. A plan which
+    timed out or failed, e.g. without a feasible action plan, gives its
+    reason.
+    """
+
+    node: str = ""
+    operation: typing.Literal["enable", "disable"]
+    audit: str = ""
+    action_plan: str = ""
+    state: typing.Literal["succeeded", "timed-out", "failed"]
+    reason: str = ""
+    actions: list[MaintenancePlanAction] = []
+    efficacy: list[MaintenancePlanIndicator] = []
+    created_at: str = ""
+    created_by: str = ""
+
+
+class HardwareNIC(pydantic.BaseModel):
+    """Network interface of a node."""
+
+    name: str
+    mac_address: str = ""
+    speed: int = 0
+
+
+class HardwareBlockDevice(pydantic.BaseModel):
+    """Disk of a node."""
+
+    name: str
+    model: str = ""
+    size: int
+    rotational: bool = False
+
+
+class Hardware(pydantic.BaseModel):
+    """Hardware reported by a node, memory and disk sizes are in bytes."""
+
+    cpu_count: int
+    memory_total: int
+    nics: list[HardwareNIC] = []
+    block_devices: list[HardwareBlockDevice] = []
+
+
+class NodeInventory(pydantic.BaseModel):
+    """Revision of the hardware inventory of a node.
+
+    The revision is increased each time the node reports different hardware.
+    """
+
+    node: str
+    revision: int
+    collected_at: str
+    refreshed_at: str
+    hardware: Hardware
+
+
+class NodeInventories(pydantic.RootModel[list[NodeInventory]]):
+    """Inventory revisions of a node, latest first."""
+
+
+class NodeBatchResult(pydantic.BaseModel):
+    """Outcome of a single operation of a node batch."""
+
+    action: typing.Literal["add", "remove"]
+    name: str
+    status: typing.Literal["applied", "failed", "rolled_back", "skipped"]
+    error: str = ""
+
+
+class NodeBatchResponse(pydantic.BaseModel):
+    """Outcome of a node batch.
+
+    The batch is applied atomically: applied is False if any operation
+    failed, in which case none of the operations were committed.
+    """
+
+    applied: bool
+    results: list[NodeBatchResult]
+
+
+class NodeRole(pydantic.BaseModel):
+    """Role a node can be assigned, built-in or defined in the cluster config."""
+
+    name: str
+    custom: bool = False
+
+
+class ClusterCertificateMember(pydantic.BaseModel):
+    """Cluster certificate served by a member after a rotation.
+
+    The certificate is new, old, or unknown if the member cannot be reached.
+    """
+
+    name: str
+    certificate: typing.Literal["new", "old", "unknown"]
+
+
+class ClusterCertificateRotation(pydantic.BaseModel):
+    """Outcome of a cluster certificate rotation.
+
+    The rotation halts on the first member failing to validate or serve the
+    new certificate, failed then names the member.
+    """
+
+    members: list[ClusterCertificateMember]
+    failed: str = ""
+    error: str = ""
+
+
+class Webhook(pydantic.BaseModel):
+    """Target the cluster events are posted to.
+
+    Only the event types listed are delivered, all of them if none. The
+    secret signing the deliveries is not listed back by clusterd.
+    """
+
+    name: str
+    url: str
+    secret: str | None = None
+    events: list[str] = []
+
+
+class ReadOnlyToken(pydantic.BaseModel):
+    """Token authorizing its bearer to read the non-secret cluster state.
+
+    The bearer presents it in an "Authorization: Bearer <token>" header,
+    clusterd only stores its hash and rejects any request but reading the
+    nodes, the status, the metadata and the metrics.
+    """
+
+    name: str
+    created_at: str
+
+
+class JoinTokenScope(pydantic.BaseModel):
+    """Restrictions of the join token issued for a node name.
+
+    The node can only join with the roles of the scope, once and before it
+    expires. State is one of valid, expired or consumed.
+    """
+
+    name: str
+    roles: list[str]
+    uses: int
+    used: int = 0
+    expires_at: str = ""
+    state: str = "valid"
+    created_at: str = ""
+    created_by: str = ""
+
+
+class Operation(pydantic.BaseModel):
+    """Sunbeam command recorded by clusterd, run in the background by a runner.
+
+    Member is the member the operation was created on, runner the sunbeam CLI
+    which claimed it. Progress is the latest line of output of the command,
+    result the end of its output once it exited. Error holds why the
+    operation failed or was cancelled.
+    """
+
+    id: int
+    command: list[str]
+    member: str
+    runner: str = ""
+    status: typing.Literal[
+        "pending", "running", "cancelling", "succeeded", "failed", "cancelled"
+    ]
+    progress: str = ""
+    result: str = ""
+    error: str = ""
+    created_at: str = ""
+    created_by: str = ""
+    started_at: str = ""
+    finished_at: str = ""
+    heartbeat_at: str = ""
+
+
+class ClusterdMeta(pydantic.BaseModel):
+    """Versions of clusterd, of its database schema, and the API it serves."""
+
+    version: str
+    schema_internal: int
+    schema_external: int
+    api_prefixes: list[str]
+    microcluster_version: str
@@ -2,13 +2,32 @@
 # SPDX-License-Identifier: Apache-2.0
 
 import logging
+import time
 from abc import ABC
+from collections.abc import Callable
 
 from requests.exceptions import ConnectionError, HTTPError
 from requests.sessions import Session
 
+from sunbeam.log import REQUEST_ID
+
 LOG = logging.getLogger(__name__)
 
+# Retries of the requests rejected by the clusterd rate limits, which are
+# safe to retry since they were not processed
+RATE_LIMIT_RETRIES = 3
+# Longest wait before retrying a rate limited request, in seconds
+RATE_LIMIT_MAX_WAIT = 5
+# Header correlating the requests with the clusterd log lines of their
+# handling
+REQUEST_ID_HEADER = "X-Request-ID"
+# Code of the errors returned while a majority of the database voters is
+# unreachable
+QUORUM_LOST_CODE = "cluster-quorum-lost"
+# Code of the errors returned for config transactions rejected by errors on
+# some of their keys
+CONFIG_TRANSACTION_FAILED_CODE = "config-transaction-failed"
+
 
 class RemoteException(Exception):
     """An Exception raised when interacting with the remote microclusterd service."""
@@ -28,6 +47,20 @@ class ClusterServiceUnavailableException(RemoteException):
     pass
 
 
+class ClusterQuorumLostException(RemoteException):
+    """Raised when a majority of the cluster database voters is unreachable."""
+
+    def __init__(self, message: str, expected: list[str], reachable: list[str]):
+        super().__init__(message)
+        self.expected = expected
+        self.reachable = reachable
+
+    @property
+    def unreachable(self) -> list[str]:
+        """Voters not reachable from the node answering."""
+        return [name for name in self.expected if name not in self.reachable]
+
+
 class ConfigItemNotFoundException(RemoteException):
     """Raise when ConfigItem cannot be found on the remote."""
 
@@ -100,9 +133,215 @@ class StorageBackendNotFoundException(StorageBackendException):
     """Raised when storage backend is not found."""
 
 
+class InvalidNodeRoleException(RemoteException):
+    """Raised when an unknown or invalid node role is requested."""
+
+    pass
+
+
+class InvalidNodeLabelException(RemoteException):
+    """Raised when a node label or label selector is invalid."""
+
+    pass
+
+
+class InvalidNodeAddressException(RemoteException):
+    """Raised when a node address or its preferred API address is invalid."""
+
+    pass
+
+
+class InvalidMaintenanceStatusException(RemoteException):
+    """Raised when a maintenance status or TTL is invalid."""
+
+    pass
+
+
+class InvalidScheduledMaintenanceException(RemoteException):
+    """Raised when a scheduled maintenance window or option is invalid."""
+
+    pass
+
+
+class ScheduledMaintenanceNotFoundException(RemoteException):
+    """Raised when a scheduled maintenance does not exist."""
+
+    pass
+
+
+class ScheduledMaintenanceConflictException(RemoteException):
+    """Raised when a node already has a pending scheduled maintenance.
+
+    Also raised when cancelling a scheduled maintenance which is not pending.
+    """
+
+    pass
+
+
+class NodeInventoryNotFoundException(RemoteException):
+    """Raised when no hardware inventory was reported for a node."""
+
+    pass
+
+
+class InvalidNodeInventoryException(RemoteException):
+    """Raised when a hardware inventory does not follow the schema."""
+
+    pass
+
+
+class InvalidConfigException(RemoteException):
+    """Raised when a config value does not match the schema of its key."""
+
+    pass
+
+
+class ConfigTransactionFailedException(RemoteException):
+    """Raised when a config transaction is rejected, none of its keys are set.
+
+    errors maps each rejected key to the reasons it was rejected, its value
+    being invalid or its precondition failing.
+    """
+
+    def __init__(self, message: str, errors: dict[str, list[str]]):
+        super().__init__(message)
+        self.errors = errors
+
+
+class ConfigRevisionNotFoundException(RemoteException):
+    """Raised when the revision of a config key to roll back to is not recorded."""
+
+    pass
+
+
+class InvalidBackupException(RemoteException):
+    """Raised when a backup bundle cannot be restored."""
+
+    pass
+
+
+class BackupSchemaMismatchException(InvalidBackupException):
+    """Raised when a backup was taken with another database schema version."""
+
+    pass
+
+
+class InvalidCertificateException(RemoteException):
+    """Raised when a cluster certificate is rejected."""
+
+    pass
+
+
+class InvalidWebhookException(RemoteException):
+    """Raised when a webhook has an invalid name, URL, secret or event."""
+
+    pass
+
+
+class WebhookAlreadyExistsException(RemoteException):
+    """Raised when adding a webhook whose name is used."""
+
+    pass
+
+
+class WebhookNotFoundException(RemoteException):
+    """Raised when a webhook is not defined."""
+
+    pass
+
+
+class InvalidReadOnlyTokenException(RemoteException):
+    """Raised when a read-only token has an invalid name."""
+
+    pass
+
+
+class ReadOnlyTokenAlreadyExistsException(RemoteException):
+    """Raised when creating a read-only token whose name is used."""
+
+    pass
+
+
+class ReadOnlyTokenNotFoundException(RemoteException):
+    """Raised when a read-only token does not exist."""
+
+    pass
+
+
+class InvalidJoinTokenScopeException(RemoteException):
+    """Raised when a join token scope has no roles, or invalid uses or TTL."""
+
+    pass
+
+
+class JoinTokenScopeAlreadyExistsException(RemoteException):
+    """Raised when scoping a join token whose scope is still valid."""
+
+    pass
+
+
+class JoinTokenScopeNotFoundException(RemoteException):
+    """Raised when a join token is not scoped."""
+
+    pass
+
+
+class JoinTokenRejectedException(RemoteException):
+    """Raised when a node joins with an expired or consumed join token.
+
+    Or with roles its join token does not permit.
+    """
+
+    pass
+
+
+class InvalidOperationException(RemoteException):
+    """Raised when an operation command cannot run in the background.
+
+    Or when the update reported by its runner is invalid.
+    """
+
+    pass
+
+
+class OperationNotFoundException(RemoteException):
+    """Raised when an operation does not exist."""
+
+    pass
+
+
+class OperationConflictException(RemoteException):
+    """Raised when an operation is not in a status allowing the request.
+
+    Such as cancelling an operation which already completed, or claiming one
+    another runner claimed.
+    """
+
+    pass
+
+
+class RateLimitedException(RemoteException):
+    """Raised when requests to clusterd are over its rate limits."""
+
+    pass
+
+
+def _retry_after(response) -> float:
+    """Seconds to wait before retrying a rate limited request."""
+    try:
+        wait = float(response.headers.get("Retry-After", 1))
+    except ValueError:
+        wait = 1
+    return min(max(wait, 0), RATE_LIMIT_MAX_WAIT)
+
+
 class BaseService(ABC):
     """BaseService is the base service class for sunbeam clusterd services."""
 
+    # Called with the service before its next request to clusterd, until it
+    # returns without raising, such as the compatibility check of the CLI
+    before_request: "Callable[[BaseService], None] | None" = None
+
     def __init__(
         self,
         session: Session,
@@ -135,20 +374,47 @@ class BaseService(ABC):
         self._timeout = timeout
 
     def _request(self, method, path, **kwargs):  # noqa: C901 too complex
+        hook = BaseService.before_request
+        if hook is not None:
+            # Unset while it runs, it may send requests itself
+            BaseService.before_request = None
+            try:
+                hook(self)
+            except BaseException:
+                BaseService.before_request = hook
+                raise
         if path.startswith("/"):
             path = path[1:]
         netloc = self._endpoint
         url = f"{netloc}/{path}"
+        redact_request = kwargs.pop("redact_request", False)
         redact_response = kwargs.pop("redact_response", False)
+        include_headers = kwargs.pop("include_headers", False)
+        kwargs["headers"] = {
+            REQUEST_ID_HEADER: REQUEST_ID,
+            **(kwargs.get("headers") or {}),
+        }
         try:
-            LOG.debug("[%s] %s, args=%s", method, url, kwargs)
-            response = self.__session.request(
-                method=method,
-                url=url,
-                cert=self._certs,
-                timeout=self._timeout,
-                **kwargs,
-            )
+            args = kwargs
+            if redact_request:
+                args = {**kwargs, "data": "/* REDACTED */"}
+            LOG.debug("[%s] %s, args=%s", method, url, args)
+            for attempt in range(RATE_LIMIT_RETRIES + 1):
+                response = self.__session.request(
+                    method=method,
+                    url=url,
+                    cert=self._certs,
+                    timeout=self._timeout,
+                    **kwargs,
+                )
+                if (
+                    response.status_code != 429  # Too Many Requests
+                    or attempt == RATE_LIMIT_RETRIES
+                ):
+                    break
+                wait = _retry_after(response)
+                LOG.debug("Rate limited by clusterd, retrying in %ss", wait)
+                time.sleep(wait)
             output = response.text
             if redact_response:
                 output = "/* REDACTED */"
@@ -166,11 +432,31 @@ class BaseService(ABC):
             response.raise_for_status()
         except HTTPError as e:
             # Do some nice translating to sunbeamdexceptions
-            error = response.json().get("error")
-            if "remote with name" in error:
+            body = response.json()
+            error = body.get("error")
+            metadata = body.get("metadata")
+            if isinstance(metadata, dict) and metadata.get("code") == QUORUM_LOST_CODE:
+                raise ClusterQuorumLostException(
+                    error,
+                    metadata.get("expected") or [],
+                    metadata.get("reachable") or [],
+                )
+            elif (
+                isinstance(metadata, dict)
+                and metadata.get("code") == CONFIG_TRANSACTION_FAILED_CODE
+            ):
+                errors: dict[str, list[str]] = {}
+                for key_error in metadata.get("errors") or []:
+                    errors.setdefault(key_error["key"], []).append(
+                        key_error["error"]
+                    )
+                raise ConfigTransactionFailedException(error, errors)
+            elif "remote with name" in error:
                 raise NodeAlreadyExistsException(
                     "Already node exists in the sunbeam cluster"
                 )
+            elif error.startswith("Node ") and error.endswith(" already exists"):
+                raise NodeAlreadyExistsException(error)
             elif "not found" == error:
                 raise URLNotFoundException("URL not found")
             elif "No remote exists with the given name" in error:
@@ -189,6 +475,11 @@ class BaseService(ABC):
                 raise TokenAlreadyGeneratedException(
                     "Token already generated for the node"
                 )
+            elif "Daemon is shutting down" in error:
+                raise ClusterServiceUnavailableException(
+                    "Sunbeam Cluster is shutting down, retry once clusterd"
+                    " restarted"
+                )
             elif "Database is not yet initialized" in error:
                 raise ClusterServiceUnavailableException(
                     "Sunbeam Cluster not initialized"
@@ -213,8 +504,104 @@ class BaseService(ABC):
                 raise ManifestItemNotFoundException("ManifestItem not found")
             elif "StorageBackend not found" in error:
                 raise StorageBackendNotFoundException("Storage backend not found")
+            elif (
+                "Unknown node role" in error
+                or "Invalid custom node role" in error
+                or "Custom node role" in error
+            ):
+                raise InvalidNodeRoleException(error)
+            elif "Invalid label" in error:
+                raise InvalidNodeLabelException(error)
+            elif (
+                "Invalid node address" in error
+                or "Invalid preferred API address" in error
+            ):
+                raise InvalidNodeAddressException(error)
+            elif (
+                "Unknown maintenance status" in error
+                or "Invalid maintenance TTL" in error
+                or "Maintenance TTL cannot be set" in error
+            ):
+                raise InvalidMaintenanceStatusException(error)
+            elif "Invalid scheduled maintenance" in error:
+                raise InvalidScheduledMaintenanceException(error)
+            elif error.startswith("Scheduled maintenance") and "not found" in error:
+                raise ScheduledMaintenanceNotFoundException(error)
+            elif (
+                "already has pending scheduled maintenance" in error
+                or "only pending ones can be cancelled" in error
+            ):
+                raise ScheduledMaintenanceConflictException(error)
+            elif "Node inventory not found" in error:
+                raise NodeInventoryNotFoundException("Node inventory not found")
+            elif "Invalid hardware inventory" in error:
+                raise InvalidNodeInventoryException(error)
+            elif (
+                "Invalid value for config key" in error
+                or "Unknown config key" in error
+                or "cannot be overridden per role" in error
+            ):
+                raise InvalidConfigException(error)
+            elif (
+                error.startswith("Revision") and "of config key" in error
+            ) or error.startswith("No previous revision of config key"):
+                raise ConfigRevisionNotFoundException(error)
+            elif "Backup schema version mismatch" in error:
+                raise BackupSchemaMismatchException(error)
+            elif (
+                "Unsupported backup version" in error
+                or "Cannot restore a backup with redacted secrets" in error
+            ):
+                raise InvalidBackupException(error)
+            elif (
+                "Invalid certificate" in error
+                or "Invalid intermediate certificate" in error
+                or "Certificate is only valid" in error
+                or "Certificate must be self-signed" in error
+                or "Certificate chain does not verify" in error
+                or "CA must be PEM encoded" in error
+            ):
+                raise InvalidCertificateException(error)
+            elif "Invalid webhook" in error:
+                raise InvalidWebhookException(error)
+            elif error.startswith("Webhook") and "already exists" in error:
+                raise WebhookAlreadyExistsException(error)
+            elif error.startswith("Webhook") and "not found" in error:
+                raise WebhookNotFoundException(error)
+            elif "Invalid read-only token" in error:
+                raise InvalidReadOnlyTokenException(error)
+            elif error.startswith("Read-only token") and "already exists" in error:
+                raise ReadOnlyTokenAlreadyExistsException(error)
+            elif error.startswith("Read-only token") and "not found" in error:
+                raise ReadOnlyTokenNotFoundException(error)
+            elif "Invalid join token" in error or (
+                error.startswith("Join token scope") and "must" in error
+            ):
+                raise InvalidJoinTokenScopeException(error)
+            elif "is already scoped" in error:
+                raise JoinTokenScopeAlreadyExistsException(error)
+            elif error.startswith("Join token scope") and "not found" in error:
+                raise JoinTokenScopeNotFoundException(error)
+            elif "Join token of node" in error:
+                raise JoinTokenRejectedException(error)
+            elif (
+                "Invalid operation" in error or "Invalid update of operation" in error
+            ):
+                raise InvalidOperationException(error)
+            elif error.startswith("Operation") and "not found" in error:
+                raise OperationNotFoundException(error)
+            elif error.startswith("Operation") and (
+                "only pending" in error
+                or "not running" in error
+                or "is run by" in error
+            ):
+                raise OperationConflictException(error)
+            elif error.startswith("Rate limit exceeded"):
+                raise RateLimitedException(error)
             raise e
 
+        if include_headers:
+            return response.json(), response.headers
         return response.json()
 
     def _get(self, path, **kwargs):
@@ -0,0 +1,82 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+import logging
+from pathlib import Path
+
+import click
+from rich.console import Console
+
+from sunbeam.clusterd.service import InvalidBackupException
+from sunbeam.core.deployment import Deployment
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+
+@click.command()
+@click.argument("output", type=click.Path(dir_okay=False, path_type=Path))
+@click.option(
+    "--redact-secrets",
+    is_flag=True,
+    default=False,
+    help="Replace credentials with a placeholder. "
+    "A backup with redacted secrets cannot be restored.",
+)
+@click.pass_context
+def backup(ctx: click.Context, output: Path, redact_secrets: bool):
+    """Save the cluster database state to OUTPUT.
+
+    The backup holds nodes, removed nodes, config, terraform states,
+    manifests, juju users, storage backends, feature gates and join token
+    scopes.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    with console.status("Fetching cluster state..."):
+        bundle = client.cluster.backup(redact_secrets=redact_secrets)
+
+    # The backup holds credentials, keep it readable by the owner only
+    output.touch(mode=0o600, exist_ok=True)
+    output.chmod(0o600)
+    output.write_text(json.dumps(bundle, indent=2))
+    console.print(f"Cluster state saved to {str(output)!r}")
+
+
+@click.command()
+@click.argument(
+    "backup_file", type=click.Path(exists=True, dir_okay=False, path_type=Path)
+)
+@click.option(
+    "--yes", is_flag=True, default=False, help="Do not ask for confirmation."
+)
+@click.pass_context
+def restore(ctx: click.Context, backup_file: Path, yes: bool):
+    """Replace the cluster database state with the one saved in BACKUP_FILE.
+
+    The state is replaced atomically, nothing is changed if the backup cannot
+    be restored, for instance when it was taken with another database schema
+    version. The pending operations, requested against the replaced state, are
+    dropped.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        bundle = json.loads(backup_file.read_text())
+    except json.JSONDecodeError as e:
+        raise click.ClickException(f"Invalid backup file: {e}") from e
+
+    if not yes:
+        click.confirm(
+            "All the current cluster state will be replaced,"
+            " are you sure you want to restore the backup?",
+            abort=True,
+        )
+
+    try:
+        with console.status("Restoring cluster state..."):
+            client.cluster.restore(bundle)
+    except InvalidBackupException as e:
+        raise click.ClickException(f"Failed to restore backup: {e}") from e
+    console.print(f"Cluster state restored from {str(backup_file)!r}")
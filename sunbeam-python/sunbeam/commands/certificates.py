@@ -0,0 +1,150 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import datetime
+import logging
+import typing
+from pathlib import Path
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.client import Client
+from sunbeam.clusterd.models import ClusterCertificateRotation
+from sunbeam.clusterd.service import InvalidCertificateException
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+from sunbeam.lazy import LazyImport
+
+if typing.TYPE_CHECKING:
+    import cryptography.hazmat.primitives as primitives
+    import cryptography.hazmat.primitives.asymmetric.ec as ec
+    import cryptography.x509 as x509
+    import cryptography.x509.oid as x509_oid
+else:
+    ec = LazyImport("cryptography.hazmat.primitives.asymmetric.ec")
+    primitives = LazyImport("cryptography.hazmat.primitives")
+    x509 = LazyImport("cryptography.x509")
+    x509_oid = LazyImport("cryptography.x509.oid")
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+# Validity of the generated cluster certificates, as microcluster ones
+CERTIFICATE_VALIDITY = datetime.timedelta(days=3650)
+
+
+def generate_cluster_certificate(name: str = "sunbeam") -> tuple[str, str]:
+    """Generate a self-signed cluster certificate and its key, PEM encoded."""
+    key = ec.generate_private_key(ec.SECP384R1())
+    subject = x509.Name([x509.NameAttribute(x509_oid.NameOID.COMMON_NAME, name)])
+    now = datetime.datetime.now(datetime.timezone.utc)
+    certificate = (
+        x509.CertificateBuilder()
+        .subject_name(subject)
+        .issuer_name(subject)
+        .public_key(key.public_key())
+        .serial_number(x509.random_serial_number())
+        .not_valid_before(now - datetime.timedelta(minutes=5))
+        .not_valid_after(now + CERTIFICATE_VALIDITY)
+        .add_extension(
+            x509.ExtendedKeyUsage(
+                [
+                    x509_oid.ExtendedKeyUsageOID.SERVER_AUTH,
+                    x509_oid.ExtendedKeyUsageOID.CLIENT_AUTH,
+                ]
+            ),
+            critical=False,
+        )
+        .sign(key, primitives.hashes.SHA384())
+    )
+    return (
+        certificate.public_bytes(primitives.serialization.Encoding.PEM).decode(),
+        key.private_bytes(
+            primitives.serialization.Encoding.PEM,
+            primitives.serialization.PrivateFormat.PKCS8,
+            primitives.serialization.NoEncryption(),
+        ).decode(),
+    )
+
+
+def print_rotation(rotation: ClusterCertificateRotation) -> None:
+    """Print the cluster certificate served by each member."""
+    table = Table()
+    table.add_column("Member", justify="left")
+    table.add_column("Certificate", justify="left")
+    for member in rotation.members:
+        table.add_row(member.name, member.certificate)
+    console.print(table)
+
+
+@click.group("cert")
+def cert():
+    """Manage the cluster certificate."""
+
+
+@cert.command("rotate")
+@click.option(
+    "--certificate",
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+    help="PEM encoded certificate, followed by its intermediate certificates.",
+)
+@click.option(
+    "--private-key",
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+    help="PEM encoded private key of the certificate.",
+)
+@click.option(
+    "--ca",
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+    help="PEM encoded CA validating the certificate. Defaults to the cluster CA.",
+)
+@click_option_format()
+@click.pass_context
+def rotate(
+    ctx: click.Context,
+    certificate: Path | None,
+    private_key: Path | None,
+    ca: Path | None,
+    format: str,
+) -> None:
+    """Rotate the certificate served by the cluster members.
+
+    A self-signed certificate is generated unless one is given. The
+    certificate chain is validated against the CA by all the members before
+    any of them switches. The members then switch in turn, if one fails the
+    rotation halts and reports the certificate served by each member: run
+    the rotation again once the failure is fixed.
+    """
+    deployment: Deployment = ctx.obj
+    client: Client = deployment.get_client()
+
+    if (certificate is None) != (private_key is None):
+        raise click.UsageError("--certificate and --private-key go together.")
+    if certificate is None or private_key is None:
+        if ca is not None:
+            raise click.UsageError("--ca requires --certificate and --private-key.")
+        LOG.debug("Generating self-signed cluster certificate")
+        certificate_pem, private_key_pem = generate_cluster_certificate()
+    else:
+        certificate_pem = certificate.read_text()
+        private_key_pem = private_key.read_text()
+
+    try:
+        rotation = client.cluster.rotate_cluster_certificate(
+            certificate_pem, private_key_pem, ca.read_text() if ca else None
+        )
+    except InvalidCertificateException as e:
+        raise click.ClickException(f"Certificate rejected: {e}")
+
+    if format == FORMAT_TABLE:
+        print_rotation(rotation)
+    else:
+        print_structured(console, rotation.model_dump(), format)
+
+    if rotation.failed:
+        raise click.ClickException(
+            f"Rotation halted on member {rotation.failed}: {rotation.error}"
+        )
@@ -0,0 +1,342 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+from typing import TextIO
+
+import click
+import yaml
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.service import (
+    ConfigItemNotFoundException,
+    ConfigRevisionNotFoundException,
+    ConfigTransactionFailedException,
+    InvalidConfigException,
+    InvalidNodeRoleException,
+)
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+# Values longer than this are truncated in the history table
+MAX_VALUE_WIDTH = 60
+
+
+@click.group("config")
+def config():
+    """Manage the cluster config and its history.
+
+    clusterd keeps the previous revisions of each config key, 10 by default,
+    set config.history-limit to change it. Every write records the client
+    that made it and when.
+
+    Most keys can be overridden for the nodes of a role with --role, the
+    override taking precedence over the global value for those nodes.
+
+    All the keys can be exported to a single document and imported back at
+    once with export and import.
+    """
+
+
+def _shorten(value: str) -> str:
+    if len(value) <= MAX_VALUE_WIDTH:
+        return value
+    return value[: MAX_VALUE_WIDTH - 3] + "..."
+
+
+def _parse_assignment(assignment: str) -> tuple[str, str]:
+    key, sep, value = assignment.partition("=")
+    if not sep or not key:
+        raise click.BadParameter(
+            f"{assignment!r} is not of the form KEY=VALUE", param_hint="KEY=VALUE"
+        )
+    return key, value
+
+
+@config.command("set")
+@click.argument("assignments", metavar="KEY=VALUE...", nargs=-1, required=True)
+@click.option(
+    "--atomic",
+    is_flag=True,
+    default=False,
+    help="Set all the keys at once, or none of them if any is rejected.",
+)
+@click.option(
+    "--expect",
+    "expectations",
+    metavar="KEY[=VALUE]",
+    multiple=True,
+    help="Only set the keys if KEY holds VALUE, or is unset if VALUE is omitted."
+    " Requires --atomic.",
+)
+@click.option(
+    "--strict",
+    is_flag=True,
+    default=False,
+    help="Reject the keys missing from the config schema.",
+)
+@click.option(
+    "--role",
+    help="Set the overrides of the keys for the nodes of this role.",
+)
+@click.pass_context
+def set_config(
+    ctx: click.Context,
+    assignments: tuple[str, ...],
+    atomic: bool,
+    expectations: tuple[str, ...],
+    strict: bool,
+    role: str | None,
+):
+    """Set config keys, each VALUE is stored as is.
+
+    The values of the keys known to the config schema are validated. Without
+    --atomic, the keys are set one after the other and the ones set before a
+    rejected key are kept. With --atomic, they are set in a single database
+    transaction: every rejected key is reported and none is set. With --role,
+    the expectations are checked against the overrides of the role.
+    """
+    if expectations and not atomic:
+        raise click.UsageError("--expect requires --atomic")
+    values = dict(_parse_assignment(assignment) for assignment in assignments)
+    preconditions: dict[str, str | None] = {}
+    for expectation in expectations:
+        key, sep, value = expectation.partition("=")
+        preconditions[key] = value if sep else None
+
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    if atomic:
+        try:
+            client.cluster.apply_config_transaction(
+                values, preconditions, strict, role
+            )
+        except InvalidNodeRoleException as e:
+            raise click.ClickException(str(e)) from e
+        except ConfigTransactionFailedException as e:
+            # The errors name their key
+            for _, errors in sorted(e.errors.items()):
+                for error in errors:
+                    console.print(error, markup=False)
+            raise click.ClickException(
+                f"Config transaction rejected, none of the {len(values)} keys"
+                " were set"
+            ) from e
+        console.print(f"Config keys set: {', '.join(values)}")
+        return
+
+    done = []
+    for key, value in values.items():
+        try:
+            client.cluster.update_config(key, value, strict, role)
+        except (InvalidConfigException, InvalidNodeRoleException) as e:
+            message = str(e)
+            if done:
+                message += f", keys already set: {', '.join(done)}"
+            raise click.ClickException(message) from e
+        done.append(key)
+    console.print(f"Config keys set: {', '.join(done)}")
+
+
+@config.command("get")
+@click.argument("key")
+@click.option(
+    "--role",
+    help="Get the value of the key for the nodes of this role.",
+)
+@click.pass_context
+def get_config(ctx: click.Context, key: str, role: str | None):
+    """Print the value of config KEY.
+
+    With --role, the override of the role is printed if set, the global value
+    otherwise, along with the scope the value comes from.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        if role:
+            value, scope = client.cluster.get_role_config(key, role)
+        else:
+            value, scope = client.cluster.get_config(key), None
+    except ConfigItemNotFoundException as e:
+        raise click.ClickException(f"Config key {key} not found") from e
+    except InvalidNodeRoleException as e:
+        raise click.ClickException(str(e)) from e
+    console.print(value, markup=False)
+    if scope:
+        console.print(f"Scope: {scope}", style="dim", markup=False)
+
+
+@config.command("unset")
+@click.argument("key")
+@click.option(
+    "--role",
+    help="Remove the override of the key for the nodes of this role.",
+)
+@click.pass_context
+def unset_config(ctx: click.Context, key: str, role: str | None):
+    """Remove config KEY, its history is kept.
+
+    With --role, only the override of the role is removed: the nodes of the
+    role get the global value again.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        client.cluster.delete_config(key, role)
+    except ConfigItemNotFoundException as e:
+        raise click.ClickException(f"Config key {key} not found") from e
+    except (InvalidConfigException, InvalidNodeRoleException) as e:
+        raise click.ClickException(str(e)) from e
+    if role:
+        console.print(f"Config key {key} override for role {role} removed")
+    else:
+        console.print(f"Config key {key} removed")
+
+
+@config.command("history")
+@click.argument("key")
+@click_option_format()
+@click.pass_context
+def history(ctx: click.Context, key: str, format: str):
+    """List the recorded revisions of config KEY, oldest first."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        revisions = client.cluster.get_config_history(key)
+    except ConfigItemNotFoundException as e:
+        raise click.ClickException(f"Config key {key} not found") from e
+    if format == FORMAT_TABLE:
+        table = Table()
+        table.add_column("Revision", justify="right")
+        table.add_column("Changed", justify="left")
+        table.add_column("By", justify="left")
+        table.add_column("Value", justify="left")
+        for revision in revisions:
+            table.add_row(
+                str(revision.revision),
+                revision.changed_at,
+                revision.changed_by,
+                _shorten(revision.value),
+            )
+        console.print(table)
+    else:
+        print_structured(
+            console, [revision.model_dump() for revision in revisions], format
+        )
+
+
+@config.command("rollback")
+@click.argument("key")
+@click.option(
+    "--revision",
+    type=click.IntRange(min=1),
+    help="Revision to restore, the one preceding the current value by default.",
+)
+@click.pass_context
+def rollback(ctx: click.Context, key: str, revision: int | None):
+    """Restore a previous value of config KEY.
+
+    The restored value is written as a new revision, later revisions are
+    kept in the history. A deleted key is restored to its last value.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        restored = client.cluster.rollback_config(key, revision)
+    except (ConfigRevisionNotFoundException, InvalidConfigException) as e:
+        raise click.ClickException(str(e)) from e
+    console.print(
+        f"Config key {key} rolled back, its value is now revision"
+        f" {restored.revision}"
+    )
+
+
+@config.command("export")
+@click.option(
+    "--redact-secrets",
+    is_flag=True,
+    default=False,
+    help="Replace the values of the keys holding credentials by a placeholder,"
+    " those keys are skipped on import.",
+)
+@click.pass_context
+def export_config(ctx: click.Context, redact_secrets: bool):
+    """Print all the config keys as a YAML document.
+
+    The overrides of the node roles are included, KEY@role:ROLE holding the
+    override of KEY for the nodes of ROLE. Each value is printed as stored,
+    the document can be edited and applied back with import.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    document = client.cluster.export_config(redact_secrets=redact_secrets)
+    # Printed as is, the values may hold console markup
+    click.echo(yaml.safe_dump(document, sort_keys=True), nl=False)
+
+
+def _load_config_document(file: TextIO) -> dict[str, str]:
+    try:
+        document = yaml.safe_load(file)
+    except yaml.YAMLError as e:
+        raise click.ClickException(f"Invalid config document: {e}") from e
+    config = document.get("config") if isinstance(document, dict) else None
+    if not isinstance(config, dict):
+        raise click.ClickException(
+            "Invalid config document, expected a config mapping as exported"
+        )
+    for key, value in config.items():
+        if not isinstance(value, str):
+            raise click.ClickException(
+                f"Invalid value of config key {key}, expected a string as exported"
+            )
+    return config
+
+
+@config.command("import")
+@click.argument("file", type=click.File("r"))
+@click.option(
+    "--prune",
+    is_flag=True,
+    default=False,
+    help="Delete the keys absent from the document.",
+)
+@click.pass_context
+def import_config(ctx: click.Context, file: TextIO, prune: bool):
+    """Apply a config document, as printed by export, all at once.
+
+    The keys whose value differs are set in a single database transaction:
+    every rejected key is reported and none is changed. The keys holding
+    credentials whose value was redacted by export are left as is. Use `-`
+    as FILE to read from stdin.
+    """
+    config = _load_config_document(file)
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        result = client.cluster.import_config(config, prune)
+    except ConfigTransactionFailedException as e:
+        # The errors name their key
+        for _, errors in sorted(e.errors.items()):
+            for error in errors:
+                console.print(error, markup=False)
+        raise click.ClickException(
+            "Config import rejected, none of the keys were changed"
+        ) from e
+
+    changes = [
+        ("Created", result.created),
+        ("Updated", result.updated),
+        ("Deleted", result.deleted),
+        ("Skipped redacted", result.skipped),
+    ]
+    if not any(keys for _, keys in changes[:3]):
+        console.print("Config unchanged")
+    for label, keys in changes:
+        if keys:
+            console.print(f"{label} keys: {', '.join(keys)}", markup=False)
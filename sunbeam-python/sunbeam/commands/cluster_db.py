@@ -0,0 +1,80 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+
+@click.group("db")
+def db():
+    """Check and maintain the cluster database."""
+
+
+@db.command("verify")
+@click_option_format()
+@click.pass_context
+def verify(ctx: click.Context, format: str):
+    """Run the integrity check of the cluster database.
+
+    The row count of each table is reported, along with the corruptions found.
+    The database is only read, the check is safe to run while the cluster is
+    in use. The command fails if the database is corrupted.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    with console.status("Checking the cluster database..."):
+        report = client.cluster.verify_database()
+
+    if format != FORMAT_TABLE:
+        print_structured(console, report.model_dump(), format)
+    else:
+        table = Table()
+        table.add_column("Table", justify="left")
+        table.add_column("Rows", justify="right")
+        for database_table in report.tables:
+            table.add_row(database_table.name, str(database_table.rows))
+        console.print(table)
+        console.print(
+            f"{report.pages} pages of {report.page_size} bytes,"
+            f" {report.free_pages} free"
+        )
+        for problem in report.problems:
+            console.print(problem, markup=False)
+
+    if not report.ok:
+        raise click.ClickException(
+            f"Cluster database corrupted, {len(report.problems)} problems found"
+        )
+    if format == FORMAT_TABLE:
+        console.print("Cluster database integrity check passed")
+
+
+@db.command("compact")
+@click.pass_context
+def compact(ctx: click.Context):
+    """Delete the records of the cluster database no longer needed.
+
+    The config revisions past config.history-limit and the removed nodes past
+    their retention are deleted, their space being reused by later writes.
+    The disk space of the database log is reclaimed by the database itself,
+    through its periodic snapshots.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    with console.status("Compacting the cluster database..."):
+        compaction = client.cluster.compact_database()
+    console.print(
+        f"Purged {compaction.purged_revisions} config revisions and"
+        f" {compaction.purged_tombstones} removed nodes,"
+        f" {compaction.free_pages} pages free"
+    )
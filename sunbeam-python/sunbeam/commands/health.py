@@ -0,0 +1,65 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+
+import click
+from rich.console import Console
+
+from sunbeam.clusterd.service import NodeNotExistInClusterException
+from sunbeam.core.checks import (
+    check_results_report,
+    evaluate_preflight_checks,
+    print_check_results,
+    raise_for_failed_checks,
+)
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.health import cluster_health_checks
+from sunbeam.core.juju import JujuHelper
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+HEALTHY = "healthy"
+DEGRADED = "degraded"
+
+
+@click.command("health")
+@click.option(
+    "--node",
+    type=str,
+    help="Only check the node, along with the services it depends on.",
+)
+@click_option_format()
+@click.pass_context
+def health(ctx: click.Context, node: str | None, format: str) -> None:
+    """Check the health of the cluster.
+
+    Check the cluster database quorum, the agent of each node, the juju
+    controller and the units of the core applications, and the OpenStack
+    API endpoints. The cluster is healthy when all the checks pass, the
+    command fails otherwise.
+    """
+    deployment: Deployment = ctx.obj
+    jhelper = JujuHelper(deployment.juju_controller)
+    try:
+        checks = cluster_health_checks(deployment, jhelper, node)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {node}: {e}") from e
+
+    results = evaluate_preflight_checks(checks, console)
+    verdict = HEALTHY if all(passed for _, passed in results) else DEGRADED
+
+    if format == FORMAT_TABLE:
+        print_check_results(console, results)
+        color = "green" if verdict == HEALTHY else "red"
+        console.print(f"Cluster is [{color}]{verdict}[/{color}]")
+    else:
+        report: dict = {"verdict": verdict, "checks": check_results_report(results)}
+        if node:
+            report["node"] = node
+        print_structured(console, report, format)
+
+    raise_for_failed_checks(results)
@@ -0,0 +1,69 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+
+import click
+from rich.console import Console
+
+from sunbeam.commands.node_cordon import IN_MAINTENANCE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.host_inventory import (
+    inventory_hosts,
+    render_ansible_ini,
+    render_ansible_yaml,
+    render_ssh_config,
+)
+from sunbeam.core.output import click_option_format
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+FORMAT_ANSIBLE = "ansible"
+FORMAT_ANSIBLE_INI = "ansible-ini"
+FORMAT_SSH_CONFIG = "ssh-config"
+
+RENDERERS = {
+    FORMAT_ANSIBLE: render_ansible_yaml,
+    FORMAT_ANSIBLE_INI: render_ansible_ini,
+    FORMAT_SSH_CONFIG: render_ssh_config,
+}
+
+
+@click.command("inventory")
+@click_option_format(
+    FORMAT_ANSIBLE, FORMAT_ANSIBLE_INI, FORMAT_SSH_CONFIG, default=FORMAT_ANSIBLE
+)
+@click.pass_context
+def inventory(ctx: click.Context, format: str) -> None:
+    """Export the cluster nodes as an Ansible inventory or SSH config.
+
+    The ansible format is a YAML inventory, ansible-ini an INI one: hosts are
+    grouped by role and by label, as label_<key>_<value>, and carry their
+    roles, labels, cordon and maintenance as sunbeam_* host variables. The
+    cordoned nodes and the nodes in maintenance are also in the cordoned
+    and maintenance groups, so that playbooks can skip them. The ssh-config
+    format renders them as Host stanzas, commented with the same details.
+
+    The output is sorted, so that it can be committed and diffed.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    nodes = client.cluster.list_nodes()
+    members = [
+        {"name": name, **member}
+        for name, member in client.cluster.get_status().items()
+    ]
+    in_maintenance = {
+        status.node
+        for status in client.cluster.list_maintenance_status().root
+        if status.status in IN_MAINTENANCE
+    }
+    hosts = inventory_hosts(nodes, members, in_maintenance)
+    console.print(
+        RENDERERS[format](hosts),
+        end="",
+        soft_wrap=True,
+        markup=False,
+        highlight=False,
+    )
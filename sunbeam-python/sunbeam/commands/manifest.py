@@ -1,14 +1,17 @@
 # SPDX-FileCopyrightText: 2024 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import json
 import logging
 import os
+import re
 import typing
 from pathlib import Path
 
 import click
 import yaml
 from rich.console import Console
+from rich.markup import escape
 from rich.table import Table
 
 from sunbeam.clusterd.service import (
@@ -18,6 +21,7 @@ from sunbeam.clusterd.service import (
 from sunbeam.core.common import FORMAT_TABLE, FORMAT_YAML
 from sunbeam.core.deployment import Deployment
 from sunbeam.core.manifest import Manifest, SoftwareConfig
+from sunbeam.core.output import click_option_format, print_structured
 
 if typing.TYPE_CHECKING:
     from sunbeam.features.interface.v1.base import BaseFeature
@@ -25,6 +29,24 @@ if typing.TYPE_CHECKING:
 LOG = logging.getLogger(__name__)
 console = Console()
 
+# Kinds of manifest diff entries
+CHARM_CHANNEL = "charm-channel"
+CONFIG_OVERRIDE = "config-override"
+MANIFEST_KEY = "key"
+DIFF_LABELS = {
+    "added": "[green]+ added[/green]",
+    "removed": "[red]- removed[/red]",
+    "modified": "[yellow]~ modified[/yellow]",
+}
+
+MASKED_VALUE = "<redacted>"
+# Keys holding secrets, their values are masked in manifest diffs
+SECRET_KEY_PATTERN = re.compile(
+    r"password|passwd|secret|token|credential|api[-_]?key|private[-_]?key|(^|[-_])key$"
+)
+# Credentials embedded in URLs, such as proxy URLs
+URL_CREDENTIALS_PATTERN = re.compile(r"(?P<prefix>\w[\w+.-]*://[^:/@\s]*:)[^@/\s]+@")
+
 
 def generate_software_manifest(
     software_config: SoftwareConfig, initial_indent: int = 2
@@ -201,3 +223,156 @@ def generate(
         raise click.ClickException(f"Manifest generation failed: {str(e)}")
 
     click.echo(f"Generated manifest is at {str(manifest_file)}")
+
+
+def _flatten(
+    data: dict, prefix: tuple[str, ...] = ()
+) -> dict[tuple[str, ...], typing.Any]:
+    """Return the leaf values of a manifest document, keyed by their path."""
+    items = {}
+    for key, value in data.items():
+        path = prefix + (str(key),)
+        if isinstance(value, dict) and value:
+            items.update(_flatten(value, path))
+        else:
+            items[path] = value
+    return items
+
+
+def _entry_kind(path: tuple[str, ...]) -> str:
+    """Return the kind of manifest entry at path."""
+    if "charms" in path[:-2]:
+        # <...>.charms.<charm>.channel or <...>.charms.<charm>.config.<option>
+        attribute = path[path.index("charms") + 2]
+        if attribute == "channel":
+            return CHARM_CHANNEL
+        if attribute == "config":
+            return CONFIG_OVERRIDE
+    return MANIFEST_KEY
+
+
+def _mask_url_credentials(value: typing.Any) -> typing.Any:
+    if isinstance(value, str):
+        return URL_CREDENTIALS_PATTERN.sub(rf"\g<prefix>{MASKED_VALUE}@", value)
+    if isinstance(value, list):
+        return [_mask_url_credentials(item) for item in value]
+    return value
+
+
+def _is_secret(path: tuple[str, ...]) -> bool:
+    """Whether the entry at path is a secret, or nested under one."""
+    for i, key in enumerate(path):
+        # Names of features and charms, such as secrets, are not keys
+        if i > 0 and path[i - 1] in ("features", "charms"):
+            continue
+        if SECRET_KEY_PATTERN.search(key.lower()):
+            return True
+    return False
+
+
+def _masked(path: tuple[str, ...], value: typing.Any) -> typing.Any:
+    """Return value with its secrets masked, unless unset."""
+    if _is_secret(path):
+        return MASKED_VALUE if value not in (None, "") else value
+    return _mask_url_credentials(value)
+
+
+def diff_manifests(applied: dict, updated: dict) -> dict[str, list[dict]]:
+    """Return the entries added, removed and modified by the updated manifest.
+
+    Entries are the leaf values of the manifests, sorted by key. Secrets are
+    compared before being masked, so that a changed secret is reported as
+    modified without leaking its values.
+    """
+    old = _flatten(applied)
+    new = _flatten(updated)
+    diff: dict[str, list[dict]] = {"added": [], "removed": [], "modified": []}
+    for path in sorted(old.keys() | new.keys()):
+        entry = {"key": ".".join(path), "kind": _entry_kind(path)}
+        if path not in old:
+            diff["added"].append({**entry, "value": _masked(path, new[path])})
+        elif path not in new:
+            diff["removed"].append({**entry, "value": _masked(path, old[path])})
+        elif old[path] != new[path]:
+            diff["modified"].append(
+                {
+                    **entry,
+                    "old": _masked(path, old[path]),
+                    "new": _masked(path, new[path]),
+                }
+            )
+    return diff
+
+
+def _format_value(value: typing.Any) -> str:
+    return escape(value if isinstance(value, str) else json.dumps(value))
+
+
+def _print_manifest_diff(diff: dict[str, list[dict]]):
+    entries = [
+        (entry["key"], change, entry)
+        for change, change_entries in diff.items()
+        for entry in change_entries
+    ]
+    if not entries:
+        console.print("No changes to the applied manifest")
+        return
+
+    table = Table()
+    table.add_column("Change", justify="left")
+    table.add_column("Key", justify="left", overflow="fold")
+    table.add_column("Kind", justify="left")
+    table.add_column("Applied", justify="left", overflow="fold")
+    table.add_column("New", justify="left", overflow="fold")
+    for key, change, entry in sorted(entries, key=lambda e: e[0]):
+        if change == "added":
+            old, new = "", _format_value(entry["value"])
+        elif change == "removed":
+            old, new = _format_value(entry["value"]), ""
+        else:
+            old, new = _format_value(entry["old"]), _format_value(entry["new"])
+        table.add_row(DIFF_LABELS[change], escape(key), entry["kind"], old, new)
+    console.print(table)
+
+
+@click.command()
+@click.argument(
+    "manifest_file",
+    metavar="FILE",
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+)
+@click_option_format()
+@click.pass_context
+def diff(ctx: click.Context, manifest_file: Path, format: str) -> None:
+    """Show the changes FILE would apply to the latest applied manifest.
+
+    Charm channels, config overrides and other keys added, removed or
+    modified are reported. Secrets are masked.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+
+    try:
+        with manifest_file.open() as file:
+            updated = yaml.safe_load(file) or {}
+    except (yaml.YAMLError, IOError) as e:
+        LOG.debug("Failed to load manifest", exc_info=True)
+        raise click.ClickException(f"Failed to load manifest: {str(e)}")
+    if not isinstance(updated, dict):
+        raise click.ClickException(f"{str(manifest_file)} is not a manifest")
+
+    try:
+        manifest = client.cluster.get_latest_manifest()
+        applied = yaml.safe_load(manifest.get("data") or "") or {}
+    except ManifestItemNotFoundException:
+        LOG.debug("No manifest applied, comparing to an empty manifest")
+        applied = {}
+    except ClusterServiceUnavailableException as e:
+        LOG.debug(e)
+        raise click.ClickException("Not able to connect to Cluster DB")
+
+    manifest_diff = diff_manifests(applied, updated)
+    if format == FORMAT_TABLE:
+        _print_manifest_diff(manifest_diff)
+    else:
+        print_structured(console, manifest_diff, format)
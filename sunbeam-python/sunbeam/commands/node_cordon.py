@@ -0,0 +1,105 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Cordon of the cluster nodes.
+
+A cordoned node is not selected for new instances, the ones running on it
+are left in place. Unlike maintenance, cordoning a node does not migrate its
+instances away.
+"""
+
+import logging
+
+import click
+from rich.console import Console
+
+from sunbeam.clusterd.service import NodeNotExistInClusterException
+from sunbeam.core.common import run_plan
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.juju import JujuHelper
+from sunbeam.steps.hypervisor import DisableHypervisorStep, EnableHypervisorStep
+from sunbeam.utils import click_option_show_hints
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+# Maintenance statuses in which the hypervisor of the node is disabled
+IN_MAINTENANCE = {"enabled", "degraded"}
+
+
+def in_maintenance(deployment: Deployment, name: str) -> bool:
+    """Whether the node is in maintenance."""
+    statuses = deployment.get_client().cluster.list_maintenance_status()
+    return any(
+        status.node == name and status.status in IN_MAINTENANCE
+        for status in statuses.root
+    )
+
+
+def set_node_cordon(
+    deployment: Deployment,
+    jhelper: JujuHelper,
+    name: str,
+    cordoned: bool,
+    show_hints: bool = False,
+) -> None:
+    """Cordon or uncordon a node.
+
+    The hypervisor of a compute node is disabled, or enabled, before the
+    cordon state is recorded, so a failure leaves the node as recorded. The
+    hypervisor of a node in maintenance is left disabled when uncordoning
+    it, exiting maintenance enables it.
+
+    Raises NodeNotExistInClusterException if the node does not exist.
+    """
+    client = deployment.get_client()
+    node = client.cluster.get_node_info(name)
+    if "compute" in (node.get("role") or []):
+        if cordoned:
+            step: EnableHypervisorStep | None = DisableHypervisorStep(
+                client, name, jhelper, deployment.openstack_machines_model
+            )
+        elif in_maintenance(deployment, name):
+            LOG.debug(f"Node {name} in maintenance, not enabling its hypervisor")
+            step = None
+        else:
+            step = EnableHypervisorStep(
+                client, name, jhelper, deployment.openstack_machines_model
+            )
+        if step is not None:
+            run_plan([step], console, show_hints)
+    client.cluster.set_node_cordon(name, cordoned)
+
+
+@click.command("cordon")
+@click.argument("name", type=str)
+@click_option_show_hints
+@click.pass_context
+def cordon(ctx: click.Context, name: str, show_hints: bool):
+    """Stop scheduling new instances on a node.
+
+    The instances running on the node are left in place. Use maintenance to
+    also migrate them away.
+    """
+    deployment: Deployment = ctx.obj
+    jhelper = JujuHelper(deployment.juju_controller)
+    try:
+        set_node_cordon(deployment, jhelper, name, True, show_hints)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {name!r} not found") from e
+    console.print(f"Node {name!r} cordoned")
+
+
+@click.command("uncordon")
+@click.argument("name", type=str)
+@click_option_show_hints
+@click.pass_context
+def uncordon(ctx: click.Context, name: str, show_hints: bool):
+    """Schedule new instances on a cordoned node again."""
+    deployment: Deployment = ctx.obj
+    jhelper = JujuHelper(deployment.juju_controller)
+    try:
+        set_node_cordon(deployment, jhelper, name, False, show_hints)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {name!r} not found") from e
+    console.print(f"Node {name!r} uncordoned")
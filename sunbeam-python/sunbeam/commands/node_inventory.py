@@ -0,0 +1,92 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.models import NodeInventory
+from sunbeam.clusterd.service import (
+    NodeInventoryNotFoundException,
+    NodeNotExistInClusterException,
+)
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+GIB = 1024**3
+
+
+def _print_inventory_table(inventory: NodeInventory):
+    hardware = inventory.hardware
+    console.print(
+        f"Node {inventory.node!r}, revision {inventory.revision}, collected at"
+        f" {inventory.collected_at}, refreshed at {inventory.refreshed_at}"
+    )
+    console.print(f"CPUs: {hardware.cpu_count}")
+    console.print(f"Memory: {hardware.memory_total / GIB:.1f} GiB")
+
+    nics = Table(title="Network interfaces")
+    nics.add_column("Name", justify="left")
+    nics.add_column("MAC address", justify="left")
+    nics.add_column("Speed (Mbit/s)", justify="right")
+    for nic in hardware.nics:
+        nics.add_row(nic.name, nic.mac_address, str(nic.speed) if nic.speed else "")
+    console.print(nics)
+
+    devices = Table(title="Block devices")
+    devices.add_column("Name", justify="left")
+    devices.add_column("Model", justify="left")
+    devices.add_column("Size (GiB)", justify="right")
+    devices.add_column("Rotational", justify="left")
+    for device in hardware.block_devices:
+        devices.add_row(
+            device.name,
+            device.model,
+            f"{device.size / GIB:.1f}",
+            "yes" if device.rotational else "no",
+        )
+    console.print(devices)
+
+
+@click.command("inventory")
+@click.argument("name", type=str)
+@click.option(
+    "--history",
+    is_flag=True,
+    default=False,
+    help="Show all the recorded revisions, latest first, to spot hardware drift.",
+)
+@click_option_format()
+@click.pass_context
+def inventory(ctx: click.Context, name: str, history: bool, format: str):
+    """Show the hardware inventory reported by a node.
+
+    A new revision of the inventory is recorded each time the node reports
+    different hardware.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        if history:
+            inventories = client.cluster.list_node_inventories(name).root
+        else:
+            inventories = [client.cluster.get_node_inventory(name)]
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {name!r} not found") from e
+    except NodeInventoryNotFoundException as e:
+        raise click.ClickException(
+            f"No hardware inventory reported for node {name!r}"
+        ) from e
+
+    if format == FORMAT_TABLE:
+        for node_inventory in inventories:
+            _print_inventory_table(node_inventory)
+        return
+    data = [inv.model_dump() for inv in inventories]
+    print_structured(console, data if history else data[0], format)
@@ -0,0 +1,115 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.service import (
+    InvalidNodeLabelException,
+    NodeNotExistInClusterException,
+)
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+
+def parse_labels(labels: tuple[str, ...]) -> dict[str, str]:
+    """Parse key=value labels given on the command line."""
+    parsed = {}
+    for label in labels:
+        key, sep, value = label.partition("=")
+        if not sep:
+            raise click.BadParameter(
+                f"{label!r} is not in key=value form", param_hint="LABELS"
+            )
+        parsed[key] = value
+    return parsed
+
+
+@click.group("node")
+def node():
+    """Manage cluster nodes."""
+
+
+@node.group("label")
+def label():
+    """Manage node labels."""
+
+
+@label.command("set")
+@click.argument("name", type=str)
+@click.argument("labels", nargs=-1, required=True)
+@click.option(
+    "--replace",
+    is_flag=True,
+    default=False,
+    help="Replace all the labels of the node instead of merging.",
+)
+@click.pass_context
+def set_labels(
+    ctx: click.Context, name: str, labels: tuple[str, ...], replace: bool
+):
+    """Set key=value labels on a node.
+
+    Labels are merged into the existing ones, use --replace to drop the
+    labels not given.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    parsed = parse_labels(labels)
+    try:
+        if replace:
+            client.cluster.set_node_labels(name, parsed)
+        else:
+            client.cluster.update_node_labels(name, parsed)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {name!r} not found") from e
+    except InvalidNodeLabelException as e:
+        raise click.ClickException(str(e)) from e
+    console.print(f"Labels set on node {name!r}")
+
+
+@label.command("get")
+@click.argument("name", type=str)
+@click.argument("key", type=str)
+@click.pass_context
+def get_label(ctx: click.Context, name: str, key: str):
+    """Get the value of a node label."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        labels = client.cluster.get_node_labels(name)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {name!r} not found") from e
+    if key not in labels:
+        raise click.ClickException(f"Label {key!r} not set on node {name!r}")
+    console.print(labels[key])
+
+
+@label.command("list")
+@click.argument("name", type=str)
+@click_option_format()
+@click.pass_context
+def list_labels(ctx: click.Context, name: str, format: str):
+    """List the labels of a node."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        labels = client.cluster.get_node_labels(name)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(f"Node {name!r} not found") from e
+    if format == FORMAT_TABLE:
+        table = Table()
+        table.add_column("Key", justify="left")
+        table.add_column("Value", justify="left")
+        for key in sorted(labels):
+            table.add_row(key, labels[key])
+        console.print(table)
+    else:
+        print_structured(console, labels, format)
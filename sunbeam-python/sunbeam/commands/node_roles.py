@@ -0,0 +1,87 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.service import InvalidNodeRoleException
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+
+@click.group("role")
+def role():
+    """Manage the custom roles nodes can be assigned.
+
+    Custom roles tag the nodes assigned them, no services are deployed for
+    them. Nodes are assigned custom roles along with the built-in ones, with
+    --role.
+    """
+
+
+def _custom_roles(deployment: Deployment) -> list[str]:
+    client = deployment.get_client()
+    return [role.name for role in client.cluster.list_roles() if role.custom]
+
+
+def _set_custom_roles(deployment: Deployment, roles: list[str]):
+    client = deployment.get_client()
+    try:
+        client.cluster.set_custom_roles(roles)
+    except InvalidNodeRoleException as e:
+        raise click.ClickException(str(e)) from e
+
+
+@role.command("list")
+@click_option_format()
+@click.pass_context
+def list_roles(ctx: click.Context, format: str):
+    """List the built-in and custom node roles."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    roles = client.cluster.list_roles()
+    if format == FORMAT_TABLE:
+        table = Table()
+        table.add_column("Role", justify="left")
+        table.add_column("Custom", justify="left")
+        for role_ in roles:
+            table.add_row(role_.name, "yes" if role_.custom else "no")
+        console.print(table)
+    else:
+        print_structured(console, [role_.model_dump() for role_ in roles], format)
+
+
+@role.command("add")
+@click.argument("names", nargs=-1, required=True)
+@click.pass_context
+def add_roles(ctx: click.Context, names: tuple[str, ...]):
+    """Define custom node roles."""
+    deployment: Deployment = ctx.obj
+    roles = _custom_roles(deployment)
+    roles.extend(name for name in dict.fromkeys(names) if name not in roles)
+    _set_custom_roles(deployment, roles)
+    console.print(f"Custom roles defined: {', '.join(roles)}")
+
+
+@role.command("remove")
+@click.argument("names", nargs=-1, required=True)
+@click.pass_context
+def remove_roles(ctx: click.Context, names: tuple[str, ...]):
+    """Remove custom node roles.
+
+    A role still assigned to nodes cannot be removed.
+    """
+    deployment: Deployment = ctx.obj
+    roles = _custom_roles(deployment)
+    unknown = [name for name in names if name not in roles]
+    if unknown:
+        raise click.ClickException(f"Unknown custom roles: {', '.join(unknown)}")
+    _set_custom_roles(deployment, [name for name in roles if name not in names])
+    console.print(f"Custom roles removed: {', '.join(names)}")
@@ -0,0 +1,491 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import datetime
+import functools
+import logging
+import os
+import queue
+import signal
+import socket
+import subprocess
+import sys
+import threading
+import time
+from pathlib import Path
+
+import click
+from rich.console import Console
+from rich.table import Table
+from snaphelpers import Snap
+
+from sunbeam.clusterd.models import Operation
+from sunbeam.clusterd.service import (
+    InvalidOperationException,
+    OperationConflictException,
+    OperationNotFoundException,
+    RemoteException,
+)
+from sunbeam.core.common import FORMAT_TABLE, parse_duration, validate_duration
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+# Statuses of the operations whose command will not run anymore
+COMPLETED_STATUSES = ("succeeded", "failed", "cancelled")
+# Seconds between two reports of a runner, well within the 5 minutes after
+# which clusterd fails the operations whose runner stopped reporting
+HEARTBEAT_INTERVAL = 30
+# Seconds between two progress reports of a runner
+PROGRESS_INTERVAL = 2
+# Seconds an interrupted command is given to stop before it is killed
+CANCEL_GRACE_PERIOD = 120
+# Characters of the end of the output of a command recorded as its result
+MAX_RESULT_LENGTH = 16 * 1024
+
+
+def detached_command(ctx: click.Context, argv: list[str]) -> list[str]:
+    """Return the arguments running the command of ctx, without --detach.
+
+    The arguments are the path of the command from the root group, followed
+    by the arguments the command was given in argv. The options of the groups
+    are dropped.
+    """
+    path = ctx.command_path.split()[1:]
+    index = 0
+    args = argv[1:]
+    for name in path:
+        index = args.index(name, index) + 1
+    return path + [arg for arg in args[index:] if arg != "--detach"]
+
+
+def logs_dir() -> Path:
+    """Directory of the logs of the commands run in the background."""
+    return Snap().paths.user_common / "logs"
+
+
+def spawn_detached(args: list[str], log_path: Path) -> int:
+    """Run the sunbeam command of args in a new session, returning its PID.
+
+    The command outlives the session running the CLI, such as an SSH session
+    which drops. Its output is written to log_path.
+    """
+    log_path.parent.mkdir(parents=True, exist_ok=True)
+    with log_path.open("ab") as log:
+        process = subprocess.Popen(
+            [sys.argv[0], *args],
+            stdin=subprocess.DEVNULL,
+            stdout=log,
+            stderr=subprocess.STDOUT,
+            start_new_session=True,
+        )
+    return process.pid
+
+
+def click_option_detach(func: click.decorators.FC) -> click.decorators.FC:
+    """Common decorator to run a command in the background with --detach.
+
+    The command is recorded by clusterd as an operation, and run by this CLI
+    in a new session with `sunbeam operation run`, so that it outlives the
+    session running the CLI with the credentials of the operator. Commands
+    asking for confirmation must be given --yes to detach.
+    """
+
+    @functools.wraps(func)
+    def wrapper(*args, detach: bool = False, **kwargs):
+        if not detach:
+            return func(*args, **kwargs)
+        ctx = click.get_current_context()
+        if "yes" in ctx.params and not ctx.params["yes"]:
+            raise click.UsageError(
+                "--detach requires --yes, a detached command cannot ask for"
+                " confirmation"
+            )
+        deployment: Deployment = ctx.obj
+        client = deployment.get_client()
+        try:
+            operation = client.cluster.create_operation(
+                detached_command(ctx, sys.argv)
+            )
+        except InvalidOperationException as e:
+            raise click.ClickException(str(e)) from e
+        log_path = logs_dir() / f"operation-{operation.id}.log"
+        spawn_detached(["operation", "run", str(operation.id)], log_path)
+        console.print(
+            f"Operation {operation.id} started, follow it with"
+            f" `sunbeam operation wait {operation.id}`"
+        )
+
+    return click.option(
+        "--detach",
+        is_flag=True,
+        default=False,
+        help="Run the command in the background, printing the ID of the"
+        " operation tracking it.",
+    )(wrapper)  # type: ignore[return-value]
+
+
+def click_option_detach_bootstrap(func: click.decorators.FC) -> click.decorators.FC:
+    """Decorator to run bootstrap in the background with --detach.
+
+    There is no cluster to record an operation in before bootstrap, the
+    command is run in a new session with its output written to a log file
+    instead. It cannot be tracked with `sunbeam operation`. A detached
+    bootstrap cannot prompt, it must be given --accept-defaults or --manifest.
+    """
+
+    @functools.wraps(func)
+    def wrapper(*args, detach: bool = False, **kwargs):
+        if not detach:
+            return func(*args, **kwargs)
+        ctx = click.get_current_context()
+        if not ctx.params.get("accept_defaults") and not ctx.params.get(
+            "manifest_path"
+        ):
+            raise click.UsageError(
+                "--detach requires --accept-defaults or --manifest, a detached"
+                " bootstrap cannot prompt"
+            )
+        timestamp = datetime.datetime.now().strftime("%Y%m%d-%H%M%S")
+        log_path = logs_dir() / f"bootstrap-{timestamp}.log"
+        pid = spawn_detached(detached_command(ctx, sys.argv), log_path)
+        console.print(
+            f"Bootstrap running in the background with PID {pid}, follow it"
+            f" with `tail -f {log_path}`"
+        )
+
+    return click.option(
+        "--detach",
+        is_flag=True,
+        default=False,
+        help="Run the bootstrap in the background, writing its output to a log"
+        " file.",
+    )(wrapper)  # type: ignore[return-value]
+
+
+def runner_name() -> str:
+    """Name of the runner of this process, reported to clusterd."""
+    return f"{socket.gethostname()}:{os.getpid()}"
+
+
+def _read_lines(stream, lines: queue.Queue) -> None:
+    """Put the lines read from stream in lines, then None once it closed."""
+    for line in stream:
+        lines.put(line)
+    lines.put(None)
+
+
+def _exit_error(returncode: int, last_line: str) -> str:
+    """Return the error of a command which exited with returncode."""
+    if returncode < 0:
+        return f"signal: {signal.Signals(-returncode).name}: {last_line}"
+    return f"exit status {returncode}: {last_line}"
+
+
+def run_operation(deployment: Deployment, id: int, runner: str) -> Operation:
+    """Claim the pending operation id and run its command as runner.
+
+    The progress of the command is reported to clusterd while it runs, along
+    with a heartbeat. Once the operation is cancelling, the command and the
+    processes it started are interrupted as with Ctrl+C, then killed if they
+    do not stop within the grace period. Returns the completed operation.
+    """
+    client = deployment.get_client()
+    operation = client.cluster.update_operation(id, runner, status="running")
+    LOG.debug("Running operation %d: %s", id, operation.command)
+
+    process = subprocess.Popen(
+        [sys.argv[0], *operation.command],
+        stdin=subprocess.DEVNULL,
+        stdout=subprocess.PIPE,
+        stderr=subprocess.STDOUT,
+        text=True,
+        errors="replace",
+        start_new_session=True,
+    )
+    lines: queue.Queue = queue.Queue()
+    threading.Thread(
+        target=_read_lines, args=(process.stdout, lines), daemon=True
+    ).start()
+
+    output = ""
+    progress = reported = ""
+    last_report = time.monotonic()
+    interrupted_at = None
+    while True:
+        try:
+            line = lines.get(timeout=1)
+        except queue.Empty:
+            line = ""
+        if line is None:
+            break
+        if line.strip():
+            progress = line.strip()
+        output = (output + line)[-MAX_RESULT_LENGTH:]
+
+        now = time.monotonic()
+        if (progress != reported and now - last_report >= PROGRESS_INTERVAL) or (
+            now - last_report >= HEARTBEAT_INTERVAL
+        ):
+            try:
+                operation = client.cluster.update_operation(
+                    id, runner, progress=progress
+                )
+            except RemoteException as e:
+                LOG.warning("Failed to report on operation %d: %s", id, e)
+            reported, last_report = progress, now
+
+        if operation.status == "cancelling":
+            if interrupted_at is None:
+                LOG.debug("Operation %d cancelling, interrupting its command", id)
+                os.killpg(process.pid, signal.SIGINT)
+                interrupted_at = now
+            elif now - interrupted_at >= CANCEL_GRACE_PERIOD:
+                LOG.debug("Operation %d did not stop, killing its command", id)
+                os.killpg(process.pid, signal.SIGKILL)
+
+    returncode = process.wait()
+    last_line = output.strip().rsplit("\n", 1)[-1] if output.strip() else ""
+    if returncode == 0:
+        status, error = "succeeded", ""
+    else:
+        status, error = "failed", _exit_error(returncode, last_line)
+    return client.cluster.update_operation(
+        id, runner, status=status, progress=progress, result=output, error=error
+    )
+
+
+def print_operation(operation: Operation) -> None:
+    """Print the details of an operation."""
+    console.print(f"Operation: {operation.id}")
+    console.print(f"Command: sunbeam {' '.join(operation.command)}", markup=False)
+    console.print(f"Member: {operation.member}")
+    if operation.runner:
+        console.print(f"Runner: {operation.runner}")
+    console.print(f"Status: {operation.status}")
+    console.print(f"Created: {operation.created_at} by {operation.created_by}")
+    if operation.started_at:
+        console.print(f"Started: {operation.started_at}")
+    if operation.finished_at:
+        console.print(f"Finished: {operation.finished_at}")
+    elif operation.heartbeat_at:
+        console.print(f"Last report: {operation.heartbeat_at}")
+    if operation.progress:
+        console.print(f"Progress: {operation.progress}", markup=False)
+    if operation.error:
+        console.print(f"Error: {operation.error}", markup=False)
+    if operation.result:
+        console.print("Result:")
+        console.print(operation.result.rstrip(), markup=False, highlight=False)
+
+
+@click.group("operation")
+def operation():
+    """Manage the commands run in the background with --detach.
+
+    Operations are recorded by clusterd and run by the sunbeam CLI of an
+    operator, which holds the credentials the commands need. Their status,
+    progress and result can be checked from any node while or after they run.
+    """
+
+
+@operation.command("list")
+@click_option_format()
+@click.pass_context
+def list_operations(ctx: click.Context, format: str):
+    """List the operations, including the completed ones."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    operations = client.cluster.list_operations()
+    if format == FORMAT_TABLE:
+        table = Table()
+        table.add_column("ID", justify="left")
+        table.add_column("Command", justify="left")
+        table.add_column("Runner", justify="left")
+        table.add_column("Status", justify="left")
+        table.add_column("Created", justify="left")
+        table.add_column("Progress", justify="left")
+        for item in operations:
+            table.add_row(
+                str(item.id),
+                " ".join(item.command),
+                item.runner,
+                item.status,
+                item.created_at,
+                item.progress,
+            )
+        console.print(table)
+    else:
+        print_structured(console, [item.model_dump() for item in operations], format)
+
+
+@operation.command("show")
+@click.argument("id", type=click.INT)
+@click_option_format()
+@click.pass_context
+def show(ctx: click.Context, id: int, format: str):
+    """Show an operation, with the output of its command once completed."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        item = client.cluster.get_operation(id)
+    except OperationNotFoundException as e:
+        raise click.ClickException(str(e)) from e
+    if format == FORMAT_TABLE:
+        print_operation(item)
+    else:
+        print_structured(console, item.model_dump(), format)
+
+
+@operation.command("wait")
+@click.argument("id", type=click.INT)
+@click.option(
+    "--timeout",
+    default="6h",
+    show_default=True,
+    callback=validate_duration,
+    help="How long to wait for, such as 20m or 1h.",
+)
+@click.option(
+    "--interval",
+    default="5s",
+    show_default=True,
+    callback=validate_duration,
+    help="How long to wait between two checks of the operation.",
+)
+@click.pass_context
+def wait(ctx: click.Context, id: int, timeout: str, interval: str):
+    """Wait for an operation to complete.
+
+    The progress of the operation is printed as it changes. The command fails
+    if the operation failed or was cancelled, or once the timeout elapses,
+    the operation carrying on.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    deadline = time.monotonic() + parse_duration(timeout).total_seconds()
+    pause = parse_duration(interval).total_seconds()
+
+    progress = ""
+    with console.status(f"Waiting for operation {id} ... ") as status:
+        while True:
+            try:
+                item = client.cluster.get_operation(id)
+            except OperationNotFoundException as e:
+                raise click.ClickException(str(e)) from e
+            if item.progress and item.progress != progress:
+                console.print(item.progress, markup=False, highlight=False)
+                progress = item.progress
+            if item.status in COMPLETED_STATUSES:
+                break
+            status.update(f"Waiting for operation {id}, {item.status} ... ")
+            if time.monotonic() + pause > deadline:
+                raise click.ClickException(
+                    f"Timed out after {timeout} waiting for operation {id},"
+                    f" it is {item.status}"
+                )
+            time.sleep(pause)
+
+    if item.status != "succeeded":
+        raise click.ClickException(
+            f"Operation {id} {item.status}: {item.error or 'no error recorded'}."
+            f" Run `sunbeam operation show {id}` for its output"
+        )
+    console.print(f"Operation {id} [green]succeeded[/green]")
+
+
+@operation.command("cancel")
+@click.argument("id", type=click.INT)
+@click.pass_context
+def cancel(ctx: click.Context, id: int):
+    """Cancel an operation.
+
+    A pending operation is cancelled at once. The command of a running one is
+    interrupted as with Ctrl+C, so that it stops where it supports it, and
+    killed if it does not stop within a grace period.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        item = client.cluster.cancel_operation(id)
+    except (OperationNotFoundException, OperationConflictException) as e:
+        raise click.ClickException(str(e)) from e
+    if item.status == "cancelling":
+        console.print(
+            f"Operation {id} is cancelling, its command is being interrupted"
+        )
+    else:
+        console.print(f"Operation {id} cancelled")
+
+
+@operation.command("run")
+@click.argument("id", type=click.INT)
+@click.pass_context
+def run(ctx: click.Context, id: int):
+    """Run a pending operation.
+
+    The operation is claimed, so that no other runner runs it, and its
+    command run until it exits, reporting its progress. This is run in the
+    background by --detach and `sunbeam operation agent`.
+    """
+    deployment: Deployment = ctx.obj
+    try:
+        item = run_operation(deployment, id, runner_name())
+    except (
+        InvalidOperationException,
+        OperationNotFoundException,
+        OperationConflictException,
+    ) as e:
+        raise click.ClickException(str(e)) from e
+    if item.status != "succeeded":
+        raise click.ClickException(f"Operation {id} {item.status}: {item.error}")
+    console.print(f"Operation {id} [green]succeeded[/green]")
+
+
+@operation.command("agent")
+@click.option(
+    "--interval",
+    default="30s",
+    show_default=True,
+    callback=validate_duration,
+    help="How long to wait between two checks of the pending operations.",
+)
+@click.pass_context
+def agent(ctx: click.Context, interval: str):
+    """Run the pending operations as they are requested, until interrupted.
+
+    The operations are run one after the other, including those clusterd
+    requests, such as disabling maintenance once its TTL elapsed. The
+    operation-agent service of the snap runs it with the deployment and
+    credentials of the user prepare-node set:
+
+        sudo snap set openstack operation-agent.user=$USER
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    pause = parse_duration(interval).total_seconds()
+    runner = runner_name()
+    console.print(f"Running the pending operations as {runner}")
+    while True:
+        try:
+            pending = [
+                item
+                for item in client.cluster.list_operations()
+                if item.status == "pending"
+            ]
+        except RemoteException as e:
+            LOG.warning("Failed to fetch the pending operations: %s", e)
+            pending = []
+        for item in sorted(pending, key=lambda item: item.id):
+            try:
+                done = run_operation(deployment, item.id, runner)
+            except OperationConflictException:
+                LOG.debug("Operation %d claimed by another runner", item.id)
+                continue
+            except RemoteException as e:
+                LOG.warning("Failed to run operation %d: %s", item.id, e)
+                continue
+            console.print(f"Operation {item.id} {done.status}", markup=False)
+        time.sleep(pause)
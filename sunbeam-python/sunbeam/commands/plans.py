@@ -68,10 +68,19 @@ def list_plans(ctx: click.Context, format: str):
 
 @plans.command("unlock")
 @click.argument("plan", type=str)
-@click.option("--force", is_flag=True, default=False, help="Force unlock the plan.")
+@click.option(
+    "--force",
+    is_flag=True,
+    default=False,
+    help="Do not ask for confirmation if the plan was locked recently.",
+)
 @click.pass_context
 def unlock_plan(ctx: click.Context, plan: str, force: bool):
-    """Unlock a terraform plan."""
+    """Unlock a terraform plan.
+
+    The lock is cleared only if it is still the one read, use force-unlock
+    for a lock left by a crashed process.
+    """
     deployment: Deployment = ctx.obj
     client = deployment.get_client()
     try:
@@ -97,6 +106,41 @@ def unlock_plan(ctx: click.Context, plan: str, force: bool):
     console.print(f"Unlocked plan {plan!r}")
 
 
+@plans.command("force-unlock")
+@click.argument("plan", type=str)
+@click.option(
+    "--yes", is_flag=True, default=False, help="Do not ask for confirmation."
+)
+@click.pass_context
+def force_unlock_plan(ctx: click.Context, plan: str, yes: bool):
+    """Force unlock a terraform plan left locked by a crashed process.
+
+    Shows the lock metadata and clears the lock regardless of its ID.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        lock = client.cluster.get_terraform_lock(plan)
+    except ConfigItemNotFoundException as e:
+        raise click.ClickException(f"Lock for {plan!r} not found") from e
+
+    table = Table(show_header=False)
+    table.add_column("Field", justify="left")
+    table.add_column("Value", justify="left")
+    for field in ("ID", "Who", "Operation", "Created", "Info"):
+        table.add_row(field, str(lock.get(field, "")))
+    console.print(table)
+
+    if not yes:
+        click.confirm(
+            f"Make sure the holder of the lock on {plan!r} is no longer running,"
+            " are you sure you want to force unlock it?",
+            abort=True,
+        )
+    client.cluster.force_unlock_terraform_plan(plan)
+    console.print(f"Unlocked plan {plan!r}")
+
+
 @plans.command("shell")
 @click.argument("plan", type=str, required=False)
 @click.pass_context
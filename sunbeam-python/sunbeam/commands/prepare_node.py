@@ -130,6 +130,10 @@ fi
 mkdir -p $HOME/.local/share
 mkdir -p $HOME/.config/openstack
 
+# Run the operations clusterd requests, such as disabling maintenance
+# once its TTL elapsed, with the credentials of this user
+sudo snap set openstack operation-agent.user=$USER
+
 # Check the snap channel and deduce risk level from it
 snap_output=$(snap list openstack --unicode=never --color=never | grep openstack)
 track=$(awk -v col=4 '{{print $col}}' <<<"$snap_output")
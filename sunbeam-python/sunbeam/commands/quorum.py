@@ -0,0 +1,211 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Status of the cluster database and recovery of its quorum.
+
+The cluster database is replicated by raft across its voters, and has no
+quorum while half or more of them are unreachable: clusterd then rejects the
+requests with a cluster-quorum-lost error listing the expected and reachable
+voters, rather than letting them time out.
+"""
+
+import logging
+import os
+import subprocess
+
+import click
+from rich.console import Console
+from rich.panel import Panel
+from rich.table import Table
+from snaphelpers import Snap
+
+from sunbeam import utils
+from sunbeam.clusterd.client import clusterd_state_dir
+from sunbeam.clusterd.service import ClusterQuorumLostException
+from sunbeam.core.checks import CLUSTERD_SERVICE
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured, rfc3339
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+QUORUM_OK = "ok"
+QUORUM_LOST = "lost"
+
+
+def quorum_recovery_steps(snap_name: str) -> list[str]:
+    """Steps to restore the quorum of the cluster database."""
+    return [
+        "Bring the unreachable voters back: the quorum is restored as soon as a"
+        " majority of them is reachable, with no further step. Check their"
+        f" cluster service with `sudo snap services {snap_name}.clusterd`.",
+        "Only if they are lost for good, stop the cluster service on every"
+        f" node: `sudo snap stop {snap_name}.clusterd`.",
+        "On the surviving node which was the last to be the database leader, run"
+        " `sudo sunbeam cluster recover --force-quorum`, adding"
+        " `--voter <name>` for each other surviving node.",
+        "Copy the recovery tarball it writes to the same path on the other"
+        " surviving nodes, then start their cluster service and its own with"
+        f" `sudo snap start {snap_name}.clusterd`.",
+        "Remove the lost nodes with `sunbeam cluster remove --force <name>`.",
+    ]
+
+
+def print_quorum_lost(console: Console, e: ClusterQuorumLostException) -> None:
+    """Print the voters unreachable and the steps to recover the quorum."""
+    snap_name = Snap().name
+    lines = [
+        f"[bold]{e}[/bold]",
+        "",
+        f"Reachable voters: {', '.join(e.reachable) or 'none'}",
+        f"Unreachable voters: {', '.join(e.unreachable) or 'none'}",
+        "",
+        "The cluster cannot be read nor modified until the quorum is restored:",
+    ]
+    lines.extend(
+        f"  {index}. {step}"
+        for index, step in enumerate(quorum_recovery_steps(snap_name), start=1)
+    )
+    console.print(
+        Panel(
+            "\n".join(lines),
+            title="Cluster quorum lost",
+            border_style="red",
+            expand=False,
+        )
+    )
+
+
+@click.command("status")
+@click_option_format()
+@click.pass_context
+def status(ctx: click.Context, format: str) -> None:
+    """Show the status of the cluster database members.
+
+    When a majority of the database voters is unreachable, the quorum loss is
+    reported along with the steps to recover it, and the command fails.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        members = client.cluster.get_status()
+    except ClusterQuorumLostException as e:
+        if format == FORMAT_TABLE:
+            print_quorum_lost(console, e)
+        else:
+            report = {
+                "quorum": QUORUM_LOST,
+                "expected": e.expected,
+                "reachable": e.reachable,
+                "recovery": quorum_recovery_steps(Snap().name),
+            }
+            print_structured(console, report, format)
+        raise click.ClickException("Cluster quorum lost") from e
+
+    if format != FORMAT_TABLE:
+        report = {
+            "quorum": QUORUM_OK,
+            "members": [
+                {
+                    "name": name,
+                    "address": member["address"],
+                    "status": member["status"],
+                    "last_heartbeat": rfc3339(member.get("last_heartbeat")),
+                }
+                for name, member in sorted(members.items())
+            ],
+        }
+        print_structured(console, report, format)
+        return
+
+    table = Table()
+    table.add_column("Member", justify="left")
+    table.add_column("Address", justify="left")
+    table.add_column("Status", justify="left")
+    table.add_column("Last heartbeat", justify="left")
+    for name, member in sorted(members.items()):
+        table.add_row(
+            name,
+            member["address"],
+            member["status"],
+            rfc3339(member.get("last_heartbeat")) or "",
+        )
+    console.print(table)
+
+
+@click.command("recover")
+@click.option(
+    "--force-quorum",
+    is_flag=True,
+    default=False,
+    help=(
+        "Reconfigure the cluster database so that the surviving nodes alone"
+        " form its quorum."
+    ),
+)
+@click.option(
+    "--voter",
+    multiple=True,
+    help="Another surviving node to keep as a database voter, can be repeated.",
+)
+@click.option(
+    "--name",
+    type=str,
+    help="Name of this node in the cluster, its FQDN by default.",
+)
+def recover(force_quorum: bool, voter: tuple[str, ...], name: str | None) -> None:
+    """Recover the cluster database quorum from this node.
+
+    Only for when the quorum is lost and the unreachable voters cannot be
+    brought back, see `sunbeam cluster status`. Run it once, as root, on the
+    surviving node which was the last to be the database leader, with the
+    cluster service stopped on every node. This node and the given voters
+    become the only database voters, the other nodes are to be removed from
+    the cluster once it is recovered.
+    """
+    if not force_quorum:
+        raise click.UsageError(
+            "Recovering the quorum reconfigures the cluster database membership,"
+            " confirm with --force-quorum."
+        )
+    if os.geteuid() != 0:
+        raise click.ClickException(
+            "Recovering the quorum must be run as root, with sudo."
+        )
+
+    snap = Snap()
+    clusterd = snap.services.list().get(CLUSTERD_SERVICE)
+    if clusterd is not None and clusterd.active:
+        raise click.ClickException(
+            "Stop the cluster service on every node first:\n"
+            "\n"
+            f"    sudo snap stop {snap.name}.clusterd"
+        )
+
+    cmd = [
+        str(snap.paths.snap / "bin" / "sunbeamd"),
+        "recover",
+        "--state-dir",
+        str(clusterd_state_dir(snap)),
+        "--force-quorum",
+        "--name",
+        name or utils.get_fqdn(),
+    ]
+    cmd.extend(f"--voter={node}" for node in voter)
+    LOG.debug("Running command %s", " ".join(cmd))
+    try:
+        process = subprocess.run(cmd, capture_output=True, text=True, check=True)
+    except subprocess.CalledProcessError as e:
+        LOG.debug("Quorum recovery failed: %s", e.stderr)
+        raise click.ClickException(
+            f"Failed to recover the cluster quorum: {e.stderr.strip()}"
+        ) from e
+
+    console.print(process.stdout.strip())
+    console.print(
+        "Before starting their cluster service, copy the recovery tarball to the"
+        " same path on the other surviving nodes. Then start the cluster service"
+        f" on every surviving node with `sudo snap start {snap.name}.clusterd`,"
+        " and remove the lost nodes with `sunbeam cluster remove --force <name>`."
+    )
@@ -6,9 +6,18 @@ import logging
 import click
 from click.core import ParameterSource
 from rich.console import Console
+from rich.table import Table
 
 from sunbeam.clusterd.client import Client
-from sunbeam.core.common import click_option_topology, run_plan
+from sunbeam.clusterd.models import NodeBatchResponse
+from sunbeam.core.common import (
+    CustomRole,
+    Role,
+    click_option_topology,
+    roles_to_str_list,
+    run_plan,
+    validate_node_roles,
+)
 from sunbeam.core.deployment import Deployment
 from sunbeam.core.juju import JujuHelper
 from sunbeam.core.terraform import TerraformInitStep
@@ -25,6 +34,41 @@ LOG = logging.getLogger(__name__)
 console = Console()
 
 
+def _split_names(
+    ctx: click.core.Context, param: click.core.Option, value: str | None
+) -> list[str]:
+    """Split a comma separated list of node names."""
+    if not value:
+        return []
+    return [name.strip() for name in value.split(",") if name.strip()]
+
+
+def node_batch_operations(
+    add: list[str], remove: list[str], roles: list[str]
+) -> list[dict]:
+    """Build the node batch operations adding and removing the given nodes."""
+    operations: list[dict] = [
+        {"action": "add", "name": name, "role": roles} for name in add
+    ]
+    operations.extend(
+        {"action": "remove", "name": name, "reason": "cluster resize"}
+        for name in remove
+    )
+    return operations
+
+
+def print_node_batch_results(response: NodeBatchResponse) -> None:
+    """Print the outcome of each operation of a node batch."""
+    table = Table()
+    table.add_column("Node", justify="left")
+    table.add_column("Action", justify="left")
+    table.add_column("Outcome", justify="left")
+    table.add_column("Error", justify="left")
+    for result in response.results:
+        table.add_row(result.name, result.action, result.status, result.error)
+    console.print(table)
+
+
 @click.command()
 @click_option_topology
 @click.option(
@@ -36,14 +80,56 @@ console = Console()
     ),
     is_flag=True,
 )
+@click.option(
+    "--add",
+    callback=_split_names,
+    help="Comma separated list of nodes to add to the cluster.",
+)
+@click.option(
+    "--remove",
+    callback=_split_names,
+    help="Comma separated list of nodes to remove from the cluster.",
+)
+@click.option(
+    "--role",
+    "roles",
+    multiple=True,
+    default=["compute"],
+    callback=validate_node_roles,
+    help="Roles assigned to the added nodes, built-in or custom roles defined "
+    "in the cluster. Defaults to the compute role. "
+    "Can be repeated and comma separated.",
+)
 @click_option_show_hints
 @click.pass_context
 def resize(
-    ctx: click.Context, topology: str, show_hints: bool, force: bool = False
+    ctx: click.Context,
+    topology: str,
+    show_hints: bool,
+    add: list[str],
+    remove: list[str],
+    roles: list[Role | CustomRole],
+    force: bool = False,
 ) -> None:
-    """Expand the control plane to fit available nodes."""
+    """Expand the control plane to fit available nodes.
+
+    Nodes given with --add and --remove are recorded in the cluster
+    atomically before resizing: if any of them cannot be added or removed,
+    none are and the resize is aborted.
+    """
     deployment: Deployment = ctx.obj
     client: Client = deployment.get_client()
+
+    if add or remove:
+        response = client.cluster.batch_nodes(
+            node_batch_operations(add, remove, roles_to_str_list(roles))
+        )
+        if not response.applied:
+            print_node_batch_results(response)
+            raise click.ClickException(
+                "Failed to resize cluster, no nodes were added or removed."
+            )
+
     manifest = deployment.get_manifest()
 
     openstack_tfhelper = deployment.get_tfhelper("openstack-plan")
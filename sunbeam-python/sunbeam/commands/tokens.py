@@ -0,0 +1,239 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+from typing import NamedTuple, Sequence
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.service import (
+    InvalidReadOnlyTokenException,
+    ReadOnlyTokenAlreadyExistsException,
+    ReadOnlyTokenNotFoundException,
+)
+from sunbeam.core.common import (
+    FORMAT_TABLE,
+    CustomRole,
+    Role,
+    validate_duration,
+    validate_node_roles,
+)
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+TOKEN_TYPE_JOIN = "join"
+TOKEN_TYPE_READ_ONLY = "read-only"
+TOKEN_STATE_VALID = "valid"
+TOKEN_STATE_EXPIRED = "expired"
+TOKEN_STATE_CONSUMED = "consumed"
+
+
+class JoinTokenScope(NamedTuple):
+    """Restrictions of the join tokens created for nodes."""
+
+    roles: list[Role | CustomRole]
+    ttl: str | None = None
+
+
+@click.group("token")
+def token():
+    """Manage the cluster tokens.
+
+    Join tokens are created by adding nodes to the cluster, or restricted to
+    roles and a TTL by `token create --role`. Join tokens are single-use.
+    Read-only tokens let observers, such as monitoring tools, read the nodes,
+    the cluster and maintenance status, the config, the metadata and the
+    metrics without a client certificate: clusterd rejects any other request
+    bearing them with 403, as well as reads of the config keys holding
+    credentials, so that they cannot read secrets. Present them in an
+    "Authorization: Bearer <token>" header.
+    """
+
+
+def _find_add_command(ctx: click.Context) -> click.Command | None:
+    """Return the command of the provider adding nodes, None if it has none.
+
+    It is a sibling of the token group in the cluster group.
+    """
+    token_ctx = ctx.parent
+    cluster_ctx = token_ctx.parent if token_ctx else None
+    if cluster_ctx is None or not isinstance(cluster_ctx.command, click.Group):
+        return None
+    return cluster_ctx.command.get_command(cluster_ctx, "add")
+
+
+@token.command("create")
+@click.argument("name")
+@click.option(
+    "--read-only",
+    is_flag=True,
+    default=False,
+    help="Create a token only authorized to read the cluster state.",
+)
+@click.option(
+    "--role",
+    "roles",
+    multiple=True,
+    callback=validate_node_roles,
+    help="Create a join token only adding the node NAME with these roles. "
+    "Can be repeated and comma separated.",
+)
+@click.option(
+    "--ttl",
+    callback=validate_duration,
+    help="Expire the join token after the duration, such as 1h or 30m.",
+)
+@click.pass_context
+def create_token(
+    ctx: click.Context,
+    name: str,
+    read_only: bool,
+    roles: Sequence[Role | CustomRole],
+    ttl: str | None,
+):
+    """Create a token named NAME.
+
+    Join tokens restricted with --role are created for the node NAME, as
+    by `sunbeam cluster add NAME --role ...`: clusterd rejects recording the
+    node with other roles, once the token expired or once it was used.
+    """
+    if not read_only:
+        if not roles:
+            raise click.UsageError(
+                "Pass --read-only to create a read-only token, or --role to "
+                "create a join token restricted to roles. Unrestricted join "
+                "tokens are created by `sunbeam cluster add`."
+            )
+        add = _find_add_command(ctx)
+        if add is None:
+            raise click.UsageError(
+                "Join tokens cannot be created, this deployment does not add "
+                "nodes with `sunbeam cluster add`."
+            )
+        ctx.invoke(add, names=(name,), roles=roles, ttl=ttl)
+        return
+
+    if roles or ttl:
+        raise click.UsageError("--role and --ttl only apply to join tokens.")
+
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        secret = client.cluster.create_read_only_token(name)
+    except (InvalidReadOnlyTokenException, ReadOnlyTokenAlreadyExistsException) as e:
+        raise click.ClickException(str(e)) from e
+    console.print(f"Read-only token {name} created, it cannot be retrieved later:")
+    console.print(secret)
+
+
+def _join_tokens(deployment: Deployment) -> list[dict]:
+    """Return the join tokens, along with their scope if restricted.
+
+    Tokens of scopes expired or consumed are no longer listed by clusterd,
+    their scope is listed in their stead.
+    """
+    client = deployment.get_client()
+    scopes = {scope.name: scope for scope in client.cluster.list_join_token_scopes()}
+    tokens = []
+    for record in client.cluster.list_tokens() or []:
+        name = record.get("name")
+        scope = scopes.pop(name, None)
+        tokens.append(
+            {
+                "name": name,
+                "type": TOKEN_TYPE_JOIN,
+                "created_at": scope.created_at if scope else None,
+                "state": scope.state if scope else TOKEN_STATE_VALID,
+                "roles": scope.roles if scope else None,
+                "expires_at": (scope.expires_at or None) if scope else None,
+            }
+        )
+    tokens.extend(
+        {
+            "name": scope.name,
+            "type": TOKEN_TYPE_JOIN,
+            "created_at": scope.created_at,
+            "state": scope.state,
+            "roles": scope.roles,
+            "expires_at": scope.expires_at or None,
+        }
+        for scope in scopes.values()
+    )
+    return tokens
+
+
+def _format_state(state: str) -> str:
+    if state == TOKEN_STATE_EXPIRED:
+        return f"[red]{state}[/red]"
+    if state == TOKEN_STATE_CONSUMED:
+        return f"[dim]{state}[/dim]"
+    return f"[green]{state}[/green]"
+
+
+@token.command("list")
+@click_option_format()
+@click.pass_context
+def list_tokens(ctx: click.Context, format: str):
+    """List the tokens, without the tokens themselves.
+
+    Join tokens restricted with --role are listed with their roles and
+    expiry, expired and consumed ones included.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    tokens = _join_tokens(deployment)
+    tokens.extend(
+        {
+            "type": TOKEN_TYPE_READ_ONLY,
+            **record.model_dump(),
+            "state": TOKEN_STATE_VALID,
+            "roles": None,
+            "expires_at": None,
+        }
+        for record in client.cluster.list_read_only_tokens()
+    )
+    if format == FORMAT_TABLE:
+        table = Table()
+        table.add_column("Name", justify="left")
+        table.add_column("Type", justify="left")
+        table.add_column("State", justify="left")
+        table.add_column("Roles", justify="left")
+        table.add_column("Expires", justify="left")
+        table.add_column("Created", justify="left")
+        for record in tokens:
+            token_type = record["type"]
+            if token_type == TOKEN_TYPE_READ_ONLY:
+                token_type = f"[yellow]{token_type}[/yellow]"
+            table.add_row(
+                record["name"],
+                token_type,
+                _format_state(record["state"]),
+                ", ".join(record["roles"] or []),
+                record["expires_at"] or "",
+                record["created_at"] or "",
+            )
+        console.print(table)
+    else:
+        print_structured(console, tokens, format)
+
+
+@token.command("remove")
+@click.argument("name")
+@click.pass_context
+def remove_token(ctx: click.Context, name: str):
+    """Revoke the read-only token NAME.
+
+    Join tokens are removed by `sunbeam cluster remove`.
+    """
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        client.cluster.delete_read_only_token(name)
+    except ReadOnlyTokenNotFoundException as e:
+        raise click.ClickException(str(e)) from e
+    console.print(f"Read-only token {name} revoked")
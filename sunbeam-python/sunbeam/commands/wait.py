@@ -0,0 +1,89 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+import time
+
+import click
+from rich.console import Console
+
+from sunbeam.core.checks import evaluate_preflight_checks, print_check_results
+from sunbeam.core.common import parse_duration, validate_duration
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.health import WAIT_CONDITIONS, WAIT_READY, wait_checks
+from sunbeam.core.juju import JujuHelper
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+
+@click.command("wait")
+@click.option(
+    "--for",
+    "conditions",
+    type=click.Choice(WAIT_CONDITIONS),
+    multiple=True,
+    default=[WAIT_READY],
+    show_default=True,
+    help="Condition to wait for, can be repeated. ready: the cluster is "
+    "healthy with all its units idle. quorum: the cluster database has "
+    "quorum. no-maintenance: no node is in maintenance mode.",
+)
+@click.option(
+    "--timeout",
+    default="20m",
+    show_default=True,
+    callback=validate_duration,
+    help="How long to wait for, such as 20m or 1h.",
+)
+@click.option(
+    "--interval",
+    default="10s",
+    show_default=True,
+    callback=validate_duration,
+    help="How long to wait between two attempts.",
+)
+@click.pass_context
+def wait(
+    ctx: click.Context, conditions: tuple[str, ...], timeout: str, interval: str
+) -> None:
+    """Wait for the cluster to meet conditions.
+
+    The checks of `sunbeam cluster health` covering the conditions are run
+    until they all pass, printing the checks still failing whenever they
+    change. The command fails once the timeout elapses, reporting the checks
+    which did not pass.
+    """
+    deployment: Deployment = ctx.obj
+    jhelper = JujuHelper(deployment.juju_controller)
+    awaited = ", ".join(conditions)
+    deadline = time.monotonic() + parse_duration(timeout).total_seconds()
+    pause = parse_duration(interval).total_seconds()
+    # The checks report their progress in the status of the wait
+    quiet = Console(quiet=True)
+
+    failed: list[str] | None = None
+    with console.status(f"Waiting for {awaited} ... ") as status:
+        while True:
+            checks = wait_checks(deployment, jhelper, conditions)
+            results = evaluate_preflight_checks(checks, quiet)
+            pending = [check.name for check, passed in results if not passed]
+            if not pending:
+                break
+            if pending != failed:
+                console.print(
+                    f"Waiting for {awaited}, {len(pending)} of {len(results)}"
+                    f" checks failing: {', '.join(pending)}"
+                )
+                failed = pending
+            status.update(
+                f"Waiting for {awaited}, {len(pending)} checks failing ... "
+            )
+            if time.monotonic() + pause > deadline:
+                print_check_results(console, results)
+                raise click.ClickException(
+                    f"Timed out after {timeout} waiting for {awaited}"
+                )
+            time.sleep(pause)
+
+    console.print(f"Conditions met: [green]{awaited}[/green]")
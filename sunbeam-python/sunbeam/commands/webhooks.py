@@ -0,0 +1,118 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import logging
+import secrets
+
+import click
+from rich.console import Console
+from rich.table import Table
+
+from sunbeam.clusterd.models import Webhook
+from sunbeam.clusterd.service import (
+    InvalidWebhookException,
+    WebhookAlreadyExistsException,
+    WebhookNotFoundException,
+)
+from sunbeam.core.common import FORMAT_TABLE
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.output import click_option_format, print_structured
+
+LOG = logging.getLogger(__name__)
+console = Console()
+
+# Event types clusterd posts to the webhooks
+EVENT_TYPES = [
+    "node.added",
+    "node.removed",
+    "maintenance.entered",
+    "maintenance.exited",
+    "bootstrap.completed",
+]
+
+
+@click.group("webhook")
+def webhook():
+    """Manage the webhooks the cluster events are posted to.
+
+    Events are posted as json, with an X-Sunbeam-Signature header holding
+    sha256=<hex HMAC-SHA256 of the body> keyed with the webhook secret, for
+    receivers to check deliveries are authentic. Delivery is best effort,
+    failed deliveries are retried a few times.
+    """
+
+
+@webhook.command("list")
+@click_option_format()
+@click.pass_context
+def list_webhooks(ctx: click.Context, format: str):
+    """List the webhooks."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    webhooks = client.cluster.list_webhooks()
+    if format == FORMAT_TABLE:
+        table = Table()
+        table.add_column("Name", justify="left")
+        table.add_column("URL", justify="left")
+        table.add_column("Events", justify="left")
+        for hook in webhooks:
+            table.add_row(hook.name, hook.url, ", ".join(hook.events) or "all")
+        console.print(table)
+    else:
+        print_structured(
+            console,
+            [hook.model_dump(exclude={"secret"}) for hook in webhooks],
+            format,
+        )
+
+
+@webhook.command("add")
+@click.argument("name")
+@click.argument("url")
+@click.option(
+    "--secret",
+    help="Secret signing the deliveries, generated if not given.",
+)
+@click.option(
+    "--event",
+    "events",
+    multiple=True,
+    type=click.Choice(EVENT_TYPES),
+    help="Event type to deliver, can be repeated. All of them if not given.",
+)
+@click.pass_context
+def add_webhook(
+    ctx: click.Context,
+    name: str,
+    url: str,
+    secret: str | None,
+    events: tuple[str, ...],
+):
+    """Add a webhook posting the cluster events to URL."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    generated = secret is None
+    if generated:
+        secret = secrets.token_hex(32)
+    hook = Webhook(name=name, url=url, secret=secret, events=list(events))
+    try:
+        client.cluster.add_webhook(hook)
+    except (InvalidWebhookException, WebhookAlreadyExistsException) as e:
+        raise click.ClickException(str(e)) from e
+    console.print(f"Webhook {name} added")
+    if generated:
+        console.print(f"Deliveries are signed with secret: {secret}")
+
+
+@webhook.command("remove")
+@click.argument("name")
+@click.pass_context
+def remove_webhook(ctx: click.Context, name: str):
+    """Remove a webhook."""
+    deployment: Deployment = ctx.obj
+    client = deployment.get_client()
+    try:
+        client.cluster.remove_webhook(name)
+    except WebhookNotFoundException as e:
+        raise click.ClickException(str(e)) from e
+    console.print(f"Webhook {name} removed")
@@ -2,20 +2,28 @@
 # SPDX-License-Identifier: Apache-2.0
 
 import base64
+import datetime
+import email.utils
 import enum
 import grp
 import json
 import logging
 import os
 import re
+import shutil
+import typing
+import warnings
 from pathlib import Path
 from typing import Sequence
 
 import click
+import requests
 from rich.console import Console
+from rich.table import Table
 from snaphelpers import Snap, SnapCtl
 
-from sunbeam.clusterd.client import Client
+from sunbeam import utils
+from sunbeam.clusterd.client import Client, clusterd_state_dir
 from sunbeam.clusterd.service import ClusterServiceUnavailableException
 from sunbeam.core.common import (
     RAM_16_GB_IN_KB,
@@ -23,10 +31,18 @@ from sunbeam.core.common import (
     get_host_total_ram,
 )
 from sunbeam.core.juju import JujuStepHelper
+from sunbeam.core.offline import resolve_offline_artifacts
+
+if typing.TYPE_CHECKING:
+    from sunbeam.core.manifest import Manifest
 
 LOG = logging.getLogger(__name__)
 
 CLUSTERD_SERVICE = "clusterd"
+# Free disk space needed by a node joining the cluster
+MIN_FREE_DISK_SPACE = 10 * 1024**3
+# Maximum difference in seconds between the clocks of the cluster nodes
+MAX_CLOCK_SKEW = 10
 
 
 def run_preflight_checks(checks: Sequence["Check"], console: Console):
@@ -45,6 +61,62 @@ def run_preflight_checks(checks: Sequence["Check"], console: Console):
                 raise click.ClickException(check.message)
 
 
+def evaluate_preflight_checks(
+    checks: Sequence["Check"], console: Console
+) -> list[tuple["Check", bool]]:
+    """Run all the preflight checks, without stopping at the first failure.
+
+    Returns each check along with whether it passed. A check raising an
+    error fails, with the error as message.
+    """
+    results = []
+    for check in checks:
+        LOG.debug(f"Starting pre-flight check {check.name}")
+        with console.status(f"{check.description} ... "):
+            try:
+                passed = check.run()
+            except Exception as e:
+                LOG.debug(f"Pre-flight check {check.name} failed", exc_info=True)
+                check.message = str(e)
+                passed = False
+        results.append((check, passed))
+    return results
+
+
+def print_check_results(
+    console: Console, results: Sequence[tuple["Check", bool]]
+) -> None:
+    """Print a table of the result and message of each check."""
+    table = Table()
+    table.add_column("Check", justify="left")
+    table.add_column("Result", justify="left")
+    table.add_column("Message", justify="left")
+    for check, passed in results:
+        table.add_row(
+            check.name,
+            "[green]pass[/green]" if passed else "[red]fail[/red]",
+            check.message,
+        )
+    console.print(table)
+
+
+def check_results_report(results: Sequence[tuple["Check", bool]]) -> list[dict]:
+    """Return the result and message of each check, for structured output."""
+    return [
+        {"name": check.name, "passed": passed, "message": check.message}
+        for check, passed in results
+    ]
+
+
+def raise_for_failed_checks(results: Sequence[tuple["Check", bool]]) -> None:
+    """Raise a ClickException naming the failed checks, if any."""
+    failed = [check.name for check, passed in results if not passed]
+    if failed:
+        raise click.ClickException(
+            f"{len(failed)} of {len(results)} checks failed: {', '.join(failed)}"
+        )
+
+
 class Check:
     """Base class for Pre-flight checks.
 
@@ -193,6 +265,29 @@ class JujuSnapCheck(Check):
         return True
 
 
+class OfflineArtifactsCheck(Check):
+    """Check the deployment artifacts resolve from local sources."""
+
+    def __init__(self, manifest: "Manifest"):
+        super().__init__(
+            "Check for offline artifacts",
+            "Checking artifacts resolve from local sources",
+        )
+        self.manifest = manifest
+
+    def run(self) -> bool:
+        """Resolve the artifacts, listing the ones not found locally."""
+        unresolved = resolve_offline_artifacts(self.manifest)
+        if unresolved:
+            self.message = (
+                f"{len(unresolved)} artifacts could not be resolved locally:\n  - "
+                + "\n  - ".join(unresolved)
+            )
+            return False
+
+        return True
+
+
 class SshKeysConnectedCheck(Check):
     """Check if ssh-keys interface is connected or not."""
 
@@ -229,7 +324,7 @@ class DaemonGroupCheck(Check):
 
         self.user = os.environ.get("USER")
         self.group = self.snap.config.get("daemon.group")
-        self.clusterd_socket = Path(self.snap.paths.common / "state" / "control.socket")
+        self.clusterd_socket = clusterd_state_dir(self.snap) / "control.socket"
 
         super().__init__(
             "Check for snap_daemon group membership",
@@ -540,6 +635,143 @@ class TokenCheck(Check):
         return True
 
 
+def _token_join_addresses(token: str) -> list[str]:
+    """Return the join addresses in a join token, none if it is invalid."""
+    try:
+        join_addresses = json.loads(base64.b64decode(token))["join_addresses"]
+    except Exception:
+        LOG.debug("Failed to decode join token", exc_info=True)
+        return []
+    if not isinstance(join_addresses, list):
+        return []
+    return join_addresses
+
+
+class ClusterConnectivityCheck(Check):
+    """Check if the cluster is reachable from this node."""
+
+    def __init__(self, token: str):
+        super().__init__(
+            "Check for cluster connectivity",
+            "Checking if the cluster is reachable",
+        )
+        self.token = token
+
+    def run(self) -> bool:
+        """Return false if none of the join token addresses is reachable."""
+        join_addresses = _token_join_addresses(self.token)
+        if not join_addresses:
+            self.message = "Join token does not contain valid join addresses"
+            return False
+
+        if utils.first_connected_server(join_addresses) is None:
+            self.message = (
+                "Cluster not reachable on any of the join addresses: "
+                + ", ".join(join_addresses)
+            )
+            return False
+
+        return True
+
+
+class ClockSkewCheck(Check):
+    """Check if the clock of this node is in sync with the cluster."""
+
+    def __init__(self, token: str, max_skew: int = MAX_CLOCK_SKEW):
+        super().__init__(
+            "Check for clock skew",
+            "Checking if the clock is in sync with the cluster",
+        )
+        self.token = token
+        self.max_skew = max_skew
+
+    def _cluster_time(self, address: str) -> datetime.datetime | None:
+        """Return the time cluster member at address responds with."""
+        with warnings.catch_warnings():
+            # The clock is compared, not the member identity
+            warnings.simplefilter("ignore")
+            response = requests.head(f"https://{address}/", verify=False, timeout=30)
+        date = response.headers.get("Date")
+        if not date:
+            return None
+        return email.utils.parsedate_to_datetime(date)
+
+    def run(self) -> bool:
+        """Return false if the clock differs too much from a cluster member.
+
+        Checks:
+            - Time in the response of the first reachable join address does
+              not differ from the local time by more than max_skew seconds
+        """
+        address = utils.first_connected_server(_token_join_addresses(self.token))
+        if address is None:
+            self.message = "Cluster not reachable, cannot compare clocks"
+            return False
+
+        cluster_time = self._cluster_time(address)
+        if cluster_time is None:
+            self.message = f"Cluster member {address} did not report its time"
+            return False
+
+        now = datetime.datetime.now(datetime.timezone.utc)
+        skew = abs((now - cluster_time).total_seconds())
+        if skew > self.max_skew:
+            self.message = (
+                f"Clock is {skew:.0f}s off the cluster member {address}, "
+                f"more than {self.max_skew}s: check the time synchronization"
+            )
+            return False
+
+        return True
+
+
+class DiskSpaceCheck(Check):
+    """Check if there is enough free disk space."""
+
+    def __init__(self, path: Path | None = None, min_free: int = MIN_FREE_DISK_SPACE):
+        self.path = path or Snap().paths.common
+        self.min_free = min_free
+        super().__init__(
+            "Check for free disk space",
+            f"Checking for {min_free // 1024**3}G of free disk space",
+        )
+
+    def run(self) -> bool:
+        """Return false if the filesystem of path has not enough free space."""
+        free = shutil.disk_usage(self.path).free
+        if free < self.min_free:
+            self.message = (
+                f"Not enough free disk space on {self.path}: "
+                f"{free // 1024**3}G free, {self.min_free // 1024**3}G needed"
+            )
+            return False
+
+        return True
+
+
+class VerifyLocalNodeCheck(Check):
+    """Check if a node is the local host."""
+
+    def __init__(self, name: str):
+        super().__init__(
+            "Check for local node",
+            f"Checking if {name} is this host",
+        )
+        self.node = name
+
+    def run(self) -> bool:
+        """Return false if name is not the FQDN of this host."""
+        fqdn = utils.get_fqdn()
+        if self.node.rstrip(".") != fqdn:
+            self.message = (
+                f"{self.node} is not this host ({fqdn}), "
+                "run the checks on the node to add"
+            )
+            return False
+
+        return True
+
+
 class JujuControllerRegistrationCheck(Check):
     """Check if juju controller is registered or not."""
 
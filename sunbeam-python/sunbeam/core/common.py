@@ -1,12 +1,14 @@
 # SPDX-FileCopyrightText: 2023 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import datetime
 import enum
 import ipaddress
 import json
 import logging
 import os
 import queue
+import re
 import threading
 import typing
 from pathlib import Path
@@ -135,6 +137,46 @@ class Role(enum.Enum):
         return [role.name.lower() for role in cls if _is_role_enabled(role)]
 
 
+# Valid names of the custom roles, as enforced by clusterd
+CUSTOM_ROLE_PATTERN = re.compile(r"^[a-z][a-z0-9_-]{0,62}$")
+DURATION_REGEX = re.compile(r"^(\d+(h|m|s))+$")
+DURATION_PART_REGEX = re.compile(r"(\d+)(h|m|s)")
+DURATION_UNITS = {"h": "hours", "m": "minutes", "s": "seconds"}
+# Name of the address of a node in the management network, its preferred API
+# address unless another one is named
+MANAGEMENT_ADDRESS = "management"
+
+
+class CustomRole(typing.NamedTuple):
+    """A role defined in the cluster config on top of the built-in roles.
+
+    Custom roles only tag the nodes assigned them, no services are
+    deployed for them.
+    """
+
+    name: str
+
+    def is_control_node(self) -> bool:
+        """Custom roles do not require control services."""
+        return False
+
+    def is_compute_node(self) -> bool:
+        """Custom roles do not require compute services."""
+        return False
+
+    def is_storage_node(self) -> bool:
+        """Custom roles do not require storage services."""
+        return False
+
+    def is_network_node(self) -> bool:
+        """Custom roles do not require network services."""
+        return False
+
+    def is_region_controller(self) -> bool:
+        """Custom roles do not require region controller services."""
+        return False
+
+
 # Role to feature gate mapping
 # When a role should be gated, map it to its feature gate key.
 # The gate configuration (including GA status) is defined in feature_gates.py
@@ -166,7 +208,7 @@ def _is_role_enabled(role: Role) -> bool:
     return enabled
 
 
-def roles_to_str_list(roles: list[Role]) -> list[str]:
+def roles_to_str_list(roles: Sequence[Role | CustomRole]) -> list[str]:
     return [role.name.lower() for role in roles]
 
 
@@ -358,19 +400,21 @@ def get_step_message(plan_results: dict, step: Type[BaseStep]) -> Any:
     return None
 
 
-def validate_roles(
-    ctx: click.core.Context, param: click.core.Option, value: Sequence[str]
-) -> list[Role]:
-    """Validate roles and check feature gate permissions."""
+def _validate_roles(
+    value: Sequence[str], allow_custom: bool
+) -> list[Role | CustomRole]:
     roles: set[str] = set()
     for val in value:
         roles.update(val.split(","))
 
-    validated_roles = []
+    validated_roles: list[Role | CustomRole] = []
     for role_str in roles:
         try:
             role = Role[role_str.upper()]
         except KeyError as e:
+            if allow_custom and CUSTOM_ROLE_PATTERN.match(role_str.lower()):
+                validated_roles.append(CustomRole(role_str.lower()))
+                continue
             enabled_roles = Role.enabled_values()
             raise click.BadParameter(
                 f"{str(e)}. Valid choices are " + ", ".join(enabled_roles)
@@ -390,6 +434,87 @@ def validate_roles(
     return validated_roles
 
 
+def validate_roles(
+    ctx: click.core.Context, param: click.core.Option, value: Sequence[str]
+) -> list[Role]:
+    """Validate roles and check feature gate permissions."""
+    return typing.cast(list[Role], _validate_roles(value, allow_custom=False))
+
+
+def validate_node_roles(
+    ctx: click.core.Context, param: click.core.Option, value: Sequence[str]
+) -> list[Role | CustomRole]:
+    """Validate roles, accepting the names of custom roles.
+
+    Custom roles must be defined in the cluster beforehand, they are
+    validated by clusterd when the node is recorded.
+    """
+    return _validate_roles(value, allow_custom=True)
+
+
+def validate_duration(
+    ctx: click.core.Context, param: click.core.Parameter, value: str | None
+) -> str | None:
+    """Check the option is a positive duration such as 1h or 1h30m."""
+    if value is None:
+        return value
+    # Zero durations, such as 0h, have no non-zero digit
+    if not DURATION_REGEX.match(value) or not any(c in "123456789" for c in value):
+        raise click.BadParameter(
+            f"{value!r} is not a positive duration such as 1h or 1h30m"
+        )
+    return value
+
+
+def parse_duration(value: str) -> datetime.timedelta:
+    """Convert a duration validated by validate_duration to a timedelta."""
+    return sum(
+        (
+            datetime.timedelta(**{DURATION_UNITS[unit]: int(amount)})
+            for amount, unit in DURATION_PART_REGEX.findall(value)
+        ),
+        datetime.timedelta(),
+    )
+
+
+def validate_node_addresses(
+    ctx: click.core.Context, param: click.core.Parameter, value: tuple[str, ...]
+) -> dict[str, str]:
+    """Parse the NAME=IP addresses of a node, such as management=10.0.0.5."""
+    addresses: dict[str, str] = {}
+    for item in value:
+        name, sep, address = item.partition("=")
+        if not sep or not name:
+            raise click.BadParameter(
+                f"{item!r} is not an address such as management=10.0.0.5"
+            )
+        try:
+            ipaddress.ip_address(address)
+        except ValueError:
+            raise click.BadParameter(f"{address!r} of {name} is not an IP address")
+        if name in addresses:
+            raise click.BadParameter(f"Address {name} is given more than once")
+        addresses[name] = address
+    return addresses
+
+
+def preferred_api_address(
+    addresses: dict[str, str], preferred_api: str | None = None
+) -> str:
+    """Return the address of a node serving the cluster API.
+
+    The preferred API address is the management one unless named. Raises
+    ValueError if the node has no such address.
+    """
+    name = preferred_api or MANAGEMENT_ADDRESS
+    if name not in addresses:
+        raise ValueError(
+            f"Preferred API address {name!r} is not an address of the node,"
+            f" expected one of {', '.join(sorted(addresses))}"
+        )
+    return addresses[name]
+
+
 def get_host_total_ram() -> int:
     """Reads meminfo to get total ram in KB."""
     with open("/proc/meminfo") as f:
@@ -0,0 +1,315 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Checks of the health of a bootstrapped cluster.
+
+Each check covers one subsystem: the clusterd database, the clusterd daemon
+of each node, the juju controller and the units of the core applications,
+the OpenStack API endpoints, and the maintenance mode of the nodes. They are
+run with the pre-flight checks machinery, the checks raising being reported
+as failed.
+"""
+
+import logging
+import typing
+
+from sunbeam.clusterd.client import Client
+from sunbeam.core.checks import Check
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.juju import JujuHelper
+from sunbeam.core.openstack import OPENSTACK_MODEL
+from sunbeam.core.openstack_api import get_admin_connection
+
+LOG = logging.getLogger(__name__)
+
+MEMBER_ONLINE = "ONLINE"
+# Role of the cluster members taking part in the database quorum
+VOTER_ROLE = "voter"
+# Workload status of a healthy unit
+UNIT_ACTIVE = "active"
+# Agent status of a unit running no hook nor action
+UNIT_IDLE = "idle"
+MAINTENANCE_DISABLED = "disabled"
+ENDPOINT_TIMEOUT = 10
+ENDPOINT_INTERFACE = "public"
+
+
+class ClusterdQuorumCheck(Check):
+    """Check a majority of the clusterd database voters are online."""
+
+    def __init__(self, client: Client):
+        super().__init__(
+            "Cluster database quorum",
+            "Checking the cluster database has quorum",
+        )
+        self.client = client
+
+    def run(self) -> bool:
+        """Return false if half or more of the voters are not online.
+
+        All the members count as voters when their roles are not reported.
+        """
+        members = self.client.cluster.get_cluster_members()
+        voters = [m for m in members if m.get("role") == VOTER_ROLE] or members
+        online = [m["name"] for m in voters if m.get("status") == MEMBER_ONLINE]
+        summary = f"{len(online)} of {len(voters)} voters online"
+        if len(online) * 2 <= len(voters):
+            offline = sorted({m["name"] for m in voters} - set(online))
+            self.message = f"No quorum, {summary}, offline: {', '.join(offline)}"
+            return False
+
+        self.message = summary
+        return True
+
+
+class NodeAgentCheck(Check):
+    """Check the clusterd daemon of a node is reachable."""
+
+    def __init__(self, client: Client, node: str):
+        super().__init__(
+            f"Node {node} agent",
+            f"Checking the cluster agent of node {node} is reachable",
+        )
+        self.client = client
+        self.node = node
+
+    def run(self) -> bool:
+        """Return false if the node member is not online."""
+        members = self.client.cluster.get_cluster_members()
+        member = next((m for m in members if m["name"] == self.node), None)
+        if member is None:
+            self.message = f"Node {self.node} is not a cluster member"
+            return False
+
+        status = member.get("status")
+        if status != MEMBER_ONLINE:
+            self.message = f"Agent at {member.get('address')} is {status}"
+            return False
+
+        self.message = f"Agent at {member.get('address')} is online"
+        return True
+
+
+class JujuControllerCheck(Check):
+    """Check the juju controller is reachable."""
+
+    def __init__(self, jhelper: JujuHelper):
+        super().__init__(
+            "Juju controller",
+            "Checking the juju controller is reachable",
+        )
+        self.jhelper = jhelper
+
+    def run(self) -> bool:
+        """Return false if the models of the controller cannot be listed."""
+        models = self.jhelper.models()
+        self.message = f"Controller reachable, {len(models)} models"
+        return True
+
+
+def unhealthy_units(
+    status: typing.Any, machine: str | None = None, idle: bool = False
+) -> dict[str, str]:
+    """Return the status of the units which are not active in a model status.
+
+    With machine, only the units on the machine, and their subordinates, are
+    considered. With idle, the agent status of the active units which are not
+    idle is returned too.
+    """
+    unhealthy = {}
+    for app_status in status.apps.values():
+        for unit_name, unit_status in app_status.units.items():
+            if machine is not None and unit_status.machine != machine:
+                continue
+            units = {unit_name: unit_status, **(unit_status.subordinates or {})}
+            for name, unit in units.items():
+                workload = unit.workload_status.current
+                if workload != UNIT_ACTIVE:
+                    unhealthy[name] = workload
+                elif idle and unit.juju_status.current != UNIT_IDLE:
+                    unhealthy[name] = unit.juju_status.current
+    return unhealthy
+
+
+class JujuUnitsCheck(Check):
+    """Check the units of the applications of a model are active.
+
+    With idle, their agents must also be idle, no hook nor action running.
+    """
+
+    def __init__(
+        self,
+        jhelper: JujuHelper,
+        model: str,
+        machine: str | None = None,
+        idle: bool = False,
+    ):
+        scope = f" on machine {machine}" if machine is not None else ""
+        state = "active and idle" if idle else "active"
+        super().__init__(
+            f"Units of model {model}{scope}",
+            f"Checking the units of model {model}{scope} are {state}",
+        )
+        self.jhelper = jhelper
+        self.model = model
+        self.machine = machine
+        self.idle = idle
+
+    def run(self) -> bool:
+        """Return false if a unit, or a subordinate, is not active."""
+        status = self.jhelper.get_model_status(self.model)
+        unhealthy = unhealthy_units(status, self.machine, self.idle)
+        if unhealthy:
+            self.message = ", ".join(
+                f"{unit} is {workload}" for unit, workload in sorted(unhealthy.items())
+            )
+            return False
+
+        self.message = "All units active and idle" if self.idle else "All units active"
+        return True
+
+
+class NoMaintenanceCheck(Check):
+    """Check no node is in maintenance mode."""
+
+    def __init__(self, client: Client):
+        super().__init__(
+            "No maintenance",
+            "Checking no node is in maintenance mode",
+        )
+        self.client = client
+
+    def run(self) -> bool:
+        """Return false if the maintenance of a node is enabled or degraded."""
+        statuses = self.client.cluster.list_maintenance_status().root
+        in_maintenance = [
+            f"{status.node} is {status.status}"
+            for status in sorted(statuses, key=lambda status: status.node)
+            if status.status != MAINTENANCE_DISABLED
+        ]
+        if in_maintenance:
+            self.message = ", ".join(in_maintenance)
+            return False
+
+        self.message = "No node in maintenance mode"
+        return True
+
+
+class OpenStackEndpointsCheck(Check):
+    """Check the public OpenStack API endpoints respond."""
+
+    def __init__(self, deployment: Deployment, jhelper: JujuHelper):
+        super().__init__(
+            "OpenStack API endpoints",
+            "Checking the OpenStack API endpoints are reachable",
+        )
+        self.deployment = deployment
+        self.jhelper = jhelper
+
+    def run(self) -> bool:
+        """Return false if an endpoint of the catalog does not respond.
+
+        Any response below 500 counts as reachable, the endpoints are queried
+        without authentication.
+        """
+        conn = get_admin_connection(self.jhelper, self.deployment)
+        services = {service.id: service.name for service in conn.identity.services()}
+        unreachable = []
+        endpoints = list(conn.identity.endpoints(interface=ENDPOINT_INTERFACE))
+        for endpoint in endpoints:
+            # Drop the templated part of the URL, such as $(project_id)s
+            url = endpoint.url.split("$(")[0].split("%(")[0]
+            service = services.get(endpoint.service_id, endpoint.service_id)
+            try:
+                response = conn.session.get(
+                    url,
+                    authenticated=False,
+                    raise_exc=False,
+                    timeout=ENDPOINT_TIMEOUT,
+                )
+            except Exception as e:
+                # keystoneauth raises its own connection and timeout errors
+                LOG.debug(f"Endpoint {url} unreachable", exc_info=True)
+                unreachable.append(f"{service} ({type(e).__name__})")
+                continue
+            if response.status_code >= 500:
+                unreachable.append(f"{service} ({response.status_code})")
+
+        if unreachable:
+            self.message = f"Unreachable: {', '.join(unreachable)}"
+            return False
+
+        self.message = f"{len(endpoints)} endpoints reachable"
+        return True
+
+
+def cluster_health_checks(
+    deployment: Deployment,
+    jhelper: JujuHelper,
+    node: str | None = None,
+    idle: bool = False,
+) -> list[Check]:
+    """Return the checks of the cluster health, or of a node with node.
+
+    The checks of a node cover the cluster database and the juju controller
+    it depends on, its agent and the units on its machine. The OpenStack API
+    endpoints are only checked for the whole cluster. With idle, the units
+    must also be idle.
+    """
+    client = deployment.get_client()
+    checks: list[Check] = [ClusterdQuorumCheck(client)]
+    nodes = [node]
+    if node is None:
+        try:
+            nodes = [m["name"] for m in client.cluster.get_cluster_members()]
+        except Exception:
+            # Reported by the quorum check
+            LOG.debug("Failed to list the cluster members", exc_info=True)
+            nodes = []
+    checks.extend(NodeAgentCheck(client, name) for name in nodes)
+    checks.append(JujuControllerCheck(jhelper))
+
+    if node is None:
+        checks.append(
+            JujuUnitsCheck(jhelper, deployment.openstack_machines_model, idle=idle)
+        )
+        checks.append(JujuUnitsCheck(jhelper, OPENSTACK_MODEL, idle=idle))
+        checks.append(OpenStackEndpointsCheck(deployment, jhelper))
+        return checks
+
+    # Nodes not deployed yet have no machine
+    machine = client.cluster.get_node_info(node).get("machineid", -1)
+    if machine >= 0:
+        checks.append(
+            JujuUnitsCheck(
+                jhelper, deployment.openstack_machines_model, str(machine), idle
+            )
+        )
+    return checks
+
+
+# Conditions awaited by `sunbeam cluster wait`
+WAIT_READY = "ready"
+WAIT_QUORUM = "quorum"
+WAIT_NO_MAINTENANCE = "no-maintenance"
+WAIT_CONDITIONS = [WAIT_READY, WAIT_QUORUM, WAIT_NO_MAINTENANCE]
+
+
+def wait_checks(
+    deployment: Deployment, jhelper: JujuHelper, conditions: typing.Sequence[str]
+) -> list[Check]:
+    """Return the checks passing once the cluster meets all the conditions.
+
+    The cluster is ready once healthy with all its units idle. The checks
+    must be built anew for each attempt, the nodes checked are the members
+    of the cluster at the time.
+    """
+    client = deployment.get_client()
+    checks: list[Check] = []
+    if WAIT_READY in conditions:
+        checks.extend(cluster_health_checks(deployment, jhelper, idle=True))
+    elif WAIT_QUORUM in conditions:
+        checks.append(ClusterdQuorumCheck(client))
+    if WAIT_NO_MAINTENANCE in conditions:
+        checks.append(NoMaintenanceCheck(client))
+    return checks
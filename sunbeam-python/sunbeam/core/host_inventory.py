@@ -0,0 +1,166 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Inventories of the cluster nodes for configuration management tools.
+
+The nodes are rendered as an Ansible inventory, in YAML or INI, or as SSH
+config stanzas. Hosts are grouped by role and by label, and the cordoned
+nodes and the nodes in maintenance are put in groups of their own so that
+playbooks can skip them, e.g. with `hosts: compute:!cordoned:!maintenance`.
+The output is sorted so that it can be committed and diffed.
+"""
+
+import json
+import re
+import shlex
+from dataclasses import dataclass, field
+
+import yaml
+
+from sunbeam.core.topology import member_address
+
+# Groups of the nodes to be skipped by playbooks
+CORDONED_GROUP = "cordoned"
+MAINTENANCE_GROUP = "maintenance"
+# Prefix of the groups of the nodes having a label
+LABEL_GROUP_PREFIX = "label"
+# Prefix of the host variables set from the cluster
+HOSTVAR_PREFIX = "sunbeam"
+
+_INVALID_GROUP_CHARS = re.compile(r"[^A-Za-z0-9_]")
+
+
+@dataclass
+class InventoryHost:
+    """A node of the cluster, as rendered in the inventories."""
+
+    name: str
+    address: str | None = None
+    roles: list[str] = field(default_factory=list)
+    labels: dict[str, str] = field(default_factory=dict)
+    cordoned: bool = False
+    maintenance: bool = False
+
+    def groups(self) -> list[str]:
+        """Groups of the host: its roles, labels and cordon or maintenance."""
+        groups = {group_name(role) for role in self.roles}
+        groups.update(
+            group_name(f"{LABEL_GROUP_PREFIX}_{key}_{value}")
+            for key, value in self.labels.items()
+        )
+        if self.cordoned:
+            groups.add(CORDONED_GROUP)
+        if self.maintenance:
+            groups.add(MAINTENANCE_GROUP)
+        return sorted(groups)
+
+    def hostvars(self) -> dict:
+        """Variables of the host, the address being ansible_host."""
+        hostvars: dict = {
+            f"{HOSTVAR_PREFIX}_roles": self.roles,
+            f"{HOSTVAR_PREFIX}_labels": self.labels,
+            f"{HOSTVAR_PREFIX}_cordoned": self.cordoned,
+            f"{HOSTVAR_PREFIX}_maintenance": self.maintenance,
+        }
+        if self.address:
+            hostvars["ansible_host"] = self.address
+        return hostvars
+
+
+def group_name(name: str) -> str:
+    """Return name as a valid Ansible group name."""
+    return _INVALID_GROUP_CHARS.sub("_", name)
+
+
+def inventory_hosts(
+    nodes: list[dict], members: list[dict], in_maintenance: set[str]
+) -> list[InventoryHost]:
+    """Return the hosts of the nodes, sorted by name.
+
+    The addresses are the ones of the cluster members of the nodes.
+    """
+    addresses = {member["name"]: member_address(member) for member in members}
+    return [
+        InventoryHost(
+            name=node["name"],
+            address=addresses.get(node["name"]),
+            roles=sorted(node.get("role") or []),
+            labels=dict(sorted((node.get("labels") or {}).items())),
+            cordoned=bool(node.get("cordoned")),
+            maintenance=node["name"] in in_maintenance,
+        )
+        for node in sorted(nodes, key=lambda node: node["name"])
+    ]
+
+
+def _groups(hosts: list[InventoryHost]) -> dict[str, list[str]]:
+    """Names of the hosts of each group, sorted."""
+    groups: dict[str, list[str]] = {}
+    for host in hosts:
+        for group in host.groups():
+            groups.setdefault(group, []).append(host.name)
+    return dict(sorted(groups.items()))
+
+
+def render_ansible_yaml(hosts: list[InventoryHost]) -> str:
+    """Render the hosts as an Ansible YAML inventory."""
+    inventory = {
+        "all": {
+            "hosts": {host.name: host.hostvars() for host in hosts},
+            "children": {
+                group: {"hosts": {name: {} for name in names}}
+                for group, names in _groups(hosts).items()
+            },
+        }
+    }
+    return yaml.safe_dump(inventory, sort_keys=True, default_flow_style=False)
+
+
+def _ini_value(value) -> str:
+    """Render a host variable as a Python literal, as Ansible parses them."""
+    if isinstance(value, bool):
+        return str(value)
+    if isinstance(value, (list, dict)):
+        return shlex.quote(json.dumps(value, sort_keys=True))
+    return shlex.quote(str(value))
+
+
+def render_ansible_ini(hosts: list[InventoryHost]) -> str:
+    """Render the hosts as an Ansible INI inventory.
+
+    The hosts are declared with their variables in the ungrouped section,
+    then listed in the sections of their groups.
+    """
+    lines = ["[all]"]
+    for host in hosts:
+        hostvars = " ".join(
+            f"{key}={_ini_value(value)}"
+            for key, value in sorted(host.hostvars().items())
+        )
+        lines.append(f"{host.name} {hostvars}")
+    for group, names in _groups(hosts).items():
+        lines.extend(["", f"[{group}]", *names])
+    return "\n".join(lines) + "\n"
+
+
+def render_ssh_config(hosts: list[InventoryHost]) -> str:
+    """Render the hosts as SSH config stanzas.
+
+    The roles, labels and cordon or maintenance of the hosts are rendered as
+    comments, SSH config having no such attributes.
+    """
+    stanzas = []
+    for host in hosts:
+        lines = [f"Host {host.name}"]
+        if host.address:
+            lines.append(f"    HostName {host.address}")
+        lines.append(f"    # roles: {','.join(host.roles)}")
+        if host.labels:
+            labels = ",".join(f"{key}={value}" for key, value in host.labels.items())
+            lines.append(f"    # labels: {labels}")
+        if host.cordoned:
+            lines.append(f"    # {CORDONED_GROUP}")
+        if host.maintenance:
+            lines.append(f"    # {MAINTENANCE_GROUP}")
+        stanzas.append("\n".join(lines))
+    return "\n\n".join(stanzas) + "\n" if stanzas else ""
@@ -0,0 +1,85 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Collect the hardware inventory of the local node."""
+
+import logging
+from pathlib import Path
+
+from sunbeam.clusterd.models import Hardware, HardwareBlockDevice, HardwareNIC
+from sunbeam.core.common import get_host_total_cores, get_host_total_ram
+
+LOG = logging.getLogger(__name__)
+
+SYSFS = Path("/sys")
+# Size of the sectors counted in /sys/block/<device>/size
+SECTOR_SIZE = 512
+# Virtual block devices, not backed by a disk
+VIRTUAL_BLOCK_DEVICE_PREFIXES = ("loop", "ram", "zram", "dm-", "md", "nbd")
+
+
+def _read(path: Path) -> str:
+    """Return the stripped content of a sysfs attribute, empty if unreadable."""
+    try:
+        return path.read_text().strip()
+    except OSError:
+        return ""
+
+
+def collect_nics(sysfs: Path = SYSFS) -> list[HardwareNIC]:
+    """Collect the physical network interfaces.
+
+    Virtual interfaces, such as bridges, bonds or veths, have no device link
+    and are left out.
+    """
+    nics = []
+    net = sysfs / "class" / "net"
+    if not net.is_dir():
+        return nics
+    for iface in sorted(net.iterdir()):
+        if not (iface / "device").exists():
+            continue
+        speed = _read(iface / "speed")
+        nics.append(
+            HardwareNIC(
+                name=iface.name,
+                mac_address=_read(iface / "address"),
+                # speed is -1 or unreadable while the link is down
+                speed=max(int(speed), 0) if speed.lstrip("-").isdigit() else 0,
+            )
+        )
+    return nics
+
+
+def collect_block_devices(sysfs: Path = SYSFS) -> list[HardwareBlockDevice]:
+    """Collect the disks, leaving out virtual and empty block devices."""
+    devices = []
+    block = sysfs / "block"
+    if not block.is_dir():
+        return devices
+    for device in sorted(block.iterdir()):
+        if device.name.startswith(VIRTUAL_BLOCK_DEVICE_PREFIXES):
+            continue
+        sectors = _read(device / "size")
+        if not sectors.isdigit() or int(sectors) == 0:
+            continue
+        devices.append(
+            HardwareBlockDevice(
+                name=device.name,
+                model=_read(device / "device" / "model"),
+                size=int(sectors) * SECTOR_SIZE,
+                rotational=_read(device / "queue" / "rotational") == "1",
+            )
+        )
+    return devices
+
+
+def collect_hardware(sysfs: Path = SYSFS) -> Hardware:
+    """Collect the hardware inventory of the local node."""
+    return Hardware(
+        cpu_count=get_host_total_cores(),
+        # meminfo reports kB
+        memory_total=get_host_total_ram() * 1024,
+        nics=collect_nics(sysfs),
+        block_devices=collect_block_devices(sysfs),
+    )
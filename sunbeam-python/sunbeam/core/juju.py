@@ -31,6 +31,7 @@ from snaphelpers import Snap
 
 from sunbeam import utils
 from sunbeam.clusterd.client import Client
+from sunbeam.clusterd.service import ConfigItemNotFoundException
 from sunbeam.core.common import STATUS_NOT_READY, STATUS_READY, SunbeamException
 from sunbeam.versions import JUJU_BASE
 
@@ -39,6 +40,8 @@ CONTROLLER_MODEL = "admin/controller"
 CONTROLLER_APPLICATION = "controller"
 CONTROLLER = "sunbeam-controller"
 JUJU_CONTROLLER_KEY = "JujuController"
+JUJU_RETRY_POLICY_KEY = "juju.retry-policy"
+JUJU_RETRY_ENV_PREFIX = "SUNBEAM_JUJU_RETRY_"
 ACCOUNT_FILE = "account.yaml"
 OWNER_TAG_PREFIX = "user-"
 
@@ -195,6 +198,125 @@ def build_pre_status_overlay(
     return overlay
 
 
+# Errors of the Juju client or controller worth retrying, matched on stderr
+TRANSIENT_JUJU_ERRORS = (
+    "connection reset by peer",
+    "connection refused",
+    "connection is shut down",
+    "broken pipe",
+    "unexpected eof",
+    "i/o timeout",
+    "tls handshake timeout",
+    "cannot connect to api",
+    "try again",
+    "server is busy",
+    "too many requests",
+    "rate limit exceeded",
+)
+
+# Errors never retried, even when their message matches a transient error
+LOGICAL_JUJU_ERRORS = (
+    "already exists",
+    "not found",
+    "permission denied",
+    "unauthorized",
+)
+
+
+def is_transient_juju_error(error: BaseException) -> bool:
+    """Whether error is a Juju command failure a retry may overcome."""
+    if not isinstance(error, jubilant.CLIError):
+        return False
+    stderr = (error.stderr or "").lower()
+    if any(logical in stderr for logical in LOGICAL_JUJU_ERRORS):
+        return False
+    return any(transient in stderr for transient in TRANSIENT_JUJU_ERRORS)
+
+
+class JujuRetryPolicy(pydantic.BaseModel):
+    """Retry policy of the Juju commands failing with transient errors.
+
+    The delay before a retry doubles from base_delay with each attempt, up to
+    max_delay, plus a random jitter of up to jitter seconds.
+    """
+
+    model_config = pydantic.ConfigDict(extra="forbid")
+
+    max_attempts: int = pydantic.Field(default=6, ge=1)
+    base_delay: float = pydantic.Field(default=2.0, ge=0)
+    max_delay: float = pydantic.Field(default=30.0, ge=0)
+    jitter: float = pydantic.Field(default=1.0, ge=0)
+
+    @classmethod
+    def load(cls, client: Client | None = None) -> "JujuRetryPolicy":
+        """Load the policy from cluster config, overridden by the environment.
+
+        The cluster config holds the policy as json under
+        JUJU_RETRY_POLICY_KEY, the environment variables are named after the
+        fields, e.g. SUNBEAM_JUJU_RETRY_MAX_ATTEMPTS. Invalid settings are
+        ignored with a warning, not to break every command.
+        """
+        policy = cls()
+        if client is not None:
+            try:
+                policy = cls.model_validate_json(
+                    client.cluster.get_config(JUJU_RETRY_POLICY_KEY)
+                )
+            except ConfigItemNotFoundException:
+                LOG.debug("No Juju retry policy in cluster config")
+            except pydantic.ValidationError as e:
+                LOG.warning(f"Ignoring invalid Juju retry policy in clusterd: {e}")
+
+        overrides = {
+            field: os.environ[JUJU_RETRY_ENV_PREFIX + field.upper()]
+            for field in cls.model_fields
+            if JUJU_RETRY_ENV_PREFIX + field.upper() in os.environ
+        }
+        if overrides:
+            try:
+                policy = cls(**{**policy.model_dump(), **overrides})
+            except pydantic.ValidationError as e:
+                LOG.warning(f"Ignoring invalid Juju retry policy in environment: {e}")
+        return policy
+
+    def _log_retry(self, retry_state: tenacity.RetryCallState):
+        error = retry_state.outcome.exception() if retry_state.outcome else None
+        stderr = getattr(error, "stderr", None) or ""
+        delay = retry_state.next_action.sleep if retry_state.next_action else 0
+        LOG.debug(
+            f"Transient Juju error on attempt {retry_state.attempt_number}/"
+            f"{self.max_attempts}, retrying in {delay:.1f}s: {stderr.strip()}"
+        )
+
+    def call(self, func: Callable[..., T], *args, **kwargs) -> T:
+        """Call func, retrying it while it fails with transient Juju errors.
+
+        The last error is raised once all the attempts failed.
+        """
+        retrying = tenacity.Retrying(
+            retry=tenacity.retry_if_exception(is_transient_juju_error),
+            stop=tenacity.stop_after_attempt(self.max_attempts),
+            wait=tenacity.wait_exponential_jitter(
+                initial=self.base_delay, max=self.max_delay, jitter=self.jitter
+            ),
+            before_sleep=self._log_retry,
+            reraise=True,
+        )
+        return retrying(func, *args, **kwargs)
+
+
+class RetryingJuju(jubilant.Juju):
+    """Juju client retrying the commands failing with transient errors."""
+
+    def __init__(self, retry_policy: JujuRetryPolicy, **kwargs):
+        super().__init__(**kwargs)
+        self.retry_policy = retry_policy
+
+    def _cli(self, *args, **kwargs):
+        # All the jubilant commands run through _cli
+        return self.retry_policy.call(super()._cli, *args, **kwargs)
+
+
 class JujuAccount(pydantic.BaseModel):
     user: str
     password: str
@@ -233,6 +355,8 @@ class JujuController(pydantic.BaseModel):
     api_endpoints: list[str]
     ca_cert: str
     is_external: bool
+    # Loaded from its own config key, not stored with the controller
+    retry_policy: JujuRetryPolicy | None = pydantic.Field(default=None, exclude=True)
 
     def to_dict(self):
         """Return self as dict."""
@@ -240,9 +364,11 @@ class JujuController(pydantic.BaseModel):
 
     @classmethod
     def load(cls, client: Client) -> "JujuController":
-        """Load controller from clusterd."""
+        """Load controller, and the Juju retry policy, from clusterd."""
         controller = client.cluster.get_config(JUJU_CONTROLLER_KEY)
-        return JujuController(**json.loads(controller))
+        return JujuController(
+            **json.loads(controller), retry_policy=JujuRetryPolicy.load(client)
+        )
 
     def write(self, client: Client):
         """Dump self to clusterd."""
@@ -256,7 +382,8 @@ class JujuHelper:
         if controller is None:
             raise ValueError("Controller cannot be None")
         self.controller: str = controller.name
-        self._juju = jubilant.Juju()
+        self.retry_policy = controller.retry_policy or JujuRetryPolicy.load()
+        self._juju = RetryingJuju(self.retry_policy)
 
     def cli(
         self,
@@ -418,11 +545,6 @@ class JujuHelper:
         """Get juju model full name along with owner."""
         return self.get_model(model)["name"]
 
-    @tenacity.retry(
-        retry=tenacity.retry_if_exception_type(ControllerNotReachableException),
-        wait=tenacity.wait_exponential(multiplier=1, min=4, max=10),
-        stop=tenacity.stop_after_attempt(8),
-    )
     def get_model_status(self, model: str) -> "jubilant.Status":
         """Get juju filtered status."""
         with self._model(model) as juju:
@@ -92,10 +92,34 @@ class TerraformManifest(pydantic.BaseModel):
         return str(value)
 
 
+class OfflineManifest(pydantic.BaseModel):
+    """Local sources of the artifacts, for disconnected deployments.
+
+    The charms and their resources are fetched from a Charmhub mirror and the
+    snaps installed by the charms from a snap store proxy, instead of the
+    public stores.
+    """
+
+    charmhub_url: str | None = Field(
+        default=None, description="URL of the Charmhub mirror serving the charms"
+    )
+    snap_store_proxy: str | None = Field(
+        default=None, description="Store ID of the snap store proxy"
+    )
+    snap_store_assertions: Path | None = Field(
+        default=None, description="File holding the assertions of the snap store proxy"
+    )
+
+    @pydantic.field_serializer("snap_store_assertions")
+    def _serialize_snap_store_assertions(self, value: Path | None) -> str | None:
+        return str(value) if value else None
+
+
 class SoftwareConfig(pydantic.BaseModel):
     juju: JujuManifest = JujuManifest()
     charms: dict[str, CharmManifest] = {}
     terraform: dict[str, TerraformManifest] = {}
+    offline: OfflineManifest | None = None
 
     def validate_terraform_keys(self, default_software_config: "SoftwareConfig"):
         """Validate the terraform keys provided are expected."""
@@ -138,7 +162,10 @@ class SoftwareConfig(pydantic.BaseModel):
         terraform: dict[str, TerraformManifest] = utils.merge_dict(
             copy.deepcopy(self.terraform), copy.deepcopy(other.terraform)
         )
-        return SoftwareConfig(juju=juju, charms=charms, terraform=terraform)
+        offline = other.offline or self.offline
+        return SoftwareConfig(
+            juju=juju, charms=charms, terraform=terraform, offline=offline
+        )
 
 
 class FeatureConfig(pydantic.BaseModel):
@@ -0,0 +1,169 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Resolution of the deployment artifacts from local sources.
+
+Disconnected deployments fetch the charms and their resources from a
+Charmhub mirror and the snaps installed by the charms from a snap store
+proxy, both declared in the offline section of the software manifest.
+"""
+
+import logging
+import typing
+import urllib.parse
+
+import requests
+
+from sunbeam.core.manifest import JujuManifest, Manifest, OfflineManifest
+
+LOG = logging.getLogger(__name__)
+
+# Hosts of the public stores, never contacted in offline mode
+PUBLIC_STORE_HOSTS = {
+    "charmhub.io",
+    "api.charmhub.io",
+    "snapcraft.io",
+    "api.snapcraft.io",
+}
+MIRROR_TIMEOUT = 10
+DEFAULT_TRACK = "latest"
+
+# Charm info as served by the Charmhub API, None if the charm is not found
+CharmInfoFetcher = typing.Callable[[str, str], dict | None]
+
+
+def fetch_charm_info(charmhub_url: str, name: str) -> dict | None:
+    """Fetch the channel map of a charm from a Charmhub mirror."""
+    url = f"{charmhub_url.rstrip('/')}/v2/charms/info/{name}"
+    response = requests.get(
+        url,
+        params={"fields": "channel-map.revision.revision"},
+        timeout=MIRROR_TIMEOUT,
+    )
+    if response.status_code == requests.codes.not_found:
+        return None
+    response.raise_for_status()
+    return response.json()
+
+
+def is_public_store(url: str) -> bool:
+    """Whether the URL points at a public store."""
+    host = urllib.parse.urlparse(url).hostname or ""
+    return host in PUBLIC_STORE_HOSTS
+
+
+def _split_channel(channel: str) -> tuple[str, str]:
+    """Split a channel in track and risk, the track defaults to latest."""
+    parts = channel.split("/")
+    if len(parts) == 1:
+        return DEFAULT_TRACK, parts[0]
+    return parts[0], parts[1]
+
+
+def _charm_unresolved_reason(
+    info: dict | None, channel: str | None, revision: int | None
+) -> str | None:
+    """Reason the charm can't be deployed from the mirror, None if it can."""
+    if info is None:
+        return "not found on the mirror"
+
+    releases = info.get("channel-map") or []
+    if channel:
+        track, risk = _split_channel(channel)
+        releases = [
+            release
+            for release in releases
+            if release.get("channel", {}).get("track") == track
+            and release.get("channel", {}).get("risk") == risk
+        ]
+        if not releases:
+            return f"channel {channel} not found on the mirror"
+
+    if revision is not None:
+        revisions = {
+            release.get("revision", {}).get("revision") for release in releases
+        }
+        if revision not in revisions:
+            return f"revision {revision} not found on the mirror"
+
+    return None
+
+
+def resolve_offline_artifacts(
+    manifest: Manifest, fetch: CharmInfoFetcher = fetch_charm_info
+) -> list[str]:
+    """Resolve the artifacts of the deployment from its local sources.
+
+    Returns the artifacts that couldn't be resolved locally, with the
+    reason, empty if all of them were. The public stores are never
+    contacted, only the charmhub mirror is.
+    """
+    offline = manifest.core.software.offline or OfflineManifest()
+    unresolved: list[str] = []
+
+    if not offline.charmhub_url:
+        unresolved.append("charmhub mirror: charmhub_url is not set")
+    elif is_public_store(offline.charmhub_url):
+        unresolved.append(
+            f"charmhub mirror: {offline.charmhub_url} is a public store, not a mirror"
+        )
+
+    if not offline.snap_store_proxy:
+        unresolved.append("snap store proxy: snap_store_proxy is not set")
+    if offline.snap_store_assertions is None:
+        unresolved.append("snap store proxy: snap_store_assertions is not set")
+    elif not offline.snap_store_assertions.is_file():
+        unresolved.append(
+            f"snap store assertions {offline.snap_store_assertions}: file not found"
+        )
+
+    if not offline.charmhub_url or is_public_store(offline.charmhub_url):
+        # The charms can't be resolved without a mirror
+        return unresolved
+
+    for name, charm in sorted(manifest.core.software.charms.items()):
+        artifact = f"charm {name}"
+        if charm.channel:
+            artifact += f" ({charm.channel})"
+        try:
+            info = fetch(offline.charmhub_url, name)
+        except requests.RequestException as e:
+            LOG.debug("Failed to fetch charm %s from the mirror", name, exc_info=True)
+            unresolved.append(f"{artifact}: mirror unreachable: {e}")
+            continue
+        reason = _charm_unresolved_reason(info, charm.channel, charm.revision)
+        if reason:
+            unresolved.append(f"{artifact}: {reason}")
+
+    return unresolved
+
+
+def offline_model_config(offline: OfflineManifest) -> dict[str, str]:
+    """Juju model config pointing the models at the local sources."""
+    config: dict[str, str] = {}
+    if offline.charmhub_url:
+        config["charmhub-url"] = offline.charmhub_url
+    if offline.snap_store_proxy:
+        config["snap-store-proxy"] = offline.snap_store_proxy
+    if offline.snap_store_assertions:
+        config["snap-store-assertions"] = offline.snap_store_assertions.read_text()
+    return config
+
+
+def apply_offline_juju_manifest(
+    juju: JujuManifest, offline: OfflineManifest, models: list[str]
+) -> None:
+    """Point the controller bootstrapped and the models created at local sources.
+
+    The config is set as model defaults of the controller bootstrapped, and
+    on creation of the models given, the charmhub URL being immutable once
+    a model is created.
+    """
+    config = offline_model_config(offline)
+    for key, value in config.items():
+        juju.bootstrap_args.append(f"--model-default={key}={value}")
+    for model in models:
+        juju.bootstrap_model_configs[model] = {
+            **juju.bootstrap_model_configs.get(model, {}),
+            **config,
+        }
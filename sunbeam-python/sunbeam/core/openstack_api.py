@@ -4,7 +4,10 @@
 import logging
 import typing
 
+import tenacity
+
 from sunbeam.commands.configure import retrieve_admin_credentials
+from sunbeam.core.common import SunbeamException
 from sunbeam.core.deployment import Deployment
 from sunbeam.core.juju import JujuHelper
 from sunbeam.core.openstack import OPENSTACK_MODEL
@@ -17,6 +20,23 @@ else:
 
 LOG = logging.getLogger(__name__)
 
+# Timeout in seconds of a single instance migration
+MIGRATION_TIMEOUT = 60 * 60
+# Sleep interval in seconds between checks of a migration
+MIGRATION_WAIT_INTERVAL = 5
+# Nova statuses of an instance once migrated
+MIGRATED_STATUSES = ("ACTIVE", "SHUTOFF")
+# Nova statuses of a migration which did not complete
+FAILED_MIGRATION_STATUSES = ("error", "failed", "cancelled")
+
+
+class InstanceMigrationFailedException(SunbeamException):
+    """Raised when an instance migration fails or times out."""
+
+
+class _MigrationInProgressException(Exception):
+    """Raised while an instance migration is in progress."""
+
 
 def get_admin_connection(
     jhelper: JujuHelper, deployment: Deployment
@@ -57,6 +77,164 @@ def guests_on_hypervisor(
     )
 
 
+def hypervisors_free_capacity(
+    conn: "openstack.connection.Connection", exclude: str | None = None
+) -> dict[str, dict[str, int]]:
+    """Return the free resources of the hypervisors able to receive instances.
+
+    Only enabled and up hypervisors are returned. Free resources are read from
+    placement, accounting for reserved resources and allocation ratios.
+
+    :param conn: Admin connection
+    :param exclude: Name of hypervisor to leave out
+    :raises: openstack.exceptions.SDKException
+    """
+    providers = {
+        provider.name: provider for provider in conn.placement.resource_providers()
+    }
+    capacity = {}
+    for hypervisor in conn.compute.hypervisors(details=True):
+        if hypervisor.name == exclude:
+            continue
+        if hypervisor.status != "enabled" or hypervisor.state != "up":
+            continue
+        provider = providers.get(hypervisor.name)
+        if provider is None:
+            LOG.debug(f"No resource provider for hypervisor {hypervisor.name}")
+            continue
+        inventories = conn.placement.get(
+            f"/resource_providers/{provider.id}/inventories"
+        ).json()["inventories"]
+        usages = conn.placement.get(
+            f"/resource_providers/{provider.id}/usages"
+        ).json()["usages"]
+        capacity[hypervisor.name] = {
+            resource_class: int(
+                (inventory["total"] - inventory["reserved"])
+                * inventory["allocation_ratio"]
+            )
+            - usages.get(resource_class, 0)
+            for resource_class, inventory in inventories.items()
+        }
+    return capacity
+
+
+def migrate_instance(
+    conn: "openstack.connection.Connection",
+    instance_id: str,
+    source: str,
+    migration_type: str = "live",
+    destination: str | None = None,
+    timeout: int = MIGRATION_TIMEOUT,
+    progress: typing.Callable[[int | None], None] | None = None,
+) -> str:
+    """Migrate an instance off its hypervisor and wait for the migration.
+
+    Cold migrations are confirmed once the instance is resized. Return the
+    hypervisor the instance migrated to. While waiting, progress is called
+    with the percent of the migration done, None if Nova does not report it.
+
+    :param conn: Admin connection
+    :param instance_id: ID of the instance
+    :param source: Name of the hypervisor the instance runs on
+    :param migration_type: live or cold
+    :param destination: Name of the target hypervisor, None lets Nova schedule
+    :param timeout: Seconds to wait for the migration
+    :param progress: Called with the progress of the migration while waiting
+    :raises: InstanceMigrationFailedException, openstack.exceptions.SDKException
+    """
+    server = conn.compute.get_server(instance_id)
+    # Earlier migrations of the instance are not the outcome of this one
+    migrations = conn.compute.migrations(instance_uuid=instance_id)
+    previous = max((migration.id for migration in migrations), default=0)
+    if migration_type == "live":
+        conn.compute.live_migrate_server(
+            server, host=destination, block_migration="auto"
+        )
+    else:
+        conn.compute.migrate_server(server, host=destination)
+
+    try:
+        for attempt in tenacity.Retrying(
+            stop=tenacity.stop_after_delay(timeout),
+            wait=tenacity.wait_fixed(MIGRATION_WAIT_INTERVAL),
+            retry=tenacity.retry_if_exception_type(_MigrationInProgressException),
+            reraise=True,
+        ):
+            with attempt:
+                try:
+                    host = _check_migration(conn, instance_id, source, previous)
+                except _MigrationInProgressException:
+                    if progress:
+                        progress(migration_percent(conn, instance_id))
+                    raise
+    except _MigrationInProgressException as e:
+        raise InstanceMigrationFailedException(
+            f"Migration of instance {instance_id} did not complete in {timeout}s"
+        ) from e
+    return host
+
+
+def migration_percent(
+    conn: "openstack.connection.Connection", instance_id: str
+) -> int | None:
+    """Return the percent of the memory copied by the live migration in progress.
+
+    Return None if Nova does not report it, as for cold migrations.
+    """
+    try:
+        migrations = list(conn.compute.server_migrations(instance_id))
+    except openstack.exceptions.SDKException as e:
+        LOG.debug(f"Failed to fetch migrations of instance {instance_id}: {e}")
+        return None
+    for migration in migrations:
+        total = migration.memory_total_bytes
+        if total:
+            processed = migration.memory_processed_bytes or 0
+            return min(100, processed * 100 // total)
+    return None
+
+
+def _check_migration(
+    conn: "openstack.connection.Connection",
+    instance_id: str,
+    source: str,
+    previous: int,
+) -> str:
+    """Raise while the migration of the instance is in progress.
+
+    Only the migrations of the instance more recent than previous are checked.
+    Return the hypervisor the instance migrated to.
+
+    :raises: InstanceMigrationFailedException, _MigrationInProgressException
+    """
+    server = conn.compute.get_server(instance_id)
+    if server.status == "ERROR":
+        raise InstanceMigrationFailedException(
+            f"Instance {instance_id} is in ERROR status"
+        )
+    if server.status == "VERIFY_RESIZE":
+        conn.compute.confirm_server_resize(server)
+        raise _MigrationInProgressException(instance_id)
+    if server.hypervisor_hostname != source and server.status in MIGRATED_STATUSES:
+        LOG.debug(f"Instance {instance_id} migrated to {server.hypervisor_hostname}")
+        return server.hypervisor_hostname
+
+    migrations = sorted(
+        (
+            migration
+            for migration in conn.compute.migrations(instance_uuid=instance_id)
+            if migration.id > previous
+        ),
+        key=lambda migration: migration.id,
+    )
+    if migrations and migrations[-1].status in FAILED_MIGRATION_STATUSES:
+        raise InstanceMigrationFailedException(
+            f"Migration of instance {instance_id} is {migrations[-1].status}"
+        )
+    raise _MigrationInProgressException(instance_id)
+
+
 def remove_compute_service(
     hypervisor_name: str, conn: "openstack.connection.Connection"
 ) -> None:
@@ -0,0 +1,75 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Print command output as a human table or a structured format."""
+
+import datetime
+import json
+from typing import Any, Callable
+
+import click
+import yaml
+from rich.console import Console
+
+from sunbeam.core.common import FORMAT_JSON, FORMAT_TABLE, FORMAT_YAML
+
+# Go zero time, reported by microcluster for unset timestamps
+ZERO_TIME = datetime.datetime(1, 1, 1, tzinfo=datetime.timezone.utc)
+
+
+def click_option_format(
+    *formats: str, default: str = FORMAT_TABLE
+) -> Callable[[click.decorators.FC], click.decorators.FC]:
+    """Common decorator for the output format option.
+
+    Defaults to table, json and yaml formats.
+    """
+    choices = list(formats or (FORMAT_TABLE, FORMAT_JSON, FORMAT_YAML))
+
+    def decorator(func: click.decorators.FC) -> click.decorators.FC:
+        return click.option(
+            "-f",
+            "--format",
+            type=click.Choice(choices),
+            default=default,
+            help="Output format.",
+        )(func)
+
+    return decorator
+
+
+def rfc3339(value: str | datetime.datetime | None) -> str | None:
+    """Return value as a RFC3339 UTC timestamp with second precision.
+
+    Unset values, including the Go zero time, return None. Naive datetimes
+    are considered UTC.
+    """
+    if not value:
+        return None
+    if isinstance(value, str):
+        value = datetime.datetime.fromisoformat(value)
+    if value.tzinfo is None:
+        value = value.replace(tzinfo=datetime.timezone.utc)
+    if value == ZERO_TIME:
+        return None
+    return (
+        value.astimezone(datetime.timezone.utc)
+        .replace(microsecond=0)
+        .strftime("%Y-%m-%dT%H:%M:%SZ")
+    )
+
+
+def print_structured(console: Console, data: Any, format: str) -> None:
+    """Print data in a structured format, json or yaml.
+
+    The output is neither wrapped nor highlighted so that it can be parsed.
+    """
+    if format == FORMAT_JSON:
+        text = json.dumps(data, indent=2, sort_keys=True)
+        end = "\n"
+    elif format == FORMAT_YAML:
+        text = yaml.dump(data, sort_keys=True)
+        end = ""
+    else:
+        raise ValueError(f"{format!r} is not a structured format")
+    console.print(text, end=end, soft_wrap=True, markup=False, highlight=False)
@@ -35,6 +35,7 @@ from sunbeam.core.terraform import (
     TerraformHelper,
     TerraformStateLockedException,
 )
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 from sunbeam.lazy import LazyImport
 
 if typing.TYPE_CHECKING:
@@ -143,16 +144,18 @@ class DeployMachineApplicationStep(BaseStep):
 
         # Note(gboutry): application is in state unknown when it's deployed
         # without units
+        timeout = step_timeout(self, self.get_application_timeout())
         try:
             self.jhelper.wait_application_ready(
                 self.application,
                 self.model,
                 accepted_status=self.get_accepted_application_status(),
-                timeout=self.get_application_timeout(),
+                timeout=timeout,
             )
         except TimeoutError as e:
-            LOG.warning(str(e))
-            return Result(ResultType.FAILED, str(e))
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
 
         return Result(ResultType.COMPLETED)
 
@@ -231,6 +234,7 @@ class RemoveMachineUnitsStep(BaseStep):
 
     def run(self, status: Status | None = None) -> Result:
         """Remove unit from machine application on Juju model."""
+        timeout = step_timeout(self, self.get_unit_timeout())
         try:
             self.update_status(status, "Removing units")
             for unit in self.units_to_remove:
@@ -238,15 +242,19 @@ class RemoveMachineUnitsStep(BaseStep):
                 self.jhelper.remove_unit(self.application, unit, self.model)
             self.update_status(status, "Waiting for units to be removed")
             self.jhelper.wait_units_gone(
-                list(self.units_to_remove), self.model, self.get_unit_timeout()
+                list(self.units_to_remove), self.model, timeout
             )
             self.jhelper.wait_application_ready(
                 self.application,
                 self.model,
                 accepted_status=["active", "unknown"],
-                timeout=self.get_unit_timeout(),
+                timeout=timeout,
             )
-        except (ApplicationNotFoundException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except ApplicationNotFoundException as e:
             LOG.warning(str(e))
             return Result(ResultType.FAILED, str(e))
 
@@ -346,11 +354,10 @@ class DestroyMachineApplicationStep(BaseStep):
                 return Result(ResultType.FAILED, str(e))
 
         timeout_factor = 0.8
+        timeout = step_timeout(self, self.get_application_timeout())
 
         try:
-            self._wait_applications_gone(
-                int(self.get_application_timeout() * timeout_factor)
-            )
+            self._wait_applications_gone(int(timeout * timeout_factor))
         except TimeoutError:
             LOG.warning("Failed to destroy applications, trying through provider sdk")
             apps = self._list_applications(self.model)
@@ -361,12 +368,12 @@ class DestroyMachineApplicationStep(BaseStep):
             except JujuException:
                 LOG.debug("Failed to destroy applications", exc_info=True)
             try:
-                self._wait_applications_gone(
-                    int(self.get_application_timeout() * (1 - timeout_factor))
-                )
-            except TimeoutError:
+                self._wait_applications_gone(int(timeout * (1 - timeout_factor)))
+            except TimeoutError as e:
                 return Result(
-                    ResultType.FAILED, "Timed out destroying applications, try manually"
+                    ResultType.FAILED,
+                    f"{step_timeout_message(self, timeout, e)}, or destroy the"
+                    " applications manually",
                 )
 
         return Result(ResultType.COMPLETED)
@@ -0,0 +1,158 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Timeouts of the deployment steps, overridable per step."""
+
+import json
+import logging
+from pathlib import Path
+from typing import Any, Callable
+
+import yaml
+
+from sunbeam.clusterd.client import Client
+from sunbeam.clusterd.service import ConfigItemNotFoundException, RemoteException
+from sunbeam.core.common import DURATION_REGEX, parse_duration
+from sunbeam.errors import SunbeamException
+
+LOG = logging.getLogger(__name__)
+
+# Cluster config key holding the timeouts of the steps, a json object mapping
+# step names to durations such as 45m
+STEP_TIMEOUTS_KEY = "deployment.step-timeouts"
+
+
+def step_name(step: object) -> str:
+    """Return the name of a step its timeout is looked up with, its class."""
+    return type(step).__name__
+
+
+def format_duration(seconds: int) -> str:
+    """Format seconds as a duration such as 1h30m."""
+    hours, rest = divmod(seconds, 3600)
+    minutes, seconds = divmod(rest, 60)
+    parts = [
+        f"{amount}{unit}"
+        for amount, unit in ((hours, "h"), (minutes, "m"), (seconds, "s"))
+        if amount
+    ]
+    return "".join(parts) or "0s"
+
+
+def parse_step_timeouts(data: Any) -> dict[str, int]:
+    """Parse a mapping of step names to timeouts, in seconds.
+
+    Timeouts are durations such as 45m or 1h30m, or numbers of seconds.
+    Raises ValueError if a timeout is invalid.
+    """
+    if not isinstance(data, dict):
+        raise ValueError("expected a mapping of step names to timeouts")
+    timeouts = {}
+    for step, value in data.items():
+        if isinstance(value, int) and not isinstance(value, bool):
+            seconds = value
+        elif isinstance(value, str) and value.isdigit():
+            seconds = int(value)
+        elif isinstance(value, str) and DURATION_REGEX.match(value):
+            seconds = int(parse_duration(value).total_seconds())
+        else:
+            raise ValueError(
+                f"timeout {value!r} of step {step!r} is not a duration such as"
+                " 45m or 1h30m"
+            )
+        if seconds <= 0:
+            raise ValueError(f"timeout of step {step!r} must be positive")
+        timeouts[str(step)] = seconds
+    return timeouts
+
+
+def load_step_timeouts_file(path: Path) -> dict[str, int]:
+    """Load the timeouts of a yaml file mapping step names to timeouts.
+
+    Raises ValueError if the file cannot be parsed.
+    """
+    try:
+        data = yaml.safe_load(path.read_text())
+    except (OSError, yaml.YAMLError) as e:
+        raise ValueError(f"cannot read {path}: {e}") from e
+    try:
+        return parse_step_timeouts(data or {})
+    except ValueError as e:
+        raise ValueError(f"invalid timeouts in {path}: {e}") from e
+
+
+class StepTimeouts:
+    """Timeouts of the steps overriding their defaults.
+
+    The timeouts of the --timeouts file take precedence over the ones of the
+    cluster config, read once on the first lookup. Invalid timeouts in the
+    cluster config are ignored with a warning, not to break every command.
+    """
+
+    def __init__(self) -> None:
+        self.overrides: dict[str, int] = {}
+        self._client_factory: Callable[[], Client] | None = None
+        self._cluster: dict[str, int] | None = None
+
+    def configure(
+        self,
+        overrides: dict[str, int],
+        client_factory: Callable[[], Client] | None = None,
+    ) -> None:
+        """Set the overrides, and the client of the cluster config."""
+        self.overrides = dict(overrides)
+        self._client_factory = client_factory
+        self._cluster = None
+
+    def _cluster_timeouts(self) -> dict[str, int]:
+        if self._cluster is not None:
+            return self._cluster
+        self._cluster = {}
+        if self._client_factory is None:
+            return self._cluster
+        try:
+            value = self._client_factory().cluster.get_config(STEP_TIMEOUTS_KEY)
+        except ConfigItemNotFoundException:
+            LOG.debug("No step timeouts in cluster config")
+            return self._cluster
+        except (RemoteException, SunbeamException, OSError, ValueError) as e:
+            # clusterd is not available before bootstrap
+            LOG.debug(f"Cannot read step timeouts from cluster config: {e}")
+            return self._cluster
+        try:
+            self._cluster = parse_step_timeouts(json.loads(value))
+        except (TypeError, ValueError) as e:
+            LOG.warning(f"Ignoring invalid step timeouts in cluster config: {e}")
+        return self._cluster
+
+    def get(self, step: str, default: int) -> int:
+        """Return the timeout of step in seconds, default if not overridden."""
+        if step in self.overrides:
+            return self.overrides[step]
+        return self._cluster_timeouts().get(step, default)
+
+
+# Timeouts of the steps of the running command, configured by the CLI
+STEP_TIMEOUTS = StepTimeouts()
+
+
+def step_timeout(step: object, default: int) -> int:
+    """Return the timeout of step in seconds, default if not overridden.
+
+    All the steps look their timeouts up through it, so that they can be
+    overridden by name.
+    """
+    name = step_name(step)
+    timeout = STEP_TIMEOUTS.get(name, default)
+    if timeout != default:
+        LOG.debug(f"Timeout of step {name} overridden to {timeout}s")
+    return timeout
+
+
+def step_timeout_message(step: object, timeout: int, error: Exception) -> str:
+    """Return the error of step exceeding its timeout, naming both."""
+    return (
+        f"Step {step_name(step)} timed out after {format_duration(timeout)}"
+        f" ({error}). Set a longer timeout for it with --timeouts or the"
+        f" {STEP_TIMEOUTS_KEY} cluster config key"
+    )
@@ -0,0 +1,197 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+"""Declarative topology of a cluster.
+
+A topology file declares the nodes of the cluster, along with their roles,
+labels and addresses. Bootstrap converges the cluster towards it, acting only
+on the differences between the topology and the nodes of the cluster.
+"""
+
+import ipaddress
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+
+import pydantic
+import yaml
+
+from sunbeam.core.common import CUSTOM_ROLE_PATTERN, CustomRole, Role
+
+# Mirrors the validation of the node labels by clusterd
+LABEL_KEY_PATTERN = re.compile(r"^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$")
+MAX_LABEL_KEY_LENGTH = 63
+MAX_LABEL_VALUE_LENGTH = 256
+
+DEFAULT_ROLES = ["control", "compute"]
+
+
+class TopologyNode(pydantic.BaseModel):
+    """A node declared in the topology."""
+
+    model_config = pydantic.ConfigDict(extra="forbid")
+
+    name: str = pydantic.Field(description="Fully qualified domain name of the node")
+    roles: list[str] = pydantic.Field(
+        default=DEFAULT_ROLES,
+        description="Built-in or custom roles of the node",
+    )
+    labels: dict[str, str] = pydantic.Field(
+        default={}, description="Labels of the node"
+    )
+    address: str | None = pydantic.Field(
+        default=None, description="Management address of the node"
+    )
+
+    @pydantic.field_validator("name")
+    @classmethod
+    def _validate_name(cls, v: str) -> str:
+        name = v.rstrip(".")
+        if not name:
+            raise ValueError("name must not be empty")
+        return name
+
+    @pydantic.field_validator("roles")
+    @classmethod
+    def _validate_roles(cls, v: list[str]) -> list[str]:
+        if not v:
+            raise ValueError("at least one role is required")
+        roles = list(dict.fromkeys(role.lower() for role in v))
+        for role in roles:
+            if role.upper() not in Role.__members__ and not (
+                CUSTOM_ROLE_PATTERN.match(role)
+            ):
+                raise ValueError(f"invalid role {role!r}")
+        return roles
+
+    @pydantic.field_validator("labels")
+    @classmethod
+    def _validate_labels(cls, v: dict[str, str]) -> dict[str, str]:
+        for key, value in v.items():
+            if len(key) > MAX_LABEL_KEY_LENGTH or not LABEL_KEY_PATTERN.match(key):
+                raise ValueError(
+                    f"invalid label key {key!r}: must be at most"
+                    f" {MAX_LABEL_KEY_LENGTH} lowercase alphanumerics, '-', '.'"
+                    " or '_'"
+                )
+            if len(value.encode()) > MAX_LABEL_VALUE_LENGTH:
+                raise ValueError(
+                    f"invalid value for label {key!r}: must be at most"
+                    f" {MAX_LABEL_VALUE_LENGTH} bytes"
+                )
+        return v
+
+    @pydantic.field_validator("address")
+    @classmethod
+    def _validate_address(cls, v: str | None) -> str | None:
+        if v is None:
+            return v
+        return str(ipaddress.ip_address(v))
+
+    def role_list(self) -> list[Role | CustomRole]:
+        """Return the roles of the node, the unknown ones being custom."""
+        return [
+            Role[role.upper()] if role.upper() in Role.__members__ else CustomRole(role)
+            for role in self.roles
+        ]
+
+
+class Topology(pydantic.BaseModel):
+    """The nodes the cluster is made of."""
+
+    model_config = pydantic.ConfigDict(extra="forbid")
+
+    nodes: list[TopologyNode]
+
+    @pydantic.field_validator("nodes")
+    @classmethod
+    def _validate_nodes(cls, v: list[TopologyNode]) -> list[TopologyNode]:
+        if not v:
+            raise ValueError("at least one node is required")
+        names = [node.name for node in v]
+        duplicated = sorted({name for name in names if names.count(name) > 1})
+        if duplicated:
+            raise ValueError(f"duplicated nodes: {', '.join(duplicated)}")
+        return v
+
+    def get_node(self, name: str) -> TopologyNode | None:
+        """Return the node declared with name, if any."""
+        return next((node for node in self.nodes if node.name == name), None)
+
+    @classmethod
+    def load(cls, path: Path) -> "Topology":
+        """Load a topology file.
+
+        Raises ValueError if the file is not a valid topology.
+        """
+        try:
+            with path.open() as f:
+                data = yaml.safe_load(f)
+        except (OSError, yaml.YAMLError) as e:
+            raise ValueError(f"Failed to read topology {str(path)}: {e}") from e
+        try:
+            return cls.model_validate(data)
+        except pydantic.ValidationError as e:
+            raise ValueError(f"Invalid topology {str(path)}: {e}") from e
+
+
+def member_address(member: dict) -> str | None:
+    """Return the address of a cluster member, without its port."""
+    address = member.get("address")
+    if not address:
+        return None
+    host = address.rsplit(":", 1)[0]
+    return host.strip("[]")
+
+
+@dataclass
+class TopologyDrift:
+    """Differences between a topology and the nodes of the cluster."""
+
+    # Declared nodes not part of the cluster
+    missing: list[TopologyNode] = field(default_factory=list)
+    # Roles, declared and actual, of the nodes whose roles differ
+    roles: dict[str, tuple[list[str], list[str]]] = field(default_factory=dict)
+    # Declared labels of the nodes whose labels differ
+    labels: dict[str, dict[str, str]] = field(default_factory=dict)
+    # Addresses, declared and actual, of the nodes whose address differs
+    addresses: dict[str, tuple[str, str | None]] = field(default_factory=dict)
+    # Nodes of the cluster not declared in the topology
+    extra: list[str] = field(default_factory=list)
+
+    def is_empty(self) -> bool:
+        """Whether the cluster matches the topology."""
+        return not (
+            self.missing or self.roles or self.labels or self.addresses or self.extra
+        )
+
+
+def topology_drift(
+    topology: Topology, nodes: list[dict], members: list[dict]
+) -> TopologyDrift:
+    """Compare the topology with the nodes and members of the cluster.
+
+    Roles are compared regardless of their order. The addresses are only
+    compared for the nodes declaring one.
+    """
+    drift = TopologyDrift()
+    actual = {node["name"]: node for node in nodes}
+    addresses = {member["name"]: member_address(member) for member in members}
+
+    for declared in topology.nodes:
+        node = actual.get(declared.name)
+        if node is None:
+            drift.missing.append(declared)
+            continue
+
+        roles = node.get("role") or []
+        if sorted(declared.roles) != sorted(roles):
+            drift.roles[declared.name] = (declared.roles, roles)
+        if declared.labels != (node.get("labels") or {}):
+            drift.labels[declared.name] = declared.labels
+        address = addresses.get(declared.name)
+        if declared.address is not None and declared.address != address:
+            drift.addresses[declared.name] = (declared.address, address)
+
+    drift.extra = [name for name in actual if topology.get_node(name) is None]
+    return drift
@@ -44,6 +44,26 @@ class WatcherActionFailedException(Exception):
     pass
 
 
+class WatcherAuditException(SunbeamException):
+    """Raise if a Watcher audit does not produce a feasible action plan."""
+
+    def __init__(self, message: str, audit: str):
+        super().__init__(message)
+        self.audit = audit
+
+
+class WatcherAuditTimeoutException(WatcherAuditException):
+    """Raise if a Watcher audit does not complete in time."""
+
+    pass
+
+
+class WatcherAuditFailedException(WatcherAuditException):
+    """Raise if a Watcher audit fails or has no feasible action plan."""
+
+    pass
+
+
 def get_watcher_client(deployment: Deployment) -> "watcher_client.Client":
     jhelper = deployment.get_juju_helper(keystone=True)
     conn = get_admin_connection(jhelper, deployment)
@@ -127,19 +147,28 @@ def create_audit(
         audit_type=audit_type,
         parameters=parameters,
     )
-    audit_details = _wait_resource_in_target_state(
-        client=client,
-        resource_name="audit",
-        resource_uuid=audit.uuid,
-    )
+    try:
+        audit_details = _wait_resource_in_target_state(
+            client=client,
+            resource_name="audit",
+            resource_uuid=audit.uuid,
+        )
+    except SunbeamException as e:
+        raise WatcherAuditTimeoutException(
+            f"Watcher audit {audit.uuid} did not complete in {WAIT_TIMEOUT}s",
+            audit.uuid,
+        ) from e
 
     if audit_details.state == "SUCCEEDED":
         LOG.debug(f"Create Watcher audit {audit.uuid} successfully")
     else:
         LOG.debug(f"Create Watcher audit {audit.uuid} failed")
-        raise SunbeamException(
-            f"Create watcher audit failed, template: {template.name}"
-        )
+        message = f"Create watcher audit failed, template: {template.name}"
+        # Watcher explains why the audit failed from API version 1.5
+        status_message = getattr(audit_details, "status_message", None)
+        if isinstance(status_message, str) and status_message:
+            message += f": {status_message}"
+        raise WatcherAuditFailedException(message, audit.uuid)
 
     _check_audit_plans_recommended(client=client, audit=audit)
     return audit
@@ -153,11 +182,20 @@ def _check_audit_plans_recommended(
     # In case there is not action been generated, the action plan state
     # will be SUCCEEDED at the beginning.
     if not all(plan.state in ["RECOMMENDED", "SUCCEEDED"] for plan in action_plans):
-        raise SunbeamException(
-            f"Not all action plan for audit({audit.uuid}) is RECOMMENDED"
+        raise WatcherAuditFailedException(
+            f"Not all action plan for audit({audit.uuid}) is RECOMMENDED,"
+            " no feasible action plan",
+            audit.uuid,
         )
 
 
+def get_action_plans(
+    client: "watcher_client.Client", audit: "watcher.Audit"
+) -> list["watcher.ActionPlan"]:
+    """Get list of action plans by audit, with their efficacy."""
+    return client.action_plan.list(audit=audit.uuid, detail=True)
+
+
 def get_actions(
     client: "watcher_client.Client",
     audit: "watcher.Audit",
@@ -3,7 +3,7 @@
 
 import logging
 import typing
-from collections.abc import Mapping
+from collections.abc import Collection, Mapping
 
 from rich.console import Console
 
@@ -32,8 +32,10 @@ from sunbeam.core.openstack import OPENSTACK_MODEL
 from sunbeam.core.openstack_api import (
     get_admin_connection,
     guests_on_hypervisor,
+    hypervisors_free_capacity,
 )
 from sunbeam.core.watcher import WATCHER_APPLICATION
+from sunbeam.features.maintenance.utils import instance_migration_blockers
 from sunbeam.lazy import LazyImport
 from sunbeam.provider.maas.deployment import is_maas_deployment
 from sunbeam.steps.k8s import (
@@ -46,9 +48,11 @@ from sunbeam.steps.k8s import (
 if typing.TYPE_CHECKING:
     import lightkube.core.client as l_client
     import lightkube.resources.apps_v1 as l_apps
+    import openstack
 else:
     l_client = LazyImport("lightkube.core.client")
     l_apps = LazyImport("lightkube.resources.apps_v1")
+    openstack = LazyImport("openstack")
 from sunbeam.steps.microceph import APPLICATION as _MICROCEPH_APPLICATION
 
 console = Console()
@@ -145,9 +149,85 @@ class NoEphemeralDiskCheck(Check):
         return True
 
 
+class MigratableInstancesCheck(Check):
+    """Detect the instances which cannot be migrated off the node.
+
+    All the instances of the node are classified before any migration
+    starts, those blocked by a constraint are kept in blocked with the
+    constraint. They fail the check unless force, or skip_unmigratable which
+    leaves them on the node. Hosts in exclude are not migration targets.
+    """
+
+    def __init__(
+        self,
+        jhelper: JujuHelper,
+        deployment: Deployment,
+        node: str,
+        force: bool,
+        skip_unmigratable: bool = False,
+        exclude: Collection[str] = (),
+    ):
+        super().__init__(
+            "Check instances can be migrated off the node",
+            "Checking if all the instances can be migrated off the node",
+        )
+        self.jhelper = jhelper
+        self.deployment = deployment
+        self.node = node
+        self.force = force
+        self.skip_unmigratable = skip_unmigratable
+        self.exclude = exclude
+        self.blocked: list[dict[str, typing.Any]] = []
+
+    def run(self) -> bool:
+        """Run the check logic here.
+
+        Return True if check is Ok.
+        Otherwise update self.message and return False.
+        """
+        conn = get_admin_connection(self.jhelper, self.deployment)
+        instances = guests_on_hypervisor(hypervisor_name=self.node, conn=conn)
+
+        capacity: dict[str, dict[str, int]] | None
+        try:
+            capacity = {
+                host: resources
+                for host, resources in hypervisors_free_capacity(
+                    conn, exclude=self.node
+                ).items()
+                if host not in self.exclude
+            }
+        except openstack.exceptions.SDKException as e:
+            LOG.warning(f"Failed to read capacity of target hosts: {e}")
+            capacity = None
+
+        self.blocked = instance_migration_blockers(conn, instances, capacity)
+
+        if self.blocked:
+            blocked = ", ".join(
+                f"{instance['instance']} ({instance['reason']})"
+                for instance in self.blocked
+            )
+            _msg = f"Instances cannot be migrated off node {self.node}: {blocked}"
+            if self.force or self.skip_unmigratable:
+                LOG.warning(f"Ignore issue: {_msg}")
+                return True
+            self.message = (
+                f"{_msg}. Use --skip-unmigratable to leave them on the node or"
+                " --force to try anyway"
+            )
+            return False
+        return True
+
+
 class NoInstancesOnNodeCheck(Check):
     def __init__(
-        self, jhelper: JujuHelper, deployment: Deployment, node: str, force: bool
+        self,
+        jhelper: JujuHelper,
+        deployment: Deployment,
+        node: str,
+        force: bool,
+        ignore: Collection[str] = (),
     ):
         super().__init__(
             "Check no instance on the node",
@@ -157,6 +237,8 @@ class NoInstancesOnNodeCheck(Check):
         self.deployment = deployment
         self.node = node
         self.force = force
+        # Instances left on the node on purpose
+        self.ignore = ignore
 
     def run(self) -> bool:
         """Run the check logic here.
@@ -166,7 +248,11 @@ class NoInstancesOnNodeCheck(Check):
         """
         conn = get_admin_connection(self.jhelper, self.deployment)
 
-        instances = guests_on_hypervisor(hypervisor_name=self.node, conn=conn)
+        instances = [
+            inst
+            for inst in guests_on_hypervisor(hypervisor_name=self.node, conn=conn)
+            if inst.id not in self.ignore
+        ]
 
         if len(instances) > 0:
             instance_ids = ",".join([inst.id for inst in instances])
@@ -2,26 +2,70 @@
 # SPDX-License-Identifier: Apache-2.0
 
 import abc
+import datetime
+import getpass
+import json
 import logging
-from typing import Any
+import re
+import socket
+import threading
+import typing
+from concurrent.futures import ThreadPoolExecutor
+from typing import Any, Callable, Collection
 
 import click
+from requests.exceptions import HTTPError
 from rich.console import Console
+from rich.table import Table
 
+from sunbeam.clusterd.client import Client
+from sunbeam.clusterd.models import (
+    MaintenanceOptions,
+    MaintenanceProgress,
+    ScheduledMaintenance,
+)
+from sunbeam.clusterd.service import (
+    ConfigItemNotFoundException,
+    InvalidScheduledMaintenanceException,
+    NodeNotExistInClusterException,
+    RemoteException,
+    ScheduledMaintenanceConflictException,
+    ScheduledMaintenanceNotFoundException,
+)
+from sunbeam.commands.operation import click_option_detach
 from sunbeam.core.checks import Check, run_preflight_checks
 from sunbeam.core.common import (
+    FORMAT_JSON,
+    FORMAT_TABLE,
     BaseStep,
+    Result,
+    ResultType,
     get_step_message,
     run_plan,
 )
 from sunbeam.core.deployment import Deployment
 from sunbeam.core.juju import JujuHelper
+from sunbeam.core.openstack_api import (
+    get_admin_connection,
+    guests_on_hypervisor,
+    hypervisors_free_capacity,
+)
+from sunbeam.core.questions import ConfirmQuestion
 from sunbeam.features.maintenance import checks
 from sunbeam.features.maintenance.utils import (
+    FORMAT_JSON_STREAM,
+    NO_CAPACITY_REASON,
+    MigrationProgressRenderer,
+    MigrationProgressReporter,
     OperationGoal,
     OperationViewer,
+    follow_migration_progress,
     get_cluster_status,
+    plan_batch_capacity,
+    plan_instance_migrations,
+    print_migration_plan,
 )
+from sunbeam.lazy import LazyImport
 from sunbeam.steps.hypervisor import EnableHypervisorStep
 from sunbeam.steps.maintenance import (
     CordonControlRoleNodeStep,
@@ -29,20 +73,183 @@ from sunbeam.steps.maintenance import (
     CreateWatcherWorkloadBalancingAuditStep,
     DrainControlRoleNodeStep,
     MicroCephActionStep,
+    MigrateInstancesStep,
+    RunWatcherActionsStep,
     RunWatcherAuditStep,
     UncordonControlRoleNodeStep,
+    progress_message,
 )
 from sunbeam.steps.microovn import EnableMicroOVNStep
 from sunbeam.utils import click_option_show_hints, pass_method_obj
 
+if typing.TYPE_CHECKING:
+    import openstack
+else:
+    openstack = LazyImport("openstack")
+
 console = Console()
 LOG = logging.getLogger(__name__)
+TTL_REGEX = re.compile(r"^(\d+(h|m|s))+$")
+MAX_PARALLEL_MIGRATIONS_CONFIG_KEY = "maintenance.max-parallel-migrations"
 
 
 class CommandCancelledError(Exception):
     """Command cancelled error."""
 
 
+def validate_ttl(ctx: click.Context, param: click.Parameter, value: str | None):
+    """Check the maintenance TTL is a positive duration such as 2h or 1h30m."""
+    if value is None:
+        return value
+    # Zero durations, such as 0h, have no non-zero digit
+    if not TTL_REGEX.match(value) or not any(c in "123456789" for c in value):
+        raise click.BadParameter(
+            f"{value!r} is not a positive duration such as 2h or 1h30m"
+        )
+    return value
+
+
+def parse_window_time(value: str) -> str:
+    """Parse an ISO 8601 time with a timezone, return it as RFC3339 UTC."""
+    try:
+        time = datetime.datetime.fromisoformat(value)
+    except ValueError:
+        raise click.BadParameter(
+            f"{value!r} is not an ISO 8601 time such as 2025-07-01T02:00:00Z"
+        )
+    if time.tzinfo is None:
+        raise click.BadParameter(
+            f"{value!r} has no timezone, such as Z or +02:00 at its end"
+        )
+    return time.astimezone(datetime.timezone.utc).strftime("%Y-%m-%dT%H:%M:%SZ")
+
+
+def validate_at(ctx: click.Context, param: click.Parameter, value: str | None):
+    """Check the maintenance start is a time with a timezone."""
+    if value is None:
+        return value
+    return parse_window_time(value)
+
+
+def validate_window(ctx: click.Context, param: click.Parameter, value: str | None):
+    """Check the maintenance window is a START/END interval of times."""
+    if value is None:
+        return value
+    start, sep, end = value.partition("/")
+    if not sep:
+        raise click.BadParameter(
+            f"{value!r} is not a START/END window such as"
+            " 2025-07-01T02:00:00Z/2025-07-01T04:00:00Z"
+        )
+    return parse_window_time(start), parse_window_time(end)
+
+
+def get_max_parallel_migrations(client: Client, limit: int | None = None) -> int:
+    """Return how many instance migrations run at once, 0 leaves it to Watcher.
+
+    The limit given on the command line takes precedence over the one
+    configured for all the maintenance operations.
+    """
+    if limit is not None:
+        return limit
+    try:
+        value = client.cluster.get_config(MAX_PARALLEL_MIGRATIONS_CONFIG_KEY)
+    except ConfigItemNotFoundException:
+        return 0
+    return int(json.loads(value))
+
+
+def watcher_actions_step(
+    deployment: Deployment,
+    jhelper: JujuHelper,
+    node: str,
+    audit_info: dict[str, Any],
+    max_parallel_migrations: int,
+    progress: Callable[[MaintenanceProgress], None] | None = None,
+    skip: Collection[str] = (),
+) -> BaseStep:
+    """Return the step running the actions planned by a Watcher audit.
+
+    Watcher runs the action plan unless migrations are limited, in which case
+    the actions run through Nova, the limit of migrations at once, reporting
+    their progress to progress. The instances in skip are left on the node,
+    the other actions running through Nova as Watcher runs whole plans.
+    """
+    actions = [
+        action
+        for action in audit_info["actions"]
+        if action.action_type != "migrate"
+        or action.input_parameters["resource_id"] not in skip
+    ]
+    if max_parallel_migrations or skip:
+        if RunWatcherActionsStep.can_run(actions):
+            return RunWatcherActionsStep(
+                deployment=deployment,
+                jhelper=jhelper,
+                node=node,
+                actions=actions,
+                # Watcher runs all the migrations at once
+                max_parallel=max_parallel_migrations or len(actions),
+                progress=progress,
+            )
+        if skip:
+            raise click.ClickException(
+                "Watcher planned actions which cannot run outside of its action"
+                f" plan, cannot leave instances {', '.join(sorted(skip))} on"
+                f" {node}"
+            )
+        LOG.warning(
+            "Watcher planned actions which cannot be limited, not limiting"
+            " parallel migrations"
+        )
+    return RunWatcherAuditStep(
+        deployment=deployment,
+        node=node,
+        audit=audit_info["audit"],
+    )
+
+
+def maintenance_actor() -> str:
+    """Return who triggers the maintenance operation."""
+    return f"{getpass.getuser()}@{socket.gethostname()}"
+
+
+def record_maintenance_status(
+    client: Client,
+    node: str,
+    status: str,
+    results: dict[str, Result],
+    strategy: str = "",
+    ttl: str = "",
+) -> None:
+    """Persist the outcome of a maintenance operation in the cluster database.
+
+    The node is recorded as degraded if any of the operation steps failed.
+    Failing to record the status does not fail the operation.
+    """
+    failed = [
+        name
+        for name, result in results.items()
+        if result.result_type == ResultType.FAILED
+    ]
+    message = ""
+    if failed:
+        status = "degraded"
+        message = f"Failed steps: {', '.join(failed)}"
+
+    try:
+        client.cluster.update_maintenance_status(
+            node,
+            status,
+            strategy=strategy,
+            triggered_by=maintenance_actor(),
+            message=message,
+            ttl=ttl,
+        )
+    except (RemoteException, HTTPError) as e:
+        LOG.warning(f"Failed to record maintenance status of {node}: {e}")
+
+
 class MaintenanceCommand(abc.ABC):
     """Base class for any maintenance mode command.
 
@@ -113,24 +320,115 @@ class EnableMaintenance(MaintenanceCommand):
         enable_ceph_crush_rebalancing: bool = False,
         disable_live_migration: bool = False,
         disable_cold_migration: bool = False,
+        ttl: str = "",
+        output_format: str = FORMAT_TABLE,
+        max_parallel_migrations: int | None = None,
+        yes: bool = False,
+        exclude: Collection[str] = (),
+        skip_unmigratable: bool = False,
     ):
         self.node = node
         self.deployment = deployment
         self.cluster_status = cluster_status
         self.force = force
+        self.skip_unmigratable = skip_unmigratable
         self.stop_osds = stop_osds
         self.allow_downtime = allow_downtime
         self.enable_ceph_crush_rebalancing = enable_ceph_crush_rebalancing
         self.disable_live_migration = disable_live_migration
         self.disable_cold_migration = disable_cold_migration
+        self.ttl = ttl
+        self.output_format = output_format
+        self.max_parallel_migrations = max_parallel_migrations
+        self.yes = yes
+        # Hosts not to migrate instances to, such as nodes entering maintenance
+        self.exclude = exclude
+        self.check_results: list[dict[str, Any]] = []
+        self.migrations: list[dict[str, Any]] = []
+        self.unmigratable: list[dict[str, Any]] = []
+        # Instances blocked by a constraint, found by the pre-flight checks
+        self.blocked: list[dict[str, Any]] = []
+        # Instances left on the node with skip_unmigratable
+        self.skipped: set[str] = set()
+        self.capacity: dict[str, dict[str, int]] | None = None
 
         self.model = deployment.openstack_machines_model
         self.client = deployment.get_client()
         self.jhelper = JujuHelper(deployment.juju_controller)
         self.ops_viewer = OperationViewer(self.node, OperationGoal.EnableMaintenance)
 
+    @property
+    def strategy(self) -> str:
+        """Options used to enter maintenance mode."""
+        options = {
+            "force": self.force,
+            "skip-unmigratable": self.skip_unmigratable,
+            "stop-osds": self.stop_osds,
+            "allow-downtime": self.allow_downtime,
+            "enable-ceph-crush-rebalancing": self.enable_ceph_crush_rebalancing,
+            "disable-live-migration": self.disable_live_migration,
+            "disable-cold-migration": self.disable_cold_migration,
+        }
+        return ",".join(name for name, enabled in options.items() if enabled)
+
+    @property
+    def no_capacity(self) -> list[dict[str, Any]]:
+        """Instances which cannot migrate for lack of capacity on target hosts."""
+        return [
+            instance
+            for instance in self.unmigratable
+            if instance["reason"].startswith(NO_CAPACITY_REASON)
+        ]
+
+    @property
+    def plan(self) -> dict[str, Any]:
+        """Machine readable plan to enable maintenance mode."""
+        capacity = None
+        if self.capacity is not None:
+            capacity = {
+                "sufficient": not self.no_capacity,
+                "hosts": {
+                    host: {
+                        "vcpus": resources.get("VCPU", 0),
+                        "memory_mb": resources.get("MEMORY_MB", 0),
+                    }
+                    for host, resources in self.capacity.items()
+                },
+            }
+        return {
+            "node": self.node,
+            "ready": all(check["passed"] for check in self.check_results)
+            and (not self.no_capacity or self.skip_unmigratable),
+            "checks": self.check_results,
+            "operations": self.ops_viewer.operations,
+            "migrations": self.migrations,
+            "unmigratable": self.unmigratable,
+            "capacity": capacity,
+        }
+
     def check(self, console: Console) -> None:
-        """Run pre-flight checks."""
+        """Run pre-flight checks.
+
+        The outcome of each check is kept for the plan, which is printed
+        before failing when machine readable output is requested.
+        """
+        for check in self._preflight_checks():
+            LOG.debug(f"Starting pre-flight check {check.name}")
+            with console.status(f"{check.description} ... "):
+                passed = check.run()
+            if isinstance(check, checks.MigratableInstancesCheck):
+                self.blocked = check.blocked
+                self.unmigratable = list(check.blocked)
+            self.check_results.append(
+                {"name": check.name, "passed": passed, "message": check.message}
+            )
+            if not passed:
+                if self.output_format == FORMAT_JSON:
+                    console.print(json.dumps(self.plan, indent=2), end="")
+                raise click.ClickException(check.message)
+
+    def _preflight_checks(self) -> list[Check]:
+        """Return the pre-flight checks for the roles of the node."""
         node_status = self.cluster_status.get(self.node, "")
 
         preflight_checks: list[Check] = [
@@ -153,6 +451,14 @@ class EnableMaintenance(MaintenanceCommand):
                     node=self.node,
                     force=self.force,
                 ),
+                checks.MigratableInstancesCheck(
+                    jhelper=self.jhelper,
+                    deployment=self.deployment,
+                    node=self.node,
+                    force=self.force,
+                    skip_unmigratable=self.skip_unmigratable,
+                    exclude=self.exclude,
+                ),
             ]
 
         if "storage" in node_status:
@@ -195,13 +501,23 @@ class EnableMaintenance(MaintenanceCommand):
                 ),
             ]
 
-        run_preflight_checks(preflight_checks, console)
+        return preflight_checks
 
     def apply(self, console: Console, show_hints: bool, plan_results: dict) -> None:
         """Run the core commands."""
         node_status = self.cluster_status.get(self.node, "")
 
-        confirm = self.ops_viewer.prompt()
+        if self.no_capacity and not (self.force or self.skip_unmigratable):
+            instances = ", ".join(instance["instance"] for instance in self.no_capacity)
+            raise click.ClickException(
+                f"{NO_CAPACITY_REASON} to migrate instances {instances} off"
+                f" {self.node}, add compute capacity, use --skip-unmigratable to"
+                " leave them on the node or --force to try anyway"
+            )
+        if self.skip_unmigratable:
+            self.skipped = {instance["instance"] for instance in self.unmigratable}
+
+        confirm = self.yes or self.ops_viewer.prompt()
         if not confirm:
             raise CommandCancelledError("Operation Cancelled!")
 
@@ -212,10 +528,18 @@ class EnableMaintenance(MaintenanceCommand):
                 plan_results, CreateWatcherHostMaintenanceAuditStep
             )
             operation_plan.append(
-                RunWatcherAuditStep(
-                    deployment=self.deployment,
-                    node=self.node,
-                    audit=audit_info["audit"],
+                watcher_actions_step(
+                    self.deployment,
+                    self.jhelper,
+                    self.node,
+                    audit_info,
+                    get_max_parallel_migrations(
+                        self.client, self.max_parallel_migrations
+                    ),
+                    progress=MigrationProgressReporter(
+                        self.client, self.node, MigrationProgressRenderer(console)
+                    ),
+                    skip=self.skipped,
                 )
             )
 
@@ -256,6 +580,14 @@ class EnableMaintenance(MaintenanceCommand):
             ]
 
         operation_plan_results = run_plan(operation_plan, console, show_hints, True)
+        record_maintenance_status(
+            self.client,
+            self.node,
+            "enabled",
+            operation_plan_results,
+            strategy=self.strategy,
+            ttl=self.ttl,
+        )
 
         self.ops_viewer.check_operation_succeeded(operation_plan_results)
 
@@ -278,6 +610,7 @@ class EnableMaintenance(MaintenanceCommand):
                     deployment=self.deployment,
                     node=self.node,
                     force=self.force,
+                    ignore=self.skipped,
                 ),
             ]
 
@@ -363,6 +696,7 @@ class EnableMaintenance(MaintenanceCommand):
 
         if "compute" in node_status:
             self.ops_viewer.add_watch_actions(actions=audit_info["actions"])
+            self._plan_migrations(actions=audit_info["actions"])
         if "storage" in node_status:
             self.ops_viewer.add_maintenance_action_steps(
                 action_result=microceph_enter_maintenance_dry_run_action_result
@@ -375,10 +709,49 @@ class EnableMaintenance(MaintenanceCommand):
                 result=drain_k8s_node_dry_run_result
             )
 
-        console.print(self.ops_viewer.dry_run_message)
+        if self.output_format == FORMAT_JSON:
+            console.print(json.dumps(self.plan, indent=2), end="")
+        else:
+            console.print(self.ops_viewer.dry_run_message)
+            print_migration_plan(console, self.migrations, self.unmigratable)
 
         return generate_operation_plan_results
 
+    def _plan_migrations(self, actions: list) -> None:
+        """Plan where the instances of the node migrate to.
+
+        The plan runs without target hosts capacity when it cannot be read.
+        """
+        try:
+            conn = get_admin_connection(self.jhelper, self.deployment)
+            instances = guests_on_hypervisor(hypervisor_name=self.node, conn=conn)
+        except openstack.exceptions.SDKException as e:
+            raise click.ClickException(
+                f"Failed to list instances on {self.node}: {e}"
+            ) from e
+
+        try:
+            self.capacity = {
+                host: resources
+                for host, resources in hypervisors_free_capacity(
+                    conn, exclude=self.node
+                ).items()
+                if host not in self.exclude
+            }
+        except openstack.exceptions.SDKException as e:
+            LOG.warning(f"Failed to read capacity of target hosts: {e}")
+            self.capacity = None
+
+        migrations, unmigratable = plan_instance_migrations(
+            instances, actions, self.capacity
+        )
+        # Watcher plans the migration of blocked instances, which fail
+        blocked = {instance["instance"] for instance in self.blocked}
+        self.migrations = [m for m in migrations if m["instance"] not in blocked]
+        self.unmigratable = self.blocked + [
+            u for u in unmigratable if u["instance"] not in blocked
+        ]
+
 
 class DisableMaintenance(MaintenanceCommand):
     """Command to disable maintenance mode."""
@@ -389,11 +762,20 @@ class DisableMaintenance(MaintenanceCommand):
         deployment: Deployment,
         cluster_status: dict[str, Any],
         disable_instance_rebalancing: bool = False,
+        yes: bool = False,
+        max_parallel_migrations: int | None = None,
+        uncordon: bool = False,
     ):
         self.node = node
         self.deployment = deployment
         self.cluster_status = cluster_status
         self.disable_instance_rebalancing = disable_instance_rebalancing
+        self.yes = yes
+        self.max_parallel_migrations = max_parallel_migrations
+        self.uncordon = uncordon
+        # Set by the checks, a cordoned node keeps its hypervisor disabled
+        # unless uncordoned
+        self.cordoned = False
 
         self.model = deployment.openstack_machines_model
         self.client = deployment.get_client()
@@ -415,35 +797,47 @@ class DisableMaintenance(MaintenanceCommand):
             ]
 
         run_preflight_checks(preflight_checks, console)
+        node = self.client.cluster.get_node_info(self.node)
+        self.cordoned = bool(node.get("cordoned"))
+
+    @property
+    def keep_cordon(self) -> bool:
+        """Whether the node stays cordoned once out of maintenance."""
+        return self.cordoned and not self.uncordon
 
     def apply(self, console: Console, show_hints: bool, plan_results: dict) -> None:
         """Run the core commands."""
         node_status = self.cluster_status.get(self.node, "")
 
-        confirm = self.ops_viewer.prompt()
+        confirm = self.yes or self.ops_viewer.prompt()
         if not confirm:
             raise CommandCancelledError("Operation Cancelled!")
 
         operation_plan: list[BaseStep] = []
         if "compute" in node_status:
-            operation_plan += [
-                EnableHypervisorStep(
-                    client=self.client,
-                    node=self.node,
-                    jhelper=self.jhelper,
-                    model=self.model,
-                ),
-            ]
+            if not self.keep_cordon:
+                operation_plan.append(
+                    EnableHypervisorStep(
+                        client=self.client,
+                        node=self.node,
+                        jhelper=self.jhelper,
+                        model=self.model,
+                    )
+                )
             if not self.disable_instance_rebalancing:
                 audit_info = get_step_message(
                     plan_results,
                     CreateWatcherWorkloadBalancingAuditStep,
                 )
                 operation_plan += [
-                    RunWatcherAuditStep(
-                        deployment=self.deployment,
-                        node=self.node,
-                        audit=audit_info["audit"],
+                    watcher_actions_step(
+                        self.deployment,
+                        self.jhelper,
+                        self.node,
+                        audit_info,
+                        get_max_parallel_migrations(
+                            self.client, self.max_parallel_migrations
+                        ),
                     ),
                 ]
         if "storage" in node_status:
@@ -473,7 +867,19 @@ class DisableMaintenance(MaintenanceCommand):
             )
 
         operation_plan_results = run_plan(operation_plan, console, show_hints, True)
+        strategy = ""
+        if self.disable_instance_rebalancing:
+            strategy = "disable-instance-workload-rebalancing"
+        record_maintenance_status(
+            self.client,
+            self.node,
+            "disabled",
+            operation_plan_results,
+            strategy=strategy,
+        )
         self.ops_viewer.check_operation_succeeded(operation_plan_results)
+        if self.cordoned and self.uncordon:
+            self.client.cluster.set_node_cordon(self.node, False)
 
     def verify(self, console: Console) -> None:
         """Run verification steps."""
@@ -493,6 +899,11 @@ class DisableMaintenance(MaintenanceCommand):
         run_preflight_checks(post_checks, console)
 
         console.print(f"Disable maintenance for node: {self.node}")
+        if self.keep_cordon:
+            console.print(
+                f"Node {self.node} is still cordoned, uncordon it to schedule"
+                " new instances on it"
+            )
 
     def dry_run(self, console: Console, show_hints: bool) -> dict:
         """Dry run command steps."""
@@ -549,7 +960,8 @@ class DisableMaintenance(MaintenanceCommand):
         )
 
         if "compute" in node_status:
-            self.ops_viewer.add_step(step_name=EnableHypervisorStep.__name__)
+            if not self.keep_cordon:
+                self.ops_viewer.add_step(step_name=EnableHypervisorStep.__name__)
             if not self.disable_instance_rebalancing:
                 self.ops_viewer.add_watch_actions(actions=audit_info["actions"])
         if "storage" in node_status:
@@ -568,10 +980,142 @@ class DisableMaintenance(MaintenanceCommand):
         return generate_operation_plan_results
 
 
+def batch_no_capacity(
+    deployment: Deployment,
+    jhelper: JujuHelper,
+    cluster_status: dict[str, Any],
+    nodes: Collection[str],
+) -> dict[str, list[dict[str, Any]]]:
+    """Return the instances which cannot migrate off each node of a batch.
+
+    The compute nodes of the batch enter maintenance in order, their
+    instances migrate to the hosts outside the batch. Nodes whose instances
+    all fit are left out. Capacity is not verified when it cannot be read.
+    """
+    compute = [node for node in nodes if "compute" in cluster_status.get(node, "")]
+    if not compute:
+        return {}
+
+    try:
+        conn = get_admin_connection(jhelper, deployment)
+        instances = {
+            node: guests_on_hypervisor(hypervisor_name=node, conn=conn)
+            for node in compute
+        }
+        capacity = hypervisors_free_capacity(conn)
+    except openstack.exceptions.SDKException as e:
+        LOG.warning(f"Failed to read capacity of target hosts: {e}")
+        return {}
+
+    return {
+        node: no_capacity
+        for node, no_capacity in plan_batch_capacity(instances, capacity).items()
+        if no_capacity
+    }
+
+
+def run_maintenance_batch(
+    nodes: list[str],
+    run_node: Callable[[str, Console], None],
+    parallel: int = 1,
+    continue_on_error: bool = False,
+) -> dict[str, dict[str, str]]:
+    """Run the maintenance operation of each node, at most parallel at once.
+
+    Once a node fails, the nodes not started yet are skipped unless
+    continue_on_error. Nodes run in order with the console when one at a
+    time, quietly otherwise, with the nodes in progress displayed.
+    Return the status, and error if any, of each node.
+    """
+    results = {node: {"status": "pending", "error": ""} for node in nodes}
+    failed = threading.Event()
+    lock = threading.Lock()
+
+    def progress() -> str:
+        in_progress = [n for n, r in results.items() if r["status"] == "in progress"]
+        finished = [n for n, r in results.items() if r["status"] != "pending"]
+        return (
+            f"Running nodes {', '.join(in_progress)}"
+            f" ({len(finished) - len(in_progress)}/{len(nodes)} finished)"
+        )
+
+    def run(node: str, node_console: Console, status=None) -> None:
+        with lock:
+            if failed.is_set() and not continue_on_error:
+                results[node]["status"] = "skipped"
+                return
+            results[node]["status"] = "in progress"
+            if status is not None:
+                status.update(progress())
+        try:
+            run_node(node, node_console)
+        except Exception as e:
+            LOG.debug(f"Maintenance operation failed on node {node}", exc_info=True)
+            with lock:
+                results[node] = {"status": "failed", "error": str(e)}
+                failed.set()
+        else:
+            with lock:
+                results[node]["status"] = "done"
+        if status is not None:
+            with lock:
+                status.update(progress())
+
+    if parallel == 1:
+        for index, node in enumerate(nodes, start=1):
+            if not failed.is_set() or continue_on_error:
+                console.print(f"Node {node} ({index}/{len(nodes)})")
+            run(node, console)
+        return results
+
+    with (
+        console.status(progress()) as status,
+        ThreadPoolExecutor(max_workers=parallel) as executor,
+    ):
+        # Only one status can be displayed, the nodes run quietly
+        for node in nodes:
+            executor.submit(run, node, Console(quiet=True), status)
+
+    return results
+
+
+def print_batch_results(results: dict[str, dict[str, str]]) -> None:
+    """Print the outcome of the maintenance operation of each node."""
+    colors = {"done": "green", "failed": "red", "skipped": "yellow"}
+    table = Table()
+    table.add_column("Node", justify="left")
+    table.add_column("Status", justify="left")
+    table.add_column("Error", justify="left", overflow="fold")
+    for node, result in results.items():
+        color = colors.get(result["status"], "default")
+        table.add_row(
+            node, f"[{color}]{result['status']}[/{color}]", result["error"]
+        )
+    console.print(table)
+
+
 @click.command()
-@click.argument(
-    "node",
-    type=click.STRING,
+@click.argument("nodes", metavar="NODE...", nargs=-1, type=click.STRING)
+@click.option(
+    "--all-compute",
+    help="Enable maintenance mode for all the compute nodes",
+    is_flag=True,
+    default=False,
+)
+@click.option(
+    "--parallel",
+    help="Maximum number of nodes entering maintenance mode at once",
+    type=click.IntRange(min=1),
+    default=1,
+)
+@click.option(
+    "--continue-on-error",
+    help=(
+        "Keep enabling maintenance mode for the other nodes when it fails for"
+        " one. Defaults to skip the nodes not started yet."
+    ),
+    is_flag=True,
+    default=False,
 )
 @click.option(
     "--force",
@@ -579,6 +1123,16 @@ class DisableMaintenance(MaintenanceCommand):
     is_flag=True,
     default=False,
 )
+@click.option(
+    "--skip-unmigratable",
+    help=(
+        "Leave the instances which cannot be migrated off the node on it, such"
+        " as instances with PCI passthrough devices, pinned CPUs or"
+        " anti-affinity. Defaults to refuse entering maintenance mode."
+    ),
+    is_flag=True,
+    default=False,
+)
 @click.option(
     "--dry-run",
     help="Show required operation steps to put node into maintenance mode",
@@ -624,28 +1178,154 @@ class DisableMaintenance(MaintenanceCommand):
     is_flag=False,
     flag_value="both",
 )
+@click.option(
+    "--ttl",
+    help=(
+        "Disable maintenance once this duration, e.g. 2h, has elapsed. The"
+        " disable is requested as an operation, run by `sunbeam operation"
+        " agent`. Enabling maintenance again sets a new TTL from now."
+    ),
+    type=str,
+    default=None,
+    callback=validate_ttl,
+)
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_TABLE, FORMAT_JSON]),
+    default=FORMAT_TABLE,
+    help="Output format of the plan, json requires --dry-run.",
+)
+@click.option(
+    "--max-parallel-migrations",
+    help=(
+        "Maximum number of instance migrations run at once, the others are"
+        " queued. Defaults to the maintenance configuration, 0 lets Watcher"
+        " run all the migrations at once."
+    ),
+    type=click.IntRange(min=0),
+    default=None,
+)
+@click.option(
+    "--at",
+    help=(
+        "Schedule maintenance mode to be enabled at this time, e.g."
+        " 2025-07-01T02:00:00Z, instead of now."
+    ),
+    type=str,
+    default=None,
+    callback=validate_at,
+)
+@click.option(
+    "--window",
+    help=(
+        "Schedule maintenance mode to be enabled in this START/END window,"
+        " e.g. 2025-07-01T02:00:00Z/2025-07-01T04:00:00Z. It fails"
+        " without running if the window closes before it could run."
+    ),
+    type=str,
+    default=None,
+    callback=validate_window,
+)
+@click.option(
+    "--yes",
+    help="Do not ask for confirmation, used when running scheduled maintenance",
+    default=False,
+    is_flag=True,
+)
+@click_option_detach
 @click_option_show_hints
 @pass_method_obj
 def enable(
     cls,
     deployment: Deployment,
-    node,
+    nodes: tuple[str, ...],
     force,
     dry_run,
     enable_ceph_crush_rebalancing,
     stop_osds,
     allow_downtime,
     disable_migration,
+    all_compute: bool = False,
+    parallel: int = 1,
+    continue_on_error: bool = False,
+    ttl: str | None = None,
+    format: str = FORMAT_TABLE,
+    max_parallel_migrations: int | None = None,
+    at: str | None = None,
+    window: tuple[str, str] | None = None,
+    yes: bool = False,
+    skip_unmigratable: bool = False,
     show_hints: bool = False,
 ) -> None:
-    """Enable maintenance mode for node."""
+    """Enable maintenance mode for nodes.
+
+    With --dry-run, run the pre-flight checks and plan the operations, such
+    as where the instances migrate to, without changing the nodes.
+
+    The pre-flight checks classify the instances of the node, those which
+    cannot be migrated, such as instances with PCI passthrough devices, fail
+    them with the constraint blocking each, unless --skip-unmigratable leaves
+    them on the node or --force tries anyway.
+
+    Several nodes enter maintenance mode one after the other, or --parallel
+    at once. Instances migrate to the hosts outside of the nodes given: a
+    node whose instances do not fit in the capacity left by the nodes before
+    it fails, unless --force.
+
+    With --at or --window, clusterd requests an operation enabling
+    maintenance mode once the window opens, run by `sunbeam operation agent`,
+    the operation-agent service of the snap set up by prepare-node. The
+    pre-flight checks run then. Without a running agent the maintenance fails
+    once the window closes. The scheduled maintenances are managed with the
+    schedule commands.
+    """
+    if format == FORMAT_JSON and not dry_run:
+        raise click.UsageError("--format json requires --dry-run")
+    if all_compute and nodes:
+        raise click.UsageError("NODE and --all-compute are mutually exclusive")
+    if at and window:
+        raise click.UsageError("--at and --window are mutually exclusive")
+
+    if at or window:
+        if dry_run:
+            raise click.UsageError("--at and --window cannot be used with --dry-run")
+        if all_compute or len(set(nodes)) != 1:
+            raise click.UsageError("--at and --window support a single NODE")
+        start, end = window or (at, "")
+        options = MaintenanceOptions(
+            force=force,
+            skip_unmigratable=skip_unmigratable,
+            stop_osds=stop_osds,
+            allow_downtime=allow_downtime,
+            enable_ceph_crush_rebalancing=enable_ceph_crush_rebalancing,
+            disable_migration=disable_migration or "",
+            ttl=ttl or "",
+            max_parallel_migrations=max_parallel_migrations,
+        )
+        schedule_maintenance(deployment, nodes[0], start, end, options)
+        return
+
+    jhelper = JujuHelper(deployment.juju_controller)
     cluster_status = get_cluster_status(
         deployment=deployment,
-        jhelper=JujuHelper(deployment.juju_controller),
+        jhelper=jhelper,
         console=console,
         show_hints=show_hints,
     )
 
+    if all_compute:
+        nodes = tuple(
+            node for node, status in cluster_status.items() if "compute" in status
+        )
+        if not nodes:
+            raise click.ClickException("No compute nodes in the cluster")
+    if not nodes:
+        raise click.UsageError("Missing argument NODE... or --all-compute")
+    nodes = tuple(dict.fromkeys(nodes))
+    if len(nodes) > 1 and format == FORMAT_JSON:
+        raise click.UsageError("--format json supports a single node")
+
     # Default to Watcher's defaults (both False) when not specified
     if not disable_migration:
         disable_live_migration = False
@@ -654,19 +1334,92 @@ def enable(
         disable_live_migration = disable_migration in ["both", "live"]
         disable_cold_migration = disable_migration in ["both", "cold"]
 
-    enable_maintenance = EnableMaintenance(
-        node,
-        deployment,
-        cluster_status,
-        force=force,
-        stop_osds=stop_osds,
-        allow_downtime=allow_downtime,
-        enable_ceph_crush_rebalancing=enable_ceph_crush_rebalancing,
-        disable_live_migration=disable_live_migration,
-        disable_cold_migration=disable_cold_migration,
+    def enable_maintenance(node: str, **kwargs) -> EnableMaintenance:
+        return EnableMaintenance(
+            node,
+            deployment,
+            cluster_status,
+            force=force,
+            stop_osds=stop_osds,
+            allow_downtime=allow_downtime,
+            enable_ceph_crush_rebalancing=enable_ceph_crush_rebalancing,
+            disable_live_migration=disable_live_migration,
+            disable_cold_migration=disable_cold_migration,
+            ttl=ttl or "",
+            output_format=format,
+            max_parallel_migrations=max_parallel_migrations,
+            skip_unmigratable=skip_unmigratable,
+            **kwargs,
+        )
+
+    if len(nodes) == 1:
+        enable_maintenance(nodes[0], yes=yes)(console, show_hints, dry_run)
+        return
+
+    if not dry_run and not yes:
+        question = ConfirmQuestion(
+            f"Continue to enable maintenance mode for nodes {', '.join(nodes)}?"
+        )
+        if not question.ask():
+            console.print("Operation Cancelled!")
+            return
+
+    no_capacity = batch_no_capacity(deployment, jhelper, cluster_status, nodes)
+
+    def run_node(node: str, node_console: Console) -> None:
+        if node in no_capacity and not (force or skip_unmigratable):
+            instances = ", ".join(i["instance"] for i in no_capacity[node])
+            raise click.ClickException(
+                f"{NO_CAPACITY_REASON} to migrate instances {instances}, once"
+                " the nodes before it enter maintenance"
+            )
+        enable_maintenance(
+            node, yes=True, exclude=[other for other in nodes if other != node]
+        )(node_console, show_hints, dry_run)
+
+    # The plans are printed as they are made
+    results = run_maintenance_batch(
+        list(nodes),
+        run_node,
+        parallel=1 if dry_run else parallel,
+        continue_on_error=continue_on_error,
     )
+    print_batch_results(results)
 
-    enable_maintenance(console, show_hints, dry_run)
+    failed = [node for node, result in results.items() if result["status"] == "failed"]
+    if failed:
+        raise click.ClickException(
+            f"Failed to enable maintenance mode for nodes {', '.join(failed)}"
+        )
+
+
+def schedule_maintenance(
+    deployment: Deployment,
+    node: str,
+    start: str,
+    end: str,
+    options: MaintenanceOptions,
+) -> None:
+    """Record a maintenance enable of node requested by clusterd in a window."""
+    client = deployment.get_client()
+    try:
+        scheduled = client.cluster.schedule_maintenance(
+            ScheduledMaintenance(
+                node=node, window_start=start, window_end=end, options=options
+            )
+        )
+    except (
+        InvalidScheduledMaintenanceException,
+        NodeNotExistInClusterException,
+        ScheduledMaintenanceConflictException,
+    ) as e:
+        raise click.ClickException(str(e)) from e
+    window = scheduled.window_start
+    if scheduled.window_end:
+        window += f" - {scheduled.window_end}"
+    console.print(
+        f"Scheduled maintenance {scheduled.id} of node {node} in window {window}"
+    )
 
 
 @click.command()
@@ -686,6 +1439,19 @@ def enable(
     default=False,
     is_flag=True,
 )
+@click.option(
+    "--yes",
+    help="Do not ask for confirmation, used when maintenance TTL elapses",
+    default=False,
+    is_flag=True,
+)
+@click.option(
+    "--uncordon",
+    help="Uncordon the node if it is cordoned, it stays cordoned otherwise",
+    default=False,
+    is_flag=True,
+)
+@click_option_detach
 @click_option_show_hints
 @pass_method_obj
 def disable(
@@ -694,9 +1460,15 @@ def disable(
     disable_instance_workload_rebalancing,
     dry_run,
     node,
+    yes: bool = False,
+    uncordon: bool = False,
     show_hints: bool = False,
 ) -> None:
-    """Disable maintenance mode for node."""
+    """Disable maintenance mode for node.
+
+    A cordoned node stays cordoned, new instances are not scheduled on it
+    until it is uncordoned.
+    """
     cluster_status = get_cluster_status(
         deployment=deployment,
         jhelper=JujuHelper(deployment.juju_controller),
@@ -709,6 +1481,383 @@ def disable(
         deployment,
         cluster_status,
         disable_instance_rebalancing=disable_instance_workload_rebalancing,
+        yes=yes,
+        uncordon=uncordon,
     )
 
     disable_maintenance(console, show_hints, dry_run)
+
+
+def instances_not_on_node(
+    conn: "openstack.connection.Connection", node: str, instances: Collection[str]
+) -> dict[str, str]:
+    """Return where the instances not on the node are, if they exist."""
+    on_node = {
+        instance.id
+        for instance in guests_on_hypervisor(hypervisor_name=node, conn=conn)
+    }
+    mismatched = {}
+    for instance_id in instances:
+        if instance_id in on_node:
+            continue
+        server = conn.compute.find_server(
+            instance_id, ignore_missing=True, all_projects=True
+        )
+        if server is None:
+            mismatched[instance_id] = "not found"
+        else:
+            mismatched[instance_id] = f"on {server.hypervisor_hostname}"
+    return mismatched
+
+
+@click.command()
+@click.argument(
+    "node",
+    type=click.STRING,
+)
+@click.option(
+    "--instance",
+    "instances",
+    help="ID of an instance to migrate off the node, repeat for several.",
+    multiple=True,
+    required=True,
+)
+@click.option(
+    "--max-parallel-migrations",
+    help=(
+        "Maximum number of instance migrations run at once, the others are"
+        " queued. Defaults to the maintenance configuration, 0 runs all the"
+        " migrations at once."
+    ),
+    type=click.IntRange(min=0),
+    default=None,
+)
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_TABLE, FORMAT_JSON_STREAM]),
+    default=FORMAT_TABLE,
+    help="Output format, json-stream writes the progress events as json lines.",
+)
+@click_option_detach
+@click_option_show_hints
+@pass_method_obj
+def drain(
+    cls,
+    deployment: Deployment,
+    node: str,
+    instances: tuple[str, ...],
+    max_parallel_migrations: int | None = None,
+    format: str = FORMAT_TABLE,
+    show_hints: bool = False,
+) -> None:
+    """Live-migrate instances off node, without entering maintenance mode.
+
+    Only the instances given migrate, the Nova scheduler picks their target
+    hosts. The node keeps running its other instances, and can still receive
+    new ones. The progress of the migrations is shown as they run, a
+    migration making no progress for a while is reported stalled.
+    """
+    instances = tuple(dict.fromkeys(instances))
+    jhelper = JujuHelper(deployment.juju_controller)
+    try:
+        conn = get_admin_connection(jhelper, deployment)
+        mismatched = instances_not_on_node(conn, node, instances)
+    except openstack.exceptions.SDKException as e:
+        raise click.ClickException(f"Failed to list instances on {node}: {e}") from e
+    if mismatched:
+        raise click.ClickException(
+            f"Instances not on {node}: "
+            + ", ".join(
+                f"{instance} ({where})" for instance, where in mismatched.items()
+            )
+        )
+
+    client = deployment.get_client()
+    limit = get_max_parallel_migrations(client, max_parallel_migrations)
+    migrations = [
+        {"instance": instance, "migration_type": "live", "destination": None}
+        for instance in instances
+    ]
+    # The spinner goes to stderr, not to mix with the json lines
+    plan_console = console if format == FORMAT_TABLE else Console(stderr=True)
+    progress = MigrationProgressReporter(
+        client, node, MigrationProgressRenderer(plan_console, format)
+    )
+    run_plan(
+        [
+            MigrateInstancesStep(
+                deployment,
+                jhelper,
+                node,
+                migrations,
+                limit or len(migrations),
+                progress=progress,
+            )
+        ],
+        plan_console,
+        show_hints,
+        True,
+    )
+
+    failed = [m["instance"] for m in migrations if m["state"] != "SUCCEEDED"]
+    if format == FORMAT_JSON_STREAM:
+        if failed:
+            raise click.ClickException(
+                f"Failed to migrate instances {', '.join(failed)} off {node}"
+            )
+        return
+
+    table = Table()
+    table.add_column("Instance", justify="left")
+    table.add_column("Status", justify="left")
+    table.add_column("Target host", justify="left")
+    for migration in migrations:
+        table.add_row(
+            migration["instance"], migration["state"], migration["host"] or ""
+        )
+    console.print(table)
+
+    if failed:
+        raise click.ClickException(
+            f"Failed to migrate instances {', '.join(failed)} off {node}"
+        )
+
+
+@click.command()
+@click.argument(
+    "node",
+    type=click.STRING,
+)
+@click.option(
+    "--follow",
+    help="Wait for new progress events until all the migrations completed.",
+    default=False,
+    is_flag=True,
+)
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_TABLE, FORMAT_JSON_STREAM]),
+    default=FORMAT_TABLE,
+    help="Output format, json-stream writes the progress events as json lines.",
+)
+@pass_method_obj
+def progress(cls, deployment: Deployment, node: str, follow: bool, format: str):
+    """Show the progress of the instance migrations off node.
+
+    The progress is reported by the maintenance enable and drain commands
+    running on this node, while they migrate instances through Nova.
+    """
+    client = deployment.get_client()
+    if format == FORMAT_JSON_STREAM:
+        follow_migration_progress(
+            client, node, MigrationProgressRenderer(console, format), follow
+        )
+        return
+    if not follow:
+        for event in client.cluster.get_maintenance_progress(node):
+            console.print(f"{event.time} {progress_message(event)}")
+        return
+    with console.status(f"Waiting for the progress of {node}") as status:
+        follow_migration_progress(
+            client,
+            node,
+            MigrationProgressRenderer(console, format, status=status),
+            follow,
+        )
+
+
+@click.command()
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_TABLE, FORMAT_JSON]),
+    default=FORMAT_TABLE,
+    help="Output format.",
+)
+@pass_method_obj
+def status(cls, deployment: Deployment, format: str) -> None:
+    """Show the maintenance status of all the nodes."""
+    client = deployment.get_client()
+    statuses = client.cluster.list_maintenance_status().root
+
+    if format == FORMAT_JSON:
+        console.print_json(json.dumps([item.model_dump() for item in statuses]))
+        return
+
+    table = Table()
+    table.add_column("Node", justify="left")
+    table.add_column("Status", justify="left")
+    table.add_column("Entered at", justify="left")
+    table.add_column("Strategy", justify="left")
+    table.add_column("Triggered by", justify="left")
+    table.add_column("Expires at", justify="left")
+    table.add_column("Message", justify="left")
+    for item in statuses:
+        table.add_row(
+            item.node,
+            item.status,
+            item.entered_at,
+            item.strategy,
+            item.triggered_by,
+            item.expires_at,
+            item.message,
+        )
+    console.print(table)
+
+
+@click.command()
+@click.option(
+    "--max-parallel-migrations",
+    help=(
+        "Maximum number of instance migrations run at once by maintenance"
+        " operations, 0 lets Watcher run all the migrations at once."
+    ),
+    type=click.IntRange(min=0),
+    default=None,
+)
+@pass_method_obj
+def configure(
+    cls, deployment: Deployment, max_parallel_migrations: int | None = None
+) -> None:
+    """Configure the maintenance operations of all the nodes."""
+    client = deployment.get_client()
+    if max_parallel_migrations is not None:
+        client.cluster.update_config(
+            MAX_PARALLEL_MIGRATIONS_CONFIG_KEY, json.dumps(max_parallel_migrations)
+        )
+    limit = get_max_parallel_migrations(client)
+    console.print(f"Max parallel migrations: {limit or 'unlimited'}")
+
+
+@click.command("list")
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_TABLE, FORMAT_JSON]),
+    default=FORMAT_TABLE,
+    help="Output format.",
+)
+@pass_method_obj
+def list_schedule(cls, deployment: Deployment, format: str) -> None:
+    """List the scheduled maintenances, including the ones which ran."""
+    client = deployment.get_client()
+    scheduled = client.cluster.list_scheduled_maintenances()
+
+    if format == FORMAT_JSON:
+        console.print_json(json.dumps([item.model_dump() for item in scheduled]))
+        return
+
+    table = Table()
+    table.add_column("ID", justify="right")
+    table.add_column("Node", justify="left")
+    table.add_column("Window start", justify="left")
+    table.add_column("Window end", justify="left")
+    table.add_column("Status", justify="left")
+    table.add_column("Created by", justify="left")
+    table.add_column("Operation", justify="right")
+    table.add_column("Message", justify="left")
+    for item in scheduled:
+        table.add_row(
+            str(item.id),
+            item.node,
+            item.window_start,
+            item.window_end,
+            item.status,
+            item.created_by,
+            str(item.operation) if item.operation else "",
+            item.message,
+        )
+    console.print(table)
+
+
+@click.command("cancel")
+@click.argument("id", type=click.INT)
+@pass_method_obj
+def cancel_schedule(cls, deployment: Deployment, id: int) -> None:
+    """Cancel a pending scheduled maintenance."""
+    client = deployment.get_client()
+    try:
+        client.cluster.cancel_scheduled_maintenance(id)
+    except (
+        ScheduledMaintenanceNotFoundException,
+        ScheduledMaintenanceConflictException,
+    ) as e:
+        raise click.ClickException(str(e)) from e
+    console.print(f"Scheduled maintenance {id} cancelled")
+
+
+@click.command("show")
+@click.argument("node", type=str)
+@click.option(
+    "--operation",
+    type=click.Choice(["enable", "disable"]),
+    default=None,
+    help="Only show the plan of the maintenance operation.",
+)
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_TABLE, FORMAT_JSON]),
+    default=FORMAT_TABLE,
+    help="Output format.",
+)
+@pass_method_obj
+def show_plan(
+    cls, deployment: Deployment, node: str, operation: str | None, format: str
+) -> None:
+    """Show the Watcher plans of the maintenance operations of a node.
+
+    The latest plan of each operation is shown, with the actions planned by
+    Watcher, their state and rationale, or why no plan was produced.
+    """
+    client = deployment.get_client()
+    try:
+        plans = client.cluster.get_maintenance_plans(node, operation)
+    except NodeNotExistInClusterException as e:
+        raise click.ClickException(str(e)) from e
+
+    if format == FORMAT_JSON:
+        console.print_json(json.dumps([plan.model_dump() for plan in plans]))
+        return
+
+    if not plans:
+        console.print(f"No Watcher plan recorded for node {node}")
+        return
+
+    for plan in plans:
+        console.print(
+            f"Maintenance {plan.operation} plan of node {node}: {plan.state}"
+            f" (audit {plan.audit or '-'}, recorded {plan.created_at}"
+            f" by {plan.created_by})",
+            markup=False,
+        )
+        if plan.reason:
+            console.print(f"Reason: {plan.reason}", markup=False)
+        for indicator in plan.efficacy:
+            value = "-" if indicator.value is None else f"{indicator.value:g}"
+            console.print(
+                f"{indicator.description or indicator.name}: {value}"
+                f" {indicator.unit}".rstrip(),
+                markup=False,
+            )
+        if not plan.actions:
+            continue
+        table = Table()
+        table.add_column("Action", justify="left")
+        table.add_column("Type", justify="left")
+        table.add_column("State", justify="left")
+        table.add_column("Parameters", justify="left")
+        table.add_column("Rationale", justify="left")
+        for action in plan.actions:
+            table.add_row(
+                action.uuid,
+                action.type,
+                action.state,
+                ", ".join(
+                    f"{key}={value}" for key, value in sorted(action.parameters.items())
+                ),
+                action.description,
+            )
+        console.print(table)
@@ -13,12 +13,33 @@ from sunbeam.features.interface.v1.base import (
     EnableDisableFeature,
     FeatureRequirement,
 )
+from sunbeam.features.maintenance.commands import (
+    cancel_schedule as cancel_schedule_maintenance_cmd,
+)
+from sunbeam.features.maintenance.commands import (
+    configure as configure_maintenance_cmd,
+)
 from sunbeam.features.maintenance.commands import (
     disable as disable_maintenance_cmd,
 )
+from sunbeam.features.maintenance.commands import (
+    drain as drain_maintenance_cmd,
+)
 from sunbeam.features.maintenance.commands import (
     enable as enable_maintenance_cmd,
 )
+from sunbeam.features.maintenance.commands import (
+    list_schedule as list_schedule_maintenance_cmd,
+)
+from sunbeam.features.maintenance.commands import (
+    progress as progress_maintenance_cmd,
+)
+from sunbeam.features.maintenance.commands import (
+    show_plan as show_plan_maintenance_cmd,
+)
+from sunbeam.features.maintenance.commands import (
+    status as status_maintenance_cmd,
+)
 from sunbeam.utils import click_option_show_hints, pass_method_obj
 
 LOG = logging.getLogger(__name__)
@@ -67,6 +88,14 @@ class MaintenanceFeature(EnableDisableFeature):
     def maintenance_group(self) -> None:
         """Manage maintenance mode."""
 
+    @click.group()
+    def schedule_group(self) -> None:
+        """Manage the maintenances scheduled in change windows."""
+
+    @click.group()
+    def plan_group(self) -> None:
+        """Inspect the Watcher plans of the maintenance operations."""
+
     def enabled_commands(self) -> dict[str, list[dict]]:
         """Dict of clickgroup along with commands.
 
@@ -77,5 +106,18 @@ class MaintenanceFeature(EnableDisableFeature):
             "cluster.maintenance": [
                 {"name": "enable", "command": enable_maintenance_cmd},
                 {"name": "disable", "command": disable_maintenance_cmd},
+                {"name": "drain", "command": drain_maintenance_cmd},
+                {"name": "progress", "command": progress_maintenance_cmd},
+                {"name": "status", "command": status_maintenance_cmd},
+                {"name": "configure", "command": configure_maintenance_cmd},
+                {"name": "schedule", "command": self.schedule_group},
+                {"name": "plan", "command": self.plan_group},
+            ],
+            "cluster.maintenance.schedule": [
+                {"name": "list", "command": list_schedule_maintenance_cmd},
+                {"name": "cancel", "command": cancel_schedule_maintenance_cmd},
+            ],
+            "cluster.maintenance.plan": [
+                {"name": "show", "command": show_plan_maintenance_cmd},
             ],
         }
@@ -3,13 +3,21 @@
 
 import enum
 import logging
+import sys
 from os import linesep
-from typing import TYPE_CHECKING, Any
+from typing import IO, TYPE_CHECKING, Any
 
 import click
+from requests.exceptions import HTTPError
 from rich.console import Console
+from rich.status import Status
+from rich.table import Table
 
+from sunbeam.clusterd.client import Client
+from sunbeam.clusterd.models import MaintenanceProgress
+from sunbeam.clusterd.service import RemoteException
 from sunbeam.core.common import (
+    FORMAT_TABLE,
     Result,
     ResultType,
     get_step_message,
@@ -18,6 +26,7 @@ from sunbeam.core.common import (
 from sunbeam.core.deployment import Deployment
 from sunbeam.core.juju import JujuHelper
 from sunbeam.core.questions import ConfirmQuestion, Question
+from sunbeam.lazy import LazyImport
 from sunbeam.provider.local.steps import LocalClusterStatusStep
 from sunbeam.provider.maas.steps import MaasClusterStatusStep
 from sunbeam.steps.cluster_status import ClusterStatusStep
@@ -26,17 +35,29 @@ from sunbeam.steps.maintenance import (
     CordonControlRoleNodeStep,
     DrainControlRoleNodeStep,
     MicroCephActionStep,
+    RunWatcherActionsStep,
     RunWatcherAuditStep,
     UncordonControlRoleNodeStep,
+    progress_message,
 )
 from sunbeam.steps.microovn import EnableMicroOVNStep
 
 if TYPE_CHECKING:
+    import openstack
     from watcherclient import v1 as watcher
+else:
+    openstack = LazyImport("openstack")
 
 
 console = Console()
 LOG = logging.getLogger(__name__)
+NO_CAPACITY_REASON = "No target host with capacity"
+# Neutron vNIC types binding a port to a device of its host
+HOST_BOUND_VNIC_TYPES = ("direct", "direct-physical", "macvtap", "vdpa")
+# Output format writing one json object per line as events occur
+FORMAT_JSON_STREAM = "json-stream"
+# Seconds clusterd waits for new progress events when following them
+PROGRESS_WAIT = 30
 
 
 def get_cluster_status(
@@ -60,6 +81,350 @@ def get_cluster_status(
     }
 
 
+def plan_instance_migrations(
+    instances: list["openstack.compute.v2.server.Server"],
+    actions: list["watcher.Action"],
+    capacity: dict[str, dict[str, int]] | None,
+) -> tuple[list[dict[str, Any]], list[dict[str, Any]]]:
+    """Return where the instances migrate to, and those which cannot migrate.
+
+    The destination of an instance is the host planned by Watcher, or else an
+    estimate of where the Nova scheduler places it: the target host with the
+    most free memory fitting the instance, once the larger instances are
+    placed. Scheduler filters, such as affinities or aggregates, are not
+    accounted for. Capacity is not verified when unknown.
+    """
+    free = {host: dict(resources) for host, resources in (capacity or {}).items()}
+    planned = {
+        action.input_parameters["resource_id"]: action
+        for action in actions
+        if action.action_type == "migrate"
+    }
+
+    migrations = []
+    unmigratable = []
+    # Place larger instances first, they are the hardest to fit
+    for instance in sorted(
+        instances,
+        key=lambda i: (i.flavor.ram or 0, i.flavor.vcpus or 0),
+        reverse=True,
+    ):
+        action = planned.get(instance.id)
+        if action is None:
+            unmigratable.append(
+                {
+                    "instance": instance.id,
+                    "name": instance.name,
+                    "reason": "Watcher planned no migration of the instance",
+                }
+            )
+            continue
+
+        destination = action.input_parameters.get("destination_node")
+        migration = {
+            "instance": instance.id,
+            "name": instance.name,
+            "migration_type": action.input_parameters["migration_type"],
+            "destination": destination,
+            # The destination is left to the Nova scheduler
+            "scheduled": destination is None,
+        }
+        if capacity is None:
+            migrations.append(migration)
+            continue
+
+        host = _place_instance(free, instance, destination)
+        if host is None:
+            unmigratable.append(_no_capacity(instance))
+            continue
+
+        migration["destination"] = host
+        migrations.append(migration)
+
+    return migrations, unmigratable
+
+
+def plan_batch_capacity(
+    instances: dict[str, list["openstack.compute.v2.server.Server"]],
+    capacity: dict[str, dict[str, int]],
+) -> dict[str, list[dict[str, Any]]]:
+    """Return the instances of each node which cannot migrate off it.
+
+    Nodes enter maintenance in the given order, the instances of a node take
+    the capacity left by the nodes before it. A node whose instances do not
+    all fit takes no capacity, it is not entering maintenance. The nodes of
+    the batch are not target hosts, whether before or after them.
+    """
+    free = {
+        host: dict(resources)
+        for host, resources in capacity.items()
+        if host not in instances
+    }
+    no_capacity = {}
+    for node, node_instances in instances.items():
+        node_free = {host: dict(resources) for host, resources in free.items()}
+        no_capacity[node] = [
+            _no_capacity(instance)
+            for instance in sorted(
+                node_instances,
+                key=lambda i: (i.flavor.ram or 0, i.flavor.vcpus or 0),
+                reverse=True,
+            )
+            if _place_instance(node_free, instance) is None
+        ]
+        if not no_capacity[node]:
+            free = node_free
+    return no_capacity
+
+
+def _place_instance(
+    free: dict[str, dict[str, int]],
+    instance: "openstack.compute.v2.server.Server",
+    destination: str | None = None,
+) -> str | None:
+    """Take the resources of the instance off the host it fits best.
+
+    Return the host, the one with the most free memory, or None if none fits.
+    """
+    vcpus = instance.flavor.vcpus or 0
+    ram = instance.flavor.ram or 0
+    candidates = sorted(
+        (
+            host
+            for host, resources in free.items()
+            if destination in (None, host)
+            and resources.get("VCPU", 0) >= vcpus
+            and resources.get("MEMORY_MB", 0) >= ram
+        ),
+        key=lambda host: (-free[host].get("MEMORY_MB", 0), host),
+    )
+    if not candidates:
+        return None
+
+    host = candidates[0]
+    free[host]["VCPU"] = free[host].get("VCPU", 0) - vcpus
+    free[host]["MEMORY_MB"] = free[host].get("MEMORY_MB", 0) - ram
+    return host
+
+
+def _no_capacity(instance: "openstack.compute.v2.server.Server") -> dict[str, Any]:
+    """Return why the instance cannot migrate for lack of capacity."""
+    return {
+        "instance": instance.id,
+        "name": instance.name,
+        "reason": (
+            f"{NO_CAPACITY_REASON} for {instance.flavor.vcpus or 0} vCPUs and"
+            f" {instance.flavor.ram or 0} MiB of memory"
+        ),
+    }
+
+
+def instance_migration_blockers(
+    conn: "openstack.connection.Connection",
+    instances: list["openstack.compute.v2.server.Server"],
+    capacity: dict[str, dict[str, int]] | None,
+) -> list[dict[str, Any]]:
+    """Return the instances which cannot migrate off their host, and why.
+
+    Instances with PCI passthrough devices or SR-IOV ports are bound to their
+    host. Instances with pinned CPUs need a target host with as many free
+    dedicated CPUs, and instances of an anti-affinity server group a target
+    host running no other instance of the group. The target hosts are those
+    of capacity, pinned CPUs and anti-affinity are not verified when it is
+    unknown.
+
+    :raises: openstack.exceptions.SDKException
+    """
+    ids = {instance.id for instance in instances}
+    groups = {}
+    if capacity is not None:
+        for group in conn.compute.server_groups(all_projects=True):
+            policies = [group.policy] if group.policy else group.policies or []
+            if "anti-affinity" not in policies:
+                continue
+            for member in group.member_ids or []:
+                if member in ids:
+                    groups[member] = group
+
+    # Hosts of the instances of the groups which are not migrating
+    hosts: dict[str, str | None] = {}
+
+    def host_of(member: str) -> str | None:
+        if member not in hosts:
+            try:
+                hosts[member] = conn.compute.get_server(member).hypervisor_hostname
+            except openstack.exceptions.NotFoundException:
+                hosts[member] = None
+        return hosts[member]
+
+    blockers = []
+    for instance in instances:
+        extra_specs = instance.flavor.extra_specs or {}
+        vcpus = instance.flavor.vcpus or 0
+        reasons = []
+        if extra_specs.get("pci_passthrough:alias"):
+            reasons.append(
+                f"PCI passthrough device {extra_specs['pci_passthrough:alias']}"
+            )
+        ports = [
+            port.id
+            for port in conn.network.ports(device_id=instance.id)
+            if port.binding_vnic_type in HOST_BOUND_VNIC_TYPES
+        ]
+        if ports:
+            reasons.append(f"SR-IOV ports {', '.join(ports)}")
+        if capacity is not None and (
+            extra_specs.get("hw:cpu_policy") == "dedicated"
+            or "resources:PCPU" in extra_specs
+        ):
+            if not any(
+                resources.get("PCPU", 0) >= vcpus for resources in capacity.values()
+            ):
+                reasons.append(f"No target host with {vcpus} free dedicated CPUs")
+        group = groups.get(instance.id)
+        if group is not None:
+            taken = {
+                host_of(member) for member in group.member_ids if member not in ids
+            }
+            if capacity and all(host in taken for host in capacity):
+                reasons.append(
+                    f"Anti-affinity server group {group.name}, an instance of"
+                    " the group runs on every target host"
+                )
+        if reasons:
+            blockers.append(
+                {
+                    "instance": instance.id,
+                    "name": instance.name,
+                    "reason": "; ".join(reasons),
+                }
+            )
+    return blockers
+
+
+def print_migration_plan(
+    console: Console,
+    migrations: list[dict[str, Any]],
+    unmigratable: list[dict[str, Any]],
+):
+    """Print the planned instance migrations, and those which cannot happen."""
+    if migrations:
+        table = Table(title="Instance migrations")
+        table.add_column("Instance", justify="left")
+        table.add_column("Name", justify="left")
+        table.add_column("Type", justify="left")
+        table.add_column("Destination", justify="left")
+        for migration in migrations:
+            destination = migration["destination"] or "unknown"
+            if migration["scheduled"]:
+                destination += " (scheduler)"
+            table.add_row(
+                migration["instance"],
+                migration["name"],
+                migration["migration_type"],
+                destination,
+            )
+        console.print(table)
+    if unmigratable:
+        table = Table(title="Instances which cannot be migrated")
+        table.add_column("Instance", justify="left")
+        table.add_column("Name", justify="left")
+        table.add_column("Reason", justify="left")
+        for instance in unmigratable:
+            table.add_row(instance["instance"], instance["name"], instance["reason"])
+        console.print(table)
+
+
+class MigrationProgressRenderer:
+    """Render the progress events of the migrations off a node.
+
+    In json-stream format, each event is written to stream as a json line. In
+    table format, status shows the latest progress, the failed and stalled
+    migrations are printed as they are reported.
+    """
+
+    def __init__(
+        self,
+        console: Console,
+        output_format: str = FORMAT_TABLE,
+        stream: IO[str] | None = None,
+        status: Status | None = None,
+    ):
+        self.console = console
+        self.output_format = output_format
+        self.stream = stream or sys.stdout
+        self.status = status
+
+    def __call__(self, event: MaintenanceProgress) -> None:
+        """Render event."""
+        if self.output_format == FORMAT_JSON_STREAM:
+            self.stream.write(event.model_dump_json() + "\n")
+            self.stream.flush()
+            return
+        message = progress_message(event)
+        if self.status is not None:
+            self.status.update(message)
+        if event.state in ("failed", "stalled"):
+            self.console.print(f"Warning: {message}")
+
+
+class MigrationProgressReporter:
+    """Publish the progress events of the migrations off a node to clusterd.
+
+    Events are then rendered with render, numbered by clusterd. Publishing
+    is best effort, it stops once clusterd fails to record an event, as a
+    clusterd predating the progress endpoint does.
+    """
+
+    def __init__(
+        self,
+        client: Client,
+        node: str,
+        render: MigrationProgressRenderer,
+    ):
+        self.client = client
+        self.node = node
+        self.render = render
+        self.publish = True
+
+    def __call__(self, event: MaintenanceProgress) -> None:
+        """Publish event, then render it."""
+        if self.publish:
+            try:
+                event = self.client.cluster.add_maintenance_progress(
+                    self.node, [event]
+                )[0]
+            except (RemoteException, HTTPError) as e:
+                LOG.debug(f"Not publishing the progress of {self.node}: {e}")
+                self.publish = False
+        self.render(event)
+
+
+def follow_migration_progress(
+    client: Client,
+    node: str,
+    render: MigrationProgressRenderer,
+    follow: bool = False,
+) -> None:
+    """Render the progress events of the migrations off node recorded by clusterd.
+
+    With follow, wait for new events until all the migrations completed.
+    """
+    after = 0
+    while True:
+        events = client.cluster.get_maintenance_progress(
+            node, after=after, wait=PROGRESS_WAIT if follow else 0
+        )
+        for event in events:
+            render(event)
+        if events:
+            after = events[-1].seq
+            if events[-1].completed == events[-1].total:
+                return
+        if not follow:
+            return
+
+
 class OperationGoal(enum.Enum):
     EnableMaintenance = "EnableMaintenance"
     DisableMaintenance = "DisableMaintenance"
@@ -213,7 +578,7 @@ class OperationViewer:
             if result.result_type == ResultType.FAILED:
                 failed_result = result
                 failed_result_name = name
-            if name == RunWatcherAuditStep.__name__:
+            if name in (RunWatcherAuditStep.__name__, RunWatcherActionsStep.__name__):
                 self.update_watcher_actions_result(result.message)
             elif name == MicroCephActionStep.__name__:
                 self.update_maintenance_action_steps_result(result.message)
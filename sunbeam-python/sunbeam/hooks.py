@@ -6,7 +6,7 @@ import logging
 from pathlib import Path
 from typing import TYPE_CHECKING
 
-from snaphelpers import Snap
+from snaphelpers import Snap, UnknownConfigKey
 
 from sunbeam.feature_gates import FEATURE_GATES, validate_feature_gate_config
 from sunbeam.log import setup_logging
@@ -18,12 +18,16 @@ LOG = logging.getLogger(__name__)
 DEFAULT_CONFIG = {
     "daemon.group": "snap_daemon",
     "daemon.debug": False,
+    "daemon.shutdown-grace-period": "30s",
     "k8s.provider": "k8s",
     "deployment.risk": "stable",
     "deployment.version": "2024.1",
 }
 
 OPTION_KEYS = {k.split(".")[0] for k in DEFAULT_CONFIG.keys()}
+# User whose deployment and credentials the operation-agent service runs
+# the operations with, the service runs once it is set
+OPERATION_AGENT_USER_KEY = "operation-agent.user"
 
 
 def _update_default_config(snap: Snap) -> None:
@@ -204,6 +208,29 @@ def _sync_feature_gates_to_cluster(snap: Snap) -> None:
     sync_feature_gates_from_snap_to_cluster(client, snap)
 
 
+def _update_operation_agent(snap: Snap, previous_user: str) -> str:
+    """Run the operation-agent service as the configured user, if any.
+
+    :param snap: the snap reference
+    :param previous_user: the user the service ran as
+    :return: the user the service runs as, empty if it is stopped
+    """
+    try:
+        user = snap.config.get(OPERATION_AGENT_USER_KEY) or ""
+    except UnknownConfigKey:
+        user = ""
+
+    service = snap.services.list()["operation-agent"]
+    if not user:
+        service.stop(disable=True)
+    elif previous_user and previous_user != user:
+        service.start(enable=True)
+        service.restart()
+    else:
+        service.start(enable=True)
+    return user
+
+
 def install(snap: Snap) -> None:
     """Runs the 'install' hook for the snap.
 
@@ -262,6 +289,11 @@ def configure(snap: Snap) -> None:
     config_path = snap.paths.data / "config.yaml"
     old_config = _read_config(config_path)
     new_config = snap.config.get_options(*OPTION_KEYS).as_dict()
+    new_config["operation-agent"] = {
+        "user": _update_operation_agent(
+            snap, old_config.get("operation-agent", {}).get("user", "")
+        )
+    }
     _write_config(config_path, new_config)
     if old_config.get("daemon") != new_config.get("daemon"):
         snap.services.list()["clusterd"].restart()
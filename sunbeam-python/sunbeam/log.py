@@ -3,6 +3,7 @@
 
 import logging
 import sys
+import uuid
 from datetime import datetime
 from pathlib import Path
 from typing import Union
@@ -11,6 +12,10 @@ from rich.logging import RichHandler
 
 MAX_LOG_FILES = 100
 
+# ID of this command invocation, sent along each clusterd request to
+# correlate the command log with the clusterd logs
+REQUEST_ID = uuid.uuid4().hex
+
 
 def setup_root_logging(logfile: Path | None = None):
     """Sets up the root logging level for the application.
@@ -73,6 +78,7 @@ def setup_root_logging(logfile: Path | None = None):
         )
         logger.addHandler(file_handler)
         logger.debug(f"Logging to {str(logfile)!r}")
+    logger.debug(f"Request ID of the clusterd requests: {REQUEST_ID}")
 
 
 def setup_logging(logfile: Union[Path, str]) -> None:
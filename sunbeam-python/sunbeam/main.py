@@ -9,6 +9,12 @@ import click
 from snaphelpers import Snap
 
 from sunbeam import log
+from sunbeam.clusterd.cluster import ClusterService
+from sunbeam.clusterd.compatibility import (
+    IncompatibleClusterdException,
+    check_clusterd_compatibility,
+)
+from sunbeam.clusterd.service import BaseService
 from sunbeam.commands import configure as configure_cmds
 from sunbeam.commands import dashboard_url as dasboard_url_cmds
 from sunbeam.commands import generate_cloud_config as generate_cloud_config_cmds
@@ -16,12 +22,14 @@ from sunbeam.commands import juju_utils as juju_cmds
 from sunbeam.commands import launch as launch_cmds
 from sunbeam.commands import manifest as manifest_cmds
 from sunbeam.commands import openrc as openrc_cmds
+from sunbeam.commands import operation as operation_cmds
 from sunbeam.commands import plans as plans_cmd
 from sunbeam.commands import prepare_node as prepare_node_cmds
 from sunbeam.commands import proxy as proxy_cmds
 from sunbeam.commands import sso as sso_cmd
 from sunbeam.commands import utils as utils_cmds
 from sunbeam.core import deployments as deployments_jobs
+from sunbeam.core.timeouts import STEP_TIMEOUTS, load_step_timeouts_file
 from sunbeam.feature_gates import FeatureGateError, validate_feature_gate_config
 from sunbeam.feature_manager import list_feature_gates, list_features
 from sunbeam.provider import commands as provider_cmds
@@ -37,14 +45,53 @@ CONTEXT_SETTINGS = {"help_option_names": ["-h", "--help"]}
 @click.group("init", context_settings=CONTEXT_SETTINGS, cls=CatchGroup)
 @click.option("--quiet", "-q", default=False, is_flag=True)
 @click.option("--verbose", "-v", default=False, is_flag=True)
+@click.option(
+    "--skip-compatibility-check",
+    default=False,
+    is_flag=True,
+    help="Run even if clusterd is older than this CLI supports.",
+)
+@click.option(
+    "--timeouts",
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+    help="Yaml file mapping step names to timeouts, e.g. DeployControlPlaneStep:"
+    " 2h, overriding the deployment.step-timeouts cluster config.",
+)
 @click.pass_context
-def cli(ctx, quiet, verbose):
+def cli(ctx, quiet, verbose, skip_compatibility_check, timeouts):
     """Sunbeam is a small lightweight OpenStack distribution.
 
     To get started with a single node, all-in-one OpenStack installation, start
     with by initializing the local node. Once the local node has been initialized,
     run the bootstrap process to get a live cloud.
     """
+    if not skip_compatibility_check:
+        # Checked before the first request to clusterd only, so that the
+        # commands not talking to it, such as --help, do not need it
+        BaseService.before_request = check_compatibility
+    configure_step_timeouts(ctx.obj, timeouts)
+
+
+def check_compatibility(cluster: ClusterService) -> None:
+    """Refuse to talk to a clusterd older than the CLI."""
+    try:
+        warnings = check_clusterd_compatibility(cluster)
+    except IncompatibleClusterdException as e:
+        raise click.ClickException(
+            f"{e}. Upgrade clusterd, or use --skip-compatibility-check to run"
+            " anyway."
+        )
+    for warning in warnings:
+        click.echo(f"Warning: {warning}", err=True)
+
+
+def configure_step_timeouts(deployment, path: Path | None) -> None:
+    """Override the timeouts of the steps with the file and the cluster config."""
+    try:
+        overrides = load_step_timeouts_file(path) if path else {}
+    except ValueError as e:
+        raise click.BadParameter(str(e), param_hint="--timeouts")
+    STEP_TIMEOUTS.configure(overrides, deployment.get_client)
 
 
 @click.group("identity", context_settings=CONTEXT_SETTINGS, cls=CatchGroup)
@@ -151,6 +198,7 @@ def main():
     manifest.add_command(manifest_cmds.list_manifests)
     manifest.add_command(manifest_cmds.show)
     manifest.add_command(manifest_cmds.generate)
+    manifest.add_command(manifest_cmds.diff)
 
     # Proxy management
     cli.add_command(proxy)
@@ -162,6 +210,7 @@ def main():
     cli.add_command(disable)
 
     cli.add_command(plans_cmd.plans)
+    cli.add_command(operation_cmds.operation)
     cli.add_command(list_features)
     cli.add_command(list_feature_gates)
 
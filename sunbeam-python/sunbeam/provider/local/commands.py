@@ -4,6 +4,7 @@
 import base64
 import json
 import logging
+from concurrent.futures import ThreadPoolExecutor, as_completed
 from pathlib import Path
 from typing import Tuple, Type
 
@@ -11,15 +12,33 @@ import click
 import yaml
 from click.core import ParameterSource
 from rich.console import Console
+from rich.table import Table
 from snaphelpers import Snap
 
 from sunbeam import utils
+from sunbeam.clusterd.client import Client
 from sunbeam.clusterd.service import (
     ClusterServiceUnavailableException,
     ConfigItemNotFoundException,
+    InvalidNodeLabelException,
+    InvalidNodeRoleException,
 )
+from sunbeam.commands import backup as backup_cmds
+from sunbeam.commands import certificates as certificates_cmds
+from sunbeam.commands import cluster_config as cluster_config_cmds
+from sunbeam.commands import cluster_db as cluster_db_cmds
+from sunbeam.commands import health as health_cmds
+from sunbeam.commands import host_inventory as host_inventory_cmds
+from sunbeam.commands import node_cordon as node_cordon_cmds
+from sunbeam.commands import node_inventory as node_inventory_cmds
+from sunbeam.commands import node_labels as node_labels_cmds
+from sunbeam.commands import node_roles as node_roles_cmds
+from sunbeam.commands import quorum as quorum_cmds
 from sunbeam.commands import refresh as refresh_cmds
 from sunbeam.commands import resize as resize_cmds
+from sunbeam.commands import tokens as tokens_cmds
+from sunbeam.commands import wait as wait_cmds
+from sunbeam.commands import webhooks as webhooks_cmds
 from sunbeam.commands.configure import (
     DemoSetup,
     TerraformDemoInitStep,
@@ -27,22 +46,32 @@ from sunbeam.commands.configure import (
     retrieve_admin_credentials,
 )
 from sunbeam.commands.dashboard_url import retrieve_dashboard_url
+from sunbeam.commands.operation import click_option_detach_bootstrap
 from sunbeam.commands.proxy import PromptForProxyStep
 from sunbeam.core import ovn
 from sunbeam.core.checks import (
     Check,
+    ClockSkewCheck,
+    ClusterConnectivityCheck,
     DaemonGroupCheck,
+    DiskSpaceCheck,
     JujuControllerRegistrationCheck,
     JujuSnapCheck,
     LocalShareCheck,
     LxdGroupCheck,
     LXDJujuControllerRegistrationCheck,
+    OfflineArtifactsCheck,
     SshKeysConnectedCheck,
     SystemRequirementsCheck,
     TokenCheck,
     VerifyBootstrappedCheck,
     VerifyFQDNCheck,
     VerifyHypervisorHostnameCheck,
+    VerifyLocalNodeCheck,
+    check_results_report,
+    evaluate_preflight_checks,
+    print_check_results,
+    raise_for_failed_checks,
     run_preflight_checks,
 )
 from sunbeam.core.common import (
@@ -51,17 +80,24 @@ from sunbeam.core.common import (
     FORMAT_TABLE,
     FORMAT_VALUE,
     FORMAT_YAML,
+    MANAGEMENT_ADDRESS,
     BaseStep,
+    CustomRole,
     ResultType,
     Role,
     click_option_database,
     click_option_topology,
     get_step_message,
     get_step_result,
+    parse_duration,
+    preferred_api_address,
     read_config,
     roles_to_str_list,
     run_plan,
     update_config,
+    validate_duration,
+    validate_node_addresses,
+    validate_node_roles,
     validate_roles,
 )
 from sunbeam.core.deployment import (
@@ -76,9 +112,12 @@ from sunbeam.core.juju import (
 )
 from sunbeam.core.k8s import K8S_CLOUD_SUFFIX
 from sunbeam.core.manifest import AddManifestStep, Manifest
+from sunbeam.core.offline import apply_offline_juju_manifest
 from sunbeam.core.openstack import OPENSTACK_MODEL
-from sunbeam.core.questions import get_stdin_reopen_tty
+from sunbeam.core.output import click_option_format, print_structured
+from sunbeam.core.questions import ConfirmQuestion, get_stdin_reopen_tty
 from sunbeam.core.terraform import TerraformInitStep
+from sunbeam.core.topology import Topology, TopologyDrift, topology_drift
 from sunbeam.feature_gates import (
     feature_gate_command,
     feature_gate_option,
@@ -97,7 +136,11 @@ from sunbeam.provider.local.steps import (
     LocalUserQuestions,
 )
 from sunbeam.steps import cluster_status
-from sunbeam.steps.bootstrap_state import SetBootstrapped
+from sunbeam.steps.bootstrap_state import (
+    BootstrapJournal,
+    SetBootstrapped,
+    bootstrap_journal_path,
+)
 from sunbeam.steps.cinder_volume import (
     CheckCinderVolumeDistributionStep,
     DeployCinderVolumeApplicationStep,
@@ -105,16 +148,20 @@ from sunbeam.steps.cinder_volume import (
 )
 from sunbeam.steps.clusterd import (
     AskManagementCidrStep,
+    CheckNodeReachableStep,
     ClusterAddJujuUserStep,
     ClusterAddNodeStep,
     ClusterInitStep,
     ClusterJoinNodeStep,
     ClusterRemoveNodeStep,
+    ClusterScopeJoinTokenStep,
     ClusterUpdateJujuControllerStep,
     ClusterUpdateJujuUserStep,
+    ClusterUpdateNodeInventoryStep,
     ClusterUpdateNodeStep,
     PromptCheckNodeExistStep,
     SaveManagementCidrStep,
+    is_node_removed,
 )
 from sunbeam.steps.hypervisor import (
     DeployHypervisorApplicationStep,
@@ -241,8 +288,26 @@ class LocalProvider(ProviderBase):
         cluster.add_command(add_secondary_region_node)
         cluster.add_command(join)
         cluster.add_command(list_nodes)
+        cluster.add_command(node_labels_cmds.node)
+        node_labels_cmds.node.add_command(node_inventory_cmds.inventory)
+        node_labels_cmds.node.add_command(node_roles_cmds.role)
+        node_labels_cmds.node.add_command(node_cordon_cmds.cordon)
+        node_labels_cmds.node.add_command(node_cordon_cmds.uncordon)
+        node_labels_cmds.node.add_command(check_node)
         cluster.add_command(remove)
         cluster.add_command(resize_cmds.resize)
+        cluster.add_command(backup_cmds.backup)
+        cluster.add_command(backup_cmds.restore)
+        cluster.add_command(certificates_cmds.cert)
+        cluster.add_command(webhooks_cmds.webhook)
+        cluster.add_command(tokens_cmds.token)
+        cluster.add_command(cluster_config_cmds.config)
+        cluster.add_command(cluster_db_cmds.db)
+        cluster.add_command(health_cmds.health)
+        cluster.add_command(wait_cmds.wait)
+        cluster.add_command(quorum_cmds.status)
+        cluster.add_command(quorum_cmds.recover)
+        cluster.add_command(host_inventory_cmds.inventory)
         cluster.add_command(refresh_cmds.refresh)
 
     def deployment_type(self) -> Tuple[str, Type[Deployment]]:
@@ -515,6 +580,7 @@ def deploy_and_migrate_juju_controller(
     management_cidr: str,
     data_location: Path,
     accept_defaults: bool,
+    journal: BootstrapJournal,
     show_hints: bool = False,
 ):
     """Deploy LXD controller and migrate to k8s."""
@@ -541,7 +607,7 @@ def deploy_and_migrate_juju_controller(
         plan1 = get_juju_controller_plans(
             deployment, lxd_controller, data_location, external_controller=False
         )
-        run_plan(plan1, console, show_hints)
+        journal.run_plan(plan1, console, show_hints)
 
     # Reload deployment with lxd controller admin user credentials
     deployment.reload_credentials()
@@ -550,21 +616,21 @@ def deploy_and_migrate_juju_controller(
     plan2 = get_juju_model_machine_plans(
         deployment, jhelper, local_management_ip, "empty-creds", manifest
     )
-    run_plan(plan2, console, show_hints)
+    journal.run_plan(plan2, console, show_hints)
 
     plan3 = get_juju_spaces_plans(deployment, jhelper, management_cidr)
     plan3.extend(get_sunbeam_machine_plans(deployment, jhelper, manifest))
     plan3.extend(get_k8s_plans(deployment, jhelper, manifest, accept_defaults))
-    run_plan(plan3, console, show_hints)
+    journal.run_plan(plan3, console, show_hints)
     # Disconnect all pylibjuju connections before bootstrapping new controller
 
     plan4 = get_juju_bootstrap_plans(deployment, juju_bootstrap_args)
-    run_plan(plan4, console, show_hints)
+    journal.run_plan(plan4, console, show_hints)
 
     plan5 = get_juju_migrate_plans(
         deployment, lxd_controller, deployment.controller, data_location
     )
-    run_plan(plan5, console, show_hints)
+    journal.run_plan(plan5, console, show_hints)
     client.cluster.set_juju_controller_migrated()
 
     # Reload deployment with sunbeam-controller admin user credentials
@@ -574,11 +640,11 @@ def deploy_and_migrate_juju_controller(
     plan6 = [
         CreateJujuUserStep(fqdn),
     ]
-    plan6_results = run_plan(plan6, console, show_hints)
+    plan6_results = journal.run_plan(plan6, console, show_hints)
     token = get_step_message(plan6_results, CreateJujuUserStep)
 
     plan7 = get_juju_user_plans(deployment, jhelper, data_location, token)
-    run_plan(plan7, console, show_hints)
+    journal.run_plan(plan7, console, show_hints)
 
 
 @click.command()
@@ -616,9 +682,37 @@ def deploy_and_migrate_juju_controller(
     help="Token obtained from the region controller.",
     type=str,
 )
+@click.option(
+    "--rollback",
+    is_flag=True,
+    help="Remove the resources created by a failed bootstrap.",
+)
+@click.option(
+    "--offline",
+    is_flag=True,
+    help=(
+        "Deploy without access to the public stores, from the local sources"
+        " declared in the offline section of the manifest."
+    ),
+)
+@click.option(
+    "--topology-file",
+    "topology_path",
+    help=(
+        "Topology file declaring the nodes of the cluster, their roles, labels"
+        " and addresses. The cluster is converged towards it."
+    ),
+    type=click.Path(exists=True, dir_okay=False, path_type=Path),
+)
+@click.option(
+    "--prune",
+    is_flag=True,
+    help="Remove the nodes not declared in the topology file.",
+)
+@click_option_detach_bootstrap
 @click_option_show_hints
 @click.pass_context
-def bootstrap(  # noqa: C901
+def bootstrap(
     ctx: click.Context,
     roles: list[Role],
     topology: str,
@@ -628,18 +722,259 @@ def bootstrap(  # noqa: C901
     accept_defaults: bool = False,
     show_hints: bool = False,
     region_controller_token: str | None = None,
+    rollback: bool = False,
+    offline: bool = False,
+    topology_path: Path | None = None,
+    prune: bool = False,
 ) -> None:
     """Bootstrap the local node.
 
-    Initialize the sunbeam cluster.
+    Initialize the sunbeam cluster. The resources created are recorded, so
+    that a failed bootstrap can be rolled back with --rollback, even once
+    the command exited.
+
+    With --offline, the charms are fetched from a Charmhub mirror and the
+    snaps from a snap store proxy, declared in the manifest. All the
+    artifacts are resolved from them before bootstrap starts.
+
+    With --topology-file, the local node is bootstrapped with the roles it
+    is declared, then the cluster is converged towards the topology: the
+    labels of the nodes are set, join tokens are generated for the missing
+    nodes and the differences which cannot be converged are reported. Once
+    bootstrapped, running it again only acts on the differences.
     """
+    if offline and manifest_path is None:
+        raise click.UsageError(
+            "--offline requires a manifest declaring the local sources, pass it"
+            " with --manifest."
+        )
+    if prune and topology_path is None:
+        raise click.UsageError("--prune requires --topology-file.")
+
+    deployment: LocalDeployment = ctx.obj
+    manifest = deployment.get_manifest(manifest_path)
+    journal = BootstrapJournal.load(bootstrap_journal_path(Snap()))
+
+    if rollback:
+        rollback_bootstrap(deployment, manifest, journal, show_hints)
+        return
+
+    topology_config = None
+    if topology_path is not None:
+        topology_config = _load_topology(ctx, topology_path)
+        roles = _topology_bootstrap_roles(topology_config)
+        if deployment.get_client().cluster.check_sunbeam_bootstrapped():
+            _converge_topology(ctx, deployment, topology_config, prune, show_hints)
+            return
+
+    try:
+        _bootstrap(
+            ctx,
+            manifest,
+            journal,
+            roles,
+            topology,
+            juju_controller,
+            manifest_path,
+            accept_defaults,
+            show_hints,
+            region_controller_token,
+            offline,
+        )
+    except Exception:
+        if not journal.resources:
+            raise
+        question = ConfirmQuestion(
+            "Bootstrap failed, remove the resources it created?",
+            default_value=False,
+            accept_defaults=accept_defaults,
+        )
+        if question.ask():
+            rollback_bootstrap(deployment, manifest, journal, show_hints)
+        else:
+            console.print(
+                "Run `sunbeam cluster bootstrap --rollback` to remove the"
+                " resources created by bootstrap."
+            )
+        raise
+
+    journal.clear()
+
+    if topology_config is not None:
+        _converge_topology(ctx, deployment, topology_config, prune, show_hints)
+
+
+def _load_topology(ctx: click.Context, path: Path) -> Topology:
+    """Load the topology file, incompatible with --role."""
+    if ctx.get_parameter_source("roles") == ParameterSource.COMMANDLINE:
+        raise click.UsageError(
+            "--role cannot be used with --topology-file, the roles of the"
+            " bootstrap node are declared in the topology."
+        )
+    try:
+        return Topology.load(path)
+    except ValueError as e:
+        raise click.BadParameter(str(e), param_hint="--topology-file") from e
+
+
+def _topology_bootstrap_roles(topology: Topology) -> list[Role]:
+    """Return the roles the local node is declared in the topology."""
+    fqdn = utils.get_fqdn()
+    node = topology.get_node(fqdn)
+    if node is None:
+        raise click.ClickException(
+            f"The bootstrap node {fqdn} is not declared in the topology."
+        )
+    roles = node.role_list()
+    custom = [role.name for role in roles if isinstance(role, CustomRole)]
+    if custom:
+        raise click.ClickException(
+            "The bootstrap node cannot be declared custom roles, they are"
+            f" defined once bootstrapped: {', '.join(custom)}"
+        )
+    builtin = [role for role in roles if isinstance(role, Role)]
+    if Role.CONTROL not in builtin and Role.REGION_CONTROLLER not in builtin:
+        raise click.ClickException(
+            f"The bootstrap node {fqdn} must be declared the control or"
+            " region_controller role."
+        )
+    return builtin
+
+
+def _converge_topology(
+    ctx: click.Context,
+    deployment: LocalDeployment,
+    topology: Topology,
+    prune: bool,
+    show_hints: bool,
+) -> TopologyDrift:
+    """Converge the cluster towards the topology, returning the drift found.
+
+    The labels of the nodes are replaced by the declared ones and join
+    tokens are generated for the missing nodes, to join with the roles they
+    are declared. The roles and addresses of the nodes of the cluster are
+    only reported, they are set when a node joins. The nodes not declared
+    are removed with prune, reported otherwise.
+    """
+    client = deployment.get_client()
+    drift = topology_drift(
+        topology, client.cluster.list_nodes(), client.cluster.get_cluster_members()
+    )
+    if drift.is_empty():
+        console.print("Cluster matches the topology.")
+        return drift
+
+    for name, labels in drift.labels.items():
+        try:
+            client.cluster.set_node_labels(name, labels)
+        except InvalidNodeLabelException as e:
+            raise click.ClickException(f"Invalid labels for node {name}: {e}") from e
+
+    tokens: dict[str, str | None] = {}
+    if drift.missing:
+        jhelper = JujuHelper(deployment.juju_controller)
+        run_plan([JujuLoginStep(deployment.juju_account)], console, show_hints)
+        for node in drift.missing:
+            tokens[node.name] = _add_node(
+                deployment, client, jhelper, node.name, console, show_hints
+            )
+
+    if prune:
+        for name in drift.extra:
+            ctx.invoke(remove, name=name, force=False, show_hints=show_hints)
+
+    _print_topology_drift(drift, tokens, prune)
+    return drift
+
+
+def _print_topology_drift(
+    drift: TopologyDrift, tokens: dict[str, str | None], pruned: bool
+):
+    """Print the differences with the topology and how they were handled."""
+    table = Table()
+    table.add_column("Node", justify="left")
+    table.add_column("Difference", justify="left")
+    table.add_column("Action", justify="left", overflow="fold")
+    for node in drift.missing:
+        token = tokens.get(node.name)
+        if token is None:
+            table.add_row(node.name, "missing", "joining")
+            continue
+        table.add_row(
+            node.name,
+            "missing",
+            f"join with: sunbeam cluster join --role {','.join(node.roles)} {token}",
+        )
+    for name, (declared, actual) in drift.roles.items():
+        table.add_row(
+            name,
+            f"roles {','.join(actual)}, declared {','.join(declared)}",
+            "[orange1]reported[/orange1]",
+        )
+    for name in drift.labels:
+        table.add_row(name, "labels", "[green]set[/green]")
+    for name, (declared_address, address) in drift.addresses.items():
+        table.add_row(
+            name,
+            f"address {address}, declared {declared_address}",
+            "[orange1]reported[/orange1]",
+        )
+    for name in drift.extra:
+        action = (
+            "[green]removed[/green]"
+            if pruned
+            else "[orange1]reported[/orange1], use --prune to remove it"
+        )
+        table.add_row(name, "not declared", action)
+    console.print(table)
+
+
+def rollback_bootstrap(
+    deployment: LocalDeployment,
+    manifest: Manifest,
+    journal: BootstrapJournal,
+    show_hints: bool,
+) -> None:
+    """Remove the resources created by a failed bootstrap, newest first."""
+    if not journal.resources:
+        console.print("No bootstrap to roll back.")
+        return
+
+    if deployment.get_client().cluster.check_sunbeam_bootstrapped():
+        raise click.ClickException("Deployment is bootstrapped, refusing to roll back.")
+
+    deployment.reload_credentials()
+    journal.rollback(deployment, manifest, console, show_hints)
+
+    # The Juju controller is either destroyed or no longer recorded in clusterd
+    deployment.juju_controller = None
+    deployments = DeploymentsConfig.load(deployment_path(Snap()))
+    try:
+        deployments.update_deployment(deployment)
+    except ValueError:
+        LOG.debug("Deployment not found in deployments", exc_info=True)
+    console.print("Bootstrap rolled back.")
+
+
+def _bootstrap(  # noqa: C901
+    ctx: click.Context,
+    manifest: Manifest,
+    journal: BootstrapJournal,
+    roles: list[Role],
+    topology: str,
+    juju_controller: str | None,
+    manifest_path: Path | None,
+    accept_defaults: bool,
+    show_hints: bool,
+    region_controller_token: str | None,
+    offline: bool = False,
+) -> None:
     deployment: LocalDeployment = ctx.obj
     client = deployment.get_client()
     snap = Snap()
 
     path = deployment_path(snap)
     deployments = DeploymentsConfig.load(path)
-    manifest = deployment.get_manifest(manifest_path)
 
     parameter_source = click.get_current_context().get_parameter_source("database")
     if parameter_source == ParameterSource.COMMANDLINE:
@@ -681,6 +1016,9 @@ def bootstrap(  # noqa: C901
     data_location = snap.paths.user_data
 
     preflight_checks: list[Check] = []
+    if offline:
+        # Fail fast, before any resource is created
+        preflight_checks.append(OfflineArtifactsCheck(manifest))
     preflight_checks.append(SystemRequirementsCheck())
     preflight_checks.append(JujuSnapCheck())
     preflight_checks.append(SshKeysConnectedCheck())
@@ -701,6 +1039,13 @@ def bootstrap(  # noqa: C901
 
     run_preflight_checks(preflight_checks, console)
 
+    if offline_manifest := manifest.core.software.offline:
+        apply_offline_juju_manifest(
+            manifest.core.software.juju,
+            offline_manifest,
+            [deployment.openstack_machines_model],
+        )
+
     # Mark deployment as active if not yet already
     try:
         deployments.add_deployment(deployment)
@@ -742,6 +1087,7 @@ def bootstrap(  # noqa: C901
     plan.append(JujuLoginStep(deployment.juju_account))
     # bootstrapped node is always machine 0 in controller model
     plan.append(ClusterInitStep(client, roles_to_str_list(roles), 0, management_cidr))
+    plan.append(ClusterUpdateNodeInventoryStep(client, fqdn))
     plan.append(SyncFeatureGatesToCluster(client))
     plan.append(SaveManagementCidrStep(client, management_cidr))
     plan.append(SetOvnProviderStep(client, snap))
@@ -754,7 +1100,7 @@ def bootstrap(  # noqa: C901
     plan.append(PromptDatabaseTopologyStep(client, manifest, accept_defaults))
     plan.append(PromptRegionStep(client, manifest, accept_defaults))
     plan.append(ValidateIdentityManifest(client, manifest))
-    run_plan(plan, console, show_hints)
+    journal.run_plan(plan, console, show_hints)
 
     if region_controller_token:
         connect_to_region_controller(
@@ -779,7 +1125,7 @@ def bootstrap(  # noqa: C901
         plan11 = get_juju_controller_plans(
             deployment, juju_controller, data_location, external_controller=True
         )
-        run_plan(plan11, console, show_hints)
+        journal.run_plan(plan11, console, show_hints)
 
         deployment.reload_credentials()
         jhelper = JujuHelper(deployment.juju_controller)
@@ -787,13 +1133,13 @@ def bootstrap(  # noqa: C901
         plan12 = get_juju_model_machine_plans(
             deployment, jhelper, local_management_ip, None, manifest
         )
-        run_plan(plan12, console, show_hints)
+        journal.run_plan(plan12, console, show_hints)
 
         plan13 = get_juju_spaces_plans(deployment, jhelper, management_cidr)
         plan13.extend(get_sunbeam_machine_plans(deployment, jhelper, manifest))
         plan13.extend(get_k8s_plans(deployment, jhelper, manifest, accept_defaults))
         plan13.append(AddK8SCloudStep(deployment, jhelper))
-        run_plan(plan13, console, show_hints)
+        journal.run_plan(plan13, console, show_hints)
     else:
         plan21: list[BaseStep] = []
 
@@ -804,6 +1150,7 @@ def bootstrap(  # noqa: C901
             management_cidr,
             data_location,
             accept_defaults,
+            journal,
             show_hints,
         )
 
@@ -814,7 +1161,7 @@ def bootstrap(  # noqa: C901
         jhelper = JujuHelper(deployment.juju_controller)
 
         plan21.append(AddK8SCredentialStep(deployment, jhelper))
-        run_plan(plan21, console, show_hints)
+        journal.run_plan(plan21, console, show_hints)
 
     ovn_manager = deployment.get_ovn_manager()
 
@@ -950,7 +1297,7 @@ def bootstrap(  # noqa: C901
                 )
             )
 
-    run_plan(plan1, console, show_hints)
+    journal.run_plan(plan1, console, show_hints)
 
     plan2: list[BaseStep] = []
 
@@ -977,7 +1324,7 @@ def bootstrap(  # noqa: C901
         )
 
     plan2.append(SetBootstrapped(client))
-    run_plan(plan2, console, show_hints)
+    journal.run_plan(plan2, console, show_hints)
 
     click.echo(f"Node has been bootstrapped with roles: {pretty_roles}")
 
@@ -1142,51 +1489,32 @@ def _write_to_file(token: str, output: Path):
     console.print(f"Token written to file: {str(output)}")
 
 
-@click.command()
-@click.argument("name", type=str)
-@click.option(
-    "-f",
-    "--format",
-    type=click.Choice([FORMAT_DEFAULT, FORMAT_VALUE, FORMAT_YAML]),
-    default=FORMAT_DEFAULT,
-    help="Output format.",
-)
-@click.option(
-    "-o",
-    "--output",
-    type=click.Path(
-        file_okay=True,
-        dir_okay=False,
-        writable=True,
-        resolve_path=True,
-        path_type=Path,
-    ),
-    help="Output file for join token.",
-)
-@click_option_show_hints
-@click.pass_context
-def add(
-    ctx: click.Context,
+def _add_node(
+    deployment: LocalDeployment,
+    client: Client,
+    jhelper: JujuHelper,
     name: str,
-    format: str,
-    output: Path | None,
+    console: Console,
     show_hints: bool,
-) -> None:
-    """Generate a token for a new node to join the cluster.
+    scope: tokens_cmds.JoinTokenScope | None = None,
+) -> str | None:
+    """Add a node to the cluster, returning its join token.
 
-    NAME must be a fully qualified domain name.
+    None is returned if the node is already a member of the cluster. With
+    scope, the token only joins the node with the roles of the scope, until
+    its TTL elapses.
     """
-    preflight_checks = [DaemonGroupCheck(), VerifyFQDNCheck(name)]
-    run_preflight_checks(preflight_checks, console)
-    name = remove_trailing_dot(name)
-
-    deployment: LocalDeployment = ctx.obj
-    client = deployment.get_client()
-    jhelper = JujuHelper(deployment.juju_controller)
-
-    plan1: list[BaseStep] = [
-        JujuLoginStep(deployment.juju_account),
-        ClusterAddNodeStep(client, name),
+    plan1: list[BaseStep] = []
+    expire_after = None
+    if scope:
+        plan1.append(
+            ClusterScopeJoinTokenStep(
+                client, name, roles_to_str_list(scope.roles), scope.ttl
+            )
+        )
+        expire_after = parse_duration(scope.ttl) if scope.ttl else None
+    plan1 += [
+        ClusterAddNodeStep(client, name, expire_after=expire_after),
         CreateJujuUserStep(name),
         JujuGrantModelAccessStep(jhelper, name, deployment.openstack_machines_model),
         JujuGrantModelAccessStep(jhelper, name, OPENSTACK_MODEL),
@@ -1222,21 +1550,188 @@ def add(
 
     run_plan(plan_juju_user, console, show_hints)
 
-    if add_node_step_result.result_type == ResultType.COMPLETED:
-        token = str(add_node_step_result.message)
+    # A skipped step reuses the token generated earlier, if any
+    if add_node_step_result.message:
+        return str(add_node_step_result.message)
+    return None
+
+
+def _add_nodes(
+    deployment: LocalDeployment,
+    client: Client,
+    jhelper: JujuHelper,
+    names: list[str],
+    parallel: int,
+    show_hints: bool,
+    scope: tokens_cmds.JoinTokenScope | None = None,
+) -> dict[str, dict]:
+    """Add nodes to the cluster, at most parallel at once.
+
+    A failure to add a node does not stop the others from being added.
+    Return the join token, or the error, of each node.
+    """
+    results: dict[str, dict] = {}
+    with (
+        console.status(f"Adding nodes (0/{len(names)})") as status,
+        ThreadPoolExecutor(max_workers=parallel) as executor,
+    ):
+        # Only one status can be displayed, the plans of each node run quietly
+        futures = {
+            executor.submit(
+                _add_node,
+                deployment,
+                client,
+                jhelper,
+                name,
+                Console(quiet=True),
+                show_hints,
+                scope,
+            ): name
+            for name in names
+        }
+        for future in as_completed(futures):
+            name = futures[future]
+            try:
+                results[name] = {"token": future.result()}
+            except Exception as e:
+                LOG.debug(f"Failed to add node {name}", exc_info=True)
+                results[name] = {"error": str(e)}
+            status.update(f"Adding nodes ({len(results)}/{len(names)})")
+
+    return {name: results[name] for name in names}
+
+
+def _print_add_results(results: dict[str, dict], format: str):
+    """Print the outcome of adding each node."""
+    if format == FORMAT_YAML:
+        click.echo(yaml.dump(results, sort_keys=False))
+        return
+
+    table = Table()
+    table.add_column("Node", justify="left")
+    table.add_column("Status", justify="left")
+    table.add_column("Token or error", justify="left", overflow="fold")
+    for name, result in results.items():
+        if "error" in result:
+            table.add_row(name, "[red]failed[/red]", result["error"])
+        elif result["token"] is None:
+            table.add_row(name, "[green]member[/green]", "")
+        else:
+            table.add_row(name, "[green]added[/green]", result["token"])
+    console.print(table)
+
+
+@click.command()
+@click.argument("names", metavar="NAME...", nargs=-1, required=True, type=str)
+@click.option(
+    "-f",
+    "--format",
+    type=click.Choice([FORMAT_DEFAULT, FORMAT_VALUE, FORMAT_YAML]),
+    default=FORMAT_DEFAULT,
+    help="Output format.",
+)
+@click.option(
+    "-o",
+    "--output",
+    type=click.Path(
+        file_okay=True,
+        dir_okay=False,
+        writable=True,
+        resolve_path=True,
+        path_type=Path,
+    ),
+    help="Output file for join token, only when adding a single node.",
+)
+@click.option(
+    "--parallel",
+    type=click.IntRange(min=1),
+    default=1,
+    show_default=True,
+    help="Number of nodes added at once.",
+)
+@click.option(
+    "--role",
+    "roles",
+    multiple=True,
+    callback=validate_node_roles,
+    help="Restrict the join tokens to nodes joining with these roles. "
+    "Can be repeated and comma separated.",
+)
+@click.option(
+    "--ttl",
+    callback=validate_duration,
+    help="Expire the join tokens after the duration, such as 1h or 30m. "
+    "Requires --role.",
+)
+@click_option_show_hints
+@click.pass_context
+def add(
+    ctx: click.Context,
+    names: tuple[str, ...],
+    format: str,
+    output: Path | None,
+    parallel: int,
+    roles: list[Role | CustomRole],
+    ttl: str | None,
+    show_hints: bool,
+) -> None:
+    """Generate a token for new nodes to join the cluster.
+
+    NAME must be a fully qualified domain name. When several nodes are
+    added, a failure to add one of them does not stop the others: the
+    outcome of each node is reported once all are processed, and the
+    command fails if any node failed.
+
+    With --role, the cluster rejects the nodes joining with other roles, or
+    once their token expired. Join tokens are single-use.
+    """
+    if not roles and ttl:
+        raise click.UsageError("--ttl requires --role")
+    scope = tokens_cmds.JoinTokenScope(list(roles), ttl) if roles else None
+
+    preflight_checks = [DaemonGroupCheck()]
+    preflight_checks.extend(VerifyFQDNCheck(name) for name in names)
+    run_preflight_checks(preflight_checks, console)
+    # Adding the same node twice at once races on its token
+    node_names = list(dict.fromkeys(remove_trailing_dot(name) for name in names))
+
+    if len(node_names) > 1:
         if output:
+            raise click.UsageError("--output is not supported with multiple nodes")
+        if format == FORMAT_VALUE:
+            raise click.UsageError(
+                f"--format {FORMAT_VALUE} is not supported with multiple nodes"
+            )
+
+    deployment: LocalDeployment = ctx.obj
+    client = deployment.get_client()
+    jhelper = JujuHelper(deployment.juju_controller)
+
+    run_plan([JujuLoginStep(deployment.juju_account)], console, show_hints)
+
+    if len(node_names) == 1:
+        name = node_names[0]
+        token = _add_node(
+            deployment, client, jhelper, name, console, show_hints, scope
+        )
+        if token is None:
+            console.print("Node is already a member of the Sunbeam cluster")
+        elif output:
             _write_to_file(token, output)
         else:
             _print_output(token, format, name)
-    elif add_node_step_result.result_type == ResultType.SKIPPED:
-        if add_node_step_result.message:
-            token = str(add_node_step_result.message)
-            if output:
-                _write_to_file(token, output)
-            else:
-                _print_output(token, format, name)
-        else:
-            console.print("Node is already a member of the Sunbeam cluster")
+        return
+
+    results = _add_nodes(
+        deployment, client, jhelper, node_names, parallel, show_hints, scope
+    )
+    _print_add_results(results, format)
+
+    failed = [name for name, result in results.items() if "error" in result]
+    if failed:
+        raise click.ClickException(
+            f"Failed to add {len(failed)} of {len(results)} nodes: {', '.join(failed)}"
+        )
 
 
 @feature_gate_command(gate_key="feature.multi-region")
@@ -1316,6 +1811,83 @@ def add_secondary_region_node(
         _print_output(token, format, name)
 
 
+def join_preflight_checks(
+    name: str, token: str | None, is_compute_node: bool
+) -> list[Check]:
+    """Return the checks run on a node before it joins the cluster.
+
+    The checks needing the join token are left out without one.
+    """
+    preflight_checks: list[Check] = []
+    preflight_checks.append(SystemRequirementsCheck())
+    preflight_checks.append(JujuSnapCheck())
+    preflight_checks.append(SshKeysConnectedCheck())
+    preflight_checks.append(DaemonGroupCheck())
+    preflight_checks.append(LocalShareCheck())
+    preflight_checks.append(DiskSpaceCheck())
+    if token is not None:
+        preflight_checks.append(TokenCheck(token))
+        preflight_checks.append(ClusterConnectivityCheck(token))
+        preflight_checks.append(ClockSkewCheck(token))
+    if is_compute_node:
+        hypervisor_hostname = utils.get_hypervisor_hostname()
+        preflight_checks.append(
+            VerifyHypervisorHostnameCheck(name, hypervisor_hostname)
+        )
+    return preflight_checks
+
+
+@click.command("check")
+@click.argument("name", type=str)
+@click.option(
+    "--token",
+    type=str,
+    help="Join token of the node, to check the cluster is reachable from it.",
+)
+@click.option(
+    "--role",
+    "roles",
+    multiple=True,
+    default=["control", "compute"],
+    callback=validate_node_roles,
+    help="Roles the node will be assigned. Can be repeated and comma separated.",
+)
+@click_option_format()
+@click.pass_context
+def check_node(
+    ctx: click.Context,
+    name: str,
+    token: str | None,
+    roles: list[Role | CustomRole],
+    format: str,
+) -> None:
+    """Check a node can join the cluster, without changing anything.
+
+    Run on the node to add, NAME being its fully qualified domain name. All
+    the checks run before joining are run, along with the checks of the added
+    node name, and the result of each is reported.
+    """
+    if token == "-":
+        token = get_stdin_reopen_tty()
+    is_compute_node = any(role.is_compute_node() for role in roles)
+
+    checks: list[Check] = [VerifyLocalNodeCheck(name), VerifyFQDNCheck(name)]
+    checks.extend(join_preflight_checks(name, token, is_compute_node))
+    results = evaluate_preflight_checks(checks, console)
+
+    if format == FORMAT_TABLE:
+        print_check_results(console, results)
+    else:
+        report = {
+            "node": remove_trailing_dot(name),
+            "passed": all(passed for _, passed in results),
+            "checks": check_results_report(results),
+        }
+        print_structured(console, report, format)
+
+    raise_for_failed_checks(results)
+
+
 @click.command()
 @click.argument("token", type=str)
 @click.option("-a", "--accept-defaults", help="Accept all defaults.", is_flag=True)
@@ -1324,11 +1896,30 @@ def add_secondary_region_node(
     "roles",
     multiple=True,
     default=["control", "compute"],
-    callback=validate_roles,
+    callback=validate_node_roles,
     help=(
         f"Specify which roles ({', '.join(Role.enabled_values())})"
-        " the node will be assigned in the cluster."
-        " Can be repeated and comma separated."
+        " the node will be assigned in the cluster, or custom roles"
+        " defined in the cluster. Can be repeated and comma separated."
+    ),
+)
+@click.option(
+    "--address",
+    "addresses",
+    multiple=True,
+    callback=validate_node_addresses,
+    help=(
+        "Address of the node on a network, as NAME=IP such as"
+        " management=10.0.0.5 or data=10.1.0.5. Can be repeated. The"
+        " management address defaults to the one in the management network."
+    ),
+)
+@click.option(
+    "--preferred-api",
+    type=str,
+    help=(
+        "Name of the address the cluster API and database traffic use,"
+        f" defaults to {MANAGEMENT_ADDRESS}."
     ),
 )
 @feature_gate_option(
@@ -1343,7 +1934,9 @@ def add_secondary_region_node(
 def join(  # noqa: C901
     ctx: click.Context,
     token: str,
-    roles: list[Role],
+    roles: list[Role | CustomRole],
+    addresses: dict[str, str],
+    preferred_api: str | None = None,
     accept_defaults: bool = False,
     show_hints: bool = False,
     region_controller_token: str | None = None,
@@ -1377,20 +1970,7 @@ def join(  # noqa: C901
     pretty_roles = ", ".join(role_.name.lower() for role_ in roles)
     LOG.debug(f"Node joining the cluster with roles: {pretty_roles}")
 
-    preflight_checks: list[Check] = []
-    preflight_checks.append(SystemRequirementsCheck())
-    preflight_checks.append(JujuSnapCheck())
-    preflight_checks.append(SshKeysConnectedCheck())
-    preflight_checks.append(DaemonGroupCheck())
-    preflight_checks.append(LocalShareCheck())
-    preflight_checks.append(TokenCheck(token))
-    if is_compute_node:
-        hypervisor_hostname = utils.get_hypervisor_hostname()
-        preflight_checks.append(
-            VerifyHypervisorHostnameCheck(name, hypervisor_hostname)
-        )
-
-    run_preflight_checks(preflight_checks, console)
+    run_preflight_checks(join_preflight_checks(name, token, is_compute_node), console)
 
     management_cidr = None
     try:
@@ -1403,7 +1983,13 @@ def join(  # noqa: C901
         raise click.ClickException(
             f"Error in resolving management CIDR: {str(e)}"
         ) from e
-    ip = _resolve_local_ip_from_cidr(management_cidr)
+    ip = addresses.get(MANAGEMENT_ADDRESS) or _resolve_local_ip_from_cidr(
+        management_cidr
+    )
+    try:
+        preferred_api_address({MANAGEMENT_ADDRESS: ip, **addresses}, preferred_api)
+    except ValueError as e:
+        raise click.BadParameter(str(e), param_hint="--preferred-api") from e
 
     deployment: LocalDeployment = ctx.obj
     client = deployment.get_client()
@@ -1413,7 +1999,11 @@ def join(  # noqa: C901
     path = deployment_path(snap)
     deployments = DeploymentsConfig.load(path)
 
-    plan1 = [ClusterJoinNodeStep(client, token, ip, name, roles_str)]
+    plan1 = [
+        ClusterJoinNodeStep(
+            client, token, ip, name, roles_str, addresses, preferred_api
+        )
+    ]
     run_plan(plan1, console, show_hints)
 
     try:
@@ -1478,6 +2068,7 @@ def join(  # noqa: C901
     microovn_necessary = ovn_manager.is_microovn_necessary(roles)
     plan4: list[BaseStep] = []
     plan4.append(ClusterUpdateNodeStep(client, name, machine_id=machine_id))
+    plan4.append(ClusterUpdateNodeInventoryStep(client, name))
     plan4.append(TerraformInitStep(sunbeam_machine_tfhelper))
     plan4.append(
         DeploySunbeamMachineApplicationStep(
@@ -1758,21 +2349,37 @@ def _resolve_local_ip_from_cidr(cidr: str) -> str:
 
 
 @click.command("list")
+@click_option_format()
 @click.option(
-    "-f",
-    "--format",
-    type=click.Choice([FORMAT_TABLE, FORMAT_YAML]),
-    default=FORMAT_TABLE,
-    help="Output format.",
+    "--role",
+    multiple=True,
+    help="Only list nodes having this role, can be repeated.",
+)
+@click.option(
+    "--label",
+    multiple=True,
+    help="Only list nodes having this key=value label, can be repeated.",
+)
+@click.option(
+    "--limit",
+    type=click.IntRange(min=1),
+    help="Maximum number of nodes to list when filtering by role or label.",
 )
 @click_option_show_hints
 @click.pass_context
 def list_nodes(
     ctx: click.Context,
     format: str,
+    role: tuple[str, ...],
+    label: tuple[str, ...],
+    limit: int | None,
     show_hints: bool,
 ) -> None:
-    """List nodes in the cluster."""
+    """List nodes in the cluster.
+
+    The json and yaml formats list the nodes of the cluster, see
+    sunbeam.steps.cluster_status.list_node_statuses for their stable schema.
+    """
     preflight_checks = [DaemonGroupCheck()]
     run_preflight_checks(preflight_checks, console)
     deployment: LocalDeployment = ctx.obj
@@ -1780,23 +2387,53 @@ def list_nodes(
     step = LocalClusterStatusStep(deployment, jhelper)
     results = run_plan([step], console, show_hints)
     msg = get_step_message(results, LocalClusterStatusStep)
+    if format != FORMAT_TABLE:
+        try:
+            nodes = cluster_status.list_node_statuses(
+                deployment, msg, role, label, limit
+            )
+        except (InvalidNodeRoleException, InvalidNodeLabelException) as e:
+            raise click.ClickException(str(e))
+        print_structured(console, nodes, format)
+        return
+    shown = total = None
+    if role or label:
+        try:
+            msg, shown, total = cluster_status.filter_status_by_nodes(
+                deployment, msg, role, label, limit
+            )
+        except (InvalidNodeRoleException, InvalidNodeLabelException) as e:
+            raise click.ClickException(str(e))
+    msg = cluster_status.mark_cordoned_nodes(deployment, msg)
+    msg = cluster_status.mark_node_addresses(deployment, msg)
     renderables = cluster_status.format_status(deployment, msg, format)
     for renderable in renderables:
         console.print(renderable)
+    if total is not None:
+        console.print(f"Showing {shown} of {total} nodes")
 
 
 @click.command()
 @click.option(
     "--force",
     type=bool,
-    help=("Skip safety checks and ignore cleanup errors for some tasks"),
+    help=(
+        "Remove the node even if it is unreachable: skip safety checks,"
+        " instance migration and draining, and ignore cleanup errors."
+    ),
     is_flag=True,
 )
 @click.argument("name", type=str)
 @click_option_show_hints
 @click.pass_context
 def remove(ctx: click.Context, name: str, force: bool, show_hints: bool) -> None:
-    """Remove a node from the cluster."""
+    """Remove a node from the cluster.
+
+    By default the node is drained and departs the cluster gracefully, which
+    requires it to be reachable. With --force, the node is removed from the
+    cluster membership and database even if it is unreachable, so that it
+    cannot rejoin. Removing a node already removed succeeds.
+    """
     deployment: LocalDeployment = ctx.obj
     client = deployment.get_client()
     jhelper = JujuHelper(deployment.juju_controller)
@@ -1804,12 +2441,17 @@ def remove(ctx: click.Context, name: str, force: bool, show_hints: bool) -> None
     preflight_checks = [DaemonGroupCheck()]
     run_preflight_checks(preflight_checks, console)
 
+    if is_node_removed(client, name):
+        click.echo(f"Node {name} is not part of the cluster, nothing to remove")
+        return
+
     plan: list[BaseStep] = [
         JujuLoginStep(deployment.juju_account),
     ]
 
     if not force:
         plan.append(PromptCheckNodeExistStep(client, name))
+        plan.append(CheckNodeReachableStep(client, name))
 
     plan.extend(
         [
@@ -1894,16 +2536,29 @@ def remove(ctx: click.Context, name: str, force: bool, show_hints: bool) -> None
                 client, name, jhelper, deployment.openstack_machines_model
             ),
             RemoveJujuMachineStep(
-                client, name, jhelper, deployment.openstack_machines_model
+                client, name, jhelper, deployment.openstack_machines_model, force
             ),
             # Cannot remove user as the same user name cannot be resued,
             # so commenting the RemoveJujuUserStep
             # RemoveJujuUserStep(name),
-            ClusterRemoveNodeStep(client, name),
         ]
     )
 
-    run_plan(plan, console, show_hints)
+    if force:
+        # Clean up as much as possible, the membership is removed regardless
+        for step in plan:
+            results = run_plan([step], console, show_hints, no_raise=True)
+            for result in results.values():
+                if result.result_type == ResultType.FAILED:
+                    LOG.debug(f"Ignoring failed step {step.name!r}: {result.message}")
+                    console.print(
+                        f"[yellow]Warning:[/yellow] {step.name} failed, ignoring:"
+                        f" {result.message}"
+                    )
+        run_plan([ClusterRemoveNodeStep(client, name, force=True)], console, show_hints)
+    else:
+        plan.append(ClusterRemoveNodeStep(client, name))
+        run_plan(plan, console, show_hints)
     click.echo(f"Removed node {name} from the cluster")
     # Removing machine does not clean up all deployed juju components. This is
     # deliberate, see https://bugs.launchpad.net/juju/+bug/1851489.
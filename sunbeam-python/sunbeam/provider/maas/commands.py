@@ -16,9 +16,27 @@ from rich.console import Console
 from rich.table import Table
 from snaphelpers import Snap
 
-from sunbeam.clusterd.service import ConfigItemNotFoundException
+from sunbeam.clusterd.service import (
+    ConfigItemNotFoundException,
+    InvalidNodeLabelException,
+    InvalidNodeRoleException,
+)
+from sunbeam.commands import backup as backup_cmds
+from sunbeam.commands import certificates as certificates_cmds
+from sunbeam.commands import cluster_config as cluster_config_cmds
+from sunbeam.commands import cluster_db as cluster_db_cmds
+from sunbeam.commands import health as health_cmds
+from sunbeam.commands import host_inventory as host_inventory_cmds
+from sunbeam.commands import node_cordon as node_cordon_cmds
+from sunbeam.commands import node_inventory as node_inventory_cmds
+from sunbeam.commands import node_labels as node_labels_cmds
+from sunbeam.commands import node_roles as node_roles_cmds
+from sunbeam.commands import quorum as quorum_cmds
 from sunbeam.commands import refresh as refresh_cmds
 from sunbeam.commands import resize as resize_cmds
+from sunbeam.commands import tokens as tokens_cmds
+from sunbeam.commands import wait as wait_cmds
+from sunbeam.commands import webhooks as webhooks_cmds
 from sunbeam.commands.configure import (
     DemoSetup,
     TerraformDemoInitStep,
@@ -26,6 +44,7 @@ from sunbeam.commands.configure import (
     retrieve_admin_credentials,
 )
 from sunbeam.commands.dashboard_url import retrieve_dashboard_url
+from sunbeam.commands.operation import click_option_detach_bootstrap
 from sunbeam.commands.proxy import PromptForProxyStep
 from sunbeam.core import ovn
 from sunbeam.core.checks import (
@@ -67,6 +86,7 @@ from sunbeam.core.juju import (
 )
 from sunbeam.core.manifest import AddManifestStep
 from sunbeam.core.openstack import OPENSTACK_MODEL
+from sunbeam.core.output import click_option_format, print_structured
 from sunbeam.core.terraform import TerraformInitStep
 from sunbeam.feature_gates import feature_gate_option, split_roles_enabled
 from sunbeam.provider.base import ProviderBase
@@ -262,7 +282,24 @@ class MaasProvider(ProviderBase):
         cluster.add_command(bootstrap)
         cluster.add_command(deploy)
         cluster.add_command(list_nodes)
+        cluster.add_command(node_labels_cmds.node)
+        node_labels_cmds.node.add_command(node_inventory_cmds.inventory)
+        node_labels_cmds.node.add_command(node_roles_cmds.role)
+        node_labels_cmds.node.add_command(node_cordon_cmds.cordon)
+        node_labels_cmds.node.add_command(node_cordon_cmds.uncordon)
         cluster.add_command(resize_cmds.resize)
+        cluster.add_command(backup_cmds.backup)
+        cluster.add_command(backup_cmds.restore)
+        cluster.add_command(certificates_cmds.cert)
+        cluster.add_command(webhooks_cmds.webhook)
+        cluster.add_command(tokens_cmds.token)
+        cluster.add_command(cluster_config_cmds.config)
+        cluster.add_command(cluster_db_cmds.db)
+        cluster.add_command(health_cmds.health)
+        cluster.add_command(wait_cmds.wait)
+        cluster.add_command(quorum_cmds.status)
+        cluster.add_command(quorum_cmds.recover)
+        cluster.add_command(host_inventory_cmds.inventory)
         cluster.add_command(refresh_cmds.refresh)
         cluster.add_command(remove_node)
         cluster.add_command(destroy_deployment_cmd)
@@ -311,6 +348,7 @@ class MaasProvider(ProviderBase):
     help="Token obtained from the region controller.",
     type=str,
 )
+@click_option_detach_bootstrap
 @click_option_show_hints
 @click.pass_context
 def bootstrap(
@@ -1099,25 +1137,66 @@ def configure_cmd(
 
 
 @click.command("list")
+@click_option_format()
 @click.option(
-    "-f",
-    "--format",
-    type=click.Choice([FORMAT_TABLE, FORMAT_YAML]),
-    default=FORMAT_TABLE,
-    help="Output format.",
+    "--role",
+    multiple=True,
+    help="Only list nodes having this role, can be repeated.",
+)
+@click.option(
+    "--label",
+    multiple=True,
+    help="Only list nodes having this key=value label, can be repeated.",
+)
+@click.option(
+    "--limit",
+    type=click.IntRange(min=1),
+    help="Maximum number of nodes to list when filtering by role or label.",
 )
 @click_option_show_hints
 @click.pass_context
-def list_nodes(ctx: click.Context, format: str, show_hints: bool) -> None:
-    """List nodes in the custer."""
+def list_nodes(
+    ctx: click.Context,
+    format: str,
+    role: tuple[str, ...],
+    label: tuple[str, ...],
+    limit: int | None,
+    show_hints: bool,
+) -> None:
+    """List nodes in the cluster.
+
+    The json and yaml formats list the nodes of the cluster, see
+    sunbeam.steps.cluster_status.list_node_statuses for their stable schema.
+    """
     deployment: MaasDeployment = ctx.obj
     jhelper = JujuHelper(deployment.juju_controller)
     step = MaasClusterStatusStep(deployment, jhelper)
     results = run_plan([step], console, show_hints)
     msg = get_step_message(results, MaasClusterStatusStep)
+    if format != FORMAT_TABLE:
+        try:
+            nodes = cluster_status.list_node_statuses(
+                deployment, msg, role, label, limit
+            )
+        except (InvalidNodeRoleException, InvalidNodeLabelException) as e:
+            raise click.ClickException(str(e))
+        print_structured(console, nodes, format)
+        return
+    shown = total = None
+    if role or label:
+        try:
+            msg, shown, total = cluster_status.filter_status_by_nodes(
+                deployment, msg, role, label, limit
+            )
+        except (InvalidNodeRoleException, InvalidNodeLabelException) as e:
+            raise click.ClickException(str(e))
+    msg = cluster_status.mark_cordoned_nodes(deployment, msg)
+    msg = cluster_status.mark_node_addresses(deployment, msg)
     renderables = cluster_status.format_status(deployment, msg, format)
     for renderable in renderables:
         console.print(renderable)
+    if total is not None:
+        console.print(f"Showing {shown} of {total} nodes")
 
 
 @click.command("maas")
@@ -1,15 +1,52 @@
 # SPDX-FileCopyrightText: 2023 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import json
 import logging
+import os
+import tempfile
+from pathlib import Path
+from typing import Sequence
 
+import click
+from rich.console import Console
 from rich.status import Status
+from snaphelpers import Snap
 
+from sunbeam import utils
 from sunbeam.clusterd.client import Client
-from sunbeam.core.common import BaseStep, Result, ResultType
+from sunbeam.core.common import (
+    SHARE_PATH,
+    BaseStep,
+    Result,
+    ResultType,
+    run_plan,
+)
+from sunbeam.core.deployment import Deployment
+from sunbeam.core.juju import JujuHelper
+from sunbeam.core.manifest import Manifest
+from sunbeam.core.steps import DeployMachineApplicationStep
+from sunbeam.core.terraform import TerraformInitStep
+from sunbeam.steps.clusterd import ClusterInitStep, ClusterResetStep
+from sunbeam.steps.juju import (
+    AddJujuModelStep,
+    BootstrapJujuStep,
+    DestroyJujuControllerStep,
+    DestroyJujuModelStep,
+)
+from sunbeam.steps.openstack import DeployControlPlaneStep
+from sunbeam.steps.terraform import DestroyTerraformPlanStep
 
 LOG = logging.getLogger(__name__)
 
+BOOTSTRAP_JOURNAL = SHARE_PATH / "bootstrap-journal.json"
+
+# Kinds of resources created by bootstrap
+CLUSTERD = "clusterd"
+JUJU_CONTROLLER = "juju-controller"
+JUJU_MODEL = "juju-model"
+TERRAFORM_PLAN = "terraform-plan"
+
 
 class SetBootstrapped(BaseStep):
     """Post Deployment step to update bootstrap flag in cluster DB."""
@@ -23,3 +60,144 @@ class SetBootstrapped(BaseStep):
         LOG.debug("Setting deployment as bootstrapped")
         self.client.cluster.set_sunbeam_bootstrapped()
         return Result(ResultType.COMPLETED)
+
+
+def bootstrap_journal_path(snap: Snap) -> Path:
+    """Path to the journal of the resources created by bootstrap."""
+    return snap.paths.real_home / BOOTSTRAP_JOURNAL
+
+
+def created_resource(step: BaseStep) -> dict | None:
+    """Return the resource created by step, None if it creates none to undo."""
+    if isinstance(step, ClusterInitStep):
+        return {"kind": CLUSTERD, "name": utils.get_fqdn(step.management_cidr)}
+    if isinstance(step, BootstrapJujuStep):
+        return {"kind": JUJU_CONTROLLER, "controller": step.controller}
+    if isinstance(step, AddJujuModelStep):
+        return {"kind": JUJU_MODEL, "model": step.model}
+    if isinstance(step, (DeployMachineApplicationStep, DeployControlPlaneStep)):
+        return {"kind": TERRAFORM_PLAN, "plan": step.tfhelper.plan}
+    return None
+
+
+class BootstrapJournal:
+    """Journal of the resources created by bootstrap, in creation order.
+
+    A resource is recorded before the step creating it runs, so that the
+    resources partially created by a failed or interrupted step are rolled
+    back too. The journal is persisted after every change.
+    """
+
+    def __init__(self, path: Path, resources: list[dict] | None = None):
+        self.path = path
+        self.resources = resources or []
+
+    @classmethod
+    def load(cls, path: Path) -> "BootstrapJournal":
+        """Load the journal from file, empty if there is none."""
+        if not path.exists():
+            return cls(path)
+        LOG.debug(f"Loading bootstrap journal from {str(path)!r}")
+        with path.open() as fd:
+            data = json.load(fd)
+        if not isinstance(data, list):
+            raise ValueError(
+                f"{str(path)} is corrupted, delete it or restore from back-up."
+            )
+        return cls(path, data)
+
+    def write(self):
+        """Write the journal to file.
+
+        Writing to temporary file first in case there's an error during write.
+        Not to lose the original file.
+        """
+        LOG.debug(f"Writing bootstrap journal to {str(self.path)!r}")
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        with tempfile.NamedTemporaryFile(
+            "w", dir=self.path.parent, delete=False
+        ) as tmp:
+            json.dump(self.resources, tmp)
+        os.chmod(tmp.name, 0o600)
+        os.replace(tmp.name, self.path)
+
+    def clear(self):
+        """Forget all the resources, once bootstrap succeeded."""
+        self.resources = []
+        self.path.unlink(missing_ok=True)
+
+    def run_plan(self, plan: Sequence[BaseStep], console: Console, no_hint: bool):
+        """Run plan like run_plan, recording the resources created by its steps."""
+        results = {}
+        for step in plan:
+            resource = created_resource(step)
+            recorded = resource is None or resource in self.resources
+            if not recorded:
+                self.resources.append(resource)  # type: ignore[arg-type]
+                self.write()
+
+            step_results = run_plan([step], console, no_hint)
+            results.update(step_results)
+
+            skipped = (
+                step_results[step.__class__.__name__].result_type
+                == ResultType.SKIPPED
+            )
+            if not recorded and skipped:
+                # The resource is not created by this bootstrap
+                self.resources.remove(resource)  # type: ignore[arg-type]
+                self.write()
+
+        return results
+
+    def rollback(
+        self,
+        deployment: Deployment,
+        manifest: Manifest,
+        console: Console,
+        no_hint: bool,
+    ):
+        """Remove the recorded resources in the reverse order of creation.
+
+        A resource is forgotten once removed, and removing a resource already
+        gone is skipped, so an interrupted rollback can be run again.
+        """
+        while self.resources:
+            resource = self.resources[-1]
+            LOG.debug(f"Rolling back {resource}")
+            plan = get_rollback_plan(deployment, manifest, resource)
+            run_plan(plan, console, no_hint)
+            self.resources.pop()
+            self.write()
+        self.clear()
+
+
+def get_rollback_plan(
+    deployment: Deployment, manifest: Manifest, resource: dict
+) -> list[BaseStep]:
+    """Return the plan removing a resource created by bootstrap."""
+    kind = resource.get("kind")
+    if kind == TERRAFORM_PLAN:
+        tfhelper = deployment.get_tfhelper(resource["plan"])
+        return [
+            TerraformInitStep(tfhelper),
+            DestroyTerraformPlanStep(deployment, tfhelper),
+        ]
+    if kind == JUJU_CONTROLLER:
+        return [
+            DestroyJujuControllerStep(
+                deployment, manifest.core.software.juju.destroy_args
+            )
+        ]
+    if kind == JUJU_MODEL:
+        if deployment.juju_controller is None:
+            LOG.debug(f"No Juju controller, model {resource['model']} is gone")
+            return []
+        return [
+            DestroyJujuModelStep(
+                JujuHelper(deployment.juju_controller), resource["model"]
+            )
+        ]
+    if kind == CLUSTERD:
+        return [ClusterResetStep(deployment.get_client(), resource["name"])]
+    raise click.ClickException(f"Cannot roll back unknown resource {resource}")
@@ -10,6 +10,7 @@ from sunbeam.core.juju import (
     JujuWaitException,
 )
 from sunbeam.core.manifest import CharmManifest, Manifest
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 from sunbeam.versions import JUJU_BASE
 
 LOG = logging.getLogger(__name__)
@@ -70,13 +71,18 @@ class DeployCertificatesProviderApplicationStep(BaseStep):
         )
 
         apps = self.jhelper.get_application_names(self.model)
+        timeout = step_timeout(self, CERTIFICATES_APP_TIMEOUT)
         try:
             self.jhelper.wait_until_active(
                 self.model,
                 apps,
-                timeout=CERTIFICATES_APP_TIMEOUT,
+                timeout=timeout,
             )
-        except (JujuWaitException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except JujuWaitException as e:
             LOG.warning(str(e))
             return Result(ResultType.FAILED, str(e))
 
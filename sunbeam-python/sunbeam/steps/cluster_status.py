@@ -11,7 +11,10 @@ import rich.table
 import yaml
 from rich.status import Status
 
-from sunbeam.clusterd.service import ClusterServiceUnavailableException
+from sunbeam.clusterd.service import (
+    ClusterQuorumLostException,
+    ClusterServiceUnavailableException,
+)
 from sunbeam.core.common import (
     FORMAT_TABLE,
     FORMAT_YAML,
@@ -21,6 +24,7 @@ from sunbeam.core.common import (
 )
 from sunbeam.core.deployment import Deployment
 from sunbeam.core.juju import JujuHelper, ModelNotFoundException
+from sunbeam.core.output import rfc3339
 from sunbeam.core.steps import BaseStep
 from sunbeam.steps import clusterd, hypervisor, k8s, microceph, microovn
 from sunbeam.utils import merge_dict
@@ -60,6 +64,15 @@ def _capitalize(s: str) -> str:
     return " ".join(word.capitalize() for word in s.split("-"))
 
 
+def _format_addresses(node: dict) -> str:
+    """Format the named addresses of a node, marking its preferred API one."""
+    return "\n".join(
+        f"{name}={address}"
+        + (" (api)" if name == node.get("preferred_api") else "")
+        for name, address in sorted(node.get("addresses", {}).items())
+    )
+
+
 def format_status(
     deployment: Deployment,
     status: dict,
@@ -80,6 +93,10 @@ def format_status(
             role: [<role>, ...]
             status:
                 <role>: <status>
+            cordoned: true, only for the cordoned nodes
+            addresses:
+                <name>: <address>, only for the nodes with named addresses
+            preferred_api: <name>, only for the nodes with named addresses
     """
     if format == FORMAT_TABLE:
         tables = []
@@ -88,6 +105,11 @@ def format_status(
                 title=model,
             )
             table.add_column("Node", justify="left")
+            with_addresses = any(
+                node.get("addresses") for node in model_status.values()
+            )
+            if with_addresses:
+                table.add_column("Addresses", justify="left")
             column_set: set[str] = set()
             for status_name in model_status.values():
                 column_set.update(status_name.get("status", {}).keys())
@@ -97,8 +119,12 @@ def format_status(
             for column in columns:
                 table.add_column(_capitalize(column), justify="center")
             for id, node in model_status.items():
+                name = node.get("hostname", id)
+                if node.get("cordoned"):
+                    name += " " + ORANGE.format("(cordoned)")
                 table.add_row(
-                    node.get("hostname", id),
+                    name,
+                    *([_format_addresses(node)] if with_addresses else []),
                     *(
                         color_status(node.get("status", {}).get(column))
                         for column in columns
@@ -112,6 +138,147 @@ def format_status(
         return [str(status)]
 
 
+def filter_status_by_nodes(
+    deployment: Deployment,
+    status: dict,
+    role: Sequence[str] = (),
+    label: Sequence[str] = (),
+    limit: int | None = None,
+) -> tuple[dict, int, int]:
+    """Filter the openstack machines model status to matching nodes.
+
+    Nodes having any of the roles and all of the key=value labels are kept.
+    Returns the filtered status, the number of nodes kept and the total number
+    of matching nodes in the cluster database.
+    """
+    client = deployment.get_client()
+    nodes, total = client.cluster.list_nodes_page(
+        role=list(role), limit=limit, label=list(label)
+    )
+    names = {node["name"] for node in nodes}
+    filtered = dict(status)
+    model = deployment.openstack_machines_model
+    filtered[model] = {
+        machine: machine_status
+        for machine, machine_status in status.get(model, {}).items()
+        if machine_status.get("hostname") in names
+    }
+    return filtered, len(nodes), total
+
+
+def mark_cordoned_nodes(deployment: Deployment, status: dict) -> dict:
+    """Mark the cordoned nodes in the openstack machines model status."""
+    client = deployment.get_client()
+    cordoned = {
+        node["name"] for node in client.cluster.list_nodes() if node.get("cordoned")
+    }
+    marked = dict(status)
+    model = deployment.openstack_machines_model
+    marked[model] = {
+        machine: (
+            {**machine_status, "cordoned": True}
+            if machine_status.get("hostname") in cordoned
+            else machine_status
+        )
+        for machine, machine_status in status.get(model, {}).items()
+    }
+    return marked
+
+
+def mark_node_addresses(deployment: Deployment, status: dict) -> dict:
+    """Add the named addresses of the nodes to the openstack machines model."""
+    client = deployment.get_client()
+    nodes = {
+        node["name"]: node
+        for node in client.cluster.list_nodes()
+        if node.get("addresses")
+    }
+    marked = dict(status)
+    model = deployment.openstack_machines_model
+    marked[model] = {}
+    for machine, machine_status in status.get(model, {}).items():
+        node = nodes.get(machine_status.get("hostname"))
+        if node:
+            machine_status = {
+                **machine_status,
+                "addresses": node["addresses"],
+                "preferred_api": node.get("preferred_api"),
+            }
+        marked[model][machine] = machine_status
+    return marked
+
+
+def list_node_statuses(
+    deployment: Deployment,
+    status: dict,
+    role: Sequence[str] = (),
+    label: Sequence[str] = (),
+    limit: int | None = None,
+) -> list[dict]:
+    """Return the nodes of the cluster with their status, for structured output.
+
+    Nodes having any of the roles and all of the key=value labels are kept.
+    The schema of each node is stable, fields are only ever added:
+
+    name: <hostname>
+    role: [<role>, ...]
+    status:
+        <column>: <status>
+    addresses: [<address>, ...]
+    named_addresses:
+        <name>: <address>
+    preferred_api: <name or null>
+    labels:
+        <key>: <value>
+    cordoned: <bool>
+    last_heartbeat: <RFC3339 timestamp or null>
+
+    Status columns are the ones of the openstack machines model status:
+    machine, cluster and the node roles. Addresses lists the cluster address
+    of the node followed by its other named addresses.
+    """
+    client = deployment.get_client()
+    if role or label:
+        nodes, _ = client.cluster.list_nodes_page(
+            role=list(role), limit=limit, label=list(label)
+        )
+    else:
+        nodes = client.cluster.list_nodes()
+    members = client.cluster.get_status()
+    machines = {
+        machine_status.get("hostname"): machine_status.get("status", {})
+        for machine_status in status.get(
+            deployment.openstack_machines_model, {}
+        ).values()
+    }
+
+    statuses = []
+    for node in sorted(nodes, key=lambda node: node["name"]):
+        member = members.get(node["name"], {})
+        addresses = []
+        if address := member.get("address"):
+            # Drop the clusterd port
+            addresses.append(address.rsplit(":", 1)[0].strip("[]"))
+        named_addresses = node.get("addresses") or {}
+        for _, address in sorted(named_addresses.items()):
+            if address not in addresses:
+                addresses.append(address)
+        statuses.append(
+            {
+                "name": node["name"],
+                "role": sorted(node.get("role") or []),
+                "status": machines.get(node["name"], {}),
+                "addresses": addresses,
+                "named_addresses": named_addresses,
+                "preferred_api": node.get("preferred_api"),
+                "labels": node.get("labels") or {},
+                "cordoned": bool(node.get("cordoned")),
+                "last_heartbeat": rfc3339(member.get("last_heartbeat")),
+            }
+        )
+    return statuses
+
+
 class ClusterStatusStep(abc.ABC, BaseStep):
     def __init__(self, deployment: Deployment, jhelper: JujuHelper):
         super().__init__("Cluster Status", "Querying cluster status")
@@ -142,6 +309,11 @@ class ClusterStatusStep(abc.ABC, BaseStep):
         client = self.deployment.get_client()
         try:
             cluster_status = client.cluster.get_status()
+        except ClusterQuorumLostException as e:
+            LOG.debug("Failed to query cluster status", exc_info=True)
+            raise SunbeamException(
+                f"{e}. See `sunbeam cluster status` for the recovery steps."
+            ) from e
         except ClusterServiceUnavailableException:
             LOG.debug("Failed to query cluster status", exc_info=True)
             raise SunbeamException("Cluster service is not yet bootstrapped.")
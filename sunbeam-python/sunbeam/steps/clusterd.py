@@ -1,6 +1,7 @@
 # SPDX-FileCopyrightText: 2023 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import datetime
 import ipaddress
 import logging
 import re
@@ -14,6 +15,12 @@ from sunbeam.clusterd.service import (
     ClusterAlreadyBootstrappedException,
     ClusterServiceUnavailableException,
     ConfigItemNotFoundException,
+    InvalidJoinTokenScopeException,
+    InvalidNodeAddressException,
+    InvalidNodeInventoryException,
+    InvalidNodeRoleException,
+    JoinTokenRejectedException,
+    JoinTokenScopeAlreadyExistsException,
     JujuUserNotFoundException,
     LastNodeRemovalFromClusterException,
     NodeAlreadyExistsException,
@@ -23,8 +30,15 @@ from sunbeam.clusterd.service import (
     TokenNotFoundException,
     URLNotFoundException,
 )
-from sunbeam.core import questions
-from sunbeam.core.common import BaseStep, Result, ResultType, Status
+from sunbeam.core import inventory, questions
+from sunbeam.core.common import (
+    MANAGEMENT_ADDRESS,
+    BaseStep,
+    Result,
+    ResultType,
+    Status,
+    preferred_api_address,
+)
 from sunbeam.core.juju import (
     ApplicationNotFoundException,
     JujuController,
@@ -34,6 +48,7 @@ from sunbeam.core.juju import (
     ModelNotFoundException,
 )
 from sunbeam.core.manifest import CharmManifest, Manifest
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 from sunbeam.steps.juju import BOOTSTRAP_CONFIG_KEY
 
 LOG = logging.getLogger(__name__)
@@ -42,6 +57,8 @@ SUNBEAM_CLUSTERD_APP_TIMEOUT = (
     1200  # 20 minutes, adding / removing units can take a long time
 )
 CLUSTERD_PORT = 7000
+# Status of the cluster members reachable by the other members
+MEMBER_STATUS_ONLINE = "ONLINE"
 
 
 def bootstrap_questions():
@@ -108,6 +125,8 @@ class ClusterInitStep(BaseStep):
                 address=address,
                 role=self.role,
                 machineid=self.machineid,
+                addresses={MANAGEMENT_ADDRESS: ip},
+                preferred_api=MANAGEMENT_ADDRESS,
             )
             LOG.debug("Bootstrapped clusterd on %s", address)
             return Result(ResultType.COMPLETED)
@@ -231,7 +250,12 @@ class SaveManagementCidrStep(BaseStep):
 class ClusterAddNodeStep(BaseStep):
     """Generate token for new node to join in cluster."""
 
-    def __init__(self, client: Client, name: str):
+    def __init__(
+        self,
+        client: Client,
+        name: str,
+        expire_after: datetime.timedelta | None = None,
+    ):
         super().__init__(
             "Add Node Cluster",
             "Generating token for new node to join cluster",
@@ -239,6 +263,7 @@ class ClusterAddNodeStep(BaseStep):
 
         self.node_name = name
         self.client = client
+        self.expire_after = expire_after
 
     def is_skip(self, status: Status | None = None) -> Result:
         """Determines if the step should be skipped or not.
@@ -269,7 +294,9 @@ class ClusterAddNodeStep(BaseStep):
     def run(self, status: Status | None = None) -> Result:
         """Add node to sunbeam cluster."""
         try:
-            token = self.client.cluster.add_node(name=self.node_name)
+            token = self.client.cluster.add_node(
+                name=self.node_name, expire_after=self.expire_after
+            )
             LOG.debug("Generated token for node %s", self.node_name)
             return Result(result_type=ResultType.COMPLETED, message=token)
         except TokenAlreadyGeneratedException as e:
@@ -277,8 +304,74 @@ class ClusterAddNodeStep(BaseStep):
             return Result(ResultType.FAILED, str(e))
 
 
+class ClusterScopeJoinTokenStep(BaseStep):
+    """Restrict the join token of a new node to roles and a TTL.
+
+    clusterd rejects the join of the node with other roles, once the token
+    expired or once it was used.
+    """
+
+    def __init__(
+        self,
+        client: Client,
+        name: str,
+        roles: list[str],
+        ttl: str | None = None,
+    ):
+        super().__init__(
+            "Scope join token",
+            "Restricting the join token of the new node",
+        )
+
+        self.node_name = name
+        self.client = client
+        self.roles = roles
+        self.ttl = ttl
+
+    def is_skip(self, status: Status | None = None) -> Result:
+        """Determines if the step should be skipped or not.
+
+        :return: ResultType.SKIPPED if the Step should be skipped,
+                 ResultType.COMPLETED or ResultType.FAILED otherwise
+        """
+        try:
+            members = self.client.cluster.get_cluster_members()
+        except ClusterServiceUnavailableException as e:
+            LOG.debug(e)
+            return Result(ResultType.FAILED, str(e))
+
+        if self.node_name in [member.get("name") for member in members]:
+            return Result(ResultType.SKIPPED)
+
+        return Result(ResultType.COMPLETED)
+
+    def run(self, status: Status | None = None) -> Result:
+        """Record the scope of the join token in clusterd."""
+        try:
+            scope = self.client.cluster.create_join_token_scope(
+                self.node_name, self.roles, ttl=self.ttl
+            )
+        except (
+            InvalidJoinTokenScopeException,
+            InvalidNodeRoleException,
+            JoinTokenScopeAlreadyExistsException,
+        ) as e:
+            LOG.debug(e)
+            return Result(ResultType.FAILED, str(e))
+
+        LOG.debug(
+            "Join token of node %s scoped to roles %s", self.node_name, scope.roles
+        )
+        return Result(ResultType.COMPLETED, scope)
+
+
 class ClusterJoinNodeStep(BaseStep):
-    """Join node to the sunbeam cluster."""
+    """Join node to the sunbeam cluster.
+
+    The node is recorded with its named addresses, host_address being its
+    management address unless given among them. It joins the cluster on its
+    preferred API address, the management one unless named.
+    """
 
     def __init__(
         self,
@@ -287,6 +380,8 @@ class ClusterJoinNodeStep(BaseStep):
         host_address: str,
         fqdn: str,
         role: list[str],
+        addresses: dict[str, str] | None = None,
+        preferred_api: str | None = None,
     ):
         super().__init__("Join node to Cluster", "Adding node to Sunbeam cluster")
 
@@ -295,7 +390,10 @@ class ClusterJoinNodeStep(BaseStep):
         self.token = token
         self.role = role
         self.ip = host_address
+        self.addresses = {MANAGEMENT_ADDRESS: host_address, **(addresses or {})}
+        self.preferred_api = preferred_api or MANAGEMENT_ADDRESS
         self.fqdn = fqdn
+        self.joined = False
 
     def is_skip(self, status: Status | None = None) -> Result:
         """Determines if the step should be skipped or not.
@@ -308,7 +406,14 @@ class ClusterJoinNodeStep(BaseStep):
             LOG.info(members)
             member_names = [member.get("name") for member in members]
             if self.fqdn in member_names:
+                self.client.cluster.get_node_info(self.fqdn)
                 return Result(ResultType.SKIPPED)
+        except NodeNotExistInClusterException:
+            # Joined, but the node was not recorded, e.g. with an undefined
+            # custom role
+            LOG.debug(f"Node {self.fqdn} joined the cluster but is not recorded")
+            self.joined = True
+            return Result(ResultType.COMPLETED)
         except ClusterServiceUnavailableException as e:
             LOG.debug(e)
             if "Sunbeam Cluster not initialized" in str(e):
@@ -320,15 +425,35 @@ class ClusterJoinNodeStep(BaseStep):
     def run(self, status: Status | None = None) -> Result:
         """Join node to sunbeam cluster."""
         try:
-            self.client.cluster.join_node(
-                name=self.fqdn,
-                address=f"{self.ip}:{self.port}",
-                token=self.token,
-                role=self.role,
-            )
+            api_address = preferred_api_address(self.addresses, self.preferred_api)
+        except ValueError as e:
+            return Result(ResultType.FAILED, str(e))
+        try:
+            if self.joined:
+                self.client.cluster.add_node_info(
+                    self.fqdn,
+                    self.role,
+                    addresses=self.addresses,
+                    preferred_api=self.preferred_api,
+                )
+            else:
+                self.client.cluster.join_node(
+                    name=self.fqdn,
+                    address=f"{api_address}:{self.port}",
+                    token=self.token,
+                    role=self.role,
+                    addresses=self.addresses,
+                    preferred_api=self.preferred_api,
+                )
             LOG.info(self.token)
             return Result(result_type=ResultType.COMPLETED, message=self.token)
-        except (NodeAlreadyExistsException, NodeJoinException) as e:
+        except (
+            NodeAlreadyExistsException,
+            NodeJoinException,
+            InvalidNodeRoleException,
+            InvalidNodeAddressException,
+            JoinTokenRejectedException,
+        ) as e:
             LOG.warning(e)
             return Result(ResultType.FAILED, str(e))
 
@@ -389,20 +514,152 @@ class ClusterUpdateNodeStep(BaseStep):
             return Result(ResultType.FAILED, str(e))
 
 
-class ClusterRemoveNodeStep(BaseStep):
-    """Remove node from the sunbeam cluster."""
+class ClusterUpdateNodeInventoryStep(BaseStep):
+    """Report the hardware inventory of the local node to the cluster database."""
 
     def __init__(self, client: Client, name: str):
+        super().__init__(
+            "Update node inventory", "Reporting node hardware to cluster database"
+        )
+        self.client = client
+        self.node_name = name
+
+    def run(self, status: Status | None = None) -> Result:
+        """Collect and report the node hardware."""
+        try:
+            hardware = inventory.collect_hardware()
+        except Exception as e:
+            # The inventory is informational, do not fail the deployment on it
+            LOG.warning("Failed to collect hardware inventory: %s", e)
+            return Result(ResultType.SKIPPED, str(e))
+        try:
+            node_inventory = self.client.cluster.update_node_inventory(
+                self.node_name, hardware
+            )
+            LOG.debug("Recorded inventory revision %d", node_inventory.revision)
+            return Result(result_type=ResultType.COMPLETED)
+        except InvalidNodeInventoryException as e:
+            LOG.warning("Hardware inventory rejected: %s", e)
+            return Result(ResultType.SKIPPED, str(e))
+        except ClusterServiceUnavailableException as e:
+            LOG.debug(e)
+            return Result(ResultType.FAILED, str(e))
+
+
+class ClusterResetStep(BaseStep):
+    """Reset sunbeam clusterd to its state before bootstrap."""
+
+    def __init__(self, client: Client, name: str):
+        super().__init__("Reset Cluster", "Resetting Sunbeam cluster")
+        self.node_name = name
+        self.client = client
+
+    def is_skip(self, status: Status | None = None) -> Result:
+        """Determines if the step should be skipped or not.
+
+        :return: ResultType.SKIPPED if the Step should be skipped,
+                 ResultType.COMPLETED or ResultType.FAILED otherwise
+        """
+        try:
+            members = self.client.cluster.get_cluster_members()
+        except ClusterServiceUnavailableException as e:
+            LOG.debug(e)
+            if "Sunbeam Cluster not initialized" in str(e):
+                return Result(ResultType.SKIPPED)
+            return Result(ResultType.FAILED, str(e))
+
+        member_names = [member.get("name") for member in members]
+        if member_names != [self.node_name]:
+            return Result(
+                ResultType.FAILED,
+                f"Cannot reset the cluster, {self.node_name} is not its only member",
+            )
+
+        return Result(ResultType.COMPLETED)
+
+    def run(self, status: Status | None = None) -> Result:
+        """Reset sunbeam cluster."""
+        try:
+            self.client.cluster.reset(self.node_name)
+        except Exception as e:
+            LOG.debug(e)
+            return Result(ResultType.FAILED, str(e))
+
+        return Result(ResultType.COMPLETED)
+
+
+def is_node_removed(client: Client, name: str) -> bool:
+    """Whether the node is neither a member, pending join nor recorded."""
+    members = client.cluster.get_cluster_members()
+    if name in [member.get("name") for member in members]:
+        return False
+
+    if name in [token.get("name") for token in client.cluster.list_tokens()]:
+        return False
+
+    try:
+        client.cluster.get_node_info(name)
+    except NodeNotExistInClusterException:
+        return True
+    return False
+
+
+class CheckNodeReachableStep(BaseStep):
+    """Check the node can be removed gracefully.
+
+    Draining and departing a node requires it to be online, fail early with
+    a hint to force the removal rather than timing out on the node.
+    """
+
+    def __init__(self, client: Client, name: str):
+        super().__init__(
+            "Check node reachable", "Checking node is reachable for removal"
+        )
+        self.node_name = name
+        self.client = client
+
+    def run(self, status: Status | None = None) -> Result:
+        """Fail if the node is a cluster member that is not online."""
+        try:
+            members = self.client.cluster.get_cluster_members()
+        except ClusterServiceUnavailableException as e:
+            LOG.debug(e)
+            return Result(ResultType.FAILED, "Sunbeam Cluster service is unavailable.")
+
+        for member in members:
+            if member.get("name") != self.node_name:
+                continue
+            member_status = member.get("status")
+            if member_status != MEMBER_STATUS_ONLINE:
+                return Result(
+                    ResultType.FAILED,
+                    f"Node {self.node_name} is {member_status}, it cannot be"
+                    " drained and removed gracefully. Re-run with --force to"
+                    " remove it from the cluster without draining it.",
+                )
+
+        return Result(ResultType.COMPLETED)
+
+
+class ClusterRemoveNodeStep(BaseStep):
+    """Remove node from the sunbeam cluster.
+
+    With force, the node is removed from the raft membership even if it is
+    unreachable, so that it can't rejoin the cluster.
+    """
+
+    def __init__(self, client: Client, name: str, force: bool = False):
         super().__init__(
             "Remove node from Cluster", "Removing node from Sunbeam cluster"
         )
         self.node_name = name
         self.client = client
+        self.force = force
 
     def run(self, status: Status | None = None) -> Result:
         """Remove node from sunbeam cluster."""
         try:
-            self.client.cluster.remove_node(self.node_name)
+            self.client.cluster.remove_node(self.node_name, force=self.force)
             return Result(result_type=ResultType.COMPLETED)
         except (
             TokenNotFoundException,
@@ -764,13 +1021,18 @@ class DeploySunbeamClusterdApplicationStep(BaseStep):
         )
 
         apps = self.jhelper.get_application_names(self.model)
+        timeout = step_timeout(self, SUNBEAM_CLUSTERD_APP_TIMEOUT)
         try:
             self.jhelper.wait_until_active(
                 self.model,
                 apps,
-                timeout=SUNBEAM_CLUSTERD_APP_TIMEOUT,
+                timeout=timeout,
             )
-        except (JujuWaitException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except JujuWaitException as e:
             LOG.warning(str(e))
             return Result(ResultType.FAILED, str(e))
 
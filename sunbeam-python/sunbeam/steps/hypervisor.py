@@ -47,6 +47,7 @@ from sunbeam.core.terraform import (
     TerraformHelper,
     TerraformStateLockedException,
 )
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 from sunbeam.lazy import LazyImport
 from sunbeam.steps.configure import get_external_network_configs
 
@@ -264,6 +265,10 @@ class RemoveHypervisorUnitStep(BaseStep, JujuStepHelper):
         if not self.unit:
             LOG.debug(f"Unit is not deployed on machine: {self.machine_id}, skipping.")
             return Result(ResultType.SKIPPED)
+        if self.force:
+            # The unit may be unreachable, its actions would never complete
+            LOG.debug("Force mode set, not checking guests on hypervisor")
+            return Result(ResultType.COMPLETED)
         try:
             results = self.jhelper.run_action(self.unit, self.model, "running-guests")
         except ActionFailedException:
@@ -297,26 +302,34 @@ class RemoveHypervisorUnitStep(BaseStep, JujuStepHelper):
         """Remove unit from openstack-hypervisor application on Juju model."""
         if not self.unit:
             return Result(ResultType.FAILED, "Unit not found on machine")
-        try:
-            self.jhelper.run_action(self.unit, self.model, "disable")
-        except ActionFailedException as e:
-            LOG.debug(str(e))
-            return Result(ResultType.FAILED, "Failed to disable hypervisor unit")
+        if self.force:
+            LOG.debug("Force mode set, not disabling hypervisor unit")
+        else:
+            try:
+                self.jhelper.run_action(self.unit, self.model, "disable")
+            except ActionFailedException as e:
+                LOG.debug(str(e))
+                return Result(ResultType.FAILED, "Failed to disable hypervisor unit")
+        timeout = step_timeout(self, HYPERVISOR_UNIT_TIMEOUT)
         try:
             self.jhelper.remove_unit(APPLICATION, self.unit, self.model)
             self.remove_machine_id_from_tfvar()
             self.jhelper.wait_units_gone(
                 [self.unit],
                 self.model,
-                timeout=HYPERVISOR_UNIT_TIMEOUT,
+                timeout=timeout,
             )
             self.jhelper.wait_application_ready(
                 APPLICATION,
                 self.model,
                 accepted_status=["active", "unknown"],
-                timeout=HYPERVISOR_UNIT_TIMEOUT,
+                timeout=timeout,
             )
-        except (ApplicationNotFoundException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except ApplicationNotFoundException as e:
             LOG.warning(str(e))
             return Result(ResultType.FAILED, str(e))
         try:
@@ -415,17 +428,19 @@ class ReapplyHypervisorTerraformPlanStep(BaseStep):
 
         # Wait for more time since parallel node joins will take time
         # for openstack-hypervisor application to get settled
+        timeout = step_timeout(self, HYPERVISOR_UNIT_TIMEOUT)
         try:
             self.jhelper.wait_until_desired_status(
                 self.model,
                 [APPLICATION],
                 status=statuses,
                 agent_status=["idle"],
-                timeout=HYPERVISOR_UNIT_TIMEOUT,
+                timeout=timeout,
             )
         except TimeoutError as e:
-            LOG.warning(str(e))
-            return Result(ResultType.FAILED, str(e))
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
 
         return Result(ResultType.COMPLETED)
 
@@ -461,6 +476,9 @@ class DestroyHypervisorApplicationStep(DestroyMachineApplicationStep):
 class EnableHypervisorStep(BaseStep, JujuStepHelper):
     """Enable hypervisor service."""
 
+    # Action of the hypervisor charm run on the unit of the node
+    action = "enable"
+
     def __init__(
         self,
         client: Client,
@@ -469,8 +487,8 @@ class EnableHypervisorStep(BaseStep, JujuStepHelper):
         model: str,
     ):
         super().__init__(
-            "Enable hypervisor service",
-            "Enable hypervisor service for unit",
+            f"{self.action.capitalize()} hypervisor service",
+            f"{self.action.capitalize()} hypervisor service for unit",
         )
         self.client = client
         self.node = node
@@ -515,11 +533,21 @@ class EnableHypervisorStep(BaseStep, JujuStepHelper):
         if not self.unit:
             return Result(ResultType.FAILED, "Unit not found on machine")
         try:
-            self.jhelper.run_action(self.unit, self.model, "enable")
+            self.jhelper.run_action(self.unit, self.model, self.action)
         except ActionFailedException as e:
             LOG.debug(str(e))
             return Result(
                 ResultType.FAILED,
-                f"Failed to enable hypervisor service for unit {self.unit}",
+                f"Failed to {self.action} hypervisor service for unit {self.unit}",
             )
         return Result(ResultType.COMPLETED)
+
+
+class DisableHypervisorStep(EnableHypervisorStep):
+    """Disable hypervisor service.
+
+    New instances are no longer scheduled on the node, the running ones are
+    left in place.
+    """
+
+    action = "disable"
@@ -50,6 +50,7 @@ from sunbeam.core.juju import (
     JujuWaitException,
     ModelNotFoundException,
 )
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 from sunbeam.utils import random_string
 from sunbeam.versions import JUJU_BASE, JUJU_CHANNEL
 
@@ -1105,13 +1106,21 @@ class AddJujuMachineStep(BaseStep, JujuStepHelper):
 class RemoveJujuMachineStep(BaseStep, JujuStepHelper):
     """Remove machine in juju."""
 
-    def __init__(self, client: Client, name: str, jhelper: JujuHelper, model: str):
+    def __init__(
+        self,
+        client: Client,
+        name: str,
+        jhelper: JujuHelper,
+        model: str,
+        force: bool = False,
+    ):
         super().__init__("Remove machine", f"Removing machine {name} from Juju model")
 
         self.client = client
         self.jhelper = jhelper
         self.node_name = name
         self.model = model
+        self.force = force
         self.machine_id = -1
         self.model_with_owner: str | None = None
         home = os.environ.get("SNAP_REAL_HOME")
@@ -1169,6 +1178,9 @@ class RemoveJujuMachineStep(BaseStep, JujuStepHelper):
                 str(self.machine_id),
                 "--no-prompt",
             ]
+            if self.force:
+                # Remove the machine even if its agent is unreachable
+                cmd.append("--force")
             LOG.debug(f"Running command {' '.join(cmd)}")
             process = subprocess.run(cmd, capture_output=True, text=True, check=True)
             LOG.debug(
@@ -1836,13 +1848,18 @@ class IntegrateJujuApplicationsStep(BaseStep):
         # Juju might take a while to start integrate the applications
         time.sleep(15)
         apps = [self.requirer, self.provider]
+        timeout = step_timeout(self, 1200)
         try:
             self.jhelper.wait_until_active(
                 self.model,
                 apps,
-                timeout=1200,
+                timeout=timeout,
             )
-        except (JujuWaitException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except JujuWaitException as e:
             LOG.warning(str(e))
             return Result(ResultType.FAILED, str(e))
         return Result(ResultType.COMPLETED)
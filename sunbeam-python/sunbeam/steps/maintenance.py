@@ -2,13 +2,24 @@
 # SPDX-License-Identifier: Apache-2.0
 
 import logging
+import threading
+import time
 from abc import ABC, abstractmethod
-from typing import TYPE_CHECKING, Any
+from concurrent.futures import ThreadPoolExecutor, as_completed
+from typing import TYPE_CHECKING, Any, Callable, Literal
 
 import tenacity
+from requests.exceptions import HTTPError
 from rich.status import Status
 
 from sunbeam.clusterd.client import Client
+from sunbeam.clusterd.models import (
+    MaintenancePlan,
+    MaintenancePlanAction,
+    MaintenancePlanIndicator,
+    MaintenanceProgress,
+)
+from sunbeam.clusterd.service import RemoteException
 from sunbeam.core import watcher as watcher_helper
 from sunbeam.core.common import BaseStep, Result, ResultType, SunbeamException
 from sunbeam.core.deployment import Deployment
@@ -18,7 +29,17 @@ from sunbeam.core.juju import (
     JujuHelper,
     UnitNotFoundException,
 )
-from sunbeam.core.watcher import WatcherActionFailedException
+from sunbeam.core.openstack_api import (
+    InstanceMigrationFailedException,
+    get_admin_connection,
+    migrate_instance,
+)
+from sunbeam.core.watcher import (
+    WatcherActionFailedException,
+    WatcherAuditException,
+    WatcherAuditTimeoutException,
+)
+from sunbeam.lazy import LazyImport
 from sunbeam.steps.k8s import (
     CordonK8SUnitStep,
     DrainK8SUnitStep,
@@ -27,11 +48,19 @@ from sunbeam.steps.k8s import (
 from sunbeam.steps.microceph import APPLICATION as _MICROCEPH_APPLICATION
 
 if TYPE_CHECKING:
+    import openstack
     from watcherclient import v1 as watcher
     from watcherclient.v1 import client as watcher_client
+else:
+    openstack = LazyImport("openstack")
 
 LOG = logging.getLogger(__name__)
 
+# Watcher action types RunWatcherActionsStep knows how to run
+RUNNABLE_WATCHER_ACTION_TYPES = ("change_nova_service_state", "migrate")
+# Seconds without progress after which a migration is reported stalled
+MIGRATION_STALL_TIMEOUT = 5 * 60
+
 
 class MicroCephActionStep(BaseStep):
     def __init__(
@@ -81,12 +110,16 @@ class MicroCephActionStep(BaseStep):
 
 
 class CreateWatcherAuditStepABC(ABC, BaseStep):
+    # Maintenance operation planned by the audit
+    operation: Literal["enable", "disable"]
+
     def __init__(
         self,
         deployment: Deployment,
         node: str,
     ):
         super().__init__(self.name, self.description)
+        self.deployment = deployment
         self.node = node
         self.client: "watcher_client.Client" = watcher_helper.get_watcher_client(
             deployment=deployment
@@ -100,14 +133,71 @@ class CreateWatcherAuditStepABC(ABC, BaseStep):
     def _get_actions(self, audit: "watcher.Audit") -> list["watcher.Action"]:
         return watcher_helper.get_actions(client=self.client, audit=audit)
 
+    def _get_plan(
+        self, audit: "watcher.Audit", actions: list["watcher.Action"]
+    ) -> MaintenancePlan:
+        """Describe the action plan of the audit and the rationale of its actions."""
+        action_plans = watcher_helper.get_action_plans(client=self.client, audit=audit)
+        return MaintenancePlan(
+            node=self.node,
+            operation=self.operation,
+            audit=audit.uuid,
+            action_plan=action_plans[0].uuid if action_plans else "",
+            state="succeeded",
+            actions=[
+                MaintenancePlanAction(
+                    uuid=action.uuid,
+                    type=action.action_type,
+                    state=action.state,
+                    parameters=action.input_parameters or {},
+                    description=getattr(action, "description", None) or "",
+                )
+                for action in actions
+            ],
+            efficacy=[
+                MaintenancePlanIndicator(
+                    name=indicator["name"],
+                    description=indicator.get("description") or "",
+                    value=indicator.get("value"),
+                    unit=indicator.get("unit") or "",
+                )
+                for action_plan in action_plans
+                for indicator in getattr(action_plan, "global_efficacy", None) or []
+            ],
+        )
+
+    def _record_plan(self, plan: MaintenancePlan) -> None:
+        """Record the plan in the cluster database, failing only warns."""
+        try:
+            self.deployment.get_client().cluster.update_maintenance_plan(plan)
+        except (RemoteException, HTTPError) as e:
+            LOG.warning(f"Failed to record Watcher plan of {self.node}: {e}")
+
     def run(self, status: Status | None) -> Result:
         """Create Watcher audit."""
         try:
             audit = self._create_audit()
             actions = self._get_actions(audit)
+        except WatcherAuditException as e:
+            LOG.warning(e)
+            self._record_plan(
+                MaintenancePlan(
+                    node=self.node,
+                    operation=self.operation,
+                    audit=e.audit,
+                    state=(
+                        "timed-out"
+                        if isinstance(e, WatcherAuditTimeoutException)
+                        else "failed"
+                    ),
+                    reason=str(e),
+                )
+            )
+            return Result(ResultType.FAILED, str(e))
         except tenacity.RetryError as e:
             LOG.warning(e)
             return Result(ResultType.FAILED, "Unable to create Watcher audit")
+        self._record_plan(self._get_plan(audit, actions))
         return Result(
             ResultType.COMPLETED,
             {
@@ -120,6 +210,7 @@ class CreateWatcherAuditStepABC(ABC, BaseStep):
 class CreateWatcherHostMaintenanceAuditStep(CreateWatcherAuditStepABC):
     name = "Create Watcher Host maintenance audit"
     description = "Create Watcher Host maintenance audit"
+    operation = "enable"
 
     def __init__(
         self,
@@ -153,6 +244,7 @@ class CreateWatcherHostMaintenanceAuditStep(CreateWatcherAuditStepABC):
 class CreateWatcherWorkloadBalancingAuditStep(CreateWatcherAuditStepABC):
     name = "Create Watcher workload balancing audit"
     description = "Create Watcher workload balancing audit"
+    operation = "disable"
 
     def _create_audit(self) -> "watcher.Audit":
         audit_template = watcher_helper.get_workload_balancing_audit_template(
@@ -207,6 +299,292 @@ class RunWatcherAuditStep(BaseStep):
         )
 
 
+def progress_message(event: MaintenanceProgress) -> str:
+    """Describe a migration progress event in a line."""
+    message = f"migrated instances ({event.completed}/{event.total}), "
+    if event.state == "migrating":
+        message += f"migrating {event.instance}"
+        if event.percent is not None:
+            message += f" {event.percent}%"
+    elif event.state == "succeeded":
+        message += f"{event.instance} migrated"
+    elif event.state == "failed":
+        message += f"{event.instance} failed: {event.message}"
+    else:
+        message += f"{event.instance} {event.message}"
+    return message
+
+
+class MigrationProgressTracker:
+    """Report the progress of the migrations of a step as events.
+
+    A migration whose progress does not change for stall_timeout seconds is
+    reported stalled, then again each stall_timeout seconds it stays so.
+    Migrations run in threads, report is called holding a lock.
+    """
+
+    def __init__(
+        self,
+        total: int,
+        report: Callable[[MaintenanceProgress], None],
+        stall_timeout: float = MIGRATION_STALL_TIMEOUT,
+        clock: Callable[[], float] = time.monotonic,
+    ):
+        self.total = total
+        self.report = report
+        self.stall_timeout = stall_timeout
+        self.clock = clock
+        self.completed = 0
+        self._lock = threading.Lock()
+        # Per instance, its last percent, since when, and the last stall report
+        self._progress: dict[str, tuple[int | None, float, float]] = {}
+
+    def _event(self, instance: str, state: str, **kwargs) -> None:
+        self.report(
+            MaintenanceProgress(
+                instance=instance,
+                state=state,
+                completed=self.completed,
+                total=self.total,
+                **kwargs,
+            )
+        )
+
+    def started(self, instance: str) -> None:
+        """Report the migration of instance started."""
+        with self._lock:
+            now = self.clock()
+            self._progress[instance] = (None, now, now)
+            self._event(instance, "migrating")
+
+    def update(self, instance: str, percent: int | None) -> None:
+        """Report the progress of the migration of instance, if it changed."""
+        with self._lock:
+            now = self.clock()
+            last, since, reported = self._progress.get(instance, (None, now, now))
+            if percent != last:
+                self._progress[instance] = (percent, now, now)
+                self._event(instance, "migrating", percent=percent)
+                return
+            if now - reported < self.stall_timeout:
+                return
+            self._progress[instance] = (last, since, now)
+            minutes = int(now - since) // 60
+            self._event(
+                instance,
+                "stalled",
+                percent=percent,
+                message=f"no progress for {minutes} minutes",
+            )
+
+    def finished(self, instance: str, succeeded: bool, message: str = "") -> None:
+        """Report the migration of instance completed or failed."""
+        with self._lock:
+            self._progress.pop(instance, None)
+            self.completed += 1
+            self._event(
+                instance, "succeeded" if succeeded else "failed", message=message
+            )
+
+
+class MigrateInstancesStep(BaseStep):
+    """Migrate instances off a node through Nova.
+
+    At most max_parallel migrations run at once, the others are queued. A
+    failed migration frees its slot for the queued ones rather than aborting
+    them. Each migration gives the instance, its migration_type and its
+    destination, None leaving it to the Nova scheduler. Once run, it also
+    gives its state and the host the instance migrated to. The progress of
+    the migrations is shown in the step status, and passed to progress.
+    """
+
+    name = "Migrate instances"
+    description = "Migrate instances"
+
+    def __init__(
+        self,
+        deployment: Deployment,
+        jhelper: JujuHelper,
+        node: str,
+        migrations: list[dict[str, Any]],
+        max_parallel: int,
+        progress: Callable[[MaintenanceProgress], None] | None = None,
+    ):
+        super().__init__(self.name, self.description)
+        self.deployment = deployment
+        self.jhelper = jhelper
+        self.node = node
+        self.migrations = migrations
+        self.max_parallel = max_parallel
+        self.progress = progress
+
+    def _migrate(
+        self,
+        conn: "openstack.connection.Connection",
+        migration: dict[str, Any],
+        tracker: MigrationProgressTracker,
+    ) -> str:
+        instance = migration["instance"]
+        tracker.started(instance)
+        return migrate_instance(
+            conn,
+            instance,
+            self.node,
+            migration_type=migration["migration_type"],
+            destination=migration["destination"],
+            progress=lambda percent: tracker.update(instance, percent),
+        )
+
+    def _report(self, status: Status | None, event: MaintenanceProgress) -> None:
+        self.update_status(status, progress_message(event))
+        if self.progress:
+            self.progress(event)
+
+    def _run_migrations(
+        self,
+        conn: "openstack.connection.Connection",
+        migrations: list[dict[str, Any]],
+        status: Status | None,
+    ) -> bool:
+        """Run the migrations, return whether any failed."""
+        for migration in migrations:
+            migration["state"] = "PENDING"
+            migration["host"] = None
+
+        failed = False
+        tracker = MigrationProgressTracker(
+            len(migrations), lambda event: self._report(status, event)
+        )
+        with ThreadPoolExecutor(max_workers=max(self.max_parallel, 1)) as executor:
+            futures = {
+                executor.submit(self._migrate, conn, migration, tracker): migration
+                for migration in migrations
+            }
+            for future in as_completed(futures):
+                migration = futures[future]
+                try:
+                    migration["host"] = future.result()
+                    migration["state"] = "SUCCEEDED"
+                    tracker.finished(migration["instance"], True)
+                except (
+                    InstanceMigrationFailedException,
+                    openstack.exceptions.SDKException,
+                ) as e:
+                    LOG.warning(e)
+                    migration["state"] = "FAILED"
+                    tracker.finished(migration["instance"], False, str(e))
+                    failed = True
+
+        return failed
+
+    def run(self, status: Status | None) -> Result:
+        """Run the migrations, at most max_parallel at once."""
+        try:
+            conn = get_admin_connection(self.jhelper, self.deployment)
+        except openstack.exceptions.SDKException as e:
+            LOG.warning(f"Failed to connect to OpenStack: {e}")
+            for migration in self.migrations:
+                migration["state"] = "CANCELLED"
+            return Result(ResultType.FAILED, self.migrations)
+
+        failed = self._run_migrations(conn, self.migrations, status)
+        return Result(
+            ResultType.COMPLETED if not failed else ResultType.FAILED,
+            self.migrations,
+        )
+
+
+class RunWatcherActionsStep(MigrateInstancesStep):
+    """Run the actions planned by a Watcher audit through Nova.
+
+    Watcher starts all the migrations of an action plan at once, which can
+    saturate the network of large hypervisors. This step runs at most
+    max_parallel migrations at once, queuing the others. A failed migration
+    frees its slot for the queued ones rather than aborting them.
+    """
+
+    name = "Run Watcher Audit's actions"
+    description = "Run Watcher Audit's actions"
+
+    def __init__(
+        self,
+        deployment: Deployment,
+        jhelper: JujuHelper,
+        node: str,
+        actions: list["watcher.Action"],
+        max_parallel: int,
+        progress: Callable[[MaintenanceProgress], None] | None = None,
+    ):
+        super().__init__(deployment, jhelper, node, [], max_parallel, progress)
+        self.actions = actions
+
+    @staticmethod
+    def can_run(actions: list["watcher.Action"]) -> bool:
+        """Whether all the actions are of a type this step knows how to run."""
+        return all(
+            action.action_type in RUNNABLE_WATCHER_ACTION_TYPES for action in actions
+        )
+
+    def _change_service_state(
+        self, conn: "openstack.connection.Connection", action: "watcher.Action"
+    ) -> None:
+        parameters = action.input_parameters
+        for service in conn.compute.services(
+            host=parameters["resource_name"], binary="nova-compute"
+        ):
+            if parameters["state"] == "disabled":
+                conn.compute.disable_service(
+                    service, disabled_reason=parameters.get("disabled_reason")
+                )
+            else:
+                conn.compute.enable_service(service)
+
+    def run(self, status: Status | None) -> Result:
+        """Run the actions, at most max_parallel migrations at once."""
+        services = [
+            action
+            for action in self.actions
+            if action.action_type == "change_nova_service_state"
+        ]
+        for action in self.actions:
+            action.state = "PENDING"
+
+        try:
+            conn = get_admin_connection(self.jhelper, self.deployment)
+            # Disable the hypervisor first, so that no instance lands on it
+            for action in services:
+                self._change_service_state(conn, action)
+                action.state = "SUCCEEDED"
+        except openstack.exceptions.SDKException as e:
+            LOG.warning(f"Failed to change nova-compute state of {self.node}: {e}")
+            for action in self.actions:
+                action.state = "FAILED" if action in services else "CANCELLED"
+            return Result(ResultType.FAILED, self.actions)
+
+        migrations = [
+            (
+                action,
+                {
+                    "instance": action.input_parameters["resource_id"],
+                    "migration_type": action.input_parameters["migration_type"],
+                    "destination": action.input_parameters.get("destination_node"),
+                },
+            )
+            for action in self.actions
+            if action.action_type == "migrate"
+        ]
+        failed = self._run_migrations(
+            conn, [migration for _, migration in migrations], status
+        )
+        for action, migration in migrations:
+            action.state = migration["state"]
+
+        return Result(
+            ResultType.COMPLETED if not failed else ResultType.FAILED,
+            self.actions,
+        )
+
+
 class DrainControlRoleNodeStep(DrainK8SUnitStep):
     def __init__(
         self,
@@ -34,6 +34,7 @@ from sunbeam.core.terraform import (
     TerraformHelper,
     TerraformStateLockedException,
 )
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 from sunbeam.feature_gates import is_feature_gate_enabled
 from sunbeam.steps.configure import get_external_network_configs
 
@@ -226,16 +227,18 @@ class ReapplyMicroOVNTerraformPlanStep(BaseStep):
             )
         except TerraformException as e:
             return Result(ResultType.FAILED, str(e))
+        timeout = step_timeout(self, MICROOVN_UNIT_TIMEOUT)
         try:
             self.jhelper.wait_application_ready(
                 APPLICATION,
                 self.model,
                 accepted_status=statuses,
-                timeout=MICROOVN_UNIT_TIMEOUT,
+                timeout=timeout,
             )
         except TimeoutError as e:
-            LOG.warning(str(e))
-            return Result(ResultType.FAILED, str(e))
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
 
         return Result(ResultType.COMPLETED)
 
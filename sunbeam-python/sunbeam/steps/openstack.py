@@ -66,6 +66,7 @@ from sunbeam.core.terraform import (
     TerraformHelper,
     TerraformStateLockedException,
 )
+from sunbeam.core.timeouts import step_timeout, step_timeout_message
 
 LOG = logging.getLogger(__name__)
 OPENSTACK_DEPLOY_TIMEOUT = 3600  # 60 minutes
@@ -818,15 +819,20 @@ class DeployControlPlaneStep(BaseStep, JujuStepHelper):
         LOG.debug(f"Applications monitored for readiness: {apps}")
         status_queue: queue.Queue[str] = queue.Queue()
         task = update_status_background(self, apps, status_queue, status)
+        timeout = step_timeout(self, OPENSTACK_DEPLOY_TIMEOUT)
         try:
             self.jhelper.wait_until_active(
                 self.model,
                 apps,
-                timeout=OPENSTACK_DEPLOY_TIMEOUT,
+                timeout=timeout,
                 queue=status_queue,
                 overlay=build_overlay_dict(apps),
             )
-        except (JujuWaitException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except JujuWaitException as e:
             LOG.warning(str(e))
             return Result(ResultType.FAILED, str(e))
         finally:
@@ -1007,17 +1013,22 @@ class ReapplyOpenStackTerraformPlanStep(BaseStep, JujuStepHelper):
         LOG.debug(f"Pre-wait workload status for {self.model}: {pre_status}")
         status_queue: queue.Queue[str] = queue.Queue()
         task = update_status_background(self, apps, status_queue, status)
+        timeout = step_timeout(self, OPENSTACK_DEPLOY_TIMEOUT)
         try:
             self.jhelper.wait_until_active(
                 self.model,
                 apps,
-                timeout=OPENSTACK_DEPLOY_TIMEOUT,
+                timeout=timeout,
                 queue=status_queue,
                 overlay=build_pre_status_overlay(
                     apps, pre_status, build_overlay_dict(apps)
                 ),
             )
-        except (JujuWaitException, TimeoutError) as e:
+        except TimeoutError as e:
+            message = step_timeout_message(self, timeout, e)
+            LOG.warning(message)
+            return Result(ResultType.FAILED, message)
+        except JujuWaitException as e:
             LOG.debug(str(e))
             return Result(ResultType.FAILED, str(e))
         finally:
@@ -8,6 +8,8 @@ from rich.status import Status
 
 from sunbeam.core.common import BaseStep, Result, ResultType
 from sunbeam.core.deployment import Deployment
+from sunbeam.core.juju import ControllerNotFoundException, JujuStepHelper
+from sunbeam.core.terraform import TerraformException, TerraformHelper
 
 LOG = logging.getLogger(__name__)
 
@@ -38,3 +40,51 @@ class CleanTerraformPlansStep(BaseStep):
             LOG.error("Error cleaning terraform directories: %s", e)
             return Result(ResultType.FAILED)
         return Result(ResultType.COMPLETED)
+
+
+class DestroyTerraformPlanStep(BaseStep, JujuStepHelper):
+    """Destroy the resources of a terraform plan."""
+
+    def __init__(self, deployment: Deployment, tfhelper: TerraformHelper):
+        super().__init__(
+            f"Destroy {tfhelper.plan}", f"Destroying resources of {tfhelper.plan}"
+        )
+        self.deployment = deployment
+        self.tfhelper = tfhelper
+
+    def is_skip(self, status: Status | None = None) -> Result:
+        """Determines if the step should be skipped or not.
+
+        :return: ResultType.SKIPPED if the Step should be skipped,
+                ResultType.COMPLETED or ResultType.FAILED otherwise
+        """
+        try:
+            state = self.tfhelper.pull_state()
+        except TerraformException:
+            LOG.debug("Failed to pull state", exc_info=True)
+            return Result(ResultType.SKIPPED)
+
+        if not state.get("resources"):
+            return Result(ResultType.SKIPPED)
+
+        # The resources are gone with the Juju controller they were deployed on
+        controller = self.deployment.juju_controller
+        if controller is None:
+            return Result(ResultType.SKIPPED)
+        try:
+            self.get_controller(controller.name)
+        except ControllerNotFoundException:
+            LOG.debug(f"Controller {controller.name} not found")
+            return Result(ResultType.SKIPPED)
+
+        return Result(ResultType.COMPLETED)
+
+    def run(self, status: Status | None = None) -> Result:
+        """Destroy the resources of the plan."""
+        try:
+            self.tfhelper.destroy()
+        except TerraformException as e:
+            LOG.exception(f"Error destroying {self.tfhelper.plan}")
+            return Result(ResultType.FAILED, str(e))
+
+        return Result(ResultType.COMPLETED)
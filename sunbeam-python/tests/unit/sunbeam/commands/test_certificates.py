@@ -0,0 +1,123 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import MagicMock
+
+import pytest
+from click.testing import CliRunner
+from cryptography import x509
+
+from sunbeam.clusterd.models import ClusterCertificateRotation
+from sunbeam.clusterd.service import InvalidCertificateException
+from sunbeam.commands.certificates import generate_cluster_certificate, rotate
+
+
+@pytest.fixture
+def deployment():
+    return MagicMock()
+
+
+def _rotation(failed="", error="", **members):
+    return ClusterCertificateRotation(
+        members=[
+            {"name": name, "certificate": certificate}
+            for name, certificate in members.items()
+        ],
+        failed=failed,
+        error=error,
+    )
+
+
+class TestRotate:
+    def test_generated_certificate(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.rotate_cluster_certificate.return_value = _rotation(
+            node1="new", node2="new"
+        )
+
+        result = CliRunner().invoke(rotate, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert json.loads(result.output)["members"] == [
+            {"name": "node1", "certificate": "new"},
+            {"name": "node2", "certificate": "new"},
+        ]
+        certificate, private_key, ca = (
+            client.cluster.rotate_cluster_certificate.call_args.args
+        )
+        cert = x509.load_pem_x509_certificate(certificate.encode())
+        assert cert.issuer == cert.subject
+        assert "PRIVATE KEY" in private_key
+        assert ca is None
+
+    def test_given_certificate(self, tmp_path, deployment):
+        certificate, private_key = generate_cluster_certificate()
+        (tmp_path / "cert.pem").write_text(certificate)
+        (tmp_path / "key.pem").write_text(private_key)
+        (tmp_path / "ca.pem").write_text("ca")
+        client = deployment.get_client.return_value
+        client.cluster.rotate_cluster_certificate.return_value = _rotation(
+            node1="new"
+        )
+
+        result = CliRunner().invoke(
+            rotate,
+            [
+                "--certificate",
+                str(tmp_path / "cert.pem"),
+                "--private-key",
+                str(tmp_path / "key.pem"),
+                "--ca",
+                str(tmp_path / "ca.pem"),
+            ],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.rotate_cluster_certificate.assert_called_once_with(
+            certificate, private_key, "ca"
+        )
+
+    def test_certificate_without_key(self, tmp_path, deployment):
+        (tmp_path / "cert.pem").write_text("cert")
+
+        result = CliRunner().invoke(
+            rotate, ["--certificate", str(tmp_path / "cert.pem")], obj=deployment
+        )
+
+        assert result.exit_code == 2
+        client = deployment.get_client.return_value
+        client.cluster.rotate_cluster_certificate.assert_not_called()
+
+    def test_rejected_certificate(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.rotate_cluster_certificate.side_effect = (
+            InvalidCertificateException("Certificate chain does not verify")
+        )
+
+        result = CliRunner().invoke(rotate, [], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Certificate chain does not verify" in result.output
+
+    def test_partial_failure(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.rotate_cluster_certificate.return_value = _rotation(
+            failed="node2",
+            error='Member "node2" failed to reload the certificate',
+            node1="new",
+            node2="unknown",
+            node3="old",
+        )
+
+        result = CliRunner().invoke(rotate, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 1
+        output = result.output[: result.output.index("Error:")]
+        assert json.loads(output)["members"] == [
+            {"name": "node1", "certificate": "new"},
+            {"name": "node2", "certificate": "unknown"},
+            {"name": "node3", "certificate": "old"},
+        ]
+        assert "Rotation halted on member node2" in result.output
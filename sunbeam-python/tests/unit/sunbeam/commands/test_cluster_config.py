@@ -0,0 +1,422 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import MagicMock
+
+import pytest
+from click.testing import CliRunner
+
+import yaml
+
+from sunbeam.clusterd.models import ConfigImportResult, ConfigRevision
+from sunbeam.clusterd.service import (
+    ConfigItemNotFoundException,
+    ConfigRevisionNotFoundException,
+    ConfigTransactionFailedException,
+    InvalidConfigException,
+    InvalidNodeRoleException,
+)
+from sunbeam.commands.cluster_config import (
+    export_config,
+    get_config,
+    history,
+    import_config,
+    rollback,
+    set_config,
+    unset_config,
+)
+
+
+@pytest.fixture
+def deployment():
+    return MagicMock()
+
+
+def _revision(revision: int, value: str, changed_by: str = "admin"):
+    return ConfigRevision(
+        revision=revision,
+        value=value,
+        changed_at=f"2026-10-14T12:0{revision}:00Z",
+        changed_by=changed_by,
+    )
+
+
+class TestConfigHistory:
+    def test_history(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_config_history.return_value = [
+            _revision(1, '"one"'),
+            _revision(2, '"two"'),
+            _revision(3, '"three"', changed_by="operator"),
+        ]
+
+        result = CliRunner().invoke(
+            history,
+            ["maintenance.max-parallel-migrations", "--format", "json"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.get_config_history.assert_called_once_with(
+            "maintenance.max-parallel-migrations"
+        )
+        revisions = json.loads(result.output)
+        assert [r["revision"] for r in revisions] == [1, 2, 3]
+        assert revisions[2]["changed_by"] == "operator"
+
+    def test_history_truncates_values(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_config_history.return_value = [
+            _revision(1, json.dumps(["role"] * 40))
+        ]
+
+        result = CliRunner().invoke(history, ["roles.custom"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert "..." in result.output
+
+    def test_history_key_not_found(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_config_history.side_effect = ConfigItemNotFoundException(
+            "ConfigItem not found"
+        )
+
+        result = CliRunner().invoke(history, ["missing"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Config key missing not found" in result.output
+
+
+class TestConfigRollback:
+    def test_rollback_previous(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.rollback_config.return_value = _revision(4, '"two"')
+
+        result = CliRunner().invoke(rollback, ["key"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        client.cluster.rollback_config.assert_called_once_with("key", None)
+        assert "revision 4" in result.output
+
+    def test_rollback_revision(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.rollback_config.return_value = _revision(4, '"one"')
+
+        result = CliRunner().invoke(
+            rollback, ["key", "--revision", "1"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.rollback_config.assert_called_once_with("key", 1)
+
+    def test_rollback_not_recorded(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.rollback_config.side_effect = ConfigRevisionNotFoundException(
+            'No previous revision of config key "key" to roll back to'
+        )
+
+        result = CliRunner().invoke(rollback, ["key"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "No previous revision" in result.output
+
+
+class TestConfigSet:
+    def test_set(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            set_config, ["a=1", "b=x=y"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert client.cluster.update_config.call_args_list == [
+            (("a", "1", False, None),),
+            (("b", "x=y", False, None),),
+        ]
+        client.cluster.apply_config_transaction.assert_not_called()
+
+    def test_set_rejected_midway(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.update_config.side_effect = [
+            None,
+            InvalidConfigException('Invalid value for config key "b": bad'),
+        ]
+
+        result = CliRunner().invoke(
+            set_config, ["a=1", "b=2", "c=3"], obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert "keys already set: a" in result.output
+        assert client.cluster.update_config.call_count == 2
+
+    def test_set_atomic(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            set_config,
+            ["a=1", "b=2", "--atomic", "--expect", "a=0", "--expect", "c"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.apply_config_transaction.assert_called_once_with(
+            {"a": "1", "b": "2"}, {"a": "0", "c": None}, False, None
+        )
+        client.cluster.update_config.assert_not_called()
+
+    def test_set_atomic_rejected(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.apply_config_transaction.side_effect = (
+            ConfigTransactionFailedException(
+                "Config transaction failed, 2 keys rejected",
+                {
+                    "b": ['Invalid value for config key "b": bad'],
+                    "a": ['Config key "a" does not hold its expected value'],
+                },
+            )
+        )
+
+        result = CliRunner().invoke(
+            set_config, ["a=1", "b=2", "--atomic"], obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert result.output.index('config key "b"') > result.output.index(
+            'Config key "a"'
+        )
+        assert "none of the 2 keys were set" in result.output
+
+    def test_set_role(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            set_config, ["a=1", "--role", "storage"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.update_config.assert_called_once_with(
+            "a", "1", False, "storage"
+        )
+
+    def test_set_role_atomic(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            set_config,
+            ["a=1", "--atomic", "--expect", "a", "--role", "storage"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.apply_config_transaction.assert_called_once_with(
+            {"a": "1"}, {"a": None}, False, "storage"
+        )
+
+    def test_set_unknown_role(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.update_config.side_effect = InvalidNodeRoleException(
+            "Unknown node role: db"
+        )
+
+        result = CliRunner().invoke(
+            set_config, ["a=1", "--role", "db"], obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert "Unknown node role: db" in result.output
+
+    def test_expect_requires_atomic(self, deployment):
+        result = CliRunner().invoke(
+            set_config, ["a=1", "--expect", "a=0"], obj=deployment
+        )
+
+        assert result.exit_code == 2
+        deployment.get_client.assert_not_called()
+
+    def test_invalid_assignment(self, deployment):
+        result = CliRunner().invoke(set_config, ["a"], obj=deployment)
+
+        assert result.exit_code == 2
+        assert "KEY=VALUE" in result.output
+
+
+class TestConfigGet:
+    def test_get(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_config.return_value = '"10.0.0.1"'
+
+        result = CliRunner().invoke(get_config, ["a"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert '"10.0.0.1"' in result.output
+        assert "Scope" not in result.output
+        client.cluster.get_role_config.assert_not_called()
+
+    def test_get_role_override(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_role_config.return_value = ('"10.0.0.2"', "role")
+
+        result = CliRunner().invoke(
+            get_config, ["a", "--role", "storage"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert '"10.0.0.2"' in result.output
+        assert "Scope: role" in result.output
+        client.cluster.get_role_config.assert_called_once_with("a", "storage")
+
+    def test_get_role_fallback(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_role_config.return_value = ('"10.0.0.1"', "global")
+
+        result = CliRunner().invoke(
+            get_config, ["a", "--role", "storage"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert "Scope: global" in result.output
+
+    def test_get_not_found(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_config.side_effect = ConfigItemNotFoundException(
+            "ConfigItem not found"
+        )
+
+        result = CliRunner().invoke(get_config, ["a"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Config key a not found" in result.output
+
+
+class TestConfigUnset:
+    def test_unset(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(unset_config, ["a"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        client.cluster.delete_config.assert_called_once_with("a", None)
+
+    def test_unset_role(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            unset_config, ["a", "--role", "storage"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert "override for role storage removed" in result.output
+        client.cluster.delete_config.assert_called_once_with("a", "storage")
+
+
+DOCUMENT = {
+    "redacted": False,
+    "config": {
+        "deployment.type": '"local"',
+        "K8SKubeConfig": '"apiVersion: v1"',
+        "maintenance.max-parallel-migrations@role:compute": '"2"',
+        "BootstrapAnswers": '{"bootstrap": {"management_cidr": "10.0.0.0/24"}}',
+    },
+}
+
+
+class TestConfigExportImport:
+    def test_round_trip(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.export_config.return_value = DOCUMENT
+        client.cluster.import_config.return_value = ConfigImportResult()
+        runner = CliRunner()
+
+        exported = runner.invoke(export_config, [], obj=deployment)
+
+        assert exported.exit_code == 0, exported.output
+        client.cluster.export_config.assert_called_once_with(redact_secrets=False)
+        assert yaml.safe_load(exported.output) == DOCUMENT
+
+        result = runner.invoke(
+            import_config, ["-"], input=exported.output, obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.import_config.assert_called_once_with(
+            DOCUMENT["config"], False
+        )
+        assert "Config unchanged" in result.output
+
+    def test_import_reports_changes(self, deployment, tmp_path):
+        client = deployment.get_client.return_value
+        client.cluster.import_config.return_value = ConfigImportResult(
+            created=["external_gateway"],
+            updated=["deployment.type"],
+            deleted=["old-key"],
+        )
+        path = tmp_path / "config.yaml"
+        path.write_text(yaml.safe_dump(DOCUMENT))
+
+        result = CliRunner().invoke(
+            import_config, [str(path), "--prune"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.import_config.assert_called_once_with(DOCUMENT["config"], True)
+        assert "Created keys: external_gateway" in result.output
+        assert "Updated keys: deployment.type" in result.output
+        assert "Deleted keys: old-key" in result.output
+        assert "Config unchanged" not in result.output
+
+    def test_redacted_export_skipped_on_import(self, deployment):
+        client = deployment.get_client.return_value
+        redacted = {
+            "redacted": True,
+            "config": {**DOCUMENT["config"], "K8SKubeConfig": "<redacted>"},
+        }
+        client.cluster.export_config.return_value = redacted
+        client.cluster.import_config.return_value = ConfigImportResult(
+            skipped=["K8SKubeConfig"]
+        )
+        runner = CliRunner()
+
+        exported = runner.invoke(export_config, ["--redact-secrets"], obj=deployment)
+
+        assert exported.exit_code == 0, exported.output
+        client.cluster.export_config.assert_called_once_with(redact_secrets=True)
+        assert "apiVersion" not in exported.output
+
+        result = runner.invoke(
+            import_config, ["-"], input=exported.output, obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert "Config unchanged" in result.output
+        assert "Skipped redacted keys: K8SKubeConfig" in result.output
+
+    def test_import_rejected(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.import_config.side_effect = ConfigTransactionFailedException(
+            "Config transaction failed, 1 keys rejected",
+            {"deployment.type": ['Invalid value for config key "deployment.type"']},
+        )
+
+        result = CliRunner().invoke(
+            import_config, ["-"], input=yaml.safe_dump(DOCUMENT), obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert 'Invalid value for config key "deployment.type"' in result.output
+        assert "none of the keys were changed" in result.output
+
+    def test_import_invalid_document(self, deployment):
+        client = deployment.get_client.return_value
+        runner = CliRunner()
+
+        for document in ("- a\n", "config:\n  a: [1]\n", "config: [\n"):
+            result = runner.invoke(
+                import_config, ["-"], input=document, obj=deployment
+            )
+
+            assert result.exit_code == 1
+            assert "Invalid" in result.output
+        client.cluster.import_config.assert_not_called()
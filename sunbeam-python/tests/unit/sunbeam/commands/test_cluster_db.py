@@ -0,0 +1,83 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import MagicMock
+
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.models import (
+    DatabaseCompaction,
+    DatabaseReport,
+    DatabaseTable,
+)
+from sunbeam.commands.cluster_db import compact, verify
+
+
+@pytest.fixture
+def deployment():
+    return MagicMock()
+
+
+def _report(problems: list[str] | None = None) -> DatabaseReport:
+    return DatabaseReport(
+        ok=not problems,
+        problems=problems or [],
+        tables=[
+            DatabaseTable(name="config", rows=12),
+            DatabaseTable(name="nodes", rows=3),
+        ],
+        page_size=4096,
+        pages=40,
+        free_pages=2,
+    )
+
+
+class TestVerify:
+    def test_verify(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.verify_database.return_value = _report()
+
+        result = CliRunner().invoke(verify, [], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert "nodes" in result.output
+        assert "40 pages of 4096 bytes, 2 free" in result.output
+        assert "integrity check passed" in result.output
+
+    def test_verify_corrupted(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.verify_database.return_value = _report(
+            ["Page 5 is never used"]
+        )
+
+        result = CliRunner().invoke(verify, [], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Page 5 is never used" in result.output
+        assert "1 problems found" in result.output
+
+    def test_verify_json(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.verify_database.return_value = _report()
+
+        result = CliRunner().invoke(verify, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        report = json.loads(result.output)
+        assert report["ok"] is True
+        assert report["tables"][1] == {"name": "nodes", "rows": 3}
+
+
+class TestCompact:
+    def test_compact(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.compact_database.return_value = DatabaseCompaction(
+            purged_revisions=4, purged_tombstones=1, free_pages=3
+        )
+
+        result = CliRunner().invoke(compact, [], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert "Purged 4 config revisions and 1 removed nodes" in result.output
@@ -0,0 +1,94 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import MagicMock, patch
+
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.service import NodeNotExistInClusterException
+from sunbeam.commands.health import health
+from sunbeam.core.checks import Check
+
+
+class _Check(Check):
+    def __init__(self, name: str, passed: bool, message: str = "Check successful"):
+        super().__init__(name, f"Checking {name}")
+        self.passed = passed
+        self.message = message
+
+    def run(self) -> bool:
+        return self.passed
+
+
+@pytest.fixture
+def checks():
+    with (
+        patch("sunbeam.commands.health.JujuHelper"),
+        patch("sunbeam.commands.health.cluster_health_checks") as mock_checks,
+    ):
+        yield mock_checks
+
+
+class TestHealth:
+    def test_healthy(self, checks):
+        checks.return_value = [_Check("quorum", True), _Check("agents", True)]
+
+        result = CliRunner().invoke(health, ["--format", "json"], obj=MagicMock())
+
+        assert result.exit_code == 0, result.output
+        report = json.loads(result.output)
+        assert report["verdict"] == "healthy"
+        assert [check["name"] for check in report["checks"]] == ["quorum", "agents"]
+
+    def test_degraded(self, checks):
+        checks.return_value = [
+            _Check("quorum", True),
+            _Check("units", False, "openstack-hypervisor/1 is blocked"),
+        ]
+
+        result = CliRunner().invoke(health, ["--format", "json"], obj=MagicMock())
+
+        assert result.exit_code == 1
+        report = json.loads(result.output.split("Error:")[0])
+        assert report["verdict"] == "degraded"
+        assert report["checks"][1] == {
+            "name": "units",
+            "passed": False,
+            "message": "openstack-hypervisor/1 is blocked",
+        }
+        assert "1 of 2 checks failed: units" in result.output
+
+    def test_check_raising_is_degraded(self, checks):
+        failing = _Check("controller", True)
+        failing.run = MagicMock(side_effect=Exception("Controller not reachable"))
+        checks.return_value = [failing]
+
+        result = CliRunner().invoke(health, [], obj=MagicMock())
+
+        assert result.exit_code == 1
+        assert "degraded" in result.output
+        assert "Controller not reachable" in result.output
+
+    def test_node(self, checks):
+        checks.return_value = [_Check("agent", True)]
+        deployment = MagicMock()
+
+        result = CliRunner().invoke(
+            health, ["--node", "node-1", "--format", "json"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert checks.call_args.args[2] == "node-1"
+        assert json.loads(result.output)["node"] == "node-1"
+
+    def test_unknown_node(self, checks):
+        checks.side_effect = NodeNotExistInClusterException(
+            "Node does not exist in the sunbeam cluster"
+        )
+
+        result = CliRunner().invoke(health, ["--node", "node-9"], obj=MagicMock())
+
+        assert result.exit_code == 1
+        assert "node-9" in result.output
@@ -0,0 +1,65 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+from unittest.mock import MagicMock
+
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.models import MaintenanceStatus, MaintenanceStatusList
+from sunbeam.commands.host_inventory import inventory
+
+
+@pytest.fixture
+def deployment():
+    deployment = MagicMock()
+    client = deployment.get_client.return_value
+    client.cluster.list_nodes.return_value = [
+        {"name": "node-1", "role": ["compute"], "labels": {"rack": "a"}},
+        {"name": "node-2", "role": ["compute"], "labels": {}},
+    ]
+    client.cluster.get_status.return_value = {
+        "node-1": {"status": "ONLINE", "address": "10.0.0.1:7000"},
+        "node-2": {"status": "ONLINE", "address": "10.0.0.2:7000"},
+    }
+    client.cluster.list_maintenance_status.return_value = MaintenanceStatusList(
+        root=[
+            MaintenanceStatus(node="node-1", status="disabled"),
+            MaintenanceStatus(node="node-2", status="degraded"),
+        ]
+    )
+    return deployment
+
+
+class TestInventory:
+    def test_ssh_config(self, deployment):
+        result = CliRunner().invoke(
+            inventory, ["--format", "ssh-config"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert result.output == (
+            "Host node-1\n"
+            "    HostName 10.0.0.1\n"
+            "    # roles: compute\n"
+            "    # labels: rack=a\n"
+            "\n"
+            "Host node-2\n"
+            "    HostName 10.0.0.2\n"
+            "    # roles: compute\n"
+            "    # maintenance\n"
+        )
+
+    def test_ansible_ini(self, deployment):
+        result = CliRunner().invoke(
+            inventory, ["--format", "ansible-ini"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert "[maintenance]\nnode-2\n" in result.output
+        assert "[label_rack_a]\nnode-1\n" in result.output
+
+    def test_unknown_format(self, deployment):
+        result = CliRunner().invoke(inventory, ["--format", "csv"], obj=deployment)
+
+        assert result.exit_code == 2
@@ -0,0 +1,115 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+from unittest.mock import ANY, MagicMock, patch
+
+import click
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.models import MaintenanceStatus, MaintenanceStatusList
+from sunbeam.clusterd.service import NodeNotExistInClusterException
+from sunbeam.commands.node_cordon import cordon, set_node_cordon, uncordon
+
+
+def _deployment(role: list[str], maintenance: str = "disabled") -> MagicMock:
+    deployment = MagicMock()
+    deployment.openstack_machines_model = "openstack-machines"
+    client = deployment.get_client.return_value
+    client.cluster.get_node_info.return_value = {"name": "node-1", "role": role}
+    client.cluster.list_maintenance_status.return_value = MaintenanceStatusList(
+        root=[MaintenanceStatus(node="node-1", status=maintenance)]
+    )
+    return deployment
+
+
+@pytest.fixture
+def steps():
+    with (
+        patch("sunbeam.commands.node_cordon.run_plan") as run_plan,
+        patch("sunbeam.commands.node_cordon.DisableHypervisorStep") as disable,
+        patch("sunbeam.commands.node_cordon.EnableHypervisorStep") as enable,
+    ):
+        yield run_plan, disable, enable
+
+
+class TestSetNodeCordon:
+    def test_cordon_disables_hypervisor(self, steps):
+        run_plan, disable, enable = steps
+        deployment = _deployment(["compute"])
+        client = deployment.get_client.return_value
+
+        set_node_cordon(deployment, MagicMock(), "node-1", True)
+
+        disable.assert_called_once()
+        enable.assert_not_called()
+        run_plan.assert_called_once_with([disable.return_value], ANY, False)
+        client.cluster.set_node_cordon.assert_called_once_with("node-1", True)
+
+    def test_uncordon_enables_hypervisor(self, steps):
+        run_plan, disable, enable = steps
+        deployment = _deployment(["compute"])
+        client = deployment.get_client.return_value
+
+        set_node_cordon(deployment, MagicMock(), "node-1", False)
+
+        enable.assert_called_once()
+        disable.assert_not_called()
+        client.cluster.set_node_cordon.assert_called_once_with("node-1", False)
+
+    def test_uncordon_in_maintenance_keeps_hypervisor_disabled(self, steps):
+        run_plan, disable, enable = steps
+        deployment = _deployment(["compute"], maintenance="enabled")
+        client = deployment.get_client.return_value
+
+        set_node_cordon(deployment, MagicMock(), "node-1", False)
+
+        run_plan.assert_not_called()
+        client.cluster.set_node_cordon.assert_called_once_with("node-1", False)
+
+    def test_not_compute(self, steps):
+        run_plan, _, _ = steps
+        deployment = _deployment(["control"])
+        client = deployment.get_client.return_value
+
+        set_node_cordon(deployment, MagicMock(), "node-1", True)
+
+        run_plan.assert_not_called()
+        client.cluster.set_node_cordon.assert_called_once_with("node-1", True)
+
+    def test_hypervisor_failure_not_recorded(self, steps):
+        run_plan, _, _ = steps
+        run_plan.side_effect = click.ClickException("Failed to disable")
+        deployment = _deployment(["compute"])
+        client = deployment.get_client.return_value
+
+        with pytest.raises(click.ClickException):
+            set_node_cordon(deployment, MagicMock(), "node-1", True)
+
+        client.cluster.set_node_cordon.assert_not_called()
+
+
+class TestCordonCommands:
+    def test_cordon(self):
+        with (
+            patch("sunbeam.commands.node_cordon.JujuHelper"),
+            patch("sunbeam.commands.node_cordon.set_node_cordon") as set_cordon,
+        ):
+            result = CliRunner().invoke(cordon, ["node-1"], obj=MagicMock())
+
+        assert result.exit_code == 0, result.output
+        assert set_cordon.call_args.args[2:4] == ("node-1", True)
+        assert "Node 'node-1' cordoned" in result.output
+
+    def test_uncordon_not_found(self):
+        with (
+            patch("sunbeam.commands.node_cordon.JujuHelper"),
+            patch(
+                "sunbeam.commands.node_cordon.set_node_cordon",
+                side_effect=NodeNotExistInClusterException("Node not found"),
+            ),
+        ):
+            result = CliRunner().invoke(uncordon, ["node-1"], obj=MagicMock())
+
+        assert result.exit_code == 1
+        assert "Node 'node-1' not found" in result.output
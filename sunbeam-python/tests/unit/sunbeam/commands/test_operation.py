@@ -0,0 +1,393 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+import signal
+import sys
+from pathlib import Path
+from unittest.mock import MagicMock, patch
+
+import click
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.models import Operation
+from sunbeam.clusterd.service import (
+    InvalidOperationException,
+    OperationConflictException,
+    OperationNotFoundException,
+)
+from sunbeam.commands.operation import (
+    agent,
+    cancel,
+    click_option_detach,
+    click_option_detach_bootstrap,
+    detached_command,
+    list_operations,
+    run,
+    run_operation,
+    show,
+    wait,
+)
+
+COMMAND = ["cluster", "maintenance", "enable", "--yes", "node1"]
+
+
+@pytest.fixture
+def deployment():
+    return MagicMock()
+
+
+def _operation(status: str = "pending", **kwargs) -> Operation:
+    fields = {
+        "id": 3,
+        "command": COMMAND,
+        "member": "node1",
+        "status": status,
+        "created_at": "2025-07-01T02:00:00Z",
+        "created_by": "admin",
+    }
+    return Operation(**{**fields, **kwargs})
+
+
+def _cli(ran: list) -> click.Group:
+    """Return a root group with a maintenance enable command which detaches."""
+
+    @click.group("sunbeam")
+    @click.option("--verbose", "-v", is_flag=True)
+    def root(verbose):
+        pass
+
+    @root.group("cluster")
+    def cluster():
+        pass
+
+    @cluster.group("maintenance")
+    def maintenance():
+        pass
+
+    @maintenance.command("enable")
+    @click.argument("node")
+    @click.option("--yes", is_flag=True)
+    @click_option_detach
+    def enable(node, yes):
+        ran.append(node)
+
+    @cluster.command("bootstrap")
+    @click.option("--accept-defaults", is_flag=True)
+    @click.option("--manifest", "manifest_path")
+    @click_option_detach_bootstrap
+    def bootstrap(accept_defaults, manifest_path):
+        ran.append("bootstrap")
+
+    return root
+
+
+class TestDetach:
+    def test_detached_command(self):
+        ctx = MagicMock(command_path="sunbeam cluster maintenance enable")
+
+        args = detached_command(
+            ctx,
+            ["sunbeam", "-v", "cluster", "maintenance", "enable", "--detach"]
+            + COMMAND[3:],
+        )
+
+        assert args == COMMAND
+
+    def test_detach(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.create_operation.return_value = _operation()
+        ran: list = []
+        argv = ["sunbeam", "-v"] + COMMAND[:3] + ["--detach"] + COMMAND[3:]
+
+        with (
+            patch.object(sys, "argv", argv),
+            patch("sunbeam.commands.operation.logs_dir", return_value=Path("/logs")),
+            patch("sunbeam.commands.operation.spawn_detached") as spawn,
+        ):
+            result = CliRunner().invoke(_cli(ran), argv[1:], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert ran == []
+        client.cluster.create_operation.assert_called_once_with(COMMAND)
+        spawn.assert_called_once_with(
+            ["operation", "run", "3"], Path("/logs/operation-3.log")
+        )
+        assert "Operation 3 started" in result.output
+        assert "sunbeam operation wait 3" in result.output
+
+    def test_not_detached(self, deployment):
+        client = deployment.get_client.return_value
+        ran: list = []
+
+        result = CliRunner().invoke(_cli(ran), COMMAND, obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert ran == ["node1"]
+        client.cluster.create_operation.assert_not_called()
+
+    def test_detach_requires_yes(self, deployment):
+        client = deployment.get_client.return_value
+        ran: list = []
+
+        result = CliRunner().invoke(
+            _cli(ran),
+            ["cluster", "maintenance", "enable", "--detach", "node1"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 2
+        assert "--detach requires --yes" in result.output
+        client.cluster.create_operation.assert_not_called()
+
+    def test_detach_invalid(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.create_operation.side_effect = InvalidOperationException(
+            'Invalid operation command "cluster maintenance enable"'
+        )
+        argv = ["sunbeam"] + COMMAND + ["--detach"]
+
+        with patch.object(sys, "argv", argv):
+            result = CliRunner().invoke(_cli([]), argv[1:], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Invalid operation command" in result.output
+
+
+    def test_detach_bootstrap(self, deployment):
+        ran: list = []
+        argv = ["sunbeam", "cluster", "bootstrap", "--accept-defaults", "--detach"]
+
+        with (
+            patch.object(sys, "argv", argv),
+            patch("sunbeam.commands.operation.logs_dir", return_value=Path("/logs")),
+            patch(
+                "sunbeam.commands.operation.spawn_detached", return_value=42
+            ) as spawn,
+        ):
+            result = CliRunner().invoke(_cli(ran), argv[1:], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert ran == []
+        args, log_path = spawn.call_args.args
+        assert args == ["cluster", "bootstrap", "--accept-defaults"]
+        assert log_path.name.startswith("bootstrap-")
+        assert "PID 42" in result.output
+        deployment.get_client.assert_not_called()
+
+    def test_detach_bootstrap_requires_defaults(self, deployment):
+        with patch("sunbeam.commands.operation.spawn_detached") as spawn:
+            result = CliRunner().invoke(
+                _cli([]), ["cluster", "bootstrap", "--detach"], obj=deployment
+            )
+
+        assert result.exit_code == 2
+        assert "--detach requires --accept-defaults or --manifest" in result.output
+        spawn.assert_not_called()
+
+
+class TestRunOperation:
+    """Run shell commands as the sunbeam commands of the operations."""
+
+    def _run(self, deployment, script: str, *updates: Operation) -> Operation:
+        client = deployment.get_client.return_value
+        client.cluster.update_operation.side_effect = [
+            _operation("running", command=["-c", script]),
+            *updates,
+        ]
+        with (
+            patch.object(sys, "argv", ["/bin/sh"]),
+            patch("sunbeam.commands.operation.PROGRESS_INTERVAL", 0),
+        ):
+            return run_operation(deployment, 3, "laptop:1")
+
+    def test_succeeded(self, deployment):
+        client = deployment.get_client.return_value
+        done = _operation("succeeded")
+
+        item = self._run(
+            deployment,
+            "echo Migrating instances; echo Enabled maintenance",
+            *[_operation("running")] * 2,
+            done,
+        )
+
+        assert item == done
+        calls = client.cluster.update_operation.call_args_list
+        assert calls[0].args == (3, "laptop:1")
+        assert calls[0].kwargs == {"status": "running"}
+        assert calls[-1].kwargs == {
+            "status": "succeeded",
+            "progress": "Enabled maintenance",
+            "result": "Migrating instances\nEnabled maintenance\n",
+            "error": "",
+        }
+
+    def test_failed(self, deployment):
+        client = deployment.get_client.return_value
+
+        self._run(
+            deployment,
+            "echo no capacity; exit 1",
+            _operation("running"),
+            _operation("failed"),
+        )
+
+        final = client.cluster.update_operation.call_args.kwargs
+        assert final["status"] == "failed"
+        assert final["error"] == "exit status 1: no capacity"
+
+    def test_cancelled(self, deployment):
+        client = deployment.get_client.return_value
+
+        self._run(
+            deployment,
+            "echo Migrating instances; exec sleep 30",
+            _operation("cancelling"),
+            _operation("cancelled"),
+        )
+
+        final = client.cluster.update_operation.call_args.kwargs
+        assert final["status"] == "failed"
+        assert final["error"] == (
+            f"signal: {signal.SIGINT.name}: Migrating instances"
+        )
+
+    def test_run_claimed(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.update_operation.side_effect = OperationConflictException(
+            "Operation 3 is running, only pending ones can be claimed"
+        )
+
+        result = CliRunner().invoke(run, ["3"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "only pending ones can be claimed" in result.output
+
+    def test_agent(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.list_operations.return_value = [
+            _operation("running"),
+            _operation(id=4),
+        ]
+
+        with (
+            patch(
+                "sunbeam.commands.operation.run_operation",
+                return_value=_operation("succeeded"),
+            ) as run_op,
+            patch("sunbeam.commands.operation.runner_name", return_value="agent:1"),
+            patch(
+                "sunbeam.commands.operation.time.sleep",
+                side_effect=KeyboardInterrupt,
+            ),
+        ):
+            result = CliRunner().invoke(agent, [], obj=deployment)
+
+        run_op.assert_called_once_with(deployment, 4, "agent:1")
+        assert "Operation 4 succeeded" in result.output
+
+
+class TestOperation:
+    def test_list(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.list_operations.return_value = [
+            _operation("running", progress="Migrating instances")
+        ]
+
+        result = CliRunner().invoke(
+            list_operations, ["--format", "json"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        (item,) = json.loads(result.output)
+        assert item["id"] == 3
+        assert item["status"] == "running"
+        assert item["progress"] == "Migrating instances"
+
+    def test_show(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_operation.return_value = _operation(
+            "failed", error="exit status 1: no capacity", result="no capacity\n"
+        )
+
+        result = CliRunner().invoke(show, ["3"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert "Status: failed" in result.output
+        assert "Error: exit status 1: no capacity" in result.output
+        client.cluster.get_operation.assert_called_once_with(3)
+
+    def test_show_not_found(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_operation.side_effect = OperationNotFoundException(
+            "Operation 3 not found"
+        )
+
+        result = CliRunner().invoke(show, ["3"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Operation 3 not found" in result.output
+
+    def test_wait(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_operation.side_effect = [
+            _operation(),
+            _operation("running", progress="Migrating instances"),
+            _operation("running", progress="Migrating instances"),
+            _operation("succeeded", progress="Enabled maintenance"),
+        ]
+
+        with patch("sunbeam.commands.operation.time.sleep") as sleep:
+            result = CliRunner().invoke(wait, ["3"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert result.output.count("Migrating instances") == 1
+        assert "Enabled maintenance" in result.output
+        assert "Operation 3 succeeded" in result.output
+        assert sleep.call_count == 3
+
+    def test_wait_failed(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_operation.return_value = _operation(
+            "cancelled", error="Cancelled by admin"
+        )
+
+        result = CliRunner().invoke(wait, ["3"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Operation 3 cancelled: Cancelled by admin" in result.output
+
+    def test_wait_timeout(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_operation.return_value = _operation("running")
+
+        result = CliRunner().invoke(
+            wait, ["3", "--timeout", "1m", "--interval", "2m"], obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert "Timed out after 1m waiting for operation 3" in result.output
+
+    def test_cancel_running(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.cancel_operation.return_value = _operation("cancelling")
+
+        result = CliRunner().invoke(cancel, ["3"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert "Operation 3 is cancelling" in result.output
+        client.cluster.cancel_operation.assert_called_once_with(3)
+
+    def test_cancel_completed(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.cancel_operation.side_effect = OperationConflictException(
+            "Operation 3 is succeeded, only pending and running ones can be"
+            " cancelled"
+        )
+
+        result = CliRunner().invoke(cancel, ["3"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "only pending and running ones" in result.output
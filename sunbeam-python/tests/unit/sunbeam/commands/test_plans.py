@@ -0,0 +1,100 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import datetime
+from unittest.mock import MagicMock
+
+from click.testing import CliRunner
+
+from sunbeam.commands.plans import force_unlock_plan, unlock_plan
+
+
+def _lock(age: datetime.timedelta) -> dict:
+    # terraform records nanoseconds, the command drops the last digits
+    created = datetime.datetime.utcnow() - age
+    return {
+        "ID": "lock-a",
+        "Who": "ubuntu@node-1",
+        "Operation": "OperationTypeApply",
+        "Created": created.strftime("%Y-%m-%dT%H:%M:%S.%f") + "789Z",
+        "Info": "",
+    }
+
+
+def _deployment(lock: dict) -> MagicMock:
+    deployment = MagicMock()
+    client = deployment.get_client.return_value
+    client.cluster.get_terraform_lock.return_value = lock
+    return deployment
+
+
+class TestUnlockPlan:
+    def test_recent_lock_asks_for_confirmation(self):
+        deployment = _deployment(_lock(datetime.timedelta(minutes=5)))
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            unlock_plan, ["openstack-plan"], obj=deployment, input="n\n"
+        )
+
+        assert result.exit_code == 1
+        assert "locked less than an hour ago" in result.output
+        client.cluster.unlock_terraform_plan.assert_not_called()
+        client.cluster.force_unlock_terraform_plan.assert_not_called()
+
+    def test_old_lock_unlocks_with_its_id(self):
+        lock = _lock(datetime.timedelta(hours=2))
+        deployment = _deployment(lock)
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(unlock_plan, ["openstack-plan"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        client.cluster.unlock_terraform_plan.assert_called_once_with(
+            "openstack-plan", lock
+        )
+        client.cluster.force_unlock_terraform_plan.assert_not_called()
+
+    def test_force_skips_confirmation(self):
+        lock = _lock(datetime.timedelta(minutes=5))
+        deployment = _deployment(lock)
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            unlock_plan, ["openstack-plan", "--force"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.unlock_terraform_plan.assert_called_once_with(
+            "openstack-plan", lock
+        )
+        client.cluster.force_unlock_terraform_plan.assert_not_called()
+
+
+class TestForceUnlockPlan:
+    def test_force_unlock_shows_lock(self):
+        deployment = _deployment(_lock(datetime.timedelta(minutes=5)))
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            force_unlock_plan, ["openstack-plan", "--yes"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert "ubuntu@node-1" in result.output
+        client.cluster.force_unlock_terraform_plan.assert_called_once_with(
+            "openstack-plan"
+        )
+        client.cluster.unlock_terraform_plan.assert_not_called()
+
+    def test_force_unlock_declined(self):
+        deployment = _deployment(_lock(datetime.timedelta(minutes=5)))
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            force_unlock_plan, ["openstack-plan"], obj=deployment, input="n\n"
+        )
+
+        assert result.exit_code == 1
+        client.cluster.force_unlock_terraform_plan.assert_not_called()
+        client.cluster.unlock_terraform_plan.assert_not_called()
@@ -0,0 +1,185 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+import subprocess
+from pathlib import Path
+from unittest.mock import MagicMock, patch
+
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.service import ClusterQuorumLostException
+from sunbeam.commands.quorum import recover, status
+
+
+@pytest.fixture
+def deployment():
+    return MagicMock()
+
+
+def _snap(clusterd_active: bool = False) -> MagicMock:
+    snap = MagicMock()
+    snap.name = "openstack"
+    snap.paths.snap = Path("/snap/openstack/current")
+    snap.paths.common = Path("/var/snap/openstack/common")
+    snap.services.list.return_value = {"clusterd": MagicMock(active=clusterd_active)}
+    return snap
+
+
+def _quorum_lost() -> ClusterQuorumLostException:
+    return ClusterQuorumLostException(
+        "Cluster quorum lost: 1 of 3 database voters reachable,"
+        " unreachable: node-2, node-3",
+        ["node-1", "node-2", "node-3"],
+        ["node-1"],
+    )
+
+
+class TestStatus:
+    def test_status(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_status.return_value = {
+            "node-1": {
+                "status": "ONLINE",
+                "address": "10.0.0.1:7000",
+                "last_heartbeat": None,
+            }
+        }
+
+        result = CliRunner().invoke(status, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        report = json.loads(result.output)
+        assert report["quorum"] == "ok"
+        assert report["members"] == [
+            {
+                "name": "node-1",
+                "address": "10.0.0.1:7000",
+                "status": "ONLINE",
+                "last_heartbeat": None,
+            }
+        ]
+
+    def test_quorum_lost(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_status.side_effect = _quorum_lost()
+
+        with patch("sunbeam.commands.quorum.Snap", return_value=_snap()):
+            result = CliRunner().invoke(status, [], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "Unreachable voters: node-2, node-3" in result.output
+        assert "sunbeam cluster recover --force-quorum" in result.output
+
+    def test_quorum_lost_structured(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.get_status.side_effect = _quorum_lost()
+
+        with patch("sunbeam.commands.quorum.Snap", return_value=_snap()):
+            result = CliRunner().invoke(status, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 1
+        report = json.loads(result.output.split("Error:")[0])
+        assert report["quorum"] == "lost"
+        assert report["expected"] == ["node-1", "node-2", "node-3"]
+        assert report["reachable"] == ["node-1"]
+        assert len(report["recovery"]) == 5
+
+
+class TestRecover:
+    def test_requires_force_quorum(self):
+        with patch("sunbeam.commands.quorum.subprocess.run") as run:
+            result = CliRunner().invoke(recover, [])
+
+        assert result.exit_code == 2
+        assert "--force-quorum" in result.output
+        run.assert_not_called()
+
+    def test_requires_root(self):
+        with (
+            patch("sunbeam.commands.quorum.os.geteuid", return_value=1000),
+            patch("sunbeam.commands.quorum.subprocess.run") as run,
+        ):
+            result = CliRunner().invoke(recover, ["--force-quorum"])
+
+        assert result.exit_code == 1
+        assert "as root" in result.output
+        run.assert_not_called()
+
+    def test_clusterd_running(self):
+        with (
+            patch("sunbeam.commands.quorum.os.geteuid", return_value=0),
+            patch("sunbeam.commands.quorum.Snap", return_value=_snap(True)),
+            patch("sunbeam.commands.quorum.subprocess.run") as run,
+        ):
+            result = CliRunner().invoke(recover, ["--force-quorum"])
+
+        assert result.exit_code == 1
+        assert "sudo snap stop openstack.clusterd" in result.output
+        run.assert_not_called()
+
+    def test_recover(self):
+        process = MagicMock(stdout="node-1: voter\nnode-2: spare\n")
+        with (
+            patch("sunbeam.commands.quorum.os.geteuid", return_value=0),
+            patch("sunbeam.commands.quorum.Snap", return_value=_snap()),
+            patch("sunbeam.commands.quorum.utils.get_fqdn", return_value="node-1"),
+            patch(
+                "sunbeam.commands.quorum.subprocess.run", return_value=process
+            ) as run,
+        ):
+            result = CliRunner().invoke(
+                recover, ["--force-quorum", "--voter", "node-3"]
+            )
+
+        assert result.exit_code == 0, result.output
+        run.assert_called_once_with(
+            [
+                "/snap/openstack/current/bin/sunbeamd",
+                "recover",
+                "--state-dir",
+                "/var/snap/openstack/common/state",
+                "--force-quorum",
+                "--name",
+                "node-1",
+                "--voter=node-3",
+            ],
+            capture_output=True,
+            text=True,
+            check=True,
+        )
+        assert "node-2: spare" in result.output
+
+    def test_recover_state_dir(self):
+        snap = _snap()
+        snap.config.get.return_value = "/srv/clusterd"
+        process = MagicMock(stdout="node-1: voter\n")
+        with (
+            patch("sunbeam.commands.quorum.os.geteuid", return_value=0),
+            patch("sunbeam.commands.quorum.Snap", return_value=snap),
+            patch("sunbeam.commands.quorum.utils.get_fqdn", return_value="node-1"),
+            patch(
+                "sunbeam.commands.quorum.subprocess.run", return_value=process
+            ) as run,
+        ):
+            result = CliRunner().invoke(recover, ["--force-quorum"])
+
+        assert result.exit_code == 0, result.output
+        snap.config.get.assert_called_once_with("daemon.state-dir")
+        cmd = run.call_args.args[0]
+        assert cmd[cmd.index("--state-dir") + 1] == "/srv/clusterd"
+
+    def test_recover_failed(self):
+        error = subprocess.CalledProcessError(
+            1, "sunbeamd", stderr="Error: Daemon is running\n"
+        )
+        with (
+            patch("sunbeam.commands.quorum.os.geteuid", return_value=0),
+            patch("sunbeam.commands.quorum.Snap", return_value=_snap()),
+            patch("sunbeam.commands.quorum.subprocess.run", side_effect=error),
+        ):
+            result = CliRunner().invoke(recover, ["--force-quorum", "--name", "node-1"])
+
+        assert result.exit_code == 1
+        assert "Daemon is running" in result.output
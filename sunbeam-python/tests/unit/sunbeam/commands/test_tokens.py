@@ -0,0 +1,224 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import datetime
+import json
+from unittest.mock import MagicMock
+
+import click
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.models import JoinTokenScope, ReadOnlyToken
+from sunbeam.clusterd.service import (
+    ReadOnlyTokenAlreadyExistsException,
+    ReadOnlyTokenNotFoundException,
+)
+from sunbeam.commands.tokens import (
+    create_token,
+    list_tokens,
+    remove_token,
+    token,
+)
+from sunbeam.core.common import Role, parse_duration
+
+
+@pytest.fixture
+def deployment():
+    deployment = MagicMock()
+    deployment.get_client.return_value.cluster.list_join_token_scopes.return_value = []
+    return deployment
+
+
+@pytest.fixture
+def cluster():
+    """Cluster group with the token group and a command adding nodes."""
+    added = MagicMock()
+
+    @click.group()
+    def cluster():
+        pass
+
+    @cluster.command("add")
+    @click.argument("names", nargs=-1)
+    @click.option("--role", "roles", multiple=True)
+    @click.option("--ttl")
+    def add(names, roles, ttl):
+        added(names=names, roles=roles, ttl=ttl)
+
+    cluster.add_command(token)
+    cluster.added = added
+    return cluster
+
+
+class TestToken:
+    def test_create_read_only(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.create_read_only_token.return_value = "s3cret"
+
+        result = CliRunner().invoke(
+            create_token, ["grafana", "--read-only"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.create_read_only_token.assert_called_once_with("grafana")
+        assert "s3cret" in result.output
+
+    def test_create_requires_read_only(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(create_token, ["grafana"], obj=deployment)
+
+        assert result.exit_code == 2
+        assert "--read-only" in result.output
+        client.cluster.create_read_only_token.assert_not_called()
+
+    def test_create_join_scoped(self, deployment, cluster):
+        result = CliRunner().invoke(
+            cluster,
+            ["token", "create", "node-2", "--role", "compute", "--ttl", "1h"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 0, result.output
+        cluster.added.assert_called_once_with(
+            names=("node-2",), roles=[Role.COMPUTE], ttl="1h"
+        )
+
+    def test_create_join_invalid_ttl(self, deployment, cluster):
+        result = CliRunner().invoke(
+            cluster,
+            ["token", "create", "node-2", "--role", "compute", "--ttl", "0h"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 2
+        assert "positive duration" in result.output
+        cluster.added.assert_not_called()
+
+    def test_create_join_without_add(self, deployment):
+        @click.group()
+        def cluster():
+            pass
+
+        cluster.add_command(token)
+        result = CliRunner().invoke(
+            cluster,
+            ["token", "create", "node-2", "--role", "compute"],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 2
+        assert "cannot be created" in result.output
+
+    def test_create_read_only_rejects_scope(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            create_token, ["grafana", "--read-only", "--ttl", "1h"], obj=deployment
+        )
+
+        assert result.exit_code == 2
+        client.cluster.create_read_only_token.assert_not_called()
+
+    def test_parse_ttl(self):
+        assert parse_duration("1h30m") == datetime.timedelta(hours=1, minutes=30)
+        assert parse_duration("45s") == datetime.timedelta(seconds=45)
+
+    def test_create_exists(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.create_read_only_token.side_effect = (
+            ReadOnlyTokenAlreadyExistsException(
+                'Read-only token "grafana" already exists'
+            )
+        )
+
+        result = CliRunner().invoke(
+            create_token, ["grafana", "--read-only"], obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert "already exists" in result.output
+
+    def test_list_marks_read_only(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.list_tokens.return_value = [
+            {"name": "node-2", "token": "TESTTOKEN"}
+        ]
+        client.cluster.list_read_only_tokens.return_value = [
+            ReadOnlyToken(name="grafana", created_at="2026-10-14T06:00:00Z"),
+        ]
+
+        result = CliRunner().invoke(list_tokens, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        assert json.loads(result.output) == [
+            {
+                "name": "node-2",
+                "type": "join",
+                "created_at": None,
+                "state": "valid",
+                "roles": None,
+                "expires_at": None,
+            },
+            {
+                "name": "grafana",
+                "type": "read-only",
+                "created_at": "2026-10-14T06:00:00Z",
+                "state": "valid",
+                "roles": None,
+                "expires_at": None,
+            },
+        ]
+        assert "TESTTOKEN" not in result.output
+
+    def test_list_join_token_states(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.list_tokens.return_value = [
+            {"name": "node-2", "token": "TESTTOKEN"},
+            {"name": "node-3", "token": "TESTTOKEN"},
+        ]
+        client.cluster.list_join_token_scopes.return_value = [
+            JoinTokenScope(
+                name="node-2",
+                roles=["compute"],
+                uses=1,
+                expires_at="2026-10-14T07:00:00Z",
+                created_at="2026-10-14T06:00:00Z",
+            ),
+            JoinTokenScope(
+                name="node-3",
+                roles=["compute"],
+                uses=1,
+                expires_at="2026-10-14T05:00:00Z",
+                state="expired",
+            ),
+            # Consumed tokens are no longer listed by microcluster
+            JoinTokenScope(
+                name="node-4", roles=["storage"], uses=1, used=1, state="consumed"
+            ),
+        ]
+        client.cluster.list_read_only_tokens.return_value = []
+
+        result = CliRunner().invoke(list_tokens, ["--format", "json"], obj=deployment)
+
+        assert result.exit_code == 0, result.output
+        tokens = {record["name"]: record for record in json.loads(result.output)}
+        assert tokens["node-2"]["state"] == "valid"
+        assert tokens["node-2"]["roles"] == ["compute"]
+        assert tokens["node-2"]["expires_at"] == "2026-10-14T07:00:00Z"
+        assert tokens["node-2"]["created_at"] == "2026-10-14T06:00:00Z"
+        assert tokens["node-3"]["state"] == "expired"
+        assert tokens["node-4"]["state"] == "consumed"
+        assert tokens["node-4"]["type"] == "join"
+
+    def test_remove_unknown(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.delete_read_only_token.side_effect = (
+            ReadOnlyTokenNotFoundException('Read-only token "grafana" not found')
+        )
+
+        result = CliRunner().invoke(remove_token, ["grafana"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "not found" in result.output
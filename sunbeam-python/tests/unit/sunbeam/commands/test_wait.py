@@ -0,0 +1,91 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+from unittest.mock import MagicMock, patch
+
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.commands.wait import wait
+from sunbeam.core.checks import Check
+
+
+class _Check(Check):
+    def __init__(self, name: str, passed: bool):
+        super().__init__(name, f"Checking {name}")
+        self.passed = passed
+        self.message = "Check successful" if passed else f"{name} pending"
+
+    def run(self) -> bool:
+        return self.passed
+
+
+class FakeClock:
+    """Clock advancing only when sleeping."""
+
+    def __init__(self):
+        self.now = 0.0
+        self.sleeps: list[float] = []
+
+    def monotonic(self) -> float:
+        return self.now
+
+    def sleep(self, seconds: float) -> None:
+        self.sleeps.append(seconds)
+        self.now += seconds
+
+
+@pytest.fixture
+def clock():
+    fake = FakeClock()
+    with (
+        patch("sunbeam.commands.wait.JujuHelper"),
+        patch("sunbeam.commands.wait.time", fake),
+    ):
+        yield fake
+
+
+class TestWait:
+    def test_converges(self, clock):
+        attempts = [
+            [_Check("quorum", True), _Check("units", False)],
+            [_Check("quorum", True), _Check("units", False)],
+            [_Check("quorum", True), _Check("units", True)],
+        ]
+        with patch(
+            "sunbeam.commands.wait.wait_checks", side_effect=attempts
+        ) as wait_checks:
+            result = CliRunner().invoke(
+                wait, ["--timeout", "1m", "--interval", "10s"], obj=MagicMock()
+            )
+
+        assert result.exit_code == 0, result.output
+        assert clock.sleeps == [10, 10]
+        assert wait_checks.call_args.args[2] == ("ready",)
+        # Progress is only printed when the failing checks change
+        assert result.output.count("1 of 2 checks failing: units") == 1
+        assert "Conditions met: ready" in result.output
+
+    def test_times_out(self, clock):
+        with patch(
+            "sunbeam.commands.wait.wait_checks",
+            side_effect=lambda *args: [_Check("quorum", True), _Check("units", False)],
+        ):
+            result = CliRunner().invoke(
+                wait,
+                ["--for", "quorum", "--for", "no-maintenance", "--timeout", "30s"],
+                obj=MagicMock(),
+            )
+
+        assert result.exit_code == 1
+        assert clock.now <= 30
+        assert "units pending" in result.output
+        assert "Timed out after 30s waiting for quorum, no-maintenance" in (
+            result.output
+        )
+
+    def test_invalid_timeout(self, clock):
+        result = CliRunner().invoke(wait, ["--timeout", "0m"], obj=MagicMock())
+
+        assert result.exit_code == 2
+        assert "positive duration" in result.output
@@ -0,0 +1,103 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import MagicMock
+
+import pytest
+from click.testing import CliRunner
+
+from sunbeam.clusterd.models import Webhook
+from sunbeam.clusterd.service import (
+    InvalidWebhookException,
+    WebhookNotFoundException,
+)
+from sunbeam.commands.webhooks import add_webhook, list_webhooks, remove_webhook
+
+
+@pytest.fixture
+def deployment():
+    return MagicMock()
+
+
+class TestWebhook:
+    def test_add(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            add_webhook,
+            [
+                "pager",
+                "https://pager.example.com",
+                "--secret",
+                "s3cret",
+                "--event",
+                "node.added",
+                "--event",
+                "node.removed",
+            ],
+            obj=deployment,
+        )
+
+        assert result.exit_code == 0, result.output
+        client.cluster.add_webhook.assert_called_once_with(
+            Webhook(
+                name="pager",
+                url="https://pager.example.com",
+                secret="s3cret",
+                events=["node.added", "node.removed"],
+            )
+        )
+        assert "s3cret" not in result.output
+
+    def test_add_generated_secret(self, deployment):
+        client = deployment.get_client.return_value
+
+        result = CliRunner().invoke(
+            add_webhook, ["pager", "https://pager.example.com"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        (hook,) = client.cluster.add_webhook.call_args.args
+        assert len(hook.secret) == 64
+        assert hook.events == []
+        assert hook.secret in result.output.replace("\n", "")
+
+    def test_add_invalid(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.add_webhook.side_effect = InvalidWebhookException(
+            'Invalid webhook "pager": URL "pager" must be an http or https URL'
+        )
+
+        result = CliRunner().invoke(
+            add_webhook, ["pager", "pager", "--secret", "s"], obj=deployment
+        )
+
+        assert result.exit_code == 1
+        assert "must be an http or https URL" in result.output
+
+    def test_list(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.list_webhooks.return_value = [
+            Webhook(name="pager", url="https://pager.example.com"),
+        ]
+
+        result = CliRunner().invoke(
+            list_webhooks, ["--format", "json"], obj=deployment
+        )
+
+        assert result.exit_code == 0, result.output
+        assert json.loads(result.output) == [
+            {"name": "pager", "url": "https://pager.example.com", "events": []}
+        ]
+
+    def test_remove_unknown(self, deployment):
+        client = deployment.get_client.return_value
+        client.cluster.remove_webhook.side_effect = WebhookNotFoundException(
+            'Webhook "pager" not found'
+        )
+
+        result = CliRunner().invoke(remove_webhook, ["pager"], obj=deployment)
+
+        assert result.exit_code == 1
+        assert "not found" in result.output
@@ -2,10 +2,12 @@
 # SPDX-License-Identifier: Apache-2.0
 
 import base64
+import datetime
+import email.utils
 import grp
 import json
 import os
-from unittest.mock import Mock
+from unittest.mock import MagicMock, Mock
 
 from sunbeam.core import checks
 
@@ -368,3 +370,118 @@ class TestLxdControllerRegistrationCheck:
 
         assert result is False
         assert "Missing Juju controller on LXD" in check.message
+
+
+def _join_token(join_addresses: list[str]) -> str:
+    token = json.dumps(
+        {"secret": "mysecret", "join_addresses": join_addresses, "fingerprint": "1"}
+    )
+    return base64.b64encode(token.encode()).decode()
+
+
+class TestClusterConnectivityCheck:
+    def test_run(self, mocker):
+        connected = mocker.patch.object(
+            checks.utils, "first_connected_server", return_value="10.0.0.2:7000"
+        )
+
+        check = checks.ClusterConnectivityCheck(
+            _join_token(["10.0.0.1:7000", "10.0.0.2:7000"])
+        )
+
+        assert check.run() is True
+        connected.assert_called_once_with(["10.0.0.1:7000", "10.0.0.2:7000"])
+
+    def test_run_unreachable(self, mocker):
+        mocker.patch.object(checks.utils, "first_connected_server", return_value=None)
+
+        check = checks.ClusterConnectivityCheck(_join_token(["10.0.0.1:7000"]))
+
+        assert check.run() is False
+        assert "10.0.0.1:7000" in check.message
+
+    def test_run_invalid_token(self):
+        check = checks.ClusterConnectivityCheck("not a token")
+
+        assert check.run() is False
+        assert "join addresses" in check.message
+
+
+class TestClockSkewCheck:
+    def _head(self, mocker, offset: datetime.timedelta):
+        now = datetime.datetime.now(datetime.timezone.utc) + offset
+        mocker.patch.object(
+            checks.utils, "first_connected_server", return_value="10.0.0.1:7000"
+        )
+        return mocker.patch.object(
+            checks.requests,
+            "head",
+            return_value=Mock(
+                headers={"Date": email.utils.format_datetime(now, usegmt=True)}
+            ),
+        )
+
+    def test_run(self, mocker):
+        head = self._head(mocker, datetime.timedelta(seconds=2))
+
+        check = checks.ClockSkewCheck(_join_token(["10.0.0.1:7000"]))
+
+        assert check.run() is True
+        assert head.call_args.args == ("https://10.0.0.1:7000/",)
+
+    def test_run_skewed(self, mocker):
+        self._head(mocker, datetime.timedelta(minutes=-5))
+
+        check = checks.ClockSkewCheck(_join_token(["10.0.0.1:7000"]))
+
+        assert check.run() is False
+        assert "off the cluster member 10.0.0.1:7000" in check.message
+
+
+class TestDiskSpaceCheck:
+    def test_run(self, mocker, tmp_path):
+        mocker.patch.object(
+            checks.shutil, "disk_usage", return_value=Mock(free=20 * 1024**3)
+        )
+
+        check = checks.DiskSpaceCheck(tmp_path)
+
+        assert check.run() is True
+
+    def test_run_not_enough_space(self, mocker, tmp_path):
+        mocker.patch.object(
+            checks.shutil, "disk_usage", return_value=Mock(free=3 * 1024**3)
+        )
+
+        check = checks.DiskSpaceCheck(tmp_path)
+
+        assert check.run() is False
+        assert "3G free, 10G needed" in check.message
+
+
+class TestVerifyLocalNodeCheck:
+    def test_run(self, mocker):
+        mocker.patch.object(checks.utils, "get_fqdn", return_value="node1.local")
+
+        assert checks.VerifyLocalNodeCheck("node1.local.").run() is True
+
+    def test_run_other_node(self, mocker):
+        mocker.patch.object(checks.utils, "get_fqdn", return_value="node1.local")
+
+        check = checks.VerifyLocalNodeCheck("node2.local")
+
+        assert check.run() is False
+        assert "not this host (node1.local)" in check.message
+
+
+def test_evaluate_preflight_checks_runs_all_checks():
+    failing = checks.Check("failing")
+    failing.run = Mock(return_value=False)
+    raising = checks.Check("raising")
+    raising.run = Mock(side_effect=OSError("No such file"))
+    passing = checks.Check("passing")
+
+    results = checks.evaluate_preflight_checks([failing, raising, passing], MagicMock())
+
+    assert results == [(failing, False), (raising, False), (passing, True)]
+    assert raising.message == "No such file"
@@ -8,7 +8,15 @@ import click
 import pytest
 
 from sunbeam.clusterd.service import ClusterServiceUnavailableException
-from sunbeam.core.common import Role, validate_roles
+from sunbeam.core.common import (
+    CustomRole,
+    Role,
+    preferred_api_address,
+    roles_to_str_list,
+    validate_node_addresses,
+    validate_node_roles,
+    validate_roles,
+)
 from sunbeam.core.deployment import Deployment
 
 
@@ -195,3 +203,41 @@ def test_validate_roles_gated():
     with patch("sunbeam.core.common._is_role_enabled", return_value=True):
         result = validate_roles(Mock(), Mock(), ("region_controller",))
         assert result == [Role.REGION_CONTROLLER]
+
+
+def test_validate_node_roles():
+    result = validate_node_roles(Mock(), Mock(), ("compute,Load-Balancer",))
+    assert set(result) == {Role.COMPUTE, CustomRole("load-balancer")}
+    assert sorted(roles_to_str_list(result)) == ["compute", "load-balancer"]
+    assert not any(role.is_compute_node() for role in result if role != Role.COMPUTE)
+
+    # Custom role names are validated, and only accepted for nodes
+    with pytest.raises(click.BadParameter):
+        validate_node_roles(Mock(), Mock(), ("load balancer",))
+    with pytest.raises(click.BadParameter):
+        validate_roles(Mock(), Mock(), ("load-balancer",))
+
+
+def test_validate_node_addresses():
+    result = validate_node_addresses(
+        Mock(), Mock(), ("management=10.0.0.5", "data=10.1.0.5", "public=fd00::5")
+    )
+    assert result == {"management": "10.0.0.5", "data": "10.1.0.5", "public": "fd00::5"}
+    assert validate_node_addresses(Mock(), Mock(), ()) == {}
+
+    for value in ("10.0.0.5", "=10.0.0.5", "data=node-1.local", "data=10.1.0.5:7000"):
+        with pytest.raises(click.BadParameter):
+            validate_node_addresses(Mock(), Mock(), (value,))
+    with pytest.raises(click.BadParameter):
+        validate_node_addresses(Mock(), Mock(), ("data=10.1.0.5", "data=10.1.0.6"))
+
+
+def test_preferred_api_address():
+    addresses = {"management": "10.0.0.5", "data": "10.1.0.5"}
+    assert preferred_api_address(addresses) == "10.0.0.5"
+    assert preferred_api_address(addresses, "data") == "10.1.0.5"
+
+    with pytest.raises(ValueError, match="one of data, management"):
+        preferred_api_address(addresses, "public")
+    with pytest.raises(ValueError):
+        preferred_api_address({"data": "10.1.0.5"})
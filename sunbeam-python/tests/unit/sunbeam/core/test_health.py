@@ -0,0 +1,270 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+from unittest.mock import MagicMock, Mock, patch
+
+import pytest
+
+from sunbeam.core.health import (
+    ClusterdQuorumCheck,
+    JujuUnitsCheck,
+    NodeAgentCheck,
+    NoMaintenanceCheck,
+    OpenStackEndpointsCheck,
+    cluster_health_checks,
+    wait_checks,
+)
+from sunbeam.clusterd.models import MaintenanceStatus, MaintenanceStatusList
+
+
+def _member(name: str, status: str = "ONLINE", role: str = "voter") -> dict:
+    return {"name": name, "address": f"{name}:7000", "status": status, "role": role}
+
+
+def _unit(
+    workload: str,
+    machine: str = "0",
+    subordinates: dict | None = None,
+    agent: str = "idle",
+):
+    unit = Mock(machine=machine, subordinates=subordinates or {})
+    unit.workload_status.current = workload
+    unit.juju_status.current = agent
+    return unit
+
+
+@pytest.fixture
+def client():
+    client = MagicMock()
+    client.cluster.get_cluster_members.return_value = [
+        _member("node-1"),
+        _member("node-2"),
+        _member("node-3"),
+    ]
+    return client
+
+
+class TestClusterdQuorumCheck:
+    def test_quorum(self, client):
+        client.cluster.get_cluster_members.return_value[2]["status"] = "UNREACHABLE"
+
+        check = ClusterdQuorumCheck(client)
+        assert check.run()
+        assert check.message == "2 of 3 voters online"
+
+    def test_no_quorum(self, client):
+        for member in client.cluster.get_cluster_members.return_value[1:]:
+            member["status"] = "UNREACHABLE"
+
+        check = ClusterdQuorumCheck(client)
+        assert not check.run()
+        assert "offline: node-2, node-3" in check.message
+
+    def test_only_voters_count(self, client):
+        client.cluster.get_cluster_members.return_value.extend(
+            [
+                _member("node-4", status="UNREACHABLE", role="stand-by"),
+                _member("node-5", status="UNREACHABLE", role="spare"),
+            ]
+        )
+
+        assert ClusterdQuorumCheck(client).run()
+
+
+class TestNodeAgentCheck:
+    def test_online(self, client):
+        assert NodeAgentCheck(client, "node-1").run()
+
+    def test_unreachable(self, client):
+        client.cluster.get_cluster_members.return_value[1]["status"] = "UNREACHABLE"
+
+        check = NodeAgentCheck(client, "node-2")
+        assert not check.run()
+        assert check.message == "Agent at node-2:7000 is UNREACHABLE"
+
+    def test_not_member(self, client):
+        assert not NodeAgentCheck(client, "node-9").run()
+
+
+class TestJujuUnitsCheck:
+    @pytest.fixture
+    def jhelper(self):
+        jhelper = Mock()
+        status = jhelper.get_model_status.return_value
+        status.apps = {
+            "openstack-hypervisor": Mock(
+                units={
+                    "openstack-hypervisor/0": _unit("active", "0"),
+                    "openstack-hypervisor/1": _unit("blocked", "1"),
+                }
+            ),
+            "microceph": Mock(
+                units={
+                    "microceph/0": _unit(
+                        "active", "0", subordinates={"cinder-volume/0": _unit("error")}
+                    )
+                }
+            ),
+        }
+        return jhelper
+
+    def test_degraded(self, jhelper):
+        check = JujuUnitsCheck(jhelper, "openstack-machines")
+        assert not check.run()
+        assert check.message == (
+            "cinder-volume/0 is error, openstack-hypervisor/1 is blocked"
+        )
+
+    def test_scoped_to_machine(self, jhelper):
+        check = JujuUnitsCheck(jhelper, "openstack-machines", machine="1")
+        assert not check.run()
+        assert check.message == "openstack-hypervisor/1 is blocked"
+
+    def test_healthy(self, jhelper):
+        for app in jhelper.get_model_status.return_value.apps.values():
+            for unit in app.units.values():
+                unit.workload_status.current = "active"
+                unit.subordinates = {}
+
+        assert JujuUnitsCheck(jhelper, "openstack-machines").run()
+
+    def test_not_idle(self, jhelper):
+        units = jhelper.get_model_status.return_value.apps["openstack-hypervisor"]
+        units.units["openstack-hypervisor/1"] = _unit("active", "1", agent="executing")
+        jhelper.get_model_status.return_value.apps.pop("microceph")
+
+        assert JujuUnitsCheck(jhelper, "openstack-machines").run()
+        check = JujuUnitsCheck(jhelper, "openstack-machines", idle=True)
+        assert not check.run()
+        assert check.message == "openstack-hypervisor/1 is executing"
+
+
+class TestNoMaintenanceCheck:
+    def test_in_maintenance(self, client):
+        client.cluster.list_maintenance_status.return_value = MaintenanceStatusList(
+            root=[
+                MaintenanceStatus(node="node-2", status="enabled"),
+                MaintenanceStatus(node="node-1", status="disabled"),
+                MaintenanceStatus(node="node-3", status="degraded"),
+            ]
+        )
+
+        check = NoMaintenanceCheck(client)
+        assert not check.run()
+        assert check.message == "node-2 is enabled, node-3 is degraded"
+
+    def test_no_maintenance(self, client):
+        client.cluster.list_maintenance_status.return_value = MaintenanceStatusList(
+            root=[MaintenanceStatus(node="node-1", status="disabled")]
+        )
+
+        assert NoMaintenanceCheck(client).run()
+
+
+class TestOpenStackEndpointsCheck:
+    @pytest.fixture
+    def conn(self):
+        conn = Mock()
+        keystone, nova = Mock(id="s1"), Mock(id="s2")
+        # name is a Mock constructor argument
+        keystone.name, nova.name = "keystone", "nova"
+        conn.identity.services.return_value = [keystone, nova]
+        conn.identity.endpoints.return_value = [
+            Mock(url="https://10.0.0.1/openstack-keystone", service_id="s1"),
+            Mock(
+                url="https://10.0.0.1/openstack-nova/v2.1/$(project_id)s",
+                service_id="s2",
+            ),
+        ]
+        with patch("sunbeam.core.health.get_admin_connection", return_value=conn):
+            yield conn
+
+    def test_reachable(self, conn):
+        conn.session.get.return_value = Mock(status_code=401)
+
+        check = OpenStackEndpointsCheck(Mock(), Mock())
+        assert check.run()
+        urls = [call.args[0] for call in conn.session.get.call_args_list]
+        assert urls[1] == "https://10.0.0.1/openstack-nova/v2.1/"
+
+    def test_unreachable(self, conn):
+        conn.session.get.side_effect = [
+            Mock(status_code=200),
+            ConnectionError("refused"),
+        ]
+
+        check = OpenStackEndpointsCheck(Mock(), Mock())
+        assert not check.run()
+        assert check.message == "Unreachable: nova (ConnectionError)"
+
+    def test_server_error(self, conn):
+        conn.session.get.return_value = Mock(status_code=503)
+
+        check = OpenStackEndpointsCheck(Mock(), Mock())
+        assert not check.run()
+        assert "keystone (503)" in check.message
+
+
+class TestClusterHealthChecks:
+    def test_cluster(self, client):
+        deployment = Mock(openstack_machines_model="openstack-machines")
+        deployment.get_client.return_value = client
+
+        checks = cluster_health_checks(deployment, Mock())
+
+        assert [type(check).__name__ for check in checks] == [
+            "ClusterdQuorumCheck",
+            "NodeAgentCheck",
+            "NodeAgentCheck",
+            "NodeAgentCheck",
+            "JujuControllerCheck",
+            "JujuUnitsCheck",
+            "JujuUnitsCheck",
+            "OpenStackEndpointsCheck",
+        ]
+
+    def test_node(self, client):
+        deployment = Mock(openstack_machines_model="openstack-machines")
+        deployment.get_client.return_value = client
+        client.cluster.get_node_info.return_value = {"name": "node-2", "machineid": 1}
+
+        checks = cluster_health_checks(deployment, Mock(), node="node-2")
+
+        assert [check.name for check in checks] == [
+            "Cluster database quorum",
+            "Node node-2 agent",
+            "Juju controller",
+            "Units of model openstack-machines on machine 1",
+        ]
+
+    def test_clusterd_unreachable(self, client):
+        deployment = Mock(openstack_machines_model="openstack-machines")
+        deployment.get_client.return_value = client
+        client.cluster.get_cluster_members.side_effect = ConnectionError("refused")
+
+        checks = cluster_health_checks(deployment, Mock())
+
+        assert "NodeAgentCheck" not in [type(check).__name__ for check in checks]
+
+
+class TestWaitChecks:
+    @pytest.fixture
+    def deployment(self, client):
+        deployment = Mock(openstack_machines_model="openstack-machines")
+        deployment.get_client.return_value = client
+        return deployment
+
+    def test_ready_units_idle(self, deployment):
+        checks = wait_checks(deployment, Mock(), ["ready"])
+
+        units = [check for check in checks if isinstance(check, JujuUnitsCheck)]
+        assert len(units) == 2
+        assert all(check.idle for check in units)
+
+    def test_quorum_and_no_maintenance(self, deployment):
+        checks = wait_checks(deployment, Mock(), ["quorum", "no-maintenance"])
+
+        assert [type(check).__name__ for check in checks] == [
+            "ClusterdQuorumCheck",
+            "NoMaintenanceCheck",
+        ]
@@ -0,0 +1,86 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+from pathlib import Path
+
+from sunbeam.core.host_inventory import (
+    InventoryHost,
+    group_name,
+    inventory_hosts,
+    render_ansible_ini,
+    render_ansible_yaml,
+    render_ssh_config,
+)
+
+TESTDATA = Path(__file__).parent / "testdata"
+
+
+def _hosts() -> list[InventoryHost]:
+    """Hosts of a small cluster, with a cordoned node and one in maintenance."""
+    nodes = [
+        {"name": "node-3.example.com", "role": ["compute"], "labels": {}},
+        {
+            "name": "node-1.example.com",
+            "role": ["control", "compute"],
+            "labels": {"rack": "a"},
+        },
+        {
+            "name": "node-2.example.com",
+            "role": ["storage", "compute", "gpu-worker"],
+            "labels": {"zone": "z1", "rack": "b"},
+            "cordoned": True,
+        },
+    ]
+    members = [
+        {"name": "node-1.example.com", "address": "10.0.0.1:7000"},
+        {"name": "node-2.example.com", "address": "[fd00::2]:7000"},
+    ]
+    return inventory_hosts(nodes, members, {"node-3.example.com"})
+
+
+class TestInventoryHosts:
+    def test_hosts(self):
+        hosts = _hosts()
+
+        assert [host.name for host in hosts] == [
+            "node-1.example.com",
+            "node-2.example.com",
+            "node-3.example.com",
+        ]
+        assert hosts[1].address == "fd00::2"
+        assert hosts[1].groups() == [
+            "compute",
+            "cordoned",
+            "gpu_worker",
+            "label_rack_b",
+            "label_zone_z1",
+            "storage",
+        ]
+        assert hosts[2].address is None
+        assert hosts[2].maintenance
+
+    def test_group_name(self):
+        assert group_name("label_topology.kubernetes.io-zone_eu-1") == (
+            "label_topology_kubernetes_io_zone_eu_1"
+        )
+
+
+class TestRender:
+    def test_ansible_yaml(self):
+        expected = (TESTDATA / "inventory.yaml").read_text()
+
+        assert render_ansible_yaml(_hosts()) == expected
+
+    def test_ansible_ini(self):
+        expected = (TESTDATA / "inventory.ini").read_text()
+
+        assert render_ansible_ini(_hosts()) == expected
+
+    def test_ssh_config(self):
+        expected = (TESTDATA / "ssh_config").read_text()
+
+        assert render_ssh_config(_hosts()) == expected
+
+    def test_empty(self):
+        assert render_ssh_config([]) == ""
+        assert render_ansible_ini([]) == "[all]\n"
@@ -0,0 +1,52 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+from unittest.mock import patch
+
+from sunbeam.core import inventory
+
+
+def _write(path, content):
+    path.parent.mkdir(parents=True, exist_ok=True)
+    path.write_text(content + "\n")
+
+
+def test_collect_hardware(tmp_path):
+    net = tmp_path / "class" / "net"
+    _write(net / "eno1" / "address", "00:16:3e:00:00:01")
+    _write(net / "eno1" / "speed", "10000")
+    (net / "eno1" / "device").mkdir()
+    _write(net / "eno2" / "address", "00:16:3e:00:00:02")
+    _write(net / "eno2" / "speed", "-1")
+    (net / "eno2" / "device").mkdir()
+    # Virtual interface, no device link
+    _write(net / "br0" / "address", "00:16:3e:00:00:03")
+
+    block = tmp_path / "block"
+    _write(block / "sda" / "size", "1953525168")
+    _write(block / "sda" / "queue" / "rotational", "1")
+    _write(block / "sda" / "device" / "model", "ST1000DM010")
+    _write(block / "nvme0n1" / "size", "1953525168")
+    _write(block / "nvme0n1" / "queue" / "rotational", "0")
+    _write(block / "loop0" / "size", "131072")
+    # Empty card reader
+    _write(block / "sdb" / "size", "0")
+
+    with (
+        patch.object(inventory, "get_host_total_cores", return_value=16),
+        patch.object(inventory, "get_host_total_ram", return_value=65536000),
+    ):
+        hardware = inventory.collect_hardware(tmp_path)
+
+    assert hardware.cpu_count == 16
+    assert hardware.memory_total == 65536000 * 1024
+    assert [(n.name, n.mac_address, n.speed) for n in hardware.nics] == [
+        ("eno1", "00:16:3e:00:00:01", 10000),
+        ("eno2", "00:16:3e:00:00:02", 0),
+    ]
+    assert [
+        (d.name, d.model, d.size, d.rotational) for d in hardware.block_devices
+    ] == [
+        ("nvme0n1", "", 1953525168 * 512, False),
+        ("sda", "ST1000DM010", 1953525168 * 512, True),
+    ]
@@ -1260,3 +1260,110 @@ def test_snapshot_workload_status_mixed_present_and_absent(jhelper, status):
     status.apps["present"] = app_mock
     result = jhelper.snapshot_workload_status("test-model", ["present", "absent"])
     assert result == {"present": "active"}
+
+
+# ---------------------------------------------------------------------------
+# Juju retry policy
+# ---------------------------------------------------------------------------
+
+
+class FlakyJuju:
+    """Fake Juju client failing its first commands."""
+
+    def __init__(self, failures: int, stderr: str = "connection reset by peer"):
+        self.failures = failures
+        self.stderr = stderr
+        self.calls = 0
+
+    def _cli(self, *args, **kwargs):
+        self.calls += 1
+        if self.calls <= self.failures:
+            raise jubilant.CLIError(1, ["juju", *args], stderr=self.stderr)
+        return "{}", ""
+
+
+@pytest.fixture
+def retry_policy():
+    return jujulib.JujuRetryPolicy(max_attempts=3, base_delay=0, max_delay=0, jitter=0)
+
+
+@pytest.mark.parametrize(
+    "stderr,transient",
+    [
+        ("ERROR connection is shut down", True),
+        ("read tcp 10.0.0.1:17070: connection reset by peer", True),
+        ("ERROR cannot connect to API: i/o timeout", True),
+        ("ERROR controller is busy, try again", True),
+        ('ERROR cannot add application "mysql": application already exists', False),
+        ('ERROR model "openstack" not found, try again', False),
+        ("ERROR invalid charm name", False),
+        ("", False),
+    ],
+)
+def test_is_transient_juju_error(stderr, transient):
+    error = jubilant.CLIError(1, ["juju", "deploy"], stderr=stderr)
+    assert jujulib.is_transient_juju_error(error) is transient
+
+
+def test_is_transient_juju_error_other_exception():
+    assert not jujulib.is_transient_juju_error(TimeoutError("connection reset"))
+
+
+def test_retry_policy_retries_transient_errors(retry_policy, caplog):
+    juju = FlakyJuju(failures=2)
+    with caplog.at_level("DEBUG", logger="sunbeam.core.juju"):
+        assert retry_policy.call(juju._cli, "status") == ("{}", "")
+    assert juju.calls == 3
+    assert "attempt 1/3" in caplog.text
+    assert "attempt 2/3" in caplog.text
+    assert "connection reset by peer" in caplog.text
+
+
+def test_retry_policy_gives_up(retry_policy):
+    juju = FlakyJuju(failures=5)
+    with pytest.raises(jubilant.CLIError):
+        retry_policy.call(juju._cli, "status")
+    assert juju.calls == 3
+
+
+def test_retry_policy_does_not_retry_logical_errors(retry_policy):
+    juju = FlakyJuju(failures=1, stderr='application "mysql" already exists')
+    with pytest.raises(jubilant.CLIError):
+        retry_policy.call(juju._cli, "deploy", "mysql")
+    assert juju.calls == 1
+
+
+def test_retrying_juju_retries_commands(retry_policy):
+    flaky = FlakyJuju(failures=2)
+    with patch.object(jubilant.Juju, "_cli", side_effect=flaky._cli):
+        juju = jujulib.RetryingJuju(retry_policy)
+        assert juju._cli("status", "--format", "json") == ("{}", "")
+    assert flaky.calls == 3
+
+
+def test_retry_policy_load_defaults(monkeypatch):
+    for field in jujulib.JujuRetryPolicy.model_fields:
+        monkeypatch.delenv(jujulib.JUJU_RETRY_ENV_PREFIX + field.upper(), False)
+    client = Mock()
+    client.cluster.get_config.side_effect = jujulib.ConfigItemNotFoundException()
+    assert jujulib.JujuRetryPolicy.load(client) == jujulib.JujuRetryPolicy()
+
+
+def test_retry_policy_load_env_overrides_cluster_config(monkeypatch):
+    monkeypatch.setenv("SUNBEAM_JUJU_RETRY_MAX_ATTEMPTS", "10")
+    client = Mock()
+    client.cluster.get_config.return_value = json.dumps(
+        {"max_attempts": 4, "max_delay": 5}
+    )
+    policy = jujulib.JujuRetryPolicy.load(client)
+    client.cluster.get_config.assert_called_once_with(jujulib.JUJU_RETRY_POLICY_KEY)
+    assert policy.max_attempts == 10
+    assert policy.max_delay == 5
+    assert policy.base_delay == jujulib.JujuRetryPolicy().base_delay
+
+
+def test_retry_policy_load_ignores_invalid_settings(monkeypatch):
+    monkeypatch.setenv("SUNBEAM_JUJU_RETRY_MAX_ATTEMPTS", "0")
+    client = Mock()
+    client.cluster.get_config.return_value = json.dumps({"max_delay": "soon"})
+    assert jujulib.JujuRetryPolicy.load(client) == jujulib.JujuRetryPolicy()
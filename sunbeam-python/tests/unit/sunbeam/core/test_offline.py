@@ -0,0 +1,180 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import tempfile
+from pathlib import Path
+from unittest.mock import Mock
+
+import pytest
+import yaml
+
+from sunbeam.core.checks import OfflineArtifactsCheck
+from sunbeam.core.manifest import JujuManifest, Manifest
+from sunbeam.core.offline import (
+    apply_offline_juju_manifest,
+    offline_model_config,
+    resolve_offline_artifacts,
+)
+
+offline_manifest = """
+core:
+  software:
+    offline:
+      charmhub_url: http://mirror.example.com:8080
+      snap_store_proxy: 4kCOW3bFGcbQzCqLm7lPMWV3BvReDtq3
+      snap_store_assertions: {assertions}
+    charms:
+      keystone-k8s:
+        channel: 2025.1/stable
+      glance-k8s:
+        channel: 2025.1/stable
+        revision: 134
+      sunbeam-machine:
+        channel: 2025.1/stable
+"""
+
+# Channel maps served by the mirror, sunbeam-machine is not mirrored
+MIRROR = {
+    "keystone-k8s": {
+        "channel-map": [
+            {
+                "channel": {"track": "2025.1", "risk": "stable"},
+                "revision": {"revision": 250},
+            }
+        ]
+    },
+    "glance-k8s": {
+        "channel-map": [
+            {
+                "channel": {"track": "2025.1", "risk": "stable"},
+                "revision": {"revision": 134},
+            },
+            {
+                "channel": {"track": "2025.1", "risk": "edge"},
+                "revision": {"revision": 140},
+            },
+        ]
+    },
+}
+
+
+def fake_mirror(charmhub_url, name):
+    assert charmhub_url == "http://mirror.example.com:8080"
+    return MIRROR.get(name)
+
+
+@pytest.fixture
+def assertions():
+    with tempfile.NamedTemporaryFile("w", suffix=".assert") as f:
+        f.write("type: account\n")
+        f.flush()
+        yield Path(f.name)
+
+
+def load_manifest(assertions: Path) -> Manifest:
+    return Manifest.model_validate(
+        yaml.safe_load(offline_manifest.format(assertions=assertions))
+    )
+
+
+class TestResolveOfflineArtifacts:
+    def test_missing_charm_listed(self, assertions):
+        manifest = load_manifest(assertions)
+
+        unresolved = resolve_offline_artifacts(manifest, fetch=fake_mirror)
+
+        assert unresolved == [
+            "charm sunbeam-machine (2025.1/stable): not found on the mirror"
+        ]
+
+    def test_all_resolved(self, assertions):
+        manifest = load_manifest(assertions)
+        del manifest.core.software.charms["sunbeam-machine"]
+
+        assert resolve_offline_artifacts(manifest, fetch=fake_mirror) == []
+
+    def test_missing_channel_and_revision(self, assertions):
+        manifest = load_manifest(assertions)
+        del manifest.core.software.charms["sunbeam-machine"]
+        manifest.core.software.charms["keystone-k8s"].channel = "2025.1/edge"
+        manifest.core.software.charms["glance-k8s"].revision = 140
+
+        unresolved = resolve_offline_artifacts(manifest, fetch=fake_mirror)
+
+        assert unresolved == [
+            "charm glance-k8s (2025.1/stable): revision 140 not found on the mirror",
+            "charm keystone-k8s (2025.1/edge): channel 2025.1/edge not found on"
+            " the mirror",
+        ]
+
+    def test_public_store_refused(self, assertions):
+        manifest = load_manifest(assertions)
+        manifest.core.software.offline.charmhub_url = "https://api.charmhub.io"
+        fetch = Mock()
+
+        unresolved = resolve_offline_artifacts(manifest, fetch=fetch)
+
+        assert unresolved == [
+            "charmhub mirror: https://api.charmhub.io is a public store, not a mirror"
+        ]
+        fetch.assert_not_called()
+
+    def test_no_offline_section(self):
+        fetch = Mock()
+
+        unresolved = resolve_offline_artifacts(Manifest(), fetch=fetch)
+
+        assert unresolved == [
+            "charmhub mirror: charmhub_url is not set",
+            "snap store proxy: snap_store_proxy is not set",
+            "snap store proxy: snap_store_assertions is not set",
+        ]
+        fetch.assert_not_called()
+
+    def test_missing_assertions_file(self, assertions):
+        manifest = load_manifest(Path("/nonexistent/store.assert"))
+        del manifest.core.software.charms["sunbeam-machine"]
+
+        unresolved = resolve_offline_artifacts(manifest, fetch=fake_mirror)
+
+        assert unresolved == [
+            "snap store assertions /nonexistent/store.assert: file not found"
+        ]
+
+
+class TestOfflineArtifactsCheck:
+    def test_lists_unresolved_artifacts(self):
+        check = OfflineArtifactsCheck(Manifest())
+
+        assert check.run() is False
+        assert check.message.startswith("3 artifacts could not be resolved locally")
+        assert "  - charmhub mirror: charmhub_url is not set" in check.message
+
+
+class TestApplyOfflineJujuManifest:
+    def test_model_config(self, assertions):
+        manifest = load_manifest(assertions)
+        juju = JujuManifest(
+            bootstrap_args=["--agent-version=3.6.0"],
+            bootstrap_model_configs={"openstack-machines": {"logging-config": "x"}},
+        )
+
+        apply_offline_juju_manifest(
+            juju, manifest.core.software.offline, ["openstack-machines"]
+        )
+
+        config = offline_model_config(manifest.core.software.offline)
+        assert config == {
+            "charmhub-url": "http://mirror.example.com:8080",
+            "snap-store-proxy": "4kCOW3bFGcbQzCqLm7lPMWV3BvReDtq3",
+            "snap-store-assertions": "type: account\n",
+        }
+        assert juju.bootstrap_args[0] == "--agent-version=3.6.0"
+        assert (
+            "--model-default=charmhub-url=http://mirror.example.com:8080"
+            in juju.bootstrap_args
+        )
+        assert juju.bootstrap_model_configs["openstack-machines"] == {
+            "logging-config": "x",
+            **config,
+        }
@@ -73,6 +73,125 @@ class TestOpenStackAPI:
             all_projects=True, hypervisor_hostname="hyper1", status=None
         )
 
+    def test_hypervisors_free_capacity(self):
+        def hypervisor(name, status="enabled", state="up"):
+            hyper = Mock(status=status, state=state)
+            hyper.name = name
+            return hyper
+
+        def provider(name):
+            rp = Mock(id=f"rp-{name}")
+            rp.name = name
+            return rp
+
+        def placement_get(url):
+            if url.endswith("/inventories"):
+                body = {
+                    "inventories": {
+                        "VCPU": {"total": 8, "reserved": 0, "allocation_ratio": 2.0},
+                        "MEMORY_MB": {
+                            "total": 16384,
+                            "reserved": 512,
+                            "allocation_ratio": 1.0,
+                        },
+                    }
+                }
+            else:
+                body = {"usages": {"VCPU": 4, "MEMORY_MB": 4096}}
+            return Mock(json=Mock(return_value=body))
+
+        conn = Mock()
+        conn.compute.hypervisors.return_value = [
+            hypervisor("hyper1"),
+            hypervisor("hyper2"),
+            hypervisor("hyper3", status="disabled"),
+            hypervisor("hyper4", state="down"),
+        ]
+        conn.placement.resource_providers.return_value = [
+            provider("hyper1"),
+            provider("hyper2"),
+        ]
+        conn.placement.get.side_effect = placement_get
+
+        capacity = sunbeam.core.openstack_api.hypervisors_free_capacity(
+            conn, exclude="hyper1"
+        )
+
+        assert capacity == {"hyper2": {"VCPU": 12, "MEMORY_MB": 11776}}
+        conn.placement.get.assert_any_call("/resource_providers/rp-hyper2/usages")
+
+    def test_migrate_instance_live(self):
+        conn = Mock()
+        conn.compute.migrations.return_value = []
+        with patch.object(sunbeam.core.openstack_api, "_check_migration"):
+            sunbeam.core.openstack_api.migrate_instance(conn, "inst-1", "hyper1")
+        conn.compute.live_migrate_server.assert_called_once_with(
+            conn.compute.get_server.return_value, host=None, block_migration="auto"
+        )
+        conn.compute.migrate_server.assert_not_called()
+
+    def test_migrate_instance_cold(self):
+        conn = Mock()
+        conn.compute.migrations.return_value = []
+        with patch.object(sunbeam.core.openstack_api, "_check_migration"):
+            sunbeam.core.openstack_api.migrate_instance(
+                conn, "inst-1", "hyper1", migration_type="cold", destination="hyper2"
+            )
+        conn.compute.migrate_server.assert_called_once_with(
+            conn.compute.get_server.return_value, host="hyper2"
+        )
+
+    def test_migration_percent(self):
+        conn = Mock()
+        conn.compute.server_migrations.return_value = [
+            Mock(memory_total_bytes=4096, memory_processed_bytes=1024)
+        ]
+        percent = sunbeam.core.openstack_api.migration_percent(conn, "inst-1")
+        assert percent == 25
+        conn.compute.server_migrations.assert_called_once_with("inst-1")
+
+    def test_migration_percent_unknown(self):
+        conn = Mock()
+        conn.compute.server_migrations.return_value = [
+            Mock(memory_total_bytes=None, memory_processed_bytes=None)
+        ]
+        assert sunbeam.core.openstack_api.migration_percent(conn, "inst-1") is None
+        conn.compute.server_migrations.return_value = []
+        assert sunbeam.core.openstack_api.migration_percent(conn, "inst-1") is None
+
+    def test_check_migration_completed(self):
+        conn = Mock()
+        conn.compute.get_server.return_value = Mock(
+            status="ACTIVE", hypervisor_hostname="hyper2"
+        )
+        host = sunbeam.core.openstack_api._check_migration(conn, "inst-1", "hyper1", 0)
+        assert host == "hyper2"
+
+    def test_check_migration_confirms_resize(self):
+        conn = Mock()
+        server = Mock(status="VERIFY_RESIZE", hypervisor_hostname="hyper2")
+        conn.compute.get_server.return_value = server
+        with pytest.raises(sunbeam.core.openstack_api._MigrationInProgressException):
+            sunbeam.core.openstack_api._check_migration(conn, "inst-1", "hyper1", 0)
+        conn.compute.confirm_server_resize.assert_called_once_with(server)
+
+    def test_check_migration_failed(self):
+        conn = Mock()
+        conn.compute.get_server.return_value = Mock(
+            status="ACTIVE", hypervisor_hostname="hyper1"
+        )
+        conn.compute.migrations.return_value = [
+            Mock(id=1, status="error"),
+            Mock(id=2, status="error"),
+        ]
+        # The migration before the current one is not its outcome
+        with pytest.raises(sunbeam.core.openstack_api._MigrationInProgressException):
+            sunbeam.core.openstack_api._check_migration(conn, "inst-1", "hyper1", 2)
+        with pytest.raises(
+            sunbeam.core.openstack_api.InstanceMigrationFailedException
+        ):
+            sunbeam.core.openstack_api._check_migration(conn, "inst-1", "hyper1", 1)
+
     def test_remove_compute_service(self):
         service1 = Mock(binary="nova-compute", host="hyper1")
         conn = Mock()
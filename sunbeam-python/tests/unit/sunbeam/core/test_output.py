@@ -0,0 +1,41 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import datetime
+import json
+from unittest.mock import Mock
+
+import pytest
+
+from sunbeam.core import output
+
+
+@pytest.mark.parametrize(
+    "value,expected",
+    [
+        ("2025-03-01T10:20:30.123456789+02:00", "2025-03-01T08:20:30Z"),
+        ("2025-03-01T10:20:30Z", "2025-03-01T10:20:30Z"),
+        (datetime.datetime(2025, 3, 1, 10, 20, 30), "2025-03-01T10:20:30Z"),
+        ("0001-01-01T00:00:00Z", None),
+        ("", None),
+        (None, None),
+    ],
+)
+def test_rfc3339(value, expected):
+    assert output.rfc3339(value) == expected
+
+
+def test_print_structured_json():
+    console = Mock()
+
+    output.print_structured(console, {"b": [1], "a": "[red]x[/red]"}, "json")
+
+    text = console.print.call_args.args[0]
+    assert json.loads(text) == {"a": "[red]x[/red]", "b": [1]}
+    assert console.print.call_args.kwargs["markup"] is False
+    assert console.print.call_args.kwargs["soft_wrap"] is True
+
+
+def test_print_structured_table_is_not_structured():
+    with pytest.raises(ValueError):
+        output.print_structured(Mock(), {}, "table")
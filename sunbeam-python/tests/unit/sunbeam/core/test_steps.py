@@ -13,6 +13,7 @@ from sunbeam.core.steps import (
     RemoveMachineUnitsStep,
 )
 from sunbeam.core.terraform import TerraformException, TerraformStateLockedException
+from sunbeam.core.timeouts import STEP_TIMEOUTS
 
 
 @pytest.fixture()
@@ -21,6 +22,12 @@ def read_config():
         yield p
 
 
+@pytest.fixture()
+def step_timeouts():
+    yield STEP_TIMEOUTS
+    STEP_TIMEOUTS.configure({})
+
+
 class TestDeployMachineApplicationStep:
     def test_run_pristine_installation(
         self, deployment, cclient, tfhelper, jhelper, manifest
@@ -144,7 +151,32 @@ class TestDeployMachineApplicationStep:
 
         jhelper.wait_application_ready.assert_called_once()
         assert result.result_type == ResultType.FAILED
-        assert result.message == "timed out"
+        assert result.message.startswith("Step DeployMachineApplicationStep timed out")
+
+    def test_run_waiting_timeout_overridden(
+        self, deployment, cclient, tfhelper, jhelper, manifest, step_timeouts
+    ):
+        step_timeouts.configure({"DeployMachineApplicationStep": 1800})
+        jhelper.get_application.return_value = Mock(units={"app/1": Mock(machine=1)})
+        jhelper.wait_application_ready.side_effect = TimeoutError("timed out")
+
+        step = DeployMachineApplicationStep(
+            deployment,
+            cclient,
+            tfhelper,
+            jhelper,
+            manifest,
+            "tfconfig",
+            "app1",
+            "model1",
+        )
+        result = step.run()
+
+        assert jhelper.wait_application_ready.call_args.kwargs["timeout"] == 1800
+        assert result.result_type == ResultType.FAILED
+        assert result.message.startswith(
+            "Step DeployMachineApplicationStep timed out after 30m (timed out)"
+        )
 
 
 class TestRemoveMachineUnitStep:
@@ -234,4 +266,4 @@ class TestRemoveMachineUnitStep:
 
         jhelper.wait_application_ready.assert_called_once()
         assert result.result_type == ResultType.FAILED
-        assert result.message == "timed out"
+        assert result.message.startswith("Step RemoveMachineUnitsStep timed out")
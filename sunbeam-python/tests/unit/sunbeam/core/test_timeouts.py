@@ -0,0 +1,128 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import Mock
+
+import pytest
+
+from sunbeam.clusterd.service import (
+    ClusterServiceUnavailableException,
+    ConfigItemNotFoundException,
+)
+from sunbeam.core.timeouts import (
+    STEP_TIMEOUTS_KEY,
+    StepTimeouts,
+    format_duration,
+    load_step_timeouts_file,
+    parse_step_timeouts,
+    step_timeout_message,
+)
+
+
+class DeployControlPlaneStep:
+    pass
+
+
+def _client(value=None, error=None) -> Mock:
+    client = Mock()
+    client.cluster.get_config.return_value = json.dumps(value)
+    client.cluster.get_config.side_effect = error
+    return client
+
+
+class TestParseStepTimeouts:
+    def test_parse(self):
+        timeouts = parse_step_timeouts(
+            {"DeployControlPlaneStep": "1h30m", "IntegrateStep": 90, "Other": "45"}
+        )
+
+        assert timeouts == {
+            "DeployControlPlaneStep": 5400,
+            "IntegrateStep": 90,
+            "Other": 45,
+        }
+
+    @pytest.mark.parametrize("value", ["soon", -1, 0, True, None])
+    def test_invalid_timeout(self, value):
+        with pytest.raises(ValueError, match="DeployControlPlaneStep"):
+            parse_step_timeouts({"DeployControlPlaneStep": value})
+
+    def test_not_a_mapping(self):
+        with pytest.raises(ValueError, match="mapping"):
+            parse_step_timeouts(["DeployControlPlaneStep"])
+
+    def test_load_file(self, tmp_path):
+        path = tmp_path / "timeouts.yaml"
+        path.write_text("DeployControlPlaneStep: 2h\n")
+
+        assert load_step_timeouts_file(path) == {"DeployControlPlaneStep": 7200}
+
+    def test_load_invalid_file(self, tmp_path):
+        path = tmp_path / "timeouts.yaml"
+        path.write_text("DeployControlPlaneStep: soon\n")
+
+        with pytest.raises(ValueError, match=str(path)):
+            load_step_timeouts_file(path)
+
+
+class TestStepTimeouts:
+    def test_default(self):
+        timeouts = StepTimeouts()
+
+        assert timeouts.get("DeployControlPlaneStep", 600) == 600
+
+    def test_cluster_config(self):
+        client = _client({"DeployControlPlaneStep": "2h"})
+        timeouts = StepTimeouts()
+        timeouts.configure({}, lambda: client)
+
+        assert timeouts.get("DeployControlPlaneStep", 600) == 7200
+        assert timeouts.get("IntegrateStep", 600) == 600
+        client.cluster.get_config.assert_called_once_with(STEP_TIMEOUTS_KEY)
+
+    def test_file_overrides_cluster_config(self):
+        client = _client({"DeployControlPlaneStep": "2h", "IntegrateStep": "20m"})
+        timeouts = StepTimeouts()
+        timeouts.configure({"DeployControlPlaneStep": 10800}, lambda: client)
+
+        assert timeouts.get("DeployControlPlaneStep", 600) == 10800
+        assert timeouts.get("IntegrateStep", 600) == 1200
+
+    def test_cluster_config_not_set(self):
+        client = _client(error=ConfigItemNotFoundException("not found"))
+        timeouts = StepTimeouts()
+        timeouts.configure({}, lambda: client)
+
+        assert timeouts.get("DeployControlPlaneStep", 600) == 600
+
+    def test_cluster_unavailable(self):
+        client = _client(error=ClusterServiceUnavailableException("unavailable"))
+        timeouts = StepTimeouts()
+        timeouts.configure({}, lambda: client)
+
+        assert timeouts.get("DeployControlPlaneStep", 600) == 600
+
+    def test_cluster_config_invalid(self):
+        client = _client({"DeployControlPlaneStep": "soon"})
+        timeouts = StepTimeouts()
+        timeouts.configure({}, lambda: client)
+
+        assert timeouts.get("DeployControlPlaneStep", 600) == 600
+
+
+def test_format_duration():
+    assert format_duration(5400) == "1h30m"
+    assert format_duration(90) == "1m30s"
+    assert format_duration(0) == "0s"
+
+
+def test_step_timeout_message():
+    message = step_timeout_message(
+        DeployControlPlaneStep(), 1800, TimeoutError("timed out")
+    )
+
+    assert message.startswith(
+        "Step DeployControlPlaneStep timed out after 30m (timed out)."
+    )
+    assert "--timeouts" in message
@@ -0,0 +1,90 @@
+# SPDX-FileCopyrightText: 2026 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import pydantic
+import pytest
+
+from sunbeam.core.common import CustomRole, Role
+from sunbeam.core.topology import Topology, topology_drift
+
+
+def _topology(*nodes: dict) -> Topology:
+    return Topology.model_validate({"nodes": list(nodes)})
+
+
+class TestTopologySchema:
+    def test_defaults(self):
+        topology = _topology({"name": "node-1.example.com."})
+
+        node = topology.nodes[0]
+        assert node.name == "node-1.example.com"
+        assert node.roles == ["control", "compute"]
+        assert node.labels == {}
+        assert node.address is None
+
+    def test_custom_roles(self):
+        topology = _topology({"name": "node-1", "roles": ["Compute", "gpu"]})
+
+        assert topology.nodes[0].role_list() == [Role.COMPUTE, CustomRole("gpu")]
+
+    def test_invalid_role(self):
+        with pytest.raises(pydantic.ValidationError, match="invalid role"):
+            _topology({"name": "node-1", "roles": ["not a role"]})
+
+    def test_invalid_label_key(self):
+        with pytest.raises(pydantic.ValidationError, match="invalid label key"):
+            _topology({"name": "node-1", "labels": {"Rack": "a"}})
+
+    def test_invalid_address(self):
+        with pytest.raises(pydantic.ValidationError):
+            _topology({"name": "node-1", "address": "not-an-ip"})
+
+    def test_duplicated_nodes(self):
+        with pytest.raises(pydantic.ValidationError, match="duplicated nodes"):
+            _topology({"name": "node-1"}, {"name": "node-1"})
+
+    def test_unknown_field(self):
+        with pytest.raises(pydantic.ValidationError):
+            _topology({"name": "node-1", "zone": "a"})
+
+    def test_load_invalid_file(self, tmp_path):
+        path = tmp_path / "topology.yaml"
+        path.write_text("nodes: []\n")
+
+        with pytest.raises(ValueError, match="Invalid topology"):
+            Topology.load(path)
+
+
+class TestTopologyDrift:
+    def test_drift(self):
+        topology = _topology(
+            {"name": "node-1", "labels": {"rack": "a"}, "address": "10.0.0.1"},
+            {"name": "node-2", "roles": ["compute"]},
+            {"name": "node-3"},
+        )
+        nodes = [
+            {"name": "node-1", "role": ["compute", "control"], "labels": {}},
+            {"name": "node-2", "role": ["storage"]},
+            {"name": "node-4", "role": ["compute"]},
+        ]
+        members = [{"name": "node-1", "address": "10.0.0.2:7000"}]
+
+        drift = topology_drift(topology, nodes, members)
+
+        assert [node.name for node in drift.missing] == ["node-3"]
+        assert drift.roles == {"node-2": (["compute"], ["storage"])}
+        assert drift.labels == {"node-1": {"rack": "a"}}
+        assert drift.addresses == {"node-1": ("10.0.0.1", "10.0.0.2")}
+        assert drift.extra == ["node-4"]
+        assert not drift.is_empty()
+
+    def test_no_drift(self):
+        topology = _topology(
+            {"name": "node-1", "labels": {"rack": "a"}, "address": "fd00::1"}
+        )
+        nodes = [
+            {"name": "node-1", "role": ["compute", "control"], "labels": {"rack": "a"}}
+        ]
+        members = [{"name": "node-1", "address": "[fd00::1]:7000"}]
+
+        assert topology_drift(topology, nodes, members).is_empty()
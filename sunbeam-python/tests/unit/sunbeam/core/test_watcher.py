@@ -169,6 +169,41 @@ def test_create_audit_failed(mock_check_audit_plan_recommended):
     )
 
 
+@patch("sunbeam.core.watcher._wait_resource_in_target_state")
+def test_create_audit_timeout(mock_wait_resource_in_target_state):
+    mock_client = Mock()
+    mock_audit = Mock()
+    mock_audit.uuid = "audit-1"
+    mock_client.audit.create.return_value = mock_audit
+    mock_wait_resource_in_target_state.side_effect = SunbeamException(
+        "audit audit-1 not in target state"
+    )
+
+    with pytest.raises(watcher_helper.WatcherAuditTimeoutException) as e:
+        watcher_helper.create_audit(mock_client, Mock())
+
+    assert e.value.audit == "audit-1"
+    assert "did not complete" in str(e.value)
+
+
+@patch("sunbeam.core.watcher._wait_resource_in_target_state")
+def test_create_audit_failed_status_message(mock_wait_resource_in_target_state):
+    mock_client = Mock()
+    mock_audit = Mock()
+    mock_audit.uuid = "audit-1"
+    mock_client.audit.create.return_value = mock_audit
+    mock_audit_detail = Mock()
+    mock_audit_detail.state = "FAILED"
+    mock_audit_detail.status_message = "No hypervisor can host the instances"
+    mock_wait_resource_in_target_state.return_value = mock_audit_detail
+
+    with pytest.raises(watcher_helper.WatcherAuditFailedException) as e:
+        watcher_helper.create_audit(mock_client, Mock())
+
+    assert e.value.audit == "audit-1"
+    assert str(e.value).endswith(": No hypervisor can host the instances")
+
+
 def test_check_audit_plans_recommended():
     mock_client = Mock()
     mock_audit = Mock()
@@ -208,6 +243,16 @@ def test_get_actions():
     )
 
 
+def test_get_action_plans():
+    mock_client = Mock()
+    mock_audit = Mock()
+    result = watcher_helper.get_action_plans(mock_client, mock_audit)
+    assert result == mock_client.action_plan.list.return_value
+    mock_client.action_plan.list.assert_called_once_with(
+        audit=mock_audit.uuid, detail=True
+    )
+
+
 @patch("sunbeam.core.watcher._exec_plan")
 def test_exec_audit(mock_exec_plan):
     mock_client = Mock()
@@ -212,6 +212,108 @@ class TestNoInstanceOnNodeCheck:
         )
 
 
+    def test_run_ignored(
+        self, mock_conn, mock_get_admin_connection, mock_guests_on_hypervisor
+    ):
+        instances = [Mock(), Mock()]
+        instances[0].id = "inst-0"
+        instances[1].id = "inst-1"
+        mock_guests_on_hypervisor.return_value = instances
+
+        check = checks.NoInstancesOnNodeCheck(
+            Mock(), Mock(), "node1", False, ignore={"inst-0"}
+        )
+        assert not check.run()
+        assert check.message == "Instances inst-1 still on node node1"
+
+        check = checks.NoInstancesOnNodeCheck(
+            Mock(), Mock(), "node1", False, ignore={"inst-0", "inst-1"}
+        )
+        assert check.run()
+
+
+class TestMigratableInstancesCheck:
+    @pytest.fixture
+    def mock_capacity(self, mocker):
+        return mocker.patch.object(
+            checks,
+            "hypervisors_free_capacity",
+            return_value={
+                "node2": {"VCPU": 8, "MEMORY_MB": 8192},
+                "node3": {"VCPU": 8, "MEMORY_MB": 8192},
+            },
+        )
+
+    @pytest.fixture
+    def mock_blockers(self, mocker):
+        return mocker.patch.object(checks, "instance_migration_blockers")
+
+    def test_run(
+        self,
+        mock_conn,
+        mock_get_admin_connection,
+        mock_guests_on_hypervisor,
+        mock_capacity,
+        mock_blockers,
+    ):
+        mock_blockers.return_value = []
+
+        check = checks.MigratableInstancesCheck(
+            Mock(), Mock(), "node1", False, exclude=["node3"]
+        )
+        assert check.run()
+        mock_capacity.assert_called_once_with(mock_conn, exclude="node1")
+        mock_blockers.assert_called_once_with(
+            mock_conn,
+            mock_guests_on_hypervisor.return_value,
+            {"node2": {"VCPU": 8, "MEMORY_MB": 8192}},
+        )
+
+    def test_run_failed(
+        self,
+        mock_conn,
+        mock_get_admin_connection,
+        mock_guests_on_hypervisor,
+        mock_capacity,
+        mock_blockers,
+    ):
+        blocked = [
+            {"instance": "gpu", "name": "vm-1", "reason": "PCI passthrough device"},
+            {"instance": "db", "name": "vm-2", "reason": "Anti-affinity"},
+        ]
+        mock_blockers.return_value = blocked
+
+        check = checks.MigratableInstancesCheck(Mock(), Mock(), "node1", False)
+        assert not check.run()
+        assert check.blocked == blocked
+        assert check.message.startswith(
+            "Instances cannot be migrated off node node1: gpu (PCI passthrough"
+            " device), db (Anti-affinity)"
+        )
+        assert "--skip-unmigratable" in check.message
+
+    @pytest.mark.parametrize("force,skip", [(True, False), (False, True)])
+    def test_run_ignored(
+        self,
+        mock_conn,
+        mock_get_admin_connection,
+        mock_guests_on_hypervisor,
+        mock_capacity,
+        mock_blockers,
+        force,
+        skip,
+    ):
+        mock_blockers.return_value = [
+            {"instance": "gpu", "name": "vm-1", "reason": "PCI passthrough device"}
+        ]
+
+        check = checks.MigratableInstancesCheck(
+            Mock(), Mock(), "node1", force, skip_unmigratable=skip
+        )
+        assert check.run()
+        assert [instance["instance"] for instance in check.blocked] == ["gpu"]
+
+
 class TestNovaInDisableStatusCheck:
     def test_run(self, mock_conn, mock_get_admin_connection):
         services = [Mock()]
@@ -1,11 +1,44 @@
 # SPDX-FileCopyrightText: 2026 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
-from unittest.mock import Mock, patch
+import io
+import json
+from unittest.mock import ANY, MagicMock, Mock, patch
 
+import click
 import pytest
 
-from sunbeam.features.maintenance.commands import EnableMaintenance, enable
+from sunbeam.clusterd.models import (
+    MaintenanceOptions,
+    MaintenancePlan,
+    MaintenancePlanAction,
+    ScheduledMaintenance,
+)
+from sunbeam.clusterd.service import (
+    ClusterServiceUnavailableException,
+    ConfigItemNotFoundException,
+    NodeNotExistInClusterException,
+    ScheduledMaintenanceConflictException,
+)
+from sunbeam.core.common import Result, ResultType
+from sunbeam.core.openstack_api import InstanceMigrationFailedException
+from sunbeam.features.maintenance.commands import (
+    DisableMaintenance,
+    EnableMaintenance,
+    batch_no_capacity,
+    cancel_schedule,
+    drain,
+    enable,
+    get_max_parallel_migrations,
+    list_schedule,
+    record_maintenance_status,
+    run_maintenance_batch,
+    show_plan,
+    validate_at,
+    validate_ttl,
+    validate_window,
+    watcher_actions_step,
+)
 
 
 class TestDisableMigrationFlagMapping:
@@ -51,7 +84,7 @@ class TestDisableMigrationFlagMapping:
             with patch("click.get_current_context", return_value=mock_ctx):
                 enable.callback(
                     None,  # self (from pass_method_obj)
-                    node="test-node",
+                    nodes=("test-node",),
                     force=False,
                     dry_run=True,
                     enable_ceph_crush_rebalancing=False,
@@ -109,6 +142,9 @@ class TestEnableMaintenance:
             patch("sunbeam.features.maintenance.commands.run_plan") as mock_run_plan,
             patch("sunbeam.features.maintenance.commands.JujuHelper"),
             patch("sunbeam.features.maintenance.commands.OperationViewer"),
+            patch("sunbeam.features.maintenance.commands.get_admin_connection"),
+            patch("sunbeam.features.maintenance.commands.guests_on_hypervisor"),
+            patch("sunbeam.features.maintenance.commands.hypervisors_free_capacity"),
         ):
             # Set up mock class name for get_step_message
             mock_create_watcher_step.__name__ = "CreateWatcherHostMaintenanceAuditStep"
@@ -142,3 +178,764 @@ class TestEnableMaintenance:
                 disable_live_migration=disable_live_migration,
                 disable_cold_migration=disable_cold_migration,
             )
+
+
+    def test_dry_run_json_reports_no_capacity(self, mock_deployment, cluster_status):
+        instance = Mock(id="inst-1", flavor=Mock(vcpus=4, ram=8192))
+        instance.name = "vm-1"
+        action = Mock(
+            action_type="migrate",
+            input_parameters={
+                "resource_id": "inst-1",
+                "resource_name": "vm-1",
+                "migration_type": "live",
+            },
+        )
+        with (
+            patch(
+                "sunbeam.features.maintenance.commands.CreateWatcherHostMaintenanceAuditStep"
+            ) as mock_create_watcher_step,
+            patch("sunbeam.features.maintenance.commands.run_plan") as mock_run_plan,
+            patch("sunbeam.features.maintenance.commands.JujuHelper"),
+            patch("sunbeam.features.maintenance.commands.get_admin_connection"),
+            patch(
+                "sunbeam.features.maintenance.commands.guests_on_hypervisor",
+                return_value=[instance],
+            ),
+            patch(
+                "sunbeam.features.maintenance.commands.hypervisors_free_capacity",
+                return_value={"node-2": {"VCPU": 2, "MEMORY_MB": 4096}},
+            ),
+        ):
+            mock_create_watcher_step.__name__ = "CreateWatcherHostMaintenanceAuditStep"
+            mock_run_plan.return_value = {
+                "CreateWatcherHostMaintenanceAuditStep": Result(
+                    ResultType.COMPLETED, {"audit": Mock(), "actions": [action]}
+                )
+            }
+            enable_maintenance = EnableMaintenance(
+                node="test-node",
+                deployment=mock_deployment,
+                cluster_status=cluster_status,
+                output_format="json",
+            )
+            mock_console = Mock()
+
+            enable_maintenance.dry_run(mock_console, show_hints=False)
+
+        plan = json.loads(mock_console.print.call_args.args[0])
+        assert plan["node"] == "test-node"
+        assert not plan["ready"]
+        assert plan["migrations"] == []
+        assert plan["unmigratable"][0]["instance"] == "inst-1"
+        assert plan["unmigratable"][0]["reason"].startswith(
+            "No target host with capacity"
+        )
+        assert plan["capacity"] == {
+            "sufficient": False,
+            "hosts": {"node-2": {"vcpus": 2, "memory_mb": 4096}},
+        }
+        assert plan["operations"] == [
+            "Migrate instance type=live resource=vm-1",
+        ]
+
+    def test_check_json_prints_plan_on_failure(self, mock_deployment):
+        with patch("sunbeam.features.maintenance.commands.JujuHelper"):
+            enable_maintenance = EnableMaintenance(
+                node="unknown-node",
+                deployment=mock_deployment,
+                cluster_status={"test-node": "compute"},
+                output_format="json",
+            )
+        mock_console = MagicMock()
+
+        with pytest.raises(click.ClickException):
+            enable_maintenance.check(mock_console)
+
+        plan = json.loads(mock_console.print.call_args.args[0])
+        assert not plan["ready"]
+        assert [check["passed"] for check in plan["checks"]] == [False]
+
+    def test_apply_no_capacity_fails_before_prompt(
+        self, mock_deployment, cluster_status
+    ):
+        with patch("sunbeam.features.maintenance.commands.JujuHelper"):
+            enable_maintenance = EnableMaintenance(
+                node="test-node",
+                deployment=mock_deployment,
+                cluster_status=cluster_status,
+            )
+        enable_maintenance.ops_viewer = Mock()
+        enable_maintenance.unmigratable = [
+            {
+                "instance": "inst-1",
+                "name": "vm-1",
+                "reason": "No target host with capacity for 4 vCPUs",
+            }
+        ]
+
+        with pytest.raises(click.ClickException, match="inst-1"):
+            enable_maintenance.apply(Mock(), False, {})
+        enable_maintenance.ops_viewer.prompt.assert_not_called()
+
+
+    def test_apply_skip_unmigratable(self, mock_deployment, cluster_status):
+        with patch("sunbeam.features.maintenance.commands.JujuHelper"):
+            enable_maintenance = EnableMaintenance(
+                node="test-node",
+                deployment=mock_deployment,
+                cluster_status=cluster_status,
+                max_parallel_migrations=0,
+                yes=True,
+                skip_unmigratable=True,
+            )
+        enable_maintenance.ops_viewer = Mock()
+        enable_maintenance.unmigratable = [
+            {"instance": "gpu", "name": "vm-1", "reason": "PCI passthrough device"},
+            {
+                "instance": "large",
+                "name": "vm-2",
+                "reason": "No target host with capacity for 4 vCPUs",
+            },
+        ]
+        audit_info = {"audit": Mock(), "actions": []}
+
+        with (
+            patch(
+                "sunbeam.features.maintenance.commands.get_step_message",
+                return_value=audit_info,
+            ),
+            patch(
+                "sunbeam.features.maintenance.commands.watcher_actions_step"
+            ) as mock_step,
+            patch("sunbeam.features.maintenance.commands.run_plan"),
+            patch("sunbeam.features.maintenance.commands.record_maintenance_status"),
+        ):
+            enable_maintenance.apply(Mock(), False, {})
+
+        assert mock_step.call_args.kwargs["skip"] == {"gpu", "large"}
+        assert enable_maintenance.plan["unmigratable"][0]["instance"] == "gpu"
+
+
+def test_enable_json_requires_dry_run():
+    mock_ctx = Mock()
+    mock_ctx.obj = Mock()
+    with patch("click.get_current_context", return_value=mock_ctx):
+        with pytest.raises(click.UsageError, match="--dry-run"):
+            enable.callback(
+                None,
+                nodes=("test-node",),
+                force=False,
+                dry_run=False,
+                enable_ceph_crush_rebalancing=False,
+                stop_osds=False,
+                allow_downtime=False,
+                disable_migration=None,
+                format="json",
+            )
+
+class TestDisableMaintenanceCordon:
+    """Test a cordoned node stays cordoned once out of maintenance."""
+
+    def _apply(self, cordoned: bool, uncordon: bool):
+        deployment = Mock()
+        deployment.openstack_machines_model = "test-model"
+        client = deployment.get_client.return_value
+        client.cluster.get_node_info.return_value = {
+            "name": "test-node",
+            "cordoned": cordoned,
+        }
+        with (
+            patch("sunbeam.features.maintenance.commands.JujuHelper"),
+            patch("sunbeam.features.maintenance.commands.OperationViewer"),
+            patch("sunbeam.features.maintenance.commands.run_preflight_checks"),
+            patch("sunbeam.features.maintenance.commands.run_plan") as run_plan,
+            patch("sunbeam.features.maintenance.commands.record_maintenance_status"),
+        ):
+            disable_maintenance = DisableMaintenance(
+                "test-node",
+                deployment,
+                {"test-node": "compute"},
+                disable_instance_rebalancing=True,
+                yes=True,
+                uncordon=uncordon,
+            )
+            disable_maintenance.check(Mock())
+            disable_maintenance.apply(Mock(), False, {})
+        plan = [type(step).__name__ for step in run_plan.call_args.args[0]]
+        return plan, client
+
+    def test_not_cordoned(self):
+        plan, client = self._apply(cordoned=False, uncordon=False)
+
+        assert plan == ["EnableHypervisorStep"]
+        client.cluster.set_node_cordon.assert_not_called()
+
+    def test_cordoned_kept(self):
+        plan, client = self._apply(cordoned=True, uncordon=False)
+
+        assert plan == []
+        client.cluster.set_node_cordon.assert_not_called()
+
+    def test_cordoned_uncordon(self):
+        plan, client = self._apply(cordoned=True, uncordon=True)
+
+        assert plan == ["EnableHypervisorStep"]
+        client.cluster.set_node_cordon.assert_called_once_with("test-node", False)
+
+
+class TestRecordMaintenanceStatus:
+    """Test persisting the outcome of maintenance operations."""
+
+    @pytest.fixture(autouse=True)
+    def mock_actor(self):
+        with patch(
+            "sunbeam.features.maintenance.commands.maintenance_actor",
+            return_value="ubuntu@node-1",
+        ):
+            yield
+
+    def test_all_steps_completed(self):
+        client = Mock()
+        results = {
+            "RunWatcherAuditStep": Result(ResultType.COMPLETED),
+            "MicroCephActionStep": Result(ResultType.COMPLETED),
+        }
+
+        record_maintenance_status(
+            client, "node-2", "enabled", results, strategy="stop-osds"
+        )
+
+        client.cluster.update_maintenance_status.assert_called_once_with(
+            "node-2",
+            "enabled",
+            strategy="stop-osds",
+            triggered_by="ubuntu@node-1",
+            message="",
+            ttl="",
+        )
+
+    def test_partially_failed_is_degraded(self):
+        client = Mock()
+        results = {
+            "RunWatcherAuditStep": Result(ResultType.COMPLETED),
+            "MicroCephActionStep": Result(ResultType.FAILED, "osd busy"),
+        }
+
+        record_maintenance_status(client, "node-2", "enabled", results)
+
+        client.cluster.update_maintenance_status.assert_called_once_with(
+            "node-2",
+            "degraded",
+            strategy="",
+            triggered_by="ubuntu@node-1",
+            message="Failed steps: MicroCephActionStep",
+            ttl="",
+        )
+
+    def test_record_failure_does_not_raise(self):
+        client = Mock()
+        client.cluster.update_maintenance_status.side_effect = (
+            ClusterServiceUnavailableException("unavailable")
+        )
+
+        record_maintenance_status(client, "node-2", "disabled", {})
+
+        client.cluster.update_maintenance_status.assert_called_once()
+
+    def test_record_ttl(self):
+        client = Mock()
+
+        record_maintenance_status(client, "node-2", "enabled", {}, ttl="2h")
+
+        client.cluster.update_maintenance_status.assert_called_once_with(
+            "node-2",
+            "enabled",
+            strategy="",
+            triggered_by="ubuntu@node-1",
+            message="",
+            ttl="2h",
+        )
+
+
+class TestValidateTTL:
+    """Test validation of the maintenance TTL option."""
+
+    @pytest.mark.parametrize("ttl", ["2h", "1h30m", "90s", None])
+    def test_valid(self, ttl):
+        assert validate_ttl(Mock(), Mock(), ttl) == ttl
+
+    @pytest.mark.parametrize("ttl", ["0h", "2", "2d", "-1h", "h"])
+    def test_invalid(self, ttl):
+        with pytest.raises(click.BadParameter):
+            validate_ttl(Mock(), Mock(), ttl)
+
+
+class TestValidateWindow:
+    """Test validation of the scheduled maintenance window options."""
+
+    def test_at_normalized_to_utc(self):
+        at = validate_at(Mock(), Mock(), "2025-07-01T04:00:00+02:00")
+        assert at == "2025-07-01T02:00:00Z"
+
+    @pytest.mark.parametrize("at", ["tonight", "2025-07-01T02:00:00"])
+    def test_invalid_at(self, at):
+        with pytest.raises(click.BadParameter):
+            validate_at(Mock(), Mock(), at)
+
+    def test_window(self):
+        window = validate_window(
+            Mock(), Mock(), "2025-07-01T02:00:00Z/2025-07-01T04:00:00Z"
+        )
+        assert window == ("2025-07-01T02:00:00Z", "2025-07-01T04:00:00Z")
+
+    @pytest.mark.parametrize(
+        "window", ["2025-07-01T02:00:00Z", "2025-07-01T02:00:00Z/later"]
+    )
+    def test_invalid_window(self, window):
+        with pytest.raises(click.BadParameter):
+            validate_window(Mock(), Mock(), window)
+
+
+class TestScheduleMaintenance:
+    """Test scheduling maintenance in a change window."""
+
+    @pytest.fixture
+    def cluster(self):
+        mock_ctx = Mock()
+        mock_ctx.obj = Mock()
+        cluster = mock_ctx.obj.get_client.return_value.cluster
+        cluster.schedule_maintenance.side_effect = lambda scheduled: (
+            scheduled.model_copy(update={"id": 1})
+        )
+        with patch("click.get_current_context", return_value=mock_ctx):
+            yield cluster
+
+    @staticmethod
+    def _enable(**kwargs):
+        options = {
+            "nodes": ("node-1",),
+            "force": False,
+            "dry_run": False,
+            "enable_ceph_crush_rebalancing": False,
+            "stop_osds": False,
+            "allow_downtime": False,
+            "disable_migration": None,
+            **kwargs,
+        }
+        enable.callback(None, **options)
+
+    def test_at_schedules_without_running(self, cluster):
+        with patch(
+            "sunbeam.features.maintenance.commands.get_cluster_status"
+        ) as mock_status:
+            self._enable(
+                at="2025-07-01T02:00:00Z",
+                stop_osds=True,
+                disable_migration="cold",
+                max_parallel_migrations=2,
+            )
+
+        mock_status.assert_not_called()
+        cluster.schedule_maintenance.assert_called_once_with(
+            ScheduledMaintenance(
+                node="node-1",
+                window_start="2025-07-01T02:00:00Z",
+                options=MaintenanceOptions(
+                    stop_osds=True, disable_migration="cold", max_parallel_migrations=2
+                ),
+            )
+        )
+
+    def test_window(self, cluster):
+        self._enable(window=("2025-07-01T02:00:00Z", "2025-07-01T04:00:00Z"))
+
+        scheduled = cluster.schedule_maintenance.call_args.args[0]
+        assert (scheduled.window_start, scheduled.window_end) == (
+            "2025-07-01T02:00:00Z",
+            "2025-07-01T04:00:00Z",
+        )
+
+    @pytest.mark.parametrize(
+        "kwargs",
+        [
+            {"nodes": ("node-1", "node-2")},
+            {"nodes": (), "all_compute": True},
+            {"dry_run": True},
+            {"window": ("2025-07-01T02:00:00Z", "2025-07-01T04:00:00Z")},
+        ],
+    )
+    def test_invalid_usage(self, cluster, kwargs):
+        with pytest.raises(click.UsageError):
+            self._enable(at="2025-07-01T02:00:00Z", **kwargs)
+        cluster.schedule_maintenance.assert_not_called()
+
+    def test_conflict(self, cluster):
+        cluster.schedule_maintenance.side_effect = (
+            ScheduledMaintenanceConflictException(
+                'Node "node-1" already has pending scheduled maintenance 1'
+            )
+        )
+        with pytest.raises(click.ClickException, match="already has pending"):
+            self._enable(at="2025-07-01T02:00:00Z")
+
+    def test_list(self, cluster):
+        cluster.list_scheduled_maintenances.return_value = [
+            ScheduledMaintenance(
+                id=1,
+                node="node-1",
+                window_start="2025-07-01T02:00:00Z",
+                status="failed",
+                message="Node node-1 hosts the last ceph monitor",
+            )
+        ]
+        stdout = io.StringIO()
+        with patch("sys.stdout", stdout):
+            list_schedule.callback(None, format="json")
+
+        assert json.loads(stdout.getvalue())[0]["status"] == "failed"
+
+    def test_cancel(self, cluster):
+        cancel_schedule.callback(None, id=1)
+        cluster.cancel_scheduled_maintenance.assert_called_once_with(1)
+
+    def test_cancel_not_pending(self, cluster):
+        cluster.cancel_scheduled_maintenance.side_effect = (
+            ScheduledMaintenanceConflictException(
+                "Scheduled maintenance 1 is succeeded, only pending ones can be"
+                " cancelled"
+            )
+        )
+        with pytest.raises(click.ClickException, match="only pending"):
+            cancel_schedule.callback(None, id=1)
+
+
+class TestMaxParallelMigrations:
+    """Test the limit of instance migrations run at once."""
+
+    def test_option_takes_precedence(self):
+        client = Mock()
+        client.cluster.get_config.return_value = "4"
+
+        assert get_max_parallel_migrations(client, 2) == 2
+        assert get_max_parallel_migrations(client, 0) == 0
+        client.cluster.get_config.assert_not_called()
+
+    def test_config(self):
+        client = Mock()
+        client.cluster.get_config.return_value = "4"
+
+        assert get_max_parallel_migrations(client) == 4
+        client.cluster.get_config.assert_called_once_with(
+            "maintenance.max-parallel-migrations"
+        )
+
+    def test_not_configured(self):
+        client = Mock()
+        client.cluster.get_config.side_effect = ConfigItemNotFoundException("nope")
+
+        assert get_max_parallel_migrations(client) == 0
+
+    @pytest.mark.parametrize(
+        "limit,action_type,expected",
+        [
+            (0, "migrate", "RunWatcherAuditStep"),
+            (2, "migrate", "RunWatcherActionsStep"),
+            (2, "stop", "RunWatcherAuditStep"),
+        ],
+    )
+    def test_watcher_actions_step(self, limit, action_type, expected):
+        audit_info = {"audit": Mock(), "actions": [Mock(action_type=action_type)]}
+        with (
+            patch(
+                "sunbeam.features.maintenance.commands.RunWatcherAuditStep"
+            ) as mock_audit_step,
+            patch(
+                "sunbeam.features.maintenance.commands.RunWatcherActionsStep"
+            ) as mock_actions_step,
+        ):
+            mock_actions_step.can_run.side_effect = lambda actions: all(
+                action.action_type == "migrate" for action in actions
+            )
+            step = watcher_actions_step(Mock(), Mock(), "node-1", audit_info, limit)
+
+        steps = {
+            "RunWatcherAuditStep": mock_audit_step.return_value,
+            "RunWatcherActionsStep": mock_actions_step.return_value,
+        }
+        assert step is steps[expected]
+
+
+    def test_watcher_actions_step_skip(self):
+        actions = [
+            Mock(action_type="change_nova_service_state"),
+            Mock(action_type="migrate", input_parameters={"resource_id": "inst-1"}),
+            Mock(action_type="migrate", input_parameters={"resource_id": "inst-2"}),
+        ]
+        audit_info = {"audit": Mock(), "actions": actions}
+        with patch(
+            "sunbeam.features.maintenance.commands.RunWatcherActionsStep"
+        ) as mock_actions_step:
+            mock_actions_step.can_run.return_value = True
+            step = watcher_actions_step(
+                Mock(), Mock(), "node-1", audit_info, 0, skip={"inst-2"}
+            )
+
+        assert step is mock_actions_step.return_value
+        kwargs = mock_actions_step.call_args.kwargs
+        assert kwargs["actions"] == actions[:2]
+        assert kwargs["max_parallel"] == 2
+
+    def test_watcher_actions_step_skip_not_runnable(self):
+        audit_info = {"audit": Mock(), "actions": [Mock(action_type="stop")]}
+        with patch(
+            "sunbeam.features.maintenance.commands.RunWatcherActionsStep"
+        ) as mock_actions_step:
+            mock_actions_step.can_run.return_value = False
+            with pytest.raises(click.ClickException, match="inst-1"):
+                watcher_actions_step(
+                    Mock(), Mock(), "node-1", audit_info, 0, skip={"inst-1"}
+                )
+
+
+class TestRunMaintenanceBatch:
+    @staticmethod
+    def _run_node(failing):
+        ran = []
+
+        def run_node(node, console):
+            ran.append(node)
+            if node in failing:
+                raise click.ClickException(f"{node} failed")
+
+        return ran, run_node
+
+    def test_skips_after_failure(self):
+        ran, run_node = self._run_node({"node-2"})
+
+        results = run_maintenance_batch(["node-1", "node-2", "node-3"], run_node)
+
+        assert ran == ["node-1", "node-2"]
+        assert results == {
+            "node-1": {"status": "done", "error": ""},
+            "node-2": {"status": "failed", "error": "node-2 failed"},
+            "node-3": {"status": "skipped", "error": ""},
+        }
+
+    def test_continue_on_error(self):
+        ran, run_node = self._run_node({"node-1"})
+
+        results = run_maintenance_batch(
+            ["node-1", "node-2"], run_node, continue_on_error=True
+        )
+
+        assert ran == ["node-1", "node-2"]
+        assert results["node-1"]["status"] == "failed"
+        assert results["node-2"]["status"] == "done"
+
+    def test_parallel(self):
+        ran, run_node = self._run_node(set())
+
+        results = run_maintenance_batch(["node-1", "node-2", "node-3"], run_node, 2)
+
+        assert sorted(ran) == ["node-1", "node-2", "node-3"]
+        assert all(result["status"] == "done" for result in results.values())
+
+
+class TestBatchNoCapacity:
+    @pytest.fixture
+    def openstack_api(self):
+        with (
+            patch("sunbeam.features.maintenance.commands.get_admin_connection"),
+            patch(
+                "sunbeam.features.maintenance.commands.guests_on_hypervisor"
+            ) as guests,
+            patch(
+                "sunbeam.features.maintenance.commands.hypervisors_free_capacity"
+            ) as capacity,
+        ):
+            yield guests, capacity
+
+    @staticmethod
+    def _instance(id, vcpus, ram):
+        return Mock(id=id, flavor=Mock(vcpus=vcpus, ram=ram))
+
+    def test_compute_nodes_share_capacity(self, openstack_api):
+        guests, capacity = openstack_api
+        guests.side_effect = lambda hypervisor_name, conn: [
+            self._instance(f"{hypervisor_name}-inst", 2, 2048)
+        ]
+        capacity.return_value = {
+            "node-1": {"VCPU": 8, "MEMORY_MB": 8192},
+            "node-2": {"VCPU": 8, "MEMORY_MB": 8192},
+            "node-4": {"VCPU": 2, "MEMORY_MB": 2048},
+        }
+        cluster_status = {
+            "node-1": "compute",
+            "node-2": "compute,storage",
+            "node-3": "control",
+        }
+
+        no_capacity = batch_no_capacity(
+            Mock(), Mock(), cluster_status, ["node-1", "node-2", "node-3"]
+        )
+
+        assert list(no_capacity) == ["node-2"]
+        assert no_capacity["node-2"][0]["instance"] == "node-2-inst"
+
+    def test_no_compute_nodes(self, openstack_api):
+        guests, _ = openstack_api
+
+        no_capacity = batch_no_capacity(
+            Mock(), Mock(), {"node-1": "control"}, ["node-1"]
+        )
+
+        assert no_capacity == {}
+        guests.assert_not_called()
+
+
+class TestDrain:
+    @pytest.fixture
+    def conn(self):
+        with (
+            patch("sunbeam.features.maintenance.commands.JujuHelper"),
+            patch(
+                "sunbeam.features.maintenance.commands.get_admin_connection"
+            ) as mock_conn,
+            patch(
+                "sunbeam.features.maintenance.commands.guests_on_hypervisor",
+                return_value=[Mock(id="inst-1"), Mock(id="inst-4")],
+            ),
+            patch("sunbeam.steps.maintenance.get_admin_connection"),
+        ):
+            yield mock_conn.return_value
+
+    @staticmethod
+    def _drain(**kwargs):
+        mock_ctx = Mock()
+        mock_ctx.obj = Mock()
+        cluster = mock_ctx.obj.get_client.return_value.cluster
+        cluster.get_config.side_effect = ConfigItemNotFoundException(
+            "ConfigItem not found"
+        )
+        cluster.add_maintenance_progress.side_effect = lambda node, events: events
+        with patch("click.get_current_context", return_value=mock_ctx):
+            drain.callback(None, node="node-1", **kwargs)
+
+    def test_mixed_valid_and_invalid_instances(self, conn):
+        servers = {"inst-2": Mock(hypervisor_hostname="node-2"), "inst-3": None}
+        conn.compute.find_server.side_effect = lambda id, **kwargs: servers[id]
+
+        with patch("sunbeam.steps.maintenance.migrate_instance") as mock_migrate:
+            with pytest.raises(
+                click.ClickException,
+                match=r"inst-2 \(on node-2\), inst-3 \(not found\)",
+            ):
+                self._drain(instances=("inst-1", "inst-2", "inst-3"))
+
+        mock_migrate.assert_not_called()
+
+    def test_migrates_named_instances(self, conn):
+        with patch(
+            "sunbeam.steps.maintenance.migrate_instance", return_value="node-2"
+        ) as mock_migrate:
+            self._drain(instances=("inst-1",))
+
+        mock_migrate.assert_called_once_with(
+            ANY,
+            "inst-1",
+            "node-1",
+            migration_type="live",
+            destination=None,
+            progress=ANY,
+        )
+
+    def test_json_stream(self, conn):
+        def migrate(conn, instance_id, source, progress=None, **kwargs):
+            progress(30)
+            return "node-2"
+
+        stdout = io.StringIO()
+        with (
+            patch("sunbeam.steps.maintenance.migrate_instance", side_effect=migrate),
+            patch("sys.stdout", stdout),
+        ):
+            self._drain(instances=("inst-1",), format="json-stream")
+
+        lines = [json.loads(line) for line in stdout.getvalue().splitlines()]
+        assert [(line["state"], line.get("percent")) for line in lines] == [
+            ("migrating", None),
+            ("migrating", 30),
+            ("succeeded", None),
+        ]
+
+    def test_failed_migration(self, conn):
+        with patch(
+            "sunbeam.steps.maintenance.migrate_instance",
+            side_effect=InstanceMigrationFailedException("migration is error"),
+        ):
+            with pytest.raises(click.ClickException, match="inst-4 off node-1"):
+                self._drain(instances=("inst-1", "inst-4"))
+
+
+class TestShowPlan:
+    """Test showing the Watcher plans of the maintenance operations."""
+
+    @pytest.fixture
+    def cluster(self):
+        mock_ctx = Mock()
+        mock_ctx.obj = Mock()
+        cluster = mock_ctx.obj.get_client.return_value.cluster
+        cluster.get_maintenance_plans.return_value = [
+            MaintenancePlan(
+                node="node-1",
+                operation="disable",
+                audit="audit-2",
+                state="timed-out",
+                reason="Watcher audit audit-2 did not complete in 180s",
+            ),
+            MaintenancePlan(
+                node="node-1",
+                operation="enable",
+                audit="audit-1",
+                action_plan="plan-1",
+                state="succeeded",
+                actions=[
+                    MaintenancePlanAction(
+                        uuid="action-1",
+                        type="migrate",
+                        state="PENDING",
+                        parameters={"resource_id": "inst-1"},
+                        description="Moving a VM instance",
+                    )
+                ],
+            ),
+        ]
+        with patch("click.get_current_context", return_value=mock_ctx):
+            yield cluster
+
+    def test_show_json(self, cluster):
+        stdout = io.StringIO()
+        with patch("sys.stdout", stdout):
+            show_plan.callback(None, node="node-1", operation="enable", format="json")
+
+        cluster.get_maintenance_plans.assert_called_once_with("node-1", "enable")
+        plans = json.loads(stdout.getvalue())
+        assert plans[0]["reason"].endswith("did not complete in 180s")
+        assert plans[1]["actions"][0]["description"] == "Moving a VM instance"
+
+    def test_show_table(self, cluster):
+        with patch("sunbeam.features.maintenance.commands.console") as console:
+            show_plan.callback(None, node="node-1", operation=None, format="table")
+
+        printed = [str(call.args[0]) for call in console.print.call_args_list]
+        assert "Reason: Watcher audit audit-2 did not complete in 180s" in printed
+        # Only the enable plan has actions
+        assert console.print.call_count == 4
+
+    def test_show_unknown_node(self, cluster):
+        cluster.get_maintenance_plans.side_effect = NodeNotExistInClusterException(
+            "Node not found"
+        )
+        with pytest.raises(click.ClickException, match="Node not found"):
+            show_plan.callback(None, node="node-9", operation=None, format="table")
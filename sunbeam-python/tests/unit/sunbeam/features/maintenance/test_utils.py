@@ -1,16 +1,27 @@
 # SPDX-FileCopyrightText: 2024 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
+import io
+import json
 from os import linesep
 from unittest.mock import Mock, PropertyMock, call, patch
 
 import click
 import pytest
+from rich.console import Console
 
+from sunbeam.clusterd.models import MaintenanceProgress
+from sunbeam.clusterd.service import URLNotFoundException
 from sunbeam.core.common import ResultType
 from sunbeam.features.maintenance.utils import (
+    MigrationProgressRenderer,
+    MigrationProgressReporter,
     OperationGoal,
     OperationViewer,
+    follow_migration_progress,
     get_cluster_status,
+    instance_migration_blockers,
+    plan_batch_capacity,
+    plan_instance_migrations,
 )
 from sunbeam.steps.hypervisor import EnableHypervisorStep
 from sunbeam.steps.maintenance import (
@@ -391,3 +402,421 @@ class TestOperationViewer:
         result.message = {"errors": "fake-err-msg"}
         with pytest.raises(click.ClickException, match="fake-err-msg"):
             viewer._raise_exception(MicroCephActionStep.__name__, result)
+
+
+def _instance(id, vcpus, ram):
+    instance = Mock(id=id, flavor=Mock(vcpus=vcpus, ram=ram))
+    instance.name = f"name-{id}"
+    return instance
+
+
+def _migrate_action(id, destination=None):
+    parameters = {"resource_id": id, "migration_type": "live"}
+    if destination:
+        parameters["destination_node"] = destination
+    return Mock(action_type="migrate", input_parameters=parameters)
+
+
+class TestPlanInstanceMigrations:
+    def test_fits_largest_first(self):
+        instances = [_instance("small", 1, 1024), _instance("large", 4, 8192)]
+        actions = [_migrate_action("small"), _migrate_action("large")]
+        capacity = {
+            "node-2": {"VCPU": 4, "MEMORY_MB": 8192},
+            "node-3": {"VCPU": 2, "MEMORY_MB": 2048},
+        }
+
+        migrations, unmigratable = plan_instance_migrations(
+            instances, actions, capacity
+        )
+
+        assert [(m["instance"], m["destination"]) for m in migrations] == [
+            ("large", "node-2"),
+            ("small", "node-3"),
+        ]
+        assert all(m["scheduled"] for m in migrations)
+        assert unmigratable == []
+        # The capacity of the caller is left untouched
+        assert capacity["node-2"] == {"VCPU": 4, "MEMORY_MB": 8192}
+
+    def test_no_target_host_with_capacity(self):
+        instances = [_instance("large", 4, 8192)]
+        capacity = {"node-2": {"VCPU": 8, "MEMORY_MB": 4096}}
+
+        migrations, unmigratable = plan_instance_migrations(
+            instances, [_migrate_action("large")], capacity
+        )
+
+        assert migrations == []
+        assert unmigratable == [
+            {
+                "instance": "large",
+                "name": "name-large",
+                "reason": (
+                    "No target host with capacity for 4 vCPUs and 8192 MiB of memory"
+                ),
+            }
+        ]
+
+    def test_watcher_destination(self):
+        instances = [_instance("inst", 1, 1024)]
+        capacity = {
+            "node-2": {"VCPU": 8, "MEMORY_MB": 8192},
+            "node-3": {"VCPU": 1, "MEMORY_MB": 1024},
+        }
+
+        migrations, _ = plan_instance_migrations(
+            instances, [_migrate_action("inst", destination="node-3")], capacity
+        )
+
+        assert migrations[0]["destination"] == "node-3"
+        assert not migrations[0]["scheduled"]
+
+    def test_not_planned_by_watcher(self):
+        migrations, unmigratable = plan_instance_migrations(
+            [_instance("inst", 1, 1024)], [], {}
+        )
+
+        assert migrations == []
+        assert unmigratable[0]["reason"] == (
+            "Watcher planned no migration of the instance"
+        )
+
+    def test_unknown_capacity(self):
+        migrations, unmigratable = plan_instance_migrations(
+            [_instance("inst", 1, 1024)], [_migrate_action("inst")], None
+        )
+
+        assert migrations[0]["destination"] is None
+        assert unmigratable == []
+
+
+def _constrained_instance(id, vcpus=2, extra_specs=None):
+    instance = _instance(id, vcpus, 2048)
+    instance.flavor.extra_specs = extra_specs or {}
+    return instance
+
+
+def _server_group(name, policy, member_ids):
+    group = Mock(policy=policy, member_ids=member_ids)
+    group.name = name
+    return group
+
+
+class TestInstanceMigrationBlockers:
+    @pytest.fixture
+    def conn(self):
+        conn = Mock()
+        conn.compute.server_groups.return_value = []
+        conn.network.ports.return_value = []
+        return conn
+
+    def test_migratable(self, conn):
+        capacity = {"node-2": {"VCPU": 8, "MEMORY_MB": 8192}}
+
+        blockers = instance_migration_blockers(
+            conn, [_constrained_instance("inst")], capacity
+        )
+
+        assert blockers == []
+
+    def test_pci_passthrough(self, conn):
+        instance = _constrained_instance(
+            "gpu", extra_specs={"pci_passthrough:alias": "a100:1"}
+        )
+
+        blockers = instance_migration_blockers(conn, [instance], None)
+
+        assert blockers == [
+            {
+                "instance": "gpu",
+                "name": "name-gpu",
+                "reason": "PCI passthrough device a100:1",
+            }
+        ]
+
+    def test_sriov_port(self, conn):
+        ports = {
+            "sriov": [
+                Mock(id="port-1", binding_vnic_type="direct"),
+                Mock(id="port-2", binding_vnic_type="normal"),
+            ]
+        }
+        conn.network.ports.side_effect = lambda device_id: ports.get(device_id, [])
+        instances = [_constrained_instance("sriov"), _constrained_instance("virtio")]
+
+        blockers = instance_migration_blockers(conn, instances, None)
+
+        assert [(b["instance"], b["reason"]) for b in blockers] == [
+            ("sriov", "SR-IOV ports port-1")
+        ]
+
+    def test_pinned_without_compatible_target(self, conn):
+        instance = _constrained_instance(
+            "pinned", vcpus=4, extra_specs={"hw:cpu_policy": "dedicated"}
+        )
+        capacity = {
+            "node-2": {"VCPU": 16, "PCPU": 2, "MEMORY_MB": 8192},
+            "node-3": {"VCPU": 16, "MEMORY_MB": 8192},
+        }
+
+        blockers = instance_migration_blockers(conn, [instance], capacity)
+
+        assert blockers[0]["reason"] == "No target host with 4 free dedicated CPUs"
+
+    def test_pinned_with_compatible_target(self, conn):
+        instance = _constrained_instance(
+            "pinned", vcpus=4, extra_specs={"hw:cpu_policy": "dedicated"}
+        )
+        capacity = {"node-2": {"VCPU": 16, "PCPU": 4, "MEMORY_MB": 8192}}
+
+        assert instance_migration_blockers(conn, [instance], capacity) == []
+
+    def test_anti_affinity(self, conn):
+        conn.compute.server_groups.return_value = [
+            _server_group("db", "anti-affinity", ["db-1", "db-2", "db-3"]),
+            _server_group("web", "affinity", ["web-1", "web-2"]),
+        ]
+        hosts = {"db-2": "node-2", "db-3": "node-3", "web-2": "node-2"}
+        conn.compute.get_server.side_effect = lambda id: Mock(
+            hypervisor_hostname=hosts[id]
+        )
+        capacity = {
+            "node-2": {"VCPU": 8, "MEMORY_MB": 8192},
+            "node-3": {"VCPU": 8, "MEMORY_MB": 8192},
+        }
+        instances = [_constrained_instance("db-1"), _constrained_instance("web-1")]
+
+        blockers = instance_migration_blockers(conn, instances, capacity)
+
+        assert [b["instance"] for b in blockers] == ["db-1"]
+        assert blockers[0]["reason"].startswith("Anti-affinity server group db")
+
+        # A target host runs no other instance of the group
+        capacity["node-4"] = {"VCPU": 8, "MEMORY_MB": 8192}
+        assert instance_migration_blockers(conn, instances, capacity) == []
+
+    def test_several_constraints(self, conn):
+        conn.compute.server_groups.return_value = [
+            _server_group("nfv", "anti-affinity", ["nfv-1", "nfv-2"])
+        ]
+        conn.compute.get_server.return_value = Mock(hypervisor_hostname="node-2")
+        instance = _constrained_instance(
+            "nfv-1",
+            extra_specs={
+                "pci_passthrough:alias": "fpga:1",
+                "hw:cpu_policy": "dedicated",
+            },
+        )
+        capacity = {"node-2": {"VCPU": 8, "PCPU": 8, "MEMORY_MB": 8192}}
+
+        blockers = instance_migration_blockers(conn, [instance], capacity)
+
+        assert blockers[0]["reason"].split("; ")[0] == "PCI passthrough device fpga:1"
+        assert blockers[0]["reason"].split("; ")[1].startswith("Anti-affinity")
+
+    def test_unknown_capacity(self, conn):
+        instance = _constrained_instance(
+            "pinned", extra_specs={"hw:cpu_policy": "dedicated"}
+        )
+
+        assert instance_migration_blockers(conn, [instance], None) == []
+        conn.compute.server_groups.assert_not_called()
+
+
+class TestPlanBatchCapacity:
+    def test_nodes_take_capacity_in_order(self):
+        instances = {
+            "node-1": [_instance("inst-1", 2, 4096)],
+            "node-2": [_instance("inst-2", 2, 4096)],
+        }
+        capacity = {"node-3": {"VCPU": 2, "MEMORY_MB": 4096}}
+
+        no_capacity = plan_batch_capacity(instances, capacity)
+
+        assert no_capacity["node-1"] == []
+        assert [i["instance"] for i in no_capacity["node-2"]] == ["inst-2"]
+        assert no_capacity["node-2"][0]["reason"] == (
+            "No target host with capacity for 2 vCPUs and 4096 MiB of memory"
+        )
+
+    def test_fits_across_hosts(self):
+        instances = {
+            "node-1": [_instance("inst-1", 2, 4096)],
+            "node-2": [_instance("inst-2", 2, 4096)],
+        }
+        capacity = {
+            "node-3": {"VCPU": 2, "MEMORY_MB": 4096},
+            "node-4": {"VCPU": 2, "MEMORY_MB": 4096},
+        }
+
+        assert plan_batch_capacity(instances, capacity) == {
+            "node-1": [],
+            "node-2": [],
+        }
+
+    def test_batch_nodes_are_not_targets(self):
+        instances = {
+            "node-1": [_instance("inst-1", 1, 1024)],
+            "node-2": [],
+        }
+        capacity = {"node-2": {"VCPU": 8, "MEMORY_MB": 8192}}
+
+        no_capacity = plan_batch_capacity(instances, capacity)
+
+        assert [i["instance"] for i in no_capacity["node-1"]] == ["inst-1"]
+        assert no_capacity["node-2"] == []
+
+    def test_node_without_capacity_takes_none(self):
+        instances = {
+            "node-1": [_instance("small", 1, 1024), _instance("large", 4, 8192)],
+            "node-2": [_instance("inst-2", 2, 2048)],
+        }
+        capacity = {"node-3": {"VCPU": 4, "MEMORY_MB": 4096}}
+
+        no_capacity = plan_batch_capacity(instances, capacity)
+
+        # The small instance fits, node-1 does not enter maintenance though
+        assert [i["instance"] for i in no_capacity["node-1"]] == ["large"]
+        assert no_capacity["node-2"] == []
+        assert capacity["node-3"] == {"VCPU": 4, "MEMORY_MB": 4096}
+
+
+def _progress_events():
+    return [
+        MaintenanceProgress(
+            seq=1, instance="inst-0", state="migrating", completed=0, total=2
+        ),
+        MaintenanceProgress(
+            seq=2,
+            instance="inst-0",
+            state="migrating",
+            completed=0,
+            total=2,
+            percent=45,
+        ),
+        MaintenanceProgress(
+            seq=3,
+            instance="inst-0",
+            state="stalled",
+            completed=0,
+            total=2,
+            percent=45,
+            message="no progress for 5 minutes",
+        ),
+        MaintenanceProgress(
+            seq=4, instance="inst-0", state="succeeded", completed=1, total=2
+        ),
+        MaintenanceProgress(
+            seq=5,
+            instance="inst-1",
+            state="failed",
+            completed=2,
+            total=2,
+            message="migration is error",
+        ),
+    ]
+
+
+class TestMigrationProgressRenderer:
+    def test_json_stream(self):
+        stream = io.StringIO()
+        render = MigrationProgressRenderer(Mock(), "json-stream", stream=stream)
+
+        for event in _progress_events():
+            render(event)
+
+        lines = [json.loads(line) for line in stream.getvalue().splitlines()]
+        assert [(line["seq"], line["state"]) for line in lines] == [
+            (1, "migrating"),
+            (2, "migrating"),
+            (3, "stalled"),
+            (4, "succeeded"),
+            (5, "failed"),
+        ]
+        assert lines[1]["percent"] == 45
+        assert lines[2]["message"] == "no progress for 5 minutes"
+
+    def test_table(self):
+        output = io.StringIO()
+        status = Mock()
+        render = MigrationProgressRenderer(
+            Console(file=output, width=200), status=status
+        )
+
+        for event in _progress_events():
+            render(event)
+
+        assert status.update.call_args_list == [
+            call("migrated instances (0/2), migrating inst-0"),
+            call("migrated instances (0/2), migrating inst-0 45%"),
+            call("migrated instances (0/2), inst-0 no progress for 5 minutes"),
+            call("migrated instances (1/2), inst-0 migrated"),
+            call("migrated instances (2/2), inst-1 failed: migration is error"),
+        ]
+        assert output.getvalue().splitlines() == [
+            "Warning: migrated instances (0/2), inst-0 no progress for 5 minutes",
+            "Warning: migrated instances (2/2), inst-1 failed: migration is error",
+        ]
+
+
+class TestMigrationProgressReporter:
+    def test_publishes_events(self):
+        client = Mock()
+        client.cluster.add_maintenance_progress.side_effect = lambda node, events: [
+            events[0].model_copy(update={"seq": 7, "node": node})
+        ]
+        render = Mock()
+        report = MigrationProgressReporter(client, "node-1", render)
+
+        report(_progress_events()[0])
+
+        assert render.call_args.args[0].seq == 7
+        assert render.call_args.args[0].node == "node-1"
+
+    def test_stops_publishing_on_failure(self):
+        client = Mock()
+        client.cluster.add_maintenance_progress.side_effect = URLNotFoundException(
+            "not found"
+        )
+        render = Mock()
+        report = MigrationProgressReporter(client, "node-1", render)
+
+        for event in _progress_events()[:2]:
+            report(event)
+
+        client.cluster.add_maintenance_progress.assert_called_once()
+        assert render.call_count == 2
+
+
+class TestFollowMigrationProgress:
+    def test_follows_until_completed(self):
+        events = _progress_events()
+        client = Mock()
+        client.cluster.get_maintenance_progress.side_effect = [
+            events[:2],
+            [],
+            events[2:],
+        ]
+        render = Mock()
+
+        follow_migration_progress(client, "node-1", render, follow=True)
+
+        assert [c.args[0].seq for c in render.call_args_list] == [1, 2, 3, 4, 5]
+        assert client.cluster.get_maintenance_progress.call_args_list == [
+            call("node-1", after=0, wait=30),
+            call("node-1", after=2, wait=30),
+            call("node-1", after=2, wait=30),
+        ]
+
+    def test_no_follow(self):
+        client = Mock()
+        client.cluster.get_maintenance_progress.return_value = []
+        render = Mock()
+
+        follow_migration_progress(client, "node-1", render)
+
+        client.cluster.get_maintenance_progress.assert_called_once_with(
+            "node-1", after=0, wait=0
+        )
+        render.assert_not_called()
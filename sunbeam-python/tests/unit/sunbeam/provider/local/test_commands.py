@@ -0,0 +1,441 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+import threading
+import time
+from unittest.mock import MagicMock, Mock, patch
+
+import click
+import pytest
+from click.testing import CliRunner
+
+import sunbeam.provider.local.commands as local_commands
+from sunbeam.core.checks import Check
+from sunbeam.core.common import Result, ResultType
+
+NODES = [f"node{i}.example.com" for i in range(1, 5)]
+FAILING = {"node2.example.com", "node4.example.com"}
+
+
+class FakeJoins:
+    """Add nodes, failing some of them, tracking how many run at once."""
+
+    def __init__(self):
+        self.lock = threading.Lock()
+        self.running = 0
+        self.peak = 0
+        self.added: list[str] = []
+        self.scopes: list = []
+
+    def __call__(
+        self, deployment, client, jhelper, name, console, show_hints, scope=None
+    ):
+        self.scopes.append(scope)
+        with self.lock:
+            self.running += 1
+            self.peak = max(self.peak, self.running)
+        try:
+            time.sleep(0.01)
+            if name in FAILING:
+                raise click.ClickException(f"Token already generated for {name}")
+            with self.lock:
+                self.added.append(name)
+            return f"token-{name}"
+        finally:
+            with self.lock:
+                self.running -= 1
+
+
+@pytest.fixture
+def joins():
+    fake = FakeJoins()
+    with (
+        patch.object(local_commands, "_add_node", side_effect=fake),
+        patch.object(local_commands, "console", MagicMock()),
+        patch.object(local_commands, "JujuHelper"),
+        patch.object(local_commands, "run_plan"),
+        patch.object(local_commands, "run_preflight_checks"),
+    ):
+        yield fake
+
+
+def _add(
+    names,
+    parallel=2,
+    format=local_commands.FORMAT_DEFAULT,
+    roles=(),
+    ttl=None,
+):
+    cmd = local_commands.add
+    with click.Context(cmd) as ctx:
+        ctx.obj = Mock()
+        cmd.callback(
+            names=tuple(names),
+            format=format,
+            output=None,
+            parallel=parallel,
+            roles=list(roles),
+            ttl=ttl,
+            show_hints=False,
+        )
+
+
+class TestAddNodes:
+    def test_add_nodes_reports_each_node(self, joins):
+        results = local_commands._add_nodes(
+            Mock(), Mock(), Mock(), NODES, parallel=2, show_hints=False
+        )
+
+        assert list(results) == NODES
+        assert results["node1.example.com"] == {"token": "token-node1.example.com"}
+        assert results["node3.example.com"] == {"token": "token-node3.example.com"}
+        assert results["node2.example.com"] == {
+            "error": "Token already generated for node2.example.com"
+        }
+        assert "error" in results["node4.example.com"]
+        assert sorted(joins.added) == ["node1.example.com", "node3.example.com"]
+
+    def test_add_nodes_bounds_parallelism(self, joins):
+        local_commands._add_nodes(
+            Mock(), Mock(), Mock(), NODES, parallel=2, show_hints=False
+        )
+
+        assert joins.peak == 2
+
+    def test_add_fails_if_any_node_failed(self, joins):
+        with pytest.raises(
+            click.ClickException,
+            match="Failed to add 2 of 4 nodes: node2.example.com, node4.example.com",
+        ):
+            _add(NODES)
+
+        # A failed node does not stop the others
+        assert sorted(joins.added) == ["node1.example.com", "node3.example.com"]
+        local_commands.console.print.assert_called_once()
+
+    def test_add_succeeds_if_all_nodes_added(self, joins):
+        _add(["node1.example.com", "node3.example.com."])
+
+        assert sorted(joins.added) == ["node1.example.com", "node3.example.com"]
+
+    def test_add_scoped(self, joins):
+        _add(
+            ["node1.example.com", "node3.example.com"],
+            roles=[local_commands.Role.COMPUTE],
+            ttl="1h",
+        )
+
+        assert joins.scopes == [
+            local_commands.tokens_cmds.JoinTokenScope(
+                [local_commands.Role.COMPUTE], "1h"
+            )
+        ] * 2
+
+    def test_add_unscoped(self, joins):
+        _add(["node1.example.com"])
+
+        assert joins.scopes == [None]
+
+    def test_add_ttl_requires_role(self, joins):
+        with pytest.raises(click.UsageError, match="requires --role"):
+            _add(["node1.example.com"], ttl="1h")
+
+        assert joins.added == []
+
+    def test_add_rejects_value_format_with_multiple_nodes(self, joins):
+        with pytest.raises(click.UsageError):
+            _add(NODES, format=local_commands.FORMAT_VALUE)
+
+        assert joins.added == []
+
+
+class FakeCheck(Check):
+    """Check of a fake node, passing or failing as told."""
+
+    def __init__(self, name: str, passed: bool = True, message: str = ""):
+        super().__init__(name, f"Checking {name}")
+        self.passed = passed
+        if message:
+            self.message = message
+        self.ran = False
+
+    def run(self) -> bool:
+        self.ran = True
+        return self.passed
+
+
+@pytest.fixture
+def node_checks():
+    checks = [
+        FakeCheck("Check for juju snap"),
+        FakeCheck("Check for clock skew", False, "Clock is 42s off the cluster"),
+        FakeCheck("Check for free disk space"),
+    ]
+    with (
+        patch.object(
+            local_commands, "join_preflight_checks", return_value=checks
+        ) as join_checks,
+        patch("sunbeam.core.checks.utils.get_fqdn", return_value="node1.example.com"),
+    ):
+        yield checks, join_checks
+
+
+class TestCheckNode:
+    def test_check_node_flags_failed_check(self, node_checks):
+        checks, join_checks = node_checks
+
+        result = CliRunner().invoke(
+            local_commands.check_node,
+            ["node1.example.com", "--token", "token", "--format", "json"],
+        )
+
+        assert result.exit_code == 1
+        join_checks.assert_called_once_with("node1.example.com", "token", True)
+        # A failed check does not stop the others
+        assert all(check.ran for check in checks)
+        report = json.loads(result.output[: result.output.index("Error:")])
+        assert report["node"] == "node1.example.com"
+        assert report["passed"] is False
+        failed = [check for check in report["checks"] if not check["passed"]]
+        assert failed == [
+            {
+                "name": "Check for clock skew",
+                "passed": False,
+                "message": "Clock is 42s off the cluster",
+            }
+        ]
+        assert "1 of 5 checks failed: Check for clock skew" in result.output
+
+    def test_check_node_passes(self, node_checks):
+        checks, join_checks = node_checks
+        checks[1].passed = True
+
+        result = CliRunner().invoke(
+            local_commands.check_node,
+            ["node1.example.com", "--role", "control", "--format", "json"],
+        )
+
+        assert result.exit_code == 0, result.output
+        join_checks.assert_called_once_with("node1.example.com", None, False)
+        report = json.loads(result.output)
+        assert report["passed"] is True
+        assert [check["name"] for check in report["checks"]] == [
+            "Check for local node",
+            "Check for FQDN",
+            "Check for juju snap",
+            "Check for clock skew",
+            "Check for free disk space",
+        ]
+
+
+@pytest.fixture
+def removal():
+    with (
+        patch.object(local_commands, "console", MagicMock()),
+        patch.object(local_commands, "JujuHelper"),
+        patch.object(local_commands, "run_plan", return_value={}) as run_plan,
+        patch.object(local_commands, "run_preflight_checks"),
+        patch.object(
+            local_commands, "is_node_removed", return_value=False
+        ) as is_node_removed,
+    ):
+        yield run_plan, is_node_removed
+
+
+def _remove(name, force=False):
+    cmd = local_commands.remove
+    with click.Context(cmd) as ctx:
+        ctx.obj = Mock()
+        cmd.callback(name=name, force=force, show_hints=False)
+
+
+class TestRemoveNode:
+    def test_remove_already_removed_node(self, removal):
+        run_plan, is_node_removed = removal
+        is_node_removed.return_value = True
+
+        _remove("node1.example.com")
+
+        run_plan.assert_not_called()
+
+    def test_remove_checks_node_reachable(self, removal):
+        run_plan, _ = removal
+
+        _remove("node1.example.com")
+
+        (plan, *_), _ = run_plan.call_args
+        steps = [type(step) for step in plan]
+        assert local_commands.CheckNodeReachableStep in steps
+        assert steps.index(local_commands.CheckNodeReachableStep) < steps.index(
+            local_commands.RemoveHypervisorUnitStep
+        )
+        assert steps[-1] is local_commands.ClusterRemoveNodeStep
+        assert plan[-1].force is False
+
+    def test_force_remove_ignores_failed_steps(self, removal):
+        run_plan, _ = removal
+        failed = Result(ResultType.FAILED, "Machine unreachable")
+
+        def fake_run_plan(plan, console, show_hints, no_raise=False):
+            if isinstance(plan[0], local_commands.RemoveHypervisorUnitStep):
+                return {"RemoveHypervisorUnitStep": failed}
+            return {}
+
+        run_plan.side_effect = fake_run_plan
+
+        _remove("node1.example.com", force=True)
+
+        plans = [call.args[0] for call in run_plan.call_args_list]
+        assert all(len(plan) == 1 for plan in plans)
+        steps = [type(plan[0]) for plan in plans]
+        assert local_commands.CheckNodeReachableStep not in steps
+        assert local_commands.PromptCheckNodeExistStep not in steps
+        # The membership is removed after the failed cleanup
+        assert steps.index(local_commands.RemoveHypervisorUnitStep) < len(steps) - 1
+        assert steps[-1] is local_commands.ClusterRemoveNodeStep
+        assert plans[-1][0].force is True
+        local_commands.console.print.assert_called()
+
+
+class TestBootstrapOffline:
+    def test_offline_requires_manifest(self):
+        cmd = local_commands.bootstrap
+        with (
+            patch.object(local_commands, "_bootstrap") as bootstrap,
+            click.Context(cmd) as ctx,
+        ):
+            ctx.obj = Mock()
+            with pytest.raises(click.UsageError, match="--manifest"):
+                cmd.callback(
+                    roles=[],
+                    topology="auto",
+                    database="auto",
+                    offline=True,
+                )
+
+        bootstrap.assert_not_called()
+        ctx.obj.get_manifest.assert_not_called()
+
+
+TOPOLOGY = """
+nodes:
+  - name: node1.example.com
+    roles: [control, compute]
+    labels: {rack: a}
+  - name: node2.example.com
+    roles: [compute, storage]
+    labels: {rack: b}
+"""
+
+
+class FakeCluster:
+    """Nodes of a cluster, bootstrapped and joined as the topology declares."""
+
+    def __init__(self):
+        self.bootstrapped = False
+        self.nodes: list[dict] = []
+
+    def join(self, name, roles):
+        self.nodes.append({"name": name, "role": roles})
+
+    def bootstrap(self, ctx, manifest, journal, roles, *args, **kwargs):
+        self.join("node1.example.com", [role.name.lower() for role in roles])
+        self.bootstrapped = True
+
+    def set_node_labels(self, name, labels):
+        node = next(node for node in self.nodes if node["name"] == name)
+        node["labels"] = labels
+        return labels
+
+
+@pytest.fixture
+def topology_cluster():
+    fake = FakeCluster()
+    deployment = Mock()
+    cluster = deployment.get_client.return_value.cluster
+    cluster.check_sunbeam_bootstrapped.side_effect = lambda: fake.bootstrapped
+    cluster.list_nodes.side_effect = lambda: fake.nodes
+    cluster.get_cluster_members.return_value = []
+    cluster.set_node_labels.side_effect = fake.set_node_labels
+    with (
+        patch.object(local_commands, "_bootstrap", side_effect=fake.bootstrap),
+        patch.object(local_commands, "_add_node", return_value="token-2") as add,
+        patch.object(local_commands, "BootstrapJournal"),
+        patch.object(local_commands, "Snap"),
+        patch.object(local_commands, "bootstrap_journal_path"),
+        patch.object(local_commands, "JujuHelper"),
+        patch.object(local_commands, "run_plan"),
+        patch.object(local_commands, "console") as console,
+        patch.object(
+            local_commands.utils, "get_fqdn", return_value="node1.example.com"
+        ),
+    ):
+        yield fake, deployment, add, console
+
+
+def _bootstrap_topology(deployment, path):
+    cmd = local_commands.bootstrap
+    with click.Context(cmd) as ctx:
+        ctx.obj = deployment
+        cmd.callback(
+            roles=[],
+            topology="auto",
+            database="auto",
+            topology_path=path,
+        )
+
+
+class TestBootstrapTopology:
+    def test_converge_then_no_op(self, topology_cluster, tmp_path):
+        fake, deployment, add, console = topology_cluster
+        cluster = deployment.get_client.return_value.cluster
+        path = tmp_path / "topology.yaml"
+        path.write_text(TOPOLOGY)
+
+        _bootstrap_topology(deployment, path)
+
+        assert fake.nodes[0] == {
+            "name": "node1.example.com",
+            "role": ["control", "compute"],
+            "labels": {"rack": "a"},
+        }
+        add.assert_called_once()
+        assert add.call_args.args[3] == "node2.example.com"
+
+        # node2 joins with the roles and labels it is declared
+        fake.join("node2.example.com", ["storage", "compute"])
+        fake.set_node_labels("node2.example.com", {"rack": "b"})
+        add.reset_mock()
+        cluster.set_node_labels.reset_mock()
+        local_commands._bootstrap.reset_mock()
+
+        _bootstrap_topology(deployment, path)
+
+        local_commands._bootstrap.assert_not_called()
+        add.assert_not_called()
+        cluster.set_node_labels.assert_not_called()
+        console.print.assert_called_with("Cluster matches the topology.")
+
+    def test_extra_node_reported(self, topology_cluster, tmp_path):
+        fake, deployment, add, _ = topology_cluster
+        fake.bootstrapped = True
+        fake.join("node1.example.com", ["control", "compute"])
+        fake.join("node3.example.com", ["compute"])
+        path = tmp_path / "topology.yaml"
+        path.write_text(TOPOLOGY)
+
+        with patch.object(click.Context, "invoke") as invoke:
+            _bootstrap_topology(deployment, path)
+
+        invoke.assert_not_called()
+
+    def test_bootstrap_node_not_declared(self, topology_cluster, tmp_path):
+        _, deployment, _, _ = topology_cluster
+        path = tmp_path / "topology.yaml"
+        path.write_text("nodes:\n  - name: other.example.com\n")
+
+        with pytest.raises(click.ClickException, match="not declared"):
+            _bootstrap_topology(deployment, path)
+
+        local_commands._bootstrap.assert_not_called()
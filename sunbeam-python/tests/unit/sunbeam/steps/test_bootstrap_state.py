@@ -0,0 +1,230 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import MagicMock, Mock, patch
+
+import click
+import pytest
+
+from sunbeam.clusterd.service import ClusterServiceUnavailableException
+from sunbeam.core.common import Result, ResultType
+from sunbeam.core.steps import DeployMachineApplicationStep
+from sunbeam.steps.bootstrap_state import (
+    CLUSTERD,
+    JUJU_MODEL,
+    TERRAFORM_PLAN,
+    BootstrapJournal,
+)
+from sunbeam.steps.clusterd import ClusterInitStep
+from sunbeam.steps.juju import AddJujuModelStep
+from sunbeam.steps.openstack import DeployControlPlaneStep
+from sunbeam.steps.terraform import DestroyTerraformPlanStep
+
+
+class FakeCloud:
+    """Resources created by the steps of a fake bootstrap."""
+
+    def __init__(self):
+        self.clusterd: list[str] = []
+        self.models: list[str] = []
+        self.plans: dict[str, Mock] = {}
+        self.destroyed: list[str] = []
+
+    def step(self, spec, created=None, failed=False, **attrs):
+        """Return a step of class spec, creating a resource when run."""
+        step = Mock(spec=spec, **attrs)
+        step.name = spec.__name__
+        step.has_prompts.return_value = False
+        step.is_skip.return_value = Result(ResultType.COMPLETED)
+
+        def run(status):
+            if created is not None:
+                created()
+            if failed:
+                return Result(ResultType.FAILED, "unit in error")
+            return Result(ResultType.COMPLETED)
+
+        step.run.side_effect = run
+        return step
+
+    def tfhelper(self, plan):
+        tfhelper = Mock(plan=plan)
+        tfhelper.pull_state.side_effect = lambda: {
+            "resources": ["app"] if plan in self.plans else []
+        }
+
+        def destroy():
+            self.destroyed.append(plan)
+            self.plans.pop(plan)
+
+        tfhelper.destroy.side_effect = destroy
+        return tfhelper
+
+    def client(self):
+        client = Mock()
+
+        def members():
+            if not self.clusterd:
+                raise ClusterServiceUnavailableException(
+                    "Sunbeam Cluster not initialized"
+                )
+            return [{"name": name} for name in self.clusterd]
+
+        client.cluster.get_cluster_members.side_effect = members
+        client.cluster.reset.side_effect = self.clusterd.remove
+        return client
+
+    def jhelper(self):
+        jhelper = Mock()
+        jhelper.model_exists.side_effect = lambda model: model in self.models
+        jhelper.destroy_model.side_effect = lambda model, **kwargs: (
+            self.models.remove(model)
+        )
+        return jhelper
+
+    def deployment(self):
+        deployment = Mock()
+        tfhelpers = {
+            plan: self.tfhelper(plan) for plan in ("k8s-plan", "openstack-plan")
+        }
+        deployment.get_tfhelper.side_effect = tfhelpers.get
+        deployment.get_client.return_value = self.client()
+        return deployment
+
+
+@pytest.fixture
+def console():
+    return MagicMock()
+
+
+@pytest.fixture
+def cloud():
+    with (
+        patch("sunbeam.steps.bootstrap_state.utils.get_fqdn", return_value="node1"),
+        patch.object(DestroyTerraformPlanStep, "get_controller"),
+    ):
+        yield FakeCloud()
+
+
+def _failed_bootstrap_plan(cloud):
+    return [
+        cloud.step(
+            ClusterInitStep,
+            created=lambda: cloud.clusterd.append("node1"),
+            management_cidr="10.0.0.0/24",
+        ),
+        cloud.step(
+            AddJujuModelStep,
+            created=lambda: cloud.models.append("openstack-machines"),
+            model="openstack-machines",
+        ),
+        cloud.step(
+            DeployMachineApplicationStep,
+            created=lambda: cloud.plans.update({"k8s-plan": Mock()}),
+            tfhelper=Mock(plan="k8s-plan"),
+        ),
+        # A failed apply leaves part of the plan resources behind
+        cloud.step(
+            DeployControlPlaneStep,
+            created=lambda: cloud.plans.update({"openstack-plan": Mock()}),
+            failed=True,
+            tfhelper=Mock(plan="openstack-plan"),
+        ),
+    ]
+
+
+class TestBootstrapJournal:
+    def test_run_plan_records_created_resources(self, tmp_path, console, cloud):
+        journal = BootstrapJournal(tmp_path / "journal.json")
+
+        with pytest.raises(click.ClickException):
+            journal.run_plan(
+                _failed_bootstrap_plan(cloud), console, True
+            )
+
+        assert json.loads(journal.path.read_text()) == [
+            {"kind": CLUSTERD, "name": "node1"},
+            {"kind": JUJU_MODEL, "model": "openstack-machines"},
+            {"kind": TERRAFORM_PLAN, "plan": "k8s-plan"},
+            {"kind": TERRAFORM_PLAN, "plan": "openstack-plan"},
+        ]
+
+    def test_run_plan_forgets_skipped_resources(self, tmp_path, console, cloud):
+        journal = BootstrapJournal(tmp_path / "journal.json")
+        step = cloud.step(ClusterInitStep, management_cidr="10.0.0.0/24")
+        step.is_skip.return_value = Result(ResultType.SKIPPED)
+
+        journal.run_plan([step], console, True)
+
+        assert journal.resources == []
+        assert BootstrapJournal.load(journal.path).resources == []
+
+    def test_rollback_removes_created_resources(self, tmp_path, console, cloud):
+        path = tmp_path / "journal.json"
+        with pytest.raises(click.ClickException):
+            BootstrapJournal(path).run_plan(
+                _failed_bootstrap_plan(cloud), console, True
+            )
+        assert cloud.clusterd and cloud.models and cloud.plans
+
+        # Rolled back by a new process
+        journal = BootstrapJournal.load(path)
+        deployment = cloud.deployment()
+        with patch(
+            "sunbeam.steps.bootstrap_state.JujuHelper", return_value=cloud.jhelper()
+        ):
+            journal.rollback(deployment, Mock(), console, True)
+
+        assert cloud.clusterd == []
+        assert cloud.models == []
+        assert cloud.plans == {}
+        assert cloud.destroyed == ["openstack-plan", "k8s-plan"]
+        assert not path.exists()
+
+    def test_rollback_is_idempotent(self, tmp_path, console, cloud):
+        path = tmp_path / "journal.json"
+        with pytest.raises(click.ClickException):
+            BootstrapJournal(path).run_plan(
+                _failed_bootstrap_plan(cloud), console, True
+            )
+        # An interrupted rollback removed the resources but not the journal
+        cloud.clusterd.clear()
+        cloud.models.clear()
+        cloud.plans.clear()
+
+        deployment = cloud.deployment()
+        jhelper = cloud.jhelper()
+        with patch("sunbeam.steps.bootstrap_state.JujuHelper", return_value=jhelper):
+            BootstrapJournal.load(path).rollback(deployment, Mock(), console, True)
+            BootstrapJournal.load(path).rollback(deployment, Mock(), console, True)
+
+        assert cloud.destroyed == []
+        jhelper.destroy_model.assert_not_called()
+        deployment.get_client.return_value.cluster.reset.assert_not_called()
+        assert not path.exists()
+
+    def test_rollback_failure_keeps_remaining_resources(
+        self, tmp_path, console, cloud
+    ):
+        path = tmp_path / "journal.json"
+        with pytest.raises(click.ClickException):
+            BootstrapJournal(path).run_plan(
+                _failed_bootstrap_plan(cloud), console, True
+            )
+        journal = BootstrapJournal.load(path)
+        deployment = cloud.deployment()
+        jhelper = cloud.jhelper()
+        jhelper.destroy_model.side_effect = Exception("model is busy")
+
+        with (
+            patch("sunbeam.steps.bootstrap_state.JujuHelper", return_value=jhelper),
+            pytest.raises(click.ClickException, match="model is busy"),
+        ):
+            journal.rollback(deployment, Mock(), console, True)
+
+        assert cloud.plans == {}
+        assert BootstrapJournal.load(path).resources == [
+            {"kind": CLUSTERD, "name": "node1"},
+            {"kind": JUJU_MODEL, "model": "openstack-machines"},
+        ]
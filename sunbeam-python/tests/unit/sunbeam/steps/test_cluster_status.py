@@ -0,0 +1,164 @@
+# SPDX-FileCopyrightText: 2025 - Canonical Ltd
+# SPDX-License-Identifier: Apache-2.0
+
+import json
+from unittest.mock import Mock
+
+from sunbeam.steps import cluster_status
+
+STATUS = {
+    "openstack-machines": {
+        "0": {
+            "hostname": "node-1",
+            "status": {"machine": "running", "cluster": "ONLINE", "control": "active"},
+        },
+        "1": {
+            "hostname": "node-2",
+            "status": {"machine": "running", "compute": "waiting"},
+        },
+    }
+}
+
+
+def _deployment():
+    deployment = Mock()
+    deployment.openstack_machines_model = "openstack-machines"
+    client = deployment.get_client.return_value
+    client.cluster.list_nodes.return_value = [
+        {
+            "name": "node-2",
+            "role": ["compute"],
+            "machineid": 1,
+            "systemid": "",
+            "cordoned": True,
+        },
+        {
+            "name": "node-1",
+            "role": ["control", "compute"],
+            "machineid": 0,
+            "systemid": "",
+            "labels": {"rack": "r1"},
+        },
+    ]
+    client.cluster.get_status.return_value = {
+        "node-1": {
+            "status": "ONLINE",
+            "address": "10.0.0.1:7000",
+            "last_heartbeat": "2025-03-01T10:20:30.123456789+02:00",
+        },
+        "node-2": {
+            "status": "ONLINE",
+            "address": "[fd00::2]:7000",
+            "last_heartbeat": "0001-01-01T00:00:00Z",
+        },
+    }
+    return deployment
+
+
+def test_list_node_statuses_json_shape_is_stable():
+    nodes = cluster_status.list_node_statuses(_deployment(), STATUS)
+
+    # Automation parses this output, only ever add fields to it
+    assert json.loads(json.dumps(nodes)) == [
+        {
+            "name": "node-1",
+            "role": ["compute", "control"],
+            "status": {"machine": "running", "cluster": "ONLINE", "control": "active"},
+            "addresses": ["10.0.0.1"],
+            "named_addresses": {},
+            "preferred_api": None,
+            "labels": {"rack": "r1"},
+            "cordoned": False,
+            "last_heartbeat": "2025-03-01T08:20:30Z",
+        },
+        {
+            "name": "node-2",
+            "role": ["compute"],
+            "status": {"machine": "running", "compute": "waiting"},
+            "addresses": ["fd00::2"],
+            "named_addresses": {},
+            "preferred_api": None,
+            "labels": {},
+            "cordoned": True,
+            "last_heartbeat": None,
+        },
+    ]
+
+
+def test_list_node_statuses_filtered():
+    deployment = _deployment()
+    client = deployment.get_client.return_value
+    client.cluster.list_nodes_page.return_value = (
+        [{"name": "node-2", "role": ["compute"]}],
+        2,
+    )
+
+    nodes = cluster_status.list_node_statuses(
+        deployment, STATUS, role=("compute",), limit=1
+    )
+
+    assert [node["name"] for node in nodes] == ["node-2"]
+    client.cluster.list_nodes_page.assert_called_once_with(
+        role=["compute"], limit=1, label=[]
+    )
+    client.cluster.list_nodes.assert_not_called()
+
+
+def test_mark_cordoned_nodes():
+    status = cluster_status.mark_cordoned_nodes(_deployment(), STATUS)
+
+    machines = status["openstack-machines"]
+    assert "cordoned" not in machines["0"]
+    assert machines["1"]["cordoned"] is True
+    assert "cordoned" not in STATUS["openstack-machines"]["1"]
+
+
+def test_format_status_shows_cordoned():
+    deployment = _deployment()
+    status = cluster_status.mark_cordoned_nodes(deployment, STATUS)
+
+    (table,) = cluster_status.format_status(deployment, status, "table")
+
+    assert list(table.columns[0].cells) == [
+        "node-1",
+        "node-2 [orange1](cordoned)[/orange1]",
+    ]
+
+
+def _deployment_with_addresses():
+    deployment = _deployment()
+    client = deployment.get_client.return_value
+    client.cluster.list_nodes.return_value[1].update(
+        addresses={"management": "10.0.0.1", "data": "10.1.0.1"},
+        preferred_api="management",
+    )
+    return deployment
+
+
+def test_list_node_statuses_named_addresses():
+    nodes = cluster_status.list_node_statuses(_deployment_with_addresses(), STATUS)
+
+    assert nodes[0]["addresses"] == ["10.0.0.1", "10.1.0.1"]
+    assert nodes[0]["named_addresses"] == {
+        "management": "10.0.0.1",
+        "data": "10.1.0.1",
+    }
+    assert nodes[0]["preferred_api"] == "management"
+
+
+def test_format_status_shows_addresses():
+    deployment = _deployment_with_addresses()
+    status = cluster_status.mark_node_addresses(deployment, STATUS)
+
+    machines = status["openstack-machines"]
+    assert machines["0"]["preferred_api"] == "management"
+    assert "addresses" not in machines["1"]
+    assert "addresses" not in STATUS["openstack-machines"]["0"]
+
+    (table,) = cluster_status.format_status(deployment, status, "table")
+
+    assert table.columns[1].header == "Addresses"
+    assert list(table.columns[1].cells) == [
+        "data=10.1.0.1\nmanagement=10.0.0.1 (api)",
+        "",
+    ]
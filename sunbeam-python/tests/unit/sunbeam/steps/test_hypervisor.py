@@ -164,6 +164,31 @@ class TestRemoveHypervisorUnitStep:
         result = step.is_skip()
         assert result.result_type == ResultType.FAILED
 
+    def test_is_skip_force_does_not_run_actions(
+        self,
+        basic_client,
+        test_name,
+        basic_jhelper,
+        test_model,
+        basic_deployment,
+        read_config_patch,
+    ):
+        basic_client.cluster.get_node_info.return_value = {"machineid": "1"}
+        basic_jhelper.get_application.return_value = Mock(
+            units={"hypervisor/1": Mock(machine="1")}
+        )
+        step = RemoveHypervisorUnitStep(
+            basic_client,
+            basic_jhelper,
+            basic_deployment,
+            test_name,
+            test_model,
+            force=True,
+        )
+        result = step.is_skip()
+        assert result.result_type == ResultType.COMPLETED
+        basic_jhelper.run_action.assert_not_called()
+
     @patch("sunbeam.steps.hypervisor.remove_hypervisor")
     def test_run(
         self,
@@ -294,7 +319,7 @@ class TestRemoveHypervisorUnitStep:
 
         basic_jhelper.wait_application_ready.assert_called_once()
         assert result.result_type == ResultType.FAILED
-        assert result.message == "timed out"
+        assert result.message.startswith("Step RemoveHypervisorUnitStep timed out")
 
 
 class TestReapplyHypervisorTerraformPlanStep:
@@ -458,7 +483,9 @@ class TestReapplyHypervisorTerraformPlanStep:
 
         basic_jhelper.wait_until_desired_status.assert_called_once()
         assert result.result_type == ResultType.FAILED
-        assert result.message == "timed out"
+        assert result.message.startswith(
+            "Step ReapplyHypervisorTerraformPlanStep timed out"
+        )
 
 
 class TestReapplyHypervisorOptionalIntegrationsStep:
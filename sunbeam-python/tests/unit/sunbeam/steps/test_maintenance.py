@@ -1,21 +1,35 @@
 # SPDX-FileCopyrightText: 2024 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import threading
+import time
+from types import SimpleNamespace
 from unittest.mock import Mock, patch
 
+import openstack
 import pytest
 import tenacity
 from watcherclient import v1 as watcher
 
+from sunbeam.clusterd.service import RemoteException
+from sunbeam.core import watcher as watcher_helper
 from sunbeam.core.common import ResultType, SunbeamException
 from sunbeam.core.juju import ActionFailedException, UnitNotFoundException
+from sunbeam.core.openstack_api import InstanceMigrationFailedException
+from sunbeam.core.watcher import (
+    WatcherAuditFailedException,
+    WatcherAuditTimeoutException,
+)
 from sunbeam.steps.maintenance import (
+    MigrationProgressTracker,
     CordonControlRoleNodeStep,
     CreateWatcherAuditStepABC,
     CreateWatcherHostMaintenanceAuditStep,
     CreateWatcherWorkloadBalancingAuditStep,
     DrainControlRoleNodeStep,
     MicroCephActionStep,
+    MigrateInstancesStep,
+    RunWatcherActionsStep,
     RunWatcherAuditStep,
     UncordonControlRoleNodeStep,
 )
@@ -38,9 +52,10 @@ def mock_watcher_client(mock_watcher_helper):
 class DummyCreateWatcherAuditStepABC(CreateWatcherAuditStepABC):
     name = "fake-name"
     description = "fake-desc"
+    operation = "enable"
 
     def __init__(self, mock_deployment, node):
-        self.mock_audit = Mock()
+        self.mock_audit = Mock(uuid="fake-audit")
         super().__init__(mock_deployment, node)
 
     def _create_audit(self) -> watcher.Audit:
@@ -63,8 +78,9 @@ class TestCreateWatcherAuditStepABC:
         )
 
     def test_run(self, mock_watcher_helper):
+        mock_watcher_helper.get_action_plans.return_value = []
         step = DummyCreateWatcherAuditStepABC(Mock(), "fake-node")
-        step._get_actions = Mock()
+        step._get_actions = Mock(return_value=[])
         result = step.run(Mock())
         assert result.result_type == ResultType.COMPLETED
         assert result.message == {
@@ -81,6 +97,141 @@ class TestCreateWatcherAuditStepABC:
         assert result.result_type == ResultType.FAILED
 
 
+class FakeWatcherClient:
+    """Watcher client holding an action plan migrating a single instance."""
+
+    def __init__(self):
+        self.action = Mock()
+        self.action.list.return_value = [
+            SimpleNamespace(
+                uuid="action-1",
+                action_type="migrate",
+                state="PENDING",
+                input_parameters={
+                    "migration_type": "live",
+                    "resource_id": "instance-1",
+                    "source_node": "node-1",
+                },
+                description=(
+                    "Moving a VM instance from source_node to destination_node"
+                ),
+            )
+        ]
+        self.action_plan = Mock()
+        self.action_plan.list.return_value = [
+            SimpleNamespace(
+                uuid="plan-1",
+                state="RECOMMENDED",
+                global_efficacy=[
+                    {
+                        "name": "instance_migrations_count",
+                        "description": "The number of VM migrations to be performed.",
+                        "unit": None,
+                        "value": 1,
+                    }
+                ],
+            )
+        ]
+
+
+class TestCreateWatcherAuditPlan:
+    @pytest.fixture
+    def fake_watcher_client(self, mock_watcher_helper):
+        client = FakeWatcherClient()
+        mock_watcher_helper.get_watcher_client.return_value = client
+        mock_watcher_helper.get_actions.side_effect = watcher_helper.get_actions
+        mock_watcher_helper.get_action_plans.side_effect = (
+            watcher_helper.get_action_plans
+        )
+        mock_watcher_helper.create_audit.return_value = SimpleNamespace(
+            uuid="audit-1"
+        )
+        yield client
+
+    def test_run_records_plan(self, fake_watcher_client):
+        deployment = Mock()
+        step = CreateWatcherHostMaintenanceAuditStep(deployment, "node-1")
+
+        result = step.run(None)
+
+        assert result.result_type == ResultType.COMPLETED
+        fake_watcher_client.action_plan.list.assert_called_once_with(
+            audit="audit-1", detail=True
+        )
+        update = deployment.get_client.return_value.cluster.update_maintenance_plan
+        plan = update.call_args.args[0]
+        assert plan.node == "node-1"
+        assert plan.operation == "enable"
+        assert plan.audit == "audit-1"
+        assert plan.action_plan == "plan-1"
+        assert plan.state == "succeeded"
+        assert [action.model_dump() for action in plan.actions] == [
+            {
+                "uuid": "action-1",
+                "type": "migrate",
+                "state": "PENDING",
+                "parameters": {
+                    "migration_type": "live",
+                    "resource_id": "instance-1",
+                    "source_node": "node-1",
+                },
+                "description": (
+                    "Moving a VM instance from source_node to destination_node"
+                ),
+            }
+        ]
+        assert plan.efficacy[0].name == "instance_migrations_count"
+        assert plan.efficacy[0].value == 1
+        assert plan.efficacy[0].unit == ""
+
+    def test_run_records_timeout(self, fake_watcher_client, mock_watcher_helper):
+        mock_watcher_helper.create_audit.side_effect = WatcherAuditTimeoutException(
+            "Watcher audit audit-1 did not complete in 180s", "audit-1"
+        )
+        deployment = Mock()
+        step = CreateWatcherHostMaintenanceAuditStep(deployment, "node-1")
+
+        result = step.run(None)
+
+        assert result.result_type == ResultType.FAILED
+        assert result.message == "Watcher audit audit-1 did not complete in 180s"
+        update = deployment.get_client.return_value.cluster.update_maintenance_plan
+        plan = update.call_args.args[0]
+        assert plan.operation == "enable"
+        assert plan.audit == "audit-1"
+        assert plan.state == "timed-out"
+        assert plan.reason == "Watcher audit audit-1 did not complete in 180s"
+        assert plan.actions == []
+
+    def test_run_records_no_feasible_plan(
+        self, fake_watcher_client, mock_watcher_helper
+    ):
+        mock_watcher_helper.create_audit.side_effect = WatcherAuditFailedException(
+            "no feasible action plan", "audit-2"
+        )
+        deployment = Mock()
+        step = CreateWatcherWorkloadBalancingAuditStep(deployment, "node-1")
+
+        result = step.run(None)
+
+        assert result.result_type == ResultType.FAILED
+        update = deployment.get_client.return_value.cluster.update_maintenance_plan
+        plan = update.call_args.args[0]
+        assert plan.operation == "disable"
+        assert plan.state == "failed"
+        assert plan.reason == "no feasible action plan"
+
+    def test_run_record_failure_warns(self, fake_watcher_client):
+        deployment = Mock()
+        cluster = deployment.get_client.return_value.cluster
+        cluster.update_maintenance_plan.side_effect = RemoteException("unreachable")
+        step = CreateWatcherHostMaintenanceAuditStep(deployment, "node-1")
+
+        result = step.run(None)
+
+        assert result.result_type == ResultType.COMPLETED
+
+
 class TestMicroCephActionStep:
     @patch("sunbeam.steps.maintenance.JujuActionHelper")
     def test_run(self, mock_action_helper):
@@ -263,6 +414,223 @@ class TestRunWatcherAuditStep:
         )
 
 
+def _service_action():
+    return Mock(
+        action_type="change_nova_service_state",
+        input_parameters={
+            "resource_name": "fake-node",
+            "state": "disabled",
+            "disabled_reason": "watcher_maintaining",
+        },
+    )
+
+
+def _migrate_action(instance_id):
+    return Mock(
+        action_type="migrate",
+        input_parameters={"resource_id": instance_id, "migration_type": "live"},
+    )
+
+
+class TestRunWatcherActionsStep:
+    @pytest.fixture
+    def mock_conn(self):
+        with patch("sunbeam.steps.maintenance.get_admin_connection") as mock:
+            yield mock.return_value
+
+    def test_can_run(self):
+        actions = [_service_action(), _migrate_action("inst-0")]
+        assert RunWatcherActionsStep.can_run(actions)
+        assert not RunWatcherActionsStep.can_run([Mock(action_type="nop")])
+
+    def test_run_bounds_parallel_migrations(self, mock_conn):
+        lock = threading.Lock()
+        running = peak = 0
+
+        def migrate(*args, **kwargs):
+            nonlocal running, peak
+            with lock:
+                running += 1
+                peak = max(peak, running)
+            time.sleep(0.05)
+            with lock:
+                running -= 1
+
+        service = _service_action()
+        migrations = [_migrate_action(f"inst-{i}") for i in range(5)]
+        mock_conn.compute.services.return_value = ["nova-compute"]
+        with patch(
+            "sunbeam.steps.maintenance.migrate_instance", side_effect=migrate
+        ):
+            step = RunWatcherActionsStep(
+                Mock(), Mock(), "fake-node", [service, *migrations], max_parallel=2
+            )
+            result = step.run(None)
+
+        assert result.result_type == ResultType.COMPLETED
+        assert peak == 2
+        assert all(action.state == "SUCCEEDED" for action in result.message)
+        mock_conn.compute.services.assert_called_once_with(
+            host="fake-node", binary="nova-compute"
+        )
+        mock_conn.compute.disable_service.assert_called_once_with(
+            "nova-compute", disabled_reason="watcher_maintaining"
+        )
+
+    def test_run_failed_migration_keeps_draining_queue(self, mock_conn):
+        def migrate(conn, instance_id, source, **kwargs):
+            if instance_id == "inst-0":
+                raise InstanceMigrationFailedException("migration is error")
+
+        migrations = [_migrate_action(f"inst-{i}") for i in range(3)]
+        with patch(
+            "sunbeam.steps.maintenance.migrate_instance", side_effect=migrate
+        ) as mock_migrate:
+            step = RunWatcherActionsStep(
+                Mock(), Mock(), "fake-node", migrations, max_parallel=1
+            )
+            result = step.run(None)
+
+        assert result.result_type == ResultType.FAILED
+        assert [action.state for action in migrations] == [
+            "FAILED",
+            "SUCCEEDED",
+            "SUCCEEDED",
+        ]
+        assert mock_migrate.call_count == 3
+
+    def test_run_disable_service_failed(self, mock_conn):
+        mock_conn.compute.services.side_effect = openstack.exceptions.SDKException
+        service = _service_action()
+        migration = _migrate_action("inst-0")
+        with patch("sunbeam.steps.maintenance.migrate_instance") as mock_migrate:
+            step = RunWatcherActionsStep(
+                Mock(), Mock(), "fake-node", [service, migration], max_parallel=1
+            )
+            result = step.run(None)
+
+        assert result.result_type == ResultType.FAILED
+        assert service.state == "FAILED"
+        assert migration.state == "CANCELLED"
+        mock_migrate.assert_not_called()
+
+
+class TestMigrateInstancesStep:
+    def test_run_records_hosts(self):
+        def migrate(conn, instance_id, source, **kwargs):
+            if instance_id == "inst-1":
+                raise InstanceMigrationFailedException("migration is error")
+            return "node-2"
+
+        migrations = [
+            {"instance": f"inst-{i}", "migration_type": "live", "destination": None}
+            for i in range(2)
+        ]
+        with (
+            patch("sunbeam.steps.maintenance.get_admin_connection"),
+            patch("sunbeam.steps.maintenance.migrate_instance", side_effect=migrate),
+        ):
+            step = MigrateInstancesStep(
+                Mock(), Mock(), "node-1", migrations, max_parallel=2
+            )
+            result = step.run(None)
+
+        assert result.result_type == ResultType.FAILED
+        assert [(m["state"], m["host"]) for m in migrations] == [
+            ("SUCCEEDED", "node-2"),
+            ("FAILED", None),
+        ]
+
+    def test_run_reports_progress(self):
+        def migrate(conn, instance_id, source, progress=None, **kwargs):
+            progress(50)
+            return "node-2"
+
+        events = []
+        status = Mock()
+        migrations = [
+            {"instance": "inst-0", "migration_type": "live", "destination": None}
+        ]
+        with (
+            patch("sunbeam.steps.maintenance.get_admin_connection"),
+            patch("sunbeam.steps.maintenance.migrate_instance", side_effect=migrate),
+        ):
+            step = MigrateInstancesStep(
+                Mock(), Mock(), "node-1", migrations, 1, progress=events.append
+            )
+            step.run(status)
+
+        assert [(e.state, e.percent, e.completed) for e in events] == [
+            ("migrating", None, 0),
+            ("migrating", 50, 0),
+            ("succeeded", None, 1),
+        ]
+        status.update.assert_called_with(
+            "Migrate instances ... migrated instances (1/1), inst-0 migrated"
+        )
+
+
+class TestMigrationProgressTracker:
+    @pytest.fixture
+    def clock(self):
+        return Mock(return_value=0)
+
+    @pytest.fixture
+    def events(self):
+        return []
+
+    def test_reports_progress_changes(self, clock, events):
+        tracker = MigrationProgressTracker(2, events.append, clock=clock)
+
+        tracker.started("inst-0")
+        tracker.update("inst-0", 10)
+        tracker.update("inst-0", 10)
+        tracker.update("inst-0", 60)
+        tracker.finished("inst-0", True)
+
+        assert [(e.state, e.percent, e.completed) for e in events] == [
+            ("migrating", None, 0),
+            ("migrating", 10, 0),
+            ("migrating", 60, 0),
+            ("succeeded", None, 1),
+        ]
+        assert all(e.instance == "inst-0" and e.total == 2 for e in events)
+
+    def test_reports_stalled_migration(self, clock, events):
+        tracker = MigrationProgressTracker(
+            1, events.append, stall_timeout=300, clock=clock
+        )
+
+        tracker.started("inst-0")
+        tracker.update("inst-0", 40)
+        clock.return_value = 299
+        tracker.update("inst-0", 40)
+        clock.return_value = 300
+        tracker.update("inst-0", 40)
+        clock.return_value = 420
+        tracker.update("inst-0", 40)
+        clock.return_value = 600
+        tracker.update("inst-0", 40)
+
+        stalled = [e for e in events if e.state == "stalled"]
+        assert [e.message for e in stalled] == [
+            "no progress for 5 minutes",
+            "no progress for 10 minutes",
+        ]
+        assert stalled[0].percent == 40
+
+    def test_failed_migration(self, clock, events):
+        tracker = MigrationProgressTracker(1, events.append, clock=clock)
+
+        tracker.started("inst-0")
+        tracker.finished("inst-0", False, "migration is error")
+
+        assert (events[-1].state, events[-1].message) == (
+            "failed",
+            "migration is error",
+        )
+
+
 class TestDrainControlRoleNodeStep:
     def test_run(self):
         with patch("sunbeam.steps.maintenance.DrainK8SUnitStep.run") as parent_run:
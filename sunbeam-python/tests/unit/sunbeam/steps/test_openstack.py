@@ -210,7 +210,7 @@ class TestDeployControlPlaneStep:
 
         basic_jhelper.wait_until_active.assert_called_once()
         assert result.result_type == ResultType.FAILED
-        assert result.message == "timed out"
+        assert result.message.startswith("Step DeployControlPlaneStep timed out")
 
     def test_run_unit_in_error_state(
         self,
@@ -875,7 +875,9 @@ class TestReapplyOpenStackTerraformPlanStep:
 
         openstack_jhelper.wait_until_active.assert_called_once()
         assert result.result_type == ResultType.FAILED
-        assert result.message == "timed out"
+        assert result.message.startswith(
+            "Step ReapplyOpenStackTerraformPlanStep timed out"
+        )
 
     def test_run_unit_in_error_state(
         self,
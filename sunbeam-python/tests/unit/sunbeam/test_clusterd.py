@@ -1,6 +1,7 @@
 # SPDX-FileCopyrightText: 2023 - Canonical Ltd
 # SPDX-License-Identifier: Apache-2.0
 
+import datetime
 import json
 import subprocess
 from unittest.mock import MagicMock, Mock, patch
@@ -8,25 +9,35 @@ from unittest.mock import MagicMock, Mock, patch
 import pytest
 from requests.exceptions import HTTPError
 
+import sunbeam.clusterd.models as models
 import sunbeam.clusterd.service as service
 import sunbeam.core.questions
 from sunbeam.clusterd.cluster import ClusterService
+from sunbeam.clusterd.compatibility import (
+    EXPECTED_SCHEMA_VERSION,
+    IncompatibleClusterdException,
+    check_clusterd_compatibility,
+)
 from sunbeam.clusterd.service import ConfigItemNotFoundException
 from sunbeam.core.common import ResultType
 from sunbeam.core.juju import ApplicationNotFoundException
 from sunbeam.steps.clusterd import (
+    CheckNodeReachableStep,
     ClusterAddJujuUserStep,
     ClusterAddNodeStep,
     ClusterInitStep,
     ClusterJoinNodeStep,
     ClusterListNodeStep,
     ClusterRemoveNodeStep,
+    ClusterScopeJoinTokenStep,
     ClusterUpdateJujuControllerStep,
     ClusterUpdateJujuUserStep,
+    ClusterUpdateNodeInventoryStep,
     ClusterUpdateNodeStep,
     DeploySunbeamClusterdApplicationStep,
     PromptCheckNodeExistStep,
     SaveManagementCidrStep,
+    is_node_removed,
 )
 
 
@@ -74,7 +85,40 @@ class TestClusterdSteps:
         add_node_step.client = MagicMock()
         result = add_node_step.run()
         assert result.result_type == ResultType.COMPLETED
-        add_node_step.client.cluster.add_node.assert_called_once_with(name="node-1")
+        add_node_step.client.cluster.add_node.assert_called_once_with(
+            name="node-1", expire_after=None
+        )
+
+    def test_scope_join_token_step(self, cclient):
+        step = ClusterScopeJoinTokenStep(
+            cclient, name="node-2", roles=["compute"], ttl="1h"
+        )
+        step.client = MagicMock()
+        step.client.cluster.get_cluster_members.return_value = [{"name": "node-1"}]
+        assert step.is_skip().result_type == ResultType.COMPLETED
+        result = step.run()
+        assert result.result_type == ResultType.COMPLETED
+        step.client.cluster.create_join_token_scope.assert_called_once_with(
+            "node-2", ["compute"], ttl="1h"
+        )
+
+    def test_scope_join_token_step_member(self, cclient):
+        step = ClusterScopeJoinTokenStep(cclient, name="node-1", roles=["compute"])
+        step.client = MagicMock()
+        step.client.cluster.get_cluster_members.return_value = [{"name": "node-1"}]
+        assert step.is_skip().result_type == ResultType.SKIPPED
+
+    def test_scope_join_token_step_already_scoped(self, cclient):
+        step = ClusterScopeJoinTokenStep(cclient, name="node-2", roles=["compute"])
+        step.client = MagicMock()
+        step.client.cluster.create_join_token_scope.side_effect = (
+            service.JoinTokenScopeAlreadyExistsException(
+                'Join token of node "node-2" is already scoped'
+            )
+        )
+        result = step.run()
+        assert result.result_type == ResultType.FAILED
+        assert "already scoped" in result.message
 
     def test_join_node_step(self, cclient):
         join_node_step = ClusterJoinNodeStep(
@@ -89,6 +133,108 @@ class TestClusterdSteps:
         assert result.result_type == ResultType.COMPLETED
         join_node_step.client.cluster.join_node.assert_called_once()
 
+    def test_join_node_step_token_rejected(self, cclient):
+        join_node_step = ClusterJoinNodeStep(
+            cclient,
+            token="TESTTOKEN",
+            host_address="10.0.0.3",
+            fqdn="node1",
+            role=["control"],
+        )
+        join_node_step.client = MagicMock()
+        join_node_step.client.cluster.join_node.side_effect = (
+            service.JoinTokenRejectedException(
+                'Join token of node "node1" does not permit role "control", '
+                "only compute"
+            )
+        )
+        result = join_node_step.run()
+        assert result.result_type == ResultType.FAILED
+        assert "does not permit" in result.message
+
+    def test_join_node_step_records_joined_node(self, cclient):
+        # A previous join failed to record the node, e.g. on an undefined role
+        cclient.cluster.get_cluster_members.return_value = [{"name": "node1"}]
+        cclient.cluster.get_node_info.side_effect = (
+            service.NodeNotExistInClusterException("Node not found")
+        )
+        join_node_step = ClusterJoinNodeStep(
+            cclient,
+            token="TESTTOKEN",
+            host_address="10.0.0.3",
+            fqdn="node1",
+            role=["compute", "load-balancer"],
+        )
+
+        assert join_node_step.is_skip().result_type == ResultType.COMPLETED
+        result = join_node_step.run()
+
+        assert result.result_type == ResultType.COMPLETED
+        cclient.cluster.join_node.assert_not_called()
+        cclient.cluster.add_node_info.assert_called_once_with(
+            "node1",
+            ["compute", "load-balancer"],
+            addresses={"management": "10.0.0.3"},
+            preferred_api="management",
+        )
+
+    def test_join_node_step_preferred_api_address(self, cclient):
+        join_node_step = ClusterJoinNodeStep(
+            cclient,
+            token="TESTTOKEN",
+            host_address="10.0.0.3",
+            fqdn="node1",
+            role=["compute"],
+            addresses={"data": "10.1.0.3"},
+            preferred_api="data",
+        )
+
+        result = join_node_step.run()
+
+        assert result.result_type == ResultType.COMPLETED
+        cclient.cluster.join_node.assert_called_once_with(
+            name="node1",
+            address="10.1.0.3:7000",
+            token="TESTTOKEN",
+            role=["compute"],
+            addresses={"management": "10.0.0.3", "data": "10.1.0.3"},
+            preferred_api="data",
+        )
+
+    def test_join_node_step_unknown_preferred_api_address(self, cclient):
+        join_node_step = ClusterJoinNodeStep(
+            cclient,
+            token="TESTTOKEN",
+            host_address="10.0.0.3",
+            fqdn="node1",
+            role=["compute"],
+            addresses={"data": "10.1.0.3"},
+            preferred_api="public",
+        )
+
+        result = join_node_step.run()
+
+        assert result.result_type == ResultType.FAILED
+        assert "'public' is not an address" in result.message
+        cclient.cluster.join_node.assert_not_called()
+
+    def test_join_node_step_undefined_role(self, cclient):
+        cclient.cluster.join_node.side_effect = service.InvalidNodeRoleException(
+            'Unknown node role "load-balancer"'
+        )
+        join_node_step = ClusterJoinNodeStep(
+            cclient,
+            token="TESTTOKEN",
+            host_address="10.0.0.3",
+            fqdn="node1",
+            role=["load-balancer"],
+        )
+
+        result = join_node_step.run()
+
+        assert result.result_type == ResultType.FAILED
+        assert "load-balancer" in result.message
+
     def test_list_node_step(self, cclient):
         list_node_step = ClusterListNodeStep(cclient)
         list_node_step.client = MagicMock()
@@ -107,12 +253,80 @@ class TestClusterdSteps:
             "node-2", ["control"], 1
         )
 
+    def test_update_node_inventory_step(self, cclient):
+        hardware = models.Hardware(cpu_count=4, memory_total=8589934592)
+        step = ClusterUpdateNodeInventoryStep(cclient, name="node-2")
+        with patch(
+            "sunbeam.steps.clusterd.inventory.collect_hardware", return_value=hardware
+        ):
+            result = step.run()
+        assert result.result_type == ResultType.COMPLETED
+        cclient.cluster.update_node_inventory.assert_called_once_with(
+            "node-2", hardware
+        )
+
+    def test_update_node_inventory_step_collection_failure(self, cclient):
+        step = ClusterUpdateNodeInventoryStep(cclient, name="node-2")
+        with patch(
+            "sunbeam.steps.clusterd.inventory.collect_hardware",
+            side_effect=OSError("No such file"),
+        ):
+            result = step.run()
+        assert result.result_type == ResultType.SKIPPED
+        cclient.cluster.update_node_inventory.assert_not_called()
+
     def test_remove_node_step(self, cclient):
         remove_node_step = ClusterRemoveNodeStep(cclient, name="node-2")
         remove_node_step.client = MagicMock()
         result = remove_node_step.run()
         assert result.result_type == ResultType.COMPLETED
-        remove_node_step.client.cluster.remove_node.assert_called_once_with("node-2")
+        remove_node_step.client.cluster.remove_node.assert_called_once_with(
+            "node-2", force=False
+        )
+
+    def test_remove_node_step_force(self, cclient):
+        remove_node_step = ClusterRemoveNodeStep(cclient, name="node-2", force=True)
+        remove_node_step.client = MagicMock()
+        result = remove_node_step.run()
+        assert result.result_type == ResultType.COMPLETED
+        remove_node_step.client.cluster.remove_node.assert_called_once_with(
+            "node-2", force=True
+        )
+
+    def test_check_node_reachable(self, cclient):
+        cclient.cluster.get_cluster_members.return_value = [
+            {"name": "node-1", "status": "ONLINE"},
+            {"name": "node-2", "status": "ONLINE"},
+        ]
+        step = CheckNodeReachableStep(cclient, "node-2")
+        assert step.run().result_type == ResultType.COMPLETED
+
+    def test_check_node_unreachable(self, cclient):
+        cclient.cluster.get_cluster_members.return_value = [
+            {"name": "node-1", "status": "ONLINE"},
+            {"name": "node-2", "status": "UNREACHABLE"},
+        ]
+        step = CheckNodeReachableStep(cclient, "node-2")
+        result = step.run()
+        assert result.result_type == ResultType.FAILED
+        assert "UNREACHABLE" in result.message
+        assert "--force" in result.message
+
+    def test_is_node_removed(self, cclient):
+        cclient.cluster.get_cluster_members.return_value = [{"name": "node-1"}]
+        cclient.cluster.list_tokens.return_value = [{"name": "node-2"}]
+        cclient.cluster.get_node_info.side_effect = (
+            service.NodeNotExistInClusterException("Node not found")
+        )
+        assert is_node_removed(cclient, "node-1") is False
+        assert is_node_removed(cclient, "node-2") is False
+        assert is_node_removed(cclient, "node-3") is True
+
+    def test_is_node_removed_record_left(self, cclient):
+        cclient.cluster.get_cluster_members.return_value = []
+        cclient.cluster.list_tokens.return_value = []
+        cclient.cluster.get_node_info.return_value = {"name": "node-3"}
+        assert is_node_removed(cclient, "node-3") is False
 
     def test_add_juju_user_step(self, cclient):
         add_juju_user_step = ClusterAddJujuUserStep(
@@ -403,7 +617,9 @@ class TestClusterService:
         mock_session.request.return_value = mock_response
 
         cs = ClusterService(mock_session, "http+unix://mock")
-        cs.join("node-2", "10.10.1.11:7000", "TESTTOKEN")
+        cs.join("node-2", "10.10.1.11:7000", "TESTTOKEN", ["compute", "storage"])
+        data = json.loads(mock_session.request.call_args.kwargs["data"])
+        assert data["config"] == {"roles": "compute,storage"}
 
     def test_join_with_wrong_token(self):
         json_data = {
@@ -426,7 +642,9 @@ class TestClusterService:
 
         cs = ClusterService(mock_session, "http+unix://mock")
         with pytest.raises(service.NodeJoinException):
-            cs.join("node-2", "10.10.1.11:7000", "TESTTOKEN")
+            cs.join("node-2", "10.10.1.11:7000", "TESTTOKEN", ["compute", "storage"])
+        data = json.loads(mock_session.request.call_args.kwargs["data"])
+        assert data["config"] == {"roles": "compute,storage"}
 
     def test_join_when_node_already_joined(self):
         json_data = {
@@ -452,7 +670,9 @@ class TestClusterService:
 
         cs = ClusterService(mock_session, "http+unix://mock")
         with pytest.raises(service.NodeAlreadyExistsException):
-            cs.join("node-2", "10.10.1.11:7000", "TESTTOKEN")
+            cs.join("node-2", "10.10.1.11:7000", "TESTTOKEN", ["compute", "storage"])
+        data = json.loads(mock_session.request.call_args.kwargs["data"])
+        assert data["config"] == {"roles": "compute,storage"}
 
     def test_get_cluster_members(self):
         json_data = {
@@ -607,6 +827,58 @@ class TestClusterService:
         cs = ClusterService(mock_session, "http+unix://mock")
         cs.remove("node-2")
 
+    def test_remove_force(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.remove("node-2", force=True)
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/core/1.0/cluster/node-2"
+        assert kwargs["params"] == {"force": "1"}
+
+    def test_remove_node_already_removed_record(self):
+        cs = ClusterService(MagicMock(), "http+unix://mock")
+        with (
+            patch.object(cs, "get_cluster_members", return_value=[{"name": "node-2"}]),
+            patch.object(
+                cs,
+                "remove_node_info",
+                side_effect=service.NodeNotExistInClusterException("Node not found"),
+            ),
+            patch.object(cs, "remove") as remove,
+        ):
+            cs.remove_node("node-2", force=True)
+        remove.assert_called_once_with("node-2", force=True)
+
+    def test_remove_node_not_member_lifts_join_token_scope(self):
+        cs = ClusterService(MagicMock(), "http+unix://mock")
+        with (
+            patch.object(cs, "get_cluster_members", return_value=[{"name": "node-1"}]),
+            patch.object(cs, "remove_node_info"),
+            patch.object(
+                cs,
+                "delete_join_token_scope",
+                side_effect=service.JoinTokenScopeNotFoundException(
+                    'Join token scope of node "node-2" not found'
+                ),
+            ) as delete_scope,
+            patch.object(cs, "delete_token") as delete_token,
+        ):
+            cs.remove_node("node-2")
+        delete_scope.assert_called_once_with("node-2")
+        delete_token.assert_called_once_with("node-2")
+
     def test_remove_when_node_doesnot_exist(self):
         json_data = {
             "type": "error",
@@ -698,6 +970,26 @@ class TestClusterService:
         cs = ClusterService(mock_session, "http+unix://mock")
         cs.remove_node_info("node-1")
 
+    def test_remove_node_info_with_reason(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.remove_node_info("node-1", reason="disk failure")
+        _, kwargs = mock_session.request.call_args
+        assert kwargs["method"] == "delete"
+        assert kwargs["params"] == {"reason": "disk failure"}
+
     def test_list_nodes(self):
         json_data = {
             "type": "sync",
@@ -727,6 +1019,203 @@ class TestClusterService:
         nodes_from_mock = [node.get("name") for node in json_data.get("metadata")]
         assert nodes_from_mock == nodes_from_call
 
+    def test_list_nodes_page(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "name": "node-1",
+                    "role": ["compute"],
+                    "machineid": 0,
+                }
+            ],
+        }
+        mock_response = self._mock_response(
+            status=200,
+            json_data=json_data,
+        )
+        mock_response.headers = {"X-Total-Count": "312"}
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        nodes, total = cs.list_nodes_page(role=["compute", "storage"], limit=50)
+        assert [node["name"] for node in nodes] == ["node-1"]
+        assert total == 312
+        _, kwargs = mock_session.request.call_args
+        assert kwargs["params"] == {"role": ["compute", "storage"], "limit": 50}
+
+    def test_list_nodes_page_unknown_role(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 400,
+            "error": 'Unknown node role "gpu"',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=400,
+            json_data=json_data,
+            raise_for_status=HTTPError("Bad Request"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.InvalidNodeRoleException):
+            cs.list_nodes_page(role=["gpu"])
+
+    def test_list_nodes_page_by_label(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "name": "node-1",
+                    "role": ["compute"],
+                    "machineid": 0,
+                    "labels": {"gpu": "true"},
+                }
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_response.headers = {"X-Total-Count": "1"}
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        nodes, total = cs.list_nodes_page(label=["gpu=true"])
+        assert nodes[0]["labels"] == {"gpu": "true"}
+        assert total == 1
+        _, kwargs = mock_session.request.call_args
+        assert kwargs["params"] == {"label": ["gpu=true"]}
+
+    def test_update_node_labels(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {"rack": "A3"},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        labels = cs.update_node_labels("node-1", {"rack": "A3", "gpu": None})
+        assert labels == {"rack": "A3"}
+        _, kwargs = mock_session.request.call_args
+        assert kwargs["method"] == "patch"
+        assert kwargs["url"].endswith("/1.0/nodes/node-1/labels")
+        assert json.loads(kwargs["data"]) == {"rack": "A3", "gpu": None}
+
+    def test_set_node_labels_invalid_key(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 400,
+            "error": 'Invalid label key "Rack": must be at most 63 lowercase'
+            " alphanumerics, '-', '.' or '_'",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=400,
+            json_data=json_data,
+            raise_for_status=HTTPError("Bad Request"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.InvalidNodeLabelException):
+            cs.set_node_labels("node-1", {"Rack": "A3"})
+
+    def test_list_maintenance_status(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "node": "node-1",
+                    "status": "enabled",
+                    "entered_at": "2025-03-01T10:00:00Z",
+                    "strategy": "stop-osds",
+                    "triggered_by": "ubuntu@node-2",
+                    "message": "",
+                },
+                {
+                    "node": "node-2",
+                    "status": "disabled",
+                    "entered_at": "",
+                    "strategy": "",
+                    "triggered_by": "",
+                    "message": "",
+                },
+                {
+                    "node": "node-3",
+                    "status": "degraded",
+                    "entered_at": "2025-03-01T11:00:00Z",
+                    "strategy": "",
+                    "triggered_by": "ubuntu@node-2",
+                    "message": "Failed steps: MicroCephActionStep",
+                },
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        statuses = cs.list_maintenance_status().root
+        assert [(s.node, s.status) for s in statuses] == [
+            ("node-1", "enabled"),
+            ("node-2", "disabled"),
+            ("node-3", "degraded"),
+        ]
+        assert statuses[2].message == "Failed steps: MicroCephActionStep"
+
+    def test_force_unlock_terraform_plan(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.force_unlock_terraform_plan("openstack-plan")
+        mock_session.request.assert_called_once()
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "put"
+        assert kwargs["url"].endswith("/1.0/terraformunlock/openstack-plan")
+        assert kwargs["data"] == ""
+
     def test_update_node_info(self):
         json_data = {
             "type": "sync",
@@ -748,6 +1237,1491 @@ class TestClusterService:
         cs = ClusterService(mock_session, "http+unix://mock")
         cs.update_node_info("node-2", ["control"], 2)
 
+    def test_backup_redact_secrets(self):
+        bundle = {
+            "version": 1,
+            "schema_internal": 4,
+            "schema_external": 11,
+            "created_at": "2025-03-01T10:00:00Z",
+            "redacted": True,
+            "nodes": [],
+            "config": [
+                {"key": "tfstate-openstack-plan", "value": "<redacted>", "revision": 2}
+            ],
+            "manifests": [],
+            "jujuusers": [{"username": "admin", "token": "<redacted>"}],
+            "storage_backends": [],
+            "feature_gates": [],
+        }
+        mock_response = self._mock_response(status=200, json_data=bundle)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        assert cs.backup(redact_secrets=True) == bundle
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "get"
+        assert kwargs["url"].endswith("/1.0/backup")
+        assert kwargs["params"] == {"redact_secrets": "true"}
+
+    def test_restore_schema_mismatch(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": "Backup schema version mismatch: backup has internal 4 and"
+            " external 10, cluster has internal 4 and external 11",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.BackupSchemaMismatchException):
+            cs.restore({"version": 1, "schema_internal": 4, "schema_external": 10})
+
+    def test_verify_database(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "ok": False,
+                "problems": ["Page 5 is never used"],
+                "tables": [{"name": "nodes", "rows": 3}],
+                "page_size": 4096,
+                "pages": 12,
+                "free_pages": 2,
+            },
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=200, json_data=json_data
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        report = cs.verify_database()
+        assert not report.ok
+        assert report.problems == ["Page 5 is never used"]
+        assert report.tables[0].rows == 3
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "get"
+        assert kwargs["url"] == "http+unix://mock/1.0/database/verify"
+
+    def test_compact_database(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "purged_revisions": 4,
+                "purged_tombstones": 1,
+                "free_pages": 3,
+            },
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=200, json_data=json_data
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        compaction = cs.compact_database()
+        assert compaction.purged_revisions == 4
+        assert compaction.purged_tombstones == 1
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "post"
+        assert kwargs["url"] == "http+unix://mock/1.0/database/compact"
+
+    def test_batch_nodes_rolled_back(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "applied": False,
+                "results": [
+                    {"action": "add", "name": "node-2", "status": "rolled_back"},
+                    {
+                        "action": "remove",
+                        "name": "node-3",
+                        "status": "failed",
+                        "error": "Failed to retrieve node details: Node not found",
+                    },
+                    {"action": "add", "name": "node-4", "status": "skipped"},
+                ],
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        operations = [
+            {"action": "add", "name": "node-2", "role": ["compute"]},
+            {"action": "remove", "name": "node-3"},
+            {"action": "add", "name": "node-4", "role": ["compute"]},
+        ]
+        response = cs.batch_nodes(operations)
+        assert not response.applied
+        assert [(r.name, r.status) for r in response.results] == [
+            ("node-2", "rolled_back"),
+            ("node-3", "failed"),
+            ("node-4", "skipped"),
+        ]
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "post"
+        assert kwargs["url"].endswith("/1.0/nodes:batch")
+        assert json.loads(kwargs["data"]) == {"operations": operations}
+
+    def test_update_and_get_node_inventory(self):
+        inventory = {
+            "node": "node-1",
+            "revision": 2,
+            "collected_at": "2025-03-01T12:00:00Z",
+            "refreshed_at": "2025-03-01T12:00:00Z",
+            "hardware": {
+                "cpu_count": 16,
+                "memory_total": 68719476736,
+                "nics": [
+                    {"name": "eno1", "mac_address": "00:16:3e:00:00:01", "speed": 0}
+                ],
+                "block_devices": [
+                    {
+                        "name": "sda",
+                        "model": "SSD 870",
+                        "size": 1000204886016,
+                        "rotational": False,
+                    }
+                ],
+            },
+        }
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": inventory,
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        hardware = models.Hardware(**inventory["hardware"])
+        recorded = cs.update_node_inventory("node-1", hardware)
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "put"
+        assert kwargs["url"].endswith("/1.0/nodes/node-1/inventory")
+        assert json.loads(kwargs["data"]) == inventory["hardware"]
+        assert recorded.revision == 2
+
+        read = cs.get_node_inventory("node-1")
+        assert mock_session.request.call_args.kwargs["method"] == "get"
+        assert read == recorded
+        assert read.hardware.block_devices[0].size == 1000204886016
+
+    def test_update_maintenance_status_with_ttl(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.update_maintenance_status("node-1", "enabled", ttl="2h")
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"].endswith("/1.0/maintenance/node-1")
+        assert json.loads(kwargs["data"])["ttl"] == "2h"
+
+        cs.update_maintenance_status("node-1", "disabled")
+        assert "ttl" not in json.loads(mock_session.request.call_args.kwargs["data"])
+
+    def test_get_config_schema(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "key": "deployment.type",
+                    "type": "enum",
+                    "description": "Type of the deployment",
+                    "values": ["local", "maas"],
+                },
+                {"key": "sunbeam_bootstrapped", "type": "bool"},
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        schema = cs.get_config_schema()
+        assert [(k.key, k.type) for k in schema.root] == [
+            ("deployment.type", "enum"),
+            ("sunbeam_bootstrapped", "bool"),
+        ]
+        assert schema.root[0].values == ["local", "maas"]
+        assert schema.root[1].values == []
+
+    def test_update_config_invalid_value(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 400,
+            "error": 'Invalid value for config key "deployment.type":'
+            ' "manual" is not one of local, maas',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=400,
+            json_data=json_data,
+            raise_for_status=HTTPError("Bad Request"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.InvalidConfigException):
+            cs.update_config("deployment.type", "manual", strict=True)
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["headers"] == {
+            "X-Request-ID": service.REQUEST_ID,
+            "X-Sunbeam-Config-Strict": "true",
+        }
+
+    def test_get_role_config(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": '"10.0.0.1"',
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_response.headers = {"X-Sunbeam-Config-Scope": "global"}
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        value, scope = cs.get_role_config("external_gateway", "storage")
+        assert value == '"10.0.0.1"'
+        assert scope == "global"
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/config/external_gateway"
+        assert kwargs["params"] == {"role": "storage"}
+
+    def test_update_config_role(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=200, json_data=json_data
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.update_config("external_gateway", '"10.0.0.2"', role="storage")
+        assert mock_session.request.call_args.kwargs["params"] == {"role": "storage"}
+
+        cs.update_config("external_gateway", '"10.0.0.1"')
+        assert mock_session.request.call_args.kwargs["params"] is None
+
+    def test_delete_config_role(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=200, json_data=json_data
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.delete_config("external_gateway", role="storage")
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "delete"
+        assert kwargs["params"] == {"role": "storage"}
+
+    def test_get_meta(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "version": "0.1",
+                "schema_internal": 4,
+                "schema_external": 13,
+                "api_prefixes": ["1.0", "1.1"],
+                "microcluster_version": "v2.2.0",
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        meta = cs.get_meta()
+        assert meta.schema_external == 13
+        assert meta.api_prefixes == ["1.0", "1.1"]
+        url = mock_session.request.call_args.kwargs["url"]
+        assert url == "http+unix://mock/1.0/meta"
+
+    def test_get_maintenance_progress(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "seq": 3,
+                    "node": "node1",
+                    "time": "2026-01-01T00:00:00Z",
+                    "instance": "inst-1",
+                    "state": "migrating",
+                    "completed": 0,
+                    "total": 2,
+                    "percent": 40,
+                }
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        events = cs.get_maintenance_progress("node1", after=2, wait=30)
+        assert [(event.seq, event.percent) for event in events] == [(3, 40)]
+        url = mock_session.request.call_args.kwargs["url"]
+        assert url == "http+unix://mock/1.0/maintenance/node1/progress?after=2&wait=30s"
+
+    def test_update_maintenance_plan(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.update_maintenance_plan(
+            models.MaintenancePlan(
+                node="node1",
+                operation="enable",
+                audit="audit-1",
+                state="timed-out",
+                reason="Watcher audit audit-1 did not complete in 180s",
+            )
+        )
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "put"
+        assert kwargs["url"] == "http+unix://mock/1.0/maintenance/node1/plan"
+        data = json.loads(kwargs["data"])
+        assert data["state"] == "timed-out"
+        assert data["actions"] == []
+        assert "node" not in data
+
+    def test_get_maintenance_plans(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "node": "node1",
+                    "operation": "enable",
+                    "audit": "audit-1",
+                    "action_plan": "plan-1",
+                    "state": "succeeded",
+                    "reason": "",
+                    "actions": [
+                        {
+                            "uuid": "action-1",
+                            "type": "migrate",
+                            "state": "PENDING",
+                            "parameters": {"resource_id": "inst-1"},
+                            "description": "Moving a VM instance",
+                        }
+                    ],
+                    "efficacy": [],
+                    "created_at": "2026-01-01T00:00:00Z",
+                    "created_by": "ubuntu@node1",
+                }
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        plans = cs.get_maintenance_plans("node1", "enable")
+        assert plans[0].actions[0].parameters == {"resource_id": "inst-1"}
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/maintenance/node1/plan"
+        assert kwargs["params"] == {"operation": "enable"}
+
+    def test_schedule_maintenance(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "id": 4,
+                "node": "node1",
+                "window_start": "2025-07-01T02:00:00Z",
+                "options": {"force": True},
+                "status": "pending",
+                "created_at": "2025-06-30T12:00:00Z",
+                "created_by": "admin",
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        scheduled = cs.schedule_maintenance(
+            models.ScheduledMaintenance(
+                node="node1",
+                window_start="2025-07-01T02:00:00Z",
+                options=models.MaintenanceOptions(force=True),
+            )
+        )
+        assert scheduled.id == 4
+        assert scheduled.options.force
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/maintenance-schedule"
+        assert json.loads(kwargs["data"]) == {
+            "node": "node1",
+            "window_start": "2025-07-01T02:00:00Z",
+            "window_end": "",
+            "options": {"force": True},
+        }
+
+    def test_schedule_maintenance_conflict(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": 'Node "node1" already has pending scheduled maintenance 4',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ScheduledMaintenanceConflictException):
+            cs.schedule_maintenance(
+                models.ScheduledMaintenance(
+                    node="node1", window_start="2025-07-01T02:00:00Z"
+                )
+            )
+
+    def test_cancel_scheduled_maintenance_not_found(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 404,
+            "error": "Scheduled maintenance 4 not found",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=404,
+            json_data=json_data,
+            raise_for_status=HTTPError("Not Found"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ScheduledMaintenanceNotFoundException):
+            cs.cancel_scheduled_maintenance(4)
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/maintenance-schedule/4"
+
+    def test_create_operation(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "id": 3,
+                "command": ["cluster", "maintenance", "enable", "--yes", "node1"],
+                "member": "node1",
+                "status": "pending",
+                "created_at": "2025-07-01T02:00:00Z",
+                "created_by": "admin",
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        operation = cs.create_operation(
+            ["cluster", "maintenance", "enable", "--yes", "node1"]
+        )
+        assert operation.id == 3
+        assert operation.status == "pending"
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/operations"
+        assert json.loads(kwargs["data"]) == {
+            "command": ["cluster", "maintenance", "enable", "--yes", "node1"]
+        }
+
+    def test_create_operation_invalid(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 400,
+            "error": 'Invalid operation command "cluster bootstrap", expected one'
+            " of: cluster maintenance enable",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=400,
+            json_data=json_data,
+            raise_for_status=HTTPError("Bad Request"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.InvalidOperationException):
+            cs.create_operation(["cluster", "bootstrap"])
+
+    def test_get_operation_not_found(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 404,
+            "error": "Operation 3 not found",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=404,
+            json_data=json_data,
+            raise_for_status=HTTPError("Not Found"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.OperationNotFoundException):
+            cs.get_operation(3)
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/operations/3"
+
+    def test_cancel_operation_conflict(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": "Operation 3 is succeeded, only pending and running ones can"
+            " be cancelled",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.OperationConflictException):
+            cs.cancel_operation(3)
+        assert mock_session.request.call_args.kwargs["method"] == "delete"
+
+    def test_update_operation(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "id": 3,
+                "command": ["cluster", "maintenance", "enable", "--yes", "node1"],
+                "member": "node1",
+                "runner": "laptop:1",
+                "status": "cancelling",
+                "heartbeat_at": "2025-07-01T02:01:00Z",
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        operation = cs.update_operation(3, "laptop:1", progress="Migrating")
+        assert operation.status == "cancelling"
+        assert operation.runner == "laptop:1"
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "put"
+        assert kwargs["url"] == "http+unix://mock/1.0/operations/3"
+        assert json.loads(kwargs["data"]) == {
+            "status": "",
+            "runner": "laptop:1",
+            "progress": "Migrating",
+            "result": "",
+            "error": "",
+        }
+
+    def test_update_operation_claimed(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": 'Operation 3 is run by "laptop:1", not "agent:1"',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.OperationConflictException):
+            cs.update_operation(3, "agent:1", progress="Migrating")
+
+    def test_add_webhook(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.add_webhook(
+            models.Webhook(
+                name="pager", url="https://pager.example.com", secret="s3cret"
+            )
+        )
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/webhooks"
+        assert json.loads(kwargs["data"]) == {
+            "name": "pager",
+            "url": "https://pager.example.com",
+            "secret": "s3cret",
+            "events": [],
+        }
+
+    def test_add_webhook_exists(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": 'Webhook "pager" already exists',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.WebhookAlreadyExistsException):
+            cs.add_webhook(
+                models.Webhook(
+                    name="pager", url="https://pager.example.com", secret="s"
+                )
+            )
+
+    def test_list_webhooks(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "name": "pager",
+                    "url": "https://pager.example.com",
+                    "events": ["node.added"],
+                }
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        webhooks = cs.list_webhooks()
+        assert [hook.name for hook in webhooks] == ["pager"]
+        assert webhooks[0].events == ["node.added"]
+        assert webhooks[0].secret is None
+
+    def test_create_read_only_token(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {"token": "s3cret"},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        assert cs.create_read_only_token("grafana") == "s3cret"
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/read-only-tokens"
+        assert json.loads(kwargs["data"]) == {"name": "grafana"}
+
+    def test_set_node_cordon(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {"name": "node-1", "role": ["compute"], "cordoned": True},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        assert cs.set_node_cordon("node-1", True)["cordoned"] is True
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["method"] == "put"
+        assert kwargs["url"] == "http+unix://mock/1.0/nodes/node-1/cordon"
+        assert json.loads(kwargs["data"]) == {"cordoned": True}
+
+    def test_delete_read_only_token_not_found(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 404,
+            "error": 'Read-only token "grafana" not found',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=404,
+            json_data=json_data,
+            raise_for_status=HTTPError("Not Found"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ReadOnlyTokenNotFoundException):
+            cs.delete_read_only_token("grafana")
+
+    def test_create_join_token_scope(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "name": "node-2",
+                "roles": ["compute"],
+                "uses": 1,
+                "used": 0,
+                "expires_at": "2026-10-14T07:00:00Z",
+                "state": "valid",
+                "created_at": "2026-10-14T06:00:00Z",
+                "created_by": "ubuntu@node-1",
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        scope = cs.create_join_token_scope("node-2", ["compute"], ttl="1h")
+        assert scope.expires_at == "2026-10-14T07:00:00Z"
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/join-token-scopes"
+        assert json.loads(kwargs["data"]) == {
+            "name": "node-2",
+            "roles": ["compute"],
+            "ttl": "1h",
+        }
+
+    def test_generate_token_expire_after(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": "TESTTOKEN",
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        assert cs.generate_token("node-2", expire_after=datetime.timedelta(hours=1))
+        kwargs = mock_session.request.call_args.kwargs
+        assert json.loads(kwargs["data"]) == {
+            "name": "node-2",
+            "expire_after": 3600 * 1_000_000_000,
+        }
+
+    def test_export_config(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {"redacted": True, "config": {"K8SKubeConfig": "<redacted>"}},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        document = cs.export_config(redact_secrets=True)
+        assert document == json_data["metadata"]
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/config:export"
+        assert kwargs["params"] == {"redact_secrets": "true"}
+
+    def test_import_config(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "created": [],
+                "updated": ["deployment.type"],
+                "deleted": ["old-key"],
+                "skipped": [],
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        result = cs.import_config({"deployment.type": '"maas"'}, prune=True)
+        assert result.updated == ["deployment.type"]
+        assert result.deleted == ["old-key"]
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/config:import"
+        assert json.loads(kwargs["data"]) == {
+            "config": {"deployment.type": '"maas"'},
+            "prune": True,
+        }
+
+    def test_add_node_info_addresses(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {},
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.add_node_info(
+            "node-2",
+            ["compute"],
+            addresses={"management": "10.0.0.5", "data": "10.1.0.5"},
+            preferred_api="data",
+        )
+        kwargs = mock_session.request.call_args.kwargs
+        assert json.loads(kwargs["data"]) == {
+            "name": "node-2",
+            "role": ["compute"],
+            "machineid": -1,
+            "systemid": "",
+            "addresses": {"management": "10.0.0.5", "data": "10.1.0.5"},
+            "preferred_api": "data",
+        }
+
+    def test_add_node_info_invalid_preferred_api_address(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 400,
+            "error": 'Invalid preferred API address "public", it is not an address'
+            " of the node",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=400,
+            json_data=json_data,
+            raise_for_status=HTTPError("Bad Request"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.InvalidNodeAddressException):
+            cs.add_node_info(
+                "node-2",
+                ["compute"],
+                addresses={"management": "10.0.0.5"},
+                preferred_api="public",
+            )
+
+    def test_add_node_info_join_token_rejected(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 403,
+            "error": 'Join token of node "node-2" does not permit role "control", '
+            "only compute",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=403,
+            json_data=json_data,
+            raise_for_status=HTTPError("Forbidden"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.JoinTokenRejectedException):
+            cs.add_node_info("node-2", ["control"])
+
+    def test_add_node_info_already_exists(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": 'Node "node-2" already exists',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.NodeAlreadyExistsException):
+            cs.add_node_info("node-2", ["compute"])
+
+    def test_create_join_token_scope_already_scoped(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 409,
+            "error": 'Join token of node "node-2" is already scoped',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=409,
+            json_data=json_data,
+            raise_for_status=HTTPError("Conflict"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.JoinTokenScopeAlreadyExistsException):
+            cs.create_join_token_scope("node-2", ["compute"])
+
+    def test_get_config_history(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [
+                {
+                    "revision": 1,
+                    "value": '"one"',
+                    "changed_at": "2026-10-14T12:00:00Z",
+                    "changed_by": "admin",
+                },
+                {
+                    "revision": 2,
+                    "value": '"two"',
+                    "changed_at": "2026-10-14T12:01:00Z",
+                    "changed_by": "admin",
+                },
+            ],
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        history = cs.get_config_history("key")
+        assert [revision.value for revision in history] == ['"one"', '"two"']
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/config/key/history"
+
+    def test_rollback_config(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": {
+                "revision": 3,
+                "value": '"one"',
+                "changed_at": "2026-10-14T12:02:00Z",
+                "changed_by": "admin",
+            },
+        }
+        mock_response = self._mock_response(status=200, json_data=json_data)
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        assert cs.rollback_config("key", 1).revision == 3
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/config/key/rollback"
+        assert json.loads(kwargs["data"]) == {"revision": 1}
+
+    def test_rollback_config_not_recorded(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 404,
+            "error": 'No previous revision of config key "key" to roll back to',
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=404,
+            json_data=json_data,
+            raise_for_status=HTTPError("Not Found"),
+        )
+        mock_session = MagicMock()
+        mock_session.request.return_value = mock_response
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ConfigRevisionNotFoundException):
+            cs.rollback_config("key")
+        assert json.loads(mock_session.request.call_args.kwargs["data"]) == {}
+
+    def test_apply_config_transaction(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=200, json_data=json_data
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.apply_config_transaction({"a": "1"}, {"b": None}, strict=True)
+        kwargs = mock_session.request.call_args.kwargs
+        assert kwargs["url"] == "http+unix://mock/1.0/config:transaction"
+        assert json.loads(kwargs["data"]) == {
+            "set": {"a": "1"},
+            "preconditions": {"b": None},
+        }
+        assert kwargs["headers"]["X-Sunbeam-Config-Strict"] == "true"
+
+        cs.apply_config_transaction({"a": "1"}, role="storage")
+        assert json.loads(mock_session.request.call_args.kwargs["data"]) == {
+            "set": {"a": "1"},
+            "role": "storage",
+        }
+
+    def test_apply_config_transaction_failed(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 412,
+            "error": "Config transaction failed, 2 keys rejected",
+            "metadata": {
+                "code": "config-transaction-failed",
+                "errors": [
+                    {"key": "a", "error": 'Config key "a" is not set'},
+                    {"key": "b", "error": 'Config key "b" is set'},
+                ],
+            },
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=412,
+            json_data=json_data,
+            raise_for_status=HTTPError("Precondition Failed"),
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ConfigTransactionFailedException) as e:
+            cs.apply_config_transaction({"a": "1"})
+        assert e.value.errors == {
+            "a": ['Config key "a" is not set'],
+            "b": ['Config key "b" is set'],
+        }
+        kwargs = mock_session.request.call_args.kwargs
+        assert json.loads(kwargs["data"]) == {"set": {"a": "1"}}
+
+    def test_shutting_down(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 503,
+            "error": "Daemon is shutting down",
+            "metadata": None,
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=503,
+            json_data=json_data,
+            raise_for_status=HTTPError("Service Unavailable"),
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ClusterServiceUnavailableException):
+            cs.get_status()
+
+    def _rate_limited_response(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 429,
+            "error": "Rate limit exceeded, retry in 2s",
+            "metadata": None,
+        }
+        mock_response = self._mock_response(
+            status=429,
+            json_data=json_data,
+            raise_for_status=HTTPError("Too Many Requests"),
+        )
+        mock_response.headers = {"Retry-After": "2"}
+        return mock_response
+
+    def test_rate_limited_request_retried(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": None,
+        }
+        mock_session = MagicMock()
+        mock_session.request.side_effect = [
+            self._rate_limited_response(),
+            self._mock_response(status=200, json_data=json_data),
+        ]
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with patch("sunbeam.clusterd.service.time.sleep") as sleep:
+            cs.add_webhook(models.Webhook(name="hook", url="http://x", secret="s"))
+        assert mock_session.request.call_count == 2
+        sleep.assert_called_once_with(2)
+
+    def test_rate_limited_request_retries_exhausted(self):
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._rate_limited_response()
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with (
+            patch("sunbeam.clusterd.service.time.sleep") as sleep,
+            pytest.raises(service.RateLimitedException),
+        ):
+            cs.list_webhooks()
+        assert mock_session.request.call_count == service.RATE_LIMIT_RETRIES + 1
+        assert sleep.call_count == service.RATE_LIMIT_RETRIES
+
+    def test_request_id_sent(self):
+        json_data = {
+            "type": "sync",
+            "status": "Success",
+            "status_code": 200,
+            "operation": "",
+            "error_code": 0,
+            "error": "",
+            "metadata": [],
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=200, json_data=json_data
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.list_webhooks()
+        cs.list_nodes()
+
+        ids = {
+            call.kwargs["headers"]["X-Request-ID"]
+            for call in mock_session.request.call_args_list
+        }
+        assert ids == {service.REQUEST_ID}
+
+    def test_quorum_lost(self):
+        json_data = {
+            "type": "error",
+            "status": "",
+            "status_code": 0,
+            "operation": "",
+            "error_code": 503,
+            "error": (
+                "Cluster quorum lost: 1 of 3 database voters reachable,"
+                " unreachable: node-2, node-3"
+            ),
+            "metadata": {
+                "code": "cluster-quorum-lost",
+                "expected": ["node-1", "node-2", "node-3"],
+                "reachable": ["node-1"],
+            },
+        }
+        mock_session = MagicMock()
+        mock_session.request.return_value = self._mock_response(
+            status=503,
+            json_data=json_data,
+            raise_for_status=HTTPError("Service Unavailable"),
+        )
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        with pytest.raises(service.ClusterQuorumLostException) as e:
+            cs.get_status()
+        assert str(e.value).startswith("Cluster quorum lost")
+        assert e.value.expected == ["node-1", "node-2", "node-3"]
+        assert e.value.reachable == ["node-1"]
+        assert e.value.unreachable == ["node-2", "node-3"]
+        assert mock_session.request.call_count == 1
+
+
+def _meta(schema_external=EXPECTED_SCHEMA_VERSION, api_prefixes=("1.0", "1.1")):
+    return models.ClusterdMeta(
+        version="0.1",
+        schema_internal=4,
+        schema_external=schema_external,
+        api_prefixes=list(api_prefixes),
+        microcluster_version="v2.2.0",
+    )
+
+
+class TestClusterdCompatibility:
+    def test_compatible(self):
+        client = Mock()
+        client.cluster.get_meta.return_value = _meta()
+
+        assert check_clusterd_compatibility(client.cluster) == []
+
+    def test_too_old_server_rejected(self):
+        client = Mock()
+        client.cluster.get_meta.return_value = _meta(EXPECTED_SCHEMA_VERSION - 2)
+
+        with pytest.raises(
+            IncompatibleClusterdException,
+            match=(
+                "lacks maintenance disable operations, maintenance schedule"
+                " operations"
+            ),
+        ):
+            check_clusterd_compatibility(client.cluster)
+
+    def test_too_old_server_forced(self):
+        client = Mock()
+        client.cluster.get_meta.return_value = _meta(EXPECTED_SCHEMA_VERSION - 1)
+
+        warnings = check_clusterd_compatibility(client.cluster, force=True)
+        assert warnings == [
+            f"clusterd schema version {EXPECTED_SCHEMA_VERSION - 1} is older than"
+            f" {EXPECTED_SCHEMA_VERSION}, it lacks maintenance schedule operations"
+        ]
+
+    def test_newer_server(self):
+        client = Mock()
+        client.cluster.get_meta.return_value = _meta(EXPECTED_SCHEMA_VERSION + 1)
+
+        assert check_clusterd_compatibility(client.cluster) == []
+
+    def test_missing_api_prefix(self):
+        client = Mock()
+        client.cluster.get_meta.return_value = _meta(api_prefixes=["1.1"])
+
+        with pytest.raises(IncompatibleClusterdException, match="/1.0 API"):
+            check_clusterd_compatibility(client.cluster)
+
+    def test_server_without_meta(self):
+        client = Mock()
+        client.cluster.get_meta.side_effect = service.URLNotFoundException(
+            "URL not found"
+        )
+
+        warnings = check_clusterd_compatibility(client.cluster)
+        assert "compatibility is unknown" in warnings[0]
+
+    def test_unreachable_server_skipped(self):
+        client = Mock()
+        client.cluster.get_meta.side_effect = (
+            service.ClusterServiceUnavailableException("not initialized")
+        )
+
+        assert check_clusterd_compatibility(client.cluster) == []
+
+    def test_before_request_runs_once(self, mocker):
+        mock_session = MagicMock()
+        mock_session.request.return_value.json.return_value = {"metadata": {}}
+        hook = Mock()
+        mocker.patch.object(service.BaseService, "before_request", hook)
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        cs.get_config("deployment.type")
+        cs.get_config("deployment.type")
+
+        hook.assert_called_once_with(cs)
+        assert mock_session.request.call_count == 2
+
+    def test_before_request_failure_blocks_request(self, mocker):
+        mock_session = MagicMock()
+        hook = Mock(side_effect=IncompatibleClusterdException("too old"))
+        mocker.patch.object(service.BaseService, "before_request", hook)
+
+        cs = ClusterService(mock_session, "http+unix://mock")
+        for _ in range(2):
+            with pytest.raises(IncompatibleClusterdException):
+                cs.get_config("deployment.type")
+
+        assert hook.call_count == 2
+        mock_session.request.assert_not_called()
+
 
 class TestClusterUpdateJujuControllerStep:
     """Unit tests for sunbeam clusterd steps."""
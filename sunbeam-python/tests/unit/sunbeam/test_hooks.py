@@ -5,6 +5,7 @@ from unittest.mock import MagicMock
 
 from sunbeam.hooks import (
     _check_feature_gate_dependencies,
+    _update_operation_agent,
     sync_feature_gates_from_snap_to_cluster,
 )
 
@@ -198,3 +199,62 @@ class TestConfigureHookEnforcement:
             patch("sunbeam.hooks.setup_logging"),
         ):
             configure(snap)
+
+
+class TestUpdateOperationAgent:
+    """Tests for _update_operation_agent."""
+
+    def _make_snap(self, user: str | None) -> MagicMock:
+        """Create a mock snap with operation-agent.user set to user."""
+        mock_snap = MagicMock()
+
+        def config_get(key):
+            from snaphelpers import UnknownConfigKey
+
+            if user is None:
+                raise UnknownConfigKey(key)
+            return user
+
+        mock_snap.config.get.side_effect = config_get
+        return mock_snap
+
+    def _service(self, snap: MagicMock) -> MagicMock:
+        return snap.services.list.return_value.__getitem__.return_value
+
+    def test_unset_user_stops_service(self):
+        """The service is stopped and disabled without a user."""
+        snap = self._make_snap(None)
+
+        assert _update_operation_agent(snap, "") == ""
+
+        snap.services.list.return_value.__getitem__.assert_called_once_with(
+            "operation-agent"
+        )
+        self._service(snap).stop.assert_called_once_with(disable=True)
+        self._service(snap).start.assert_not_called()
+
+    def test_user_starts_service(self):
+        """The service is enabled once a user is set."""
+        snap = self._make_snap("ubuntu")
+
+        assert _update_operation_agent(snap, "") == "ubuntu"
+
+        self._service(snap).start.assert_called_once_with(enable=True)
+        self._service(snap).restart.assert_not_called()
+
+    def test_unchanged_user_keeps_service(self):
+        """The running service is not restarted for the same user."""
+        snap = self._make_snap("ubuntu")
+
+        assert _update_operation_agent(snap, "ubuntu") == "ubuntu"
+
+        self._service(snap).restart.assert_not_called()
+
+    def test_changed_user_restarts_service(self):
+        """The service is restarted to run as the new user."""
+        snap = self._make_snap("admin")
+
+        assert _update_operation_agent(snap, "ubuntu") == "admin"
+
+        self._service(snap).start.assert_called_once_with(enable=True)
+        self._service(snap).restart.assert_called_once_with()